@@ -0,0 +1,358 @@
+// Package align merges a machine translation with a human translation of the
+// same subtitles that is partial or timed differently (e.g. made for another
+// release): the cues keep the timing of the source, and take the human text
+// where a human cue matches them by time and by similarity with the machine
+// text, or the machine text otherwise.
+package align
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// DefaultThreshold is the minimum similarity of a human text with the machine
+// text of the cues it overlaps to replace it: two translations of a line
+// usually share half their letter pairs or more, unrelated lines a fifth.
+const DefaultThreshold = 0.4
+
+// DefaultMaxOffset is the largest offset of the human translation looked for.
+const DefaultMaxOffset = 10 * time.Second
+
+// Offsets are estimated from pairs of cues at least this similar, and only
+// from minAnchors of them or more: short or common lines match too easily.
+const (
+	anchorSimilarity = 0.7
+	minAnchors       = 3
+)
+
+type Options struct {
+	SourcePath  string // the file the machine translation was translated from
+	MachinePath string // the machine translation, a cue per cue of the source
+	HumanPath   string
+	OutputPath  string
+	// Threshold is the minimum similarity (0-1) of a human text with the
+	// machine text it replaces; 0 means DefaultThreshold.
+	Threshold float64
+	// MaxOffset is the largest offset of the human translation looked for;
+	// 0 means DefaultMaxOffset, and a negative one matches the cues as
+	// timed.
+	MaxOffset    time.Duration
+	BackupExt    string
+	CreateBackup bool // back up the machine translation when overwriting it
+}
+
+type Result struct {
+	WrittenPath string
+	Cues        []*srt.Subtitle
+	Human       int // cues with human text
+	Machine     int // cues with machine text
+	// Merged is the number of source cues joined to the previous one because
+	// a human cue spans them.
+	Merged int
+	// Unmatched is the number of human cues matching no cue; they are
+	// dropped.
+	Unmatched int
+	// Offset is how much later the human translation is timed, subtracted
+	// before matching.
+	Offset time.Duration
+}
+
+// Run aligns the human translation of opts with the machine one and writes
+// the result (see Align).
+func Run(opts Options) (Result, error) {
+	if opts.SourcePath == "" || opts.MachinePath == "" || opts.HumanPath == "" {
+		return Result{}, errors.New("source, machine and human translation paths are required")
+	}
+	if opts.OutputPath == "" {
+		opts.OutputPath = opts.MachinePath
+	}
+	source, err := readDocument(opts.SourcePath)
+	if err != nil {
+		return Result{}, err
+	}
+	machine, err := readDocument(opts.MachinePath)
+	if err != nil {
+		return Result{}, err
+	}
+	human, err := readDocument(opts.HumanPath)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := Align(source.Cues, machine.Cues, human.Cues, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := srt.Write(&buf, machine.WithCues(res.Cues), false); err != nil {
+		return Result{}, err
+	}
+	if opts.CreateBackup && fs.SameFilePath(opts.OutputPath, opts.MachinePath) {
+		backupPath := opts.MachinePath + opts.BackupExt
+		_ = os.Remove(backupPath)
+		if err := fs.CopyFile(opts.MachinePath, backupPath); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := fs.WriteFile(&buf, opts.OutputPath); err != nil {
+		return Result{}, err
+	}
+	res.WrittenPath = opts.OutputPath
+	return res, nil
+}
+
+func readDocument(path string) (*srt.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+	doc, err := srt.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, w := range doc.Warnings {
+		slog.Warn("subtitles file read with a warning", "input_path", path, "warning", w)
+	}
+	srt.Sort(doc.Cues)
+	return doc, nil
+}
+
+// match is a run of source cues and a run of human cues with the same text,
+// one of them a single cue.
+type match struct {
+	source, human []int
+	score         float64
+}
+
+// Align returns the cues of source with the text of machine, the cue at the
+// same position, replaced by the text of human where it matches: human cues,
+// shifted by the offset estimated between both translations, are matched
+// with the source cues they overlap, one to one, one source cue to the human
+// cues it was split into, or the source cues a human cue joins (which are
+// merged), best similarity first. All three are sorted by time.
+func Align(source, machine, human []*srt.Subtitle, opts Options) (Result, error) {
+	if len(source) != len(machine) {
+		return Result{}, fmt.Errorf("the machine translation has %d cues and the source %d; align needs the translation as written by translate", len(machine), len(source))
+	}
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	maxOffset := opts.MaxOffset
+	if maxOffset == 0 {
+		maxOffset = DefaultMaxOffset
+	}
+
+	machineGrams := make([]map[string]int, len(machine))
+	for i, m := range machine {
+		machineGrams[i] = letterPairs(m.Text)
+	}
+	humanGrams := make([]map[string]int, len(human))
+	for k, h := range human {
+		humanGrams[k] = letterPairs(h.Text)
+	}
+	var res Result
+	if maxOffset > 0 {
+		res.Offset = estimateOffset(source, machineGrams, human, humanGrams, maxOffset)
+	}
+
+	// The human cues as timed in the source.
+	shifted := make([]*srt.Subtitle, len(human))
+	for k, h := range human {
+		s := *h
+		s.FromTime -= res.Offset
+		s.ToTime -= res.Offset
+		shifted[k] = &s
+	}
+	matches := candidates(source, shifted)
+	for i := range matches {
+		m := &matches[i]
+		m.score = similarity(joinedPairs(machine, m.source), joinedPairs(human, m.human))
+	}
+	// Best first; on a tie, the match of fewer cues.
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].source)+len(matches[i].human) < len(matches[j].source)+len(matches[j].human)
+	})
+	sourceMatch := make([]*match, len(source))
+	humanUsed := make([]bool, len(human))
+	for i := range matches {
+		m := &matches[i]
+		if m.score < threshold || slices.ContainsFunc(m.source, func(s int) bool { return sourceMatch[s] != nil }) ||
+			slices.ContainsFunc(m.human, func(h int) bool { return humanUsed[h] }) {
+			continue
+		}
+		for _, s := range m.source {
+			sourceMatch[s] = m
+		}
+		for _, h := range m.human {
+			humanUsed[h] = true
+		}
+	}
+
+	for i, s := range source {
+		m := sourceMatch[i]
+		if m == nil {
+			c := *machine[i]
+			c.FromTime, c.ToTime = s.FromTime, s.ToTime
+			res.Cues = append(res.Cues, &c)
+			res.Machine++
+			continue
+		}
+		if i != m.source[0] {
+			res.Merged++
+			continue
+		}
+		texts := make([]string, len(m.human))
+		for j, h := range m.human {
+			texts[j] = human[h].Text
+		}
+		text := srt.CleanText(strings.Join(texts, "\n"))
+		forced := slices.ContainsFunc(m.source, func(s int) bool { return strings.Contains(machine[s].Text, srt.ForcedTag) })
+		if forced && !strings.Contains(text, srt.ForcedTag) {
+			text = srt.ForcedTag + text
+		}
+		c := &srt.Subtitle{FromTime: s.FromTime, ToTime: source[m.source[len(m.source)-1]].ToTime, Text: text, Notes: machine[i].Notes}
+		srt.InferFlags(c)
+		res.Cues = append(res.Cues, c)
+		res.Human++
+	}
+	for i, c := range res.Cues {
+		c.Idx = i + 1
+	}
+	for _, used := range humanUsed {
+		if !used {
+			res.Unmatched++
+		}
+	}
+	return res, nil
+}
+
+// estimateOffset returns the median of how much later the human cues are
+// timed than the source cues whose machine text they closely match, looked
+// for within maxOffset; 0 with too few of them.
+func estimateOffset(source []*srt.Subtitle, machineGrams []map[string]int, human []*srt.Subtitle, humanGrams []map[string]int, maxOffset time.Duration) time.Duration {
+	var deltas []time.Duration
+	for i, s := range source {
+		if len(machineGrams[i]) == 0 {
+			continue
+		}
+		from := sort.Search(len(human), func(k int) bool { return human[k].FromTime >= s.FromTime-maxOffset })
+		best, bestScore := -1, anchorSimilarity
+		for k := from; k < len(human) && human[k].FromTime <= s.FromTime+maxOffset; k++ {
+			if score := similarity(machineGrams[i], humanGrams[k]); score >= bestScore {
+				best, bestScore = k, score
+			}
+		}
+		if best >= 0 {
+			deltas = append(deltas, human[best].FromTime-s.FromTime)
+		}
+	}
+	if len(deltas) < minAnchors {
+		return 0
+	}
+	slices.Sort(deltas)
+	return deltas[len(deltas)/2]
+}
+
+// candidates returns the possible matches of source and human cues: every
+// pair that overlaps, every source cue with the human cues centered in it
+// when there are several, and every human cue with the source cues centered
+// in it when there are several.
+func candidates(source, human []*srt.Subtitle) []match {
+	var longest time.Duration
+	for _, h := range human {
+		longest = max(longest, h.ToTime-h.FromTime)
+	}
+	var matches []match
+	centeredSources := make([][]int, len(human))
+	for i, s := range source {
+		var centered []int
+		from := sort.Search(len(human), func(k int) bool { return human[k].FromTime > s.FromTime-longest })
+		for k := from; k < len(human) && human[k].FromTime < s.ToTime; k++ {
+			h := human[k]
+			if min(s.ToTime, h.ToTime) <= max(s.FromTime, h.FromTime) {
+				continue
+			}
+			matches = append(matches, match{source: []int{i}, human: []int{k}})
+			if centeredIn(h, s) {
+				centered = append(centered, k)
+			}
+			if centeredIn(s, h) {
+				centeredSources[k] = append(centeredSources[k], i)
+			}
+		}
+		if len(centered) > 1 {
+			matches = append(matches, match{source: []int{i}, human: centered})
+		}
+	}
+	for k, centered := range centeredSources {
+		if len(centered) > 1 {
+			matches = append(matches, match{source: centered, human: []int{k}})
+		}
+	}
+	return matches
+}
+
+// centeredIn reports whether the middle of a is within b.
+func centeredIn(a, b *srt.Subtitle) bool {
+	mid := a.FromTime + (a.ToTime-a.FromTime)/2
+	return mid >= b.FromTime && mid < b.ToTime
+}
+
+func joinedPairs(subs []*srt.Subtitle, idxs []int) map[string]int {
+	if len(idxs) == 1 {
+		return letterPairs(subs[idxs[0]].Text)
+	}
+	texts := make([]string, len(idxs))
+	for j, i := range idxs {
+		texts[j] = subs[i].Text
+	}
+	return letterPairs(strings.Join(texts, " "))
+}
+
+// letterPairs returns the pairs of consecutive letters and digits of the
+// visible text, lowercase, with a space between words, and how many times
+// each occurs.
+func letterPairs(text string) map[string]int {
+	words := strings.FieldsFunc(strings.ToLower(srt.VisibleText(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	runes := []rune(strings.Join(words, " "))
+	pairs := make(map[string]int, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		pairs[string(runes[i:i+2])]++
+	}
+	return pairs
+}
+
+// similarity is the Dice coefficient of two multisets of letter pairs: twice
+// the pairs they share over all their pairs.
+func similarity(a, b map[string]int) float64 {
+	total, common := 0, 0
+	for p, n := range a {
+		total += n
+		common += min(n, b[p])
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(common) / float64(total)
+}
@@ -0,0 +1,94 @@
+package align
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// cues returns cues of texts, a second apart from start, each shown for a
+// second.
+func cues(start time.Duration, texts ...string) []*srt.Subtitle {
+	subs := make([]*srt.Subtitle, len(texts))
+	for i, text := range texts {
+		from := start + time.Duration(i)*time.Second
+		subs[i] = &srt.Subtitle{Idx: i + 1, FromTime: from, ToTime: from + time.Second, Text: text}
+	}
+	return subs
+}
+
+func TestAlign_OffsetAndPartialHuman(t *testing.T) {
+	source := cues(10*time.Second, "Where are you going?", "I don't know what to do with this.", "He never told me the truth.",
+		"The meeting is tomorrow at ten.", "We have to leave right now.", "Where is my brother?")
+	machine := cues(10*time.Second, "¿A dónde vas?", "No sé qué hacer con esto.", "Él nunca me dijo la verdad.",
+		"La reunión es mañana a las diez.", "Tenemos que irnos ahora mismo.", "¿Dónde está mi hermano?")
+	// Timed 2.5s later and without the last two lines; the fifth slot holds
+	// an unrelated line.
+	human := cues(12500*time.Millisecond, "¿Adónde vas?", "No sé qué hacer con eso.", "Nunca me contó la verdad.",
+		"Mañana a las diez es la reunión.", "Qué lindo día.")
+
+	res, err := Align(source, machine, human, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Offset != 2500*time.Millisecond {
+		t.Errorf("offset = %v, want 2.5s", res.Offset)
+	}
+	want := []string{"¿Adónde vas?", "No sé qué hacer con eso.", "Nunca me contó la verdad.",
+		"Mañana a las diez es la reunión.", "Tenemos que irnos ahora mismo.", "¿Dónde está mi hermano?"}
+	if len(res.Cues) != len(want) {
+		t.Fatalf("got %d cues, want %d", len(res.Cues), len(want))
+	}
+	for i, c := range res.Cues {
+		if c.Text != want[i] || c.FromTime != source[i].FromTime || c.Idx != i+1 {
+			t.Errorf("cue %d = %d %v %q, want %q at %v", i+1, c.Idx, c.FromTime, c.Text, want[i], source[i].FromTime)
+		}
+	}
+	if res.Human != 4 || res.Machine != 2 || res.Unmatched != 1 || res.Merged != 0 {
+		t.Errorf("human %d, machine %d, unmatched %d, merged %d", res.Human, res.Machine, res.Unmatched, res.Merged)
+	}
+}
+
+func TestAlign_SplitAndMergedCues(t *testing.T) {
+	source := cues(0, "Hello there, my friend.", "We need to talk about the money you owe me.", "Right now.", "Come in.")
+	machine := cues(0, "Hola, amigo mío.", "Tenemos que hablar del dinero que me debes.", "Ahora mismo.", "Pasa.")
+	machine[3].Text = srt.ForcedTag + "Pasa."
+	human := []*srt.Subtitle{
+		// The second source cue, split in two.
+		{FromTime: 1 * time.Second, ToTime: 1500 * time.Millisecond, Text: "Tenemos que hablar"},
+		{FromTime: 1500 * time.Millisecond, ToTime: 2 * time.Second, Text: "del dinero que me debes."},
+		// The third and fourth, joined.
+		{FromTime: 2 * time.Second, ToTime: 4 * time.Second, Text: "Ahora mismo. Pasa."},
+	}
+
+	res, err := Align(source, machine, human, Options{MaxOffset: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range res.Cues {
+		got = append(got, srt.FormatTime(c.FromTime)+" "+srt.FormatTime(c.ToTime)+" "+c.Text)
+	}
+	want := []string{
+		"00:00:00,000 00:00:01,000 Hola, amigo mío.",
+		"00:00:01,000 00:00:02,000 Tenemos que hablar\ndel dinero que me debes.",
+		"00:00:02,000 00:00:04,000 " + srt.ForcedTag + "Ahora mismo. Pasa.",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("cues =\n%q\nwant\n%q", got, want)
+	}
+	if !res.Cues[2].Forced {
+		t.Error("the merged cue lost the forced flag of the machine cue")
+	}
+	if res.Human != 2 || res.Machine != 1 || res.Merged != 1 || res.Unmatched != 0 {
+		t.Errorf("human %d, machine %d, merged %d, unmatched %d", res.Human, res.Machine, res.Merged, res.Unmatched)
+	}
+}
+
+func TestAlign_CueCountMismatch(t *testing.T) {
+	if _, err := Align(cues(0, "A", "B"), cues(0, "A"), nil, Options{}); err == nil {
+		t.Fatal("expected an error with a machine translation of other cues")
+	}
+}
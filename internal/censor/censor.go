@@ -0,0 +1,168 @@
+// Package censor masks the words of a list (profanity, slurs...) in subtitle
+// text, for media servers that need cleaned subtitle tracks.
+package censor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+)
+
+// Censoring styles.
+const (
+	// StyleStars keeps the first letter of a word and masks the rest
+	// (f***).
+	StyleStars = "stars"
+	// StyleRemoveCue drops the cues with a listed word.
+	StyleRemoveCue = "remove-cue"
+	// StyleBeepText replaces the word with BeepText.
+	StyleBeepText = "beep-text"
+
+	DefaultStyle = StyleStars
+)
+
+// BeepText is the text that replaces the listed words with StyleBeepText.
+const BeepText = "[beep]"
+
+// NormalizeStyle trims and lowercases style.
+func NormalizeStyle(style string) string {
+	return strings.ToLower(strings.TrimSpace(style))
+}
+
+// IsValidStyle reports whether style is one of the censoring styles.
+func IsValidStyle(style string) bool {
+	switch style {
+	case StyleStars, StyleRemoveCue, StyleBeepText:
+		return true
+	default:
+		return false
+	}
+}
+
+// List is a list of words to censor, loaded with Load.
+type List struct {
+	entries []entry
+}
+
+type entry struct {
+	pattern *regexp.Regexp
+	// replacement, when set, replaces the word instead of the style.
+	replacement string
+}
+
+// Load reads a word list: a word or phrase per line, matched as whole words
+// and ignoring case. A trailing * also matches the words starting with it
+// (damn* matches damned), and word=replacement sets the text that replaces it
+// instead of the style. Empty lines and lines starting with # are ignored.
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+
+	l := &List{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, replacement, _ := strings.Cut(line, "=")
+		word, replacement = strings.TrimSpace(word), strings.TrimSpace(replacement)
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.TrimSpace(strings.TrimSuffix(word, "*"))
+		if word == "" {
+			return nil, fmt.Errorf("%s:%d: expected a word", path, n)
+		}
+		// The words of a phrase may be broken across lines.
+		expr := strings.Join(quoteWords(strings.Fields(word)), `\s+`)
+		if prefix {
+			expr += `[\p{L}\p{N}]*`
+		}
+		l.entries = append(l.entries, entry{
+			pattern:     regexp.MustCompile(`(?i)` + expr),
+			replacement: replacement,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func quoteWords(words []string) []string {
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	return words
+}
+
+// Apply masks the listed words in text following style and returns the
+// result and the words found, as written in text. With StyleRemoveCue, text
+// is returned as is: dropping the cue is up to the caller.
+func (l *List) Apply(text, style string) (string, []string) {
+	var found []string
+	for _, e := range l.entries {
+		var b strings.Builder
+		last := 0
+		for _, m := range e.pattern.FindAllStringIndex(text, -1) {
+			if !isWordBoundary(text, m[0], m[1]) {
+				continue
+			}
+			word := text[m[0]:m[1]]
+			found = append(found, word)
+			b.WriteString(text[last:m[0]])
+			b.WriteString(mask(word, e.replacement, style))
+			last = m[1]
+		}
+		if last > 0 {
+			b.WriteString(text[last:])
+			text = b.String()
+		}
+	}
+	return text, found
+}
+
+// isWordBoundary reports whether text[start:end] is a whole word: it isn't
+// preceded or followed by a letter or digit.
+func isWordBoundary(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func mask(word, replacement, style string) string {
+	switch {
+	case style == StyleRemoveCue:
+		return word
+	case replacement != "":
+		return replacement
+	case style == StyleBeepText:
+		return BeepText
+	}
+	// Keep the first letter and the spaces between the words of a phrase.
+	var b strings.Builder
+	for i, r := range word {
+		if i == 0 || !isWordRune(r) {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteByte('*')
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package censor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func loadList(t *testing.T, content string) *List {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	l, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return l
+}
+
+func TestApply(t *testing.T) {
+	l := loadList(t, "# family list\n\ndamn*\nhell\nson of a bitch\nfreaking=flipping\n")
+	cases := []struct {
+		style string
+		text  string
+		want  string
+		found []string
+	}{
+		{StyleStars, "Damn it, hell no!", "D*** it, h*** no!", []string{"Damn", "hell"}},
+		{StyleStars, "Hello, damned shell.", "Hello, d***** shell.", []string{"damned"}},
+		{StyleStars, "You son of a\nbitch.", "You s** ** *\n*****.", []string{"son of a\nbitch"}},
+		{StyleBeepText, "Hell, hell.", "[beep], [beep].", []string{"Hell", "hell"}},
+		{StyleBeepText, "Freaking great.", "flipping great.", []string{"Freaking"}},
+		{StyleRemoveCue, "What the hell?", "What the hell?", []string{"hell"}},
+		{StyleStars, "Nothing here.", "Nothing here.", nil},
+	}
+	for _, tc := range cases {
+		got, found := l.Apply(tc.text, tc.style)
+		if got != tc.want || !slices.Equal(found, tc.found) {
+			t.Fatalf("Apply(%q, %s) = %q, %q; want %q, %q", tc.text, tc.style, got, found, tc.want, tc.found)
+		}
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("=oops\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected an error for a line without a word")
+	}
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/align"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var alignCmd = &cobra.Command{
+	Use:   "align [flags] <source-file> <machine-translation>",
+	Short: "Merge a machine translation with a partial or differently timed human translation, preferring the human text where it matches",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		humanPath, _ := cmd.Flags().GetString(flagHuman)
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		skipBackup, _ := cmd.Flags().GetBool(flagSkipBackup)
+		threshold, _ := cmd.Flags().GetFloat64(flagThreshold)
+		maxOffset, _ := cmd.Flags().GetDuration(flagMaxOffset)
+
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("invalid --%s %g (must be > 0 and <= 1)", flagThreshold, threshold)
+		}
+		if maxOffset < 0 {
+			return fmt.Errorf("invalid --%s %s (must be >= 0)", flagMaxOffset, maxOffset)
+		}
+		if maxOffset == 0 {
+			maxOffset = -1 // match the cues as timed
+		}
+		if args[0] == "-" || args[1] == "-" {
+			return errors.New("stdin is not supported; pass file paths")
+		}
+		sourcePath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		machinePath, err := fs.ResolveAbsPath(args[1])
+		if err != nil {
+			return err
+		}
+		if humanPath, err = fs.ResolveAbsPath(humanPath); err != nil {
+			return err
+		}
+		if outputPath != "" {
+			if outputPath, err = fs.ResolveAbsPath(outputPath); err != nil {
+				return err
+			}
+		} else {
+			outputPath = inPlaceOutputPath(machinePath)
+		}
+
+		result, err := align.Run(align.Options{
+			SourcePath:   sourcePath,
+			MachinePath:  machinePath,
+			HumanPath:    humanPath,
+			OutputPath:   outputPath,
+			Threshold:    threshold,
+			MaxOffset:    maxOffset,
+			BackupExt:    ".bak",
+			CreateBackup: !skipBackup,
+		})
+		if err != nil {
+			return err
+		}
+
+		if result.Unmatched > 0 {
+			log.Warn("human cues matching no cue of the machine translation were dropped", "count", result.Unmatched)
+		}
+		recordFile(alignFileResult{
+			Command:     "align",
+			Source:      sourcePath,
+			Machine:     machinePath,
+			Human:       humanPath,
+			Output:      result.WrittenPath,
+			Offset:      result.Offset.String(),
+			Cues:        len(result.Cues),
+			HumanCues:   result.Human,
+			MachineCues: result.Machine,
+			Merged:      result.Merged,
+			Unmatched:   result.Unmatched,
+		})
+		log.Info("translations aligned", "path", result.WrittenPath, "offset", result.Offset, "human", result.Human, "machine", result.Machine, "merged", result.Merged)
+		return nil
+	},
+}
+
+// alignFileResult is the --json entry of a merged translation.
+type alignFileResult struct {
+	Command     string `json:"command"`
+	Source      string `json:"source"`
+	Machine     string `json:"machine"`
+	Human       string `json:"human"`
+	Output      string `json:"output"`
+	Offset      string `json:"offset"` // of the human translation, e.g. "2.5s"
+	Cues        int    `json:"cues"`
+	HumanCues   int    `json:"human_cues"`   // cues with the human text
+	MachineCues int    `json:"machine_cues"` // cues with the machine text
+	Merged      int    `json:"merged"`       // source cues joined because a human cue spans them
+	Unmatched   int    `json:"unmatched"`    // human cues dropped
+}
+
+func init() {
+	alignCmd.Flags().String(flagHuman, "", "Human translation to take the text from (partial or timed for another release)")
+	alignCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to overwriting the machine translation)")
+	alignCmd.Flags().Bool(flagSkipBackup, false, "Do not create a .bak backup when overwriting the machine translation")
+	alignCmd.Flags().Float64(flagThreshold, align.DefaultThreshold, "Minimum text similarity (0-1) of a human cue with the machine text it replaces")
+	alignCmd.Flags().Duration(flagMaxOffset, align.DefaultMaxOffset, "Largest offset of the human translation to look for, either way (0 matches the cues as timed)")
+	_ = alignCmd.MarkFlagRequired(flagHuman)
+}
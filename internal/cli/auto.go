@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/extract"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+)
+
+// autoSteps are the steps run on the source subtitle.
+var autoSteps = []string{stepFix, stepTranslate}
+
+// autoFlags are the flags of auto itself, never passed to a step. The hooks
+// run once, when auto ends.
+var autoFlags = map[string]bool{
+	flagOutput: true, flagDryRun: true, flagWorkdir: true, flagTrack: true, flagTool: true,
+	flagPlexNaming: true, flagJellyfinNaming: true,
+	flagOnSuccess: true, flagOnFailure: true,
+}
+
+var autoCmd = &cobra.Command{
+	Use:   "auto [flags] <video-file>",
+	Short: "Fix and translate the subtitle of a video (next to it or embedded), writing the translation next to the video",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagTool, envExtractTool); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		track, _ := cmd.Flags().GetInt(flagTrack)
+		tool, _ := cmd.Flags().GetString(flagTool)
+		targetLang, _ := cmd.Flags().GetString(flagTargetLanguage)
+		sourceLang, _ := cmd.Flags().GetString(flagSourceLanguage)
+
+		targetLang = strings.TrimSpace(targetLang)
+		sourceLang = strings.TrimSpace(sourceLang)
+		if targetLang == "" {
+			return fmt.Errorf("--%s is required", flagTargetLanguage)
+		}
+		if strings.Contains(targetLang, ",") {
+			return fmt.Errorf("--%s must be a single language", flagTargetLanguage)
+		}
+		namingScheme, err := namingSchemeFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if namingScheme != "" && outputPath != "" {
+			return fmt.Errorf("--output can't be combined with --%s or --%s", flagPlexNaming, flagJellyfinNaming)
+		}
+		tool = extract.NormalizeTool(tool)
+		if !extract.IsValidTool(tool) {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s, %s)", flagTool, tool, extract.ToolAuto, extract.ToolFFmpeg, extract.ToolMKVExtract)
+		}
+		if track < extract.NoTrack {
+			return fmt.Errorf("invalid --%s %d (must be a stream index >= 0)", flagTrack, track)
+		}
+
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a video file path")
+		}
+		videoPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(videoPath); err != nil {
+			return err
+		}
+		if outputPath == "" {
+			outputPath = naming.Path(videoPath, namingScheme, naming.Name{Language: targetLang})
+		}
+		if outputPath, err = resolveNewOutputPath(outputPath); err != nil {
+			return err
+		}
+
+		// A subtitle next to the video is used as is, unless a track is
+		// picked.
+		var sourcePath string
+		if track == extract.NoTrack {
+			subs, err := naming.FindSubtitles(videoPath)
+			if err != nil {
+				return err
+			}
+			if sourcePath, err = pickSourceSubtitle(subs, targetLang, sourceLang); err != nil {
+				return err
+			}
+		}
+
+		if workdir != "" {
+			if workdir, err = fs.ResolveAbsPath(workdir); err != nil {
+				return err
+			}
+		}
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "auto")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		result := autoResult{Command: "auto", Input: videoPath, Source: sourcePath, Steps: autoSteps}
+		var language string
+		if sourcePath != "" {
+			log.Info("using the subtitle next to the video", "path", sourcePath)
+			_, name := naming.Parse(sourcePath)
+			language = name.Language
+		} else {
+			if track == extract.NoTrack && sourceLang != "" {
+				if track, err = trackOfLanguage(cmd, videoPath, tool, sourceLang); err != nil {
+					return err
+				}
+			}
+			log.Info("extracting the subtitle track of the video", "path", videoPath)
+			extracted, err := extract.Run(ctx, extract.Options{
+				InputPath:  videoPath,
+				OutputPath: filepath.Join(runWorkdir, "00-extract.srt"),
+				WorkDir:    runWorkdir,
+				Track:      track,
+				Tool:       tool,
+			})
+			if err != nil {
+				if track == extract.NoTrack {
+					return fmt.Errorf("no subtitle file next to the video, and none could be extracted: %w", err)
+				}
+				return err
+			}
+			sourcePath = extracted.WrittenPath
+			result.Track = &extracted.Track.ID
+			if lang := extracted.Track.Language; lang != "" && lang != naming.Undetermined {
+				language = naming.ShortLanguage(lang)
+			}
+			log.Info("subtitle track extracted", "track", extracted.Track.ID, "language", extracted.Track.Language, "cues", extracted.Cues)
+		}
+		if language == "" {
+			language = sourceLang
+		}
+
+		current, err := runSteps(ctx, cmd, autoSteps, autoFlags, sourcePath, language, targetLang, runWorkdir)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			result.Output = current
+			recordFile(result)
+			log.Info("translated subtitle written (dry-run)", "path", current)
+			return nil
+		}
+		if err := fs.MoveFile(current, outputPath); err != nil {
+			return err
+		}
+		result.Output = outputPath
+		recordFile(result)
+		log.Info("translated subtitle written", "path", outputPath)
+		return nil
+	},
+}
+
+// autoResult is the --json entry of the translated subtitle, after the
+// entries of its steps (which write to the workdir).
+type autoResult struct {
+	Command string   `json:"command"`
+	Input   string   `json:"input"`
+	Source  string   `json:"source,omitempty"` // the subtitle next to the video, if used
+	Track   *int     `json:"track,omitempty"`  // the track extracted otherwise
+	Output  string   `json:"output"`
+	Steps   []string `json:"steps"`
+}
+
+// pickSourceSubtitle returns the subtitle of paths to translate: not in the
+// target language, in the source language when given, and preferring full
+// subtitles to SDH and forced ones. It returns "" when none fits, and an
+// error when several fit equally.
+func pickSourceSubtitle(paths []string, targetLang, sourceLang string) (string, error) {
+	rank := func(n naming.Name) int {
+		switch {
+		case n.Forced:
+			return 2
+		case n.SDH:
+			return 1
+		default:
+			return 0
+		}
+	}
+	var best []string
+	bestRank := -1
+	for _, path := range paths {
+		_, n := naming.Parse(path)
+		if n.Language != "" && sameLanguage(n.Language, targetLang) {
+			continue
+		}
+		if sourceLang != "" && (n.Language == "" || !sameLanguage(n.Language, sourceLang)) {
+			continue
+		}
+		switch r := rank(n); {
+		case bestRank < 0 || r < bestRank:
+			best, bestRank = []string{path}, r
+		case r == bestRank:
+			best = append(best, path)
+		}
+	}
+	switch len(best) {
+	case 0:
+		return "", nil
+	case 1:
+		return best[0], nil
+	}
+	sort.Strings(best)
+	names := make([]string, len(best))
+	for i, path := range best {
+		names[i] = filepath.Base(path)
+	}
+	return "", fmt.Errorf("several subtitles next to the video (%s); pick one with --%s", strings.Join(names, ", "), flagSourceLanguage)
+}
+
+func sameLanguage(a, b string) bool {
+	return strings.EqualFold(naming.ShortLanguage(a), naming.ShortLanguage(b))
+}
+
+// trackOfLanguage returns the stream index of the text subtitle track of
+// videoPath in lang, preferring full subtitles to forced ones.
+func trackOfLanguage(cmd *cobra.Command, videoPath, tool, lang string) (int, error) {
+	tracks, err := extract.ListTracks(cmd.Context(), videoPath, tool)
+	if err != nil {
+		return 0, fmt.Errorf("no subtitle file next to the video, and none could be extracted: %w", err)
+	}
+	id := extract.NoTrack
+	for _, t := range tracks {
+		if !t.Text || !sameLanguage(t.Language, lang) {
+			continue
+		}
+		if !t.Forced {
+			return t.ID, nil
+		}
+		if id == extract.NoTrack {
+			id = t.ID
+		}
+	}
+	if id == extract.NoTrack {
+		return 0, fmt.Errorf("no %s subtitle file next to the video, and no %s text subtitle track in it", lang, lang)
+	}
+	return id, nil
+}
+
+func init() {
+	registerAutoFlags(autoCmd)
+}
+
+func registerAutoFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to <video>.<target-language>.srt next to the video; must not already exist)")
+	cmd.Flags().Bool(flagDryRun, false, "Leave the output in the workdir and do not create the final output file")
+	cmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base, shared by every step. If set, a unique subdirectory is created per run")
+	cmd.Flags().Int(flagTrack, extract.NoTrack, "Stream index of the subtitle track to translate, extracted even when there is a subtitle next to the video (see extract --list)")
+	cmd.Flags().String(flagTool, extract.DefaultTool, "Extraction tool of embedded tracks: auto, ffmpeg or mkvextract")
+	addNamingFlags(cmd, "Name the output")
+	addStepFlags(cmd)
+}
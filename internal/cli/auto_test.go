@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAutoCLI_AdjacentSubtitle(t *testing.T) {
+	// Steps read the config file; keep the user's one out of the test.
+	t.Setenv(envConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	// A DeepL stand-in that "translates" by upper-casing.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text []string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type translation struct {
+			Text string `json:"text"`
+		}
+		var resp struct {
+			Translations []translation `json:"translations"`
+		}
+		for _, text := range req.Text {
+			resp.Translations = append(resp.Translations, translation{Text: strings.ToUpper(text)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	video := filepath.Join(dir, "movie.mkv")
+	source := filepath.Join(dir, "movie.en.srt")
+	orig := "1\n00:00:01,000 --> 00:00:02,000\nHello there\n\n2\n00:00:03,000 --> 00:00:04,000\n[door slams]\n\n"
+	for path, content := range map[string]string{
+		video:  "",
+		source: orig,
+		// Forced subtitles are only used when there is nothing else.
+		filepath.Join(dir, "movie.en.forced.srt"): "1\n00:00:01,000 --> 00:00:02,000\nSign\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	args := []string{
+		"--target-language", "es", "--provider", "deepl", "--url", server.URL, "--api-key", "k", "--no-cache",
+		"--strip-hi", "--progress", "off", video,
+	}
+	cmd := newAutoTestCommand()
+	cmd.SetArgs(args)
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("auto: %v", err)
+	}
+
+	want := "1\n00:00:01,000 --> 00:00:02,000\nHELLO THERE\n\n"
+	if b, err := os.ReadFile(filepath.Join(dir, "movie.es.srt")); err != nil || string(b) != want {
+		t.Fatalf("unexpected output %q (err %v)", b, err)
+	}
+	if b, err := os.ReadFile(source); err != nil || string(b) != orig {
+		t.Fatalf("expected the source to be untouched, got %q (err %v)", b, err)
+	}
+
+	// The translation next to the video is never overwritten, nor taken as
+	// the source.
+	cmd = newAutoTestCommand()
+	cmd.SetArgs(args)
+	if err := cmd.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an existing output error, got %v", err)
+	}
+}
+
+func TestPickSourceSubtitle(t *testing.T) {
+	paths := []string{"/m/movie.en.forced.srt", "/m/movie.en.sdh.srt", "/m/movie.es.srt", "/m/movie.fr.srt"}
+	tests := []struct {
+		paths              []string
+		target, source     string
+		want, wantErrMatch string
+	}{
+		{paths: paths[:3], target: "es", want: "/m/movie.en.sdh.srt"},
+		{paths: paths[:1], target: "spa", want: "/m/movie.en.forced.srt"},
+		{paths: paths, target: "de", source: "fre", want: "/m/movie.fr.srt"},
+		{paths: paths, target: "de", wantErrMatch: "movie.es.srt, movie.fr.srt"},
+		{paths: paths[2:3], target: "es", want: ""},
+		{paths: []string{"/m/movie.srt"}, target: "es", source: "en", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := pickSourceSubtitle(tt.paths, tt.target, tt.source)
+		if tt.wantErrMatch != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrMatch) {
+				t.Errorf("pickSourceSubtitle(%q, %q, %q) error = %v, want %q", tt.paths, tt.target, tt.source, err, tt.wantErrMatch)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("pickSourceSubtitle(%q, %q, %q) = %q, %v, want %q", tt.paths, tt.target, tt.source, got, err, tt.want)
+		}
+	}
+}
+
+func newAutoTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           autoCmd.Use,
+		Args:          autoCmd.Args,
+		RunE:          autoCmd.RunE,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	registerAutoFlags(cmd)
+	return cmd
+}
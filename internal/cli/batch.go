@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	stfs "github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+// Placeholders of the output and report paths in batch mode, replaced by the
+// directory of each input file and its name without the extension.
+const (
+	inputDirPlaceholder  = "{dir}"
+	inputNamePlaceholder = "{name}"
+)
+
+// defaultInclude selects the files of directory inputs.
+const defaultInclude = "*.srt"
+
+func addBatchFlags(cmd *cobra.Command, defaultJobs int) {
+	_ = cmd.Flags().Bool(flagRecursive, false, "Also process the subdirectories of directory inputs")
+	_ = cmd.Flags().StringSlice(flagInclude, []string{defaultInclude}, "File name patterns of the files processed in directory inputs (repeatable or comma-separated)")
+	_ = cmd.Flags().Int(flagJobs, defaultJobs, "Number of files processed concurrently when there are several inputs")
+}
+
+// batchInput is a file to process; Name is the path as found from the
+// command line arguments, used in messages.
+type batchInput struct {
+	Name string
+	Path string // absolute
+}
+
+// expandInputs resolves the input arguments: files, glob patterns and
+// directories (the files matching --include, descending into subdirectories
+// with --recursive). batch is false for a single file argument, which keeps
+// the single-file behavior (and options such as --video).
+func expandInputs(cmd *cobra.Command, args []string) (inputs []batchInput, batch bool, err error) {
+	recursive, _ := cmd.Flags().GetBool(flagRecursive)
+	include, _ := cmd.Flags().GetStringSlice(flagInclude)
+	for _, pattern := range include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, false, fmt.Errorf("invalid --%s pattern %q: %w", flagInclude, pattern, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	add := func(name string) error {
+		abs, err := stfs.ResolveAbsPath(name)
+		if err != nil {
+			return err
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			inputs = append(inputs, batchInput{Name: name, Path: abs})
+		}
+		return nil
+	}
+	for _, arg := range args {
+		if arg == "-" {
+			return nil, false, errors.New("stdin is not supported yet; pass a subtitle file path")
+		}
+		matches := []string{arg}
+		if _, statErr := os.Stat(arg); statErr != nil && strings.ContainsAny(arg, "*?[") {
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, false, fmt.Errorf("invalid pattern %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, false, fmt.Errorf("no files match %s", arg)
+			}
+			batch = true
+		}
+		for _, m := range matches {
+			st, err := os.Stat(m)
+			if err != nil || !st.IsDir() {
+				// Missing files are reported when they are processed.
+				if err := add(m); err != nil {
+					return nil, false, err
+				}
+				continue
+			}
+			batch = true
+			files, err := findInputFiles(m, recursive, include)
+			if err != nil {
+				return nil, false, err
+			}
+			for _, f := range files {
+				if err := add(f); err != nil {
+					return nil, false, err
+				}
+			}
+		}
+	}
+	if len(args) > 1 {
+		batch = true
+	}
+	if len(inputs) == 0 {
+		return nil, false, fmt.Errorf("no files matching %s found in %s", strings.Join(include, ", "), strings.Join(args, ", "))
+	}
+	return inputs, batch, nil
+}
+
+// findInputFiles returns the files of dir whose name matches one of the
+// include patterns, sorted by path.
+func findInputFiles(dir string, recursive bool, include []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, pattern := range include {
+			if ok, _ := filepath.Match(pattern, d.Name()); ok {
+				files = append(files, path)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// expandInputPlaceholders replaces {dir} and {name} in path with the
+// directory of inputPath and its file name without the extension.
+func expandInputPlaceholders(path, inputPath string) string {
+	name := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	path = strings.ReplaceAll(path, inputDirPlaceholder, filepath.Dir(inputPath))
+	return strings.ReplaceAll(path, inputNamePlaceholder, name)
+}
+
+// requireNamePlaceholder checks that a path flag set in batch mode contains
+// {name}, so every input file gets its own path.
+func requireNamePlaceholder(flag, path string) error {
+	if path != "" && !strings.Contains(path, inputNamePlaceholder) {
+		return fmt.Errorf("--%s must contain %s when processing multiple files (e.g. %s/%s.srt)", flag, inputNamePlaceholder, inputDirPlaceholder, inputNamePlaceholder)
+	}
+	return nil
+}
+
+// runBatch calls process for every input (i is its position in inputs), up to
+// jobs files at a time, and reports the progress in files. A failed file
+// doesn't stop the others: the outcome of each file is logged at the end and
+// the returned error (a *batchError) lists the files that failed.
+func runBatch(ctx context.Context, reporter progress.Reporter, task string, inputs []batchInput, jobs int, process func(ctx context.Context, i int, in batchInput) error) error {
+	log := logging.FromContext(ctx)
+	jobs = max(1, min(jobs, len(inputs)))
+
+	errs := make([]error, len(inputs))
+	var mu sync.Mutex
+	done := 0
+	reporter.Update(progress.Snapshot{Task: task, Unit: "files", Total: len(inputs)})
+
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, in := range inputs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+			} else {
+				errs[i] = process(ctx, i, in)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			reporter.Update(progress.Snapshot{Task: task, Unit: "files", Done: done, Total: len(inputs)})
+		}()
+	}
+	wg.Wait()
+	reporter.Finish()
+
+	var failed []string
+	var failedErrs []error
+	for i, in := range inputs {
+		if errs[i] != nil {
+			failed = append(failed, in.Name)
+			failedErrs = append(failedErrs, errs[i])
+			log.Error("file failed", "path", in.Name, "err", errs[i])
+			recordFile(fileError{Command: task, Input: in.Path, Error: errs[i].Error()})
+		} else {
+			log.Info("file done", "path", in.Name)
+		}
+	}
+	log.Info("batch finished", "files", len(inputs), "succeeded", len(inputs)-len(failed), "failed", len(failed))
+	if len(failed) > 0 {
+		return &batchError{
+			errs:  failedErrs,
+			total: len(inputs),
+			msg:   fmt.Sprintf("%d of %d files failed: %s", len(failed), len(inputs), strings.Join(failed, ", ")),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/config"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const envConfig = "SUBTITLE_TOOLS_CONFIG"
+
+// configAnnotation marks the flags whose value comes from the config file.
+const configAnnotation = "subtitle-tools/config"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View or edit the configuration file with the default flag values",
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the path and the content of the configuration file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showSecrets, _ := cmd.Flags().GetBool(flagShowSecrets)
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if !cfg.Exists() {
+			_, err := fmt.Fprintf(out, "# %s (not found)\n", cfg.Path)
+			return err
+		}
+		b, err := cfg.Bytes()
+		if err != nil {
+			return err
+		}
+		if !showSecrets {
+			b = maskConfigSecrets(b)
+		}
+		if _, err := fmt.Fprintf(out, "# %s\n", cfg.Path); err != nil {
+			return err
+		}
+		_, err = out.Write(b)
+		return err
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>...",
+	Short: "Set a default flag value, for every command (e.g. workdir) or one command (e.g. translate.model)",
+	Long: "Set a default flag value in the configuration file, creating it if needed.\n\n" +
+		"The key is a flag name, applied to every command with that flag, or a command and a flag name\n" +
+		"separated by a dot (e.g. translate.model), applied to that command only. Several values set a list.",
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, values := args[0], args[1:]
+		if err := validateConfigValue(key, values); err != nil {
+			return err
+		}
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Set(key, values); err != nil {
+			return err
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		logging.FromContext(cmd.Context()).Info("config updated", "path", cfg.Path, "key", key)
+		return nil
+	},
+}
+
+// configPath returns the path of the configuration file: --config, then
+// SUBTITLE_TOOLS_CONFIG, then the default path.
+func configPath(cmd *cobra.Command) (string, error) {
+	if p, _ := cmd.Flags().GetString(flagConfig); p != "" {
+		return p, nil
+	}
+	if p, ok := envString(envConfig); ok {
+		return p, nil
+	}
+	return config.DefaultPath()
+}
+
+func loadConfig(cmd *cobra.Command) (*config.File, error) {
+	path, err := configPath(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return config.Load(path)
+}
+
+// applyConfig sets the flags of cmd that weren't given on the command line to
+// their value in cfg: the top-level keys, then the keys of each section in
+// order, then the keys of the selected --profile. The flags aren't marked as
+// changed, so the environment variables, resolved by each command, still take
+// precedence over the file.
+func applyConfig(cmd *cobra.Command, cfg *config.File, sections ...string) error {
+	values, err := cfg.Values(sections...)
+	if err != nil {
+		return err
+	}
+	if err := setConfigFlags(cmd, cfg, values); err != nil {
+		return err
+	}
+
+	if cmd.Flags().Lookup(flagProfile) == nil {
+		return nil
+	}
+	if err := resolveStringFlagFromEnv(cmd, flagProfile, envTranslateProfile); err != nil {
+		return err
+	}
+	name, _ := cmd.Flags().GetString(flagProfile)
+	if name == "" {
+		return nil
+	}
+	profile, err := cfg.Profile(name)
+	if err != nil {
+		return fmt.Errorf("--%s: %w", flagProfile, err)
+	}
+	for key := range profile {
+		if cmd.Flags().Lookup(key) == nil || key == flagProfile || key == flagConfig {
+			return fmt.Errorf("config %s: unknown flag %q in profile %s", cfg.Path, key, name)
+		}
+	}
+	return setConfigFlags(cmd, cfg, profile)
+}
+
+// setConfigFlags sets the flags of cmd not given on the command line to values
+// from cfg, skipping the names that aren't flags of cmd.
+func setConfigFlags(cmd *cobra.Command, cfg *config.File, values map[string][]string) error {
+	for name, v := range values {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed || name == flagConfig {
+			continue // top-level keys may be flags of other commands only
+		}
+		if err := setFlagValue(f, v); err != nil {
+			return fmt.Errorf("config %s: %s: %w", cfg.Path, name, err)
+		}
+		if f.Annotations == nil {
+			f.Annotations = make(map[string][]string)
+		}
+		f.Annotations[configAnnotation] = []string{"true"}
+		// A value in the file satisfies a required flag.
+		delete(f.Annotations, cobra.BashCompOneRequiredFlag)
+	}
+	return nil
+}
+
+// checkConfigSection checks that the keys of the section of a command are
+// flags of cmd, catching typos that would otherwise be ignored.
+func checkConfigSection(cmd *cobra.Command, cfg *config.File, section string) error {
+	values, err := cfg.Section(section)
+	if err != nil {
+		return err
+	}
+	for name := range values {
+		if cmd.Flags().Lookup(name) == nil || name == flagConfig {
+			return fmt.Errorf("config %s: unknown flag %q for %s", cfg.Path, name, section)
+		}
+	}
+	return nil
+}
+
+// setFlagValue sets f to values without marking it as changed.
+func setFlagValue(f *pflag.Flag, values []string) error {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		if len(values) == 1 && strings.HasSuffix(f.Value.Type(), "Slice") {
+			// Like on the command line, "a,b" is a list.
+			values = strings.Split(values[0], ",")
+		}
+		return sv.Replace(values)
+	}
+	if len(values) != 1 {
+		return errors.New("expected a single value")
+	}
+	return f.Value.Set(values[0])
+}
+
+// flagSet reports whether the flag was given on the command line, by an
+// environment variable or in the configuration file.
+func flagSet(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	return f != nil && (f.Changed || f.Annotations[configAnnotation] != nil)
+}
+
+// configCommand returns the name of the top-level command of cmd, whose
+// config section applies.
+func configCommand(cmd *cobra.Command) string {
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	return cmd.Name()
+}
+
+// validateConfigValue checks that key names a flag (of some command, or of the
+// command of its section) and that values parse as that flag.
+func validateConfigValue(key string, values []string) error {
+	command, name, ok := strings.Cut(key, ".")
+	if !ok {
+		command, name = "", key
+	} else if command == config.ProfilesKey {
+		// profiles.<profile>.<flag> sets a flag of the commands with --profile.
+		profile, flag, ok := strings.Cut(name, ".")
+		if !ok || profile == "" {
+			return fmt.Errorf("invalid key %q (expected %s.<profile>.<flag>)", key, config.ProfilesKey)
+		}
+		if flag == flagProfile {
+			return fmt.Errorf("invalid key %q (a profile can't select another profile)", key)
+		}
+		command, name = translateCmd.Name(), flag
+	}
+	known := command == ""
+	var flags []*pflag.Flag
+	for _, c := range rootCmd.Commands() {
+		if c == configCmd || (command != "" && c.Name() != command) {
+			continue
+		}
+		known = true
+		if f := c.Flags().Lookup(name); f != nil {
+			flags = append(flags, f)
+		} else if f := c.PersistentFlags().Lookup(name); f != nil {
+			flags = append(flags, f) // e.g. --jobs-dir of the jobs subcommands
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown command %q in key %q", command, key)
+	}
+	if f := rootCmd.PersistentFlags().Lookup(name); f != nil && name != flagConfig {
+		flags = append(flags, f)
+	}
+	if len(flags) == 0 {
+		if command != "" {
+			return fmt.Errorf("unknown flag %q for %s", name, command)
+		}
+		return fmt.Errorf("unknown flag %q", name)
+	}
+	for _, f := range flags {
+		if err := checkFlagValue(f, values); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// checkFlagValue parses values as the type of f, without setting it.
+func checkFlagValue(f *pflag.Flag, values []string) error {
+	typ := f.Value.Type()
+	if strings.HasSuffix(typ, "Slice") || strings.HasSuffix(typ, "Array") {
+		typ = strings.TrimSuffix(strings.TrimSuffix(typ, "Slice"), "Array")
+	} else if len(values) != 1 {
+		return errors.New("expected a single value")
+	}
+	fs := pflag.NewFlagSet("check", pflag.ContinueOnError)
+	switch typ {
+	case "bool":
+		_ = fs.Bool("v", false, "")
+	case "int":
+		_ = fs.Int("v", 0, "")
+	case "float64":
+		_ = fs.Float64("v", 0, "")
+	case "duration":
+		_ = fs.Duration("v", time.Duration(0), "")
+	default:
+		return nil
+	}
+	for _, v := range values {
+		if err := fs.Lookup("v").Value.Set(v); err != nil {
+			return fmt.Errorf("%q is not a valid %s", v, typ)
+		}
+	}
+	return nil
+}
+
+// maskConfigSecrets masks the values of the API key entries of a config
+// document.
+func maskConfigSecrets(b []byte) []byte {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return b
+	}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		for i := 0; i < len(n.Content); i++ {
+			if n.Kind == yaml.MappingNode && i%2 == 0 && i+1 < len(n.Content) && strings.HasSuffix(n.Content[i].Value, flagApiKey) {
+				maskNode(n.Content[i+1])
+				i++
+				continue
+			}
+			walk(n.Content[i])
+		}
+	}
+	walk(&doc)
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return b
+	}
+	return out.Bytes()
+}
+
+func maskNode(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode {
+		n.Value = run.MaskKeys(n.Value, ",")
+		return
+	}
+	for _, c := range n.Content {
+		maskNode(c)
+	}
+}
+
+func init() {
+	configViewCmd.Flags().Bool(flagShowSecrets, false, "Print the API keys instead of masking them")
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configSetCmd)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfig_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "workdir: /from-config\ndry-run: true\nmodel: top\ntranslate:\n  model: from-config\n  target-language: es\n  include: '*.srt,*.ass'\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	cmd := &cobra.Command{Use: "translate"}
+	cmd.Flags().String(flagWorkdir, "", "")
+	cmd.Flags().Bool(flagDryRun, false, "")
+	cmd.Flags().String(flagModel, "", "")
+	cmd.Flags().String(flagTargetLanguage, "", "")
+	cmd.Flags().StringSlice(flagInclude, nil, "")
+	cmd.Flags().Float64(flagTemperature, 0, "")
+	_ = cmd.MarkFlagRequired(flagTargetLanguage)
+	_ = cmd.Flags().Set(flagWorkdir, "/from-flag")
+	t.Setenv(envDryRun, "false")
+
+	if err := checkConfigSection(cmd, cfg, "translate"); err != nil {
+		t.Fatalf("checkConfigSection: %v", err)
+	}
+	if err := applyConfig(cmd, cfg, "translate"); err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+	if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+		t.Fatalf("resolveBoolFlagFromEnv: %v", err)
+	}
+
+	workdir, _ := cmd.Flags().GetString(flagWorkdir)
+	dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+	model, _ := cmd.Flags().GetString(flagModel)
+	include, _ := cmd.Flags().GetStringSlice(flagInclude)
+	if workdir != "/from-flag" || dryRun || model != "from-config" || strings.Join(include, "|") != "*.srt|*.ass" {
+		t.Fatalf("workdir=%q dry-run=%v model=%q include=%v", workdir, dryRun, model, include)
+	}
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		t.Fatalf("expected the config to satisfy --%s: %v", flagTargetLanguage, err)
+	}
+	if !flagSet(cmd, flagModel) || flagSet(cmd, flagTemperature) {
+		t.Fatalf("flagSet: model=%v temperature=%v", flagSet(cmd, flagModel), flagSet(cmd, flagTemperature))
+	}
+
+	// Section keys must be flags of the command.
+	if err := checkConfigSection(&cobra.Command{Use: "translate"}, cfg, "translate"); err == nil {
+		t.Fatalf("expected an unknown flag error")
+	}
+}
+
+func TestValidateConfigValue(t *testing.T) {
+	for key, ok := range map[string]bool{
+		"workdir":              true,
+		"verbose":              true,
+		"translate.model":      true,
+		"fix.max-cps":          true,
+		"fix.model":            false,
+		"nope.model":           false,
+		"unknown":              false,
+		"translate.max-cps=x":  false,
+		"profiles.work.rps":    true,
+		"profiles.work.delete": false,
+		"profiles.work":        false,
+		"jobs.jobs-dir":        true,
+	} {
+		err := validateConfigValue(key, []string{"1"})
+		if (err == nil) != ok {
+			t.Errorf("validateConfigValue(%q) = %v, want ok=%v", key, err, ok)
+		}
+	}
+	if err := validateConfigValue("fix.max-cps", []string{"fast"}); err == nil {
+		t.Errorf("expected an invalid float error")
+	}
+	if err := validateConfigValue("fix.include", []string{"*.srt", "*.ass"}); err != nil {
+		t.Errorf("list value: %v", err)
+	}
+	if err := validateConfigValue("translate.model", []string{"a", "b"}); err == nil {
+		t.Errorf("expected a single value error")
+	}
+}
+
+func TestApplyConfig_Profile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "translate:\n  model: from-section\n  rps: 1\n  profile: work\nprofiles:\n  work:\n    model: gpt-4o-mini\n    api-key: sk-work\n  local:\n    model: ollama:llama3.1\n    max-workers: 1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "translate"}
+		registerTranslateFlags(cmd)
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags: %v", err)
+		}
+		return cmd
+	}
+
+	// The profile selected in the file overrides the section.
+	cmd := newCmd()
+	if err := applyConfig(cmd, cfg, "translate"); err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+	model, _ := cmd.Flags().GetString(flagModel)
+	apiKey, _ := cmd.Flags().GetString(flagApiKey)
+	rps, _ := cmd.Flags().GetFloat64(flagRPS)
+	if model != "gpt-4o-mini" || apiKey != "sk-work" || rps != 1 {
+		t.Fatalf("model=%q api-key=%q rps=%v", model, apiKey, rps)
+	}
+
+	// --profile overrides the file, and other flags override the profile.
+	cmd = newCmd("--profile", "local", "--max-workers", "3")
+	if err := applyConfig(cmd, cfg, "translate"); err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+	model, _ = cmd.Flags().GetString(flagModel)
+	workers, _ := cmd.Flags().GetInt(flagMaxWorkers)
+	if model != "ollama:llama3.1" || workers != 3 {
+		t.Fatalf("model=%q max-workers=%d", model, workers)
+	}
+
+	t.Setenv(envTranslateProfile, "home")
+	if err := applyConfig(newCmd(), cfg, "translate"); err == nil || !strings.Contains(err.Error(), "work, local") {
+		t.Fatalf("expected an unknown profile error, got %v", err)
+	}
+}
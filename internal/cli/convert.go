@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/spf13/cobra"
+)
+
+// formatExtensions are the file extensions of the formats convert writes,
+// which also pick the format of --output.
+var formatExtensions = map[string]string{
+	srt.FormatSRT:      ".srt",
+	srt.FormatMicroDVD: ".sub",
+	srt.FormatTTML:     ".ttml",
+}
+
+// otherFormatExtensions are the other extensions of the formats, which also
+// pick the format of --output.
+var otherFormatExtensions = map[string]string{
+	".dfxp": srt.FormatTTML,
+	".xml":  srt.FormatTTML,
+}
+
+// inPlaceOutputPath returns the output of a command that overwrites its input
+// file by default: the input, or the input with the .srt extension for the
+// formats only read (SAMI, SubViewer), which are written as SRT.
+func inPlaceOutputPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".smi", ".sami", ".sbv":
+		return strings.TrimSuffix(path, filepath.Ext(path)) + formatExtensions[srt.FormatSRT]
+	}
+	return path
+}
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [flags] <input-file>...",
+	Short: "Convert subtitle files between SRT, MicroDVD (.sub) and TTML/IMSC 1.1, converting frame numbers to times with the frame rate",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logging.FromContext(cmd.Context())
+
+		to, _ := cmd.Flags().GetString(flagTo)
+		fps, _ := cmd.Flags().GetFloat64(flagFPS)
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		wordTiming, _ := cmd.Flags().GetString(flagWordTiming)
+
+		to = strings.ToLower(strings.TrimSpace(to))
+		if to == "" && outputPath != "" {
+			ext := strings.ToLower(filepath.Ext(outputPath))
+			for format, formatExt := range formatExtensions {
+				if ext == formatExt {
+					to = format
+				}
+			}
+			if format, ok := otherFormatExtensions[ext]; ok {
+				to = format
+			}
+		}
+		if to == "" {
+			to = srt.FormatSRT
+		}
+		if _, ok := formatExtensions[to]; !ok {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s, %s)", flagTo, to, srt.FormatSRT, srt.FormatMicroDVD, srt.FormatTTML)
+		}
+		if fps < 0 {
+			return fmt.Errorf("invalid --%s %g (must be positive)", flagFPS, fps)
+		}
+		wordTiming = strings.ToLower(strings.TrimSpace(wordTiming))
+		if wordTiming != "" && !srt.IsValidTimingUnit(wordTiming) {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagWordTiming, wordTiming, srt.TimingWord, srt.TimingPhrase)
+		}
+		if len(args) > 1 {
+			if err := requireNamePlaceholder(flagOutput, outputPath); err != nil {
+				return err
+			}
+		}
+
+		for _, arg := range args {
+			inputPath, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			output := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + formatExtensions[to]
+			if outputPath != "" {
+				if output, err = fs.ResolveAbsPath(expandInputPlaceholders(outputPath, inputPath)); err != nil {
+					return err
+				}
+			}
+			if fs.SameFilePath(output, inputPath) {
+				return fmt.Errorf("%s: the output is the input file (set --%s or --%s)", arg, flagOutput, flagTo)
+			}
+
+			doc, err := readConvertInput(inputPath, fps)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", arg, err)
+			}
+			for _, w := range doc.Warnings {
+				log.Warn("input file: "+w, "path", arg)
+			}
+			from := doc.Format
+			cues := doc.Cues
+			if wordTiming != "" {
+				cues = srt.SplitTiming(cues, wordTiming)
+			}
+			doc = doc.WithCues(cues)
+			doc.Format = to
+			if fps > 0 {
+				doc.FrameRate = fps
+			}
+			if doc.Language == "" {
+				_, name := naming.Parse(inputPath)
+				doc.Language = name.Language
+			}
+			if to == srt.FormatMicroDVD && doc.FrameRate <= 0 {
+				return fmt.Errorf("--%s is required to write MicroDVD (the frame rate of the video, e.g. 23.976 or 25)", flagFPS)
+			}
+
+			var buf bytes.Buffer
+			if err := srt.Write(&buf, doc, false); err != nil {
+				return err
+			}
+			if err := fs.WriteFile(&buf, output); err != nil {
+				return err
+			}
+			recordFile(convertFileResult{Command: "convert", Input: inputPath, Output: output, From: from, To: to, Cues: len(doc.Cues)})
+			log.Info("subtitle converted", "path", output, "from", from, "to", to, "cues", len(doc.Cues))
+		}
+		return nil
+	},
+}
+
+// readConvertInput reads a subtitle file in any format convert reads: a
+// MicroDVD file (whose frames are converted at fps, or at its own frame
+// rate), TTML, or any format srt.Read detects (SRT, SAMI, SubViewer).
+func readConvertInput(path string, fps float64) (*srt.Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if srt.IsMicroDVD(b) {
+		doc, err := srt.ReadMicroDVD(bytes.NewReader(b), fps)
+		if errors.Is(err, srt.ErrNoFrameRate) {
+			return nil, fmt.Errorf("%w (set --%s to the frame rate of the video, e.g. 23.976 or 25)", err, flagFPS)
+		}
+		return doc, err
+	}
+	if srt.IsTTML(b) {
+		return srt.ReadTTML(bytes.NewReader(b))
+	}
+	doc, err := srt.Read(bytes.NewReader(b))
+	if err != nil && strings.EqualFold(filepath.Ext(path), formatExtensions[srt.FormatMicroDVD]) {
+		return nil, errors.New("not a MicroDVD file (VobSub .sub files hold images and can't be converted)")
+	}
+	return doc, err
+}
+
+// convertFileResult is the --json entry of a converted file.
+type convertFileResult struct {
+	Command string `json:"command"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Cues    int    `json:"cues"`
+}
+
+func init() {
+	convertCmd.Flags().String(flagTo, "", "Output format: srt, microdvd or ttml (defaults to the extension of --output, or srt)")
+	convertCmd.Flags().Float64(flagFPS, 0, "Frame rate of the video (e.g. 23.976, 25) to convert MicroDVD frame numbers; overrides the frame rate of the file")
+	convertCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path; {dir} and {name} are those of the input (optional; defaults to the input with the extension of the format)")
+	convertCmd.Flags().String(flagWordTiming, "", "Split every cue into sequential cues of a word or a phrase each, with interpolated times: word or phrase (e.g. for karaoke or lip-sync tools)")
+}
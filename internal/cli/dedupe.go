@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/dedupe"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe [flags] <directory>",
+	Short: "Find subtitle files with the same or nearly the same text, optionally deleting the redundant copies",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logging.FromContext(cmd.Context())
+
+		format, _ := cmd.Flags().GetString(flagFormat)
+		threshold, _ := cmd.Flags().GetFloat64(flagThreshold)
+		recursive, _ := cmd.Flags().GetBool(flagRecursive)
+		del, _ := cmd.Flags().GetBool(flagDelete)
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("invalid --%s %g (must be > 0 and <= 1)", flagThreshold, threshold)
+		}
+
+		dir, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		groups, err := dedupe.Scan(dir, dedupe.Options{Recursive: recursive, Threshold: threshold})
+		if err != nil {
+			return err
+		}
+		if groups == nil {
+			groups = []dedupe.Group{}
+		}
+
+		if len(groups) == 0 {
+			log.Info("no duplicate subtitle files found", "dir", dir)
+		} else if del {
+			removed, err := dedupe.Remove(groups)
+			if err != nil {
+				return err
+			}
+			log.Info("duplicate subtitle files removed", "count", len(removed))
+		}
+
+		out := cmd.OutOrStdout()
+		if format == formatJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(groups)
+		}
+		return writeDedupe(out, groups, del)
+	},
+}
+
+// writeDedupe writes the file kept of each group followed by its duplicates.
+func writeDedupe(w io.Writer, groups []dedupe.Group, del bool) error {
+	label := "duplicate:"
+	if del {
+		label = "removed:"
+	}
+	var b strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		_, _ = fmt.Fprintf(&b, "%-10s %s (%d cues)\n", "keep:", g.Keep.Path, g.Keep.Cues)
+		for _, d := range g.Duplicates {
+			detail := fmt.Sprintf("similarity %.3f", d.Similarity)
+			if d.Identical {
+				detail = "identical"
+			}
+			_, _ = fmt.Fprintf(&b, "%-10s %s (%d cues, %s)\n", label, d.Path, d.Cues, detail)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func init() {
+	dedupeCmd.Flags().String(flagFormat, formatText, "Output format: text or json")
+	dedupeCmd.Flags().Float64(flagThreshold, dedupe.DefaultThreshold, "Minimum text similarity (0-1) of two files to be reported as duplicates")
+	dedupeCmd.Flags().Bool(flagRecursive, false, "Also scan subdirectories")
+	dedupeCmd.Flags().Bool(flagDelete, false, "Delete the duplicates, keeping one file of each group")
+}
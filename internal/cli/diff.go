@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/subdiff"
+	"github.com/spf13/cobra"
+)
+
+var errFilesDiffer = errors.New("files differ")
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [flags] <file-a> <file-b>",
+	Short: "Compare two subtitle files, pairing cues by time overlap",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString(flagFormat)
+		tolerance, _ := cmd.Flags().GetDuration(flagTolerance)
+		exitCode, _ := cmd.Flags().GetBool(flagExitCode)
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+		if tolerance < 0 {
+			return fmt.Errorf("invalid --%s %s (must be >= 0)", flagTolerance, tolerance)
+		}
+
+		pathA, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		pathB, err := fs.ResolveAbsPath(args[1])
+		if err != nil {
+			return err
+		}
+
+		res, err := subdiff.Files(pathA, pathB, subdiff.Options{Tolerance: tolerance})
+		if err != nil {
+			return err
+		}
+		res.A, res.B = args[0], args[1]
+
+		out := cmd.OutOrStdout()
+		if format == formatJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(res)
+		} else {
+			err = writeDiff(out, res)
+		}
+		if err != nil {
+			return err
+		}
+		if exitCode && !res.Equal() {
+			return errFilesDiffer
+		}
+		return nil
+	},
+}
+
+// writeDiff writes a unified-diff-like report: "~" for paired cues that
+// changed, "-" for cues only in the first file and "+" for cues only in the
+// second one, each followed by the text lines that changed.
+func writeDiff(w io.Writer, res subdiff.Result) error {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "--- %s\n+++ %s\n", res.A, res.B)
+	for _, c := range res.Changes {
+		switch c.Kind {
+		case subdiff.KindChanged:
+			_, _ = fmt.Fprintf(&b, "~ #%d -> #%d  %s --> %s", c.A.Idx, c.B.Idx, c.A.Start, c.A.End)
+			if c.TimingChanged {
+				_, _ = fmt.Fprintf(&b, "  =>  %s --> %s (start %s, end %s)", c.B.Start, c.B.End, formatDelta(c.StartDeltaMS), formatDelta(c.EndDeltaMS))
+			}
+			b.WriteString("\n")
+			if c.TextChanged {
+				writeTextLines(&b, "-", c.A.Text)
+				writeTextLines(&b, "+", c.B.Text)
+			}
+		case subdiff.KindRemoved:
+			_, _ = fmt.Fprintf(&b, "- #%d  %s --> %s\n", c.A.Idx, c.A.Start, c.A.End)
+			writeTextLines(&b, "-", c.A.Text)
+		case subdiff.KindAdded:
+			_, _ = fmt.Fprintf(&b, "+ #%d  %s --> %s\n", c.B.Idx, c.B.Start, c.B.End)
+			writeTextLines(&b, "+", c.B.Text)
+		}
+	}
+	s := res.Summary
+	_, _ = fmt.Fprintf(&b, "%d unchanged, %d retimed, %d edited, %d removed, %d added\n", s.Unchanged, s.Retimed, s.Edited, s.Removed, s.Added)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTextLines(b *strings.Builder, prefix, text string) {
+	for line := range strings.SplitSeq(text, "\n") {
+		b.WriteString("    " + prefix + " " + line + "\n")
+	}
+}
+
+// formatDelta formats a millisecond offset with its sign (e.g. "+500ms").
+func formatDelta(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+func init() {
+	diffCmd.Flags().String(flagFormat, formatText, "Output format: text or json")
+	diffCmd.Flags().Duration(flagTolerance, 0, "Largest start/end difference not reported as a timing change (e.g. 20ms)")
+	diffCmd.Flags().Bool(flagExitCode, false, "Exit with status 1 when the files differ")
+}
@@ -11,49 +11,271 @@ import (
 )
 
 const (
-	envVerbose = "SUBTITLE_TOOLS_VERBOSE"
-	envDryRun  = "SUBTITLE_TOOLS_DRY_RUN"
-	envWorkdir = "SUBTITLE_TOOLS_WORKDIR"
+	envVerbose      = "SUBTITLE_TOOLS_VERBOSE"
+	envJSON         = "SUBTITLE_TOOLS_JSON"
+	envDryRun       = "SUBTITLE_TOOLS_DRY_RUN"
+	envWorkdir      = "SUBTITLE_TOOLS_WORKDIR"
+	envProgress     = "SUBTITLE_TOOLS_PROGRESS"
+	envJobsDir      = "SUBTITLE_TOOLS_JOBS_DIR"
+	envUpdateNotify = "SUBTITLE_TOOLS_UPDATE_NOTIFY"
+	// Network flags (translate, ocr and update).
+	envProxy              = "SUBTITLE_TOOLS_PROXY"
+	envCACert             = "SUBTITLE_TOOLS_CA_CERT"
+	envInsecureSkipVerify = "SUBTITLE_TOOLS_INSECURE_SKIP_VERIFY"
+	// Extract flags.
+	envExtractTool = "SUBTITLE_TOOLS_EXTRACT_TOOL"
+	// OCR flags.
+	envOCREngine         = "SUBTITLE_TOOLS_OCR_ENGINE"
+	envOCRAPIKey         = "SUBTITLE_TOOLS_OCR_API_KEY"
+	envOCRModel          = "SUBTITLE_TOOLS_OCR_MODEL"
+	envOCRBaseURL        = "SUBTITLE_TOOLS_OCR_URL"
+	envOCRBatchSize      = "SUBTITLE_TOOLS_OCR_BATCH_SIZE"
+	envOCRRequestTimeout = "SUBTITLE_TOOLS_OCR_REQUEST_TIMEOUT"
+	envOCRCacheDir       = "SUBTITLE_TOOLS_OCR_CACHE_DIR"
+	envOCRNoCache        = "SUBTITLE_TOOLS_OCR_NO_CACHE"
+	envOCRTokenPrice     = "SUBTITLE_TOOLS_OCR_TOKEN_PRICE"
 	// Update flags.
-	envGithubAPIKey = "SUBTITLE_TOOLS_GITHUB_API_KEY"
+	envGithubAPIKey    = "SUBTITLE_TOOLS_GITHUB_API_KEY"
+	envUpdateChannel   = "SUBTITLE_TOOLS_UPDATE_CHANNEL"
+	envUpdatePublicKey = "SUBTITLE_TOOLS_UPDATE_PUBLIC_KEY"
+	envUpdateAPIURL    = "SUBTITLE_TOOLS_UPDATE_API_URL"
+	envUpdateRepo      = "SUBTITLE_TOOLS_UPDATE_REPO"
+	envUpdateAssetURL  = "SUBTITLE_TOOLS_UPDATE_ASSET_URL"
 	// Translate tuning flags.
-	envTranslateAPIKey         = "SUBTITLE_TOOLS_TRANSLATE_API_KEY"
-	envTranslateModel          = "SUBTITLE_TOOLS_TRANSLATE_MODEL"
-	envTranslateBaseURL        = "SUBTITLE_TOOLS_TRANSLATE_URL"
-	envTranslateMaxBatchChars  = "SUBTITLE_TOOLS_TRANSLATE_MAX_BATCH_CHARS"
-	envTranslateMaxWorkers     = "SUBTITLE_TOOLS_TRANSLATE_MAX_WORKERS"
-	envTranslateRPS            = "SUBTITLE_TOOLS_TRANSLATE_RPS"
-	envTranslateRetryMax       = "SUBTITLE_TOOLS_TRANSLATE_RETRY_MAX_ATTEMPTS"
-	envTranslateRetryParseMax  = "SUBTITLE_TOOLS_TRANSLATE_RETRY_PARSE_MAX_ATTEMPTS"
-	envTranslateRequestTimeout = "SUBTITLE_TOOLS_TRANSLATE_REQUEST_TIMEOUT"
+	envTranslateAPIKey          = "SUBTITLE_TOOLS_TRANSLATE_API_KEY"
+	envTranslateModel           = "SUBTITLE_TOOLS_TRANSLATE_MODEL"
+	envTranslateBaseURL         = "SUBTITLE_TOOLS_TRANSLATE_URL"
+	envTranslateMaxBatchChars   = "SUBTITLE_TOOLS_TRANSLATE_MAX_BATCH_CHARS"
+	envTranslateMaxWorkers      = "SUBTITLE_TOOLS_TRANSLATE_MAX_WORKERS"
+	envTranslateAdaptive        = "SUBTITLE_TOOLS_TRANSLATE_ADAPTIVE_WORKERS"
+	envTranslateBreaker         = "SUBTITLE_TOOLS_TRANSLATE_CIRCUIT_BREAKER_THRESHOLD"
+	envTranslateBreakerCooldown = "SUBTITLE_TOOLS_TRANSLATE_CIRCUIT_BREAKER_COOLDOWN"
+	envTranslateRPS             = "SUBTITLE_TOOLS_TRANSLATE_RPS"
+	envTranslateRPSPerKey       = "SUBTITLE_TOOLS_TRANSLATE_RPS_PER_KEY"
+	envTranslateRetryMax        = "SUBTITLE_TOOLS_TRANSLATE_RETRY_MAX_ATTEMPTS"
+	envTranslateRetryParseMax   = "SUBTITLE_TOOLS_TRANSLATE_RETRY_PARSE_MAX_ATTEMPTS"
+	envTranslateRequestTimeout  = "SUBTITLE_TOOLS_TRANSLATE_REQUEST_TIMEOUT"
+	envTranslateResponseMode    = "SUBTITLE_TOOLS_TRANSLATE_RESPONSE_MODE"
+	envTranslateParseMode       = "SUBTITLE_TOOLS_TRANSLATE_PARSE_MODE"
+	envTranslateProvider        = "SUBTITLE_TOOLS_TRANSLATE_PROVIDER"
+	envTranslateProfile         = "SUBTITLE_TOOLS_TRANSLATE_PROFILE"
+	envTranslateFormality       = "SUBTITLE_TOOLS_TRANSLATE_FORMALITY"
+	envTranslateSafety          = "SUBTITLE_TOOLS_TRANSLATE_SAFETY_THRESHOLD"
+	envTranslateCheckModel      = "SUBTITLE_TOOLS_TRANSLATE_CHECK_MODEL"
+	envTranslateFallbackModel   = "SUBTITLE_TOOLS_TRANSLATE_FALLBACK_MODEL"
+	envTranslateCacheDir        = "SUBTITLE_TOOLS_TRANSLATE_CACHE_DIR"
+	envTranslateNoCache         = "SUBTITLE_TOOLS_TRANSLATE_NO_CACHE"
+	envTranslatePromptFile      = "SUBTITLE_TOOLS_TRANSLATE_PROMPT_FILE"
+	envTranslateGlossaryFile    = "SUBTITLE_TOOLS_TRANSLATE_GLOSSARY_FILE"
+	envTranslateStyle           = "SUBTITLE_TOOLS_TRANSLATE_STYLE"
+	envTranslateAudience        = "SUBTITLE_TOOLS_TRANSLATE_AUDIENCE"
+	envTranslateNotes           = "SUBTITLE_TOOLS_TRANSLATE_NOTES"
+	envTranslatePreserveIndex   = "SUBTITLE_TOOLS_TRANSLATE_PRESERVE_INDEX"
+	envTranslateOnlyForced      = "SUBTITLE_TOOLS_TRANSLATE_ONLY_FORCED"
+	envTranslateSkipSDH         = "SUBTITLE_TOOLS_TRANSLATE_SKIP_SDH"
+	envTranslateSDH             = "SUBTITLE_TOOLS_TRANSLATE_SDH"
+	envTranslateSkipTagProtect  = "SUBTITLE_TOOLS_TRANSLATE_SKIP_TAG_PROTECTION"
+	envTranslateSkipPromptCache = "SUBTITLE_TOOLS_TRANSLATE_SKIP_PROMPT_CACHE"
+	envTranslateCensorList      = "SUBTITLE_TOOLS_TRANSLATE_CENSOR_LIST"
+	envTranslateCensorStyle     = "SUBTITLE_TOOLS_TRANSLATE_CENSOR_STYLE"
+	envTranslateRetryTags       = "SUBTITLE_TOOLS_TRANSLATE_RETRY_TAG_MISMATCH"
+	envTranslateReview          = "SUBTITLE_TOOLS_TRANSLATE_REVIEW"
+	envTranslateMaxCPS          = "SUBTITLE_TOOLS_TRANSLATE_MAX_CPS"
+	envTranslateMaxLineLen      = "SUBTITLE_TOOLS_TRANSLATE_MAX_LINE_LEN"
+	envTranslateMaxLines        = "SUBTITLE_TOOLS_TRANSLATE_MAX_LINES"
+	envTranslateLengthHints     = "SUBTITLE_TOOLS_TRANSLATE_LENGTH_HINTS"
+	envTranslateLineBreaks      = "SUBTITLE_TOOLS_TRANSLATE_LINEBREAKS"
+	envTranslateLocalize        = "SUBTITLE_TOOLS_TRANSLATE_LOCALIZE_NUMBERS"
+	envTranslateConvertUnits    = "SUBTITLE_TOOLS_TRANSLATE_CONVERT_UNITS"
+	envTranslateSeriesContext   = "SUBTITLE_TOOLS_TRANSLATE_SERIES_CONTEXT"
+	envTranslateRTLMarks        = "SUBTITLE_TOOLS_TRANSLATE_RTL_MARKS"
+	envTranslateLengthPolicy    = "SUBTITLE_TOOLS_TRANSLATE_LENGTH_POLICY"
+	envTranslateSideBySide      = "SUBTITLE_TOOLS_TRANSLATE_SIDE_BY_SIDE"
+	envTranslateForce           = "SUBTITLE_TOOLS_TRANSLATE_FORCE"
+	envTranslateStream          = "SUBTITLE_TOOLS_TRANSLATE_STREAM"
+	envTranslateBatchAPI        = "SUBTITLE_TOOLS_TRANSLATE_BATCH_API"
+	envTranslateTemperature     = "SUBTITLE_TOOLS_TRANSLATE_TEMPERATURE"
+	envTranslateTopP            = "SUBTITLE_TOOLS_TRANSLATE_TOP_P"
+	envTranslateMaxOutTokens    = "SUBTITLE_TOOLS_TRANSLATE_MAX_OUTPUT_TOKENS"
+	envTranslateTokenPrice      = "SUBTITLE_TOOLS_TRANSLATE_TOKEN_PRICE"
+	envTranslateReasoning       = "SUBTITLE_TOOLS_TRANSLATE_REASONING_EFFORT"
+	envTranslateTranscriptDir   = "SUBTITLE_TOOLS_TRANSLATE_TRANSCRIPT_DIR"
 )
 
 const (
-	flagApiKey           = "api-key"
-	flagDryRun           = "dry-run"
-	flagMaxBatchChars    = "max-batch-chars"
-	flagMaxLineLen       = "max-line-len"
-	flagMaxWorkers       = "max-workers"
-	flagMinWordsMerge    = "min-words-merge"
-	flagModel            = "model"
-	flagOutputShorthand  = "o"
-	flagOutput           = "output"
-	flagRPS              = "rps"
-	flagRequestTimeout   = "request-timeout"
-	flagRetryMax         = "retry-max-attempts"
-	flagRetryParseMax    = "retry-parse-max-attempts"
-	flagShiftTime        = "shift-time"
-	flagSkipBackup       = "skip-backup"
-	flagStripHI          = "strip-hi"
-	flagStripHIMode      = "strip-hi-mode"
-	flagSourceLanguage   = "source-language"
-	flagStripStyle       = "strip-style"
-	flagTargetLanguage   = "target-language"
-	flagURL              = "url"
-	flagVerboseShorthand = "v"
-	flagVerbose          = "verbose"
-	flagWorkdirShorthand = "w"
-	flagWorkdir          = "workdir"
+	flagAdaptiveWorkers    = "adaptive-workers"
+	flagAllowDowngrade     = "allow-downgrade"
+	flagApiKey             = "api-key"
+	flagAPIURL             = "api-url"
+	flagAssetURL           = "asset-url"
+	flagAt                 = "at"
+	flagAudience           = "audience"
+	flagBatchAPI           = "batch-api"
+	flagBatchSize          = "batch-size"
+	flagBalanceLines       = "balance-lines"
+	flagCACert             = "ca-cert"
+	flagCacheDir           = "cache-dir"
+	flagChannel            = "channel"
+	flagCheck              = "check"
+	flagCensorList         = "censor-list"
+	flagCensorStyle        = "censor-style"
+	flagCheckModel         = "check-model"
+	flagBreaker            = "circuit-breaker-threshold"
+	flagBreakerCooldown    = "circuit-breaker-cooldown"
+	flagConfig             = "config"
+	flagConvertUnits       = "convert-units"
+	flagCreditsBlocklist   = "credits-blocklist"
+	flagCues               = "cues"
+	flagDashStyle          = "dash-style"
+	flagDefault            = "default"
+	flagDelete             = "delete"
+	flagDialogueDashes     = "dialogue-dashes"
+	flagDictionary         = "dictionary"
+	flagDiff               = "diff"
+	flagDisable            = "disable"
+	flagDryRun             = "dry-run"
+	flagEllipsis           = "ellipsis"
+	flagEngine             = "engine"
+	flagEvery              = "every"
+	flagExitCode           = "exit-code"
+	flagFallbackAPIKey     = "fallback-api-key"
+	flagFallbackModel      = "fallback-model"
+	flagFallbackURL        = "fallback-url"
+	flagFix                = "fix"
+	flagFixOCR             = "fix-ocr"
+	flagFixSpacing         = "fix-spacing"
+	flagFlags              = "flags"
+	flagForce              = "force"
+	flagForced             = "forced"
+	flagFormat             = "format"
+	flagFPS                = "fps"
+	flagFormality          = "formality"
+	flagGlossaryFile       = "glossary-file"
+	flagHuman              = "human"
+	flagInclude            = "include"
+	flagInsecureSkipVerify = "insecure-skip-verify"
+	flagInvertedMarks      = "inverted-marks"
+	flagJellyfinNaming     = "jellyfin-naming"
+	flagJobs               = "jobs"
+	flagJobsDir            = "jobs-dir"
+	flagJSON               = "json"
+	flagKeepCredits        = "keep-credits"
+	flagKeepTags           = "keep-tags"
+	flagKeepExisting       = "keep-existing"
+	flagLanguage           = "language"
+	flagLengthHints        = "length-hints"
+	flagLengthPolicy       = "length-policy"
+	flagLengthReport       = "length-report"
+	flagLineBreaks         = "linebreaks"
+	flagList               = "list"
+	flagLocalizeNumbers    = "localize-numbers"
+	flagMaxBatchChars      = "max-batch-chars"
+	flagMaxCPS             = "max-cps"
+	flagMaxLines           = "max-lines"
+	flagMaxLineLen         = "max-line-len"
+	flagMaxOffset          = "max-offset"
+	flagMaxOutputTokens    = "max-output-tokens"
+	flagMaxTerms           = "max-terms"
+	flagMaxWorkers         = "max-workers"
+	flagMedia              = "media"
+	flagMediaDuration      = "media-duration"
+	flagMinCount           = "min-count"
+	flagMinDuration        = "min-duration"
+	flagMinGap             = "min-gap"
+	flagMinWordsMerge      = "min-words-merge"
+	flagModel              = "model"
+	flagNoCache            = "no-cache"
+	flagNormalizeUnicode   = "normalize-unicode"
+	flagNotes              = "notes"
+	flagOffset             = "offset" // join: --offset2, --offset3, ...
+	flagOnFailure          = "on-failure"
+	flagOnSuccess          = "on-success"
+	flagOutputShorthand    = "o"
+	flagOnlyForced         = "only-forced"
+	flagOutput             = "output"
+	flagOCRReplacements    = "ocr-replacements"
+	flagOverlapPolicy      = "overlap-policy"
+	flagParseMode          = "parse-mode"
+	flagParts              = "parts"
+	flagPlexNaming         = "plex-naming"
+	flagProgress           = "progress"
+	flagPreserveIndex      = "preserve-index"
+	flagProfile            = "profile"
+	flagPromptFile         = "prompt-file"
+	flagProvider           = "provider"
+	flagProxy              = "proxy"
+	flagPublicKey          = "public-key"
+	flagQuotes             = "quotes"
+	flagRange              = "range"
+	flagReasoningEffort    = "reasoning-effort"
+	flagRecursive          = "recursive"
+	flagRollback           = "rollback"
+	flagRPS                = "rps"
+	flagRPSPerKey          = "rps-per-key"
+	flagRemoveSDH          = "remove-sdh"
+	flagRename             = "rename"
+	flagReport             = "report"
+	flagRepo               = "repo"
+	flagRequestTimeout     = "request-timeout"
+	flagResponseMode       = "response-mode"
+	flagRetryMax           = "retry-max-attempts"
+	flagRetryParseMax      = "retry-parse-max-attempts"
+	flagRetryTagMismatch   = "retry-tag-mismatch"
+	flagReview             = "review"
+	flagReviewReport       = "review-report"
+	flagRTLMarks           = "rtl-marks"
+	flagRules              = "rules"
+	flagSafetyThreshold    = "safety-threshold"
+	flagSceneScore         = "scene-score"
+	flagSDH                = "sdh"
+	flagSeriesContext      = "series-context"
+	flagShiftTime          = "shift-time"
+	flagShowSecrets        = "show-secrets"
+	flagSideBySide         = "side-by-side"
+	flagSkipBackup         = "skip-backup"
+	flagSkipChecksum       = "skip-checksum"
+	flagSkipFramerate      = "skip-framerate"
+	flagSkipSDH            = "skip-sdh"
+	flagSkipTagProtect     = "skip-tag-protection"
+	flagSkipPromptCache    = "skip-prompt-cache"
+	flagSnapScenes         = "snap-scenes"
+	flagSnapThreshold      = "snap-threshold"
+	flagSource             = "source"
+	flagSpellcheck         = "spellcheck"
+	flagSteps              = "steps"
+	flagStream             = "stream"
+	flagStripASSTags       = "strip-ass-tags"
+	flagStripHI            = "strip-hi"
+	flagStripHIMode        = "strip-hi-mode"
+	flagSourceLanguage     = "source-language"
+	flagStripTags          = "strip-tags"
+	flagStripStyle         = "strip-style"
+	flagStyle              = "style"
+	flagTargetLanguage     = "target-language"
+	flagTemperature        = "temperature"
+	flagThreshold          = "threshold"
+	flagTitle              = "title"
+	flagTMXExport          = "tmx-export"
+	flagTMXImport          = "tmx-import"
+	flagTo                 = "to"
+	flagTolerance          = "tolerance"
+	flagTool               = "tool"
+	flagTokenPrice         = "token-price"
+	flagTopGaps            = "top-gaps"
+	flagTopP               = "top-p"
+	flagTrack              = "track"
+	flagTranscriptDir      = "transcript-dir"
+	flagUpdateNotify       = "update-notify"
+	flagURL                = "url"
+	flagVerboseShorthand   = "v"
+	flagVerbose            = "verbose"
+	flagVersion            = "version"
+	flagVideo              = "video"
+	flagWait               = "wait"
+	flagWords              = "words"
+	flagWordTiming         = "word-timing"
+	flagWorkdirShorthand   = "w"
+	flagWorkdir            = "workdir"
 )
 
 func parseEnvBool(key string) (bool, bool, error) {
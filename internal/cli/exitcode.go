@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+)
+
+// Exit codes, so wrapper scripts can branch on the failure class. 1 is any
+// other error (including validation failures and diff --exit-code); 2 is not
+// used, as shells and other tools use it for usage errors.
+const (
+	exitOK         = 0
+	exitError      = 1
+	exitInputParse = 3
+	exitAuth       = 4
+	exitRateLimit  = 5
+	exitParseRetry = 6
+	exitPartial    = 7
+	exitFilesystem = 8
+)
+
+// Exit reasons of the --json result, one per exit code.
+const (
+	exitReasonSuccess    = "success"
+	exitReasonError      = "error"
+	exitReasonInputParse = "input-parse-error"
+	exitReasonAuth       = "auth-failure"
+	exitReasonRateLimit  = "rate-limit-exhausted"
+	exitReasonParseRetry = "parse-retry-exhausted"
+	exitReasonPartial    = "partial-success"
+	exitReasonFilesystem = "filesystem-error"
+)
+
+// batchError is returned by runBatch when files failed.
+type batchError struct {
+	errs  []error // of the failed files
+	total int
+	msg   string
+}
+
+func (e *batchError) Error() string   { return e.msg }
+func (e *batchError) Unwrap() []error { return e.errs }
+
+// exitStatus returns the exit code and reason of the error of a command. A
+// batch where some files succeeded is a partial success; otherwise the class
+// of the first failure applies.
+func exitStatus(err error) (int, string) {
+	if err == nil {
+		return exitOK, exitReasonSuccess
+	}
+	var bErr *batchError
+	if errors.As(err, &bErr) {
+		if len(bErr.errs) < bErr.total {
+			return exitPartial, exitReasonPartial
+		}
+		if len(bErr.errs) > 0 {
+			err = bErr.errs[0]
+		}
+	}
+
+	var parseErr *srt.ParseError
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	switch {
+	case translate.IsAuthError(err):
+		return exitAuth, exitReasonAuth
+	case translate.IsRateLimitError(err):
+		return exitRateLimit, exitReasonRateLimit
+	case translate.IsParseRetryError(err):
+		return exitParseRetry, exitReasonParseRetry
+	case errors.As(err, &parseErr):
+		return exitInputParse, exitReasonInputParse
+	case errors.As(err, &pathErr), errors.As(err, &linkErr):
+		return exitFilesystem, exitReasonFilesystem
+	default:
+		return exitError, exitReasonError
+	}
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestExitStatus(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/input.srt")
+	parseErr := fmt.Errorf("read input: %w", &srt.ParseError{Msg: "invalid subtitle timing"})
+	cases := []struct {
+		name   string
+		err    error
+		code   int
+		reason string
+	}{
+		{"success", nil, exitOK, exitReasonSuccess},
+		{"other", errors.New("boom"), exitError, exitReasonError},
+		{"parse", parseErr, exitInputParse, exitReasonInputParse},
+		{"filesystem", statErr, exitFilesystem, exitReasonFilesystem},
+		{"partial", &batchError{errs: []error{parseErr}, total: 2, msg: "1 of 2 files failed"}, exitPartial, exitReasonPartial},
+		{"all failed", &batchError{errs: []error{statErr, parseErr}, total: 2, msg: "2 of 2 files failed"}, exitFilesystem, exitReasonFilesystem},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, reason := exitStatus(tc.err)
+			if code != tc.code || reason != tc.reason {
+				t.Fatalf("exitStatus = %d %q, want %d %q", code, reason, tc.code, tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adrianmusante/subtitle-tools/internal/extract"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+)
+
+var extractCmd = &cobra.Command{
+	Use:   "extract [flags] <video-file>",
+	Short: "Extract a subtitle track from a video container (mkv, mp4...) as SRT",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagTool, envExtractTool); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		track, _ := cmd.Flags().GetInt(flagTrack)
+		list, _ := cmd.Flags().GetBool(flagList)
+		tool, _ := cmd.Flags().GetString(flagTool)
+
+		namingScheme, err := namingSchemeFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if namingScheme != "" && outputPath != "" {
+			return fmt.Errorf("--output can't be combined with --%s or --%s", flagPlexNaming, flagJellyfinNaming)
+		}
+
+		tool = extract.NormalizeTool(tool)
+		if !extract.IsValidTool(tool) {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s, %s)", flagTool, tool, extract.ToolAuto, extract.ToolFFmpeg, extract.ToolMKVExtract)
+		}
+		if track < extract.NoTrack {
+			return fmt.Errorf("invalid --%s %d (must be a stream index >= 0)", flagTrack, track)
+		}
+
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a video file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+
+		if list {
+			tracks, err := extract.ListTracks(ctx, inputPath, tool)
+			if err != nil {
+				return err
+			}
+			return printTracks(cmd, tracks)
+		}
+
+		if outputPath != "" {
+			absOut, err := fs.ResolveAbsPath(outputPath)
+			if err != nil {
+				return err
+			}
+			outputPath = absOut
+		}
+
+		if workdir != "" {
+			absWorkdir, err := fs.ResolveAbsPath(workdir)
+			if err != nil {
+				return err
+			}
+			workdir = absWorkdir
+		}
+
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "extract")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		opts := extract.Options{
+			InputPath:  inputPath,
+			OutputPath: outputPath,
+			DryRun:     dryRun,
+			WorkDir:    runWorkdir,
+			Track:      track,
+			Tool:       tool,
+			Naming:     namingScheme,
+		}
+
+		log.Debug("running extract", "opts", opts)
+
+		result, err := extract.Run(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		recordFile(extractFileResult{
+			Command:  "extract",
+			Input:    inputPath,
+			Output:   result.WrittenPath,
+			Track:    result.Track.ID,
+			Language: result.Track.Language,
+			Cues:     result.Cues,
+		})
+		log.Info("subtitle track extracted", "path", result.WrittenPath, "track", result.Track.ID, "language", result.Track.Language, "cues", result.Cues)
+
+		return nil
+	},
+}
+
+// extractFileResult is the --json entry of an extracted track.
+type extractFileResult struct {
+	Command  string `json:"command"`
+	Input    string `json:"input"`
+	Output   string `json:"output"`
+	Track    int    `json:"track"`
+	Language string `json:"language,omitempty"`
+	Cues     int    `json:"cues"`
+}
+
+// printTracks writes one line per track to stdout.
+func printTracks(cmd *cobra.Command, tracks []extract.Track) error {
+	if len(tracks) == 0 {
+		return extract.ErrNoSubtitleTrack
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TRACK\tCODEC\tLANGUAGE\tTITLE\tFLAGS")
+	for _, t := range tracks {
+		var flags []string
+		if t.Default {
+			flags = append(flags, "default")
+		}
+		if t.Forced {
+			flags = append(flags, "forced")
+		}
+		if !t.Text {
+			flags = append(flags, "bitmap")
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Codec, dashIfEmpty(t.Language), dashIfEmpty(t.Title), dashIfEmpty(strings.Join(flags, ",")))
+	}
+	return w.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	extractCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to <input>.<language>.srt next to the input)")
+	extractCmd.Flags().Bool(flagDryRun, false, "Write output to a temporary file in the workdir")
+	extractCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	extractCmd.Flags().Int(flagTrack, extract.NoTrack, "Stream index of the subtitle track to extract (see --list; defaults to the default text track)")
+	addNamingFlags(extractCmd, "Write the output next to the video")
+	extractCmd.Flags().Bool(flagList, false, "List the subtitle tracks of the file and exit")
+	extractCmd.Flags().String(flagTool, extract.DefaultTool, "Extraction tool: auto, ffmpeg or mkvextract")
+}
@@ -2,19 +2,29 @@ package cli
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
 
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
+	"github.com/adrianmusante/subtitle-tools/internal/extract"
 	"github.com/adrianmusante/subtitle-tools/internal/fix"
 	"github.com/adrianmusante/subtitle-tools/internal/fs"
 	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/progress"
 	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/scenes"
+	"github.com/adrianmusante/subtitle-tools/internal/unidiff"
 	"github.com/spf13/cobra"
 )
 
 var fixCmd = &cobra.Command{
-	Use:   "fix [flags] <input-file>",
+	Use:   "fix [flags] <input-file|directory|glob>...",
 	Short: "Fix common issues in subtitle files (overlaps, out-of-order cues, etc.)",
-	Args:  cobra.ExactArgs(1),
+	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Allow resolving some flags from env vars.
 		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
@@ -24,11 +34,15 @@ var fixCmd = &cobra.Command{
 			return err
 		}
 
+		reporter, err := newProgressReporter(cmd)
+		if err != nil {
+			return err
+		}
+		defer reporter.Finish()
+
 		ctx := cmd.Context()
 		log := logging.FromContext(ctx)
 
-		inputPath := args[0]
-
 		outputPath, _ := cmd.Flags().GetString(flagOutput)
 		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
 		workdir, _ := cmd.Flags().GetString(flagWorkdir)
@@ -36,29 +50,118 @@ var fixCmd = &cobra.Command{
 
 		minWords, _ := cmd.Flags().GetInt(flagMinWordsMerge)
 		maxLineLen, _ := cmd.Flags().GetInt(flagMaxLineLen)
+		if !flagSet(cmd, flagMaxLineLen) {
+			maxLineLen = 0 // the default of the language (see fix.MaxLineLengthFor)
+		}
 		stripHI, _ := cmd.Flags().GetBool(flagStripHI)
 		stripHIMode, _ := cmd.Flags().GetString(flagStripHIMode)
 		stripStyle, _ := cmd.Flags().GetBool(flagStripStyle)
+		stripASSTags, _ := cmd.Flags().GetBool(flagStripASSTags)
+		keepTags, _ := cmd.Flags().GetStringSlice(flagKeepTags)
+		stripTags, _ := cmd.Flags().GetStringSlice(flagStripTags)
+		removeSDH, _ := cmd.Flags().GetBool(flagRemoveSDH)
+		onlyForced, _ := cmd.Flags().GetBool(flagOnlyForced)
+		skipSDH, _ := cmd.Flags().GetBool(flagSkipSDH)
 		shiftTime, _ := cmd.Flags().GetDuration(flagShiftTime)
+		reportPath, _ := cmd.Flags().GetString(flagReport)
+		diffPath, _ := cmd.Flags().GetString(flagDiff)
+		minDuration, _ := cmd.Flags().GetDuration(flagMinDuration)
+		maxLines, _ := cmd.Flags().GetInt(flagMaxLines)
+		maxCPS, _ := cmd.Flags().GetFloat64(flagMaxCPS)
+		overlapPolicy, _ := cmd.Flags().GetString(flagOverlapPolicy)
+		preserveIndex, _ := cmd.Flags().GetBool(flagPreserveIndex)
+		dialogueDashes, _ := cmd.Flags().GetString(flagDialogueDashes)
+		normalizeUnicode, _ := cmd.Flags().GetBool(flagNormalizeUnicode)
+		fixOCR, _ := cmd.Flags().GetBool(flagFixOCR)
+		spellcheck, _ := cmd.Flags().GetBool(flagSpellcheck)
+		dictionary, _ := cmd.Flags().GetString(flagDictionary)
+		wordsPath, _ := cmd.Flags().GetString(flagWords)
+		ocrReplacements, _ := cmd.Flags().GetString(flagOCRReplacements)
+		rulesPath, _ := cmd.Flags().GetString(flagRules)
+		censorList, _ := cmd.Flags().GetString(flagCensorList)
+		censorStyle, _ := cmd.Flags().GetString(flagCensorStyle)
+		keepCredits, _ := cmd.Flags().GetBool(flagKeepCredits)
+		quotes, _ := cmd.Flags().GetString(flagQuotes)
+		dashStyle, _ := cmd.Flags().GetString(flagDashStyle)
+		ellipsis, _ := cmd.Flags().GetString(flagEllipsis)
+		fixSpacing, _ := cmd.Flags().GetBool(flagFixSpacing)
+		invertedMarks, _ := cmd.Flags().GetBool(flagInvertedMarks)
+		creditsBlocklist, _ := cmd.Flags().GetString(flagCreditsBlocklist)
+		balanceLines, _ := cmd.Flags().GetBool(flagBalanceLines)
+		language, _ := cmd.Flags().GetString(flagLanguage)
+		minGap, _ := cmd.Flags().GetDuration(flagMinGap)
+		mediaDuration, _ := cmd.Flags().GetDuration(flagMediaDuration)
+		videoPath, _ := cmd.Flags().GetString(flagVideo)
+		snapScenes, _ := cmd.Flags().GetBool(flagSnapScenes)
+		snapThreshold, _ := cmd.Flags().GetDuration(flagSnapThreshold)
+		sceneScore, _ := cmd.Flags().GetFloat64(flagSceneScore)
+		selection, err := selectionFromFlags(cmd)
+		if err != nil {
+			return err
+		}
 
-		if inputPath == "-" {
-			return errors.New("stdin is not supported yet; pass a subtitle file path")
+		if len(stripTags) > 0 && (stripStyle || len(keepTags) > 0) {
+			return fmt.Errorf("--%s can't be combined with --%s or --%s", flagStripTags, flagStripStyle, flagKeepTags)
+		}
+
+		if removeSDH {
+			// --remove-sdh is shorthand for the most thorough HI stripping.
+			if cmd.Flags().Changed(flagStripHIMode) {
+				return fmt.Errorf("--%s and --%s are mutually exclusive (--%s uses mode %s)", flagRemoveSDH, flagStripHIMode, flagRemoveSDH, fix.StripHIModeStandardPlus)
+			}
+			stripHI = true
+			stripHIMode = fix.StripHIModeStandardPlus
 		}
 
-		absInput, err := fs.ResolveAbsPath(inputPath)
+		inputs, batch, err := expandInputs(cmd, args)
 		if err != nil {
 			return err
 		}
-		inputPath = absInput
+		jobs, _ := cmd.Flags().GetInt(flagJobs)
+		if batch {
+			if jobs < 1 {
+				return fmt.Errorf("invalid --%s %d (must be >= 1)", flagJobs, jobs)
+			}
+			if err := requireNamePlaceholder(flagOutput, outputPath); err != nil {
+				return err
+			}
+			if err := requireNamePlaceholder(flagReport, reportPath); err != nil {
+				return err
+			}
+			if diffPath != diffStdout {
+				if err := requireNamePlaceholder(flagDiff, diffPath); err != nil {
+					return err
+				}
+			}
+			if videoPath != "" {
+				return fmt.Errorf("--%s can't be used with multiple input files (use --%s)", flagVideo, flagMediaDuration)
+			}
+		}
 
-		if outputPath == "" {
-			outputPath = inputPath
-		} else {
-			absOut, err := fs.ResolveAbsPath(outputPath)
-			if err != nil {
+		// The output and report paths of each input; {dir} and {name} are
+		// only expanded in batch mode.
+		pathFor := func(path string, in batchInput) (string, error) {
+			if path == "" {
+				return "", nil
+			}
+			if batch {
+				path = expandInputPlaceholders(path, in.Path)
+			}
+			return fs.ResolveAbsPath(path)
+		}
+		outputs := make([]string, len(inputs))
+		outputOf := make(map[string]string, len(inputs))
+		for i, in := range inputs {
+			if outputs[i], err = pathFor(outputPath, in); err != nil {
 				return err
 			}
-			outputPath = absOut
+			if outputs[i] == "" {
+				outputs[i] = inPlaceOutputPath(in.Path)
+			}
+			if prev, ok := outputOf[outputs[i]]; ok {
+				return fmt.Errorf("input files %s and %s have the same output path %s", prev, in.Name, outputs[i])
+			}
+			outputOf[outputs[i]] = in.Name
 		}
 
 		// Temporarily disabled: failing to write the result is less costly than pre‑validating write access.
@@ -66,6 +169,95 @@ var fixCmd = &cobra.Command{
 		//	return fmt.Errorf("invalid --output path %s: %w", outputPath, err)
 		//}
 
+		if ocrReplacements != "" {
+			if !fixOCR {
+				return fmt.Errorf("--%s requires --%s", flagOCRReplacements, flagFixOCR)
+			}
+			absReplacements, err := fs.ResolveAbsPath(ocrReplacements)
+			if err != nil {
+				return err
+			}
+			ocrReplacements = absReplacements
+		}
+
+		if snapScenes && videoPath == "" {
+			return fmt.Errorf("--%s requires --%s", flagSnapScenes, flagVideo)
+		}
+		if (flagSet(cmd, flagSnapThreshold) || flagSet(cmd, flagSceneScore)) && !snapScenes {
+			return fmt.Errorf("--%s and --%s require --%s", flagSnapThreshold, flagSceneScore, flagSnapScenes)
+		}
+		if snapThreshold < 0 {
+			return fmt.Errorf("invalid --%s %s (must not be negative)", flagSnapThreshold, snapThreshold)
+		}
+		if sceneScore <= 0 || sceneScore >= 1 {
+			return fmt.Errorf("invalid --%s %g (must be between 0 and 1)", flagSceneScore, sceneScore)
+		}
+
+		if (dictionary != "" || wordsPath != "") && !spellcheck {
+			return fmt.Errorf("--%s and --%s require --%s", flagDictionary, flagWords, flagSpellcheck)
+		}
+		for _, p := range []*string{&dictionary, &wordsPath} {
+			if *p != "" {
+				if *p, err = fs.ResolveAbsPath(*p); err != nil {
+					return err
+				}
+			}
+		}
+
+		var creditPatterns []string
+		if creditsBlocklist != "" {
+			if keepCredits {
+				return fmt.Errorf("--%s and --%s are mutually exclusive", flagCreditsBlocklist, flagKeepCredits)
+			}
+			creditPatterns, err = fix.LoadCreditPatterns(creditsBlocklist)
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %w", flagCreditsBlocklist, err)
+			}
+		}
+
+		if rulesPath != "" {
+			absRules, err := fs.ResolveAbsPath(rulesPath)
+			if err != nil {
+				return err
+			}
+			rulesPath = absRules
+		}
+
+		if censorList != "" {
+			absCensorList, err := fs.ResolveAbsPath(censorList)
+			if err != nil {
+				return err
+			}
+			censorList = absCensorList
+		}
+
+		if diffPath != "" {
+			// --diff previews the changes: the input is never overwritten.
+			dryRun = true
+		}
+
+		var cuts scenes.Cuts
+		if videoPath != "" {
+			if cmd.Flags().Changed(flagMediaDuration) {
+				return fmt.Errorf("--%s and --%s are mutually exclusive", flagMediaDuration, flagVideo)
+			}
+			absVideo, err := fs.ResolveAbsPath(videoPath)
+			if err != nil {
+				return err
+			}
+			if mediaDuration, err = extract.MediaDuration(ctx, absVideo); err != nil {
+				return fmt.Errorf("read duration of --%s: %w", flagVideo, err)
+			}
+			log.Debug("media duration read from video", "video", absVideo, "duration", mediaDuration)
+			if snapScenes {
+				log.Info("detecting shot changes", "video", absVideo)
+				if cuts, err = scenes.Detect(ctx, absVideo, sceneScore); err != nil {
+					return fmt.Errorf("detect shot changes of --%s: %w", flagVideo, err)
+				}
+				log.Info("shot changes detected", "shot_changes", len(cuts.Times), "frame", cuts.Frame)
+			}
+		}
+
 		if workdir != "" {
 			absWorkdir, err := fs.ResolveAbsPath(workdir)
 			if err != nil {
@@ -83,35 +275,188 @@ var fixCmd = &cobra.Command{
 			defer cleanup()
 		}
 
-		opts := fix.Options{
-			InputPath:      inputPath,
-			OutputPath:     outputPath,
-			DryRun:         dryRun,
-			WorkDir:        runWorkdir,
-			MaxLineLength:  maxLineLen,
-			MinWordsMerge:  minWords,
-			StripHI:        stripHI,
-			StripHIMode:    stripHIMode,
-			StripStyle:     stripStyle,
-			BackupExt:      ".bak",
-			CreateBackup:   !dryRun && !skipBackup,
-			SkipTranslator: true,
-			ShiftTime:      shiftTime,
+		base := fix.Options{
+			DryRun:              dryRun,
+			MaxLineLength:       maxLineLen,
+			MinWordsMerge:       minWords,
+			MaxLines:            maxLines,
+			BalanceLines:        balanceLines,
+			Language:            language,
+			StripHI:             stripHI,
+			StripHIMode:         stripHIMode,
+			StripStyle:          stripStyle,
+			StripASSTags:        stripASSTags,
+			KeepTags:            keepTags,
+			StripTags:           stripTags,
+			BackupExt:           ".bak",
+			CreateBackup:        !dryRun && !skipBackup,
+			SkipTranslator:      true,
+			OnlyForced:          onlyForced,
+			SkipSDH:             skipSDH,
+			Selection:           selection,
+			RemoveCredits:       !keepCredits,
+			CreditPatterns:      creditPatterns,
+			ShiftTime:           shiftTime,
+			MaxCPS:              maxCPS,
+			OverlapPolicy:       overlapPolicy,
+			PreserveIndex:       preserveIndex,
+			DialogueDashes:      strings.ToLower(strings.TrimSpace(dialogueDashes)),
+			NormalizeUnicode:    normalizeUnicode,
+			FixOCR:              fixOCR,
+			OCRReplacementsPath: ocrReplacements,
+			Spellcheck:          spellcheck,
+			DictionaryPath:      dictionary,
+			SpellingWordsPath:   wordsPath,
+			RulesPath:           rulesPath,
+			CensorListPath:      censorList,
+			CensorStyle:         censorStyle,
+			Typography: fix.Typography{
+				Quotes:        strings.ToLower(strings.TrimSpace(quotes)),
+				DialogueDash:  strings.ToLower(strings.TrimSpace(dashStyle)),
+				Ellipsis:      strings.ToLower(strings.TrimSpace(ellipsis)),
+				Spacing:       fixSpacing,
+				InvertedMarks: invertedMarks,
+			},
+			MinDuration:   minDuration,
+			MinGap:        minGap,
+			MediaDuration: mediaDuration,
+			ShotChanges:   cuts.Times,
+			SnapThreshold: snapThreshold,
+			// Cues end two frames before a cut, as usual in professional
+			// subtitling.
+			ShotChangeGap: 2 * cuts.Frame,
 		}
 
-		log.Debug("running fix", "opts", opts)
+		// diffMu keeps the diffs of concurrent files apart on stdout.
+		var diffMu sync.Mutex
+		fixFile := func(ctx context.Context, in batchInput, output, workdir string, progressFn fix.ProgressFunc) error {
+			opts := base
+			opts.InputPath = in.Path
+			opts.OutputPath = output
+			opts.WorkDir = workdir
+			opts.Progress = progressFn
+			var err error
+			if opts.ReportPath, err = pathFor(reportPath, in); err != nil {
+				return err
+			}
+			log.Debug("running fix", "opts", opts)
 
-		result, err := fix.Run(ctx, opts)
-		if err != nil {
-			return err
+			result, err := fix.Run(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			for _, a := range result.Actions {
+				if a.Kind == fix.ActionRemovedCredit {
+					log.Info("removed credit", "idx", a.Idx, "time", a.Time, "text", a.Detail)
+				}
+			}
+			recordFile(newFixFileResult(in, result))
+			log.Info("fixed subtitles written", "path", result.WrittenPath, "actions", len(result.Actions))
+			if opts.ReportPath != "" {
+				log.Info("fix report written", "path", opts.ReportPath)
+			}
+			if diffPath != "" {
+				target := diffPath
+				if target != diffStdout {
+					if target, err = pathFor(diffPath, in); err != nil {
+						return err
+					}
+				}
+				diffMu.Lock()
+				err := writeFixDiff(cmd.OutOrStdout(), in.Name, in.Path, result.WrittenPath, target)
+				diffMu.Unlock()
+				if err != nil {
+					return fmt.Errorf("write diff: %w", err)
+				}
+				if target != diffStdout {
+					log.Info("fix diff written", "path", target)
+				}
+			}
+			return nil
 		}
 
-		log.Info("fixed subtitles written", "path", result.WrittenPath)
+		if !batch {
+			err := fixFile(ctx, inputs[0], outputs[0], runWorkdir, func(p fix.Progress) {
+				reporter.Update(progress.Snapshot{
+					Task:   "fix",
+					Unit:   "steps",
+					Done:   p.Completed,
+					Total:  p.Total,
+					Fields: []progress.Field{{Key: "step", Value: p.Step}},
+				})
+			})
+			reporter.Finish()
+			return err
+		}
 
-		return nil
+		return runBatch(ctx, reporter, "fix", inputs, jobs, func(ctx context.Context, i int, in batchInput) error {
+			// Each file gets its own workdir: temporary files are named after the output.
+			workdir, _, err := run.NewWorkdir(runWorkdir, "file")
+			if err != nil {
+				return err
+			}
+			return fixFile(ctx, in, outputs[i], workdir, nil)
+		})
 	},
 }
 
+// fixFileResult is the --json entry of a fixed file.
+type fixFileResult struct {
+	Command string `json:"command"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	// Unchanged is true when the output was empty and the input was kept.
+	Unchanged bool `json:"unchanged,omitempty"`
+	Actions   int  `json:"actions"`
+	// ChangedCues counts the cues with at least one action.
+	ChangedCues int            `json:"changed_cues"`
+	Summary     map[string]int `json:"summary"` // actions per kind
+}
+
+func newFixFileResult(in batchInput, res fix.Result) fixFileResult {
+	r := fixFileResult{
+		Command:   "fix",
+		Input:     in.Path,
+		Output:    res.WrittenPath,
+		Unchanged: res.WasEmpty,
+		Actions:   len(res.Actions),
+		Summary:   make(map[string]int),
+	}
+	cues := make(map[int]bool)
+	for _, a := range res.Actions {
+		r.Summary[a.Kind]++
+		if a.Idx > 0 {
+			cues[a.Idx] = true
+		}
+	}
+	r.ChangedCues = len(cues)
+	return r
+}
+
+// diffStdout is the --diff value (and its default) that prints the diff.
+const diffStdout = "-"
+
+// writeFixDiff writes the unified diff between the input and the fixed file
+// to w (diffPath "-") or to diffPath. name is the file name of the diff
+// headers, as given on the command line, so the patch applies with patch -p0.
+func writeFixDiff(w io.Writer, name, inputPath, fixedPath, diffPath string) error {
+	before, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	after, err := os.ReadFile(fixedPath)
+	if err != nil {
+		return err
+	}
+	d := unidiff.Diff(name, name, string(before), string(after), unidiff.DefaultContext)
+	if diffPath == diffStdout {
+		_, err = io.WriteString(w, d)
+		return err
+	}
+	return fs.WriteFile(strings.NewReader(d), diffPath)
+}
+
 func init() {
 	registerFixFlags(fixCmd)
 }
@@ -123,11 +468,54 @@ func registerFixFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
 
 	cmd.Flags().Int(flagMinWordsMerge, fix.DefaultMinWordsForMerging, "Minimum words to consider a line 'short' for merging")
-	cmd.Flags().Int(flagMaxLineLen, fix.DefaultMaxLineLength, "Max line length when wrapping")
+	cmd.Flags().Int(flagMaxLineLen, fix.DefaultMaxLineLength, "Max line length when wrapping, in columns (full-width characters count as two; defaults to 26 for ja and 32 for zh and ko)")
+	cmd.Flags().Int(flagMaxLines, 0, "Max lines per cue (e.g. 2); longer cues are broken again into balanced lines (0 disables)")
+	cmd.Flags().Bool(flagBalanceLines, false, "Rebreak multi-line cues so their lines have similar lengths")
+	cmd.Flags().String(flagLanguage, "", "Language used for line breaking rules (e.g. en, es; defaults to the file name suffix, e.g. movie.es.srt)")
 	cmd.Flags().Bool(flagStripHI, false, "Remove hearing-impaired (HI) cues like [music]")
 	cmd.Flags().String(flagStripHIMode, fix.DefaultStripHIMode, "HI stripping mode: safe, standard, safe-plus, or standard-plus")
 	cmd.Flags().Bool(flagStripStyle, false, "Remove HTML/XML style tags from subtitle text")
+	cmd.Flags().Bool(flagStripASSTags, false, "Remove ASS override codes such as {\\an8} (kept codes don't count toward --max-line-len)")
+	cmd.Flags().StringSlice(flagKeepTags, nil, "Remove every style tag except these (e.g. i,b), keeping italics that carry meaning; implies --strip-style")
+	cmd.Flags().StringSlice(flagStripTags, nil, "Remove only these style tags (e.g. font,span)")
+	cmd.Flags().Bool(flagNormalizeUnicode, false, "Compose text to NFC, remove zero-width spaces, stray BOMs and soft hyphens, and replace unusual spaces")
+	cmd.Flags().Bool(flagFixOCR, false, "Correct common OCR errors (l/I and 0/O confusion, stray |, doubled apostrophes, missing spaces, broken ellipses)")
+	cmd.Flags().String(flagOCRReplacements, "", "File of extra OCR replacements, one wrong=right pair per line (requires --fix-ocr)")
+	cmd.Flags().Bool(flagSpellcheck, false, "Fix the misspelled words with a single suggestion in the hunspell dictionary of --language")
+	cmd.Flags().String(flagDictionary, "", "Hunspell .dic file used by --spellcheck (with its .aff next to it; defaults to the dictionary of --language)")
+	cmd.Flags().String(flagWords, "", "File of words accepted by --spellcheck (character names, places...), one per line")
+	cmd.Flags().String(flagOverlapPolicy, fix.DefaultOverlapPolicy, "How overlapping cues are fixed: merge, trim, shift, or keep")
+	cmd.Flags().String(flagDialogueDashes, "", "Dash convention of cues with several speakers: all (a dash per speaker), second (only the second speaker) or none; overlapping cues merged together become separate speakers")
+	cmd.Flags().Float64(flagMaxCPS, 0, "Split cues read faster than this many characters per second at their sentence boundaries (0 disables)")
+	cmd.Flags().Duration(flagMinDuration, 0, "Minimum cue duration (e.g. 1s); shorter cues are extended into the following gap or merged with the next cue (0 disables)")
+	cmd.Flags().Duration(flagMinGap, 0, "Minimum gap between consecutive cues (e.g. 80ms), enforced by trimming end times (0 disables)")
+	cmd.Flags().Duration(flagMediaDuration, 0, "Duration of the video (e.g. 1h42m13s): cues starting after it are dropped and cues ending after it are clamped (0 disables)")
+	cmd.Flags().String(flagVideo, "", "Video file whose duration (read with ffprobe) is used as --media-duration")
+	cmd.Flags().Bool(flagSnapScenes, false, "Snap cue start and end times near a shot change of --video to it, so cues don't cross a cut by a few frames (detected with ffmpeg)")
+	cmd.Flags().Duration(flagSnapThreshold, fix.DefaultSnapThreshold, "How far from a shot change cue times are snapped to it (requires --snap-scenes)")
+	cmd.Flags().Float64(flagSceneScore, scenes.DefaultMinScore, "Scene score (0-1) from which a frame is a shot change; lower finds more cuts (requires --snap-scenes)")
+	cmd.Flags().Bool(flagRemoveSDH, false, "Remove SDH text: sound descriptions in brackets/parentheses, speaker labels and music-only cues (same as --strip-hi --strip-hi-mode standard-plus)")
+	cmd.Flags().Bool(flagOnlyForced, false, "Keep only the forced cues (tagged {\\forced}), to build a forced track from a full one")
+	cmd.Flags().Bool(flagSkipSDH, false, "Drop the cues that only describe sounds, e.g. [door slams] or (laughs), keeping the rest untouched")
+	addSelectionFlags(cmd, "fix")
+	cmd.Flags().Bool(flagKeepCredits, false, "Keep ad, subtitle credit and URL lines (e.g. \"Downloaded from...\", \"Subtitles by...\")")
+	cmd.Flags().String(flagCreditsBlocklist, "", "File of extra credit patterns, one case-insensitive regular expression per line")
+	cmd.Flags().String(flagQuotes, "", "Normalize quotes: straight or curly (the quotes of --language, e.g. “” in English, « » in French)")
+	cmd.Flags().String(flagDashStyle, "", "Normalize the dash of dialogue lines: hyphen, en-dash or em-dash")
+	cmd.Flags().String(flagEllipsis, "", "Normalize ellipses: dots (...) or char (…)")
+	cmd.Flags().Bool(flagFixSpacing, false, "Collapse repeated spaces and fix spacing around punctuation following --language conventions")
+	cmd.Flags().Bool(flagInvertedMarks, false, "Add missing opening ¿ and ¡ to Spanish questions and exclamations (requires a Spanish --language or file name)")
+	cmd.Flags().String(flagRules, "", "YAML file of ordered regex find/replace rules applied to each cue")
+	cmd.Flags().String(flagCensorList, "", "File of words to censor, one word or phrase per line (word* also matches the words starting with it, word=text sets its replacement)")
+	cmd.Flags().String(flagCensorStyle, censor.DefaultStyle, "How listed words are censored: stars (f***), beep-text ([beep]) or remove-cue (drops the cue)")
+	cmd.Flags().Bool(flagPreserveIndex, false, "Keep the original cue numbers and copy unchanged cues byte for byte, so only changed cues differ from the input")
+	cmd.Flags().String(flagDiff, "", "Preview the changes as a unified diff instead of writing the file (implies --dry-run); prints to stdout or, with --diff=<path>, writes a .patch file")
+	cmd.Flags().Lookup(flagDiff).NoOptDefVal = diffStdout
+	cmd.Flags().String(flagReport, "", "Write the list of changes made (merged, removed, rewrapped cues...) as JSON to this path")
 	cmd.Flags().Duration(flagShiftTime, 0, "Shift all cue times by the specified duration (e.g. 500ms, -2s, 1s250ms)")
+	addBatchFlags(cmd, runtime.NumCPU())
+	addProgressFlag(cmd)
+	addHookFlags(cmd)
 }
 
 // for tests / future hooking
@@ -225,3 +225,70 @@ func newFixTestCommand() *cobra.Command {
 
 	return cmd
 }
+
+func TestFixCLI_DiffPreviewsChanges(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "in.srt")
+	orig := "1\n00:00:01,000 --> 00:00:02,000\n<b>Hello</b>\n\n"
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cmd := newFixTestCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--strip-style", "--diff", "-w", t.TempDir(), input})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("fix --diff: %v", err)
+	}
+
+	want := "--- " + input + "\n+++ " + input + "\n@@ -1,4 +1,4 @@\n 1\n 00:00:01,000 --> 00:00:02,000\n-<b>Hello</b>\n+Hello\n \n"
+	if out.String() != want {
+		t.Fatalf("unexpected diff:\n got %q\nwant %q", out.String(), want)
+	}
+	if b, err := os.ReadFile(input); err != nil || string(b) != orig {
+		t.Fatalf("expected --diff to leave the input untouched, got %q (err %v)", b, err)
+	}
+}
+
+func TestFixCLI_BatchProcessesDirectory(t *testing.T) {
+	dir := t.TempDir()
+	cue := "1\n00:00:01,000 --> 00:00:02,000\n<b>Hello</b>\n\n"
+	files := map[string]string{
+		"a.srt":          cue,
+		"b.es.srt":       cue,
+		"sub/c.srt":      cue,
+		"notes.txt":      cue,
+		"sub/broken.srt": "1\nnot a timing line\nHello\n\n",
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	cmd := newFixTestCommand()
+	cmd.SetArgs([]string{"--strip-style", "--recursive", "--jobs", "2", "-o", "{dir}/{name}.fixed.srt", "-w", t.TempDir(), dir})
+	err := cmd.ExecuteContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 files failed: "+filepath.Join(dir, "sub", "broken.srt")) {
+		t.Fatalf("expected only the broken file to fail, got %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"
+	for _, name := range []string{"a.fixed.srt", "b.es.fixed.srt", "sub/c.fixed.srt"} {
+		if b, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(b) != want {
+			t.Fatalf("%s: got %q (err %v)", name, b, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.fixed.srt")); !os.IsNotExist(err) {
+		t.Fatalf("expected files not matching --include to be skipped, got %v", err)
+	}
+
+	// Several inputs need a per-file output path.
+	cmd = newFixTestCommand()
+	cmd.SetArgs([]string{"-o", filepath.Join(dir, "out.srt"), filepath.Join(dir, "*.srt")})
+	if err := cmd.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), "--output must contain {name}") {
+		t.Fatalf("expected an --output placeholder error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/glossary"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var glossaryCmd = &cobra.Command{
+	Use:   "glossary",
+	Short: "Build glossaries for the translate command",
+}
+
+var glossaryBuildCmd = &cobra.Command{
+	Use:   "build [flags] <input-file>...",
+	Short: "Extract the recurring names and terms of subtitle files into a CSV glossary to fill in",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		minCount, _ := cmd.Flags().GetInt(flagMinCount)
+		maxTerms, _ := cmd.Flags().GetInt(flagMaxTerms)
+		if minCount < 1 {
+			return fmt.Errorf("invalid --%s %d (must be >= 1)", flagMinCount, minCount)
+		}
+		if maxTerms < 0 {
+			return fmt.Errorf("invalid --%s %d (must be >= 0)", flagMaxTerms, maxTerms)
+		}
+		if outputPath != "" {
+			var err error
+			if outputPath, err = resolveNewOutputPath(outputPath); err != nil {
+				return err
+			}
+		}
+
+		var texts []string
+		for _, arg := range args {
+			path, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			subs, err := readSubtitles(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			for _, sub := range subs {
+				texts = append(texts, sub.Text)
+			}
+		}
+		terms := glossary.Extract(texts, minCount)
+		if maxTerms > 0 && len(terms) > maxTerms {
+			terms = terms[:maxTerms]
+		}
+
+		if outputPath == "" {
+			return glossary.WriteCSV(cmd.OutOrStdout(), terms)
+		}
+		var b bytes.Buffer
+		if err := glossary.WriteCSV(&b, terms); err != nil {
+			return err
+		}
+		if err := fs.WriteFile(&b, outputPath); err != nil {
+			return err
+		}
+		logging.FromContext(cmd.Context()).Info("glossary written", "path", outputPath, "terms", len(terms))
+		return nil
+	},
+}
+
+func init() {
+	glossaryBuildCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output CSV path (defaults to stdout; must not already exist)")
+	glossaryBuildCmd.Flags().Int(flagMinCount, glossary.DefaultMinCount, "Min occurrences of a name or term across the inputs")
+	glossaryBuildCmd.Flags().Int(flagMaxTerms, 0, "Max terms written, most frequent first (0 means all)")
+	glossaryCmd.AddCommand(glossaryBuildCmd)
+}
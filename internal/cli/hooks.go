@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/httpclient"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+// hookTimeout bounds each hook, so a stuck webhook doesn't hang the command.
+const hookTimeout = 30 * time.Second
+
+// Environment variables set for command hooks, next to the JSON result on
+// stdin.
+const (
+	envHookExitCode   = "SUBTITLE_TOOLS_EXIT_CODE"
+	envHookExitReason = "SUBTITLE_TOOLS_EXIT_REASON"
+)
+
+func addHookFlags(cmd *cobra.Command) {
+	_ = cmd.Flags().StringArray(flagOnSuccess, nil, "Shell command or webhook URL run when the command succeeds, receiving the --json result (repeatable)")
+	_ = cmd.Flags().StringArray(flagOnFailure, nil, "Shell command or webhook URL run when the command fails, receiving the --json result (repeatable)")
+}
+
+// hasHooks reports whether a hook of cmd is set, so the result they receive
+// has to be collected even without --json.
+func hasHooks(cmd *cobra.Command) bool {
+	return flagSet(cmd, flagOnSuccess) || flagSet(cmd, flagOnFailure)
+}
+
+// runHooks runs the --on-success or --on-failure hooks of cmd, depending on
+// runErr, in order, passing them doc (the --json result). A failed hook is
+// logged but doesn't change the outcome of the command.
+func runHooks(ctx context.Context, cmd *cobra.Command, doc []byte, runErr error) {
+	name := flagOnSuccess
+	if runErr != nil {
+		name = flagOnFailure
+	}
+	hooks, _ := cmd.Flags().GetStringArray(name)
+	if len(hooks) == 0 {
+		return
+	}
+	log := logging.FromContext(ctx)
+	code, reason := exitStatus(runErr)
+	for _, hook := range hooks {
+		hook = strings.TrimSpace(hook)
+		if hook == "" {
+			continue
+		}
+		var err error
+		if isWebhookURL(hook) {
+			err = postWebhook(ctx, cmd, hook, doc)
+			hook = maskURL(hook)
+		} else {
+			err = runHookCommand(ctx, hook, doc, code, reason)
+		}
+		if err != nil {
+			log.Error("hook failed", "flag", "--"+name, "hook", hook, "err", err)
+			continue
+		}
+		log.Debug("hook done", "flag", "--"+name, "hook", hook)
+	}
+}
+
+func isWebhookURL(hook string) bool {
+	return strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://")
+}
+
+// postWebhook sends doc to url as a JSON POST, with the network flags of cmd
+// (if any); any 2xx status is a success.
+func postWebhook(ctx context.Context, cmd *cobra.Command, url string, doc []byte) error {
+	netOpts, err := networkOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	transport, err := httpclient.NewTransport(netOpts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(doc))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", rootCmd.Name()+"/"+rootCmd.Version)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// runHookCommand runs hook with the system shell, the result on its stdin and
+// the exit code and reason in its environment. Its output goes to stderr, so
+// stdout keeps only the result of the command.
+func runHookCommand(ctx context.Context, hook string, doc []byte, code int, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", hook)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", hook)
+	}
+	c.Stdin = bytes.NewReader(doc)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), envHookExitCode+"="+strconv.Itoa(code), envHookExitReason+"="+reason)
+	return c.Run()
+}
+
+// maskURL drops the path and query of url for logging: chat webhook URLs
+// carry their token there.
+func maskURL(url string) string {
+	scheme, rest, _ := strings.Cut(url, "://")
+	host, _, _ := strings.Cut(rest, "/")
+	if _, h, ok := strings.Cut(host, "@"); ok {
+		host = h
+	}
+	return scheme + "://" + host + "/..."
+}
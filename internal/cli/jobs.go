@@ -0,0 +1,520 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/jobs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/progress"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// jobHeartbeatInterval is how often the worker refreshes its lock, well
+	// within jobs.StaleAfter.
+	jobHeartbeatInterval = 5 * time.Second
+	// jobCancelPollInterval is how often the worker looks for the cancel
+	// marker of the running job.
+	jobCancelPollInterval = time.Second
+	// jobWaitInterval is how often jobs run --wait looks for new jobs.
+	jobWaitInterval = 2 * time.Second
+)
+
+// errJobCanceled is the cause of the cancellation of a job by jobs cancel.
+var errJobCanceled = errors.New("job canceled")
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Queue fix and translate runs on disk and run them with a worker that can cancel and resume them",
+}
+
+var jobsAddCmd = &cobra.Command{
+	Use:   "add <fix|translate> [flags] <input-file>...",
+	Short: "Queue a fix or translate run; the flags and arguments are the ones of the command",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command, cmdArgs := args[0], args[1:]
+		if command != stepFix && command != stepTranslate {
+			return fmt.Errorf("invalid job command %q (supported: %s, %s)", command, stepFix, stepTranslate)
+		}
+		// Catch typos now rather than when the worker runs the job.
+		stepCmd := newStepCommand(command)
+		if err := stepCmd.ParseFlags(cmdArgs); err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+		if err := stepCmd.ValidateArgs(stepCmd.Flags().Args()); err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		job, err := store.Add(command, cmdArgs, dir)
+		if err != nil {
+			return err
+		}
+		logging.FromContext(cmd.Context()).Info("job queued", "id", job.ID, "command", command)
+		recordFile(jobResult{Command: "jobs add", ID: job.ID, State: job.State})
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), job.ID)
+		return err
+	},
+}
+
+var jobsRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the queued jobs one at a time, oldest first, until the queue is empty",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+		wait, _ := cmd.Flags().GetBool(flagWait)
+
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		lock, err := store.LockWorker()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := lock.Release(); err != nil {
+				log.Warn("cannot release the worker lock", "err", err)
+			}
+		}()
+		stopHeartbeat := startHeartbeat(ctx, lock)
+		defer stopHeartbeat()
+
+		// Holding the lock, running jobs were left behind by a worker that
+		// stopped: run them again.
+		requeued, err := store.Requeue()
+		if err != nil {
+			return err
+		}
+		for _, job := range requeued {
+			log.Info("job requeued after an interrupted run", "id", job.ID)
+		}
+
+		var errs []error
+		total := 0
+		for {
+			job, ok, err := store.Next()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				if !wait {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(jobWaitInterval):
+				}
+				continue
+			}
+			total++
+			if err := runJob(ctx, cmd, store, job); err != nil {
+				errs = append(errs, err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if total == 0 {
+			log.Info("no queued jobs", "dir", store.Dir())
+		}
+		if len(errs) > 0 {
+			return &batchError{errs: errs, total: total, msg: fmt.Sprintf("%d of %d jobs failed", len(errs), total)}
+		}
+		return nil
+	},
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the jobs, oldest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString(flagFormat)
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		all, err := store.List()
+		if err != nil {
+			return err
+		}
+		for i := range all {
+			all[i].Args = maskJobArgs(all[i].Args)
+		}
+		if format == formatJSON {
+			if all == nil {
+				all = []jobs.Job{}
+			}
+			return writeJSON(cmd.OutOrStdout(), all)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tSTATE\tPROGRESS\tCREATED\tCOMMAND")
+		for _, job := range all {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.ID, job.State, dashIfEmpty(formatJobProgress(job.Progress)),
+				job.CreatedAt.Local().Format("2006-01-02 15:04"), strings.Join(append([]string{job.Command}, job.Args...), " "))
+		}
+		return w.Flush()
+	},
+}
+
+var jobsShowCmd = &cobra.Command{
+	Use:   "show <job-id>",
+	Short: "Print a job as JSON: its state, progress and the result of its last run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		job, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		job.Args = maskJobArgs(job.Args)
+		return writeJSON(cmd.OutOrStdout(), job)
+	},
+}
+
+var jobsCancelCmd = &cobra.Command{
+	Use:   "cancel <job-id>",
+	Short: "Cancel a queued or running job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		job, err := store.Cancel(args[0])
+		if err != nil {
+			return err
+		}
+		log := logging.FromContext(cmd.Context())
+		if job.State == jobs.StateRunning {
+			log.Info("job cancel requested; the worker stops it shortly", "id", job.ID)
+		} else {
+			log.Info("job canceled", "id", job.ID)
+		}
+		recordFile(jobResult{Command: "jobs cancel", ID: job.ID, State: job.State})
+		return nil
+	},
+}
+
+var jobsResumeCmd = &cobra.Command{
+	Use:   "resume <job-id>",
+	Short: "Queue again a failed, partial or canceled job (or one left running by a stopped worker)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		job, err := store.Resume(args[0])
+		if err != nil {
+			return err
+		}
+		logging.FromContext(cmd.Context()).Info("job queued", "id", job.ID)
+		recordFile(jobResult{Command: "jobs resume", ID: job.ID, State: job.State})
+		return nil
+	},
+}
+
+var jobsRemoveCmd = &cobra.Command{
+	Use:   "rm <job-id>",
+	Short: "Delete a job that isn't running",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openJobStore(cmd)
+		if err != nil {
+			return err
+		}
+		job, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if err := store.Remove(job.ID); err != nil {
+			return err
+		}
+		logging.FromContext(cmd.Context()).Info("job removed", "id", job.ID)
+		return nil
+	},
+}
+
+// jobResult is the --json entry of a job added, run, canceled or resumed.
+type jobResult struct {
+	Command string     `json:"command"`
+	ID      string     `json:"id"`
+	State   jobs.State `json:"state"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// openJobStore opens the store of --jobs-dir (default: subtitle-tools/jobs in
+// the user cache directory).
+func openJobStore(cmd *cobra.Command) (*jobs.Store, error) {
+	if err := resolveStringFlagFromEnv(cmd, flagJobsDir, envJobsDir); err != nil {
+		return nil, err
+	}
+	dir, _ := cmd.Flags().GetString(flagJobsDir)
+	var err error
+	if dir == "" {
+		if dir, err = jobs.DefaultDir(); err != nil {
+			return nil, fmt.Errorf("cannot resolve the jobs dir, set --%s: %w", flagJobsDir, err)
+		}
+	}
+	if dir, err = fs.ResolveAbsPath(dir); err != nil {
+		return nil, err
+	}
+	return jobs.Open(dir)
+}
+
+// startHeartbeat refreshes the worker lock until the returned function is
+// called.
+func startHeartbeat(ctx context.Context, lock *jobs.WorkerLock) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := lock.Refresh(); err != nil {
+					logging.FromContext(ctx).Warn("cannot refresh the worker lock", "err", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// runJob runs job and records its final state. The returned error is the one
+// of a job that failed, for the exit code of the worker.
+func runJob(ctx context.Context, worker *cobra.Command, store *jobs.Store, job jobs.Job) error {
+	log := logging.FromContext(ctx).With("job", job.ID)
+	if err := store.Start(&job); err != nil {
+		return err
+	}
+	log.Info("job started", "command", job.Command, "attempt", job.Attempts)
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		ticker := time.NewTicker(jobCancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if store.Canceled(job.ID) {
+					cancel(errJobCanceled)
+					return
+				}
+			}
+		}
+	}()
+
+	recorder := &jobRecorder{store: store, job: &job, log: log}
+	result, runErr := runJobCommand(withProgressRecorder(jobCtx, recorder), worker, job, log)
+
+	state := jobs.StateDone
+	switch {
+	case runErr == nil:
+	case context.Cause(jobCtx) == errJobCanceled:
+		state = jobs.StateCanceled
+	case ctx.Err() != nil:
+		state = jobs.StateQueued // the worker is stopping: run it again next time
+	default:
+		state = jobs.StateFailed
+		if code, _ := exitStatus(runErr); code == exitPartial {
+			state = jobs.StatePartial
+		}
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	job.Result = result
+	errMsg, exitReason := "", ""
+	if runErr != nil {
+		errMsg = runErr.Error()
+		_, exitReason = exitStatus(runErr)
+	}
+	if state == jobs.StateQueued {
+		job.State = state
+		if err := store.Save(&job); err != nil {
+			return err
+		}
+	} else if err := store.Finish(&job, state, errMsg, exitReason); err != nil {
+		return err
+	}
+	recordFile(jobResult{Command: "jobs run", ID: job.ID, State: state, Error: errMsg})
+
+	switch state {
+	case jobs.StateDone:
+		log.Info("job done")
+	case jobs.StateCanceled:
+		log.Info("job canceled")
+	case jobs.StateQueued:
+		log.Info("job interrupted; it runs again with the next worker")
+	default:
+		log.Error("job failed", "state", state, "err", runErr)
+		return fmt.Errorf("job %s: %w", job.ID, runErr)
+	}
+	return nil
+}
+
+// runJobCommand runs the command of job from its directory, like the command
+// line would with the same flags and the current config file, including its
+// hooks, and returns its --json result.
+func runJobCommand(ctx context.Context, worker *cobra.Command, job jobs.Job, log *slog.Logger) (json.RawMessage, error) {
+	// Every job gets its own --json result, saved with the job.
+	prev := currentResult
+	result := newRunResult(rootCmd.Name() + " " + job.Command)
+	currentResult = result
+	defer func() { currentResult = prev }()
+	log = slog.New(&warningRecorder{Handler: log.Handler(), result: result})
+
+	stepCmd := newStepCommand(job.Command)
+	runErr := func() error {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if err := os.Chdir(job.Dir); err != nil {
+			return err
+		}
+		defer func() {
+			if err := os.Chdir(wd); err != nil {
+				log.Warn("cannot restore the working directory", "dir", wd, "err", err)
+			}
+		}()
+
+		// Parse the flags first: the selected --profile decides the config
+		// values applied.
+		if err := stepCmd.ParseFlags(job.Args); err != nil {
+			return err
+		}
+		cfg, err := loadConfig(worker)
+		if err != nil {
+			return err
+		}
+		if err := applyConfig(stepCmd, cfg, job.Command); err != nil {
+			return err
+		}
+		stepCmd.SetArgs(append([]string{"--"}, stepCmd.Flags().Args()...))
+		stepCmd.SetOut(io.MultiWriter(&lockedWriter{mu: &result.mu, w: &result.stdout}, worker.OutOrStdout()))
+		return stepCmd.ExecuteContext(logging.WithLogger(ctx, log))
+	}()
+
+	var buf bytes.Buffer
+	if err := writeResult(&buf, runErr); err != nil {
+		log.Warn("cannot encode the job result", "err", err)
+		return nil, runErr
+	}
+	runHooks(logging.WithLogger(ctx, log), stepCmd, buf.Bytes(), runErr)
+	return bytes.TrimSpace(buf.Bytes()), runErr
+}
+
+// jobRecorder saves the progress of a running job.
+type jobRecorder struct {
+	store *jobs.Store
+	log   *slog.Logger
+
+	mu  sync.Mutex
+	job *jobs.Job
+}
+
+func (r *jobRecorder) Update(s progress.Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.job.SetProgress(jobs.Progress{Task: s.Task, Unit: s.Unit, Done: s.Done, Total: s.Total})
+	if err := r.store.Save(r.job); err != nil {
+		r.log.Warn("cannot save the job progress", "err", err)
+	}
+}
+
+func (r *jobRecorder) Finish() {}
+
+// formatJobProgress describes the progress of every task, e.g.
+// "translate es 3/12 batches".
+func formatJobProgress(ps []jobs.Progress) string {
+	parts := make([]string, 0, len(ps))
+	for _, p := range ps {
+		s := fmt.Sprintf("%s %d/%d", p.Task, p.Done, p.Total)
+		if p.Unit != "" {
+			s += " " + p.Unit
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// maskJobArgs returns args with the values of the API key flags masked.
+func maskJobArgs(args []string) []string {
+	masked := slices.Clone(args)
+	for i := 0; i < len(masked); i++ {
+		name, value, inline := strings.Cut(strings.TrimLeft(masked[i], "-"), "=")
+		if !strings.HasPrefix(masked[i], "--") || !strings.HasSuffix(name, flagApiKey) {
+			continue
+		}
+		if inline {
+			masked[i] = "--" + name + "=" + run.MaskKeys(value, ",")
+		} else if i+1 < len(masked) {
+			i++
+			masked[i] = run.MaskKeys(masked[i], ",")
+		}
+	}
+	return masked
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func init() {
+	jobsCmd.PersistentFlags().String(flagJobsDir, "", "Directory of the job queue (default: subtitle-tools/jobs in the user cache directory, e.g. ~/.cache)")
+	// Everything after the command of the job is its own flags and arguments.
+	jobsAddCmd.Flags().SetInterspersed(false)
+	jobsRunCmd.Flags().Bool(flagWait, false, "Keep waiting for new jobs when the queue is empty instead of exiting")
+	jobsListCmd.Flags().String(flagFormat, formatText, "Output format: text or json")
+
+	jobsCmd.AddCommand(jobsAddCmd)
+	jobsCmd.AddCommand(jobsCancelCmd)
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsRemoveCmd)
+	jobsCmd.AddCommand(jobsResumeCmd)
+	jobsCmd.AddCommand(jobsRunCmd)
+	jobsCmd.AddCommand(jobsShowCmd)
+}
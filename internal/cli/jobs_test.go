@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/jobs"
+	"github.com/spf13/cobra"
+)
+
+func TestRunJob_RecordsOutcome(t *testing.T) {
+	// Jobs read the config file; keep the user's one out of the test.
+	t.Setenv(envConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	dir := t.TempDir()
+	input := "1\n00:00:01,000 --> 00:00:02,000\n<b>Hello</b> there\n\n"
+	if err := os.WriteFile(filepath.Join(dir, "movie.en.srt"), []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.en.srt"), []byte("not a subtitle\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := jobs.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Relative paths are resolved from the directory the job was added from.
+	ok, err := store.Add(stepFix, []string{"--strip-style", "--progress", "off", "-o", "movie.fixed.srt", "movie.en.srt"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := store.Add(stepFix, []string{"--progress", "off", "broken.en.srt"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worker := &cobra.Command{}
+	if err := runJob(context.Background(), worker, store, ok); err != nil {
+		t.Fatalf("runJob: %v", err)
+	}
+	if err := runJob(context.Background(), worker, store, failed); err == nil {
+		t.Fatal("runJob of an invalid input: want error")
+	}
+
+	got, err := store.Get(ok.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != jobs.StateDone || got.FinishedAt == nil || len(got.Progress) == 0 {
+		t.Fatalf("done job = %+v", got)
+	}
+	var result struct {
+		OK    bool `json:"ok"`
+		Files []struct {
+			Output string `json:"output"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(got.Result, &result); err != nil {
+		t.Fatalf("job result %s: %v", got.Result, err)
+	}
+	if !result.OK || len(result.Files) != 1 || result.Files[0].Output != filepath.Join(dir, "movie.fixed.srt") {
+		t.Fatalf("job result = %s", got.Result)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "movie.fixed.srt")); err != nil || string(b) != "1\n00:00:01,000 --> 00:00:02,000\nHello there\n\n" {
+		t.Fatalf("unexpected output %q (err %v)", b, err)
+	}
+
+	if got, err = store.Get(failed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.State != jobs.StateFailed || got.Error == "" || got.ExitReason != exitReasonInputParse {
+		t.Fatalf("failed job = %+v", got)
+	}
+	if currentResult != nil {
+		t.Fatal("runJob left its result as the current one")
+	}
+}
+
+func TestMaskJobArgs(t *testing.T) {
+	args := []string{"--api-key", "sk-1234567890", "--fallback-api-key=sk-abcdefghij", "-t", "es", "movie.srt"}
+	got := maskJobArgs(args)
+	if got[1] == args[1] || got[2] == args[2] || !slices.Equal(got[3:], args[3:]) {
+		t.Fatalf("maskJobArgs(%q) = %q", args, got)
+	}
+	if args[1] != "sk-1234567890" {
+		t.Fatal("maskJobArgs modified its input")
+	}
+}
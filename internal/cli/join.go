@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/extract"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/join"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/spf13/cobra"
+)
+
+// maxOffsetFlag is the last file with an offset flag (--offset2 to
+// --offset9); later files start where the previous ends.
+const maxOffsetFlag = 9
+
+var joinCmd = &cobra.Command{
+	Use:   "join [flags] <input-file> <input-file>...",
+	Short: "Join subtitle files of a release in several parts (CD1/CD2) into one, shifting every part to its start",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		videos, _ := cmd.Flags().GetStringSlice(flagVideo)
+
+		if slices.Contains(args, "-") {
+			return errors.New("stdin is not supported; pass file paths")
+		}
+		var inputPaths []string
+		for _, arg := range args {
+			p, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			inputPaths = append(inputPaths, p)
+		}
+
+		offsets := map[int]time.Duration{}
+		for n := 2; n <= maxOffsetFlag; n++ {
+			flag := flagOffset + strconv.Itoa(n)
+			if !cmd.Flags().Changed(flag) {
+				continue
+			}
+			if n > len(inputPaths) {
+				return fmt.Errorf("--%s set for %d input files", flag, len(inputPaths))
+			}
+			v, _ := cmd.Flags().GetString(flag)
+			d, err := srt.ParseTime(v)
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %w", flag, err)
+			}
+			offsets[n-1] = d
+		}
+
+		var durations []time.Duration
+		if len(videos) > 0 {
+			if len(videos) != len(inputPaths) {
+				return fmt.Errorf("--%s needs a video per input file (got %d videos for %d files)", flagVideo, len(videos), len(inputPaths))
+			}
+			for _, v := range videos {
+				absVideo, err := fs.ResolveAbsPath(v)
+				if err != nil {
+					return err
+				}
+				d, err := extract.MediaDuration(ctx, absVideo)
+				if err != nil {
+					return fmt.Errorf("read duration of %s: %w", absVideo, err)
+				}
+				durations = append(durations, d)
+			}
+		}
+
+		absOut, err := fs.ResolveAbsPath(outputPath)
+		if err != nil {
+			return err
+		}
+		outputPath = absOut
+
+		if workdir != "" {
+			absWorkdir, err := fs.ResolveAbsPath(workdir)
+			if err != nil {
+				return err
+			}
+			workdir = absWorkdir
+		}
+
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "join")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		opts := join.Options{
+			InputPaths: inputPaths,
+			OutputPath: outputPath,
+			Offsets:    offsets,
+			Durations:  durations,
+			DryRun:     dryRun,
+			WorkDir:    runWorkdir,
+		}
+
+		log.Debug("running join", "opts", opts)
+
+		result, err := join.Run(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, part := range result.Parts {
+			log.Debug("subtitle part joined", "path", part.Path, "offset", srt.FormatTime(part.Offset), "cues", part.Cues)
+		}
+		for _, v := range result.Violations {
+			log.Warn("joined subtitles have a timing problem", "rule", v.Rule, "position", v.Position, "time", v.Time, "message", v.Message)
+		}
+		offsetsOut := make([]string, len(result.Parts))
+		for i, part := range result.Parts {
+			offsetsOut[i] = srt.FormatTime(part.Offset)
+		}
+		recordFile(joinFileResult{
+			Command:    "join",
+			Inputs:     inputPaths,
+			Output:     result.WrittenPath,
+			Offsets:    offsetsOut,
+			Cues:       result.Cues,
+			Violations: len(result.Violations),
+		})
+		log.Info("subtitle files joined", "path", result.WrittenPath, "files", len(result.Parts), "cues", result.Cues)
+
+		return nil
+	},
+}
+
+// joinFileResult is the --json entry of a joined file.
+type joinFileResult struct {
+	Command    string   `json:"command"`
+	Inputs     []string `json:"inputs"`
+	Output     string   `json:"output"`
+	Offsets    []string `json:"offsets"` // where every input starts in the output
+	Cues       int      `json:"cues"`
+	Violations int      `json:"violations"` // timing problems of the output
+}
+
+func init() {
+	joinCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path")
+	joinCmd.Flags().Bool(flagDryRun, false, "Write output to a temporary file instead of the output path")
+	joinCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	joinCmd.Flags().StringSlice(flagVideo, nil, "Video files of the parts, in order; a part without an offset starts after the video of the previous one (requires ffprobe)")
+	for n := 2; n <= maxOffsetFlag; n++ {
+		flag := flagOffset + strconv.Itoa(n)
+		usage := fmt.Sprintf("Start of input file %d in the output (e.g. 00:45:00 or 45m; defaults to where the previous file ends)", n)
+		if n == 2 {
+			usage = strings.TrimSuffix(usage, ")") + fmt.Sprintf("; --offset3 to --offset%d for the next files)", maxOffsetFlag)
+		}
+		joinCmd.Flags().String(flag, "", usage)
+		if n > 2 {
+			// Documented by --offset2, to keep the help short.
+			_ = joinCmd.Flags().MarkHidden(flag)
+		}
+	}
+	_ = joinCmd.MarkFlagRequired(flagOutput)
+}
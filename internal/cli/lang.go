@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/langdetect"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/spf13/cobra"
+)
+
+var langCmd = &cobra.Command{
+	Use:   "lang [flags] <input-file>...",
+	Short: "Print the detected language of subtitle files, optionally adding it to their file names",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+
+		log := logging.FromContext(cmd.Context())
+
+		format, _ := cmd.Flags().GetString(flagFormat)
+		rename, _ := cmd.Flags().GetBool(flagRename)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+
+		all := make([]langResult, 0, len(args))
+		for _, arg := range args {
+			path, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			res, err := naming.TagLanguage(naming.TagOptions{SubtitlePath: path, Rename: rename, DryRun: dryRun})
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			detected := res.Detected.Language
+			if res.Named != "" && detected != "" && !langdetect.Matches(detected, res.Named) {
+				log.Warn("file name language differs from the detected one", "path", path, "named", res.Named, "detected", detected)
+			}
+			if res.RenamedTo != "" {
+				recordFile(renameFileResult{Command: "lang", Input: path, Output: res.RenamedTo, Renamed: !dryRun})
+				if dryRun {
+					log.Info("subtitle file would be renamed (dry-run)", "from", path, "to", res.RenamedTo)
+				} else {
+					log.Info("subtitle file renamed", "from", path, "to", res.RenamedTo)
+				}
+			}
+			out := langResult{
+				Path:          arg,
+				Language:      detected,
+				NamedLanguage: res.Named,
+				RenamedTo:     res.RenamedTo,
+			}
+			if detected != "" {
+				out.Name = langdetect.Name(detected)
+				out.Confidence = math.Round(res.Detected.Confidence*100) / 100
+			}
+			all = append(all, out)
+		}
+
+		out := cmd.OutOrStdout()
+		if format == formatJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(all)
+		}
+		return writeLang(out, all)
+	},
+}
+
+// langResult is the detected language of a file.
+type langResult struct {
+	Path          string  `json:"path"`
+	Language      string  `json:"language,omitempty"` // ISO 639-1 code; empty when it can't be detected
+	Name          string  `json:"name,omitempty"`
+	Confidence    float64 `json:"confidence,omitempty"`
+	NamedLanguage string  `json:"named_language,omitempty"` // the language suffix of the file name
+	RenamedTo     string  `json:"renamed_to,omitempty"`
+}
+
+// writeLang writes a line per file: its path, the detected language and the
+// new path when renamed.
+func writeLang(w io.Writer, all []langResult) error {
+	var b strings.Builder
+	for _, r := range all {
+		if r.Language == "" {
+			_, _ = fmt.Fprintf(&b, "%s: unknown", r.Path)
+		} else {
+			_, _ = fmt.Fprintf(&b, "%s: %s (%s, confidence %.2f)", r.Path, r.Language, r.Name, r.Confidence)
+		}
+		if r.RenamedTo != "" {
+			_, _ = fmt.Fprintf(&b, " -> %s", r.RenamedTo)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func init() {
+	langCmd.Flags().String(flagFormat, formatText, "Output format: text or json")
+	langCmd.Flags().Bool(flagRename, false, "Add the detected language to file names without one (movie.srt -> movie.es.srt)")
+	langCmd.Flags().Bool(flagDryRun, false, "With --rename, only print the new names")
+}
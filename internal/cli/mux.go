@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/mux"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+)
+
+var muxCmd = &cobra.Command{
+	Use:   "mux [flags] <video-file> <subtitle-file>",
+	Short: "Embed a subtitle file into a video file as a new (or replacing) track",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		language, _ := cmd.Flags().GetString(flagLanguage)
+		title, _ := cmd.Flags().GetString(flagTitle)
+		isDefault, _ := cmd.Flags().GetBool(flagDefault)
+		forced, _ := cmd.Flags().GetBool(flagForced)
+		keepExisting, _ := cmd.Flags().GetBool(flagKeepExisting)
+
+		if args[0] == "-" || args[1] == "-" {
+			return errors.New("stdin is not supported; pass file paths")
+		}
+		videoPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		subtitlePath, err := fs.ResolveAbsPath(args[1])
+		if err != nil {
+			return err
+		}
+
+		if outputPath != "" {
+			absOut, err := fs.ResolveAbsPath(outputPath)
+			if err != nil {
+				return err
+			}
+			outputPath = absOut
+		}
+
+		if workdir != "" {
+			absWorkdir, err := fs.ResolveAbsPath(workdir)
+			if err != nil {
+				return err
+			}
+			workdir = absWorkdir
+		}
+
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "mux")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		opts := mux.Options{
+			VideoPath:    videoPath,
+			SubtitlePath: subtitlePath,
+			OutputPath:   outputPath,
+			DryRun:       dryRun,
+			WorkDir:      runWorkdir,
+			Language:     language,
+			Title:        title,
+			Default:      isDefault,
+			Forced:       forced,
+			KeepExisting: keepExisting,
+		}
+
+		log.Debug("running mux", "opts", opts)
+
+		result, err := mux.Run(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		recordFile(muxFileResult{
+			Command:        "mux",
+			Input:          subtitlePath,
+			Output:         result.WrittenPath,
+			Language:       result.Language,
+			ReplacedTracks: len(result.Replaced),
+			SubtitleTracks: result.Tracks,
+		})
+		log.Info("subtitle track muxed", "path", result.WrittenPath, "language", result.Language, "replaced_tracks", len(result.Replaced), "subtitle_tracks", result.Tracks)
+
+		return nil
+	},
+}
+
+// muxFileResult is the --json entry of a muxed video.
+type muxFileResult struct {
+	Command        string `json:"command"`
+	Input          string `json:"input"` // the subtitle file
+	Output         string `json:"output"`
+	Language       string `json:"language"`
+	ReplacedTracks int    `json:"replaced_tracks"`
+	SubtitleTracks int    `json:"subtitle_tracks"`
+}
+
+func init() {
+	muxCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to replacing the video file)")
+	muxCmd.Flags().Bool(flagDryRun, false, "Write output to a temporary file and do not overwrite the original")
+	muxCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	muxCmd.Flags().String(flagLanguage, "", "Language of the subtitle track (e.g. es or spa; defaults to the subtitle file name suffix, e.g. movie.es.srt)")
+	muxCmd.Flags().String(flagTitle, "", "Title of the subtitle track")
+	muxCmd.Flags().Bool(flagDefault, false, "Mark the subtitle track as default (clears the flag on the other subtitle tracks)")
+	muxCmd.Flags().Bool(flagForced, false, "Mark the subtitle track as forced")
+	muxCmd.Flags().Bool(flagKeepExisting, false, "Keep existing subtitle tracks in the same language instead of replacing them")
+}
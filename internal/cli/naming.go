@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/spf13/cobra"
+)
+
+// addNamingFlags registers the media server naming flags.
+func addNamingFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().Bool(flagPlexNaming, false, usage+" using Plex naming (e.g. Movie (2020).es.forced.srt)")
+	cmd.Flags().Bool(flagJellyfinNaming, false, usage+" using Jellyfin naming (e.g. Movie (2020).spa.forced.srt)")
+}
+
+// namingSchemeFromFlags returns the naming scheme selected with the naming
+// flags, or "" when none is set.
+func namingSchemeFromFlags(cmd *cobra.Command) (string, error) {
+	plex, _ := cmd.Flags().GetBool(flagPlexNaming)
+	jellyfin, _ := cmd.Flags().GetBool(flagJellyfinNaming)
+	switch {
+	case plex && jellyfin:
+		return "", fmt.Errorf("--%s and --%s are mutually exclusive", flagPlexNaming, flagJellyfinNaming)
+	case plex:
+		return naming.SchemePlex, nil
+	case jellyfin:
+		return naming.SchemeJellyfin, nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"github.com/adrianmusante/subtitle-tools/internal/httpclient"
+	"github.com/spf13/cobra"
+)
+
+// addNetworkFlags registers the proxy and TLS flags shared by the commands that
+// call remote APIs.
+func addNetworkFlags(cmd *cobra.Command) {
+	_ = cmd.Flags().String(flagProxy, "", "Proxy URL for API requests (http, https or socks5). Defaults to HTTPS_PROXY/HTTP_PROXY/NO_PROXY")
+	_ = cmd.Flags().String(flagCACert, "", "PEM file with extra CA certificates to trust (e.g. a corporate TLS proxy)")
+	_ = cmd.Flags().Bool(flagInsecureSkipVerify, false, "Disable TLS certificate verification (insecure; for testing only)")
+}
+
+// networkOptionsFromFlags resolves the network flags (and their env vars).
+func networkOptionsFromFlags(cmd *cobra.Command) (httpclient.Options, error) {
+	if err := resolveStringFlagFromEnv(cmd, flagProxy, envProxy); err != nil {
+		return httpclient.Options{}, err
+	}
+	if err := resolveStringFlagFromEnv(cmd, flagCACert, envCACert); err != nil {
+		return httpclient.Options{}, err
+	}
+	if err := resolveBoolFlagFromEnv(cmd, flagInsecureSkipVerify, envInsecureSkipVerify); err != nil {
+		return httpclient.Options{}, err
+	}
+	proxy, _ := cmd.Flags().GetString(flagProxy)
+	caCert, _ := cmd.Flags().GetString(flagCACert)
+	insecure, _ := cmd.Flags().GetBool(flagInsecureSkipVerify)
+	return httpclient.Options{ProxyURL: proxy, CACertFile: caCert, InsecureSkipVerify: insecure}, nil
+}
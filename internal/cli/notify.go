@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/update"
+	"github.com/spf13/cobra"
+)
+
+// updateNotifyTimeout bounds the background release query of --update-notify.
+const updateNotifyTimeout = 10 * time.Second
+
+// startUpdateNotify starts the --update-notify check and returns a function,
+// called when the command ends, that tells whether a newer release exists.
+// The version found by the last check is used until a new one is due, which
+// runs in the background: it is never waited for, so if it hasn't finished
+// when the command ends, the next command retries it.
+func startUpdateNotify(cmd *cobra.Command) func() {
+	if on, _ := cmd.Flags().GetBool(flagUpdateNotify); !on || version == "" || configCommand(cmd) == "update" {
+		return func() {}
+	}
+	log := logging.FromContext(cmd.Context())
+	statePath, err := update.DefaultNotifyStatePath()
+	if err != nil {
+		log.Debug("update check skipped", "err", err)
+		return func() {}
+	}
+	notify := func(v string) {
+		if v != "" {
+			log.Info("a new version of subtitle-tools is available (run: subtitle-tools update)", "current", version, "version", v)
+		}
+	}
+
+	cached, due := update.NotifyCached(statePath, version)
+	if !due {
+		return func() { notify(cached) }
+	}
+	found := make(chan string, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), updateNotifyTimeout)
+		defer cancel()
+		v, err := update.Notify(ctx, update.Options{CurrentVersion: version}, statePath)
+		if err != nil {
+			log.Debug("update check failed", "err", err)
+			v = cached
+		}
+		found <- v
+	}()
+	return func() {
+		select {
+		case v := <-found:
+			notify(v)
+		default:
+			notify(cached)
+		}
+	}
+}
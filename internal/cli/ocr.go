@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/httpclient"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/ocr"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+var ocrCmd = &cobra.Command{
+	Use:   "ocr [flags] <bitmap-subtitle-file>",
+	Short: "Convert bitmap subtitles (PGS .sup, VobSub .idx/.sub) to SRT with an OCR engine",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagEngine, envOCREngine); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagApiKey, envOCRAPIKey); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagModel, envOCRModel); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagURL, envOCRBaseURL); err != nil {
+			return err
+		}
+		if err := resolveIntFlagFromEnv(cmd, flagBatchSize, envOCRBatchSize); err != nil {
+			return err
+		}
+		if err := resolveDurationFlagFromEnv(cmd, flagRequestTimeout, envOCRRequestTimeout); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagCacheDir, envOCRCacheDir); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagNoCache, envOCRNoCache); err != nil {
+			return err
+		}
+		if err := resolveFloat64FlagFromEnv(cmd, flagTokenPrice, envOCRTokenPrice); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		engine, _ := cmd.Flags().GetString(flagEngine)
+		language, _ := cmd.Flags().GetString(flagLanguage)
+		batchSize, _ := cmd.Flags().GetInt(flagBatchSize)
+		tokenPrice, _ := cmd.Flags().GetFloat64(flagTokenPrice)
+
+		engine = ocr.NormalizeEngine(engine)
+		if !ocr.IsValidEngine(engine) {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagEngine, engine, ocr.EngineTesseract, ocr.EngineVision)
+		}
+		if batchSize < 1 {
+			return fmt.Errorf("invalid --%s %d (must be >= 1)", flagBatchSize, batchSize)
+		}
+		if tokenPrice < 0 {
+			return fmt.Errorf("invalid --%s %g (must be >= 0)", flagTokenPrice, tokenPrice)
+		}
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		if outputPath != "" {
+			if outputPath, err = fs.ResolveAbsPath(outputPath); err != nil {
+				return err
+			}
+		}
+		if workdir != "" {
+			if workdir, err = fs.ResolveAbsPath(workdir); err != nil {
+				return err
+			}
+		}
+
+		opts := ocr.Options{
+			InputPath:  inputPath,
+			OutputPath: outputPath,
+			Engine:     engine,
+			Language:   language,
+			BatchSize:  batchSize,
+		}
+		if engine == ocr.EngineVision {
+			if opts.Client, err = visionClientFromFlags(cmd); err != nil {
+				return err
+			}
+			if noCache, _ := cmd.Flags().GetBool(flagNoCache); !noCache {
+				cacheDir, _ := cmd.Flags().GetString(flagCacheDir)
+				if cacheDir == "" {
+					if cacheDir, err = ocr.DefaultCacheDir(); err != nil {
+						log.Warn("OCR cache disabled: cannot resolve user cache dir", "err", err)
+					}
+				}
+				if cacheDir != "" {
+					if cacheDir, err = fs.ResolveAbsPath(cacheDir); err != nil {
+						return err
+					}
+				}
+				opts.CacheDir = cacheDir
+			}
+		}
+
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "ocr")
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		log.Debug("using workdir", "workdir", runWorkdir)
+
+		opts.WorkDir = runWorkdir
+		result, err := ocr.Run(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		fileResult := ocrFileResult{
+			Command:  "ocr",
+			Input:    inputPath,
+			Output:   result.WrittenPath,
+			Language: result.Language,
+			Cues:     result.Cues,
+			Empty:    result.Empty,
+			Tokens:   result.Tokens,
+			Cached:   result.Cached,
+		}
+		if tokenPrice > 0 && engine == ocr.EngineVision {
+			cost := float64(result.Tokens) / 1e6 * tokenPrice
+			fileResult.Cost = &cost
+		}
+		recordFile(fileResult)
+		log.Info("bitmap subtitles recognized", "path", result.WrittenPath, "language", result.Language, "cues", result.Cues, "empty", result.Empty)
+		if engine == ocr.EngineVision {
+			log.Info("vision model usage", "tokens", result.Tokens, "cached_images", result.Cached)
+		}
+		return nil
+	},
+}
+
+// visionClientFromFlags returns the client of the vision engine from --model,
+// --api-key, --url and the network flags.
+func visionClientFromFlags(cmd *cobra.Command) (*translate.OpenAIClient, error) {
+	model, _ := cmd.Flags().GetString(flagModel)
+	if model == "" {
+		return nil, fmt.Errorf("--%s is required with --%s %s (a model that accepts images, e.g. gpt-4o-mini)", flagModel, flagEngine, ocr.EngineVision)
+	}
+	apiKey, _ := cmd.Flags().GetString(flagApiKey)
+	baseURL, _ := cmd.Flags().GetString(flagURL)
+	requestTimeout, _ := cmd.Flags().GetDuration(flagRequestTimeout)
+	netOpts, err := networkOptionsFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := httpclient.NewTransport(netOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &translate.OpenAIClient{
+		BaseURL:      baseURL,
+		APIKey:       apiKey,
+		Model:        model,
+		Transport:    transport,
+		Timeout:      requestTimeout,
+		RetryOptions: translate.DefaultRetryOptions(),
+	}, nil
+}
+
+// ocrFileResult is the --json entry of a recognized subtitle file.
+type ocrFileResult struct {
+	Command  string `json:"command"`
+	Input    string `json:"input"`
+	Output   string `json:"output"`
+	Language string `json:"language,omitempty"`
+	Cues     int    `json:"cues"`
+	Empty    int    `json:"empty"`
+	// Vision engine usage.
+	Tokens int64    `json:"tokens,omitempty"`
+	Cached int      `json:"cached,omitempty"` // images whose text came from the cache
+	Cost   *float64 `json:"cost,omitempty"`   // with --token-price
+}
+
+func init() {
+	ocrCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to <input>.srt next to the input)")
+	ocrCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	ocrCmd.Flags().String(flagEngine, ocr.DefaultEngine, "OCR engine: tesseract, vision")
+	ocrCmd.Flags().String(flagLanguage, "", "Language of the text (e.g. en, es; defaults to the VobSub index, the file name suffix, or en)")
+	ocrCmd.Flags().String(flagModel, "", "Model of the vision engine, which must accept images (e.g. gpt-4o-mini, gemini-flash-latest, ollama:llava)")
+	ocrCmd.Flags().String(flagApiKey, "", "API key of the vision engine. A comma-separated list of keys can be provided to distribute requests across multiple keys")
+	ocrCmd.Flags().String(flagURL, "", "Base URL for the API endpoint of the vision engine (optional; inferred from --model if omitted)")
+	ocrCmd.Flags().Int(flagBatchSize, ocr.DefaultBatchSize, "Images sent in each request of the vision engine")
+	ocrCmd.Flags().Duration(flagRequestTimeout, translate.DefaultRequestTimeout, "HTTP request timeout duration of the vision engine (e.g. 30s, 1m; 0 disables timeout)")
+	ocrCmd.Flags().String(flagCacheDir, "", "Cache of the texts recognized by the vision engine, by image (default: <user cache dir>/subtitle-tools/ocr)")
+	ocrCmd.Flags().Bool(flagNoCache, false, "Disable the OCR cache (always call the vision model)")
+	ocrCmd.Flags().Float64(flagTokenPrice, 0, "Price per million tokens of the vision engine, used to report the cost in the --json result (0 omits it)")
+	addNetworkFlags(ocrCmd)
+}
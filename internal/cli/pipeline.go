@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Pipeline steps.
+const (
+	stepFix       = "fix"
+	stepTranslate = "translate"
+)
+
+const defaultPipelineSteps = stepFix + "," + stepTranslate + "," + stepFix
+
+// pipelineFlags are the flags of the pipeline itself, never passed to a step.
+// The hooks run once, when the pipeline ends.
+var pipelineFlags = map[string]bool{
+	flagOutput: true, flagDryRun: true, flagWorkdir: true, flagSteps: true,
+	flagOnSuccess: true, flagOnFailure: true,
+}
+
+// skippedStepFlags are the step flags that don't apply to a pipeline: every
+// step reads the previous result and writes the next one.
+var skippedStepFlags = map[string]bool{
+	flagPlexNaming: true, flagJellyfinNaming: true,
+	flagInclude: true, flagJobs: true, flagRecursive: true,
+	flagDiff: true, flagSkipBackup: true,
+	// The cue indexes and times change between steps.
+	flagCues: true, flagRange: true,
+}
+
+// firstStepFlags select the cues, so only the first step gets them: the
+// others read its output, which may have lost the tags they go by (e.g.
+// {\forced} with --strip-ass-tags).
+var firstStepFlags = map[string]bool{
+	flagOnlyForced: true, flagSkipSDH: true,
+}
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline [flags] <input-file>",
+	Short: "Run fix and translate steps in one go (e.g. fix, translate, fix), sharing one workdir",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		stepsFlag, _ := cmd.Flags().GetString(flagSteps)
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		targetLang, _ := cmd.Flags().GetString(flagTargetLanguage)
+
+		steps, err := parsePipelineSteps(stepsFlag)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(steps, stepTranslate) {
+			if strings.TrimSpace(targetLang) == "" {
+				return fmt.Errorf("--%s is required by the %s step", flagTargetLanguage, stepTranslate)
+			}
+			if strings.Contains(targetLang, ",") {
+				return fmt.Errorf("--%s must be a single language in a pipeline", flagTargetLanguage)
+			}
+		}
+
+		if args[0] == "-" {
+			return errors.New("stdin is not supported yet; pass a subtitle file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		if outputPath == "" {
+			return errors.New("--output is required and must not exist")
+		}
+		if outputPath, err = resolveNewOutputPath(outputPath); err != nil {
+			return err
+		}
+
+		if workdir != "" {
+			if workdir, err = fs.ResolveAbsPath(workdir); err != nil {
+				return err
+			}
+		}
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "pipeline")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		_, name := naming.Parse(inputPath)
+		current, err := runSteps(ctx, cmd, steps, pipelineFlags, inputPath, name.Language, strings.TrimSpace(targetLang), runWorkdir)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			recordFile(pipelineResult{Command: "pipeline", Input: inputPath, Output: current, Steps: steps})
+			log.Info("pipeline output written (dry-run)", "path", current)
+			return nil
+		}
+		if err := fs.MoveFile(current, outputPath); err != nil {
+			return err
+		}
+		recordFile(pipelineResult{Command: "pipeline", Input: inputPath, Output: outputPath, Steps: steps})
+		log.Info("pipeline output written", "path", outputPath)
+		return nil
+	},
+}
+
+// pipelineResult is the --json entry of the pipeline output, after the entries
+// of its steps (which write to the workdir).
+type pipelineResult struct {
+	Command string   `json:"command"`
+	Input   string   `json:"input"`
+	Output  string   `json:"output"`
+	Steps   []string `json:"steps"`
+}
+
+func parsePipelineSteps(s string) ([]string, error) {
+	var steps []string
+	translates := 0
+	for _, step := range strings.Split(s, ",") {
+		step = strings.ToLower(strings.TrimSpace(step))
+		switch step {
+		case stepFix:
+		case stepTranslate:
+			translates++
+		default:
+			return nil, fmt.Errorf("invalid --%s step %q (supported: %s, %s)", flagSteps, step, stepFix, stepTranslate)
+		}
+		steps = append(steps, step)
+	}
+	if translates > 1 {
+		return nil, fmt.Errorf("--%s can't translate more than once", flagSteps)
+	}
+	return steps, nil
+}
+
+// newStepCommand returns a fresh copy of the fix or translate command, so a
+// step runs exactly as the command would with the same flags.
+func newStepCommand(step string) *cobra.Command {
+	template, register := fixCmd, registerFixFlags
+	if step == stepTranslate {
+		template, register = translateCmd, registerTranslateFlags
+	}
+	cmd := &cobra.Command{
+		Use:           template.Use,
+		Args:          template.Args,
+		RunE:          template.RunE,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	register(cmd)
+	return cmd
+}
+
+// runSteps runs steps one after the other from inputPath, in language (empty
+// when unknown), each reading the result of the previous one and writing its
+// own to workdir, and returns the path of the last result. The steps get the
+// flags of parent that were set and that they understand, except own (the
+// flags of parent itself), and the defaults of the config file.
+func runSteps(ctx context.Context, parent *cobra.Command, steps []string, own map[string]bool, inputPath, language, targetLang, workdir string) (string, error) {
+	log := logging.FromContext(ctx)
+	// Intermediate files keep the language suffix, which fix reads as its
+	// default --language.
+	current := inputPath
+	for i, step := range steps {
+		if step == stepTranslate {
+			language = targetLang
+		}
+		next := fmt.Sprintf("%02d-%s", i+1, step)
+		if language != "" {
+			next += "." + language
+		}
+		next = filepath.Join(workdir, next+".srt")
+
+		first := i == 0
+		pass := func(name string) bool {
+			return !own[name] && (!firstStepFlags[name] || first)
+		}
+		log.Info("running step", "step", step, "n", fmt.Sprintf("%d/%d", i+1, len(steps)))
+		if err := runStep(ctx, parent, step, pass, []string{"--" + flagOutput, next, "--" + flagWorkdir, workdir, current}); err != nil {
+			return "", fmt.Errorf("step %d (%s): %w", i+1, step, err)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// runStep runs step with args, passing it the flags of parent that were set,
+// that the step understands and that pass accepts. The step reads its own
+// config section; the section of parent, more specific, takes precedence.
+func runStep(ctx context.Context, parent *cobra.Command, step string, pass func(name string) bool, args []string) error {
+	stepCmd := newStepCommand(step)
+	var err error
+	parent.Flags().Visit(func(f *pflag.Flag) {
+		dst := stepCmd.Flags().Lookup(f.Name)
+		if err != nil || dst == nil || !pass(f.Name) {
+			return
+		}
+		if src, ok := f.Value.(pflag.SliceValue); ok {
+			if err = dst.Value.(pflag.SliceValue).Replace(src.GetSlice()); err == nil {
+				dst.Changed = true
+			}
+			return
+		}
+		err = stepCmd.Flags().Set(f.Name, f.Value.String())
+	})
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(parent)
+	if err != nil {
+		return err
+	}
+	if err := applyConfig(stepCmd, cfg, step, parent.Name()); err != nil {
+		return err
+	}
+	stepCmd.SetArgs(args)
+	stepCmd.SetOut(parent.OutOrStdout())
+	return stepCmd.ExecuteContext(ctx)
+}
+
+// addStepFlags adds the flags of the fix and translate commands to cmd. The
+// flags both commands define (e.g. --max-line-len) are passed to both steps.
+func addStepFlags(cmd *cobra.Command) {
+	for _, step := range []string{stepTranslate, stepFix} {
+		newStepCommand(step).Flags().VisitAll(func(f *pflag.Flag) {
+			if skippedStepFlags[f.Name] || cmd.Flags().Lookup(f.Name) != nil {
+				return
+			}
+			// Required flags are only required by their step.
+			delete(f.Annotations, cobra.BashCompOneRequiredFlag)
+			cmd.Flags().AddFlag(f)
+		})
+	}
+}
+
+func init() {
+	registerPipelineFlags(pipelineCmd)
+}
+
+func registerPipelineFlags(cmd *cobra.Command) {
+	cmd.Flags().String(flagSteps, defaultPipelineSteps, "Comma-separated steps run in order: fix, translate (at most once)")
+	cmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (required; must not already exist)")
+	cmd.Flags().Bool(flagDryRun, false, "Leave the output in the workdir and do not create the final output file")
+	cmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base, shared by every step. If set, a unique subdirectory is created per run")
+	addStepFlags(cmd)
+}
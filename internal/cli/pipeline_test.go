@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPipelineCLI_FixTranslateFix(t *testing.T) {
+	// Steps read the config file; keep the user's one out of the test.
+	t.Setenv(envConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	// A DeepL stand-in that "translates" by upper-casing.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text []string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type translation struct {
+			Text string `json:"text"`
+		}
+		var resp struct {
+			Translations []translation `json:"translations"`
+		}
+		for _, text := range req.Text {
+			resp.Translations = append(resp.Translations, translation{Text: strings.ToUpper(text)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "movie.en.srt")
+	orig := "1\n00:00:01,000 --> 00:00:02,000\n<b>Hello</b> there\n\n2\n00:00:03,000 --> 00:00:04,000\n[door slams]\n\n"
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	output := filepath.Join(dir, "movie.es.srt")
+
+	cmd := newPipelineTestCommand()
+	cmd.SetArgs([]string{
+		"--target-language", "es", "--provider", "deepl", "--url", server.URL, "--api-key", "k", "--no-cache",
+		"--strip-style", "--strip-hi", "--progress", "off", "-o", output, input,
+	})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+
+	want := "1\n00:00:01,000 --> 00:00:02,000\nHELLO THERE\n\n"
+	if b, err := os.ReadFile(output); err != nil || string(b) != want {
+		t.Fatalf("unexpected output %q (err %v)", b, err)
+	}
+	if b, err := os.ReadFile(input); err != nil || string(b) != orig {
+		t.Fatalf("expected the input to be untouched, got %q (err %v)", b, err)
+	}
+
+	// The output is never overwritten.
+	cmd = newPipelineTestCommand()
+	cmd.SetArgs([]string{"--steps", "fix", "-o", output, input})
+	if err := cmd.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an existing output error, got %v", err)
+	}
+}
+
+func TestParsePipelineSteps(t *testing.T) {
+	if steps, err := parsePipelineSteps(" Fix, translate ,fix"); err != nil || strings.Join(steps, ",") != "fix,translate,fix" {
+		t.Fatalf("parsePipelineSteps = %v, %v", steps, err)
+	}
+	for _, s := range []string{"fix,translate,translate", "fix,extract", ""} {
+		if _, err := parsePipelineSteps(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
+
+func newPipelineTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           pipelineCmd.Use,
+		Args:          pipelineCmd.Args,
+		RunE:          pipelineCmd.RunE,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	registerPipelineFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+func addProgressFlag(cmd *cobra.Command) {
+	_ = cmd.Flags().String(flagProgress, progress.DefaultMode, "Progress output: auto (bar on a terminal, log records otherwise), bar, log, off")
+}
+
+type progressRecorderKey struct{}
+
+// withProgressRecorder makes the commands run with ctx also pass their
+// progress to r, whatever --progress says (e.g. a job saving it to disk).
+func withProgressRecorder(ctx context.Context, r progress.Reporter) context.Context {
+	return context.WithValue(ctx, progressRecorderKey{}, r)
+}
+
+// newProgressReporter resolves --progress. In bar mode the logger is replaced
+// by one writing through the bar, so log records don't break the bar line.
+func newProgressReporter(cmd *cobra.Command) (progress.Reporter, error) {
+	reporter, err := newModeReporter(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := cmd.Context().Value(progressRecorderKey{}).(progress.Reporter); ok {
+		return progress.Multi{reporter, r}, nil
+	}
+	return reporter, nil
+}
+
+func newModeReporter(cmd *cobra.Command) (progress.Reporter, error) {
+	if err := resolveStringFlagFromEnv(cmd, flagProgress, envProgress); err != nil {
+		return nil, err
+	}
+	mode, _ := cmd.Flags().GetString(flagProgress)
+	mode = progress.NormalizeMode(mode)
+	if mode == "" {
+		mode = progress.DefaultMode
+	}
+	if !progress.IsValidMode(mode) {
+		return nil, fmt.Errorf("invalid --%s %q (supported: %s, %s, %s, %s)", flagProgress, mode, progress.ModeAuto, progress.ModeBar, progress.ModeLog, progress.ModeOff)
+	}
+
+	switch progress.ResolveMode(mode, os.Stderr) {
+	case progress.ModeBar:
+		bar := progress.NewBar(os.Stderr)
+		setLogger(cmd, logging.New(bar, logLevel()))
+		return bar, nil
+	case progress.ModeLog:
+		return progress.NewLog(logging.FromContext(cmd.Context()), progress.DefaultLogInterval), nil
+	default:
+		return progress.Nop{}, nil
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename [flags] <subtitle-file>",
+	Short: "Rename a subtitle file after its video, following media server naming (Plex, Jellyfin)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		videoPath, _ := cmd.Flags().GetString(flagVideo)
+		language, _ := cmd.Flags().GetString(flagLanguage)
+		forced, _ := cmd.Flags().GetBool(flagForced)
+		sdh, _ := cmd.Flags().GetBool(flagSDH)
+
+		scheme, err := namingSchemeFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if scheme == "" {
+			scheme = naming.SchemePlex
+		}
+
+		subtitlePath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		if videoPath != "" {
+			if videoPath, err = fs.ResolveAbsPath(videoPath); err != nil {
+				return err
+			}
+		}
+
+		result, err := naming.Rename(naming.RenameOptions{
+			SubtitlePath: subtitlePath,
+			VideoPath:    videoPath,
+			Scheme:       scheme,
+			Language:     language,
+			Forced:       forced,
+			SDH:          sdh,
+			DryRun:       dryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		recordFile(renameFileResult{Command: "rename", Input: result.From, Output: result.To, Renamed: result.From != result.To && !dryRun})
+		switch {
+		case result.From == result.To:
+			log.Info("subtitle file already named after its video", "path", result.To)
+		case dryRun:
+			log.Info("subtitle file would be renamed (dry-run)", "from", result.From, "to", result.To)
+		default:
+			log.Info("subtitle file renamed", "from", result.From, "to", result.To)
+		}
+		return nil
+	},
+}
+
+// renameFileResult is the --json entry of a renamed file.
+type renameFileResult struct {
+	Command string `json:"command"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	Renamed bool   `json:"renamed"` // false when already named so, or with --dry-run
+}
+
+func init() {
+	addNamingFlags(renameCmd, "Rename (Plex by default)")
+	renameCmd.Flags().Bool(flagDryRun, false, "Only print the new name")
+	renameCmd.Flags().String(flagVideo, "", "Video file the subtitle belongs to (optional; defaults to the video next to the subtitle)")
+	renameCmd.Flags().String(flagLanguage, "", "Language of the subtitle (optional; defaults to the file name suffix or the detected language)")
+	renameCmd.Flags().Bool(flagForced, false, "Mark the subtitle as forced")
+	renameCmd.Flags().Bool(flagSDH, false, "Mark the subtitle as SDH (for the deaf and hard of hearing)")
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// runResult is the document printed by --json when a command ends: what the
+// command did, for programs calling the tool. Logs still go to stderr.
+type runResult struct {
+	Command    string `json:"command"`
+	OK         bool   `json:"ok"`
+	ExitCode   int    `json:"exit_code"`
+	ExitReason string `json:"exit_reason"`
+	Error      string `json:"error,omitempty"`
+	// Files has one entry per file written (or failed) by the command.
+	Files    []any        `json:"files"`
+	Warnings []runWarning `json:"warnings"`
+	// Output is what the command prints on stdout (e.g. stats or validate):
+	// the JSON document itself with --format json, a string otherwise.
+	Output json.RawMessage `json:"output,omitempty"`
+
+	mu     sync.Mutex
+	stdout bytes.Buffer
+}
+
+// runWarning is a warning logged during the run.
+type runWarning struct {
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// fileError is the entry of a file that failed in batch mode.
+type fileError struct {
+	Command string `json:"command"`
+	Input   string `json:"input"`
+	Error   string `json:"error"`
+}
+
+// currentResult collects the result of the running command; nil without
+// --json.
+var currentResult *runResult
+
+func newRunResult(command string) *runResult {
+	return &runResult{Command: command, Files: []any{}, Warnings: []runWarning{}}
+}
+
+// startResult enables the --json result of cmd, capturing its stdout.
+func startResult(cmd *cobra.Command) {
+	currentResult = newRunResult(cmd.CommandPath())
+	cmd.SetOut(&lockedWriter{mu: &currentResult.mu, w: &currentResult.stdout})
+}
+
+// recordFile adds a file entry to the --json result, if enabled.
+func recordFile(entry any) {
+	r := currentResult
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Files = append(r.Files, entry)
+}
+
+// writeResult completes the --json result with the outcome of the command and
+// writes it to w. It does nothing without --json.
+func writeResult(w io.Writer, runErr error) error {
+	r := currentResult
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.OK = runErr == nil
+	r.ExitCode, r.ExitReason = exitStatus(runErr)
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	if out := bytes.TrimSpace(r.stdout.Bytes()); len(out) > 0 {
+		if json.Valid(out) {
+			r.Output = out
+		} else if b, err := json.Marshal(r.stdout.String()); err == nil {
+			r.Output = b
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// lockedWriter serializes the writes of concurrent files.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// warningRecorder is a slog.Handler that adds the warnings to the --json
+// result before passing them on.
+type warningRecorder struct {
+	slog.Handler
+	result *runResult
+	attrs  []slog.Attr
+}
+
+func (h *warningRecorder) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level >= slog.LevelWarn {
+		w := runWarning{Message: rec.Message}
+		add := func(a slog.Attr) bool {
+			if w.Fields == nil {
+				w.Fields = make(map[string]string)
+			}
+			w.Fields[a.Key] = a.Value.Resolve().String()
+			return true
+		}
+		for _, a := range h.attrs {
+			add(a)
+		}
+		rec.Attrs(add)
+		h.result.mu.Lock()
+		h.result.Warnings = append(h.result.Warnings, w)
+		h.result.mu.Unlock()
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *warningRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &warningRecorder{Handler: h.Handler.WithAttrs(attrs), result: h.result, attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *warningRecorder) WithGroup(name string) slog.Handler {
+	return &warningRecorder{Handler: h.Handler.WithGroup(name), result: h.result, attrs: h.attrs}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWriteResult(t *testing.T) {
+	t.Cleanup(func() { currentResult = nil })
+
+	cmd := &cobra.Command{Use: "t"}
+	startResult(cmd)
+	log := slog.New(&warningRecorder{Handler: slog.NewTextHandler(io.Discard, nil), result: currentResult}).With("path", "a.srt")
+	log.Info("not recorded")
+	log.Warn("skipping file", "err", errors.New("boom"))
+	recordFile(fileError{Command: "fix", Input: "a.srt", Error: "boom"})
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), "text output")
+
+	var buf bytes.Buffer
+	if err := writeResult(&buf, errors.New("1 of 1 files failed: a.srt")); err != nil {
+		t.Fatalf("writeResult: %v", err)
+	}
+	var got struct {
+		Command    string          `json:"command"`
+		OK         bool            `json:"ok"`
+		ExitReason string          `json:"exit_reason"`
+		Error      string          `json:"error"`
+		Files      []fileError     `json:"files"`
+		Warnings   []runWarning    `json:"warnings"`
+		Output     json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, buf.String())
+	}
+	if got.Command != "t" || got.OK || got.ExitReason != exitReasonError || got.Error == "" {
+		t.Fatalf("unexpected result: %s", buf.String())
+	}
+	if len(got.Files) != 1 || got.Files[0].Input != "a.srt" {
+		t.Fatalf("unexpected files: %+v", got.Files)
+	}
+	if len(got.Warnings) != 1 || got.Warnings[0].Message != "skipping file" || got.Warnings[0].Fields["path"] != "a.srt" || got.Warnings[0].Fields["err"] != "boom" {
+		t.Fatalf("unexpected warnings: %+v", got.Warnings)
+	}
+	if string(got.Output) != `"text output\n"` {
+		t.Fatalf("unexpected output: %s", got.Output)
+	}
+
+	// JSON printed by the command is embedded as is.
+	cmd = &cobra.Command{Use: "t"}
+	startResult(cmd)
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), `{"cues": 2}`)
+	buf.Reset()
+	if err := writeResult(&buf, nil); err != nil {
+		t.Fatalf("writeResult: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	var output struct {
+		Cues int `json:"cues"`
+	}
+	if err := json.Unmarshal(got.Output, &output); err != nil || !got.OK || got.ExitReason != exitReasonSuccess || output.Cues != 2 {
+		t.Fatalf("unexpected result: %s", buf.String())
+	}
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/retranslate"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// retranslateFlags are the flags of retranslate itself, never passed to the
+// translate step. The hooks run once, when retranslate ends.
+var retranslateFlags = map[string]bool{
+	flagCues: true, flagSource: true, flagOutput: true, flagSkipBackup: true, flagWorkdir: true,
+	flagOnSuccess: true, flagOnFailure: true,
+}
+
+// retranslateSkippedFlags are the translate flags that don't apply: the cues
+// are read from the source and patched back into the translation one by one,
+// and the cache would return the translations being replaced.
+var retranslateSkippedFlags = map[string]bool{
+	flagPlexNaming: true, flagJellyfinNaming: true,
+	flagInclude: true, flagJobs: true, flagRecursive: true,
+	flagDryRun: true, flagSideBySide: true, flagRange: true,
+	flagPreserveIndex: true, flagOnlyForced: true, flagSkipSDH: true,
+	flagCacheDir: true, flagNoCache: true,
+}
+
+var retranslateCmd = &cobra.Command{
+	Use:   "retranslate [flags] <translated-file>",
+	Short: "Translate some cues of a translation again, picked from a report or a list of indexes, patching them into the file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		cuesSpec, _ := cmd.Flags().GetString(flagCues)
+		sourcePath, _ := cmd.Flags().GetString(flagSource)
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		skipBackup, _ := cmd.Flags().GetBool(flagSkipBackup)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		if sourcePath, err = fs.ResolveAbsPath(sourcePath); err != nil {
+			return err
+		}
+		if outputPath != "" {
+			if outputPath, err = fs.ResolveAbsPath(outputPath); err != nil {
+				return err
+			}
+		}
+		if !flagSet(cmd, flagTargetLanguage) {
+			// The translation is usually named after its language.
+			if _, name := naming.Parse(inputPath); name.Language != "" {
+				_ = cmd.Flags().Set(flagTargetLanguage, name.Language)
+			}
+		}
+		if lang, _ := cmd.Flags().GetString(flagTargetLanguage); strings.Contains(lang, ",") {
+			return fmt.Errorf("--%s: a translation has a single language", flagTargetLanguage)
+		}
+
+		sel, err := retranslate.Selection(cuesSpec, inputPath)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", flagCues, err)
+		}
+		translation, err := readSubtitles(inputPath)
+		if err != nil {
+			return err
+		}
+		source, err := readSubtitles(sourcePath)
+		if err != nil {
+			return err
+		}
+		cues, err := retranslate.SourceCues(translation, source, sel)
+		if err != nil {
+			return err
+		}
+
+		if workdir != "" {
+			if workdir, err = fs.ResolveAbsPath(workdir); err != nil {
+				return err
+			}
+		}
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "retranslate")
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		log.Debug("using workdir", "workdir", runWorkdir)
+
+		// The cues keep the numbers of the translation, so the new
+		// translations are patched back by index.
+		selectedPath := filepath.Join(runWorkdir, filepath.Base(sourcePath))
+		var buf bytes.Buffer
+		if err := srt.WriteAllIndexed(&buf, cues); err != nil {
+			return err
+		}
+		if err := fs.WriteFile(&buf, selectedPath); err != nil {
+			return err
+		}
+		translatedPath := filepath.Join(runWorkdir, "retranslated.srt")
+		log.Info("translating cues again", "cues", len(cues))
+		pass := func(name string) bool { return !retranslateFlags[name] }
+		stepArgs := []string{
+			"--" + flagOutput, translatedPath, "--" + flagWorkdir, runWorkdir,
+			"--" + flagPreserveIndex, "--" + flagNoCache, "--" + flagDryRun + "=false", selectedPath,
+		}
+		if err := runStep(ctx, cmd, stepTranslate, pass, stepArgs); err != nil {
+			return err
+		}
+
+		retranslated, err := readSubtitles(translatedPath)
+		if err != nil {
+			return err
+		}
+		texts := make(map[int]string, len(retranslated))
+		for _, c := range retranslated {
+			texts[c.Idx] = c.Text
+		}
+		result, err := retranslate.Patch(retranslate.Options{
+			InputPath:    inputPath,
+			Texts:        texts,
+			OutputPath:   outputPath,
+			BackupExt:    ".bak",
+			CreateBackup: !skipBackup,
+		})
+		if err != nil {
+			return err
+		}
+		recordFile(retranslateFileResult{
+			Command:  "retranslate",
+			Input:    inputPath,
+			Source:   sourcePath,
+			Output:   result.WrittenPath,
+			Selected: len(cues),
+			Patched:  result.Patched,
+		})
+		log.Info("retranslated cues written", "path", result.WrittenPath, "selected", len(cues), "patched", result.Patched)
+		return nil
+	},
+}
+
+// retranslateFileResult is the --json entry of a patched translation, after
+// the entry of the translate step (which writes to the workdir).
+type retranslateFileResult struct {
+	Command  string `json:"command"`
+	Input    string `json:"input"`
+	Source   string `json:"source"`
+	Output   string `json:"output"`
+	Selected int    `json:"selected"` // cues translated again
+	Patched  int    `json:"patched"`  // cues whose text was replaced
+}
+
+// readSubtitles reads the cues of the file at path.
+func readSubtitles(path string) ([]*srt.Subtitle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+	doc, err := srt.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return doc.Cues, nil
+}
+
+func init() {
+	registerRetranslateFlags(retranslateCmd)
+}
+
+func registerRetranslateFlags(cmd *cobra.Command) {
+	cmd.Flags().String(flagCues, "", "Cues to translate again: a report (the JSON of validate --format json, or the review or length report of translate) or a comma-separated list of indexes and ranges (e.g. 12,40-45)")
+	cmd.Flags().String(flagSource, "", "File the translation was translated from")
+	cmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to overwriting the translation)")
+	cmd.Flags().Bool(flagSkipBackup, false, "Do not create a .bak backup when overwriting the translation")
+	cmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	newStepCommand(stepTranslate).Flags().VisitAll(func(f *pflag.Flag) {
+		if retranslateSkippedFlags[f.Name] || cmd.Flags().Lookup(f.Name) != nil {
+			return
+		}
+		// --target-language defaults to the language of the file name.
+		delete(f.Annotations, cobra.BashCompOneRequiredFlag)
+		cmd.Flags().AddFlag(f)
+	})
+	_ = cmd.MarkFlagRequired(flagCues)
+	_ = cmd.MarkFlagRequired(flagSource)
+}
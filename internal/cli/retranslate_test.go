@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRetranslateCLI_PatchesSelectedCues(t *testing.T) {
+	t.Setenv(envConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	var sent []string
+	// A DeepL stand-in that "translates" by upper-casing.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text []string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type translation struct {
+			Text string `json:"text"`
+		}
+		var resp struct {
+			Translations []translation `json:"translations"`
+		}
+		for _, text := range req.Text {
+			sent = append(sent, text)
+			resp.Translations = append(resp.Translations, translation{Text: strings.ToUpper(text)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "movie.en.srt")
+	if err := os.WriteFile(source, []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nBye\n\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	input := filepath.Join(dir, "movie.es.srt")
+	orig := "1\r\n00:00:01,000 --> 00:00:02,000\r\nHola\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nHello?\r\n\r\n"
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cmd := &cobra.Command{
+		Use:           retranslateCmd.Use,
+		Args:          retranslateCmd.Args,
+		RunE:          retranslateCmd.RunE,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	registerRetranslateFlags(cmd)
+	cmd.SetArgs([]string{
+		"--source", source, "--cues", "2", "--provider", "deepl", "--url", server.URL, "--api-key", "k",
+		"--progress", "off", input,
+	})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("retranslate: %v", err)
+	}
+
+	if len(sent) != 1 || sent[0] != "Bye" {
+		t.Fatalf("expected only the selected cue to be sent, got %q", sent)
+	}
+	want := "1\r\n00:00:01,000 --> 00:00:02,000\r\nHola\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nBYE\r\n\r\n"
+	if b, err := os.ReadFile(input); err != nil || string(b) != want {
+		t.Fatalf("unexpected output %q (err %v)", b, err)
+	}
+	if b, err := os.ReadFile(input + ".bak"); err != nil || string(b) != orig {
+		t.Fatalf("expected the original in the backup, got %q (err %v)", b, err)
+	}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/review"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [flags] <input-file>",
+	Short: "Step through the cues flagged by validate or the translate review, editing them in the terminal",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logging.FromContext(cmd.Context())
+
+		flagsPath, _ := cmd.Flags().GetString(flagFlags)
+		sourcePath, _ := cmd.Flags().GetString(flagSource)
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		skipBackup, _ := cmd.Flags().GetBool(flagSkipBackup)
+
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		flagsPath, err = fs.ResolveAbsPath(flagsPath)
+		if err != nil {
+			return err
+		}
+		if sourcePath != "" {
+			if sourcePath, err = fs.ResolveAbsPath(sourcePath); err != nil {
+				return err
+			}
+		}
+		if outputPath != "" {
+			if outputPath, err = fs.ResolveAbsPath(outputPath); err != nil {
+				return err
+			}
+		} else {
+			outputPath = inPlaceOutputPath(inputPath)
+		}
+
+		flags, err := review.LoadFlags(flagsPath, inputPath)
+		if err != nil {
+			return err
+		}
+		result, err := review.Run(review.Options{
+			InputPath:    inputPath,
+			Flags:        flags,
+			SourcePath:   sourcePath,
+			OutputPath:   outputPath,
+			BackupExt:    ".bak",
+			CreateBackup: !skipBackup,
+			In:           cmd.InOrStdin(),
+			Out:          cmd.OutOrStdout(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if result.Flagged == 0 {
+			log.Info("no flagged cues to review", "path", inputPath)
+			return nil
+		}
+		if result.WrittenPath == "" {
+			log.Info("review ended without changes written", "reviewed", result.Reviewed, "flagged", result.Flagged)
+			return nil
+		}
+		recordFile(reviewFileResult{
+			Command:  "review",
+			Input:    inputPath,
+			Output:   result.WrittenPath,
+			Flagged:  result.Flagged,
+			Reviewed: result.Reviewed,
+			Edited:   result.Edited,
+			Deleted:  result.Deleted,
+		})
+		log.Info("reviewed subtitles written", "path", result.WrittenPath, "reviewed", result.Reviewed, "edited", result.Edited, "deleted", result.Deleted)
+		return nil
+	},
+}
+
+// reviewFileResult is the --json entry of a reviewed file.
+type reviewFileResult struct {
+	Command  string `json:"command"`
+	Input    string `json:"input"`
+	Output   string `json:"output"`
+	Flagged  int    `json:"flagged"`
+	Reviewed int    `json:"reviewed"`
+	Edited   int    `json:"edited"`
+	Deleted  int    `json:"deleted"`
+}
+
+func init() {
+	reviewCmd.Flags().String(flagFlags, "", "Report with the flagged cues: the JSON of validate --format json, or the review or length report of translate")
+	reviewCmd.Flags().String(flagSource, "", "File the input was translated from, to show the source text of the cues (optional)")
+	reviewCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to overwriting the input file)")
+	reviewCmd.Flags().Bool(flagSkipBackup, false, "Do not create a .bak backup when overwriting the input file")
+	_ = reviewCmd.MarkFlagRequired(flagFlags)
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"log/slog"
 	"os"
 
@@ -10,6 +11,9 @@ import (
 
 var verbose bool
 
+// finishUpdateNotify reports the --update-notify check when the command ends.
+var finishUpdateNotify = func() {}
+
 // version and commit are set at build time via -ldflags.
 // If left empty, they show as "dev".
 var version = ""
@@ -21,18 +25,46 @@ var rootCmd = &cobra.Command{
 	SilenceErrors: true,
 	SilenceUsage:  true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Fill the flags that weren't provided from the config file, first so
+		// the env vars below and in each command take precedence.
+		if command := configCommand(cmd); command != configCmd.Name() {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if err := checkConfigSection(cmd, cfg, command); err != nil {
+				return err
+			}
+			if err := applyConfig(cmd, cfg, command); err != nil {
+				return err
+			}
+		}
+
 		// Allow configuring verbosity via env var when the flag isn't provided.
 		if err := resolveBoolFlagFromEnv(cmd, flagVerbose, envVerbose); err != nil {
 			return err
 		}
 
-		level := slog.LevelInfo
-		if verbose {
-			level = slog.LevelDebug
+		if err := resolveBoolFlagFromEnv(cmd, flagJSON, envJSON); err != nil {
+			return err
+		}
+
+		logger := logging.New(os.Stderr, logLevel())
+		if asJSON, _ := cmd.Flags().GetBool(flagJSON); asJSON || hasHooks(cmd) {
+			if asJSON {
+				startResult(cmd)
+			} else {
+				// Only the hooks receive the result: stdout is left alone.
+				currentResult = newRunResult(cmd.CommandPath())
+			}
+			logger = slog.New(&warningRecorder{Handler: logger.Handler(), result: currentResult})
+		}
+		setLogger(cmd, logger)
+
+		if err := resolveBoolFlagFromEnv(cmd, flagUpdateNotify, envUpdateNotify); err != nil {
+			return err
 		}
-		logger := logging.New(os.Stderr, level)
-		slog.SetDefault(logger)
-		cmd.SetContext(logging.WithLogger(cmd.Context(), logger))
+		finishUpdateNotify = startUpdateNotify(cmd)
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -41,16 +73,46 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+func logLevel() slog.Level {
+	if verbose {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// setLogger makes logger the default one and stores it in the command context.
+func setLogger(cmd *cobra.Command, logger *slog.Logger) {
+	slog.SetDefault(logger)
+	cmd.SetContext(logging.WithLogger(cmd.Context(), logger))
+}
+
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	cmd, err := rootCmd.ExecuteC()
+	if currentResult != nil {
+		var doc bytes.Buffer
+		if werr := writeResult(&doc, err); werr != nil {
+			_, _ = os.Stderr.WriteString(werr.Error() + "\n")
+		} else {
+			if asJSON, _ := cmd.Flags().GetBool(flagJSON); asJSON {
+				_, _ = os.Stdout.Write(doc.Bytes())
+			}
+			runHooks(cmd.Context(), cmd, doc.Bytes(), err)
+		}
+	}
+	finishUpdateNotify()
+	if err != nil {
 		// Cobra already formatted errors; keep it simple.
 		_, _ = os.Stderr.WriteString(err.Error() + "\n")
-		os.Exit(1)
+		code, _ := exitStatus(err)
+		os.Exit(code)
 	}
 }
 
 func init() {
+	rootCmd.PersistentFlags().String(flagConfig, "", "Config file with default flag values (default: subtitle-tools/config.yaml in the user config directory, e.g. ~/.config)")
+	rootCmd.PersistentFlags().Bool(flagJSON, false, "Print a JSON document with the result of the command on stdout when it ends (logs stay on stderr)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, flagVerbose, flagVerboseShorthand, false, "Enable verbose (debug) logging")
+	rootCmd.PersistentFlags().Bool(flagUpdateNotify, false, "Check for a new release in the background, at most once a day, and tell when the command ends")
 
 	v := version
 	if v == "" {
@@ -64,7 +126,29 @@ func init() {
 	// Enable Cobra's built-in --version flag. This prints Version and exits.
 	rootCmd.SetVersionTemplate("{{.Version}}\n")
 
+	rootCmd.AddCommand(alignCmd)
+	rootCmd.AddCommand(autoCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(dedupeCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(extractCmd)
 	rootCmd.AddCommand(fixCmd)
+	rootCmd.AddCommand(glossaryCmd)
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(joinCmd)
+	rootCmd.AddCommand(langCmd)
+	rootCmd.AddCommand(muxCmd)
+	rootCmd.AddCommand(ocrCmd)
+	rootCmd.AddCommand(pipelineCmd)
+	rootCmd.AddCommand(renameCmd)
+	rootCmd.AddCommand(retranslateCmd)
+	rootCmd.AddCommand(reviewCmd)
+	rootCmd.AddCommand(spellcheckCmd)
+	rootCmd.AddCommand(splitCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(translateCmd)
 	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(validateCmd)
 }
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/spf13/cobra"
+)
+
+// addSelectionFlags registers the flags that limit a command to some cues,
+// passing the others through unchanged.
+func addSelectionFlags(cmd *cobra.Command, verb string) {
+	_ = cmd.Flags().String(flagCues, "", "Only "+verb+" the cues with these indexes: a comma-separated list of indexes and ranges (e.g. 120-180,200,250-); other cues are written unchanged")
+	_ = cmd.Flags().StringArray(flagRange, nil, "Only "+verb+" the cues overlapping this time range (e.g. 00:10:00-00:20:00 or 10m-20m; repeatable); other cues are written unchanged")
+}
+
+// selectionFromFlags parses the selection flags. With both set, a cue must
+// match both.
+func selectionFromFlags(cmd *cobra.Command) (srt.Selection, error) {
+	var sel srt.Selection
+	cues, _ := cmd.Flags().GetString(flagCues)
+	ranges, _ := cmd.Flags().GetStringArray(flagRange)
+	var err error
+	if sel.Idxs, err = srt.ParseIdxRanges(cues); err != nil {
+		return srt.Selection{}, fmt.Errorf("invalid --%s: %w", flagCues, err)
+	}
+	for _, r := range ranges {
+		timeRange, err := srt.ParseTimeRange(r)
+		if err != nil {
+			return srt.Selection{}, fmt.Errorf("invalid --%s: %w", flagRange, err)
+		}
+		sel.Times = append(sel.Times, timeRange)
+	}
+	return sel, nil
+}
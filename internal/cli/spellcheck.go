@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/spell"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/spf13/cobra"
+)
+
+var errMisspellings = errors.New("misspelled words found")
+
+var spellcheckCmd = &cobra.Command{
+	Use:   "spellcheck [flags] <input-file>...",
+	Short: "Report the misspelled words of subtitle files with hunspell dictionaries, optionally fixing the obvious ones",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logging.FromContext(cmd.Context())
+
+		format, _ := cmd.Flags().GetString(flagFormat)
+		language, _ := cmd.Flags().GetString(flagLanguage)
+		dictionary, _ := cmd.Flags().GetString(flagDictionary)
+		wordsPath, _ := cmd.Flags().GetString(flagWords)
+		fix, _ := cmd.Flags().GetBool(flagFix)
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		skipBackup, _ := cmd.Flags().GetBool(flagSkipBackup)
+		exitCode, _ := cmd.Flags().GetBool(flagExitCode)
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+		if outputPath != "" {
+			if !fix {
+				return fmt.Errorf("--%s requires --%s", flagOutput, flagFix)
+			}
+			if len(args) > 1 {
+				return fmt.Errorf("--%s can't be used with multiple input files", flagOutput)
+			}
+		}
+		var err error
+		for _, p := range []*string{&dictionary, &wordsPath, &outputPath} {
+			if *p != "" {
+				if *p, err = fs.ResolveAbsPath(*p); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Dictionaries are loaded once per language.
+		checkers := make(map[string]*spell.Checker)
+		reports := make([]spellcheckReport, 0, len(args))
+		remaining := 0
+		for _, arg := range args {
+			path, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			fileLanguage := language
+			if fileLanguage == "" {
+				_, name := naming.Parse(path)
+				fileLanguage = name.Language
+			}
+			if fileLanguage == "" && dictionary == "" {
+				return fmt.Errorf("%s: unknown language (set --%s or --%s)", arg, flagLanguage, flagDictionary)
+			}
+			key := dictionary
+			if key == "" {
+				key = naming.ShortLanguage(fileLanguage)
+			}
+			checker, ok := checkers[key]
+			if !ok {
+				if checker, err = spell.Open(dictionary, wordsPath, fileLanguage); err != nil {
+					return fmt.Errorf("load dictionary: %w", err)
+				}
+				checkers[key] = checker
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			doc, err := srt.Read(bytes.NewReader(b))
+			if err != nil {
+				return fmt.Errorf("parse %s: %w", arg, err)
+			}
+			report := spellcheckReport{Path: arg, Cues: len(doc.Cues), Misspellings: spell.CheckCues(doc.Cues, checker, fix)}
+			for _, m := range report.Misspellings {
+				if m.Fix != "" {
+					report.Fixed++
+				}
+			}
+			remaining += len(report.Misspellings) - report.Fixed
+			reports = append(reports, report)
+
+			if report.Fixed == 0 {
+				continue
+			}
+			output := outputPath
+			if output == "" {
+				output = inPlaceOutputPath(path)
+			}
+			if fs.SameFilePath(output, path) && !skipBackup {
+				backupPath := path + ".bak"
+				_ = os.Remove(backupPath)
+				if err := fs.CopyFile(path, backupPath); err != nil {
+					return err
+				}
+			}
+			var buf bytes.Buffer
+			if err := srt.Write(&buf, doc, false); err != nil {
+				return err
+			}
+			if err := fs.WriteFile(&buf, output); err != nil {
+				return err
+			}
+			recordFile(spellcheckFileResult{Command: "spellcheck", Input: path, Output: output, Misspellings: len(report.Misspellings), Fixed: report.Fixed})
+			log.Info("spelling fixes written", "path", output, "fixed", report.Fixed)
+		}
+
+		out := cmd.OutOrStdout()
+		if format == formatJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(reports)
+		} else {
+			err = writeSpellcheckReports(out, reports)
+		}
+		if err != nil {
+			return err
+		}
+		if exitCode && remaining > 0 {
+			return errMisspellings
+		}
+		return nil
+	},
+}
+
+// spellcheckReport is the report of a checked file.
+type spellcheckReport struct {
+	Path         string                 `json:"path"`
+	Cues         int                    `json:"cues"`
+	Misspellings []spell.CueMisspelling `json:"misspellings"`
+	Fixed        int                    `json:"fixed,omitempty"`
+}
+
+// spellcheckFileResult is the --json entry of a file whose misspellings were
+// fixed.
+type spellcheckFileResult struct {
+	Command      string `json:"command"`
+	Input        string `json:"input"`
+	Output       string `json:"output"`
+	Misspellings int    `json:"misspellings"`
+	Fixed        int    `json:"fixed"`
+}
+
+// writeSpellcheckReports writes one line per misspelled word ("path:idx time
+// word (suggestions)", or "word -> fix" when fixed) and a summary per file.
+func writeSpellcheckReports(w io.Writer, reports []spellcheckReport) error {
+	for _, r := range reports {
+		for _, m := range r.Misspellings {
+			var err error
+			switch {
+			case m.Fix != "":
+				_, err = fmt.Fprintf(w, "%s:%d %s %s -> %s\n", r.Path, m.Idx, m.Time, m.Word, m.Fix)
+			case len(m.Suggestions) > 0:
+				_, err = fmt.Fprintf(w, "%s:%d %s %s (suggestions: %s)\n", r.Path, m.Idx, m.Time, m.Word, strings.Join(m.Suggestions, ", "))
+			default:
+				_, err = fmt.Fprintf(w, "%s:%d %s %s\n", r.Path, m.Idx, m.Time, m.Word)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		summary := fmt.Sprintf("%s: ok (%d cues)", r.Path, r.Cues)
+		if len(r.Misspellings) > 0 {
+			summary = fmt.Sprintf("%s: %d misspelled word(s) in %d cues", r.Path, len(r.Misspellings), r.Cues)
+			if r.Fixed > 0 {
+				summary += fmt.Sprintf(", %d fixed", r.Fixed)
+			}
+		}
+		if _, err := fmt.Fprintln(w, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	spellcheckCmd.Flags().String(flagLanguage, "", "Language of the dictionary (e.g. en, es, pt-BR; defaults to the file name suffix, e.g. movie.es.srt)")
+	spellcheckCmd.Flags().String(flagDictionary, "", "Hunspell .dic file (with its .aff next to it); defaults to the dictionary of the language found in DICPATH or the usual directories")
+	spellcheckCmd.Flags().String(flagWords, "", "File of accepted words (character names, places...), one per line")
+	spellcheckCmd.Flags().String(flagFormat, formatText, "Report format: text or json")
+	spellcheckCmd.Flags().Bool(flagFix, false, "Fix the misspelled words with a single suggestion (not capitalized words in the middle of a sentence, which may be names)")
+	spellcheckCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path with --fix (optional; defaults to overwriting the input file)")
+	spellcheckCmd.Flags().Bool(flagSkipBackup, false, "Do not create a .bak backup when overwriting the input file")
+	spellcheckCmd.Flags().Bool(flagExitCode, false, "Exit with status 1 when misspelled words remain")
+}
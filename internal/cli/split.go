@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/split"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/spf13/cobra"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split [flags] <input-file>",
+	Short: "Split a subtitle file into parts by time or cue count, shifting every part to start at 00:00:00",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		atValues, _ := cmd.Flags().GetStringSlice(flagAt)
+		parts, _ := cmd.Flags().GetInt(flagParts)
+		every, _ := cmd.Flags().GetInt(flagEvery)
+
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+
+		var at []time.Duration
+		for _, v := range atValues {
+			d, err := srt.ParseTime(v)
+			if err != nil {
+				return err
+			}
+			at = append(at, d)
+		}
+		set := 0
+		for _, flag := range []string{flagAt, flagParts, flagEvery} {
+			if cmd.Flags().Changed(flag) {
+				set++
+			}
+		}
+		if set != 1 {
+			return errors.New("set exactly one of --at, --parts and --every")
+		}
+
+		if outputPath != "" {
+			absOut, err := fs.ResolveAbsPath(expandInputPlaceholders(outputPath, inputPath))
+			if err != nil {
+				return err
+			}
+			outputPath = absOut
+		}
+
+		if workdir != "" {
+			absWorkdir, err := fs.ResolveAbsPath(workdir)
+			if err != nil {
+				return err
+			}
+			workdir = absWorkdir
+		}
+
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "split")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		opts := split.Options{
+			InputPath:     inputPath,
+			OutputPattern: outputPath,
+			At:            at,
+			Parts:         parts,
+			Every:         every,
+			DryRun:        dryRun,
+			WorkDir:       runWorkdir,
+		}
+
+		log.Debug("running split", "opts", opts)
+
+		result, err := split.Run(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		for i, part := range result.Parts {
+			recordFile(splitFileResult{
+				Command: "split",
+				Input:   inputPath,
+				Output:  part.Path,
+				Part:    i + 1,
+				Offset:  srt.FormatTime(part.Offset),
+				Cues:    part.Cues,
+			})
+			log.Info("subtitle part written", "path", part.Path, "part", i+1, "offset", srt.FormatTime(part.Offset), "cues", part.Cues)
+		}
+
+		return nil
+	},
+}
+
+// splitFileResult is the --json entry of a part written by split.
+type splitFileResult struct {
+	Command string `json:"command"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	Part    int    `json:"part"`
+	Offset  string `json:"offset"` // where the part starts in the input
+	Cues    int    `json:"cues"`
+}
+
+func init() {
+	splitCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output path of the parts, with {n} for the part number; {dir} and {name} are those of the input (optional; defaults to <name>.part{n}.srt next to the input, before the language suffix)")
+	splitCmd.Flags().Bool(flagDryRun, false, "Write the parts to the working directory instead of the output path")
+	splitCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	splitCmd.Flags().StringSlice(flagAt, nil, "Times to cut at, comma-separated or repeated (e.g. 00:45:00 or 45m)")
+	splitCmd.Flags().Int(flagParts, 0, "Cut into this many parts of about the same length, at the longest pause near each even cut")
+	splitCmd.Flags().Int(flagEvery, 0, "Cut every this many cues")
+}
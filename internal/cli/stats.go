@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [flags] <input-file>...",
+	Short: "Print reading speed, line length, timing and language statistics of subtitle files",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString(flagFormat)
+		topGaps, _ := cmd.Flags().GetInt(flagTopGaps)
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+
+		all := make([]stats.Stats, 0, len(args))
+		for _, arg := range args {
+			path, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			st, err := stats.File(path, stats.Options{TopGaps: topGaps})
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			st.Path = arg
+			all = append(all, st)
+		}
+
+		out := cmd.OutOrStdout()
+		if format == formatJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(all)
+		}
+		for i, st := range all {
+			if i > 0 {
+				if _, err := fmt.Fprintln(out); err != nil {
+					return err
+				}
+			}
+			if err := writeStats(out, st); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// writeStats writes the human-readable statistics of a file.
+func writeStats(w io.Writer, st stats.Stats) error {
+	var b strings.Builder
+	row := func(label, format string, args ...any) {
+		line := fmt.Sprintf("  %-15s "+format, append([]any{label + ":"}, args...)...)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	b.WriteString(st.Path + "\n")
+	row("cues", "%d", st.Cues)
+	coveredPct := 0.0
+	if st.SpanMS > 0 {
+		coveredPct = float64(st.CoveredMS) * 100 / float64(st.SpanMS)
+	}
+	row("span", "%s (covered %s, %.0f%%)", formatMS(st.SpanMS), formatMS(st.CoveredMS), coveredPct)
+	if st.Language != "" {
+		row("language", "%s (confidence %.2f)", st.Language, st.LanguageConfidence)
+	} else {
+		row("language", "unknown")
+	}
+	row("cps", "%s", formatDistribution(st.CPS))
+	row("line length", "%s", formatDistribution(st.LineLength))
+	for _, bucket := range st.LineLengthHistogram {
+		pct := 0.0
+		if st.LineLength.Count > 0 {
+			pct = float64(bucket.Count) * 100 / float64(st.LineLength.Count)
+		}
+		_, _ = fmt.Fprintf(&b, "    %-7s %6d  %5.1f%%\n", bucket.Range, bucket.Count, pct)
+	}
+	row("words per cue", "%s", formatDistribution(st.Words))
+	if len(st.LongestGaps) == 0 {
+		row("longest gaps", "none")
+	} else {
+		row("longest gaps", "")
+		for _, g := range st.LongestGaps {
+			_, _ = fmt.Fprintf(&b, "    %s -> %s  %s (after cue %d)\n", g.Start, g.End, formatMS(g.DurationMS), g.AfterIdx)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatDistribution(d stats.Distribution) string {
+	if d.Count == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("avg %g, p50 %g, p90 %g, p95 %g, max %g", d.Avg, d.P50, d.P90, d.P95, d.Max)
+}
+
+// formatMS formats a duration in milliseconds keeping them, as a gap of 800ms
+// matters in subtitle timing (e.g. "1m2.5s", "800ms").
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+func init() {
+	statsCmd.Flags().String(flagFormat, formatText, "Output format: text or json")
+	statsCmd.Flags().Int(flagTopGaps, stats.DefaultTopGaps, "Number of longest gaps to show")
+}
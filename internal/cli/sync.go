@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/mediasync"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [flags] <input-file>",
+	Short: "Align subtitle times to the speech of a video (or to a subtitle file already in sync)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Allow resolving some flags from env vars.
+		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagWorkdir, envWorkdir); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		log := logging.FromContext(ctx)
+
+		outputPath, _ := cmd.Flags().GetString(flagOutput)
+		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		workdir, _ := cmd.Flags().GetString(flagWorkdir)
+		skipBackup, _ := cmd.Flags().GetBool(flagSkipBackup)
+		media, _ := cmd.Flags().GetString(flagMedia)
+		track, _ := cmd.Flags().GetInt(flagTrack)
+		maxOffset, _ := cmd.Flags().GetDuration(flagMaxOffset)
+		skipFramerate, _ := cmd.Flags().GetBool(flagSkipFramerate)
+
+		if track < 0 {
+			return fmt.Errorf("invalid --%s %d (must be an audio stream number >= 0)", flagTrack, track)
+		}
+		if maxOffset <= 0 {
+			return fmt.Errorf("invalid --%s %s (must be > 0)", flagMaxOffset, maxOffset)
+		}
+		if args[0] == "-" {
+			return errors.New("stdin is not supported; pass a file path")
+		}
+		inputPath, err := fs.ResolveAbsPath(args[0])
+		if err != nil {
+			return err
+		}
+		mediaPath, err := fs.ResolveAbsPath(media)
+		if err != nil {
+			return err
+		}
+
+		if outputPath != "" {
+			absOut, err := fs.ResolveAbsPath(outputPath)
+			if err != nil {
+				return err
+			}
+			outputPath = absOut
+		} else {
+			outputPath = inPlaceOutputPath(inputPath)
+		}
+
+		if workdir != "" {
+			absWorkdir, err := fs.ResolveAbsPath(workdir)
+			if err != nil {
+				return err
+			}
+			workdir = absWorkdir
+		}
+
+		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "sync")
+		if err != nil {
+			return err
+		}
+		log.Debug("using workdir", "workdir", runWorkdir)
+		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+			defer cleanup()
+		}
+
+		opts := mediasync.Options{
+			InputPath:     inputPath,
+			MediaPath:     mediaPath,
+			OutputPath:    outputPath,
+			AudioTrack:    track,
+			MaxOffset:     maxOffset,
+			SkipFramerate: skipFramerate,
+			DryRun:        dryRun,
+			WorkDir:       runWorkdir,
+			BackupExt:     ".bak",
+			CreateBackup:  !dryRun && !skipBackup,
+		}
+
+		log.Debug("running sync", "opts", opts)
+
+		result, err := mediasync.Run(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		if result.Dropped > 0 {
+			log.Warn("cues moved before the start of the media were dropped", "count", result.Dropped)
+		}
+		recordFile(syncFileResult{
+			Command: "sync",
+			Input:   inputPath,
+			Output:  result.WrittenPath,
+			Media:   mediaPath,
+			Offset:  result.Offset.String(),
+			Scale:   result.Scale,
+			Score:   result.Score,
+			Dropped: result.Dropped,
+		})
+		log.Info("subtitles synced", "path", result.WrittenPath, "offset", result.Offset, "scale", result.Scale, "score", result.Score)
+
+		return nil
+	},
+}
+
+// syncFileResult is the --json entry of a synced file.
+type syncFileResult struct {
+	Command string  `json:"command"`
+	Input   string  `json:"input"`
+	Output  string  `json:"output"`
+	Media   string  `json:"media"`
+	Offset  string  `json:"offset"` // e.g. "-2.4s"
+	Scale   float64 `json:"scale"`  // 1 without frame rate drift
+	Score   float64 `json:"score"`  // share of the cue time over speech
+	Dropped int     `json:"dropped"`
+}
+
+func init() {
+	syncCmd.Flags().String(flagMedia, "", "Video or audio file to sync to (requires ffmpeg), or a subtitle file (.srt) already in sync with it")
+	syncCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (optional; defaults to overwriting the input file)")
+	syncCmd.Flags().Bool(flagDryRun, false, "Write output to a temporary file and do not overwrite the original")
+	syncCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	syncCmd.Flags().Bool(flagSkipBackup, false, "Do not create a .bak backup when overwriting the input file")
+	syncCmd.Flags().Int(flagTrack, 0, "Audio stream of the media to listen to, from 0 (e.g. 1 for the second audio track)")
+	syncCmd.Flags().Duration(flagMaxOffset, mediasync.DefaultMaxOffset, "Largest offset to look for, either way")
+	syncCmd.Flags().Bool(flagSkipFramerate, false, "Only look for an offset, without trying to fix a frame rate drift (23.976/24/25 fps)")
+	_ = syncCmd.MarkFlagRequired(flagMedia)
+}
@@ -1,21 +1,28 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
 	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/httpclient"
 	"github.com/adrianmusante/subtitle-tools/internal/logging"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/progress"
 	"github.com/adrianmusante/subtitle-tools/internal/run"
 	"github.com/adrianmusante/subtitle-tools/internal/translate"
 	"github.com/spf13/cobra"
 )
 
 var translateCmd = &cobra.Command{
-	Use:   "translate [flags] <input-file>",
-	Short: "Translate subtitles to another language using an OpenAI-compatible API",
-	Args:  cobra.ExactArgs(1),
+	Use:   "translate [flags] <input-file|directory|glob>...",
+	Short: "Translate subtitles to another language using an OpenAI-compatible API or DeepL",
+	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Allow resolving some flags from env vars.
 		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
@@ -42,6 +49,9 @@ var translateCmd = &cobra.Command{
 		if err := resolveFloat64FlagFromEnv(cmd, flagRPS, envTranslateRPS); err != nil {
 			return err
 		}
+		if err := resolveFloat64FlagFromEnv(cmd, flagRPSPerKey, envTranslateRPSPerKey); err != nil {
+			return err
+		}
 		if err := resolveIntFlagFromEnv(cmd, flagRetryMax, envTranslateRetryMax); err != nil {
 			return err
 		}
@@ -51,54 +61,437 @@ var translateCmd = &cobra.Command{
 		if err := resolveDurationFlagFromEnv(cmd, flagRequestTimeout, envTranslateRequestTimeout); err != nil {
 			return err
 		}
+		if err := resolveIntFlagFromEnv(cmd, flagBreaker, envTranslateBreaker); err != nil {
+			return err
+		}
+		if err := resolveDurationFlagFromEnv(cmd, flagBreakerCooldown, envTranslateBreakerCooldown); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagResponseMode, envTranslateResponseMode); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagParseMode, envTranslateParseMode); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagProvider, envTranslateProvider); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagFormality, envTranslateFormality); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagSafetyThreshold, envTranslateSafety); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagCheckModel, envTranslateCheckModel); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagAdaptiveWorkers, envTranslateAdaptive); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagCacheDir, envTranslateCacheDir); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagNoCache, envTranslateNoCache); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagPromptFile, envTranslatePromptFile); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagGlossaryFile, envTranslateGlossaryFile); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagStyle, envTranslateStyle); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagAudience, envTranslateAudience); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagNotes, envTranslateNotes); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagSkipTagProtect, envTranslateSkipTagProtect); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagSkipPromptCache, envTranslateSkipPromptCache); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagPreserveIndex, envTranslatePreserveIndex); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagOnlyForced, envTranslateOnlyForced); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagSkipSDH, envTranslateSkipSDH); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagSDH, envTranslateSDH); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagCensorList, envTranslateCensorList); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagCensorStyle, envTranslateCensorStyle); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagRetryTagMismatch, envTranslateRetryTags); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagReview, envTranslateReview); err != nil {
+			return err
+		}
+		if err := resolveFloat64FlagFromEnv(cmd, flagMaxCPS, envTranslateMaxCPS); err != nil {
+			return err
+		}
+		if err := resolveIntFlagFromEnv(cmd, flagMaxLineLen, envTranslateMaxLineLen); err != nil {
+			return err
+		}
+		if err := resolveIntFlagFromEnv(cmd, flagMaxLines, envTranslateMaxLines); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagLengthHints, envTranslateLengthHints); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagLineBreaks, envTranslateLineBreaks); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagLocalizeNumbers, envTranslateLocalize); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagConvertUnits, envTranslateConvertUnits); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagSeriesContext, envTranslateSeriesContext); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagRTLMarks, envTranslateRTLMarks); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagLengthPolicy, envTranslateLengthPolicy); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagSideBySide, envTranslateSideBySide); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagForce, envTranslateForce); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagStream, envTranslateStream); err != nil {
+			return err
+		}
+		if err := resolveBoolFlagFromEnv(cmd, flagBatchAPI, envTranslateBatchAPI); err != nil {
+			return err
+		}
+		if err := resolveFloat64FlagFromEnv(cmd, flagTemperature, envTranslateTemperature); err != nil {
+			return err
+		}
+		if err := resolveFloat64FlagFromEnv(cmd, flagTopP, envTranslateTopP); err != nil {
+			return err
+		}
+		if err := resolveIntFlagFromEnv(cmd, flagMaxOutputTokens, envTranslateMaxOutTokens); err != nil {
+			return err
+		}
+		if err := resolveFloat64FlagFromEnv(cmd, flagTokenPrice, envTranslateTokenPrice); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagReasoningEffort, envTranslateReasoning); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagFallbackModel, envTranslateFallbackModel); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagTranscriptDir, envTranslateTranscriptDir); err != nil {
+			return err
+		}
+		netOpts, err := networkOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		transport, err := httpclient.NewTransport(netOpts)
+		if err != nil {
+			return err
+		}
+
+		reporter, err := newProgressReporter(cmd)
+		if err != nil {
+			return err
+		}
+		defer reporter.Finish()
 
 		ctx := cmd.Context()
 		log := logging.FromContext(ctx)
 
-		inputPath := args[0]
-		if inputPath == "-" {
-			return errors.New("stdin is not supported yet; pass a subtitle file path")
-		}
-		absInput, err := fs.ResolveAbsPath(inputPath)
+		inputs, batch, err := expandInputs(cmd, args)
 		if err != nil {
 			return err
 		}
-		inputPath = absInput
 
 		outputPath, _ := cmd.Flags().GetString("output")
-		if outputPath == "" {
+		namingScheme, err := namingSchemeFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if namingScheme != "" {
+			if outputPath != "" {
+				return fmt.Errorf("--output can't be combined with --%s or --%s", flagPlexNaming, flagJellyfinNaming)
+			}
+		} else if outputPath == "" {
 			return errors.New("--output is required and must not exist (we never overwrite on translate)")
 		}
-		absOutput, err := fs.ResolveAbsPath(outputPath)
+
+		sourceLang, _ := cmd.Flags().GetString(flagSourceLanguage)
+		targetLang, _ := cmd.Flags().GetString(flagTargetLanguage)
+		var targetLangs []string
+		if csv := run.NormalizeCSV(targetLang); csv != "" {
+			targetLangs = strings.Split(csv, run.CommaSeparator)
+		}
+		if len(targetLangs) == 0 {
+			targetLangs = []string{targetLang} // empty; reported by translate.Run
+		}
+		tmxExport, _ := cmd.Flags().GetString(flagTMXExport)
+		reviewReport, _ := cmd.Flags().GetString(flagReviewReport)
+		lengthReport, _ := cmd.Flags().GetString(flagLengthReport)
+		sdhMode, _ := cmd.Flags().GetString(flagSDH)
+		selection, err := selectionFromFlags(cmd)
 		if err != nil {
 			return err
 		}
-		outputPath = absOutput
-		if _, err := os.Stat(outputPath); err == nil {
-			return errors.New("output file already exists")
-		} else if !errors.Is(err, os.ErrNotExist) {
-			return err
+		multi := len(targetLangs) > 1
+		if multi && namingScheme == "" && !strings.Contains(outputPath, outputLanguagePlaceholder) {
+			return fmt.Errorf("--output must contain %s when translating to multiple languages (e.g. movie.%s.srt)", outputLanguagePlaceholder, outputLanguagePlaceholder)
 		}
-		if err := fs.ValidatePathWritable(outputPath); err != nil {
-			return fmt.Errorf("invalid --output path %s: %w", outputPath, err)
+		if multi && tmxExport != "" && !strings.Contains(tmxExport, outputLanguagePlaceholder) {
+			return fmt.Errorf("--%s must contain %s when translating to multiple languages", flagTMXExport, outputLanguagePlaceholder)
+		}
+		if multi && reviewReport != "" && !strings.Contains(reviewReport, outputLanguagePlaceholder) {
+			return fmt.Errorf("--%s must contain %s when translating to multiple languages", flagReviewReport, outputLanguagePlaceholder)
+		}
+		if multi && lengthReport != "" && !strings.Contains(lengthReport, outputLanguagePlaceholder) {
+			return fmt.Errorf("--%s must contain %s when translating to multiple languages", flagLengthReport, outputLanguagePlaceholder)
+		}
+		jobs, _ := cmd.Flags().GetInt(flagJobs)
+		if batch {
+			if jobs < 1 {
+				return fmt.Errorf("invalid --%s %d (must be >= 1)", flagJobs, jobs)
+			}
+			for flag, path := range map[string]string{flagOutput: outputPath, flagTMXExport: tmxExport, flagReviewReport: reviewReport, flagLengthReport: lengthReport} {
+				if err := requireNamePlaceholder(flag, path); err != nil {
+					return err
+				}
+			}
+		}
+
+		// targetsFor resolves the output and report paths of every target
+		// language of an input; {dir} and {name} are only expanded in batch mode.
+		targetsFor := func(in batchInput) ([]translate.Target, error) {
+			expand := func(path, lang string) string {
+				if batch {
+					path = expandInputPlaceholders(path, in.Path)
+				}
+				return expandOutputLanguage(path, lang)
+			}
+			var videoPath string
+			var inputName naming.Name
+			if namingScheme != "" {
+				var err error
+				if videoPath, err = naming.FindVideo(in.Path); err != nil {
+					return nil, err
+				}
+				_, inputName = naming.Parse(in.Path)
+			}
+			targets := make([]translate.Target, 0, len(targetLangs))
+			for _, lang := range targetLangs {
+				out := expand(outputPath, lang)
+				if namingScheme != "" {
+					// Keep the forced/sdh flags of the input name, unless the
+					// SDH mode changes the kind of track.
+					name := naming.Name{Language: lang, Forced: inputName.Forced, SDH: inputName.SDH}
+					switch strings.ToLower(strings.TrimSpace(sdhMode)) {
+					case translate.SDHStrip:
+						name.SDH = false
+					case translate.SDHGenerate:
+						name.SDH = true
+					}
+					out = naming.Path(videoPath, namingScheme, name)
+				}
+				out, err := resolveNewOutputPath(out)
+				if err != nil {
+					return nil, err
+				}
+				target := translate.Target{Language: lang, OutputPath: out}
+				if tmxExport != "" {
+					if target.TMXExportPath, err = fs.ResolveAbsPath(expand(tmxExport, lang)); err != nil {
+						return nil, err
+					}
+					if err := fs.ValidatePathWritable(target.TMXExportPath); err != nil {
+						return nil, fmt.Errorf("invalid --%s path %s: %w", flagTMXExport, target.TMXExportPath, err)
+					}
+				}
+				if target.ReviewReportPath, err = resolveReportPath(flagReviewReport, expand(reviewReport, lang), lang); err != nil {
+					return nil, err
+				}
+				if target.LengthReportPath, err = resolveReportPath(flagLengthReport, expand(lengthReport, lang), lang); err != nil {
+					return nil, err
+				}
+				targets = append(targets, target)
+			}
+			return targets, nil
+		}
+
+		// In batch mode a file whose paths can't be resolved (e.g. its output
+		// already exists) fails on its own; other errors stop the command.
+		inputTargets := make([][]translate.Target, len(inputs))
+		targetErrs := make([]error, len(inputs))
+		outputOf := make(map[string]string)
+		for i, in := range inputs {
+			targets, err := targetsFor(in)
+			if err != nil && !batch {
+				return err
+			}
+			for _, t := range targets {
+				if prev, ok := outputOf[t.OutputPath]; ok {
+					err = fmt.Errorf("input files %s and %s have the same output path %s", prev, in.Name, t.OutputPath)
+					break
+				}
+			}
+			if err != nil {
+				targetErrs[i] = err
+				continue
+			}
+			for _, t := range targets {
+				outputOf[t.OutputPath] = in.Name
+			}
+			inputTargets[i] = targets
 		}
 
-		sourceLang, _ := cmd.Flags().GetString(flagSourceLanguage)
-		targetLang, _ := cmd.Flags().GetString(flagTargetLanguage)
 		apiKey, _ := cmd.Flags().GetString(flagApiKey)
 		model, _ := cmd.Flags().GetString(flagModel)
 		baseURL, _ := cmd.Flags().GetString(flagURL)
 		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		sideBySide, _ := cmd.Flags().GetString(flagSideBySide)
 		workdir, _ := cmd.Flags().GetString(flagWorkdir)
 		maxBatchChars, _ := cmd.Flags().GetInt(flagMaxBatchChars)
 		maxWorkers, _ := cmd.Flags().GetInt(flagMaxWorkers)
+		adaptiveWorkers, _ := cmd.Flags().GetBool(flagAdaptiveWorkers)
 		rps, _ := cmd.Flags().GetFloat64(flagRPS)
+		rpsPerKey, _ := cmd.Flags().GetFloat64(flagRPSPerKey)
 		retryMaxAttempts, _ := cmd.Flags().GetInt(flagRetryMax)
 		retryParseMaxAttempts, _ := cmd.Flags().GetInt(flagRetryParseMax)
 		requestTimeout, _ := cmd.Flags().GetDuration(flagRequestTimeout)
+		breakerThreshold, _ := cmd.Flags().GetInt(flagBreaker)
+		breakerCooldown, _ := cmd.Flags().GetDuration(flagBreakerCooldown)
+		responseMode, _ := cmd.Flags().GetString(flagResponseMode)
+		parseMode, _ := cmd.Flags().GetString(flagParseMode)
+		provider, _ := cmd.Flags().GetString(flagProvider)
+		formality, _ := cmd.Flags().GetString(flagFormality)
+		safetyThreshold, _ := cmd.Flags().GetString(flagSafetyThreshold)
+		checkModel, _ := cmd.Flags().GetBool(flagCheckModel)
+		fallbackModels, _ := cmd.Flags().GetStringSlice(flagFallbackModel)
+		fallbackAPIKeys, _ := cmd.Flags().GetStringArray(flagFallbackAPIKey)
+		fallbackURLs, _ := cmd.Flags().GetStringArray(flagFallbackURL)
+		style, _ := cmd.Flags().GetString(flagStyle)
+		audience, _ := cmd.Flags().GetString(flagAudience)
+		notes, _ := cmd.Flags().GetString(flagNotes)
+		skipTagProtection, _ := cmd.Flags().GetBool(flagSkipTagProtect)
+		skipPromptCache, _ := cmd.Flags().GetBool(flagSkipPromptCache)
+		preserveIndex, _ := cmd.Flags().GetBool(flagPreserveIndex)
+		onlyForced, _ := cmd.Flags().GetBool(flagOnlyForced)
+		skipSDH, _ := cmd.Flags().GetBool(flagSkipSDH)
+		censorStyle, _ := cmd.Flags().GetString(flagCensorStyle)
+		retryTagMismatch, _ := cmd.Flags().GetBool(flagRetryTagMismatch)
+		review, _ := cmd.Flags().GetString(flagReview)
+		maxCPS, _ := cmd.Flags().GetFloat64(flagMaxCPS)
+		maxLineLen, _ := cmd.Flags().GetInt(flagMaxLineLen)
+		maxLines, _ := cmd.Flags().GetInt(flagMaxLines)
+		lengthHints, _ := cmd.Flags().GetBool(flagLengthHints)
+		lineBreaks, _ := cmd.Flags().GetString(flagLineBreaks)
+		rtlMarks, _ := cmd.Flags().GetBool(flagRTLMarks)
+		localizeNumbers, _ := cmd.Flags().GetBool(flagLocalizeNumbers)
+		convertUnits, _ := cmd.Flags().GetBool(flagConvertUnits)
+		lengthPolicy, _ := cmd.Flags().GetString(flagLengthPolicy)
+		force, _ := cmd.Flags().GetBool(flagForce)
+		stream, _ := cmd.Flags().GetBool(flagStream)
+		batchAPI, _ := cmd.Flags().GetBool(flagBatchAPI)
+		maxOutputTokens, _ := cmd.Flags().GetInt(flagMaxOutputTokens)
+		reasoningEffort, _ := cmd.Flags().GetString(flagReasoningEffort)
+		tokenPrice, _ := cmd.Flags().GetFloat64(flagTokenPrice)
+		if tokenPrice < 0 {
+			return fmt.Errorf("invalid --%s %g (must be >= 0)", flagTokenPrice, tokenPrice)
+		}
+		// Unset sampling flags keep the per-model defaults.
+		var temperature, topP *float64
+		if flagSet(cmd, flagTemperature) {
+			v, _ := cmd.Flags().GetFloat64(flagTemperature)
+			temperature = &v
+		}
+		if flagSet(cmd, flagTopP) {
+			v, _ := cmd.Flags().GetFloat64(flagTopP)
+			topP = &v
+		}
+
+		promptFile, _ := cmd.Flags().GetString(flagPromptFile)
+		if promptFile != "" {
+			if promptFile, err = fs.ResolveAbsPath(promptFile); err != nil {
+				return err
+			}
+		}
+		glossaryFile, _ := cmd.Flags().GetString(flagGlossaryFile)
+		// One context for all the files, so each learns from those before it.
+		var seriesContext *translate.SeriesContext
+		if v, _ := cmd.Flags().GetBool(flagSeriesContext); v {
+			seriesContext = translate.NewSeriesContext()
+			// In order, unless asked otherwise: concurrent files don't see
+			// each other's terms.
+			if !flagSet(cmd, flagJobs) {
+				jobs = 1
+			}
+		}
+		if glossaryFile != "" {
+			if glossaryFile, err = fs.ResolveAbsPath(glossaryFile); err != nil {
+				return err
+			}
+		}
+
+		censorList, _ := cmd.Flags().GetString(flagCensorList)
+		if censorList != "" {
+			if censorList, err = fs.ResolveAbsPath(censorList); err != nil {
+				return err
+			}
+		}
+
+		tmxImport, _ := cmd.Flags().GetString(flagTMXImport)
+		if tmxImport != "" {
+			if tmxImport, err = fs.ResolveAbsPath(tmxImport); err != nil {
+				return err
+			}
+		}
+
+		cacheDir := ""
+		if noCache, _ := cmd.Flags().GetBool(flagNoCache); !noCache {
+			cacheDir, _ = cmd.Flags().GetString(flagCacheDir)
+			if cacheDir == "" {
+				if cacheDir, err = translate.DefaultCacheDir(); err != nil {
+					log.Warn("translation cache disabled: cannot resolve user cache dir", "err", err)
+				}
+			}
+			if cacheDir != "" {
+				if cacheDir, err = fs.ResolveAbsPath(cacheDir); err != nil {
+					return err
+				}
+			}
+		}
+
+		transcriptDir, _ := cmd.Flags().GetString(flagTranscriptDir)
+		if transcriptDir != "" {
+			if transcriptDir, err = fs.ResolveAbsPath(transcriptDir); err != nil {
+				return err
+			}
+		}
 
 		// Normalize comma-separated api keys early so opts don't carry spaces.
 		apiKey = run.NormalizeCSV(apiKey)
+		for i := range fallbackAPIKeys {
+			fallbackAPIKeys[i] = run.NormalizeCSV(fallbackAPIKeys[i])
+		}
 
 		if workdir != "" {
 			absWorkdir, err := fs.ResolveAbsPath(workdir)
@@ -108,6 +501,15 @@ var translateCmd = &cobra.Command{
 			workdir = absWorkdir
 		}
 
+		batchStateDir := ""
+		if batchAPI {
+			if workdir == "" {
+				return fmt.Errorf("--%s requires --%s, which keeps the state of the batch jobs", flagBatchAPI, flagWorkdir)
+			}
+			// Outside the run subdirectory, so the next run resumes the jobs.
+			batchStateDir = filepath.Join(workdir, "batch-api")
+		}
+
 		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "translate")
 		if err != nil {
 			return err
@@ -117,54 +519,385 @@ var translateCmd = &cobra.Command{
 			defer cleanup()
 		}
 
-		opts := translate.Options{
-			InputPath:             inputPath,
-			OutputPath:            outputPath,
-			DryRun:                dryRun,
-			WorkDir:               runWorkdir,
-			SourceLanguage:        sourceLang,
-			TargetLanguage:        targetLang,
-			APIKey:                apiKey,
-			Model:                 model,
-			BaseURL:               baseURL,
-			MaxBatchChars:         maxBatchChars,
-			MaxWorkers:            maxWorkers,
-			RPS:                   rps,
-			RetryMaxAttempts:      retryMaxAttempts,
-			RetryParseMaxAttempts: retryParseMaxAttempts,
-			RequestTimeout:        requestTimeout,
-		}
-
-		safeOpts := opts
-		safeOpts.APIKey = run.MaskKeys(opts.APIKey, run.CommaSeparator)
-		log.Debug("translate run", "opts", safeOpts)
-
-		res, err := translate.Run(ctx, opts)
-		if err != nil {
-			return err
+		base := translate.Options{
+			DryRun:                  dryRun,
+			SideBySideFormat:        sideBySide,
+			SourceLanguage:          sourceLang,
+			APIKey:                  apiKey,
+			Model:                   model,
+			BaseURL:                 baseURL,
+			MaxBatchChars:           maxBatchChars,
+			MaxWorkers:              maxWorkers,
+			AdaptiveWorkers:         adaptiveWorkers,
+			RPS:                     rps,
+			KeyRPS:                  rpsPerKey,
+			RetryMaxAttempts:        retryMaxAttempts,
+			RetryParseMaxAttempts:   retryParseMaxAttempts,
+			RequestTimeout:          requestTimeout,
+			CircuitBreakerThreshold: breakerThreshold,
+			CircuitBreakerCooldown:  breakerCooldown,
+			ResponseMode:            responseMode,
+			ParseStrictness:         parseMode,
+			Provider:                provider,
+			Formality:               formality,
+			SafetyThreshold:         safetyThreshold,
+			CheckModel:              checkModel,
+			FallbackModels:          fallbackModels,
+			FallbackAPIKeys:         fallbackAPIKeys,
+			FallbackBaseURLs:        fallbackURLs,
+			CacheDir:                cacheDir,
+			TMXImportPath:           tmxImport,
+			PromptFile:              promptFile,
+			GlossaryFile:            glossaryFile,
+			Style:                   style,
+			Audience:                audience,
+			Notes:                   notes,
+			SkipTagProtection:       skipTagProtection,
+			PreserveIndex:           preserveIndex,
+			OnlyForced:              onlyForced,
+			SkipSDH:                 skipSDH,
+			Selection:               selection,
+			SDH:                     sdhMode,
+			CensorListPath:          censorList,
+			CensorStyle:             censorStyle,
+			RetryTagMismatch:        retryTagMismatch,
+			Review:                  review,
+			MaxCPS:                  maxCPS,
+			MaxLineLength:           maxLineLen,
+			MaxLines:                maxLines,
+			LengthHints:             lengthHints,
+			LineBreaks:              lineBreaks,
+			RTLMarks:                rtlMarks,
+			LocalizeNumbers:         localizeNumbers,
+			ConvertUnits:            convertUnits,
+			SeriesContext:           seriesContext,
+			LengthPolicy:            lengthPolicy,
+			Force:                   force,
+			Stream:                  stream,
+			SkipPromptCache:         skipPromptCache,
+			BatchAPI:                batchAPI,
+			BatchStateDir:           batchStateDir,
+			Temperature:             temperature,
+			TopP:                    topP,
+			MaxOutputTokens:         maxOutputTokens,
+			ReasoningEffort:         reasoningEffort,
+			Transport:               transport,
+			TranscriptDir:           transcriptDir,
+		}
+
+		translateFile := func(ctx context.Context, in batchInput, targets []translate.Target, workdir string, progressFn translate.ProgressFunc) error {
+			opts := base
+			opts.InputPath = in.Path
+			opts.OutputPath = targets[0].OutputPath
+			opts.WorkDir = workdir
+			opts.TargetLanguage = targets[0].Language
+			opts.TMXExportPath = targets[0].TMXExportPath
+			opts.ReviewReportPath = targets[0].ReviewReportPath
+			opts.LengthReportPath = targets[0].LengthReportPath
+			opts.Progress = progressFn
+
+			safeOpts := opts
+			safeOpts.APIKey = run.MaskKeys(opts.APIKey, run.CommaSeparator)
+			safeOpts.FallbackAPIKeys = make([]string, 0, len(opts.FallbackAPIKeys))
+			for _, k := range opts.FallbackAPIKeys {
+				safeOpts.FallbackAPIKeys = append(safeOpts.FallbackAPIKeys, run.MaskKeys(k, run.CommaSeparator))
+			}
+			log.Debug("translate run", "opts", safeOpts)
+
+			results, err := translate.RunTargets(ctx, opts, targets)
+			if err != nil {
+				return err
+			}
+
+			for _, res := range results {
+				recordFile(newTranslateFileResult(in, res, tokenPrice))
+				log.Info("translated subtitles written", "target_language", res.TargetLanguage, "path", res.WrittenPath, "batches", res.Batches, "tokens", res.TokensUsed, "cached_tokens", res.CachedTokens, "cache_hits", res.CacheHits, "memory_hits", res.MemoryHits, "tag_mismatches", res.TagMismatches)
+				if p := res.Parse; p.Salvaged > 0 || p.Repaired > 0 || p.Failures > 0 || p.Truncated > 0 {
+					log.Info("model output repaired while parsing", "target_language", res.TargetLanguage, "modes", p.Modes, "salvaged_lines", p.Salvaged, "repaired_objects", p.Repaired, "failures", p.Failures, "truncated", p.Truncated)
+				}
+				if res.SideBySidePath != "" {
+					log.Info("side-by-side review file written", "target_language", res.TargetLanguage, "path", res.SideBySidePath)
+				}
+				if res.ReviewReportPath != "" {
+					log.Info("translation review report written", "target_language", res.TargetLanguage, "path", res.ReviewReportPath, "flagged", res.ReviewFlagged, "corrected", res.ReviewCorrected)
+				}
+				if res.SDHStripped > 0 {
+					log.Info("sound-only cues left out of the translation", "target_language", res.TargetLanguage, "cues", res.SDHStripped)
+				}
+				if res.Censored > 0 {
+					log.Info("translation censored", "target_language", res.TargetLanguage, "style", censorStyle, "cues", res.Censored)
+				}
+				if res.Blocked > 0 {
+					log.Warn("cues blocked by the content filters of the provider written untranslated", "target_language", res.TargetLanguage, "cues", res.Blocked)
+				}
+				if res.Unselected > 0 {
+					log.Info("cues outside the selection written untranslated", "target_language", res.TargetLanguage, "cues", res.Unselected)
+				}
+				if maxCPS > 0 || maxLineLen > 0 || maxLines > 0 {
+					log.Info("translation length limits applied", "target_language", res.TargetLanguage, "wrapped", res.LengthWrapped, "shortened", res.LengthShortened, "violations", res.LengthViolations, "report", res.LengthReportPath)
+				}
+				if localizeNumbers || convertUnits {
+					log.Info("numbers and units localized", "target_language", res.TargetLanguage, "cues", res.Localized)
+				}
+				if res.RTLMarked > 0 {
+					log.Info("right-to-left marks added", "target_language", res.TargetLanguage, "cues", res.RTLMarked)
+				}
+				if seriesContext != nil {
+					log.Info("series context updated", "target_language", res.TargetLanguage, "new_terms", res.SeriesTerms)
+				}
+			}
+			if len(results) > 0 && results[0].TranscriptDir != "" {
+				log.Info("translation transcript written", "dir", results[0].TranscriptDir)
+			}
+			return nil
 		}
 
-		log.Info("translated subtitles written", "path", res.WrittenPath, "batches", res.Batches)
-		return nil
+		if !batch {
+			err := translateFile(ctx, inputs[0], inputTargets[0], runWorkdir, translateProgress(reporter))
+			reporter.Finish()
+			return err
+		}
+		return runBatch(ctx, reporter, "translate", inputs, jobs, func(ctx context.Context, i int, in batchInput) error {
+			if targetErrs[i] != nil {
+				return targetErrs[i]
+			}
+			// Each file gets its own workdir: temporary files are named after the output.
+			workdir, _, err := run.NewWorkdir(runWorkdir, "file")
+			if err != nil {
+				return err
+			}
+			return translateFile(ctx, in, inputTargets[i], workdir, nil)
+		})
 	},
 }
 
+// translateFileResult is the --json entry of a translated file.
+type translateFileResult struct {
+	Command          string               `json:"command"`
+	Input            string               `json:"input"`
+	Output           string               `json:"output"`
+	TargetLanguage   string               `json:"target_language"`
+	Batches          int                  `json:"batches"`
+	Tokens           int64                `json:"tokens"`
+	CachedTokens     int64                `json:"cached_tokens"`  // prompt tokens read from the prompt cache of the provider
+	Cost             *float64             `json:"cost,omitempty"` // with --token-price
+	CacheHits        int                  `json:"cache_hits"`
+	MemoryHits       int                  `json:"memory_hits"`
+	TagMismatches    int                  `json:"tag_mismatches"`
+	Blocked          int                  `json:"blocked"` // cues blocked by the content filters, written untranslated
+	ReviewFlagged    int                  `json:"review_flagged"`
+	ReviewCorrected  int                  `json:"review_corrected"`
+	LengthWrapped    int                  `json:"length_wrapped"`
+	LengthShortened  int                  `json:"length_shortened"`
+	LengthViolations int                  `json:"length_violations"`
+	Localized        int                  `json:"localized"`    // cues fixed by --localize-numbers/--convert-units
+	SeriesTerms      int                  `json:"series_terms"` // names and terms learned with --series-context
+	RTLMarked        int                  `json:"rtl_marked"`   // cues given right-to-left marks by --rtl-marks
+	SDHStripped      int                  `json:"sdh_stripped"`
+	Censored         int                  `json:"censored"`
+	Unselected       int                  `json:"unselected"`             // cues outside --cues/--range, written untranslated
+	SideBySide       string               `json:"side_by_side,omitempty"` // with --dry-run
+	Parse            translateParseResult `json:"parse"`
+}
+
+// translateParseResult is how the model responses of a translation were
+// parsed, to track the output quality of a model across runs.
+type translateParseResult struct {
+	Modes           map[string]int `json:"modes"` // responses per parse mode, e.g. "objects" or "lines-repaired"
+	CodeFences      int            `json:"code_fences"`
+	SalvagedLines   int            `json:"salvaged_lines"`
+	RepairedObjects int            `json:"repaired_objects"`
+	Failures        int            `json:"failures"`  // responses retried because they couldn't be parsed
+	Truncated       int            `json:"truncated"` // responses cut at the output token limit (batch split)
+}
+
+func newTranslateFileResult(in batchInput, res translate.Result, tokenPrice float64) translateFileResult {
+	r := translateFileResult{
+		Command:          "translate",
+		Input:            in.Path,
+		Output:           res.WrittenPath,
+		TargetLanguage:   res.TargetLanguage,
+		Batches:          res.Batches,
+		Tokens:           res.TokensUsed,
+		CachedTokens:     res.CachedTokens,
+		CacheHits:        res.CacheHits,
+		MemoryHits:       res.MemoryHits,
+		TagMismatches:    res.TagMismatches,
+		Blocked:          res.Blocked,
+		ReviewFlagged:    res.ReviewFlagged,
+		ReviewCorrected:  res.ReviewCorrected,
+		LengthWrapped:    res.LengthWrapped,
+		LengthShortened:  res.LengthShortened,
+		LengthViolations: res.LengthViolations,
+		Localized:        res.Localized,
+		SeriesTerms:      res.SeriesTerms,
+		RTLMarked:        res.RTLMarked,
+		SDHStripped:      res.SDHStripped,
+		Censored:         res.Censored,
+		Unselected:       res.Unselected,
+		SideBySide:       res.SideBySidePath,
+		Parse: translateParseResult{
+			Modes:           res.Parse.Modes,
+			CodeFences:      res.Parse.CodeFences,
+			SalvagedLines:   res.Parse.Salvaged,
+			RepairedObjects: res.Parse.Repaired,
+			Failures:        res.Parse.Failures,
+			Truncated:       res.Parse.Truncated,
+		},
+	}
+	if r.Parse.Modes == nil {
+		r.Parse.Modes = map[string]int{}
+	}
+	if tokenPrice > 0 {
+		cost := float64(res.TokensUsed) / 1e6 * tokenPrice
+		r.Cost = &cost
+	}
+	return r
+}
+
+// translateProgress forwards translation progress to reporter.
+func translateProgress(reporter progress.Reporter) translate.ProgressFunc {
+	return func(p translate.Progress) {
+		fields := []progress.Field{{Key: "cues", Value: fmt.Sprintf("%d/%d", p.CuesDone, p.TotalCues)}}
+		if p.TokensUsed > 0 {
+			fields = append(fields, progress.Field{Key: "tokens", Value: progress.FormatCount(p.TokensUsed)})
+		}
+		reporter.Update(progress.Snapshot{
+			Task:   "translate " + p.TargetLanguage,
+			Unit:   "batches",
+			Done:   p.CompletedBatches,
+			Total:  p.TotalBatches,
+			Fields: fields,
+			ETA:    p.ETA,
+		})
+	}
+}
+
+// outputLanguagePlaceholder is replaced by each target language in --output
+// (and the --tmx-export and report paths) when translating to multiple languages.
+const outputLanguagePlaceholder = "{lang}"
+
+func expandOutputLanguage(path, lang string) string {
+	return strings.ReplaceAll(path, outputLanguagePlaceholder, lang)
+}
+
+// resolveReportPath expands {lang} in an optional report path and checks that
+// it can be written. An empty path stays empty.
+func resolveReportPath(flag, path, lang string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	abs, err := fs.ResolveAbsPath(expandOutputLanguage(path, lang))
+	if err != nil {
+		return "", err
+	}
+	if err := fs.ValidatePathWritable(abs); err != nil {
+		return "", fmt.Errorf("invalid --%s path %s: %w", flag, abs, err)
+	}
+	return abs, nil
+}
+
+// resolveNewOutputPath returns the absolute output path, making sure it doesn't
+// exist yet (translate never overwrites) and that its directory is writable.
+func resolveNewOutputPath(outputPath string) (string, error) {
+	absOutput, err := fs.ResolveAbsPath(outputPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(absOutput); err == nil {
+		return "", fmt.Errorf("output file already exists: %s", absOutput)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err := fs.ValidatePathWritable(absOutput); err != nil {
+		return "", fmt.Errorf("invalid --output path %s: %w", absOutput, err)
+	}
+	return absOutput, nil
+}
+
 func init() {
-	_ = translateCmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (required; must not already exist)")
-	_ = translateCmd.Flags().String(flagSourceLanguage, "", "Source language (optional; helps disambiguate the input)")
-	_ = translateCmd.Flags().String(flagTargetLanguage, "", "Target language (e.g. es, es-MX, fr)")
-	_ = translateCmd.Flags().String(flagApiKey, "", "API key. A comma-separated list of keys can be provided to distribute requests across multiple keys")
-	_ = translateCmd.Flags().String(flagModel, "", "Model to use (e.g. gpt-5, gemini-flash-latest)")
-	_ = translateCmd.Flags().String(flagURL, "", "Base URL for the API endpoint (optional; inferred from --model if omitted)")
-	_ = translateCmd.Flags().Bool(flagDryRun, false, "Write output to a temporary file and do not create the final output file")
-	_ = translateCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
-	_ = translateCmd.Flags().Int(flagMaxBatchChars, translate.DefaultMaxBatchChars, "Soft limit for the batch payload size")
-	_ = translateCmd.Flags().Int(flagMaxWorkers, translate.DefaultMaxWorkers, "Number of concurrent translation workers (batches in-flight)")
-	_ = translateCmd.Flags().Float64(flagRPS, translate.DefaultRequestPerSecond, "Max requests per second (0 disables rate limiting)")
-	_ = translateCmd.Flags().Int(flagRetryMax, translate.DefaultRetryMaxAttempts, "Max attempts per request for retryable errors")
-	_ = translateCmd.Flags().Int(flagRetryParseMax, translate.DefaultParseRetryMaxAttempts, "Max attempts per batch when the model output is invalid/unparseable (ParseTranslatedLines/mismatch)")
-	_ = translateCmd.Flags().Duration(flagRequestTimeout, translate.DefaultRequestTimeout, "HTTP request timeout duration (e.g. 30s, 1m; 0 disables timeout)")
-
-	_ = translateCmd.MarkFlagRequired(flagTargetLanguage)
+	registerTranslateFlags(translateCmd)
+}
+
+func registerTranslateFlags(cmd *cobra.Command) {
+	_ = cmd.Flags().StringP(flagOutput, flagOutputShorthand, "", "Output file path (required unless a naming flag is set; must not already exist). Use {lang} in the path with multiple target languages")
+	addNamingFlags(cmd, "Write the output next to the video of the input file")
+	_ = cmd.Flags().String(flagSourceLanguage, "", "Source language (optional; helps disambiguate the input)")
+	_ = cmd.Flags().String(flagTargetLanguage, "", "Target language (e.g. es, es-MX, fr). A comma-separated list writes one output per language")
+	_ = cmd.Flags().String(flagApiKey, "", "API key. A comma-separated list of keys can be provided to distribute requests across multiple keys")
+	_ = cmd.Flags().String(flagModel, "", "Model to use (e.g. gpt-5, gemini-flash-latest, ollama:llama3.1, lmstudio:qwen2.5-7b-instruct)")
+	_ = cmd.Flags().StringSlice(flagFallbackModel, nil, "Fallback model(s) tried in order when a batch exhausts retries on the primary provider (repeatable or comma-separated)")
+	_ = cmd.Flags().StringArray(flagFallbackAPIKey, nil, "API key(s) for the fallback model at the same position (repeatable; defaults to --api-key)")
+	_ = cmd.Flags().StringArray(flagFallbackURL, nil, "Base URL for the fallback model at the same position (repeatable; inferred from the model if omitted)")
+	_ = cmd.Flags().Float64(flagTemperature, 0, "Sampling temperature (default: 0, or the provider default for reasoning models)")
+	_ = cmd.Flags().Float64(flagTopP, 0, "Nucleus sampling top_p (default: provider default)")
+	_ = cmd.Flags().Int(flagMaxOutputTokens, 0, "Max tokens in each response (0 = provider default)")
+	_ = cmd.Flags().Float64(flagTokenPrice, 0, "Price per million tokens, used to report the cost of the run in the --json result (0 omits it)")
+	_ = cmd.Flags().String(flagReasoningEffort, "", "Reasoning effort for reasoning models: none, minimal, low, medium, high")
+	_ = cmd.Flags().Bool(flagStream, false, "Stream chat completions (SSE); --request-timeout then limits the time between received chunks")
+	_ = cmd.Flags().Bool(flagBatchAPI, false, "Send the batches as a job of the Batch API of the provider (openai, gemini), at about half the price, and wait for it (requires --workdir)")
+	_ = cmd.Flags().Bool(flagCheckModel, false, "Query the provider's model list and fail early if the model is not available")
+	_ = cmd.Flags().String(flagURL, "", "Base URL for the API endpoint (optional; inferred from --model if omitted)")
+	_ = cmd.Flags().String(flagCacheDir, "", "Translation cache directory (default: <user cache dir>/subtitle-tools/translate)")
+	_ = cmd.Flags().Bool(flagNoCache, false, "Disable the translation cache (always call the provider)")
+	_ = cmd.Flags().String(flagPromptFile, "", "Go text/template file that replaces the built-in prompt (chat models only)")
+	_ = cmd.Flags().String(flagGlossaryFile, "", "Glossary text file injected into the prompt (chat models only)")
+	_ = cmd.Flags().Bool(flagSeriesContext, false, "Learn the recurring names and terms of each translated file and add them to the prompts of the next files, to keep them consistent across the episodes of a season (chat models only)")
+	_ = cmd.Flags().String(flagStyle, "", "Tone/style: formal, informal, colloquial, neutral, or free text")
+	_ = cmd.Flags().String(flagAudience, "", "Target audience added to the prompt (e.g. \"children\", \"medical professionals\")")
+	_ = cmd.Flags().String(flagNotes, "", "Free-text translation notes added to the prompt")
+	_ = cmd.Flags().Bool(flagPreserveIndex, false, "Keep the cue numbers of the input instead of renumbering from 1")
+	_ = cmd.Flags().Bool(flagOnlyForced, false, "Translate and write only the forced cues (tagged {\\forced}), to build a forced track")
+	_ = cmd.Flags().Bool(flagSkipSDH, false, "Leave out the cues that only describe sounds, e.g. [door slams] or (laughs)")
+	addSelectionFlags(cmd, "translate")
+	_ = cmd.Flags().String(flagSDH, translate.DefaultSDH, "Hearing-impaired annotations: keep (translate as-is), strip (leave out sound descriptions and speaker labels) or generate (add speaker labels and sound cues); chat models only")
+	_ = cmd.Flags().String(flagCensorList, "", "File of words to censor in the translation, one word or phrase per line (word* also matches the words starting with it, word=text sets its replacement)")
+	_ = cmd.Flags().String(flagCensorStyle, censor.DefaultStyle, "How listed words are censored: stars (f***), beep-text ([beep]) or remove-cue (drops the cue)")
+	_ = cmd.Flags().Bool(flagSkipTagProtect, false, "Send inline tags (<i>, <font>, {\\an8}) as-is instead of replacing them with placeholders")
+	_ = cmd.Flags().Bool(flagSkipPromptCache, false, "Do not send prompt caching hints (prompt_cache_key to the OpenAI API, cache_control to Claude models)")
+	_ = cmd.Flags().Bool(flagRetryTagMismatch, false, "Retry a batch when a translated cue's inline tags don't match the source (uses --retry-parse-max-attempts)")
+	_ = cmd.Flags().String(flagReview, "", "Review the translations with a second LLM pass: fix (apply corrections) or report (flag only). --review alone means fix")
+	cmd.Flags().Lookup(flagReview).NoOptDefVal = translate.ReviewModeFix
+	_ = cmd.Flags().String(flagReviewReport, "", "Review report path (JSON; default: <output>.review.json). Use {lang} with multiple target languages")
+	_ = cmd.Flags().Float64(flagMaxCPS, 0, "Max characters per second of a translated cue (0 disables)")
+	_ = cmd.Flags().Int(flagMaxLineLen, 0, "Max line length of a translated cue (0 disables)")
+	_ = cmd.Flags().Int(flagMaxLines, 0, "Max lines of a translated cue (0 disables)")
+	_ = cmd.Flags().Bool(flagLengthHints, false, "Send --max-line-len/--max-lines with every cue and ask the model to wrap the translations to fit them (chat models only)")
+	_ = cmd.Flags().Bool(flagLocalizeNumbers, false, "Write numbers, dates and currency amounts with the conventions of the target locale, fixing the decimal and thousands separators the model copied from the source")
+	_ = cmd.Flags().Bool(flagConvertUnits, false, "Convert imperial units to metric (e.g. 1.5 miles to 2,4 km), unless the target locale uses them, converting locally the measures the model left as they were")
+	_ = cmd.Flags().Bool(flagRTLMarks, false, "Add right-to-left marks (RLM) around the lines of a right-to-left target language (ar, he, fa...) that start or end with punctuation, digits or Latin words, so players show them on the right side")
+	_ = cmd.Flags().String(flagLineBreaks, translate.DefaultLineBreaks, "Line breaks inside a cue: preserve (the model keeps them) or reflow (the model may move them and the translation is broken again into balanced lines)")
+	_ = cmd.Flags().String(flagLengthPolicy, translate.DefaultLengthPolicy, "What to do with cues over --max-cps/--max-line-len/--max-lines: wrap, shorten (ask the model to condense), report")
+	_ = cmd.Flags().String(flagLengthReport, "", "Write the cues still over --max-cps/--max-line-len/--max-lines to this JSON file. Use {lang} with multiple target languages")
+	_ = cmd.Flags().String(flagTMXImport, "", "TMX file used as a pre-seeded translation memory (matching cues are not sent to the provider)")
+	_ = cmd.Flags().String(flagTranscriptDir, "", "Write each batch request payload and raw model response to numbered files in this directory")
+	_ = cmd.Flags().String(flagTMXExport, "", "Write the source/translated cue pairs to this TMX file")
+	addNetworkFlags(cmd)
+	addBatchFlags(cmd, 1)
+	addProgressFlag(cmd)
+	addHookFlags(cmd)
+	_ = cmd.Flags().Bool(flagForce, false, "Translate even if the input already looks like it is in the target language")
+	_ = cmd.Flags().Bool(flagDryRun, false, "Write output to a temporary file and do not create the final output file")
+	_ = cmd.Flags().String(flagSideBySide, translate.DefaultSideBySide, "Format of the review file written with --dry-run next to the output, with the source and translated text of every cue side by side: markdown, csv or html")
+	_ = cmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
+	_ = cmd.Flags().Int(flagMaxBatchChars, translate.DefaultMaxBatchChars, "Soft limit for the batch payload size")
+	_ = cmd.Flags().Int(flagMaxWorkers, translate.DefaultMaxWorkers, "Number of concurrent translation workers (batches in-flight)")
+	_ = cmd.Flags().Bool(flagAdaptiveWorkers, false, "Adjust concurrency automatically (AIMD) up to --max-workers, backing off on 429/timeouts")
+	_ = cmd.Flags().Float64(flagRPS, translate.DefaultRequestPerSecond, "Max requests per second (0 disables rate limiting)")
+	_ = cmd.Flags().Float64(flagRPSPerKey, 0, "Max requests per second for each API key (0 disables per-key rate limiting)")
+	_ = cmd.Flags().Int(flagRetryMax, translate.DefaultRetryMaxAttempts, "Max attempts per request for retryable errors")
+	_ = cmd.Flags().Int(flagRetryParseMax, translate.DefaultParseRetryMaxAttempts, "Max attempts per batch when the model output is invalid/unparseable (ParseTranslatedLines/mismatch)")
+	_ = cmd.Flags().Duration(flagRequestTimeout, translate.DefaultRequestTimeout, "HTTP request timeout duration (e.g. 30s, 1m; 0 disables timeout)")
+	_ = cmd.Flags().Int(flagBreaker, translate.DefaultCircuitBreakerThreshold, "Consecutive server errors (5xx) of a provider, across workers, that pause all its requests for --circuit-breaker-cooldown (0 disables the circuit breaker)")
+	_ = cmd.Flags().Duration(flagBreakerCooldown, translate.DefaultCircuitBreakerCooldown, "How long the requests to a provider are paused when its circuit breaker trips")
+	_ = cmd.Flags().String(flagProfile, "", "Profile of the config file with the provider settings to use (e.g. model, url, api-key, rps, max-workers)")
+	_ = cmd.Flags().String(flagProvider, translate.DefaultProvider, "Translation backend: openai (any OpenAI-compatible API; gemini-* models use the native Gemini API unless --url is set), gemini (the native Gemini API) or deepl")
+	_ = cmd.Flags().String(flagSafetyThreshold, translate.DefaultSafetyThreshold, "Safety filter threshold of the native Gemini API for every harm category: none (never block), off, high, medium, low (block content of that harm probability and above) or default (the API defaults)")
+	_ = cmd.Flags().String(flagFormality, "", "Formality for providers that support it (deepl): default, more, less, prefer_more, prefer_less")
+	_ = cmd.Flags().String(flagResponseMode, translate.DefaultResponseMode, "How the output format is enforced: auto (structured output with NDJSON fallback), ndjson, or json-schema")
+	_ = cmd.Flags().String(flagParseMode, translate.DefaultParseStrictness, "How malformed model output is read: strict (well-formed JSON only), tolerant (also code fences and objects not one per line) or salvage (also repair broken JSON)")
+
+	_ = cmd.MarkFlagRequired(flagTargetLanguage)
 	// NOTE: api-key and model can be provided via env vars, so we validate at runtime.
+	// model is only required for the openai provider.
 }
@@ -1,7 +1,12 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/httpclient"
 	"github.com/adrianmusante/subtitle-tools/internal/logging"
 	"github.com/adrianmusante/subtitle-tools/internal/run"
 	"github.com/adrianmusante/subtitle-tools/internal/update"
@@ -10,7 +15,7 @@ import (
 
 var updateCmd = &cobra.Command{
 	Use:   "update",
-	Short: "Download and replace the CLI with the latest version from GitHub releases",
+	Short: "Download and replace the CLI with the latest (or a given) version from GitHub releases",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := resolveBoolFlagFromEnv(cmd, flagDryRun, envDryRun); err != nil {
 			return err
@@ -21,51 +26,193 @@ var updateCmd = &cobra.Command{
 		if err := resolveStringFlagFromEnv(cmd, flagApiKey, envGithubAPIKey); err != nil {
 			return err
 		}
+		if err := resolveStringFlagFromEnv(cmd, flagChannel, envUpdateChannel); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagPublicKey, envUpdatePublicKey); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagAPIURL, envUpdateAPIURL); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagRepo, envUpdateRepo); err != nil {
+			return err
+		}
+		if err := resolveStringFlagFromEnv(cmd, flagAssetURL, envUpdateAssetURL); err != nil {
+			return err
+		}
+
+		if rollback, _ := cmd.Flags().GetBool(flagRollback); rollback {
+			return runRollback(cmd)
+		}
+
+		netOpts, err := networkOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		transport, err := httpclient.NewTransport(netOpts)
+		if err != nil {
+			return err
+		}
 
 		apiKey, _ := cmd.Flags().GetString(flagApiKey)
 		workdir, _ := cmd.Flags().GetString(flagWorkdir)
 		dryRun, _ := cmd.Flags().GetBool(flagDryRun)
+		channel, _ := cmd.Flags().GetString(flagChannel)
+		pinned, _ := cmd.Flags().GetString(flagVersion)
+		check, _ := cmd.Flags().GetBool(flagCheck)
+		allowDowngrade, _ := cmd.Flags().GetBool(flagAllowDowngrade)
+		skipChecksum, _ := cmd.Flags().GetBool(flagSkipChecksum)
+		publicKey, _ := cmd.Flags().GetString(flagPublicKey)
+		apiURL, _ := cmd.Flags().GetString(flagAPIURL)
+		repo, _ := cmd.Flags().GetString(flagRepo)
+		assetURL, _ := cmd.Flags().GetString(flagAssetURL)
 		ctx := cmd.Context()
 		log := logging.FromContext(ctx)
 
-		if workdir != "" {
-			absWorkdir, err := fs.ResolveAbsPath(workdir)
-			if err != nil {
-				return err
-			}
-			workdir = absWorkdir
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if channel != update.ChannelStable && channel != update.ChannelPrerelease {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagChannel, channel, update.ChannelStable, update.ChannelPrerelease)
 		}
 
-		runWorkdir, cleanup, err := run.NewWorkdir(workdir, "update")
+		publicKey, err = readPublicKey(publicKey)
 		if err != nil {
 			return err
 		}
-		log.Debug("using workdir", "workdir", runWorkdir)
-		if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
-			defer cleanup()
+
+		var owner string
+		if ownerRepo := strings.TrimSpace(repo); ownerRepo != "" {
+			var ok bool
+			owner, repo, ok = strings.Cut(ownerRepo, "/")
+			if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+				return fmt.Errorf("invalid --%s %q (expected OWNER/REPO)", flagRepo, ownerRepo)
+			}
+		}
+
+		runWorkdir := ""
+		if !check {
+			if workdir != "" {
+				absWorkdir, err := fs.ResolveAbsPath(workdir)
+				if err != nil {
+					return err
+				}
+				workdir = absWorkdir
+			}
+
+			var cleanup func()
+			runWorkdir, cleanup, err = run.NewWorkdir(workdir, "update")
+			if err != nil {
+				return err
+			}
+			log.Debug("using workdir", "workdir", runWorkdir)
+			if !dryRun { // Only defer cleanup if not dry-run, so we can inspect files afterwards.
+				defer cleanup()
+			}
 		}
 
 		res, err := update.Run(ctx, update.Options{
+			APIURL:         strings.TrimSpace(apiURL),
+			Owner:          owner,
+			Repo:           repo,
+			AssetURL:       strings.TrimSpace(assetURL),
 			APIKey:         apiKey,
 			CurrentVersion: version,
+			Channel:        channel,
+			Version:        strings.TrimSpace(pinned),
+			CheckOnly:      check,
+			AllowDowngrade: allowDowngrade,
+			SkipChecksum:   skipChecksum,
+			PublicKey:      publicKey,
 			DryRun:         dryRun,
 			WorkDir:        runWorkdir,
+			Transport:      transport,
 		})
 		if err != nil {
 			return err
 		}
+		entry := updateResult{Command: "update", Updated: res.Updated, Available: res.Available, Downgrade: res.Downgrade, Version: res.Version, Prerelease: res.Prerelease, Asset: res.AssetName}
 		if res.Updated {
-			log.Info("updated subtitle-tools", "version", res.Version, "asset", res.AssetName, "path", res.ExePath)
-			return nil
+			entry.Output = res.ExePath
+		}
+		recordFile(entry)
+		switch {
+		case res.Updated:
+			if dryRun {
+				log.Info("updated subtitle-tools", "version", res.Version, "asset", res.AssetName, "path", res.ExePath)
+			} else {
+				log.Info("updated subtitle-tools", "version", res.Version, "asset", res.AssetName, "path", res.ExePath, "previous", update.PrevPath(res.ExePath))
+			}
+		case res.Available:
+			log.Info("update available", "current", rootCmd.Version, "version", res.Version, "prerelease", res.Prerelease, "asset", res.AssetName)
+		case res.Downgrade:
+			log.Info("current version is newer than the release, not downgrading (use --"+flagAllowDowngrade+")", "current", rootCmd.Version, "version", res.Version)
+		default:
+			log.Info("already up to date", "version", res.Version)
 		}
-
-		log.Info("already up to date", "version", res.Version)
 		return nil
 	},
 }
 
+// runRollback restores the executable replaced by the last update.
+func runRollback(cmd *cobra.Command) error {
+	for _, name := range []string{flagCheck, flagVersion, flagChannel, flagDryRun} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can't be used with --%s", flagRollback, name)
+		}
+	}
+	ctx := cmd.Context()
+	res, err := update.Rollback(ctx, "")
+	if err != nil {
+		return err
+	}
+	recordFile(updateResult{Command: "update", Output: res.ExePath, Updated: true, RolledBack: true, Version: res.Version})
+	logging.FromContext(ctx).Info("rolled back subtitle-tools", "version", res.Version, "path", res.ExePath, "kept", update.PrevPath(res.ExePath))
+	return nil
+}
+
+// updateResult is the --json entry of an update.
+type updateResult struct {
+	Command    string `json:"command"`
+	Output     string `json:"output,omitempty"` // the replaced executable
+	Updated    bool   `json:"updated"`
+	Available  bool   `json:"available"`
+	Downgrade  bool   `json:"downgrade,omitempty"`
+	RolledBack bool   `json:"rolled_back,omitempty"`
+	Version    string `json:"version"`
+	Prerelease bool   `json:"prerelease,omitempty"`
+	Asset      string `json:"asset,omitempty"`
+}
+
+// readPublicKey returns the --public-key value: the key itself, or the
+// content of the .pub file it names.
+func readPublicKey(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	if info, err := os.Stat(v); err == nil && !info.IsDir() {
+		b, err := os.ReadFile(v)
+		if err != nil {
+			return "", fmt.Errorf("read --%s: %w", flagPublicKey, err)
+		}
+		return string(b), nil
+	}
+	return v, nil
+}
+
 func init() {
 	updateCmd.Flags().Bool(flagDryRun, false, "Download the update to a temporary file but do not replace the current executable")
 	updateCmd.Flags().StringP(flagWorkdir, flagWorkdirShorthand, "", "Working directory base. If set, a unique subdirectory is created per run")
 	updateCmd.Flags().String(flagApiKey, "", "GitHub API key (optional; helps avoid rate limits)")
+	updateCmd.Flags().String(flagAPIURL, "", "GitHub API base URL, for GitHub Enterprise or a mirror (default: https://api.github.com)")
+	updateCmd.Flags().String(flagRepo, "", "GitHub repository of the releases as OWNER/REPO (default: adrianmusante/subtitle-tools)")
+	updateCmd.Flags().String(flagAssetURL, "", "Download the archive from this URL instead of GitHub; {version}, {os}, {arch} and {ext} are replaced")
+	updateCmd.Flags().String(flagChannel, update.DefaultChannel, "Release channel: stable (latest release) or prerelease (newest release, prereleases included)")
+	updateCmd.Flags().String(flagVersion, "", "Install this release (e.g. v1.2.3) instead of the newest one of the channel; older releases too")
+	updateCmd.Flags().Bool(flagCheck, false, "Only report whether an update is available, without downloading it")
+	updateCmd.Flags().Bool(flagAllowDowngrade, false, "Install the release of the channel even when it is older than the current version")
+	updateCmd.Flags().String(flagPublicKey, "", "Minisign public key (or path to its .pub file) that must have signed the checksums of the release")
+	updateCmd.Flags().Bool(flagRollback, false, "Restore the version replaced by the last update (kept as subtitle-tools.prev)")
+	updateCmd.Flags().Bool(flagSkipChecksum, false, "Install the release without verifying its checksum, e.g. when it has no checksums file (insecure)")
+	addNetworkFlags(updateCmd)
 }
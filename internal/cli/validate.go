@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/validate"
+	"github.com/spf13/cobra"
+)
+
+// Report formats.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+var validateCmd = &cobra.Command{
+	Use:     "validate [flags] <input-file>...",
+	Aliases: []string{"lint"},
+	Short:   "Check subtitle files for timing and formatting problems",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString(flagFormat)
+		maxLines, _ := cmd.Flags().GetInt(flagMaxLines)
+		maxCPS, _ := cmd.Flags().GetFloat64(flagMaxCPS)
+		disable, _ := cmd.Flags().GetString(flagDisable)
+
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != formatText && format != formatJSON {
+			return fmt.Errorf("invalid --%s %q (supported: %s, %s)", flagFormat, format, formatText, formatJSON)
+		}
+		var disabled []string
+		if csv := run.NormalizeCSV(disable); csv != "" {
+			disabled = strings.Split(csv, run.CommaSeparator)
+		}
+		for _, rule := range disabled {
+			if !validate.IsValidRule(rule) {
+				return fmt.Errorf("invalid --%s rule %q (supported: %s)", flagDisable, rule, strings.Join(validate.Rules, ", "))
+			}
+		}
+
+		opts := validate.Options{MaxLines: maxLines, MaxCPS: maxCPS, Disabled: disabled}
+		reports := make([]validate.Report, 0, len(args))
+		violations := 0
+		for _, arg := range args {
+			path, err := fs.ResolveAbsPath(arg)
+			if err != nil {
+				return err
+			}
+			report, err := validate.File(path, opts)
+			if err != nil {
+				return err
+			}
+			report.Path = arg
+			reports = append(reports, report)
+			violations += report.Errors()
+		}
+
+		out := cmd.OutOrStdout()
+		if format == formatJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(reports); err != nil {
+				return err
+			}
+		} else if err := writeValidateReports(out, reports); err != nil {
+			return err
+		}
+
+		if violations > 0 {
+			return fmt.Errorf("validation failed: %d violation(s)", violations)
+		}
+		return nil
+	},
+}
+
+// writeValidateReports writes one line per violation ("path:idx time [rule]
+// message", with "warning: " before the message of warnings) and a summary
+// per file.
+func writeValidateReports(w io.Writer, reports []validate.Report) error {
+	for _, r := range reports {
+		for _, v := range r.Violations {
+			message := v.Message
+			if v.Warning {
+				message = "warning: " + message
+			}
+			var err error
+			if v.Position == 0 {
+				_, err = fmt.Fprintf(w, "%s [%s] %s\n", r.Path, v.Rule, message)
+			} else {
+				_, err = fmt.Fprintf(w, "%s:%d %s [%s] %s\n", r.Path, v.Idx, v.Time, v.Rule, message)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(r.Violations) == 0 {
+			if _, err := fmt.Fprintf(w, "%s: ok (%d cues)\n", r.Path, r.Cues); err != nil {
+				return err
+			}
+			continue
+		}
+		counts := r.Counts()
+		var parts []string
+		for _, rule := range validate.Rules {
+			if n := counts[rule]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d", rule, n))
+			}
+		}
+		summary := fmt.Sprintf("%d violation(s)", r.Errors())
+		if warnings := len(r.Violations) - r.Errors(); warnings > 0 {
+			summary += fmt.Sprintf(", %d warning(s)", warnings)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s in %d cues (%s)\n", r.Path, summary, r.Cues, strings.Join(parts, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	validateCmd.Flags().String(flagFormat, formatText, "Report format: text or json")
+	validateCmd.Flags().Int(flagMaxLines, validate.DefaultMaxLines, "Max lines per cue")
+	validateCmd.Flags().Float64(flagMaxCPS, validate.DefaultMaxCPS, "Max reading speed in characters per second")
+	validateCmd.Flags().String(flagDisable, "", "Comma-separated list of rules to skip (e.g. max-cps,too-many-lines)")
+}
@@ -0,0 +1,318 @@
+// Package config reads and edits the configuration file of subtitle-tools
+// (by default ~/.config/subtitle-tools/config.yaml), which holds default
+// values of the command flags.
+//
+// The keys of the file are flag names. Top-level keys apply to every command
+// with that flag, and the keys of a section named after a command (e.g.
+// "translate:") only to that command, taking precedence:
+//
+//	workdir: /tmp/subtitle-tools
+//	translate:
+//	  model: gpt-4o-mini
+//	  target-language: es
+//	fix:
+//	  include: ["*.srt", "*.en.srt"]
+//
+// Named profiles group settings selected together with --profile, such as the
+// model and API key of a translation provider:
+//
+//	profiles:
+//	  local:
+//	    model: ollama:llama3.1
+//	    max-workers: 1
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfilesKey is the top-level key of the named profiles.
+const ProfilesKey = "profiles"
+
+// File is a loaded configuration file. The zero value is an empty
+// configuration.
+type File struct {
+	Path string
+	doc  yaml.Node
+}
+
+// DefaultPath returns the path of the configuration file in the user config
+// directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "subtitle-tools", "config.yaml"), nil
+}
+
+// Load reads the configuration file at path. A missing file is an empty
+// configuration.
+func Load(path string) (*File, error) {
+	f := &File{Path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &f.doc); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if root := f.root(); root != nil && root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parse config %s: expected a mapping of flag names", path)
+	}
+	return f, nil
+}
+
+// Exists reports whether the file was read from disk.
+func (f *File) Exists() bool {
+	return f.root() != nil
+}
+
+// Values returns the flag values for a command: the top-level keys,
+// overridden by the keys of each of the given sections in order. A scalar is
+// a single-item list.
+func (f *File) Values(sections ...string) (map[string][]string, error) {
+	root := f.root()
+	if root == nil {
+		return nil, nil
+	}
+	values := make(map[string][]string)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i].Value, root.Content[i+1]
+		if val.Kind == yaml.MappingNode {
+			continue // a command section or the profiles
+		}
+		v, err := nodeValues(f.Path, key, val)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = v
+	}
+	for _, name := range sections {
+		section, err := f.Section(name)
+		if err != nil {
+			return nil, err
+		}
+		for key, v := range section {
+			values[key] = v
+		}
+	}
+	return values, nil
+}
+
+// Section returns the flag values of a command section.
+func (f *File) Section(name string) (map[string][]string, error) {
+	root := f.root()
+	if root == nil {
+		return nil, nil
+	}
+	return mappingValues(f.Path, name, mappingValue(root, name))
+}
+
+// Profile returns the flag values of a named profile.
+func (f *File) Profile(name string) (map[string][]string, error) {
+	var profile *yaml.Node
+	if root := f.root(); root != nil {
+		if profiles := mappingValue(root, ProfilesKey); profiles != nil {
+			profile = mappingValue(profiles, name)
+		}
+	}
+	if profile == nil {
+		names := f.Profiles()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown profile %q (no profiles in %s)", name, f.Path)
+		}
+		return nil, fmt.Errorf("unknown profile %q (defined in %s: %s)", name, f.Path, strings.Join(names, ", "))
+	}
+	return mappingValues(f.Path, ProfilesKey+"."+name, profile)
+}
+
+// Profiles returns the names of the profiles, in file order.
+func (f *File) Profiles() []string {
+	root := f.root()
+	if root == nil {
+		return nil
+	}
+	profiles := mappingValue(root, ProfilesKey)
+	if profiles == nil || profiles.Kind != yaml.MappingNode {
+		return nil
+	}
+	var names []string
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		names = append(names, profiles.Content[i].Value)
+	}
+	return names
+}
+
+// Set sets key, a flag name optionally prefixed with the sections that hold
+// it ("translate.model", "profiles.local.model"), to values: a scalar when
+// there is one value, a list otherwise. Comments and the order of the other
+// keys are kept.
+func (f *File) Set(key string, values []string) error {
+	if len(values) == 0 {
+		return errors.New("missing value")
+	}
+	path := strings.Split(key, ".")
+	if slices.Contains(path, "") {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	sections, name := path[:len(path)-1], path[len(path)-1]
+
+	if f.root() == nil {
+		f.doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	m := f.root()
+	for i, section := range sections {
+		s := mappingValue(m, section)
+		if s == nil {
+			s = &yaml.Node{Kind: yaml.MappingNode}
+			m.Content = append(m.Content, scalarNode(section), s)
+		} else if s.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a section", strings.Join(path[:i+1], "."))
+		}
+		m = s
+	}
+	if v := mappingValue(m, name); v != nil && v.Kind == yaml.MappingNode {
+		return fmt.Errorf("%s is a section", key)
+	}
+
+	val := scalarNode(values[0])
+	if len(values) > 1 {
+		val = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, v := range values {
+			val.Content = append(val.Content, scalarNode(v))
+		}
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == name {
+			val.LineComment = m.Content[i+1].LineComment
+			m.Content[i+1] = val
+			return nil
+		}
+	}
+	m.Content = append(m.Content, scalarNode(name), val)
+	return nil
+}
+
+// Bytes returns the YAML document.
+func (f *File) Bytes() ([]byte, error) {
+	if f.root() == nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&f.doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the file, creating its directory. The file is only readable by
+// the user, as it may hold API keys.
+func (f *File) Save() error {
+	b, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, b, 0o600)
+}
+
+func (f *File) root() *yaml.Node {
+	if f.doc.Kind != yaml.DocumentNode || len(f.doc.Content) == 0 {
+		return nil
+	}
+	return f.doc.Content[0]
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingValues returns the flag values of a section (nil when missing).
+func mappingValues(path, name string, section *yaml.Node) (map[string][]string, error) {
+	if section == nil {
+		return nil, nil
+	}
+	if section.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config %s: %s must be a mapping of flag names", path, name)
+	}
+	values := make(map[string][]string)
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		key := section.Content[i].Value
+		v, err := nodeValues(path, name+"."+key, section.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		values[key] = v
+	}
+	return values, nil
+}
+
+func nodeValues(path, key string, n *yaml.Node) ([]string, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return []string{expandHome(n.Value)}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("config %s: %s must be a list of values", path, key)
+			}
+			values = append(values, expandHome(item.Value))
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("config %s: %s must be a value or a list of values", path, key)
+	}
+}
+
+// expandHome replaces a leading "~/" with the home directory, as the shell
+// would for a path on the command line.
+func expandHome(v string) string {
+	rest, ok := strings.CutPrefix(v, "~/")
+	if !ok {
+		return v
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return v
+	}
+	return filepath.Join(home, rest)
+}
+
+func scalarNode(v string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	// Values such as "true" or "1.5" are written unquoted: flags parse them
+	// from their text either way.
+	var probe any
+	if err := yaml.Unmarshal([]byte(v), &probe); err == nil {
+		switch probe.(type) {
+		case bool, int, float64:
+			n.Tag = ""
+		}
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad_ValuesMergeSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "# defaults\nworkdir: /tmp/st\nmodel: top\ninclude: ['*.srt', '*.ass']\ntranslate:\n  model: gpt-4o-mini # cheap\n  target-language: es\npipeline:\n  model: pipeline\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	values, err := cfg.Values("fix")
+	if err != nil {
+		t.Fatalf("Values: %v", err)
+	}
+	if got := values["model"]; !slices.Equal(got, []string{"top"}) {
+		t.Fatalf("fix model = %v", got)
+	}
+	if got := values["include"]; !slices.Equal(got, []string{"*.srt", "*.ass"}) {
+		t.Fatalf("fix include = %v", got)
+	}
+	if _, ok := values["target-language"]; ok {
+		t.Fatalf("translate section applied to fix: %v", values)
+	}
+
+	values, _ = cfg.Values("translate")
+	if got := values["model"]; !slices.Equal(got, []string{"gpt-4o-mini"}) {
+		t.Fatalf("translate model = %v", got)
+	}
+	// Later sections take precedence.
+	values, _ = cfg.Values("translate", "pipeline")
+	if got := values["model"]; !slices.Equal(got, []string{"pipeline"}) || values["target-language"] == nil {
+		t.Fatalf("translate+pipeline values = %v", values)
+	}
+}
+
+func TestLoad_MissingFileIsEmpty(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Exists() {
+		t.Fatalf("expected a missing file")
+	}
+	if values, err := cfg.Values("fix"); err != nil || len(values) != 0 {
+		t.Fatalf("Values = %v, %v", values, err)
+	}
+}
+
+func TestLoad_RejectsNestedValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("fix:\n  rules:\n    a: b\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := cfg.Values("fix"); err == nil || !strings.Contains(err.Error(), "fix.rules") {
+		t.Fatalf("expected an error naming fix.rules, got %v", err)
+	}
+}
+
+func TestSet_KeepsCommentsAndCreatesSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.yaml")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.Set("translate.model", []string{"a"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	b, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, []byte("# my settings\n"+string(b)), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if cfg, err = Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, set := range []struct {
+		key    string
+		values []string
+	}{
+		{"translate.model", []string{"b"}},
+		{"dry-run", []string{"true"}},
+		{"fix.include", []string{"*.srt", "*.ass"}},
+		{"fix.language", []string{"no"}},
+		{"profiles.local.model", []string{"ollama:llama3.1"}},
+	} {
+		if err := cfg.Set(set.key, set.values); err != nil {
+			t.Fatalf("Set %s: %v", set.key, err)
+		}
+	}
+	if err := cfg.Set("translate", []string{"x"}); err == nil {
+		t.Fatalf("expected an error replacing a section")
+	}
+	if err := cfg.Set("dry-run.model", []string{"x"}); err == nil {
+		t.Fatalf("expected an error using a value as a section")
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	b, _ = os.ReadFile(path)
+	want := "# my settings\ntranslate:\n  model: b\ndry-run: true\nfix:\n  include: ['*.srt', '*.ass']\n  language: no\nprofiles:\n  local:\n    model: ollama:llama3.1\n"
+	if string(b) != want {
+		t.Fatalf("unexpected file:\n%s\nwant:\n%s", b, want)
+	}
+	if st, _ := os.Stat(path); st.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, want 0600", st.Mode().Perm())
+	}
+}
+
+func TestProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "profiles:\n  work:\n    model: gpt-4o-mini\n    rps: 4\n  local:\n    model: ollama:llama3.1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Profiles(); !slices.Equal(got, []string{"work", "local"}) {
+		t.Fatalf("Profiles = %v", got)
+	}
+	values, err := cfg.Profile("work")
+	if err != nil || !slices.Equal(values["rps"], []string{"4"}) {
+		t.Fatalf("Profile(work) = %v, %v", values, err)
+	}
+	if _, err := cfg.Profile("home"); err == nil || !strings.Contains(err.Error(), "work, local") {
+		t.Fatalf("expected an unknown profile error listing the profiles, got %v", err)
+	}
+	// Profiles are not a command section.
+	if values, _ := cfg.Values("translate"); len(values) != 0 {
+		t.Fatalf("Values = %v", values)
+	}
+}
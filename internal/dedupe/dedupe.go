@@ -0,0 +1,253 @@
+// Package dedupe finds subtitle files of a directory with the same or nearly
+// the same text ("Movie.srt" and a re-timed "Movie.en.srt"), so the redundant
+// copies can be removed.
+package dedupe
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	stfs "github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// DefaultThreshold is the minimum similarity of two files to be reported as
+// duplicates.
+const DefaultThreshold = 0.9
+
+// shingleSize is the number of consecutive words compared. Comparing word
+// runs instead of cues makes the similarity independent of the timing and of
+// how the text is split into cues.
+const shingleSize = 3
+
+type Options struct {
+	Recursive bool    // also scan subdirectories
+	Threshold float64 // minimum similarity, in (0, 1] (0 means DefaultThreshold)
+}
+
+// File is a subtitle file of the scanned directory.
+type File struct {
+	Path     string `json:"path"`
+	Cues     int    `json:"cues"`
+	Language string `json:"language,omitempty"` // from the file name suffix
+}
+
+// Duplicate is a file whose text matches the one kept in its group.
+type Duplicate struct {
+	File
+	// Similarity is the share of word runs (shingles) both files have in
+	// common, from 0 to 1.
+	Similarity float64 `json:"similarity"`
+	// Identical is true when the files are byte for byte the same.
+	Identical bool `json:"identical"`
+}
+
+// Group is a file worth keeping and its redundant copies.
+type Group struct {
+	Keep       File        `json:"keep"`
+	Duplicates []Duplicate `json:"duplicates"`
+}
+
+type candidate struct {
+	File
+	tagged   bool
+	shingles map[string]struct{}
+}
+
+// Scan reads the .srt files of dir and groups the duplicates. The file kept
+// in a group is the one with a language in its name, then the one with more
+// cues. Files that can't be parsed are skipped with a warning.
+func Scan(dir string, opts Options) ([]Group, error) {
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1, got %g", threshold)
+	}
+
+	paths, err := findSubtitles(dir, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*candidate
+	for _, p := range paths {
+		c, err := load(p)
+		if err != nil {
+			slog.Warn("skipping unreadable subtitle file", "path", p, "error", err)
+			continue
+		}
+		if len(c.shingles) == 0 {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	slices.SortStableFunc(candidates, func(a, b *candidate) int {
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(b.Cues, a.Cues); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	// Every file is compared with the kept file of each group, so a copy is
+	// only ever removed in favor of a file it matches.
+	type cluster struct {
+		keep  *candidate
+		group Group
+	}
+	var clusters []*cluster
+	for _, c := range candidates {
+		var best *cluster
+		bestSim := 0.0
+		for _, cl := range clusters {
+			// The index can't exceed the ratio of the set sizes.
+			if !sizesWithin(len(cl.keep.shingles), len(c.shingles), threshold) {
+				continue
+			}
+			if sim := similarity(cl.keep.shingles, c.shingles); sim >= threshold && sim > bestSim {
+				best, bestSim = cl, sim
+			}
+		}
+		if best == nil {
+			clusters = append(clusters, &cluster{keep: c, group: Group{Keep: c.File}})
+			continue
+		}
+		identical, err := stfs.FilesEqual(best.keep.Path, c.Path)
+		if err != nil {
+			return nil, err
+		}
+		best.group.Duplicates = append(best.group.Duplicates, Duplicate{
+			File:       c.File,
+			Similarity: math.Floor(bestSim*1000) / 1000,
+			Identical:  identical,
+		})
+	}
+
+	var groups []Group
+	for _, cl := range clusters {
+		if len(cl.group.Duplicates) > 0 {
+			groups = append(groups, cl.group)
+		}
+	}
+	slices.SortFunc(groups, func(a, b Group) int { return cmp.Compare(a.Keep.Path, b.Keep.Path) })
+	return groups, nil
+}
+
+// Remove deletes the duplicates of groups and returns the removed paths.
+func Remove(groups []Group) ([]string, error) {
+	var removed []string
+	for _, g := range groups {
+		for _, d := range g.Duplicates {
+			if err := os.Remove(d.Path); err != nil {
+				return removed, err
+			}
+			removed = append(removed, d.Path)
+		}
+	}
+	return removed, nil
+}
+
+// findSubtitles returns the .srt files of dir, sorted by path.
+func findSubtitles(dir string, recursive bool) ([]string, error) {
+	st, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && strings.EqualFold(filepath.Ext(path), ".srt") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+func load(path string) (*candidate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stfs.CloseOrLog(f, path)
+	subs, err := srt.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var words []string
+	for _, sub := range subs {
+		words = append(words, splitWords(srt.VisibleText(sub.Text))...)
+	}
+	_, name := naming.Parse(path)
+	return &candidate{
+		File:     File{Path: path, Cues: len(subs), Language: name.Language},
+		tagged:   name.Language != "",
+		shingles: shingles(words),
+	}, nil
+}
+
+// splitWords returns the lowercase words of text, without punctuation.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// shingles returns the runs of shingleSize consecutive words (or the whole
+// text, when shorter).
+func shingles(words []string) map[string]struct{} {
+	out := make(map[string]struct{})
+	if len(words) == 0 {
+		return out
+	}
+	n := min(shingleSize, len(words))
+	for i := 0; i+n <= len(words); i++ {
+		out[strings.Join(words[i:i+n], " ")] = struct{}{}
+	}
+	return out
+}
+
+func sizesWithin(a, b int, threshold float64) bool {
+	return float64(min(a, b)) >= threshold*float64(max(a, b))
+}
+
+// similarity is the Jaccard index of two shingle sets.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	common := 0
+	for s := range a {
+		if _, ok := b[s]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
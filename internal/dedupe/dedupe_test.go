@@ -0,0 +1,101 @@
+package dedupe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScan_GroupsDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	original := "1\n00:00:01,000 --> 00:00:03,000\nWhere are you going tonight?\n\n2\n00:00:04,000 --> 00:00:06,000\n<i>Nowhere special, just out.</i>\n\n3\n00:00:07,000 --> 00:00:09,000\nThen take the umbrella with you.\n\n"
+	// Same text, re-timed and split into different cues.
+	retimed := "1\n00:00:02,000 --> 00:00:05,000\nWhere are you going tonight?\nNowhere special, just out.\n\n2\n00:00:08,000 --> 00:00:10,000\nThen take the umbrella with you.\n\n"
+	other := "1\n00:00:01,000 --> 00:00:03,000\nA completely different movie.\n\n"
+	files := map[string]string{
+		"Movie.srt":         original,
+		"Movie.en.srt":      retimed,
+		"Movie.copy.srt":    original,
+		"Other.srt":         other,
+		"sub/Movie.en.srt":  original,
+		"notes.txt":         original,
+		"Empty.srt":         "",
+		"Broken.srt":        "not a subtitle",
+		"Other.es.srt":      "1\n00:00:01,000 --> 00:00:03,000\nUna película completamente distinta.\n\n",
+		"sub/Other.srt.bak": other,
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	groups, err := Scan(dir, Options{})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group, got %+v", groups)
+	}
+	g := groups[0]
+	// The file with a language in its name is kept.
+	if g.Keep.Path != filepath.Join(dir, "Movie.en.srt") || g.Keep.Language != "en" || g.Keep.Cues != 2 {
+		t.Fatalf("unexpected kept file: %+v", g.Keep)
+	}
+	if len(g.Duplicates) != 2 {
+		t.Fatalf("expected 2 duplicates, got %+v", g.Duplicates)
+	}
+	for _, d := range g.Duplicates {
+		if d.Similarity != 1 || d.Identical {
+			t.Fatalf("unexpected duplicate: %+v", d)
+		}
+	}
+
+	// Subdirectories are only scanned with Recursive; the identical copy is
+	// kept over the others as it is tagged and has more cues.
+	groups, err = Scan(dir, Options{Recursive: true})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Keep.Path != filepath.Join(dir, "sub", "Movie.en.srt") || len(groups[0].Duplicates) != 3 {
+		t.Fatalf("unexpected recursive groups: %+v", groups)
+	}
+	identical := 0
+	for _, d := range groups[0].Duplicates {
+		if d.Identical {
+			identical++
+		}
+	}
+	if identical != 2 {
+		t.Fatalf("expected 2 identical duplicates, got %+v", groups[0].Duplicates)
+	}
+
+	removed, err := Remove(groups)
+	if err != nil || len(removed) != 3 {
+		t.Fatalf("Remove: %v %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Movie.srt")); !os.IsNotExist(err) {
+		t.Fatalf("expected Movie.srt to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "Movie.en.srt")); err != nil {
+		t.Fatalf("expected the kept file to remain: %v", err)
+	}
+}
+
+func TestSimilarity_Threshold(t *testing.T) {
+	words := func(s string) map[string]struct{} {
+		return shingles(splitWords(s))
+	}
+	a := words("one two three four five six seven eight nine ten")
+	b := words("one two three four five six seven eight nine eleven")
+	// 8 shingles each, 7 in common: 7/9.
+	if got := similarity(a, b); got < 0.77 || got > 0.78 {
+		t.Fatalf("similarity = %g, want 7/9", got)
+	}
+	if got := similarity(a, a); got != 1 {
+		t.Fatalf("similarity of a set with itself = %g", got)
+	}
+}
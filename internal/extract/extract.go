@@ -0,0 +1,300 @@
+// Package extract pulls subtitle tracks out of video containers (mkv, mp4...)
+// using the tools found on PATH: ffmpeg/ffprobe or MKVToolNix
+// (mkvmerge/mkvextract).
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Tools used to read the container.
+const (
+	ToolAuto       = "auto" // ffmpeg if available, otherwise mkvextract
+	ToolFFmpeg     = "ffmpeg"
+	ToolMKVExtract = "mkvextract"
+)
+
+const DefaultTool = ToolAuto
+
+// NoTrack selects the first text subtitle track (preferring default tracks).
+const NoTrack = -1
+
+var (
+	ErrNoTool          = errors.New("no extraction tool found on PATH (install ffmpeg or mkvtoolnix)")
+	ErrNoSubtitleTrack = errors.New("no subtitle tracks found")
+)
+
+// Track is a subtitle track of a container.
+type Track struct {
+	// ID is the stream index in the container, as accepted by Options.Track.
+	ID       int    `json:"id"`
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Default  bool   `json:"default"`
+	Forced   bool   `json:"forced"`
+	// Text is false for bitmap subtitles (PGS, VobSub, DVB), which must be
+	// OCR'd instead of extracted.
+	Text bool `json:"text"`
+}
+
+type Options struct {
+	InputPath  string
+	OutputPath string // empty means <input>.<language>.srt next to the input
+	DryRun     bool
+	WorkDir    string
+	Track      int    // container stream index (NoTrack picks the first text track)
+	Tool       string // auto, ffmpeg or mkvextract
+	// Naming is the media server naming scheme (naming.SchemePlex or
+	// naming.SchemeJellyfin) of the default output path.
+	Naming string
+}
+
+type Result struct {
+	WrittenPath string
+	Track       Track
+	Cues        int
+}
+
+// backend lists and extracts tracks with an external tool.
+type backend interface {
+	name() string
+	list(ctx context.Context, inputPath string) ([]Track, error)
+	// extract writes track as SRT to outputPath.
+	extract(ctx context.Context, inputPath string, track Track, outputPath string) error
+}
+
+// lookPath is replaced in tests.
+var lookPath = exec.LookPath
+
+func NormalizeTool(tool string) string {
+	return strings.ToLower(strings.TrimSpace(tool))
+}
+
+func IsValidTool(tool string) bool {
+	return tool == ToolAuto || tool == ToolFFmpeg || tool == ToolMKVExtract
+}
+
+// newBackend returns the backend for tool, checking that its binaries are on
+// PATH.
+func newBackend(tool string) (backend, error) {
+	tool = NormalizeTool(tool)
+	if tool == "" {
+		tool = DefaultTool
+	}
+	if !IsValidTool(tool) {
+		return nil, fmt.Errorf("invalid tool %q (supported: %s, %s, %s)", tool, ToolAuto, ToolFFmpeg, ToolMKVExtract)
+	}
+	candidates := []string{tool}
+	if tool == ToolAuto {
+		candidates = []string{ToolFFmpeg, ToolMKVExtract}
+	}
+	var missing []string
+	for _, c := range candidates {
+		var bins []string
+		switch c {
+		case ToolFFmpeg:
+			bins = []string{"ffprobe", "ffmpeg"}
+		case ToolMKVExtract:
+			bins = []string{"mkvmerge", "mkvextract"}
+		}
+		paths := make([]string, len(bins))
+		ok := true
+		for i, b := range bins {
+			p, err := lookPath(b)
+			if err != nil {
+				missing = append(missing, b)
+				ok = false
+				break
+			}
+			paths[i] = p
+		}
+		if !ok {
+			continue
+		}
+		if c == ToolFFmpeg {
+			return ffmpegBackend{ffprobe: paths[0], ffmpeg: paths[1]}, nil
+		}
+		return mkvtoolnixBackend{mkvmerge: paths[0], mkvextract: paths[1]}, nil
+	}
+	if tool == ToolAuto {
+		return nil, ErrNoTool
+	}
+	return nil, fmt.Errorf("%s not found on PATH (missing %s)", tool, strings.Join(missing, ", "))
+}
+
+// ListTracks returns the subtitle tracks of inputPath.
+func ListTracks(ctx context.Context, inputPath string, tool string) ([]Track, error) {
+	if inputPath == "" {
+		return nil, errors.New("input path is required")
+	}
+	b, err := newBackend(tool)
+	if err != nil {
+		return nil, err
+	}
+	return b.list(ctx, inputPath)
+}
+
+// Run extracts a subtitle track as SRT.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if opts.InputPath == "" {
+		return Result{}, errors.New("input path is required")
+	}
+	if opts.WorkDir == "" {
+		return Result{}, errors.New("workdir is required (create one with run.NewWorkdir)")
+	}
+	b, err := newBackend(opts.Tool)
+	if err != nil {
+		return Result{}, err
+	}
+	tracks, err := b.list(ctx, opts.InputPath)
+	if err != nil {
+		return Result{}, err
+	}
+	track, err := selectTrack(tracks, opts.Track)
+	if err != nil {
+		return Result{}, err
+	}
+
+	outputPath := opts.OutputPath
+	if outputPath == "" && opts.Naming != "" {
+		name := naming.Name{Language: track.Language, Forced: track.Forced}
+		if name.Language == naming.Undetermined {
+			name.Language = ""
+		}
+		outputPath = naming.Path(opts.InputPath, opts.Naming, name)
+	} else if outputPath == "" {
+		outputPath = DefaultOutputPath(opts.InputPath, track)
+	}
+	if !opts.DryRun {
+		if _, err := os.Stat(outputPath); err == nil {
+			return Result{}, fmt.Errorf("output file already exists: %s", outputPath)
+		}
+	}
+
+	slog.Info("extracting subtitle track", "input_path", opts.InputPath, "track", track.ID, "codec", track.Codec, "language", track.Language, "tool", b.name())
+
+	namer := run.NewTempNamer(opts.WorkDir, outputPath)
+	tmpPath := namer.Step("extract")
+	if err := b.extract(ctx, opts.InputPath, track, tmpPath); err != nil {
+		return Result{}, err
+	}
+	cues, err := countCues(tmpPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("extracted track is not valid srt: %w", err)
+	}
+
+	if opts.DryRun {
+		outputPath = tmpPath
+	} else if err := fs.MoveFile(tmpPath, outputPath); err != nil {
+		return Result{}, err
+	}
+	return Result{WrittenPath: outputPath, Track: track, Cues: cues}, nil
+}
+
+// selectTrack returns the track with the given id, or the first text track
+// (default tracks first, forced tracks last) when id is NoTrack.
+func selectTrack(tracks []Track, id int) (Track, error) {
+	if len(tracks) == 0 {
+		return Track{}, ErrNoSubtitleTrack
+	}
+	if id != NoTrack {
+		for _, t := range tracks {
+			if t.ID != id {
+				continue
+			}
+			if !t.Text {
+				return Track{}, fmt.Errorf("track %d is a bitmap subtitle (%s) and can't be converted to srt; it needs OCR", t.ID, t.Codec)
+			}
+			return t, nil
+		}
+		return Track{}, fmt.Errorf("track %d is not a subtitle track (available: %s)", id, trackIDs(tracks))
+	}
+
+	var best *Track
+	rank := func(t Track) int {
+		switch {
+		case t.Default && !t.Forced:
+			return 0
+		case !t.Forced:
+			return 1
+		default:
+			return 2
+		}
+	}
+	for i := range tracks {
+		t := tracks[i]
+		if t.Text && (best == nil || rank(t) < rank(*best)) {
+			best = &tracks[i]
+		}
+	}
+	if best == nil {
+		return Track{}, fmt.Errorf("no text subtitle tracks found (bitmap tracks: %s)", trackIDs(tracks))
+	}
+	return *best, nil
+}
+
+func trackIDs(tracks []Track) string {
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		ids[i] = fmt.Sprint(t.ID)
+	}
+	return strings.Join(ids, ", ")
+}
+
+// DefaultOutputPath is <input>.<language>.srt next to the input, or
+// <input>.track<id>.srt when the track has no language.
+func DefaultOutputPath(inputPath string, track Track) string {
+	label := track.Language
+	if label == "" || label == "und" {
+		label = fmt.Sprintf("track%d", track.ID)
+	}
+	if track.Forced {
+		label += ".forced"
+	}
+	return strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "." + label + ".srt"
+}
+
+func countCues(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer fs.CloseOrLog(f, path)
+	subs, err := srt.ReadAll(f)
+	if err != nil {
+		return 0, err
+	}
+	return len(subs), nil
+}
+
+// runTool runs a command and returns its stdout; stderr is included in the
+// error.
+func runTool(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	slog.Debug("running extraction tool", "bin", bin, "args", args)
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return nil, fmt.Errorf("%s: %w: %s", filepath.Base(bin), err, msg)
+	}
+	return stdout.Bytes(), nil
+}
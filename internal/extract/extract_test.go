@@ -0,0 +1,148 @@
+package extract
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFFprobeTracks(t *testing.T) {
+	out := []byte(`{"streams":[
+		{"index":2,"codec_name":"subrip","disposition":{"default":1,"forced":0},"tags":{"language":"eng","title":"English"}},
+		{"index":3,"codec_name":"hdmv_pgs_subtitle","disposition":{"default":0,"forced":0},"tags":{"language":"spa"}},
+		{"index":4,"codec_name":"ass","disposition":{"default":0,"forced":1}}
+	]}`)
+	tracks, err := parseFFprobeTracks(out)
+	if err != nil {
+		t.Fatalf("parseFFprobeTracks: %v", err)
+	}
+	want := []Track{
+		{ID: 2, Codec: "subrip", Language: "eng", Title: "English", Default: true, Text: true},
+		{ID: 3, Codec: "hdmv_pgs_subtitle", Language: "spa"},
+		{ID: 4, Codec: "ass", Forced: true, Text: true},
+	}
+	if len(tracks) != len(want) {
+		t.Fatalf("expected %d tracks, got %+v", len(want), tracks)
+	}
+	for i := range want {
+		if tracks[i] != want[i] {
+			t.Fatalf("track %d = %+v, want %+v", i, tracks[i], want[i])
+		}
+	}
+}
+
+func TestParseFFprobeDuration(t *testing.T) {
+	got, err := parseFFprobeDuration([]byte(`{"format":{"duration":"6133.0416"}}`))
+	if err != nil {
+		t.Fatalf("parseFFprobeDuration: %v", err)
+	}
+	if want := time.Hour + 42*time.Minute + 13*time.Second + 42*time.Millisecond; got != want {
+		t.Fatalf("duration = %s, want %s", got, want)
+	}
+	if _, err := parseFFprobeDuration([]byte(`{"format":{}}`)); err == nil {
+		t.Fatalf("expected an error without duration")
+	}
+}
+
+func TestParseMkvmergeTracks(t *testing.T) {
+	out := []byte(`{"tracks":[
+		{"id":0,"type":"video","codec":"AVC/H.264/MPEG-4p10","properties":{"codec_id":"V_MPEG4/ISO/AVC"}},
+		{"id":2,"type":"subtitles","codec":"SubRip/SRT","properties":{"codec_id":"S_TEXT/UTF8","language":"eng","track_name":"Full","default_track":true,"forced_track":false}},
+		{"id":3,"type":"subtitles","codec":"HDMV PGS","properties":{"codec_id":"S_HDMV/PGS","language":"spa","default_track":false,"forced_track":true}}
+	]}`)
+	tracks, err := parseMkvmergeTracks(out)
+	if err != nil {
+		t.Fatalf("parseMkvmergeTracks: %v", err)
+	}
+	want := []Track{
+		{ID: 2, Codec: "S_TEXT/UTF8", Language: "eng", Title: "Full", Default: true, Text: true},
+		{ID: 3, Codec: "S_HDMV/PGS", Language: "spa", Forced: true},
+	}
+	if len(tracks) != len(want) {
+		t.Fatalf("expected %d tracks, got %+v", len(want), tracks)
+	}
+	for i := range want {
+		if tracks[i] != want[i] {
+			t.Fatalf("track %d = %+v, want %+v", i, tracks[i], want[i])
+		}
+	}
+}
+
+func TestSelectTrack(t *testing.T) {
+	tracks := []Track{
+		{ID: 2, Codec: "subrip", Forced: true, Text: true},
+		{ID: 3, Codec: "hdmv_pgs_subtitle", Default: true},
+		{ID: 4, Codec: "subrip", Text: true},
+		{ID: 5, Codec: "subrip", Default: true, Text: true},
+	}
+	got, err := selectTrack(tracks, NoTrack)
+	if err != nil || got.ID != 5 {
+		t.Fatalf("expected the default text track 5, got %+v (%v)", got, err)
+	}
+	got, err = selectTrack(tracks[:3], NoTrack)
+	if err != nil || got.ID != 4 {
+		t.Fatalf("expected the non-forced text track 4, got %+v (%v)", got, err)
+	}
+	got, err = selectTrack(tracks, 2)
+	if err != nil || got.ID != 2 {
+		t.Fatalf("expected track 2, got %+v (%v)", got, err)
+	}
+	if _, err := selectTrack(tracks, 3); err == nil || !strings.Contains(err.Error(), "OCR") {
+		t.Fatalf("expected a bitmap error for track 3, got %v", err)
+	}
+	if _, err := selectTrack(tracks, 9); err == nil || !strings.Contains(err.Error(), "available: 2, 3, 4, 5") {
+		t.Fatalf("expected an unknown track error, got %v", err)
+	}
+	if _, err := selectTrack(nil, NoTrack); !errors.Is(err, ErrNoSubtitleTrack) {
+		t.Fatalf("expected ErrNoSubtitleTrack, got %v", err)
+	}
+}
+
+func TestDefaultOutputPath(t *testing.T) {
+	cases := []struct {
+		track Track
+		want  string
+	}{
+		{Track{ID: 2, Language: "eng"}, "/media/movie.eng.srt"},
+		{Track{ID: 3, Language: "spa", Forced: true}, "/media/movie.spa.forced.srt"},
+		{Track{ID: 4, Language: "und"}, "/media/movie.track4.srt"},
+	}
+	for _, c := range cases {
+		if got := DefaultOutputPath("/media/movie.mkv", c.track); got != c.want {
+			t.Fatalf("DefaultOutputPath(%+v) = %q, want %q", c.track, got, c.want)
+		}
+	}
+}
+
+func TestNewBackend_AutoFallsBackToMKVToolNix(t *testing.T) {
+	available := map[string]bool{"mkvmerge": true, "mkvextract": true}
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+	lookPath = func(name string) (string, error) {
+		if available[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	b, err := newBackend(ToolAuto)
+	if err != nil {
+		t.Fatalf("newBackend: %v", err)
+	}
+	if b.name() != ToolMKVExtract {
+		t.Fatalf("expected mkvextract backend, got %s", b.name())
+	}
+	if _, err := newBackend(ToolFFmpeg); err == nil || !strings.Contains(err.Error(), "ffprobe") {
+		t.Fatalf("expected missing ffprobe error, got %v", err)
+	}
+
+	available = map[string]bool{}
+	if _, err := newBackend(""); !errors.Is(err, ErrNoTool) {
+		t.Fatalf("expected ErrNoTool, got %v", err)
+	}
+	if _, err := newBackend("vlc"); err == nil {
+		t.Fatal("expected invalid tool error")
+	}
+}
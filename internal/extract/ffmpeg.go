@@ -0,0 +1,122 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ffmpegBitmapCodecs are image-based subtitle codecs, which ffmpeg can't
+// convert to srt.
+var ffmpegBitmapCodecs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"dvd_subtitle":      true,
+	"dvb_subtitle":      true,
+	"xsub":              true,
+}
+
+// ffmpegBackend lists tracks with ffprobe and converts them with ffmpeg, so any
+// text codec (srt, ass, webvtt, mov_text...) can be extracted.
+type ffmpegBackend struct {
+	ffprobe string
+	ffmpeg  string
+}
+
+func (ffmpegBackend) name() string { return ToolFFmpeg }
+
+type ffprobeOutput struct {
+	Streams []struct {
+		Index       int    `json:"index"`
+		CodecName   string `json:"codec_name"`
+		Disposition struct {
+			Default int `json:"default"`
+			Forced  int `json:"forced"`
+		} `json:"disposition"`
+		Tags struct {
+			Language string `json:"language"`
+			Title    string `json:"title"`
+		} `json:"tags"`
+	} `json:"streams"`
+}
+
+func (b ffmpegBackend) list(ctx context.Context, inputPath string) ([]Track, error) {
+	out, err := runTool(ctx, b.ffprobe,
+		"-v", "error",
+		"-select_streams", "s",
+		"-show_entries", "stream=index,codec_name:stream_disposition=default,forced:stream_tags=language,title",
+		"-of", "json",
+		inputPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseFFprobeTracks(out)
+}
+
+func parseFFprobeTracks(out []byte) ([]Track, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	tracks := make([]Track, 0, len(probe.Streams))
+	for _, s := range probe.Streams {
+		tracks = append(tracks, Track{
+			ID:       s.Index,
+			Codec:    s.CodecName,
+			Language: s.Tags.Language,
+			Title:    s.Tags.Title,
+			Default:  s.Disposition.Default == 1,
+			Forced:   s.Disposition.Forced == 1,
+			Text:     !ffmpegBitmapCodecs[s.CodecName],
+		})
+	}
+	return tracks, nil
+}
+
+func (b ffmpegBackend) extract(ctx context.Context, inputPath string, track Track, outputPath string) error {
+	_, err := runTool(ctx, b.ffmpeg,
+		"-nostdin", "-v", "error", "-y",
+		"-i", inputPath,
+		"-map", fmt.Sprintf("0:%d", track.ID),
+		"-c:s", "srt", "-f", "srt",
+		outputPath)
+	return err
+}
+
+type ffprobeFormatOutput struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// MediaDuration returns the duration of the media file inputPath, read with
+// ffprobe.
+func MediaDuration(ctx context.Context, inputPath string) (time.Duration, error) {
+	ffprobe, err := lookPath("ffprobe")
+	if err != nil {
+		return 0, errors.New("ffprobe not found on PATH (install ffmpeg)")
+	}
+	out, err := runTool(ctx, ffprobe,
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "json",
+		inputPath)
+	if err != nil {
+		return 0, err
+	}
+	return parseFFprobeDuration(out)
+}
+
+func parseFFprobeDuration(out []byte) (time.Duration, error) {
+	var probe ffprobeFormatOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return 0, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("ffprobe reported no duration (%q)", probe.Format.Duration)
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond), nil
+}
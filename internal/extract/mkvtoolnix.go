@@ -0,0 +1,86 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// mkvSRTCodecID is the only codec mkvextract writes as srt.
+const mkvSRTCodecID = "S_TEXT/UTF8"
+
+// mkvBitmapCodecIDs are image-based Matroska subtitle codecs.
+var mkvBitmapCodecIDs = map[string]bool{
+	"S_HDMV/PGS": true,
+	"S_VOBSUB":   true,
+	"S_DVBSUB":   true,
+}
+
+// mkvtoolnixBackend lists tracks with mkvmerge and extracts them with
+// mkvextract. mkvextract keeps the native format, so only SRT tracks can be
+// extracted (other text formats need ffmpeg).
+type mkvtoolnixBackend struct {
+	mkvmerge   string
+	mkvextract string
+}
+
+func (mkvtoolnixBackend) name() string { return ToolMKVExtract }
+
+type mkvmergeOutput struct {
+	Tracks []struct {
+		ID         int    `json:"id"`
+		Type       string `json:"type"`
+		Codec      string `json:"codec"`
+		Properties struct {
+			CodecID      string `json:"codec_id"`
+			Language     string `json:"language"`
+			TrackName    string `json:"track_name"`
+			DefaultTrack bool   `json:"default_track"`
+			ForcedTrack  bool   `json:"forced_track"`
+		} `json:"properties"`
+	} `json:"tracks"`
+}
+
+func (b mkvtoolnixBackend) list(ctx context.Context, inputPath string) ([]Track, error) {
+	out, err := runTool(ctx, b.mkvmerge, "-J", inputPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseMkvmergeTracks(out)
+}
+
+func parseMkvmergeTracks(out []byte) ([]Track, error) {
+	var info mkvmergeOutput
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("decode mkvmerge output: %w", err)
+	}
+	var tracks []Track
+	for _, t := range info.Tracks {
+		if t.Type != "subtitles" {
+			continue
+		}
+		codec := t.Properties.CodecID
+		if codec == "" {
+			codec = t.Codec
+		}
+		tracks = append(tracks, Track{
+			ID:       t.ID,
+			Codec:    codec,
+			Language: t.Properties.Language,
+			Title:    t.Properties.TrackName,
+			Default:  t.Properties.DefaultTrack,
+			Forced:   t.Properties.ForcedTrack,
+			Text:     !mkvBitmapCodecIDs[codec],
+		})
+	}
+	return tracks, nil
+}
+
+func (b mkvtoolnixBackend) extract(ctx context.Context, inputPath string, track Track, outputPath string) error {
+	if track.Codec != mkvSRTCodecID {
+		return fmt.Errorf("mkvextract can only extract srt (%s) tracks; track %d is %s (install ffmpeg to convert it)", mkvSRTCodecID, track.ID, track.Codec)
+	}
+	_, err := runTool(ctx, b.mkvextract, inputPath, "tracks", strconv.Itoa(track.ID)+":"+outputPath)
+	return err
+}
@@ -0,0 +1,33 @@
+package fix
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// assOverridePattern matches ASS override blocks, which many SRT files carry
+// for positioning ({\an8}) or styling ({\i1}, {\c&H00FFFF&}).
+var assOverridePattern = regexp.MustCompile(`\{\\[^{}]*\}`)
+
+// stripASSOverrides removes the ASS override blocks of text, dropping the
+// lines left empty.
+func stripASSOverrides(text string) string {
+	if !strings.Contains(text, `{\`) {
+		return text
+	}
+	var kept []string
+	for _, line := range strings.Split(assOverridePattern.ReplaceAllString(text, ""), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return srt.CleanText(strings.Join(kept, "\n"))
+}
+
+// textLength is the length of text used for wrapping, in columns (see
+// srt.DisplayWidth): tags are not shown on screen, so they don't count.
+func textLength(text string) int {
+	return srt.DisplayWidth(text)
+}
@@ -0,0 +1,196 @@
+package fix
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// noBreakAfterWords are the words (articles, prepositions, conjunctions...) a
+// line should not end with, by ISO 639-1 language code.
+var noBreakAfterWords = map[string][]string{
+	"en": {"a", "an", "the", "of", "to", "in", "on", "at", "by", "for", "with", "from", "into", "and", "or", "but", "my", "your", "his", "her", "our", "their", "its"},
+	"es": {"el", "la", "los", "las", "un", "una", "unos", "unas", "lo", "al", "del", "de", "a", "en", "con", "por", "para", "sin", "sobre", "y", "e", "o", "u", "que", "mi", "tu", "su", "mis", "tus", "sus"},
+	"pt": {"o", "a", "os", "as", "um", "uma", "uns", "umas", "de", "do", "da", "dos", "das", "em", "no", "na", "nos", "nas", "com", "por", "para", "sem", "e", "ou", "que", "meu", "minha", "seu", "sua"},
+	"fr": {"le", "la", "les", "l'", "un", "une", "des", "du", "de", "d'", "au", "aux", "à", "en", "dans", "sur", "par", "pour", "avec", "sans", "et", "ou", "que", "mon", "ma", "mes", "ton", "ta", "tes", "son", "sa", "ses"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "un", "uno", "una", "di", "a", "da", "in", "con", "su", "per", "tra", "fra", "del", "della", "al", "alla", "nel", "nella", "e", "o", "che"},
+	"de": {"der", "die", "das", "den", "dem", "des", "ein", "eine", "einen", "einem", "einer", "eines", "zu", "in", "im", "an", "am", "auf", "mit", "von", "vom", "für", "bei", "aus", "nach", "und", "oder", "dass"},
+}
+
+// lineBreakRules scores the candidate positions for a line break.
+type lineBreakRules struct {
+	noBreakAfter map[string]struct{}
+}
+
+// newLineBreakRules returns the rules for language (any tag accepted by
+// naming.ShortLanguage). Unknown or empty languages only use punctuation.
+func newLineBreakRules(language string) lineBreakRules {
+	rules := lineBreakRules{noBreakAfter: make(map[string]struct{})}
+	primary, _, _ := strings.Cut(naming.ShortLanguage(language), "-")
+	for _, w := range noBreakAfterWords[primary] {
+		rules.noBreakAfter[w] = struct{}{}
+	}
+	return rules
+}
+
+// breakPenalty returns the cost of ending a line with word, in characters of
+// length difference between lines.
+func (r lineBreakRules) breakPenalty(word string) float64 {
+	visible := strings.TrimSpace(srt.VisibleText(word))
+	if visible == "" {
+		return 0
+	}
+	last, _ := utf8.DecodeLastRuneInString(visible)
+	switch last {
+	case '.', '!', '?', ';', ':', '。', '！', '？', '；', '：':
+		return -25
+	case ',', '、', '，':
+		return -15
+	}
+	if _, ok := r.noBreakAfter[strings.ToLower(visible)]; ok {
+		return 20
+	}
+	return 0
+}
+
+// isReflowable reports whether the lines of a cue can be joined and broken
+// again: dialogue cues ("- Hi." / "- Hello.") and tag-only lines are kept.
+func isReflowable(lines []string) bool {
+	for i, line := range lines {
+		if strings.TrimSpace(srt.VisibleText(line)) == "" {
+			return false
+		}
+		if i > 0 && strings.HasPrefix(strings.TrimSpace(line), "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// IsReflowable reports whether the lines of text can be joined and broken
+// again (see BalanceLines): dialogue cues and text with blank lines can't.
+func IsReflowable(text string) bool {
+	return isReflowable(strings.Split(text, "\n"))
+}
+
+// reflowLines breaks text into at most maxLines lines (0 means no limit) of
+// balanced length, keeping its number of lines when under the limit. When
+// balance is false, text is only changed if it has too many lines. The line limit wins over maxLen: text that doesn't fit is split
+// into maxLines lines longer than maxLen.
+func reflowLines(text string, maxLen, maxLines int, balance bool, rules lineBreakRules) string {
+	lines := strings.Split(text, "\n")
+	overLimit := maxLines > 0 && len(lines) > maxLines
+	if (!balance && !overLimit) || !isReflowable(lines) {
+		return text
+	}
+	words := tokenize(text)
+	if len(words) < 2 {
+		return text
+	}
+
+	// Keep the number of lines (already wrapped to maxLen) unless over the limit.
+	n := len(lines)
+	if overLimit {
+		n = maxLines
+	}
+	n = min(n, len(words))
+	if n == 1 {
+		return joinTokens(words)
+	}
+	return strings.Join(balanceWords(words, n, maxLen, rules), "\n")
+}
+
+// ReflowLines breaks text with more than maxLines lines again into maxLines
+// balanced lines, using the line break rules of language (see
+// newLineBreakRules). Dialogue cues and text within maxLines are returned
+// unchanged.
+func ReflowLines(text string, maxLen, maxLines int, language string) string {
+	return reflowLines(text, maxLen, maxLines, false, newLineBreakRules(language))
+}
+
+// BalanceLines joins the lines of text and breaks them again into n balanced
+// lines (fewer when text has fewer words), using the line break rules of
+// language. Dialogue cues and text with blank lines are returned unchanged.
+func BalanceLines(text string, n, maxLen int, language string) string {
+	lines := strings.Split(text, "\n")
+	if n <= 0 || !isReflowable(lines) {
+		return text
+	}
+	words := tokenize(text)
+	n = min(n, len(words))
+	if n <= 1 {
+		return joinTokens(words)
+	}
+	return strings.Join(balanceWords(words, n, maxLen, newLineBreakRules(language)), "\n")
+}
+
+// balanceWords splits words (see tokenize) into exactly n lines minimizing
+// the deviation from the average line length plus the break penalties. Lines
+// over maxLen are heavily penalized, so they are only used when unavoidable.
+func balanceWords(words []token, n, maxLen int, rules lineBreakRules) []string {
+	k := len(words)
+	lengths := make([]int, k)
+	total, spaces := 0, 0
+	for i, w := range words {
+		lengths[i] = srt.DisplayWidth(w.text)
+		total += lengths[i]
+		if i > 0 && w.space {
+			spaces++
+		}
+	}
+	target := float64(total+spaces-(n-1)) / float64(n)
+
+	lineCost := func(from, to int) float64 { // words[from:to]
+		length := 0
+		for i := from; i < to; i++ {
+			length += lengths[i]
+			if i > from && words[i].space {
+				length++
+			}
+		}
+		cost := math.Abs(float64(length) - target)
+		if length > maxLen {
+			cost += 1e6 + float64(length-maxLen)*1e3
+		}
+		if to < k {
+			cost += rules.breakPenalty(words[to-1].text)
+		}
+		return cost
+	}
+
+	// cost[j][i]: best cost of words[:i] in j lines; prev[j][i]: start of the last line.
+	cost := make([][]float64, n+1)
+	prev := make([][]int, n+1)
+	for j := range cost {
+		cost[j] = make([]float64, k+1)
+		prev[j] = make([]int, k+1)
+		for i := range cost[j] {
+			cost[j][i] = math.Inf(1)
+		}
+	}
+	cost[0][0] = 0
+	for j := 1; j <= n; j++ {
+		for i := j; i <= k-(n-j); i++ {
+			for s := j - 1; s < i; s++ {
+				if math.IsInf(cost[j-1][s], 1) {
+					continue
+				}
+				if c := cost[j-1][s] + lineCost(s, i); c < cost[j][i] {
+					cost[j][i], prev[j][i] = c, s
+				}
+			}
+		}
+	}
+
+	lines := make([]string, n)
+	end := k
+	for j := n; j >= 1; j-- {
+		start := prev[j][end]
+		lines[j-1] = joinTokens(words[start:end])
+		end = start
+	}
+	return lines
+}
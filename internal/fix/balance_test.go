@@ -0,0 +1,124 @@
+package fix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReflowLines(t *testing.T) {
+	en := newLineBreakRules("en")
+	cases := []struct {
+		name     string
+		text     string
+		maxLines int
+		balance  bool
+		rules    lineBreakRules
+		want     string
+	}{
+		{
+			name:     "caps lines and breaks after punctuation",
+			text:     "I told you that we should have left the house before the storm came,\nbut you never listen to me\nand now we are stuck here.",
+			maxLines: 2,
+			rules:    en,
+			want:     "I told you that we should have left the house before the storm came,\nbut you never listen to me and now we are stuck here.",
+		},
+		{
+			name:    "balances without ending a line with an article",
+			text:    "We are going to look for a\nhouse.",
+			balance: true,
+			rules:   en,
+			want:    "We are going\nto look for a house.",
+		},
+		{
+			name:    "uses the rules of the language",
+			text:    "Vamos a buscar una casa en el\ncampo.",
+			balance: true,
+			rules:   newLineBreakRules("spa"),
+			want:    "Vamos a buscar\nuna casa en el campo.",
+		},
+		{
+			name:     "keeps dialogue cues",
+			text:     "- Hi.\n- Hello there.\n- Bye.",
+			maxLines: 2,
+			balance:  true,
+			rules:    en,
+			want:     "- Hi.\n- Hello there.\n- Bye.",
+		},
+		{
+			name:  "leaves text under the limit unless balancing",
+			text:  "We are going to look for a\nhouse.",
+			rules: en,
+			want:  "We are going to look for a\nhouse.",
+		},
+	}
+	for _, tc := range cases {
+		if got := reflowLines(tc.text, DefaultMaxLineLength, tc.maxLines, tc.balance, tc.rules); got != tc.want {
+			t.Fatalf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	cases := []struct {
+		name   string
+		line   string
+		maxLen int
+		want   []string
+	}{
+		{
+			name:   "breaks at spaces",
+			line:   "We are going to look for a house.",
+			maxLen: 17,
+			want:   []string{"We are going to", "look for a house."},
+		},
+		{
+			name:   "breaks Japanese between characters",
+			line:   "今日はとても天気がいいですね。",
+			maxLen: 16,
+			want:   []string{"今日はとても天気", "がいいですね。"},
+		},
+		{
+			name:   "keeps closing punctuation with the character before it",
+			line:   "ありがとうございます。",
+			maxLen: 20,
+			want:   []string{"ありがとうございま", "す。"},
+		},
+		{
+			name:   "keeps opening brackets with the character after them",
+			line:   "彼は「はい」と言った",
+			maxLen: 6,
+			want:   []string{"彼は", "「は", "い」と", "言った"},
+		},
+		{
+			name:   "breaks Korean at spaces",
+			line:   "오늘은 날씨가 정말 좋네요",
+			maxLen: 16,
+			want:   []string{"오늘은 날씨가", "정말 좋네요"},
+		},
+		{
+			name:   "keeps inline tags with their text",
+			line:   "<i>今日は</i>天気",
+			maxLen: 6,
+			want:   []string{"<i>今日は</i>", "天気"},
+		},
+	}
+	for _, tc := range cases {
+		got := WrapLine(tc.line, tc.maxLen)
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBalanceLines_CJK(t *testing.T) {
+	// The lines of Japanese text are joined without a space.
+	if got := BalanceLines("今日はとても\n天気がいいですね。", 2, MaxLineLengthFor("ja"), "ja"); got != "今日はとても天\n気がいいですね。" {
+		t.Fatalf("got %q", got)
+	}
+	if got := MaxLineLengthFor("jpn"); got != 26 {
+		t.Fatalf("MaxLineLengthFor(jpn) = %d", got)
+	}
+	if got := MaxLineLengthFor("es"); got != DefaultMaxLineLength {
+		t.Fatalf("MaxLineLengthFor(es) = %d", got)
+	}
+}
@@ -0,0 +1,125 @@
+package fix
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// minSplitDuration is the shortest cue created by splitting a fast cue.
+const minSplitDuration = 500 * time.Millisecond
+
+// splitFastCues splits the cues read faster than maxCPS at their sentence
+// boundaries, numbering the result from 1.
+func splitFastCues(subtitles []*srt.Subtitle, maxCPS float64, changes *changeLog) []*srt.Subtitle {
+	slog.Info("splitting fast cues", "max_cps", maxCPS)
+	var result []*srt.Subtitle
+	for _, sub := range subtitles {
+		parts := splitFastCue(sub, maxCPS)
+		if len(parts) > 1 {
+			changes.add(ActionSplitFastCue, sub, "%.1f chars/s split into %d cues", srt.CharsPerSecond(sub), len(parts))
+		}
+		result = append(result, parts...)
+	}
+	settleCues(result, false)
+	return result
+}
+
+// splitFastCue splits sub in two at the sentence boundary closest to the middle
+// of its text, dividing the time in proportion to the length of each part, and
+// repeats on each part while it is still faster than maxCPS.
+func splitFastCue(sub *srt.Subtitle, maxCPS float64) []*srt.Subtitle {
+	duration := sub.ToTime - sub.FromTime
+	if srt.CharsPerSecond(sub) <= maxCPS || duration < 2*minSplitDuration {
+		return []*srt.Subtitle{sub}
+	}
+	// Line breaks are kept as "\n" words.
+	var words []string
+	for i, line := range strings.Split(sub.Text, "\n") {
+		if i > 0 {
+			words = append(words, "\n")
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	total := 0
+	for _, w := range words {
+		total += srt.VisibleLength(w)
+	}
+
+	best, bestDistance := -1, 0
+	length := 0
+	for i := 0; i < len(words)-1; i++ {
+		length += srt.VisibleLength(words[i])
+		next := words[i+1]
+		if next == "\n" && i+2 < len(words) {
+			next = words[i+2]
+		}
+		if !isSentenceEnd(words[i], next) {
+			continue
+		}
+		if d := abs(2*length - total); best < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	if best < 0 {
+		return []*srt.Subtitle{sub}
+	}
+
+	first := joinWords(words[:best+1])
+	second := joinWords(words[best+1:])
+	if strings.Contains(sub.Text, "\n") {
+		first, second = trimDialogueDash(first), trimDialogueDash(second)
+	}
+	firstLen, secondLen := srt.VisibleLength(first), srt.VisibleLength(second)
+	split := sub.FromTime + time.Duration(float64(duration)*float64(firstLen)/float64(firstLen+secondLen)).Round(time.Millisecond)
+	split = min(max(split, sub.FromTime+minSplitDuration), sub.ToTime-minSplitDuration)
+
+	parts := splitFastCue(&srt.Subtitle{Idx: sub.Idx, ID: sub.ID, FromTime: sub.FromTime, ToTime: split, Text: first, Forced: sub.Forced, SDH: sub.SDH, Speaker: sub.Speaker}, maxCPS)
+	return append(parts, splitFastCue(&srt.Subtitle{Idx: sub.Idx, FromTime: split, ToTime: sub.ToTime, Text: second, Forced: sub.Forced, SDH: sub.SDH}, maxCPS)...)
+}
+
+// isSentenceEnd reports whether a sentence ends with word, given the word
+// that follows it.
+func isSentenceEnd(word, next string) bool {
+	if word == "\n" {
+		return false
+	}
+	visible := srt.VisibleText(word)
+	if visible == "" || !strings.ContainsAny(visible[len(visible)-1:], ".!?…") {
+		return false
+	}
+	r := []rune(srt.VisibleText(next))
+	return len(r) > 0 && !unicode.IsLower(r[0])
+}
+
+// joinWords joins words with spaces, turning "\n" words back into line
+// breaks.
+func joinWords(words []string) string {
+	lines := strings.Split(strings.Join(words, " "), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// trimDialogueDash removes the dash of a single-line part of a dialogue cue,
+// which now has a single speaker.
+func trimDialogueDash(text string) string {
+	if strings.Contains(text, "\n") || !strings.HasPrefix(text, "-") {
+		return text
+	}
+	return strings.TrimSpace(strings.TrimPrefix(text, "-"))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
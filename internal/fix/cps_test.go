@@ -0,0 +1,54 @@
+package fix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestSplitFastCue(t *testing.T) {
+	sub := &srt.Subtitle{
+		Idx:      7,
+		FromTime: 10 * time.Second,
+		ToTime:   12 * time.Second,
+		Text:     "We need to leave now. The storm is coming\nand the roads will close. Hurry!",
+	}
+	got := splitFastCue(sub, 20)
+
+	want := []struct {
+		from, to time.Duration
+		text     string
+	}{
+		// 21 of 72 characters.
+		{10 * time.Second, 10*time.Second + 583*time.Millisecond, "We need to leave now."},
+		// The last part keeps the minimum duration of a split cue.
+		{10*time.Second + 583*time.Millisecond, 11*time.Second + 500*time.Millisecond, "The storm is coming\nand the roads will close."},
+		{11*time.Second + 500*time.Millisecond, 12 * time.Second, "Hurry!"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d cues, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].FromTime != w.from || got[i].ToTime != w.to || got[i].Text != w.text {
+			t.Fatalf("cue %d = %v --> %v %q, want %v --> %v %q", i, got[i].FromTime, got[i].ToTime, got[i].Text, w.from, w.to, w.text)
+		}
+	}
+}
+
+func TestSplitFastCue_KeepsCuesWithoutSentenceBoundary(t *testing.T) {
+	slow := &srt.Subtitle{FromTime: 0, ToTime: 5 * time.Second, Text: "Hi. Hello."}
+	fast := &srt.Subtitle{FromTime: 0, ToTime: time.Second, Text: "we need to leave now because the storm is coming"}
+	dialogue := &srt.Subtitle{FromTime: 0, ToTime: 1500 * time.Millisecond, Text: "- Where are you going tonight?\n- To the station, I think."}
+
+	if got := splitFastCue(slow, 20); len(got) != 1 {
+		t.Fatalf("slow cue was split: %+v", got)
+	}
+	if got := splitFastCue(fast, 20); len(got) != 1 {
+		t.Fatalf("cue without sentence boundary was split: %+v", got)
+	}
+	got := splitFastCue(dialogue, 20)
+	if len(got) != 2 || got[0].Text != "Where are you going tonight?" || got[1].Text != "To the station, I think." {
+		t.Fatalf("unexpected dialogue split: %+v", got)
+	}
+}
@@ -0,0 +1,87 @@
+package fix
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+)
+
+// defaultCreditPatterns match the lines of ads, subtitle credits and
+// URL-bearing watermarks found in downloaded subtitles. They are
+// case-insensitive.
+var defaultCreditPatterns = []string{
+	`\bdownloaded\s+from\b`,
+	`\bsubtitles?\s+(?:by|from|ripped\s+by|synced\s+by|provided\s+by|downloaded\s+from)\b`,
+	`\b(?:sync(?:ed|hronized)?|resync(?:ed)?)\s*(?:&|and)\s*correct(?:ed|ions)?\s+by\b`,
+	`\b(?:sync(?:ed|hronized)?|ripped|encoded|corrected)\s+by\b`,
+	`\b(?:opensubtitles|subscene|addic7ed|podnapisi|yifysubtitles|subdivx|tusubtitulo)\b`,
+	`\bsupport\s+us\s+and\s+become\s+vip\b`,
+	`\badvertise\s+your\s+product\b`,
+	`(?:https?://|\bwww\.)\S+`,
+	`\b[a-z0-9-]+\.(?:com|net|org|io|tv|to|me|info)\b`,
+}
+
+func compileCreditPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(`(?i)` + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credit pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// LoadCreditPatterns reads a blocklist file: one regular expression per line
+// (case-insensitive). Empty lines and lines starting with # are ignored.
+func LoadCreditPatterns(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// removeCreditLines removes the lines of text matching any of patterns and
+// returns the removed lines.
+func removeCreditLines(text string, patterns []*regexp.Regexp) (string, []string) {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	var removed []string
+	for _, line := range lines {
+		if matchesAny(line, patterns) {
+			removed = append(removed, line)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(removed) == 0 {
+		return text, nil
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package fix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveCreditLines_DefaultPatterns(t *testing.T) {
+	patterns, err := compileCreditPatterns(defaultCreditPatterns)
+	if err != nil {
+		t.Fatalf("compileCreditPatterns: %v", err)
+	}
+	cases := map[string]string{
+		"Downloaded from YTS.MX":                          "",
+		"Subtitles by explosiveskull":                     "",
+		"Sync & corrections by n17t01":                    "",
+		"<font color=\"#ffff00\">www.addic7ed.com</font>": "",
+		"Visit https://example.org for more":              "",
+		"Where were you?\nsubscene.com":                   "Where were you?",
+		"I'll sync the files by noon.":                    "I'll sync the files by noon.",
+		"He subtitled the film. So what?":                 "He subtitled the film. So what?",
+	}
+	for text, want := range cases {
+		if got, _ := removeCreditLines(text, patterns); got != want {
+			t.Fatalf("removeCreditLines(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestFixFile_RemoveCredits_AnyPosition(t *testing.T) {
+	workdir := t.TempDir()
+	input := filepath.Join(workdir, "in.srt")
+	orig := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nSubtitles by someone\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\nBrought to you by ACME\n\n" +
+		"4\n00:00:07,000 --> 00:00:08,000\nBye\n\n"
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	res, err := Run(context.Background(), Options{
+		InputPath:      input,
+		DryRun:         true,
+		WorkDir:        workdir,
+		RemoveCredits:  true,
+		CreditPatterns: []string{`brought to you by`},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got, err := os.ReadFile(res.WrittenPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:07,000 --> 00:00:08,000\nBye\n\n"
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if counts := summarizeActions(res.Actions); counts[ActionRemovedCredit] != 2 {
+		t.Fatalf("unexpected actions: %+v", res.Actions)
+	}
+}
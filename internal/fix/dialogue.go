@@ -0,0 +1,88 @@
+package fix
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Dialogue dash conventions for cues with several speakers.
+const (
+	DialogueDashesAll    = "all"    // - Hi.\n- Hello.
+	DialogueDashesSecond = "second" // Hi.\n- Hello.
+	DialogueDashesNone   = "none"   // Hi.\nHello.
+)
+
+func isValidDialogueDashes(style string) bool {
+	return style == DialogueDashesAll || style == DialogueDashesSecond || style == DialogueDashesNone
+}
+
+func isDialogueDash(r rune) bool {
+	return r == '-' || r == '–' || r == '—'
+}
+
+// dialogueDash returns the dash of the first line of text starting with one,
+// or "" when there is none.
+func dialogueDash(line string) string {
+	r, _ := utf8.DecodeRuneInString(strings.TrimSpace(line))
+	if isDialogueDash(r) {
+		return string(r)
+	}
+	return ""
+}
+
+// trimDialogueDashPrefix removes the leading dash of line.
+func trimDialogueDashPrefix(line string) string {
+	line = strings.TrimSpace(line)
+	if dash := dialogueDash(line); dash != "" {
+		return strings.TrimSpace(strings.TrimPrefix(line, dash))
+	}
+	return line
+}
+
+// markSpeakerTurn makes text start with a dialogue dash, so it remains a
+// separate speaker turn when merged with another cue.
+func markSpeakerTurn(text string) string {
+	if dialogueDash(text) != "" {
+		return text
+	}
+	return "- " + strings.TrimSpace(text)
+}
+
+// normalizeDialogueDashes rewrites the dashes of a cue with several speakers
+// following style. A speaker turn starts at the first line and at each line
+// starting with a dash; the other lines continue the previous turn. Cues
+// with a single speaker are returned unchanged.
+func normalizeDialogueDashes(text, style, defaultDash string) string {
+	lines := strings.Split(text, "\n")
+	turns := 1
+	dash := ""
+	for i, line := range lines {
+		d := dialogueDash(line)
+		if d != "" && dash == "" {
+			dash = d
+		}
+		if i > 0 && d != "" {
+			turns++
+		}
+	}
+	if turns < 2 {
+		return text
+	}
+	if defaultDash != "" {
+		dash = defaultDash
+	}
+
+	turn := 0
+	for i, line := range lines {
+		if i > 0 && dialogueDash(line) == "" {
+			continue // continuation of the previous turn
+		}
+		line = trimDialogueDashPrefix(line)
+		if style == DialogueDashesAll || (style == DialogueDashesSecond && turn > 0) {
+			line = dash + " " + line
+		}
+		lines[i] = line
+		turn++
+	}
+	return strings.Join(lines, "\n")
+}
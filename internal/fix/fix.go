@@ -1,19 +1,22 @@
 package fix
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
 	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
 	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/spell"
 	"github.com/adrianmusante/subtitle-tools/internal/srt"
 )
 
@@ -38,6 +41,10 @@ var decorativeLineSymbols = map[rune]struct{}{
 
 var ErrSubtitlesOutOfOrder = errors.New("subtitles are out of order")
 
+// ErrNoForcedCues is returned by Run with OnlyForced when the input has no
+// forced cue, instead of falling back to the input as for other empty outputs.
+var ErrNoForcedCues = srt.ErrNoForcedCues
+
 type Options struct {
 	InputPath  string
 	OutputPath string
@@ -46,44 +53,172 @@ type Options struct {
 
 	MaxLineLength int
 	MinWordsMerge int
-
-	StripStyle     bool
+	// MaxLines, when positive, caps the lines per cue; longer cues are broken
+	// again into MaxLines balanced lines.
+	MaxLines int
+	// BalanceLines rebreaks multi-line cues so their lines have similar
+	// lengths, preferring breaks after punctuation.
+	BalanceLines bool
+	// Language selects the words a line should not end with when breaking
+	// lines (articles, prepositions...). Empty means the language of the input
+	// file name (e.g. movie.es.srt), if any.
+	Language string
+
+	// NormalizeUnicode composes the text to NFC, removes invisible characters
+	// (zero-width spaces, stray byte order marks, soft hyphens) and replaces
+	// the space variants with the common ones, before any other cleanup.
+	NormalizeUnicode bool
+
+	// FixOCR corrects common OCR artifacts (l/I and 0/O confusion, stray
+	// pipes, broken ellipses...) using the rules of Language.
+	FixOCR bool
+	// OCRReplacementsPath, when set, is a file of "wrong=right" whole-word
+	// replacements applied after the built-in OCR rules (requires FixOCR).
+	OCRReplacementsPath string
+
+	// Spellcheck corrects the misspelled words that have a single suggestion
+	// in the hunspell dictionary at DictionaryPath, or the one of Language
+	// (see spell.Find) when empty. The words of SpellingWordsPath (character
+	// names, places...) are accepted as spelled.
+	Spellcheck        bool
+	DictionaryPath    string
+	SpellingWordsPath string
+
+	// Typography selects the punctuation normalizations (quotes, dashes,
+	// ellipses, spacing) applied following the conventions of Language.
+	Typography Typography
+	// RulesPath, when set, is a YAML file of ordered regex find/replace rules
+	// applied to the text of each cue after the built-in cleanups.
+	RulesPath string
+	// CensorListPath, when set, is a word list (see censor.Load) whose words
+	// are censored following CensorStyle (censor.DefaultStyle when empty).
+	// With censor.StyleRemoveCue, the cues with a listed word are dropped.
+	CensorListPath string
+	CensorStyle    string
+
+	StripStyle bool
+	// KeepTags, when set, strips every style tag except these (e.g. "i",
+	// "b"), so italics carrying meaning survive. It implies StripStyle.
+	KeepTags []string
+	// StripTags, when set, strips only these style tags (e.g. "font",
+	// "span"). It can't be combined with StripStyle or KeepTags.
+	StripTags []string
+	// StripASSTags removes ASS override blocks ({\an8}, {\i1}...). When
+	// kept, they don't count toward the line length.
+	StripASSTags   bool
 	StripHI        bool
 	StripHIMode    string
 	SkipTranslator bool
+	// OnlyForced keeps only the forced cues (see srt.Subtitle), to build a
+	// forced track from a full one; SkipSDH drops the cues that only describe
+	// sounds. Both go by the flags read with the cues, before any change.
+	OnlyForced bool
+	SkipSDH    bool
+	// Selection limits the fixes to the cues it selects, e.g. to fix a
+	// section again; the other cues are written unchanged. The zero Selection
+	// fixes every cue.
+	Selection srt.Selection
+	// RemoveCredits removes, at any position, the lines of ads, subtitle
+	// credits and URL watermarks ("Downloaded from...", "Subtitles by...");
+	// cues left empty are dropped.
+	RemoveCredits bool
+	// CreditPatterns are extra case-insensitive regular expressions added to
+	// the built-in credit blocklist.
+	CreditPatterns []string
 	CreateBackup   bool
 	BackupExt      string
 	ShiftTime      time.Duration
+	// OverlapPolicy is how overlapping cues are fixed (DefaultOverlapPolicy
+	// when empty).
+	OverlapPolicy string
+	// DialogueDashes, when set, is the dash convention of cues with several
+	// speakers (DialogueDashesAll, DialogueDashesSecond or DialogueDashesNone).
+	// Cues merged because they overlap become separate speaker turns.
+	DialogueDashes string
+	// PreserveIndex keeps the original cue numbers (removed and merged cues
+	// leave gaps) and copies unchanged cues byte for byte from the input, so
+	// the output only differs where cues changed. It can't be combined with
+	// MaxCPS, whose new cues have no original number.
+	PreserveIndex bool
+	// MaxCPS, when positive, is the reading speed (characters per second)
+	// above which cues with several sentences are split in two.
+	MaxCPS float64
+	// MinDuration, when positive, is the minimum duration of a cue: shorter
+	// cues are extended into the following gap or merged with the next cue.
+	MinDuration time.Duration
+	// MediaDuration, when positive, is the duration of the video: cues
+	// starting after it are dropped and cues ending after it are clamped.
+	MediaDuration time.Duration
+	// MinGap, when positive, is the minimum gap between consecutive cues,
+	// enforced by trimming end times.
+	MinGap time.Duration
+	// ShotChanges, when set, are the times of the shot changes of the video
+	// (see scenes.Detect), in order. Cue starts within SnapThreshold
+	// (DefaultSnapThreshold when zero) of one are snapped to it, and cue ends
+	// to ShotChangeGap before it, so cues don't cross a cut by a few frames.
+	// It runs before MinDuration and MinGap are enforced.
+	ShotChanges   []time.Duration
+	SnapThreshold time.Duration
+	ShotChangeGap time.Duration
+
+	// Progress, when set, is called after each processing step.
+	Progress ProgressFunc
+	// ReportPath, when set, receives the actions of the run as JSON.
+	ReportPath string
+
+	ocrRules       []ocrRule        // resolved by Run when FixOCR is set
+	replaceRules   []replaceRule    // loaded by Run from RulesPath
+	creditMatchers []*regexp.Regexp // compiled by Run when RemoveCredits is set
+	censorList     *censor.List     // loaded by Run from CensorListPath
+	spellChecker   *spell.Checker   // loaded by Run when Spellcheck is set
+}
+
+// Processing steps reported to Options.Progress.
+const (
+	StepMerge  = "merge"
+	StepSort   = "sort"
+	StepShift  = "shift"
+	StepSplit  = "split"
+	StepSnap   = "snap"
+	StepTiming = "timing"
+	StepClamp  = "clamp"
+	StepWrite  = "write"
+)
+
+// Progress reports the last completed processing step.
+type Progress struct {
+	Step      string
+	Completed int // steps completed so far
+	Total     int // grows by one when out-of-order cues must be sorted
+	Elapsed   time.Duration
 }
 
+type ProgressFunc func(Progress)
+
 type Result struct {
 	WrittenPath string
 	// WasEmpty is true when processing produced an empty output; in that case
 	// the original input file is left untouched and WrittenPath points to it.
 	WasEmpty bool
+	// Actions lists the changes made, in processing order.
+	Actions []Action
 }
 
 func Run(ctx context.Context, opts Options) (Result, error) {
-	_ = ctx
 	wasEmptyOutput := false
 	if opts.InputPath == "" {
 		return Result{}, errors.New("input path is required")
 	}
-	if opts.MaxLineLength <= 0 {
-		opts.MaxLineLength = DefaultMaxLineLength
-	}
-	if opts.MinWordsMerge <= 0 {
-		opts.MinWordsMerge = DefaultMinWordsForMerging
-	}
 	if opts.CreateBackup && opts.BackupExt == "" {
 		return Result{}, errors.New("backup ext is required")
 	}
-	if opts.StripHIMode == "" {
-		opts.StripHIMode = DefaultStripHIMode
+	if opts.Language == "" {
+		_, name := naming.Parse(opts.InputPath)
+		opts.Language = name.Language
 	}
-	opts.StripHIMode = normalizeStripHIMode(opts.StripHIMode)
-	if !isValidStripHIMode(opts.StripHIMode) {
-		return Result{}, fmt.Errorf("invalid strip-hi mode %q (supported: %s, %s, %s, %s)", opts.StripHIMode, StripHIModeSafe, StripHIModeSafePlus, StripHIModeStandard, StripHIModeStandardPlus)
+	opts, err := prepareOptions(opts)
+	if err != nil {
+		return Result{}, err
 	}
 	if opts.WorkDir == "" {
 		return Result{}, errors.New("workdir is required (create one with run.NewWorkdir)")
@@ -92,41 +227,42 @@ func Run(ctx context.Context, opts Options) (Result, error) {
 	slog.Info("fixing subtitles file", "input_path", opts.InputPath)
 
 	namer := run.NewTempNamer(opts.WorkDir, opts.InputPath)
-
-	tmpOutputPath, err := mergeSubtitles(opts.InputPath, opts, namer)
+	doc, err := readDocument(opts.InputPath)
 	if err != nil {
-		if !errors.Is(err, ErrSubtitlesOutOfOrder) {
-			return Result{}, err
-		}
-		slog.Warn("Subtitles out of order. Trying to sort and remerge.")
-		// Attempt sort + remerge
-		sortedPath, err2 := sortSubtitles(tmpOutputPath, namer)
-		if err2 != nil {
-			return Result{}, fmt.Errorf("out of order; sorting failed: %w", err2)
-		}
-		mergedSortedFilePath, err3 := mergeSubtitles(sortedPath, opts, namer)
-		if err3 != nil {
-			return Result{}, fmt.Errorf("out of order; remerge failed: %w", err3)
-		}
-		tmpOutputPath = mergedSortedFilePath
+		return Result{}, err
+	}
+	for _, w := range doc.Warnings {
+		slog.Warn("subtitles file read with a warning", "input_path", opts.InputPath, "warning", w)
+	}
+	if opts.OnlyForced && !slices.ContainsFunc(doc.Cues, func(s *srt.Subtitle) bool { return s.Forced }) {
+		return Result{}, ErrNoForcedCues
 	}
 
-	tmpOutputPath, err = shiftTimeSubtitles(tmpOutputPath, opts.ShiftTime, namer)
+	steps := newStepProgress(opts, 1) // and write
+	changes := &changeLog{}
+	subtitles, err := fixCues(ctx, doc.Cues, opts, changes, steps)
 	if err != nil {
 		return Result{}, err
 	}
+	doc = doc.WithCues(subtitles)
 
-	// Guard: if all subtitles were stripped, preserve original content as fallback
-	// and keep the regular output flow so alternate destinations still get a file.
-	if info, statErr := os.Stat(tmpOutputPath); statErr == nil && info.Size() == 0 {
+	var tmpOutputPath string
+	if len(subtitles) == 0 {
+		// Guard: if all subtitles were stripped, preserve original content as fallback
+		// and keep the regular output flow so alternate destinations still get a file.
 		wasEmptyOutput = true
 		slog.Warn("processing produced an empty output; using original input as fallback",
 			"input_path", opts.InputPath)
-		fallbackOutputPath := namer.Step("empty-fallback")
-		if err := fs.CopyFile(opts.InputPath, fallbackOutputPath); err != nil {
+		tmpOutputPath = namer.Step("empty-fallback")
+		if err := fs.CopyFile(opts.InputPath, tmpOutputPath); err != nil {
+			return Result{}, err
+		}
+	} else if opts.PreserveIndex {
+		if tmpOutputPath, err = restoreUnchangedCues(opts.InputPath, doc, namer); err != nil {
 			return Result{}, err
 		}
-		tmpOutputPath = fallbackOutputPath
+	} else if tmpOutputPath, err = writeTempDocument(doc, namer); err != nil {
+		return Result{}, err
 	}
 
 	outputPath := opts.OutputPath
@@ -157,7 +293,336 @@ func Run(ctx context.Context, opts Options) (Result, error) {
 		}
 	}
 
-	return Result{WrittenPath: outputPath, WasEmpty: wasEmptyOutput}, nil
+	steps.done(StepWrite)
+
+	if opts.ReportPath != "" {
+		report := Report{
+			InputPath:  opts.InputPath,
+			OutputPath: outputPath,
+			Summary:    summarizeActions(changes.actions),
+			Actions:    changes.actions,
+		}
+		if err := writeReport(opts.ReportPath, report); err != nil {
+			return Result{}, fmt.Errorf("write fix report: %w", err)
+		}
+	}
+
+	return Result{WrittenPath: outputPath, WasEmpty: wasEmptyOutput, Actions: changes.actions}, nil
+}
+
+// FixDocument applies the fixes of opts to a parsed document, without files:
+// it returns the fixed document, with the metadata of doc, and the actions
+// made, and leaves doc untouched. The file options (InputPath, OutputPath,
+// DryRun, WorkDir, backups and ReportPath) are ignored, and an empty Language
+// is not guessed. Unlike Run, an empty result is returned as is.
+func FixDocument(ctx context.Context, doc *srt.Document, opts Options) (*srt.Document, []Action, error) {
+	opts, err := prepareOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	subtitles := make([]*srt.Subtitle, 0, len(doc.Cues))
+	for i, sub := range doc.Cues {
+		if sub == nil {
+			return nil, nil, fmt.Errorf("nil subtitle at position %d", i+1)
+		}
+		c := *sub
+		c.Text = srt.CleanText(c.Text)
+		subtitles = append(subtitles, &c)
+	}
+	changes := &changeLog{}
+	subtitles, err = fixCues(ctx, subtitles, opts, changes, newStepProgress(opts, 0))
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc.WithCues(subtitles), changes.actions, nil
+}
+
+// FixSubtitles is FixDocument for bare cues: it returns the fixed cues.
+func FixSubtitles(ctx context.Context, subs []*srt.Subtitle, opts Options) ([]*srt.Subtitle, []Action, error) {
+	doc, actions, err := FixDocument(ctx, &srt.Document{Cues: subs}, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc.Cues, actions, nil
+}
+
+// prepareOptions validates opts and resolves their defaults and rules, except
+// for the file options.
+func prepareOptions(opts Options) (Options, error) {
+	if opts.MaxLineLength <= 0 {
+		opts.MaxLineLength = MaxLineLengthFor(opts.Language)
+	}
+	if opts.MinWordsMerge <= 0 {
+		opts.MinWordsMerge = DefaultMinWordsForMerging
+	}
+	if opts.StripHIMode == "" {
+		opts.StripHIMode = DefaultStripHIMode
+	}
+	opts.StripHIMode = normalizeStripHIMode(opts.StripHIMode)
+	if !isValidStripHIMode(opts.StripHIMode) {
+		return Options{}, fmt.Errorf("invalid strip-hi mode %q (supported: %s, %s, %s, %s)", opts.StripHIMode, StripHIModeSafe, StripHIModeSafePlus, StripHIModeStandard, StripHIModeStandardPlus)
+	}
+	if opts.OverlapPolicy == "" {
+		opts.OverlapPolicy = DefaultOverlapPolicy
+	}
+	opts.OverlapPolicy = normalizeOverlapPolicy(opts.OverlapPolicy)
+	if !isValidOverlapPolicy(opts.OverlapPolicy) {
+		return Options{}, fmt.Errorf("invalid overlap policy %q (supported: %s, %s, %s, %s)", opts.OverlapPolicy, OverlapPolicyMerge, OverlapPolicyTrim, OverlapPolicyShift, OverlapPolicyKeep)
+	}
+	if opts.DialogueDashes != "" && !isValidDialogueDashes(opts.DialogueDashes) {
+		return Options{}, fmt.Errorf("invalid dialogue dashes %q (supported: %s, %s, %s)", opts.DialogueDashes, DialogueDashesAll, DialogueDashesSecond, DialogueDashesNone)
+	}
+	if opts.MaxLines < 0 {
+		return Options{}, errors.New("max lines must not be negative")
+	}
+	if opts.MaxCPS < 0 {
+		return Options{}, errors.New("max cps must not be negative")
+	}
+	if opts.PreserveIndex && opts.MaxCPS > 0 {
+		return Options{}, errors.New("preserve index can't be combined with max cps")
+	}
+	if opts.OCRReplacementsPath != "" && !opts.FixOCR {
+		return Options{}, errors.New("OCR replacements require FixOCR")
+	}
+	if opts.FixOCR {
+		var replacements []ocrRule
+		if opts.OCRReplacementsPath != "" {
+			var err error
+			if replacements, err = loadOCRReplacements(opts.OCRReplacementsPath); err != nil {
+				return Options{}, fmt.Errorf("load OCR replacements: %w", err)
+			}
+		}
+		opts.ocrRules = ocrRules(opts.Language, replacements)
+	}
+	if (opts.DictionaryPath != "" || opts.SpellingWordsPath != "") && !opts.Spellcheck {
+		return Options{}, errors.New("a dictionary or spelling words require Spellcheck")
+	}
+	if opts.Spellcheck {
+		var err error
+		if opts.spellChecker, err = spell.Open(opts.DictionaryPath, opts.SpellingWordsPath, opts.Language); err != nil {
+			return Options{}, fmt.Errorf("load dictionary: %w", err)
+		}
+	}
+	if opts.RemoveCredits {
+		var err error
+		if opts.creditMatchers, err = compileCreditPatterns(append(append([]string(nil), defaultCreditPatterns...), opts.CreditPatterns...)); err != nil {
+			return Options{}, err
+		}
+	}
+	if err := opts.Typography.validate(); err != nil {
+		return Options{}, err
+	}
+	if opts.RulesPath != "" {
+		var err error
+		if opts.replaceRules, err = loadReplaceRules(opts.RulesPath); err != nil {
+			return Options{}, fmt.Errorf("load rules: %w", err)
+		}
+	}
+	if opts.CensorStyle == "" {
+		opts.CensorStyle = censor.DefaultStyle
+	}
+	opts.CensorStyle = censor.NormalizeStyle(opts.CensorStyle)
+	if !censor.IsValidStyle(opts.CensorStyle) {
+		return Options{}, fmt.Errorf("invalid censor style %q (supported: %s, %s, %s)", opts.CensorStyle, censor.StyleStars, censor.StyleRemoveCue, censor.StyleBeepText)
+	}
+	if opts.CensorListPath != "" {
+		var err error
+		if opts.censorList, err = censor.Load(opts.CensorListPath); err != nil {
+			return Options{}, fmt.Errorf("load censor list: %w", err)
+		}
+	}
+	if len(opts.StripTags) > 0 && (opts.StripStyle || len(opts.KeepTags) > 0) {
+		return Options{}, errors.New("strip tags can't be combined with strip style or keep tags")
+	}
+	if opts.MediaDuration < 0 {
+		return Options{}, errors.New("media duration must not be negative")
+	}
+	if opts.MinDuration < 0 || opts.MinGap < 0 {
+		return Options{}, errors.New("min duration and min gap must not be negative")
+	}
+	if opts.SnapThreshold < 0 || opts.ShotChangeGap < 0 {
+		return Options{}, errors.New("snap threshold and shot change gap must not be negative")
+	}
+	if opts.SnapThreshold == 0 {
+		opts.SnapThreshold = DefaultSnapThreshold
+	}
+	return opts, nil
+}
+
+// stepProgress reports the completed processing steps to Options.Progress.
+type stepProgress struct {
+	fn        ProgressFunc
+	started   time.Time
+	completed int
+	total     int
+}
+
+// newStepProgress counts the processing steps that opts enable, plus extra
+// steps run after them.
+func newStepProgress(opts Options, extra int) *stepProgress {
+	total := 2 + extra // merge, shift
+	if opts.MinDuration > 0 || opts.MinGap > 0 {
+		total++
+	}
+	if opts.MaxCPS > 0 {
+		total++
+	}
+	if len(opts.ShotChanges) > 0 {
+		total++
+	}
+	if opts.MediaDuration > 0 {
+		total++
+	}
+	return &stepProgress{fn: opts.Progress, started: time.Now(), total: total}
+}
+
+func (p *stepProgress) done(step string) {
+	p.completed++
+	if p.fn != nil {
+		p.fn(Progress{Step: step, Completed: p.completed, Total: p.total, Elapsed: time.Since(p.started)})
+	}
+}
+
+// fixCues runs the processing steps on the cues of subtitles selected by
+// opts, which are modified, and returns them fixed along with the unselected
+// cues, unchanged.
+func fixCues(ctx context.Context, subtitles []*srt.Subtitle, opts Options, changes *changeLog, steps *stepProgress) ([]*srt.Subtitle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	selected, unselected := opts.Selection.Split(subtitles)
+	if len(selected) == 0 {
+		return nil, srt.ErrNoSelectedCues
+	}
+	if len(unselected) == 0 {
+		return fixSelectedCues(selected, opts, changes, steps)
+	}
+	slog.Info("fixing the selected cues only", "selected", len(selected), "unselected", len(unselected))
+	// The selected cues keep their numbers until they are merged back with
+	// the others.
+	selectedOpts := opts
+	selectedOpts.PreserveIndex = true
+	fixed, err := fixSelectedCues(selected, selectedOpts, changes, steps)
+	if err != nil {
+		return nil, err
+	}
+	subtitles = mergeByStart(fixed, unselected)
+	if !opts.PreserveIndex {
+		srt.Reindex(subtitles)
+	}
+	return subtitles, nil
+}
+
+// mergeByStart merges two lists of cues by start time, keeping the order of
+// each list.
+func mergeByStart(a, b []*srt.Subtitle) []*srt.Subtitle {
+	out := make([]*srt.Subtitle, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].FromTime < a[0].FromTime {
+			out, b = append(out, b[0]), b[1:]
+		} else {
+			out, a = append(out, a[0]), a[1:]
+		}
+	}
+	return append(append(out, a...), b...)
+}
+
+// fixSelectedCues runs the processing steps on subtitles, which are
+// modified, and returns the fixed cues.
+func fixSelectedCues(subtitles []*srt.Subtitle, opts Options, changes *changeLog, steps *stepProgress) ([]*srt.Subtitle, error) {
+	notes := detachNotes(subtitles)
+	subtitles = selectFlaggedCues(subtitles, opts, changes)
+	merged, err := mergeCues(subtitles, opts, changes)
+	if err != nil {
+		if !errors.Is(err, ErrSubtitlesOutOfOrder) {
+			return nil, err
+		}
+		slog.Warn("Subtitles out of order. Trying to sort and remerge.")
+		steps.total++
+		srt.Sort(merged)
+		if !opts.PreserveIndex {
+			srt.Reindex(merged)
+		}
+		changes.add(ActionSorted, nil, "cues sorted by start time")
+		steps.done(StepSort)
+		if merged, err = mergeCues(merged, opts, changes); err != nil {
+			return nil, fmt.Errorf("out of order; remerge failed: %w", err)
+		}
+	}
+	subtitles = merged
+	steps.done(StepMerge)
+
+	if err := shiftCues(subtitles, opts.ShiftTime); err != nil {
+		return nil, err
+	}
+	if opts.ShiftTime != 0 {
+		changes.add(ActionShifted, nil, "all cues shifted by %s", opts.ShiftTime)
+	}
+	steps.done(StepShift)
+
+	if opts.MaxCPS > 0 {
+		subtitles = splitFastCues(subtitles, opts.MaxCPS, changes)
+		steps.done(StepSplit)
+	}
+
+	if len(opts.ShotChanges) > 0 {
+		snapToShotChanges(subtitles, opts.ShotChanges, opts.SnapThreshold, opts.ShotChangeGap, opts.PreserveIndex, changes)
+		steps.done(StepSnap)
+	}
+
+	if opts.MinDuration > 0 || opts.MinGap > 0 {
+		subtitles = enforceTiming(subtitles, opts.MinDuration, opts.MinGap, opts.PreserveIndex, changes)
+		steps.done(StepTiming)
+	}
+
+	if opts.MediaDuration > 0 {
+		subtitles = clampCues(subtitles, opts.MediaDuration, opts.PreserveIndex, changes)
+		steps.done(StepClamp)
+	}
+	attachNotes(subtitles, notes, opts.ShiftTime)
+	return subtitles, nil
+}
+
+// selectFlaggedCues drops the cues excluded by OnlyForced and SkipSDH.
+func selectFlaggedCues(subtitles []*srt.Subtitle, opts Options, changes *changeLog) []*srt.Subtitle {
+	if !opts.OnlyForced && !opts.SkipSDH {
+		return subtitles
+	}
+	kept := make([]*srt.Subtitle, 0, len(subtitles))
+	for _, sub := range subtitles {
+		switch {
+		case opts.OnlyForced && !sub.Forced:
+			changes.add(ActionDroppedNotForced, sub, "")
+		case opts.SkipSDH && sub.SDH:
+			changes.add(ActionDroppedSDH, sub, "%q", sub.Text)
+		default:
+			kept = append(kept, sub)
+		}
+	}
+	return kept
+}
+
+func readDocument(path string) (*srt.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+	return srt.Read(f)
+}
+
+// writeTempDocument writes the fixed document, with the cues numbered from 1,
+// to a temp file.
+func writeTempDocument(doc *srt.Document, namer run.TempNamer) (string, error) {
+	var b strings.Builder
+	if err := srt.Write(&b, doc, false); err != nil {
+		return "", err
+	}
+	outputTmpPath := namer.Step("fix")
+	if err := fs.WriteFile(strings.NewReader(b.String()), outputTmpPath); err != nil {
+		return "", err
+	}
+	return outputTmpPath, nil
 }
 
 func isContinueLine(s string) bool {
@@ -177,15 +642,84 @@ func isEndLine(s string) bool {
 	return r == '.' || r == '>'
 }
 
-func normalizeSubtitleText(text string, opts Options) string {
-	text = srt.CleanText(text)
-	if opts.StripStyle {
-		text = stripSubtitleStyles(text)
+// normalizeSubtitleText cleans the text of sub, recording the changes of each
+// step in changes.
+func normalizeSubtitleText(sub *srt.Subtitle, opts Options, changes *changeLog) string {
+	text := srt.CleanText(sub.Text)
+	if opts.NormalizeUnicode {
+		if normalized := normalizeUnicode(text); normalized != text {
+			changes.add(ActionNormalizedUnicode, sub, "")
+			text = srt.CleanText(normalized)
+		}
+	}
+	if opts.FixOCR {
+		if fixed := fixOCRErrors(text, opts.ocrRules); fixed != text {
+			changes.add(ActionFixedOCR, sub, "%q -> %q", text, fixed)
+			text = fixed
+		}
+	}
+	if opts.spellChecker != nil {
+		if fixed, misspellings := opts.spellChecker.Fix(text); fixed != text {
+			var fixes []string
+			for _, m := range misspellings {
+				if m.Fix != "" {
+					fixes = append(fixes, fmt.Sprintf("%q -> %q", m.Word, m.Fix))
+				}
+			}
+			changes.add(ActionFixedSpelling, sub, "%s", strings.Join(fixes, ", "))
+			text = fixed
+		}
+	}
+	if opts.RemoveCredits {
+		if cleaned, removed := removeCreditLines(text, opts.creditMatchers); len(removed) > 0 {
+			changes.add(ActionRemovedCredit, sub, "%q", strings.Join(removed, "\n"))
+			text = cleaned
+		}
+	}
+	if opts.stripsStyle() {
+		if stripped := stripSubtitleStyles(text, opts.stripsStyleTag); stripped != text {
+			changes.add(ActionStrippedStyle, sub, "")
+			text = stripped
+		}
+	}
+	if opts.StripASSTags {
+		if stripped := stripASSOverrides(text); stripped != text {
+			changes.add(ActionStrippedASSTags, sub, "")
+			text = stripped
+		}
 	}
 	if opts.StripHI {
-		text = stripSubtitleHI(text, opts.StripHIMode)
+		if stripped := stripSubtitleHI(text, opts.StripHIMode); stripped != text {
+			changes.add(ActionStrippedHI, sub, "")
+			text = stripped
+		}
+	}
+	if cleaned := removeDecorativeLines(text); cleaned != text {
+		changes.add(ActionRemovedDecorativeLines, sub, "")
+		text = cleaned
+	}
+	if opts.Typography.enabled() {
+		if normalized := applyTypography(text, opts.Typography, opts.Language); normalized != text {
+			changes.add(ActionNormalizedTypography, sub, "")
+			text = normalized
+		}
+	}
+	for _, rule := range opts.replaceRules {
+		if replaced := rule.apply(text); replaced != text {
+			changes.add(ActionAppliedRule, sub, "%s", rule.name)
+			text = replaced
+		}
+	}
+	if opts.censorList != nil {
+		if censored, words := opts.censorList.Apply(text, opts.CensorStyle); len(words) > 0 {
+			if opts.CensorStyle == censor.StyleRemoveCue {
+				changes.add(ActionDroppedCensored, sub, "%q", text)
+				return ""
+			}
+			changes.add(ActionCensored, sub, "%s", strings.Join(words, ", "))
+			text = censored
+		}
 	}
-	text = removeDecorativeLines(text)
 	return srt.CleanText(text)
 }
 
@@ -245,11 +779,11 @@ func mergeShortLines(text string, minWords int, maxLineLen int) string {
 			(buffer == "" || (isContinueLine(line) && !isEndLine(buffer))) {
 			var candidate string
 			if len(buffer) > 0 {
-				candidate = buffer + " " + line
+				candidate = joinLines(buffer, line)
 			} else {
 				candidate = line
 			}
-			if len(candidate) >= maxLineLen {
+			if textLength(candidate) >= maxLineLen {
 				if len(buffer) > 0 {
 					merged = append(merged, buffer)
 				}
@@ -280,68 +814,32 @@ func wrapSubtitleLines(text string, maxLen int) string {
 			result = append(result, line)
 			continue
 		}
-		words := strings.Fields(line)
-		var currentLine string
-		var currentLen int
-
-		for _, word := range words {
-			extra := 0
-			if currentLen > 0 {
-				extra = 1
-			}
-			if currentLen+len(word)+extra > maxLen {
-				result = append(result, currentLine)
-				currentLine = word
-				currentLen = len(word)
-			} else {
-				if currentLen > 0 {
-					currentLine += " "
-					currentLen++
-				}
-				currentLine += word
-				currentLen += len(word)
-			}
-		}
-		if currentLen > 0 {
-			result = append(result, currentLine)
-		}
+		result = append(result, WrapLine(line, maxLen)...)
 	}
 	return srt.CleanText(strings.Join(result, "\n"))
 }
 
-func mergeSubtitles(inputPath string, opts Options, namer run.TempNamer) (string, error) {
-	if inputPath == "" {
-		return "", errors.New("empty file path")
-	}
-	outputTmpPath := namer.Step("merge")
-
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return "", err
-	}
-	defer fs.CloseOrLog(f, inputPath)
-
-	out, err := os.Create(outputTmpPath)
-	if err != nil {
-		return "", err
-	}
-	defer fs.CloseOrLog(out, outputTmpPath)
-
-	scanner := bufio.NewScanner(f)
-
+// mergeCues cleans the text of subtitles, drops the empty, invalid and
+// duplicated cues and resolves overlaps, returning the resulting cues
+// numbered from 1 (or with their own numbers with PreserveIndex). When cues
+// are out of order, it returns them with ErrSubtitlesOutOfOrder, to be sorted
+// and merged again. subtitles are modified.
+func mergeCues(subtitles []*srt.Subtitle, opts Options, changes *changeLog) ([]*srt.Subtitle, error) {
 	newIdx := 1
+	reindexed := 0
+	breakRules := newLineBreakRules(opts.Language)
 	var lastSubtitle *srt.Subtitle
-	var processed []*srt.Subtitle
+	var processed, merged []*srt.Subtitle
 	outOfOrder := false
 
-	for {
-		subtitle, err := srt.ReadOne(scanner)
-		if err != nil {
-			return outputTmpPath, err
+	for i := 0; i <= len(subtitles); i++ {
+		var subtitle *srt.Subtitle
+		if i < len(subtitles) {
+			subtitle = subtitles[i]
 		}
 
 		if subtitle != nil { // Normalize text early to improve deduplication and translator skipping.
-			normalizedText := normalizeSubtitleText(subtitle.Text, opts)
+			normalizedText := normalizeSubtitleText(subtitle, opts, changes)
 			if normalizedText != subtitle.Text {
 				subtitle.Text = normalizedText
 			}
@@ -350,14 +848,17 @@ func mergeSubtitles(inputPath string, opts Options, namer run.TempNamer) (string
 		if lastSubtitle == nil {
 			if subtitle != nil && opts.SkipTranslator && translatorPattern.MatchString(subtitle.Text) {
 				slog.Debug("skipping translator subtitle", "subtitle", subtitle)
+				changes.add(ActionDroppedTranslatorCredit, subtitle, "%q", subtitle.Text)
 				continue
 			}
 		} else {
 			if subtitle != nil {
 				if len(subtitle.Text) == 0 {
+					changes.add(ActionRemovedEmpty, subtitle, "")
 					continue
 				}
 				if subtitle.FromTime > subtitle.ToTime {
+					changes.add(ActionRemovedInvalidTiming, subtitle, "ends at %s", srt.FormatTime(subtitle.ToTime))
 					continue
 				}
 				duplicate := false
@@ -368,6 +869,7 @@ func mergeSubtitles(inputPath string, opts Options, namer run.TempNamer) (string
 					}
 				}
 				if duplicate {
+					changes.add(ActionRemovedDuplicate, subtitle, "")
 					continue
 				}
 				processed = append(processed, &srt.Subtitle{FromTime: subtitle.FromTime, ToTime: subtitle.ToTime, Text: subtitle.Text})
@@ -375,15 +877,21 @@ func mergeSubtitles(inputPath string, opts Options, namer run.TempNamer) (string
 				if subtitle.ToTime < lastSubtitle.FromTime { // Subtitles may not be synchronized when translations or descriptions are added that appear on the screen (tag: hi).
 					outOfOrder = true
 				} else { // Check for overlapping subtitles
-					if subtitle.FromTime-lastSubtitle.ToTime < 0 {
+					if subtitle.FromTime-lastSubtitle.ToTime < 0 && !resolveOverlap(lastSubtitle, subtitle, opts.OverlapPolicy, changes) {
 						// If the next subtitle overlaps the previous one, merge the text and extend the end time.
-						lastSubtitle.Text = strings.Join([]string{lastSubtitle.Text, subtitle.Text}, "\n")
+						if opts.DialogueDashes != "" {
+							lastSubtitle.Text = strings.Join([]string{markSpeakerTurn(lastSubtitle.Text), markSpeakerTurn(subtitle.Text)}, "\n")
+						} else {
+							lastSubtitle.Text = strings.Join([]string{lastSubtitle.Text, subtitle.Text}, "\n")
+						}
 						lastSubtitle.ToTime = subtitle.ToTime
+						changes.add(ActionMergedOverlap, subtitle, "merged into cue %d", lastSubtitle.Idx)
 						continue
 					}
 					// Skip super-short subtitles that mostly repeat the previous text; extend the previous subtitle instead.
 					if subtitle.ToTime-subtitle.FromTime < DefaultMinSubtitleDurationForDedup && strings.Contains(lastSubtitle.Text, subtitle.Text) {
 						lastSubtitle.ToTime = subtitle.ToTime
+						changes.add(ActionMergedRepeat, subtitle, "repeats cue %d; its end time was extended", lastSubtitle.Idx)
 						continue
 					}
 				}
@@ -391,14 +899,40 @@ func mergeSubtitles(inputPath string, opts Options, namer run.TempNamer) (string
 
 			lastSubtitle.Text = srt.CleanText(lastSubtitle.Text)
 			if len(lastSubtitle.Text) > 0 {
-				lastSubtitle.Text = wrapSubtitleLines(lastSubtitle.Text, opts.MaxLineLength)
+				if wrapped := wrapSubtitleLines(lastSubtitle.Text, opts.MaxLineLength); wrapped != lastSubtitle.Text {
+					changes.add(ActionRewrapped, lastSubtitle, "")
+					lastSubtitle.Text = wrapped
+				}
 				lines := strings.Split(lastSubtitle.Text, "\n")
 				if len(lines) > DefaultMaxLinesPerSubtitle {
-					lastSubtitle.Text = mergeShortLines(lastSubtitle.Text, opts.MinWordsMerge, opts.MaxLineLength)
+					if merged := mergeShortLines(lastSubtitle.Text, opts.MinWordsMerge, opts.MaxLineLength); merged != lastSubtitle.Text {
+						changes.add(ActionMergedShortLines, lastSubtitle, "%d lines", len(lines))
+						lastSubtitle.Text = merged
+					}
 				}
-				if err := srt.WriteOne(out, lastSubtitle, &newIdx); err != nil {
-					return outputTmpPath, err
+				if opts.MaxLines > 0 || opts.BalanceLines {
+					if reflowed := reflowLines(lastSubtitle.Text, opts.MaxLineLength, opts.MaxLines, opts.BalanceLines, breakRules); reflowed != lastSubtitle.Text {
+						changes.add(ActionRebalanced, lastSubtitle, "%d -> %d lines", strings.Count(lastSubtitle.Text, "\n")+1, strings.Count(reflowed, "\n")+1)
+						lastSubtitle.Text = reflowed
+					}
 				}
+				if opts.DialogueDashes != "" {
+					if normalized := normalizeDialogueDashes(lastSubtitle.Text, opts.DialogueDashes, dashes[opts.Typography.DialogueDash]); normalized != lastSubtitle.Text {
+						changes.add(ActionNormalizedDialogue, lastSubtitle, "")
+						lastSubtitle.Text = normalized
+					}
+				}
+				idx := lastSubtitle.Idx
+				if !opts.PreserveIndex {
+					if idx != newIdx {
+						reindexed++
+					}
+					idx = newIdx
+					newIdx++
+				}
+				merged = append(merged, &srt.Subtitle{Idx: idx, ID: lastSubtitle.ID, FromTime: lastSubtitle.FromTime, ToTime: lastSubtitle.ToTime, Text: srt.CleanText(lastSubtitle.Text), Forced: lastSubtitle.Forced, SDH: lastSubtitle.SDH, Speaker: lastSubtitle.Speaker})
+			} else {
+				changes.add(ActionRemovedEmpty, lastSubtitle, "")
 			}
 		}
 
@@ -409,78 +943,24 @@ func mergeSubtitles(inputPath string, opts Options, namer run.TempNamer) (string
 	}
 
 	if outOfOrder {
-		return outputTmpPath, ErrSubtitlesOutOfOrder
-	}
-	return outputTmpPath, nil
-}
-
-func sortSubtitles(inputPath string, namer run.TempNamer) (string, error) {
-	if inputPath == "" {
-		return "", errors.New("empty file path")
+		return merged, ErrSubtitlesOutOfOrder
 	}
-	outputPath := namer.Step("sort")
-
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return "", err
+	if reindexed > 0 {
+		changes.add(ActionReindexed, nil, "%d cues renumbered", reindexed)
 	}
-	defer fs.CloseOrLog(f, inputPath)
-
-	subtitles, err := srt.ReadAll(f)
-	if err != nil {
-		return outputPath, err
-	}
-
-	srt.Sort(subtitles)
-
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return "", err
-	}
-	defer fs.CloseOrLog(out, outputPath)
-
-	err = srt.WriteAll(out, subtitles)
-	if err != nil {
-		return outputPath, err
-	}
-
-	return outputPath, nil
+	return merged, nil
 }
 
-func shiftTimeSubtitles(inputPath string, shiftTime time.Duration, namer run.TempNamer) (string, error) {
-	if inputPath == "" {
-		return "", errors.New("empty file path")
-	}
+// shiftCues moves subtitles by shiftTime, failing when a cue would start or
+// end before zero.
+func shiftCues(subtitles []*srt.Subtitle, shiftTime time.Duration) error {
 	if shiftTime == 0 {
-		return inputPath, nil
+		return nil
 	}
 
 	slog.Info("shifting subtitle times", "shift_time", shiftTime)
 
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return "", err
-	}
-	defer fs.CloseOrLog(f, inputPath)
-
-	outputTmpPath := namer.Step("shift-time")
-	out, err := os.Create(outputTmpPath)
-	if err != nil {
-		return "", err
-	}
-	defer fs.CloseOrLog(out, outputTmpPath)
-
-	scanner := bufio.NewScanner(f)
-	newIdx := 1
-	for {
-		subtitle, err := srt.ReadOne(scanner)
-		if err != nil {
-			return outputTmpPath, err
-		}
-		if subtitle == nil {
-			break
-		}
-
+	for _, subtitle := range subtitles {
 		// Shift times and check for negative results.
 		origFrom := subtitle.FromTime
 		origTo := subtitle.ToTime
@@ -491,18 +971,14 @@ func shiftTimeSubtitles(inputPath string, shiftTime time.Duration, namer run.Tem
 			slog.Debug("negative subtitle time after shift", "subtitle", subtitle,
 				"shifted_from", shiftedFrom, "shifted_to", shiftedTo,
 				"shift_time", shiftTime)
-			return outputTmpPath, fmt.Errorf(
+			return fmt.Errorf(
 				"negative subtitle time after shift for cue %d: original [%v --> %v], shifted [%v --> %v], shift %v",
-				newIdx, origFrom, origTo, shiftedFrom, shiftedTo, shiftTime,
+				subtitle.Idx, origFrom, origTo, shiftedFrom, shiftedTo, shiftTime,
 			)
 		}
 
 		subtitle.FromTime = shiftedFrom
 		subtitle.ToTime = shiftedTo
-
-		if err := srt.WriteOne(out, subtitle, &newIdx); err != nil {
-			return outputTmpPath, err
-		}
 	}
-	return outputTmpPath, nil
+	return nil
 }
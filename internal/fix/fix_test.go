@@ -2,6 +2,8 @@ package fix
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,6 +11,7 @@ import (
 	"time"
 
 	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
 )
 
 func TestFixFile_DryRun_WritesTempAndKeepsOriginal(t *testing.T) {
@@ -60,6 +63,38 @@ func TestFixFile_DryRun_WritesTempAndKeepsOriginal(t *testing.T) {
 	}
 }
 
+func TestFixFile_ReportsProgress(t *testing.T) {
+	workdir := t.TempDir()
+	input := filepath.Join(workdir, "in.srt")
+	// Out of order: the sort step is added to the pipeline.
+	orig := "1\n00:00:03,000 --> 00:00:04,000\nBye\n\n2\n00:00:01,000 --> 00:00:02,000\nHello\n\n"
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var steps []Progress
+	_, err := Run(context.Background(), Options{
+		InputPath:      input,
+		DryRun:         true,
+		WorkDir:        workdir,
+		SkipTranslator: true,
+		Progress:       func(p Progress) { steps = append(steps, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var got []string
+	for _, p := range steps {
+		if p.Total != 4 {
+			t.Fatalf("unexpected total: %+v", p)
+		}
+		got = append(got, p.Step)
+	}
+	if strings.Join(got, ",") != "sort,merge,shift,write" || steps[len(steps)-1].Completed != 4 {
+		t.Fatalf("unexpected steps: %+v", steps)
+	}
+}
+
 func TestFixFile_InPlace_CreatesBackup(t *testing.T) {
 	workdir, cleanup, err := run.NewWorkdir("", "test")
 	if err != nil {
@@ -756,40 +791,19 @@ func TestFixFile_StripStyleThenHI(t *testing.T) {
 	}
 }
 
-func TestShiftTimeSubtitles_ZeroShift_ReturnsSamePath(t *testing.T) {
-	workdir, cleanup, err := run.NewWorkdir("", "test")
-	if err != nil {
-		t.Fatalf("NewWorkdir: %v", err)
-	}
-	defer cleanup()
+func TestShiftCues_ZeroShift_KeepsCues(t *testing.T) {
+	subs := parseCues(t, "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n")
 
-	input := filepath.Join(workdir, "in.srt")
-	orig := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"
-	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
-		t.Fatalf("WriteFile: %v", err)
+	if err := shiftCues(subs, 0); err != nil {
+		t.Fatalf("shiftCues: %v", err)
 	}
-
-	namer := run.NewTempNamer(workdir, input)
-	shiftTime := time.Duration(0)
-
-	outPath, err := shiftTimeSubtitles(input, shiftTime, namer)
-	if err != nil {
-		t.Fatalf("shiftTimeSubtitles: %v", err)
-	}
-	if outPath != input {
-		t.Fatalf("zero shift should return input path unchanged; got %q", outPath)
+	if subs[0].FromTime != time.Second || subs[0].ToTime != 2*time.Second {
+		t.Fatalf("zero shift should keep the cues unchanged; got %+v", subs[0])
 	}
 }
 
-func TestShiftTimeSubtitles_PositiveShift(t *testing.T) {
-	workdir, cleanup, err := run.NewWorkdir("", "test")
-	if err != nil {
-		t.Fatalf("NewWorkdir: %v", err)
-	}
-	defer cleanup()
-
-	input := filepath.Join(workdir, "in.srt")
-	orig := strings.Join([]string{
+func TestShiftCues_PositiveShift(t *testing.T) {
+	subs := parseCues(t, strings.Join([]string{
 		"1",
 		"00:00:01,000 --> 00:00:02,000",
 		"Hello",
@@ -799,10 +813,7 @@ func TestShiftTimeSubtitles_PositiveShift(t *testing.T) {
 		"World",
 		"",
 		"",
-	}, "\n")
-	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
-		t.Fatalf("WriteFile: %v", err)
-	}
+	}, "\n"))
 
 	expected := strings.Join([]string{
 		"1",
@@ -816,32 +827,16 @@ func TestShiftTimeSubtitles_PositiveShift(t *testing.T) {
 		"",
 	}, "\n")
 
-	namer := run.NewTempNamer(workdir, input)
-	shiftTime := 2 * time.Second
-
-	outPath, err := shiftTimeSubtitles(input, shiftTime, namer)
-	if err != nil {
-		t.Fatalf("shiftTimeSubtitles: %v", err)
+	if err := shiftCues(subs, 2*time.Second); err != nil {
+		t.Fatalf("shiftCues: %v", err)
 	}
-
-	b, err := os.ReadFile(outPath)
-	if err != nil {
-		t.Fatalf("ReadFile: %v", err)
-	}
-	if string(b) != expected {
-		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, string(b))
+	if actual := formatCues(t, subs); actual != expected {
+		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, actual)
 	}
 }
 
-func TestShiftTimeSubtitles_NegativeShift(t *testing.T) {
-	workdir, cleanup, err := run.NewWorkdir("", "test")
-	if err != nil {
-		t.Fatalf("NewWorkdir: %v", err)
-	}
-	defer cleanup()
-
-	input := filepath.Join(workdir, "in.srt")
-	orig := strings.Join([]string{
+func TestShiftCues_NegativeShift(t *testing.T) {
+	subs := parseCues(t, strings.Join([]string{
 		"1",
 		"00:00:02,000 --> 00:00:03,000",
 		"Hello",
@@ -851,10 +846,7 @@ func TestShiftTimeSubtitles_NegativeShift(t *testing.T) {
 		"World",
 		"",
 		"",
-	}, "\n")
-	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
-		t.Fatalf("WriteFile: %v", err)
-	}
+	}, "\n"))
 
 	expected := strings.Join([]string{
 		"1",
@@ -868,43 +860,39 @@ func TestShiftTimeSubtitles_NegativeShift(t *testing.T) {
 		"",
 	}, "\n")
 
-	namer := run.NewTempNamer(workdir, input)
-	shiftTime := -500 * time.Millisecond
-
-	outPath, err := shiftTimeSubtitles(input, shiftTime, namer)
-	if err != nil {
-		t.Fatalf("shiftTimeSubtitles: %v", err)
-	}
-
-	b, err := os.ReadFile(outPath)
-	if err != nil {
-		t.Fatalf("ReadFile: %v", err)
+	if err := shiftCues(subs, -500*time.Millisecond); err != nil {
+		t.Fatalf("shiftCues: %v", err)
 	}
-	if string(b) != expected {
-		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, string(b))
+	if actual := formatCues(t, subs); actual != expected {
+		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, actual)
 	}
 }
 
-func TestShiftTimeSubtitles_NegativeResult_ReturnsError(t *testing.T) {
-	workdir, cleanup, err := run.NewWorkdir("", "test")
-	if err != nil {
-		t.Fatalf("NewWorkdir: %v", err)
-	}
-	defer cleanup()
+func TestShiftCues_NegativeResult_ReturnsError(t *testing.T) {
+	subs := parseCues(t, "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n")
 
-	input := filepath.Join(workdir, "in.srt")
-	orig := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"
-	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
-		t.Fatalf("WriteFile: %v", err)
+	// -2s, causes 1s - 2s = -1s
+	if err := shiftCues(subs, -2*time.Second); err == nil {
+		t.Fatal("expected an error for negative subtitle time, got nil")
 	}
+}
 
-	namer := run.NewTempNamer(workdir, input)
-	shiftTime := -2 * time.Second // -2s, causes 1s - 2s = -1s
+func parseCues(t *testing.T, content string) []*srt.Subtitle {
+	t.Helper()
+	subs, err := srt.ReadAll(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return subs
+}
 
-	_, err = shiftTimeSubtitles(input, shiftTime, namer)
-	if err == nil {
-		t.Fatal("expected an error for negative subtitle time, got nil")
+func formatCues(t *testing.T, subs []*srt.Subtitle) string {
+	t.Helper()
+	var b strings.Builder
+	if err := srt.WriteAll(&b, subs); err != nil {
+		t.Fatalf("WriteAll: %v", err)
 	}
+	return b.String()
 }
 
 func TestFixFile_KeepStyle_Default(t *testing.T) {
@@ -1606,3 +1594,492 @@ func TestFixFile_StripHI_SafePlus_RemovesClosedMultilineBraceCue(t *testing.T) {
 		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, string(b))
 	}
 }
+
+func TestFixFile_ReportsActions(t *testing.T) {
+	workdir := t.TempDir()
+	input := filepath.Join(workdir, "in.srt")
+	orig := "1\n00:00:00,500 --> 00:00:01,000\nTranslated by someone\n\n" +
+		"2\n00:00:01,000 --> 00:00:03,000\nHello\n\n" +
+		"3\n00:00:02,000 --> 00:00:04,000\nWorld\n\n" +
+		"4\n00:00:05,000 --> 00:00:06,000\nAgain\n\n" +
+		"5\n00:00:05,000 --> 00:00:06,000\nAgain\n\n"
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	reportPath := filepath.Join(workdir, "report.json")
+
+	res, err := Run(context.Background(), Options{
+		InputPath:      input,
+		DryRun:         true,
+		WorkDir:        workdir,
+		MaxLineLength:  DefaultMaxLineLength,
+		MinWordsMerge:  DefaultMinWordsForMerging,
+		SkipTranslator: true,
+		ReportPath:     reportPath,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var kinds []string
+	for _, a := range res.Actions {
+		kinds = append(kinds, a.Kind)
+	}
+	want := "dropped-translator-credit,merged-overlap,removed-duplicate,reindexed"
+	if strings.Join(kinds, ",") != want {
+		t.Fatalf("actions = %s, want %s (%+v)", strings.Join(kinds, ","), want, res.Actions)
+	}
+	if a := res.Actions[1]; a.Idx != 3 || a.Time != "00:00:02,000" || a.Detail != "merged into cue 2" {
+		t.Fatalf("unexpected overlap action: %+v", a)
+	}
+
+	b, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("ReadFile report: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("Unmarshal report: %v", err)
+	}
+	if report.InputPath != input || len(report.Actions) != 4 || report.Summary[ActionRemovedDuplicate] != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestFixFile_OverlapPolicies(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:03,000\n- Where were you?\n\n" +
+		"2\n00:00:02,500 --> 00:00:04,000\n- Out.\n\n"
+	cases := map[string]string{
+		OverlapPolicyMerge: "1\n00:00:01,000 --> 00:00:04,000\n- Where were you?\n- Out.\n\n",
+		OverlapPolicyTrim:  "1\n00:00:01,000 --> 00:00:02,500\n- Where were you?\n\n2\n00:00:02,500 --> 00:00:04,000\n- Out.\n\n",
+		OverlapPolicyShift: "1\n00:00:01,000 --> 00:00:03,000\n- Where were you?\n\n2\n00:00:03,000 --> 00:00:04,500\n- Out.\n\n",
+		OverlapPolicyKeep:  orig,
+	}
+	for policy, want := range cases {
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		res, err := Run(context.Background(), Options{
+			InputPath:     input,
+			DryRun:        true,
+			WorkDir:       workdir,
+			OverlapPolicy: policy,
+		})
+		if err != nil {
+			t.Fatalf("%s: Run: %v", policy, err)
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s:\n got %q\nwant %q", policy, got, want)
+		}
+	}
+}
+
+func TestFixFile_DialogueDashes(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:03,000\nWhere were you?\n\n" +
+		"2\n00:00:02,500 --> 00:00:04,000\nOut.\n\n" +
+		"3\n00:00:05,000 --> 00:00:07,000\n- Did you see him?\nNo, I was\nat home.\n\n" +
+		"4\n00:00:08,000 --> 00:00:09,000\n- Alone.\n\n"
+	cases := map[string]string{
+		DialogueDashesAll: "1\n00:00:01,000 --> 00:00:04,000\n- Where were you?\n- Out.\n\n" +
+			"2\n00:00:05,000 --> 00:00:07,000\n- Did you see him?\nNo, I was\nat home.\n\n" +
+			"3\n00:00:08,000 --> 00:00:09,000\n- Alone.\n\n",
+		DialogueDashesSecond: "1\n00:00:01,000 --> 00:00:04,000\nWhere were you?\n- Out.\n\n" +
+			"2\n00:00:05,000 --> 00:00:07,000\n- Did you see him?\nNo, I was\nat home.\n\n" +
+			"3\n00:00:08,000 --> 00:00:09,000\n- Alone.\n\n",
+		DialogueDashesNone: "1\n00:00:01,000 --> 00:00:04,000\nWhere were you?\nOut.\n\n" +
+			"2\n00:00:05,000 --> 00:00:07,000\n- Did you see him?\nNo, I was\nat home.\n\n" +
+			"3\n00:00:08,000 --> 00:00:09,000\n- Alone.\n\n",
+	}
+	for style, want := range cases {
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		res, err := Run(context.Background(), Options{
+			InputPath:      input,
+			DryRun:         true,
+			WorkDir:        workdir,
+			DialogueDashes: style,
+		})
+		if err != nil {
+			t.Fatalf("%s: Run: %v", style, err)
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s:\n got %q\nwant %q", style, got, want)
+		}
+	}
+}
+
+func TestFixFile_SelectiveStyleStripping(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:03,000\n<font color=\"#ffff00\"><i>Off-screen voice.</i></font>\n\n" +
+		"2\n00:00:04,000 --> 00:00:06,000\n<span>Hello</span> <b>there</b>.\n\n"
+	cases := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "keep",
+			opts: Options{KeepTags: []string{"i", "b"}},
+			want: "1\n00:00:01,000 --> 00:00:03,000\n<i>Off-screen voice.</i>\n\n" +
+				"2\n00:00:04,000 --> 00:00:06,000\nHello <b>there</b>.\n\n",
+		},
+		{
+			name: "strip",
+			opts: Options{StripTags: []string{"font"}},
+			want: "1\n00:00:01,000 --> 00:00:03,000\n<i>Off-screen voice.</i>\n\n" +
+				"2\n00:00:04,000 --> 00:00:06,000\n<span>Hello</span> <b>there</b>.\n\n",
+		},
+	}
+	for _, tc := range cases {
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		opts := tc.opts
+		opts.InputPath, opts.DryRun, opts.WorkDir = input, true, workdir
+		res, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: Run: %v", tc.name, err)
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(got) != tc.want {
+			t.Fatalf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+
+	_, err := Run(context.Background(), Options{InputPath: "in.srt", WorkDir: t.TempDir(), StripStyle: true, StripTags: []string{"font"}})
+	if err == nil {
+		t.Fatalf("expected an error combining strip style and strip tags")
+	}
+}
+
+func TestFixFile_ASSOverrides(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:03,000\n{\\an8}The override is not counted\n\n"
+	cases := map[bool]string{
+		false: "1\n00:00:01,000 --> 00:00:03,000\n{\\an8}The override is not counted\n\n",
+		true:  "1\n00:00:01,000 --> 00:00:03,000\nThe override is not counted\n\n",
+	}
+	for strip, want := range cases {
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		res, err := Run(context.Background(), Options{
+			InputPath:     input,
+			DryRun:        true,
+			WorkDir:       workdir,
+			MaxLineLength: len("The override is not counted"),
+			FixOCR:        true,
+			StripASSTags:  strip,
+		})
+		if err != nil {
+			t.Fatalf("strip=%v: Run: %v", strip, err)
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("strip=%v:\n got %q\nwant %q", strip, got, want)
+		}
+	}
+}
+
+func TestFixFile_PreserveIndex(t *testing.T) {
+	orig := "5\r\n00:00:01,000 --> 00:00:02,000\r\nHello  \r\n\r\n" +
+		"6\r\n00:00:02,500 --> 00:00:03,000\r\n{\\an8}\r\n\r\n" +
+		"7\r\n00:00:03,000 --> 00:00:04,000\r\n<font color=\"red\">Bye</font>\r\n\r\n"
+	workdir := t.TempDir()
+	input := filepath.Join(workdir, "in.srt")
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	res, err := Run(context.Background(), Options{
+		InputPath:     input,
+		DryRun:        true,
+		WorkDir:       workdir,
+		StripStyle:    true,
+		StripASSTags:  true,
+		PreserveIndex: true,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got, err := os.ReadFile(res.WrittenPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "5\r\n00:00:01,000 --> 00:00:02,000\r\nHello  \r\n\r\n" +
+		"7\r\n00:00:03,000 --> 00:00:04,000\r\nBye\r\n\r\n"
+	if string(got) != want {
+		t.Fatalf("unexpected output:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestFixSubtitles_InMemory(t *testing.T) {
+	subs := parseCues(t, strings.Join([]string{
+		"1",
+		"00:00:00,100 --> 00:00:00,900",
+		"Hi",
+		"",
+		"2",
+		"00:00:01,000 --> 00:00:02,000",
+		"<i>Hello</i>",
+		"",
+		"3",
+		"00:00:01,000 --> 00:00:02,000",
+		"<i>Hello</i>",
+		"",
+		"4",
+		"00:00:03,000 --> 00:00:04,000",
+		"[DOOR SLAMS]",
+		"",
+		"5",
+		"00:00:05,000 --> 00:00:06,000",
+		"Bye",
+		"",
+	}, "\n"))
+
+	var steps []string
+	fixed, actions, err := FixSubtitles(context.Background(), subs, Options{
+		StripStyle: true,
+		StripHI:    true,
+		ShiftTime:  time.Second,
+		Progress:   func(p Progress) { steps = append(steps, p.Step) },
+	})
+	if err != nil {
+		t.Fatalf("FixSubtitles: %v", err)
+	}
+	expected := "1\n00:00:01,100 --> 00:00:01,900\nHi\n\n2\n00:00:02,000 --> 00:00:03,000\nHello\n\n3\n00:00:06,000 --> 00:00:07,000\nBye\n\n"
+	if actual := formatCues(t, fixed); actual != expected {
+		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, actual)
+	}
+	if fixed[2].Idx != 3 {
+		t.Fatalf("expected the cues renumbered, got %+v", fixed[2])
+	}
+	if subs[1].Text != "<i>Hello</i>" || subs[1].FromTime != time.Second || len(subs) != 5 {
+		t.Fatalf("input cues were modified: %+v", subs[1])
+	}
+	summary := summarizeActions(actions)
+	if summary[ActionStrippedHI] != 1 || summary[ActionRemovedDuplicate] != 1 || summary[ActionShifted] != 1 {
+		t.Fatalf("unexpected actions: %v", summary)
+	}
+	if strings.Join(steps, ",") != "merge,shift" {
+		t.Fatalf("unexpected steps: %v", steps)
+	}
+}
+
+func TestFixSubtitles_Selection(t *testing.T) {
+	subs := parseCues(t, strings.Join([]string{
+		"1",
+		"00:00:01,000 --> 00:00:02,000",
+		"<i>One</i>",
+		"",
+		"2",
+		"00:00:03,000 --> 00:00:04,000",
+		"<i>Two</i>",
+		"",
+		"3",
+		"00:00:05,000 --> 00:00:06,000",
+		"<i>Three</i>",
+		"",
+	}, "\n"))
+	timeRange, err := srt.ParseTimeRange("00:00:02,500-00:00:07")
+	if err != nil {
+		t.Fatalf("ParseTimeRange: %v", err)
+	}
+
+	fixed, _, err := FixSubtitles(context.Background(), subs, Options{
+		StripStyle: true,
+		ShiftTime:  500 * time.Millisecond,
+		Selection:  srt.Selection{Idxs: []srt.IdxRange{{From: 1, To: 2}}, Times: []srt.TimeRange{timeRange}},
+	})
+	if err != nil {
+		t.Fatalf("FixSubtitles: %v", err)
+	}
+	expected := "1\n00:00:01,000 --> 00:00:02,000\n<i>One</i>\n\n" +
+		"2\n00:00:03,500 --> 00:00:04,500\nTwo\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\n<i>Three</i>\n\n"
+	if actual := formatCues(t, fixed); actual != expected {
+		t.Fatalf("output mismatch\nexpected:\n%s\n\nactual:\n%s", expected, actual)
+	}
+
+	_, _, err = FixSubtitles(context.Background(), subs, Options{Selection: srt.Selection{Idxs: []srt.IdxRange{{From: 9}}}})
+	if !errors.Is(err, srt.ErrNoSelectedCues) {
+		t.Fatalf("expected ErrNoSelectedCues, got %v", err)
+	}
+}
+
+func TestFixFile_KeepsMetadata(t *testing.T) {
+	orig := "\ufeffNOTE header\r\n\r\n" +
+		"1\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n" +
+		"NOTE about cue 2\r\n\r\n" +
+		"2\r\n00:00:01,500 --> 00:00:03,000\r\n<i>World</i>\r\n\r\n" +
+		"Credits\r\n"
+	for _, preserveIndex := range []bool{false, true} {
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		res, err := Run(context.Background(), Options{
+			InputPath:     input,
+			DryRun:        true,
+			WorkDir:       workdir,
+			StripStyle:    true,
+			PreserveIndex: preserveIndex,
+		})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		// The overlapping cues are merged, with the note of the second.
+		want := "\ufeffNOTE header\n\n" +
+			"NOTE about cue 2\n\n" +
+			"1\n00:00:01,000 --> 00:00:03,000\nHello\nWorld\n\n" +
+			"Credits\n\n"
+		if preserveIndex {
+			want = strings.ReplaceAll(want, "\n", "\r\n")
+		}
+		if string(got) != want {
+			t.Fatalf("PreserveIndex=%v: unexpected output:\n got %q\nwant %q", preserveIndex, got, want)
+		}
+	}
+}
+
+func TestFixDocument_KeepsMetadata(t *testing.T) {
+	doc := &srt.Document{
+		Header:   []string{"NOTE header"},
+		Trailer:  []string{"Credits"},
+		Warnings: []string{"read warning"},
+		Cues: []*srt.Subtitle{
+			{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: "<i>Hello</i>", ID: "intro", Notes: []string{"NOTE first"}},
+		},
+	}
+	fixed, _, err := FixDocument(context.Background(), doc, Options{StripStyle: true})
+	if err != nil {
+		t.Fatalf("FixDocument: %v", err)
+	}
+	if len(fixed.Header) != 1 || len(fixed.Trailer) != 1 || len(fixed.Warnings) != 0 {
+		t.Fatalf("unexpected document: %+v", fixed)
+	}
+	if c := fixed.Cues[0]; c.Text != "Hello" || c.ID != "intro" || len(c.Notes) != 1 {
+		t.Fatalf("unexpected cue: %+v", c)
+	}
+	if doc.Cues[0].Text != "<i>Hello</i>" || len(doc.Cues[0].Notes) != 1 {
+		t.Fatalf("FixDocument modified its input: %+v", doc.Cues[0])
+	}
+}
+
+func TestFixFile_OnlyForcedAndSkipSDH(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\n{\\forced}Welcome to Berlin\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\n[DOOR SLAMS]\n\n" +
+		"4\n00:00:07,000 --> 00:00:08,000\n{\\forced}[SIREN WAILING]\n\n"
+	fixFile := func(t *testing.T, opts Options) (string, []Action, error) {
+		t.Helper()
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		opts.InputPath, opts.DryRun, opts.WorkDir = input, true, workdir
+		res, err := Run(context.Background(), opts)
+		if err != nil {
+			return "", nil, err
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		return string(got), res.Actions, nil
+	}
+
+	got, actions, err := fixFile(t, Options{OnlyForced: true, SkipSDH: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "1\n00:00:03,000 --> 00:00:04,000\n{\\forced}Welcome to Berlin\n\n"; got != want {
+		t.Fatalf("unexpected output:\n got %q\nwant %q", got, want)
+	}
+	summary := summarizeActions(actions)
+	if summary[ActionDroppedNotForced] != 2 || summary[ActionDroppedSDH] != 1 {
+		t.Fatalf("unexpected actions: %v", summary)
+	}
+
+	if got, _, err = fixFile(t, Options{SkipSDH: true}); err != nil || strings.Contains(got, "DOOR") || !strings.Contains(got, "Hello") {
+		t.Fatalf("Run with SkipSDH = %q, %v", got, err)
+	}
+
+	orig = "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"
+	if _, _, err := fixFile(t, Options{OnlyForced: true}); !errors.Is(err, ErrNoForcedCues) {
+		t.Fatalf("Run: %v, want %v", err, ErrNoForcedCues)
+	}
+}
+
+func TestFixFile_Censor(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:02,000\nWhat the hell?\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nHello there.\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\nDamn it.\n\n"
+	cases := map[string]string{
+		"": "1\n00:00:01,000 --> 00:00:02,000\nWhat the h***?\n\n" +
+			"2\n00:00:03,000 --> 00:00:04,000\nHello there.\n\n" +
+			"3\n00:00:05,000 --> 00:00:06,000\nD*** it.\n\n",
+		"beep-text": "1\n00:00:01,000 --> 00:00:02,000\nWhat the [beep]?\n\n" +
+			"2\n00:00:03,000 --> 00:00:04,000\nHello there.\n\n" +
+			"3\n00:00:05,000 --> 00:00:06,000\n[beep] it.\n\n",
+		"remove-cue": "1\n00:00:03,000 --> 00:00:04,000\nHello there.\n\n",
+	}
+	for style, want := range cases {
+		workdir := t.TempDir()
+		input := filepath.Join(workdir, "in.srt")
+		if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		list := filepath.Join(workdir, "words.txt")
+		if err := os.WriteFile(list, []byte("hell\ndamn*\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		res, err := Run(context.Background(), Options{
+			InputPath:      input,
+			DryRun:         true,
+			WorkDir:        workdir,
+			CensorListPath: list,
+			CensorStyle:    style,
+		})
+		if err != nil {
+			t.Fatalf("%s: Run: %v", style, err)
+		}
+		got, err := os.ReadFile(res.WrittenPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s:\n got %q\nwant %q", style, got, want)
+		}
+	}
+
+	if _, _, err := FixSubtitles(context.Background(), nil, Options{CensorStyle: "bleep"}); err == nil {
+		t.Fatalf("expected an error for an invalid censor style")
+	}
+}
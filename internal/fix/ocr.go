@@ -0,0 +1,120 @@
+package fix
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+)
+
+// ocrRule replaces the matches of pattern with replacement (regexp.Expand
+// syntax). Patterns are multi-line: ^ and $ match at line breaks.
+type ocrRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func newOCRRule(pattern, replacement string) ocrRule {
+	return ocrRule{pattern: regexp.MustCompile(`(?m)` + pattern), replacement: replacement}
+}
+
+// ocrCommonRules fix artifacts of any language.
+var ocrCommonRules = []ocrRule{
+	// Stray pipes: "he|lo" -> "hello", "| think" -> "I think".
+	newOCRRule(`(\p{Ll})\|`, "${1}l"),
+	newOCRRule(`\|(\p{Ll})`, "l${1}"),
+	newOCRRule(`(^|\s)\|(\s|$)`, "${1}I${2}"),
+	// l/I confusion inside words: "heIlo" -> "hello", "ClTY" -> "CITY".
+	newOCRRule(`(\p{Ll})I(\p{Ll})`, "${1}l${2}"),
+	newOCRRule(`(\p{Lu})l(\p{Lu})`, "${1}I${2}"),
+	newOCRRule(`\bl(\p{Lu}{2,})`, "I${1}"),
+	// 0/O confusion: "G0OD" -> "GOOD", "c0ld" -> "cold", "2O15" -> "2015".
+	newOCRRule(`(\p{Lu})0(\p{Lu})`, "${1}O${2}"),
+	newOCRRule(`(\p{Ll})0(\p{Ll})`, "${1}o${2}"),
+	newOCRRule(`(\d)[Oo](\d)`, "${1}0${2}"),
+	// Doubled apostrophes used as quotes.
+	newOCRRule(`''`, `"`),
+	// Broken ellipses: ". . ." and ".." -> "...".
+	newOCRRule(`\.(?: \.){2}`, "..."),
+	newOCRRule(`(\p{L})\.\.(\s|$)`, "${1}...${2}"),
+	// Missing space after punctuation: "Hello.How" -> "Hello. How", "Yes,sir" -> "Yes, sir".
+	newOCRRule(`(\p{Ll}[.!?])(\p{Lu})`, "${1} ${2}"),
+	newOCRRule(`(\p{L}[,;])(\p{L})`, "${1} ${2}"),
+}
+
+// ocrLanguageRules fix artifacts of a language, by ISO 639-1 code.
+var ocrLanguageRules = map[string][]ocrRule{
+	"en": {
+		// "l" read instead of the pronoun "I": "l'm", "l'll", "l think".
+		newOCRRule(`\bl('(?:m|ll|ve|d|s))\b`, "I${1}"),
+		newOCRRule(`(^|[\s"¿¡-])l(\s)`, "${1}I${2}"),
+	},
+	"es": {
+		// "I" read instead of "l": "Ia casa" -> "la casa".
+		newOCRRule(`\bI(a|as|o|os|e|es)\b`, "l${1}"),
+	},
+	"fr": {
+		newOCRRule(`\bI(a|e|es)\b`, "l${1}"),
+		newOCRRule(`\bI'`, "l'"),
+	},
+	"pt": {
+		newOCRRule(`\bI(he|hes)\b`, "l${1}"),
+	},
+}
+
+// ocrRules returns the rules for language (any tag accepted by
+// naming.ShortLanguage): the common rules plus the rules of the language, if
+// any, plus the user replacements.
+func ocrRules(language string, replacements []ocrRule) []ocrRule {
+	primary, _, _ := strings.Cut(naming.ShortLanguage(language), "-")
+	rules := append([]ocrRule(nil), ocrCommonRules...)
+	rules = append(rules, ocrLanguageRules[primary]...)
+	return append(rules, replacements...)
+}
+
+// fixOCRErrors applies rules to the text of a cue, leaving its tags alone.
+func fixOCRErrors(text string, rules []ocrRule) string {
+	return mapTextTokens(text, func(raw string) string {
+		for _, r := range rules {
+			raw = r.pattern.ReplaceAllString(raw, r.replacement)
+		}
+		return raw
+	})
+}
+
+// loadOCRReplacements reads a replacements file: one "wrong=right" pair per
+// line, replaced as whole words. Empty lines and lines starting with # are
+// ignored.
+func loadOCRReplacements(path string) ([]ocrRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+
+	var rules []ocrRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		wrong, right, ok := strings.Cut(line, "=")
+		wrong, right = strings.TrimSpace(wrong), strings.TrimSpace(right)
+		if !ok || wrong == "" {
+			return nil, fmt.Errorf("%s:%d: expected wrong=right", path, n)
+		}
+		rules = append(rules, ocrRule{
+			pattern:     regexp.MustCompile(`(?m)(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(wrong) + `($|[^\p{L}\p{N}])`),
+			replacement: "${1}" + strings.ReplaceAll(right, "$", "$$") + "${2}",
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,52 @@
+package fix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixOCRErrors(t *testing.T) {
+	cases := []struct {
+		language string
+		text     string
+		want     string
+	}{
+		{"en", "| think he|lo is heIlo.", "I think hello is hello."},
+		{"en", "l'm sure l know the ClTY.", "I'm sure I know the CITY."},
+		{"en", "G0OD c0ld in 2O15", "GOOD cold in 2015"},
+		{"en", "He said ''no''.", `He said "no".`},
+		{"en", "Wait.. Well. . . fine.Really,sir", "Wait... Well... fine. Really, sir"},
+		{"en", "<font color=\"#0F0F0F\">Hello.How</font>", "<font color=\"#0F0F0F\">Hello. How</font>"},
+		{"es", "Ia casa y Ios perros", "la casa y los perros"},
+		{"", "Ia casa", "Ia casa"},
+	}
+	for _, tc := range cases {
+		if got := fixOCRErrors(tc.text, ocrRules(tc.language, nil)); got != tc.want {
+			t.Fatalf("fixOCRErrors(%q, %s) = %q, want %q", tc.text, tc.language, got, tc.want)
+		}
+	}
+}
+
+func TestLoadOCRReplacements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ocr.txt")
+	content := "# house list\n\nrnay=may\nJohn=Jon\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	replacements, err := loadOCRReplacements(path)
+	if err != nil {
+		t.Fatalf("loadOCRReplacements: %v", err)
+	}
+	got := fixOCRErrors("You rnay go, John. Johnny stays.", ocrRules("en", replacements))
+	if want := "You may go, Jon. Johnny stays."; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("no separator\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := loadOCRReplacements(path); err == nil {
+		t.Fatalf("expected an error for a line without =")
+	}
+}
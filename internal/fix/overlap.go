@@ -0,0 +1,53 @@
+package fix
+
+import (
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+const DefaultOverlapPolicy = OverlapPolicyMerge
+
+// Overlap policies: how a cue starting before the previous one ends is fixed.
+const (
+	OverlapPolicyMerge = "merge" // join both texts in a single cue
+	OverlapPolicyTrim  = "trim"  // end the previous cue when the next one starts
+	OverlapPolicyShift = "shift" // start the next cue when the previous one ends, keeping its duration
+	OverlapPolicyKeep  = "keep"  // leave both cues as they are
+)
+
+func isValidOverlapPolicy(policy string) bool {
+	return policy == OverlapPolicyMerge ||
+		policy == OverlapPolicyTrim ||
+		policy == OverlapPolicyShift ||
+		policy == OverlapPolicyKeep
+}
+
+func normalizeOverlapPolicy(policy string) string {
+	return strings.ToLower(strings.TrimSpace(policy))
+}
+
+// resolveOverlap fixes sub, which starts before last ends, following policy.
+// It returns false when sub must be merged into last instead: with the merge
+// policy, or when trimming would leave last without duration.
+func resolveOverlap(last, sub *srt.Subtitle, policy string, changes *changeLog) bool {
+	switch policy {
+	case OverlapPolicyTrim:
+		if sub.FromTime <= last.FromTime {
+			return false
+		}
+		changes.add(ActionTrimmedOverlap, last, "end %s -> %s", srt.FormatTime(last.ToTime), srt.FormatTime(sub.FromTime))
+		last.ToTime = sub.FromTime
+		return true
+	case OverlapPolicyShift:
+		delta := last.ToTime - sub.FromTime
+		changes.add(ActionShiftedOverlap, sub, "start %s -> %s", srt.FormatTime(sub.FromTime), srt.FormatTime(last.ToTime))
+		sub.FromTime += delta
+		sub.ToTime += delta
+		return true
+	case OverlapPolicyKeep:
+		return true
+	default:
+		return false
+	}
+}
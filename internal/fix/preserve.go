@@ -0,0 +1,171 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// rawCue is a cue of the input file with its exact bytes.
+type rawCue struct {
+	sub *srt.Subtitle
+	raw string // index, timing and text lines with their original line endings
+}
+
+// restoreUnchangedCues writes the fixed document (with the original cue
+// indexes) to a temp file, copying the cues that didn't change byte for byte
+// from inputPath. Changed cues and the other blocks are formatted with the
+// line endings of the input.
+func restoreUnchangedCues(inputPath string, doc *srt.Document, namer run.TempNamer) (string, error) {
+	content, err := os.ReadFile(inputPath)
+	if err != nil {
+		return "", err
+	}
+	// The BOM is written from doc, not with the first cue.
+	originals := readRawCues(strings.Replace(string(content), "\uFEFF", "", 1))
+	newline := "\n"
+	if strings.Contains(string(content), "\r\n") {
+		newline = "\r\n"
+	}
+
+	var b strings.Builder
+	writeBlocks := func(blocks []string) {
+		for _, block := range blocks {
+			b.WriteString(strings.ReplaceAll(block, "\n", newline) + newline + newline)
+		}
+	}
+	if doc.BOM {
+		b.WriteString("\uFEFF")
+	}
+	writeBlocks(doc.Header)
+	for _, sub := range doc.Cues {
+		if original, ok := originals[sub.Idx]; ok && sameCue(original.sub, sub) {
+			writeBlocks(sub.Notes)
+			b.WriteString(original.raw)
+			if !strings.HasSuffix(original.raw, "\n") {
+				b.WriteString(newline)
+			}
+		} else {
+			var cue strings.Builder
+			idx := sub.Idx
+			if err := srt.WriteOne(&cue, sub, &idx); err != nil {
+				return "", err
+			}
+			b.WriteString(strings.ReplaceAll(strings.TrimSuffix(cue.String(), "\n"), "\n", newline))
+		}
+		b.WriteString(newline)
+	}
+	writeBlocks(doc.Trailer)
+
+	outputTmpPath := namer.Step("preserve")
+	if err := fs.WriteFile(strings.NewReader(b.String()), outputTmpPath); err != nil {
+		return "", err
+	}
+	return outputTmpPath, nil
+}
+
+// readRawCues splits content into cues keyed by index; a cue named by an
+// identifier gets the index after the previous cue, as in srt.Read. Blocks
+// that don't parse as a single cue, and repeated indexes, are left out so
+// their cues are always rewritten.
+func readRawCues(content string) map[int]rawCue {
+	cues := make(map[int]rawCue)
+	repeated := make(map[int]bool)
+	prevIdx := 0
+	var block strings.Builder
+	flush := func() {
+		raw := block.String()
+		block.Reset()
+		if strings.TrimSpace(raw) == "" {
+			return
+		}
+		subs, err := srt.ReadAll(strings.NewReader(raw))
+		if err != nil || len(subs) != 1 {
+			return
+		}
+		if subs[0].ID != "" {
+			subs[0].Idx = prevIdx + 1
+		}
+		idx := subs[0].Idx
+		prevIdx = idx
+		if _, ok := cues[idx]; ok || repeated[idx] {
+			delete(cues, idx)
+			repeated[idx] = true
+			return
+		}
+		cues[idx] = rawCue{sub: subs[0], raw: raw}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Split(scanLinesWithEndings)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimRight(line, "\r\n") == "" {
+			flush()
+			continue
+		}
+		block.WriteString(line)
+	}
+	flush()
+	return cues
+}
+
+// scanLinesWithEndings is bufio.ScanLines keeping the line endings.
+func scanLinesWithEndings(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func sameCue(a, b *srt.Subtitle) bool {
+	return a.Idx == b.Idx && a.ID == b.ID && a.FromTime == b.FromTime && a.ToTime == b.ToTime && a.Text == b.Text
+}
+
+// cueNotes are the comment blocks of a cue and when it started.
+type cueNotes struct {
+	at    time.Duration
+	notes []string
+}
+
+// detachNotes removes the comment blocks from subtitles, since the processing
+// steps merge, split and drop cues, so attachNotes can put them back.
+func detachNotes(subtitles []*srt.Subtitle) []cueNotes {
+	var notes []cueNotes
+	for _, sub := range subtitles {
+		if len(sub.Notes) > 0 {
+			notes = append(notes, cueNotes{at: sub.FromTime, notes: sub.Notes})
+			sub.Notes = nil
+		}
+	}
+	return notes
+}
+
+// attachNotes puts the comment blocks back on the first cue ending after their
+// cue started (moved by shiftTime): the cue itself, the one it was merged into
+// or the next one when it was dropped. With none, they go on the last cue, so
+// no comment is lost.
+func attachNotes(subtitles []*srt.Subtitle, notes []cueNotes, shiftTime time.Duration) {
+	if len(subtitles) == 0 {
+		return
+	}
+	for _, n := range notes {
+		target := subtitles[len(subtitles)-1]
+		for _, sub := range subtitles {
+			if sub.ToTime > n.at+shiftTime {
+				target = sub
+				break
+			}
+		}
+		target.Notes = append(target.Notes, n.notes...)
+	}
+}
@@ -0,0 +1,109 @@
+package fix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Action kinds recorded in Result.Actions.
+const (
+	ActionDroppedTranslatorCredit = "dropped-translator-credit"
+	ActionDroppedNotForced        = "dropped-not-forced"
+	ActionDroppedSDH              = "dropped-sdh"
+	ActionNormalizedUnicode       = "normalized-unicode"
+	ActionFixedOCR                = "fixed-ocr"
+	ActionFixedSpelling           = "fixed-spelling"
+	ActionRemovedCredit           = "removed-credit"
+	ActionStrippedStyle           = "stripped-style"
+	ActionStrippedASSTags         = "stripped-ass-tags"
+	ActionStrippedHI              = "stripped-hi"
+	ActionRemovedDecorativeLines  = "removed-decorative-lines"
+	ActionNormalizedTypography    = "normalized-typography"
+	ActionAppliedRule             = "applied-rule"
+	ActionCensored                = "censored"
+	ActionDroppedCensored         = "dropped-censored"
+	ActionRemovedEmpty            = "removed-empty"
+	ActionRemovedInvalidTiming    = "removed-invalid-timing"
+	ActionRemovedDuplicate        = "removed-duplicate"
+	ActionMergedOverlap           = "merged-overlap"
+	ActionTrimmedOverlap          = "trimmed-overlap"
+	ActionShiftedOverlap          = "shifted-overlap"
+	ActionMergedRepeat            = "merged-repeat"
+	ActionRewrapped               = "rewrapped"
+	ActionMergedShortLines        = "merged-short-lines"
+	ActionRebalanced              = "rebalanced-lines"
+	ActionDroppedPastEnd          = "dropped-past-media-end"
+	ActionClampedToEnd            = "clamped-to-media-end"
+	ActionNormalizedDialogue      = "normalized-dialogue-dashes"
+	ActionReindexed               = "reindexed"
+	ActionSorted                  = "sorted"
+	ActionShifted                 = "shifted"
+	ActionTrimmedForGap           = "trimmed-for-gap"
+	ActionExtendedDuration        = "extended-duration"
+	ActionMergedShortCue          = "merged-short-cue"
+	ActionSplitFastCue            = "split-fast-cue"
+	ActionSnappedToShot           = "snapped-to-shot-change"
+)
+
+// Action is a change made by Run. Idx and Time identify the cue in the file
+// being processed (the input, or the sorted intermediate file when the input
+// was out of order); actions on the whole file have no Idx.
+type Action struct {
+	Kind   string `json:"kind"`
+	Idx    int    `json:"idx,omitempty"`
+	Time   string `json:"time,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the JSON document written to Options.ReportPath.
+type Report struct {
+	InputPath  string         `json:"input_path"`
+	OutputPath string         `json:"output_path"`
+	Summary    map[string]int `json:"summary"` // actions per kind
+	Actions    []Action       `json:"actions"`
+}
+
+// changeLog collects the actions of a run. A nil changeLog records nothing.
+type changeLog struct {
+	actions []Action
+}
+
+func (c *changeLog) add(kind string, sub *srt.Subtitle, format string, args ...any) {
+	if c == nil {
+		return
+	}
+	a := Action{Kind: kind}
+	if sub != nil {
+		a.Idx = sub.Idx
+		a.Time = srt.FormatTime(sub.FromTime)
+	}
+	if format != "" {
+		a.Detail = fmt.Sprintf(format, args...)
+	}
+	c.actions = append(c.actions, a)
+}
+
+// summarizeActions counts the actions per kind.
+func summarizeActions(actions []Action) map[string]int {
+	summary := make(map[string]int)
+	for _, a := range actions {
+		summary[a.Kind]++
+	}
+	return summary
+}
+
+func writeReport(path string, report Report) error {
+	if report.Actions == nil {
+		report.Actions = []Action{}
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	return fs.WriteFile(bytes.NewReader(b), path)
+}
@@ -0,0 +1,88 @@
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes of a find/replace rule.
+const (
+	RuleScopeText = "text" // the text between tags, line by line
+	RuleScopeCue  = "cue"  // the whole cue text, including tags and line breaks
+)
+
+// rulesFile is the format of the --rules file.
+type rulesFile struct {
+	Rules []struct {
+		Name    string `yaml:"name"`
+		Find    string `yaml:"find"`
+		Replace string `yaml:"replace"`
+		Scope   string `yaml:"scope"`
+	} `yaml:"rules"`
+}
+
+// replaceRule is a user find/replace rule; replace uses regexp.Expand syntax
+// ($1, ${name}).
+type replaceRule struct {
+	name    string
+	find    *regexp.Regexp
+	replace string
+	scope   string
+}
+
+// loadReplaceRules reads the ordered rules of a YAML rules file.
+func loadReplaceRules(path string) ([]replaceRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file rulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	rules := make([]replaceRule, 0, len(file.Rules))
+	for i, r := range file.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if r.Find == "" {
+			return nil, fmt.Errorf("%s: %s: find is required", path, name)
+		}
+		scope := strings.ToLower(strings.TrimSpace(r.Scope))
+		if scope == "" {
+			scope = RuleScopeText
+		}
+		if scope != RuleScopeText && scope != RuleScopeCue {
+			return nil, fmt.Errorf("%s: %s: invalid scope %q (supported: %s, %s)", path, name, r.Scope, RuleScopeText, RuleScopeCue)
+		}
+		pattern := r.Find
+		if scope == RuleScopeText {
+			pattern = "(?m)" + pattern
+		}
+		find, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		rules = append(rules, replaceRule{name: name, find: find, replace: r.Replace, scope: scope})
+	}
+	return rules, nil
+}
+
+// apply runs the rule on text.
+func (r replaceRule) apply(text string) string {
+	if r.scope == RuleScopeCue {
+		return r.find.ReplaceAllString(text, r.replace)
+	}
+	return mapTextTokens(text, func(raw string) string {
+		return r.find.ReplaceAllString(raw, r.replace)
+	})
+}
@@ -0,0 +1,58 @@
+package fix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadReplaceRules_AppliesInOrderWithScopes(t *testing.T) {
+	path := writeRules(t, `
+rules:
+  - name: watermark
+    find: '(?i)\s*www\.\S+'
+    replace: ''
+  - name: okay
+    find: '\bOK\b'
+    replace: 'Okay'
+  - name: italics to quotes
+    find: '<i>(.*?)</i>'
+    replace: '"$1"'
+    scope: cue
+`)
+	rules, err := loadReplaceRules(path)
+	if err != nil {
+		t.Fatalf("loadReplaceRules: %v", err)
+	}
+	text := "OK, see <i>the Sign</i> WWW.SUBS.EXAMPLE\n<font color=\"OK\">OK.</font>"
+	for _, r := range rules {
+		text = r.apply(text)
+	}
+	if want := "Okay, see \"the Sign\"\n<font color=\"OK\">Okay.</font>"; text != want {
+		t.Fatalf("got %q, want %q", text, want)
+	}
+}
+
+func TestLoadReplaceRules_InvalidRules(t *testing.T) {
+	cases := map[string]string{
+		"missing find":  "rules:\n  - name: x\n    replace: y\n",
+		"invalid scope": "rules:\n  - find: x\n    scope: line\n",
+		"invalid regex": "rules:\n  - find: '(x'\n",
+		"unknown field": "rules:\n  - find: x\n    replacement: y\n",
+	}
+	for name, content := range cases {
+		if _, err := loadReplaceRules(writeRules(t, content)); err == nil || !strings.Contains(err.Error(), "rules.yaml") {
+			t.Fatalf("%s: expected an error naming the file, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package fix
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// DefaultSnapThreshold is how far from a shot change a cue start or end is
+// snapped to it: 12 frames at 24 fps.
+const DefaultSnapThreshold = 500 * time.Millisecond
+
+// snapToShotChanges moves the cue starts within threshold of a shot change
+// to it, and the cue ends within threshold of one to gap before it, so cues
+// neither cross a cut by a few frames nor end just before one. A time isn't
+// moved when that would leave the cue empty or make it overlap its
+// neighbors. subtitles must be sorted and shotChanges in order.
+func snapToShotChanges(subtitles []*srt.Subtitle, shotChanges []time.Duration, threshold, gap time.Duration, preserveIndex bool, changes *changeLog) {
+	slog.Info("snapping cues to shot changes", "shot_changes", len(shotChanges), "threshold", threshold)
+	for i, sub := range subtitles {
+		if cut, ok := nearestShotChange(shotChanges, sub.FromTime, threshold); ok && cut != sub.FromTime {
+			overlapsPrev := i > 0 && cut < sub.FromTime && cut < subtitles[i-1].ToTime
+			if cut < sub.ToTime && !overlapsPrev {
+				changes.add(ActionSnappedToShot, sub, "start %s -> %s", srt.FormatTime(sub.FromTime), srt.FormatTime(cut))
+				sub.FromTime = cut
+			}
+		}
+		if cut, ok := nearestShotChange(shotChanges, sub.ToTime, threshold); ok && cut-gap != sub.ToTime {
+			end := cut - gap
+			overlapsNext := i+1 < len(subtitles) && end > sub.ToTime && end > subtitles[i+1].FromTime
+			if end > sub.FromTime && !overlapsNext {
+				changes.add(ActionSnappedToShot, sub, "end %s -> %s", srt.FormatTime(sub.ToTime), srt.FormatTime(end))
+				sub.ToTime = end
+			}
+		}
+	}
+	settleCues(subtitles, preserveIndex)
+}
+
+// nearestShotChange returns the shot change closest to t, if it's within
+// threshold.
+func nearestShotChange(shotChanges []time.Duration, t, threshold time.Duration) (time.Duration, bool) {
+	i, _ := slices.BinarySearch(shotChanges, t)
+	best, found := time.Duration(0), false
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(shotChanges) {
+			continue
+		}
+		if d := (shotChanges[j] - t).Abs(); d <= threshold && (!found || d < (best-t).Abs()) {
+			best, found = shotChanges[j], true
+		}
+	}
+	return best, found
+}
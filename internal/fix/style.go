@@ -22,6 +22,7 @@ type subtitleTagType int
 const (
 	subtitleTokenText subtitleTokenKind = iota
 	subtitleTokenTag
+	subtitleTokenOverride // ASS override block ({\an8}), kept as is
 )
 
 const (
@@ -35,7 +36,32 @@ func isHtmlTagLine(s string) bool {
 	return strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">")
 }
 
-func stripSubtitleStyles(text string) string {
+// stripsStyle reports whether the options remove any style tag.
+func (o Options) stripsStyle() bool {
+	return o.StripStyle || len(o.KeepTags) > 0 || len(o.StripTags) > 0
+}
+
+// stripsStyleTag reports whether the style tag name is removed following
+// StripTags or KeepTags (every tag when neither is set).
+func (o Options) stripsStyleTag(name string) bool {
+	if len(o.StripTags) > 0 {
+		return containsTagName(o.StripTags, name)
+	}
+	return !containsTagName(o.KeepTags, name)
+}
+
+func containsTagName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripSubtitleStyles removes the tags of text for which strip returns true.
+// Open tags without a matching close tag are kept.
+func stripSubtitleStyles(text string, strip func(tagName string) bool) string {
 	tokens := tokenizeSubtitleText(text)
 	if !tokensContainTags(tokens) {
 		return text
@@ -44,7 +70,7 @@ func stripSubtitleStyles(text string) string {
 	var stack []int
 	for i := range tokens {
 		tok := &tokens[i]
-		if tok.kind != subtitleTokenTag {
+		if tok.kind != subtitleTokenTag || !strip(tok.tagName) {
 			continue
 		}
 		switch tok.tagType {
@@ -110,17 +136,37 @@ func tokensContainTags(tokens []subtitleToken) bool {
 	return false
 }
 
+// mapTextTokens applies fn to the text between the tags of text, in order.
+func mapTextTokens(text string, fn func(string) string) string {
+	var b strings.Builder
+	for _, token := range tokenizeSubtitleText(text) {
+		if token.kind == subtitleTokenText {
+			b.WriteString(fn(token.raw))
+		} else {
+			b.WriteString(token.raw)
+		}
+	}
+	return b.String()
+}
+
 func tokenizeSubtitleText(text string) []subtitleToken {
 	var tokens []subtitleToken
 	for i := 0; i < len(text); {
+		if text[i] == '{' {
+			if loc := assOverridePattern.FindStringIndex(text[i:]); loc != nil && loc[0] == 0 {
+				tokens = append(tokens, subtitleToken{kind: subtitleTokenOverride, raw: text[i : i+loc[1]]})
+				i += loc[1]
+				continue
+			}
+		}
 		if text[i] != '<' {
-			next := strings.IndexByte(text[i:], '<')
+			next := strings.IndexAny(text[i+1:], "<{")
 			if next == -1 {
 				tokens = append(tokens, subtitleToken{kind: subtitleTokenText, raw: text[i:]})
 				break
 			}
-			tokens = append(tokens, subtitleToken{kind: subtitleTokenText, raw: text[i : i+next]})
-			i += next
+			tokens = append(tokens, subtitleToken{kind: subtitleTokenText, raw: text[i : i+1+next]})
+			i += 1 + next
 			continue
 		}
 		end := strings.IndexByte(text[i:], '>')
@@ -204,10 +250,7 @@ func isValidSubtitleTagName(name string) bool {
 func hasTextBeforeNewline(tokens []subtitleToken, startIdx int) bool {
 	for i := startIdx + 1; i < len(tokens); i++ {
 		tok := tokens[i]
-		if tok.kind == subtitleTokenTag {
-			if tok.remove {
-				continue
-			}
+		if tok.kind != subtitleTokenText {
 			continue
 		}
 		if tok.raw == "" {
@@ -0,0 +1,112 @@
+package fix
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// maxTimingMergeLines is the max number of lines of a cue created by merging a
+// too-short cue with the next one.
+const maxTimingMergeLines = 2
+
+// enforceTiming applies the minimum duration and minimum gap rules to
+// subtitles.
+func enforceTiming(subtitles []*srt.Subtitle, minDuration, minGap time.Duration, preserveIndex bool, changes *changeLog) []*srt.Subtitle {
+	slog.Info("enforcing cue timing", "min_duration", minDuration, "min_gap", minGap)
+	subtitles = applyTimingRules(subtitles, minDuration, minGap, changes)
+	settleCues(subtitles, preserveIndex)
+	return subtitles
+}
+
+// clampCues drops the cues starting at or after mediaDuration and ends the
+// others no later than it.
+func clampCues(subtitles []*srt.Subtitle, mediaDuration time.Duration, preserveIndex bool, changes *changeLog) []*srt.Subtitle {
+	slog.Info("clamping cues to the media duration", "media_duration", mediaDuration)
+	kept := subtitles[:0]
+	for _, sub := range subtitles {
+		if sub.FromTime >= mediaDuration {
+			changes.add(ActionDroppedPastEnd, sub, "starts after the media ends at %s", srt.FormatTime(mediaDuration))
+			continue
+		}
+		if sub.ToTime > mediaDuration {
+			changes.add(ActionClampedToEnd, sub, "end %s -> %s", srt.FormatTime(sub.ToTime), srt.FormatTime(mediaDuration))
+			sub.ToTime = mediaDuration
+		}
+		kept = append(kept, sub)
+	}
+	settleCues(kept, preserveIndex)
+	return kept
+}
+
+// settleCues cleans the text of the cues and truncates their times to
+// milliseconds, as writing them to a file would, so the following steps see
+// the cues as they'll be written. They are renumbered from 1 unless
+// preserveIndex is set.
+func settleCues(subtitles []*srt.Subtitle, preserveIndex bool) {
+	for i, sub := range subtitles {
+		sub.Text = srt.CleanText(sub.Text)
+		sub.FromTime = sub.FromTime.Truncate(time.Millisecond)
+		sub.ToTime = sub.ToTime.Truncate(time.Millisecond)
+		if !preserveIndex {
+			sub.Idx = i + 1
+		}
+	}
+}
+
+// applyTimingRules enforces a gap of at least minGap between consecutive cues
+// by trimming end times, and extends cues shorter than minDuration as far as
+// the next cue (and minGap) allows. A cue that still can't reach minDuration
+// is merged with the next one when the result fits in maxTimingMergeLines.
+// subtitles must be sorted; overlapping cues (kept with OverlapPolicyKeep)
+// are left as they are.
+func applyTimingRules(subtitles []*srt.Subtitle, minDuration, minGap time.Duration, changes *changeLog) []*srt.Subtitle {
+	kept := make([]*srt.Subtitle, 0, len(subtitles))
+	for i := 0; i < len(subtitles); i++ {
+		sub := subtitles[i]
+		for {
+			var next *srt.Subtitle
+			limit := time.Duration(-1) // no limit for the last cue
+			if i+1 < len(subtitles) {
+				next = subtitles[i+1]
+				limit = next.FromTime - max(minGap, 0)
+			}
+			if next != nil && next.FromTime < sub.ToTime {
+				break
+			}
+
+			if limit >= 0 && sub.ToTime > limit && limit > sub.FromTime {
+				changes.add(ActionTrimmedForGap, sub, "end %s -> %s", srt.FormatTime(sub.ToTime), srt.FormatTime(limit))
+				sub.ToTime = limit
+			}
+			if minDuration <= 0 || sub.ToTime-sub.FromTime >= minDuration {
+				break
+			}
+
+			target := sub.FromTime + minDuration
+			if limit >= 0 && target > limit {
+				target = limit
+			}
+			if target > sub.ToTime {
+				changes.add(ActionExtendedDuration, sub, "end %s -> %s", srt.FormatTime(sub.ToTime), srt.FormatTime(target))
+				sub.ToTime = target
+			}
+			if sub.ToTime-sub.FromTime >= minDuration || next == nil {
+				break
+			}
+
+			text := sub.Text + "\n" + next.Text
+			if len(strings.Split(text, "\n")) > maxTimingMergeLines {
+				break
+			}
+			changes.add(ActionMergedShortCue, next, "merged into cue %d", sub.Idx)
+			sub.Text = text
+			sub.ToTime = max(sub.ToTime, next.ToTime)
+			i++ // next is consumed; check the merged cue against the one after it
+		}
+		kept = append(kept, sub)
+	}
+	return kept
+}
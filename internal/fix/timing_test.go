@@ -0,0 +1,107 @@
+package fix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func ms(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+func TestApplyTimingRules_TrimsGapAndExtendsShortCues(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, FromTime: ms(1000), ToTime: ms(2980), Text: "Hello"}, // gap of 20ms to the next cue
+		{Idx: 2, FromTime: ms(3000), ToTime: ms(3300), Text: "Hi"},    // too short, room to extend
+		{Idx: 3, FromTime: ms(6000), ToTime: ms(6400), Text: "Wait"},  // too short, extension limited by cue 4
+		{Idx: 4, FromTime: ms(6700), ToTime: ms(8000), Text: "For me"},
+		{Idx: 5, FromTime: ms(9000), ToTime: ms(9200), Text: "Bye\nfolks"}, // too short, too many lines to merge
+		{Idx: 6, FromTime: ms(9300), ToTime: ms(10000), Text: "Bye"},
+	}
+	changes := &changeLog{}
+	got := applyTimingRules(subs, time.Second, ms(80), changes)
+
+	want := []struct {
+		from, to int
+		text     string
+	}{
+		{1000, 2920, "Hello"},
+		{3000, 4000, "Hi"},
+		{6000, 8000, "Wait\nFor me"},
+		{9000, 9220, "Bye\nfolks"},
+		{9300, 10300, "Bye"}, // the last cue is extended freely
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d cues, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].FromTime != ms(w.from) || got[i].ToTime != ms(w.to) || got[i].Text != w.text {
+			t.Fatalf("cue %d = %v --> %v %q, want %dms --> %dms %q", i, got[i].FromTime, got[i].ToTime, got[i].Text, w.from, w.to, w.text)
+		}
+	}
+
+	counts := summarizeActions(changes.actions)
+	if counts[ActionTrimmedForGap] != 1 || counts[ActionExtendedDuration] != 4 || counts[ActionMergedShortCue] != 1 {
+		t.Fatalf("unexpected actions: %+v", changes.actions)
+	}
+}
+
+func TestFixFile_ClampsToMediaDuration(t *testing.T) {
+	orig := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"2\n00:00:09,000 --> 00:00:11,000\nPast the end\n\n" +
+		"3\n00:00:12,000 --> 00:00:13,000\nAfter the end\n\n"
+	workdir := t.TempDir()
+	input := filepath.Join(workdir, "in.srt")
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	res, err := Run(context.Background(), Options{
+		InputPath:     input,
+		DryRun:        true,
+		WorkDir:       workdir,
+		MediaDuration: 10 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got, err := os.ReadFile(res.WrittenPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"2\n00:00:09,000 --> 00:00:10,000\nPast the end\n\n"
+	if string(got) != want {
+		t.Fatalf("unexpected output:\n got %q\nwant %q", got, want)
+	}
+	summary := summarizeActions(res.Actions)
+	if summary[ActionClampedToEnd] != 1 || summary[ActionDroppedPastEnd] != 1 {
+		t.Fatalf("unexpected actions: %v", summary)
+	}
+}
+
+func TestSnapToShotChanges(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, FromTime: ms(1000), ToTime: ms(5100)},   // ends 100ms into the next shot
+		{Idx: 2, FromTime: ms(5080), ToTime: ms(7000)},   // starts 80ms after the cut
+		{Idx: 3, FromTime: ms(9700), ToTime: ms(10300)},  // snapping the end too would leave it empty
+		{Idx: 4, FromTime: ms(12000), ToTime: ms(19700)}, // ends 300ms before the cut
+		{Idx: 5, FromTime: ms(20100), ToTime: ms(22000)},
+		{Idx: 6, FromTime: ms(38000), ToTime: ms(40600)},
+		{Idx: 7, FromTime: ms(40300), ToTime: ms(42000)}, // would overlap cue 6 more
+	}
+	changes := &changeLog{}
+	snapToShotChanges(subs, []time.Duration{ms(5000), ms(10000), ms(20000), ms(40000)}, DefaultSnapThreshold, ms(83), false, changes)
+
+	want := [][2]int{{1000, 4917}, {5000, 7000}, {10000, 10300}, {12000, 19917}, {20000, 22000}, {38000, 40600}, {40300, 42000}}
+	for i, w := range want {
+		if subs[i].FromTime != ms(w[0]) || subs[i].ToTime != ms(w[1]) {
+			t.Errorf("cue %d = %v --> %v, want %dms --> %dms", i+1, subs[i].FromTime, subs[i].ToTime, w[0], w[1])
+		}
+	}
+	if got := summarizeActions(changes.actions)[ActionSnappedToShot]; got != 5 {
+		t.Fatalf("got %d snaps, want 5: %+v", got, changes.actions)
+	}
+}
@@ -0,0 +1,206 @@
+package fix
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+)
+
+// Quote styles.
+const (
+	QuotesStraight = "straight" // "text" and it's
+	QuotesCurly    = "curly"    // the quotes of the language: “text” (en), „text“ (de), « text » (fr)
+)
+
+// Dialogue dash styles.
+const (
+	DashHyphen = "hyphen"  // - Hi.
+	DashEnDash = "en-dash" // – Hi.
+	DashEmDash = "em-dash" // — Hi.
+)
+
+// Ellipsis styles.
+const (
+	EllipsisDots = "dots" // ...
+	EllipsisChar = "char" // …
+)
+
+// Typography selects the punctuation and typography normalizations applied
+// by Run. Each one is independent; the zero value changes nothing.
+type Typography struct {
+	Quotes       string // QuotesStraight or QuotesCurly
+	DialogueDash string // DashHyphen, DashEnDash or DashEmDash
+	Ellipsis     string // EllipsisDots or EllipsisChar
+	// Spacing collapses repeated spaces and fixes the spacing around
+	// punctuation following the conventions of the language (e.g. a
+	// non-breaking space before ; : ! ? in French).
+	Spacing bool
+	// InvertedMarks adds the missing opening ¿ and ¡ of Spanish questions and
+	// exclamations.
+	InvertedMarks bool
+}
+
+func (t Typography) enabled() bool {
+	return t != Typography{}
+}
+
+func (t Typography) validate() error {
+	if t.Quotes != "" && t.Quotes != QuotesStraight && t.Quotes != QuotesCurly {
+		return fmt.Errorf("invalid quote style %q (supported: %s, %s)", t.Quotes, QuotesStraight, QuotesCurly)
+	}
+	if t.DialogueDash != "" && t.DialogueDash != DashHyphen && t.DialogueDash != DashEnDash && t.DialogueDash != DashEmDash {
+		return fmt.Errorf("invalid dash style %q (supported: %s, %s, %s)", t.DialogueDash, DashHyphen, DashEnDash, DashEmDash)
+	}
+	if t.Ellipsis != "" && t.Ellipsis != EllipsisDots && t.Ellipsis != EllipsisChar {
+		return fmt.Errorf("invalid ellipsis style %q (supported: %s, %s)", t.Ellipsis, EllipsisDots, EllipsisChar)
+	}
+	return nil
+}
+
+const nbsp = "\u00a0"
+
+var (
+	dotsPattern            = regexp.MustCompile(`\.{3,}`)
+	dialogueDashPattern    = regexp.MustCompile(`(?m)^[-–—][ \t]*(\S)`)
+	multiSpacePattern      = regexp.MustCompile(`[ \t\x{00a0}]{2,}`)
+	spaceBeforePunctuation = regexp.MustCompile(`([^\s\-–—])[ \t\x{00a0}]+([,.;:!?…)\]])`)
+	spaceAfterOpening      = regexp.MustCompile(`([¿¡(\[])[ \t\x{00a0}]+`)
+	frenchHighPunctuation  = regexp.MustCompile(`(?m)([^\s;:!?«])[ \t\x{00a0}]*([;:!?]+)($|[^\d])`)
+	frenchGuillemetsOpen   = regexp.MustCompile(`«[ \t\x{00a0}]*`)
+	frenchGuillemetsClose  = regexp.MustCompile(`[ \t\x{00a0}]*»`)
+)
+
+// dashes are the characters of the dash styles.
+var dashes = map[string]string{DashHyphen: "-", DashEnDash: "–", DashEmDash: "—"}
+
+// curlyQuotes are the double quotes (open, close) of each language.
+var curlyQuotes = map[string][2]string{
+	"de": {"„", "“"},
+	"fr": {"«" + nbsp, nbsp + "»"},
+}
+
+var defaultCurlyQuotes = [2]string{"“", "”"}
+
+// applyTypography normalizes the text between the tags of a cue.
+func applyTypography(text string, t Typography, language string) string {
+	primary, _, _ := strings.Cut(naming.ShortLanguage(language), "-")
+	if t.Ellipsis != "" {
+		text = mapTextTokens(text, func(s string) string {
+			if t.Ellipsis == EllipsisChar {
+				return dotsPattern.ReplaceAllString(s, "…")
+			}
+			return strings.ReplaceAll(s, "…", "...")
+		})
+	}
+	if t.DialogueDash != "" {
+		dash := dashes[t.DialogueDash]
+		text = mapTextTokens(text, func(s string) string {
+			return dialogueDashPattern.ReplaceAllString(s, dash+" $1")
+		})
+	}
+	if t.Quotes != "" {
+		text = normalizeQuotes(text, t.Quotes, primary)
+	}
+	if t.InvertedMarks && primary == "es" {
+		text = mapTextTokens(text, addInvertedMarks)
+	}
+	if t.Spacing {
+		text = mapTextTokens(text, func(s string) string {
+			s = multiSpacePattern.ReplaceAllString(s, " ")
+			s = spaceAfterOpening.ReplaceAllString(s, "$1")
+			if primary != "fr" {
+				return spaceBeforePunctuation.ReplaceAllString(s, "$1$2")
+			}
+			s = frenchHighPunctuation.ReplaceAllString(s, "$1"+nbsp+"$2$3")
+			s = frenchGuillemetsOpen.ReplaceAllString(s, "«"+nbsp)
+			return frenchGuillemetsClose.ReplaceAllString(s, nbsp+"»")
+		})
+	}
+	return text
+}
+
+// normalizeQuotes makes all the quotes straight, and then curly when style is
+// QuotesCurly. A quote opens at the start of the text or after a space,
+// dash or opening bracket, and closes otherwise.
+func normalizeQuotes(text, style, language string) string {
+	straight := strings.NewReplacer(
+		"«"+nbsp, `"`, nbsp+"»", `"`, "« ", `"`, " »", `"`,
+		"“", `"`, "”", `"`, "„", `"`, "«", `"`, "»", `"`, "‹", `'`, "›", `'`,
+		"‘", `'`, "’", `'`, "‚", `'`,
+	)
+	if style == QuotesStraight {
+		return mapTextTokens(text, straight.Replace)
+	}
+
+	double, ok := curlyQuotes[language]
+	if !ok {
+		double = defaultCurlyQuotes
+	}
+	prev := ' ' // the last character, across tags
+	return mapTextTokens(text, func(s string) string {
+		var b strings.Builder
+		for _, r := range straight.Replace(s) {
+			opening := unicode.IsSpace(prev) || strings.ContainsRune("([{-–—", prev)
+			switch {
+			case r == '"' && opening:
+				b.WriteString(double[0])
+			case r == '"':
+				b.WriteString(double[1])
+			case r == '\'' && opening:
+				b.WriteRune('‘')
+			case r == '\'':
+				b.WriteRune('’')
+			default:
+				b.WriteRune(r)
+			}
+			prev = r
+		}
+		return b.String()
+	})
+}
+
+// addInvertedMarks adds the opening ¿ or ¡ of the questions and exclamations
+// that lack it: "Qué hora es?" -> "¿Qué hora es?".
+func addInvertedMarks(s string) string {
+	runes := []rune(s)
+	var out []rune
+	start := 0 // start of the current sentence in runes
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r != '?' && r != '!' && r != '.' && r != '…' {
+			continue
+		}
+		end := i
+		for end+1 < len(runes) && strings.ContainsRune("?!.…", runes[end+1]) {
+			end++
+		}
+		sentence := runes[start : end+1]
+		if opening := invertedMark(r); opening != 0 && !strings.ContainsRune(string(sentence), opening) {
+			// Insert after the leading spaces, dashes, quotes and ellipsis.
+			at := 0
+			for at < len(sentence) && (unicode.IsSpace(sentence[at]) || strings.ContainsRune(`-–—"“«'.…`, sentence[at])) {
+				at++
+			}
+			if at < len(sentence) && sentence[at] != r {
+				sentence = append(append(append([]rune(nil), sentence[:at]...), opening), sentence[at:]...)
+			}
+		}
+		out = append(out, sentence...)
+		start, i = end+1, end
+	}
+	return string(append(out, runes[start:]...))
+}
+
+func invertedMark(r rune) rune {
+	switch r {
+	case '?':
+		return '¿'
+	case '!':
+		return '¡'
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,31 @@
+package fix
+
+import "testing"
+
+func TestApplyTypography(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		t        Typography
+		language string
+		want     string
+	}{
+		{"curly quotes", `He said "it's 'fine'".`, Typography{Quotes: QuotesCurly}, "en", "He said “it’s ‘fine’”."},
+		{"german quotes", `Er sagt: "Ja".`, Typography{Quotes: QuotesCurly}, "de", "Er sagt: „Ja“."},
+		{"straight quotes", "« Oui », c’est “ça”.", Typography{Quotes: QuotesStraight}, "fr", `"Oui", c'est "ça".`},
+		{"quotes across tags", `<i>"Run"</i>`, Typography{Quotes: QuotesCurly}, "en", "<i>“Run”</i>"},
+		{"dialogue dash", "-Hi.\n—  Hello.", Typography{DialogueDash: DashEnDash}, "en", "– Hi.\n– Hello."},
+		{"ellipsis char", "Wait.... what", Typography{Ellipsis: EllipsisChar}, "en", "Wait… what"},
+		{"ellipsis dots", "Wait… what", Typography{Ellipsis: EllipsisDots}, "en", "Wait... what"},
+		{"spacing", "Hello  , world ! ( yes )\n- ... and then", Typography{Spacing: true}, "en", "Hello, world! (yes)\n- ... and then"},
+		{"french spacing", "Quoi? Il est 10:30 ! «Oui»", Typography{Spacing: true}, "fr", "Quoi\u00a0? Il est 10:30\u00a0! «\u00a0Oui\u00a0»"},
+		{"inverted marks", "- Qué hora es?\n- Las diez. Vamos!", Typography{InvertedMarks: true}, "es", "- ¿Qué hora es?\n- Las diez. ¡Vamos!"},
+		{"inverted marks kept", "Hola, ¿qué tal?\nBien, y tú?", Typography{InvertedMarks: true}, "es", "Hola, ¿qué tal?\n¿Bien, y tú?"},
+		{"inverted marks only in spanish", "What?", Typography{InvertedMarks: true}, "en", "What?"},
+	}
+	for _, tc := range cases {
+		if got := applyTypography(tc.text, tc.t, tc.language); got != tc.want {
+			t.Fatalf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+}
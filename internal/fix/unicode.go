@@ -0,0 +1,51 @@
+package fix
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// invisibleChars are removed by normalizeUnicode: zero-width spaces, byte
+// order marks left in the middle of the text (e.g. by joining files), soft
+// hyphens and word joiners. Some players show them as boxes, and they make
+// identical lines compare different. Zero-width joiners and non-joiners and
+// the direction marks are kept, as they change how text is shown.
+var invisibleChars = map[rune]bool{
+	'\u200b': true, // zero width space
+	'\ufeff': true, // byte order mark (zero width no-break space)
+	'\u00ad': true, // soft hyphen
+	'\u2060': true, // word joiner
+	'\u180e': true, // mongolian vowel separator
+}
+
+// spaceReplacements are the space variants replaced by normalizeUnicode: the
+// non-breaking ones by the common no-break space, which every player font has
+// (and typography spacing uses), and the others by a plain space. Ideographic
+// spaces (U+3000) are part of CJK text and are kept.
+var spaceReplacements = map[rune]rune{
+	'\u202f': '\u00a0', // narrow no-break space
+	'\u2007': '\u00a0', // figure space
+	// en, em, thin, hair and other typographic spaces
+	'\u2000': ' ', '\u2001': ' ', '\u2002': ' ', '\u2003': ' ', '\u2004': ' ', '\u2005': ' ',
+	'\u2006': ' ', '\u2008': ' ', '\u2009': ' ', '\u200a': ' ', '\u205f': ' ',
+}
+
+// normalizeUnicode removes invisible characters, makes space variants
+// consistent and composes the text to NFC, so a letter written as a base and
+// a combining accent (as some tools and macOS file names do) becomes the
+// single precomposed character players and comparisons expect.
+func normalizeUnicode(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		if invisibleChars[r] {
+			continue
+		}
+		if s, ok := spaceReplacements[r]; ok {
+			r = s
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
@@ -0,0 +1,28 @@
+package fix
+
+import "testing"
+
+func TestNormalizeUnicode(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"composes accents", "Cafe\u0301 con leche, nin\u0303o", "Café con leche, niño"},
+		{"composes several marks", "Vie\u0323\u0302t", "Việt"},
+		{"composes past a mark of another class", "a\u0332\u0301", "\u00e1\u0332"},
+		{"reorders marks", "a\u0302\u0323", "\u1ead"},
+		{"composes kana", "か\u3099ん", "がん"},
+		{"composes hangul jamo", "\u1112\u1161\u11ab", "한"},
+		{"removes invisible characters", "Hel\u00adlo\u200b wor\ufeffld", "Hello world"},
+		{"keeps joiners and direction marks", "\u200fשלום\u200f \u200d", "\u200fשלום\u200f \u200d"},
+		{"replaces spaces", "10\u202f%\u2009ok\u2003yes", "10\u00a0% ok yes"},
+		{"replaces singletons", "5\u2126, 3\u212b, \uf900", "5\u03a9, 3\u00c5, \u8c48"},
+		{"leaves composed text", "Ça va? ¿Qué tal? 日本語", "Ça va? ¿Qué tal? 日本語"},
+	}
+	for _, tc := range cases {
+		if got := normalizeUnicode(tc.text); got != tc.want {
+			t.Errorf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package fix
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// languageMaxLineLengths are the default line lengths of the languages
+// written without spaces or in wide characters, in columns (a full-width
+// character counts as two): 13 characters in Japanese and 16 in Chinese and
+// Korean, as in common subtitling guidelines.
+var languageMaxLineLengths = map[string]int{
+	"ja": 26,
+	"zh": 32,
+	"ko": 32,
+}
+
+// MaxLineLengthFor returns the default line length of language (any tag
+// accepted by naming.ShortLanguage): DefaultMaxLineLength unless the
+// language has its own.
+func MaxLineLengthFor(language string) int {
+	primary, _, _ := strings.Cut(naming.ShortLanguage(language), "-")
+	if n, ok := languageMaxLineLengths[primary]; ok {
+		return n
+	}
+	return DefaultMaxLineLength
+}
+
+// isCJKBreakable reports whether a line can be broken next to r without a
+// space: Chinese and Japanese are written without spaces between words.
+// Hangul is left out, as Korean separates its words with spaces.
+func isCJKBreakable(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xffef // CJK punctuation, fullwidth forms
+}
+
+// noLineStart and noLineEnd are the characters a line must not start or end
+// with (kinsoku shori): closing punctuation, small kana and the prolonged
+// sound mark stay with the character before them, opening brackets with the
+// one after.
+const (
+	noLineStart = "、。，．・：；？！゛゜ヽヾゝゞ々ー）］｝」』〉》】〕〙〗…‥ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮヵヶ!?),.:;"
+	noLineEnd   = "（［｛「『〈《【〔〘〖("
+)
+
+// token is a unit of text a line can be broken before: a word, or a single
+// character of Chinese or Japanese text.
+type token struct {
+	text  string
+	space bool // separated from the previous token by a space
+}
+
+// tokenize splits text into the units a line can be broken before. Line
+// breaks within Chinese or Japanese text are dropped instead of becoming
+// spaces.
+func tokenize(text string) []token {
+	var tokens []token
+	var prev rune // last visible rune of the previous field
+	rest := text
+	for rest != "" {
+		field := strings.TrimLeftFunc(rest, unicode.IsSpace)
+		sep := rest[:len(rest)-len(field)]
+		if end := strings.IndexFunc(field, unicode.IsSpace); end >= 0 {
+			rest = field[end:]
+			field = field[:end]
+		} else {
+			rest = ""
+		}
+		if field == "" {
+			break
+		}
+		runes := visibleRunes(field)
+		// Words are separated by spaces, except a line break between two
+		// characters of text without spaces.
+		space := len(tokens) > 0
+		if space && len(runes) > 0 && isCJKBreakable(prev) && isCJKBreakable(runes[0].r) && strings.Trim(sep, "\r\n") == "" {
+			space = false
+		}
+		start := 0
+		for j, r := range runes {
+			if j > 0 && canBreakBetween(runes[j-1].r, r.r) {
+				tokens = append(tokens, token{text: field[start:r.at], space: space})
+				start, space = r.at, false
+			}
+		}
+		tokens = append(tokens, token{text: field[start:], space: space})
+		if len(runes) > 0 {
+			prev = runes[len(runes)-1].r
+		}
+	}
+	return tokens
+}
+
+// visibleRune is a rune of a field outside its inline tags, at byte offset at.
+// The offset of the first rune of a run after a tag is that of the tag, so a
+// break keeps the tag with the text it formats.
+type visibleRune struct {
+	r  rune
+	at int
+}
+
+func visibleRunes(field string) []visibleRune {
+	var runes []visibleRune
+	tagStart := -1
+	for i := 0; i < len(field); {
+		if loc := inlineTagAt(field[i:]); loc > 0 {
+			if tagStart < 0 {
+				tagStart = i
+			}
+			i += loc
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(field[i:])
+		at := i
+		if tagStart >= 0 && !strings.HasPrefix(field[tagStart:], "</") {
+			at = tagStart // an opening tag goes with the text after it
+		}
+		runes = append(runes, visibleRune{r: r, at: at})
+		tagStart = -1
+		i += size
+	}
+	return runes
+}
+
+// inlineTagAt returns the length of the inline tag (<i>, </i>, {\an8}) at the
+// start of s, or 0.
+func inlineTagAt(s string) int {
+	var end int
+	switch {
+	case strings.HasPrefix(s, `{\`):
+		end = strings.IndexByte(s, '}')
+	case strings.HasPrefix(s, "<") && len(s) > 1 && (s[1] == '/' || unicode.IsLetter(rune(s[1]))):
+		end = strings.IndexByte(s, '>')
+	default:
+		return 0
+	}
+	return end + 1
+}
+
+// canBreakBetween reports whether a line can be broken between a and b,
+// which are not separated by a space.
+func canBreakBetween(a, b rune) bool {
+	if !isCJKBreakable(a) && !isCJKBreakable(b) {
+		return false
+	}
+	return !strings.ContainsRune(noLineStart, b) && !strings.ContainsRune(noLineEnd, a)
+}
+
+// joinTokens joins tokens into a single line.
+func joinTokens(tokens []token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.space {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// tokensWidth is the width of tokens joined into a line.
+func tokensWidth(tokens []token) int {
+	width := 0
+	for i, t := range tokens {
+		if i > 0 && t.space {
+			width++
+		}
+		width += srt.DisplayWidth(t.text)
+	}
+	return width
+}
+
+// joinLines joins two lines with a space, or without one between Chinese
+// or Japanese text.
+func joinLines(a, b string) string {
+	last, _ := utf8.DecodeLastRuneInString(srt.VisibleText(a))
+	first, _ := utf8.DecodeRuneInString(srt.VisibleText(b))
+	if isCJKBreakable(last) && isCJKBreakable(first) {
+		return a + b
+	}
+	return a + " " + b
+}
+
+// WrapLine breaks line into lines of at most maxLen columns (see
+// srt.DisplayWidth), at spaces or between the characters of Chinese and
+// Japanese text. A word longer than maxLen is kept whole.
+func WrapLine(line string, maxLen int) []string {
+	var lines []string
+	var cur []token
+	for _, t := range tokenize(line) {
+		if len(cur) > 0 && tokensWidth(append(cur, t)) > maxLen {
+			lines = append(lines, joinTokens(cur))
+			t.space = false
+			cur = nil
+		}
+		cur = append(cur, t)
+	}
+	if len(cur) > 0 {
+		lines = append(lines, joinTokens(cur))
+	}
+	return lines
+}
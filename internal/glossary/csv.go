@@ -0,0 +1,30 @@
+package glossary
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// DefaultMinCount is the default number of occurrences for a phrase to be a
+// term.
+const DefaultMinCount = 2
+
+// csvHeader is the header of the CSV glossary. The translation column is left
+// empty, to be filled in before using it with translate --glossary-file.
+var csvHeader = []string{"term", "translation", "count"}
+
+// WriteCSV writes terms as a CSV glossary.
+func WriteCSV(w io.Writer, terms []Term) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, t := range terms {
+		if err := cw.Write([]string{t.Text, "", strconv.Itoa(t.Count)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,189 @@
+// Package glossary finds the recurring proper nouns and terms of subtitle
+// text (character names, places, organizations...), whose translation should
+// stay consistent. It is a small offline heuristic based on capitalization
+// and frequency.
+package glossary
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// maxPhraseWords is the longest phrase, in capitalized words.
+const maxPhraseWords = 4
+
+// connectors are the lowercase words allowed between the capitalized words of
+// a phrase (e.g. "Bank of America", "Trono de Hierro").
+var connectors = map[string]bool{
+	"of": true, "the": true, "de": true, "del": true, "la": true, "le": true, "les": true, "da": true,
+	"di": true, "do": true, "dos": true, "das": true, "du": true, "von": true, "van": true, "der": true,
+	"den": true, "al": true, "el": true,
+}
+
+// titles are the abbreviations left out of phrases; their period doesn't end
+// a sentence.
+var titles = map[string]bool{"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "st.": true, "jr.": true, "sr.": true}
+
+// Phrase is a run of capitalized words.
+type Phrase struct {
+	Text string
+	// SentenceStart is set when the phrase starts a sentence, where the
+	// capital of its first word may just be the sentence's.
+	SentenceStart bool
+}
+
+// Tail returns the phrase without its first word when it starts a sentence,
+// where that word may only be capitalized as the sentence's ("Tell Jesse",
+// "The Iron Throne"); empty otherwise.
+func (p Phrase) Tail() string {
+	if !p.SentenceStart {
+		return ""
+	}
+	words := strings.Fields(p.Text)
+	i := 1
+	for i < len(words) && connectors[words[i]] {
+		i++
+	}
+	return strings.Join(words[min(i, len(words)):], " ")
+}
+
+// Phrases returns the capitalized phrases of text, ignoring inline tags.
+func Phrases(text string) []Phrase {
+	var out []Phrase
+	var run []string
+	runStart := false
+	flush := func() {
+		// Trailing connectors are not part of the phrase.
+		for len(run) > 0 && connectors[strings.ToLower(run[len(run)-1])] {
+			run = run[:len(run)-1]
+		}
+		if len(run) > 0 {
+			out = append(out, Phrase{Text: strings.Join(run, " "), SentenceStart: runStart})
+		}
+		run = nil
+	}
+
+	sentenceStart := true
+	for raw := range strings.FieldsSeq(srt.VisibleText(text)) {
+		word := strings.TrimFunc(raw, isWordTrim)
+		if titles[strings.ToLower(raw)] {
+			// Left out: "Mr. White" gives "White".
+			flush()
+			sentenceStart = false
+			continue
+		}
+		if word == "" {
+			flush()
+			if strings.ContainsAny(raw, "-♪") {
+				sentenceStart = true
+			}
+			continue
+		}
+		// Punctuation before the word ("-Walter", "«Walter") breaks the phrase
+		// too; a dialogue dash starts a sentence.
+		if lead := raw[:strings.Index(raw, word)]; lead != "" {
+			flush()
+			if strings.ContainsAny(lead, "-—¿¡") {
+				sentenceStart = true
+			}
+		}
+		switch {
+		case isCapitalized(word):
+			if countCapitalized(run) == maxPhraseWords {
+				flush()
+			}
+			if len(run) == 0 {
+				runStart = sentenceStart
+			}
+			run = append(run, word)
+		case len(run) > 0 && connectors[word]:
+			run = append(run, word)
+		default:
+			flush()
+		}
+		// Punctuation after the word ends the phrase.
+		if endsWithPunct(raw) {
+			flush()
+		}
+		sentenceStart = endsSentence(raw)
+	}
+	flush()
+	return out
+}
+
+func isWordTrim(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func endsWithPunct(raw string) bool {
+	r, _ := utf8.DecodeLastRuneInString(raw)
+	return isWordTrim(r)
+}
+
+func endsSentence(raw string) bool {
+	raw = strings.TrimRight(raw, `"'”’)»`)
+	return strings.HasSuffix(raw, ".") || strings.HasSuffix(raw, "!") || strings.HasSuffix(raw, "?") ||
+		strings.HasSuffix(raw, "…") || strings.HasSuffix(raw, ":")
+}
+
+// isCapitalized reports whether word starts with an uppercase letter and is
+// not the pronoun "I" (or a contraction such as "I'm").
+func isCapitalized(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	if !unicode.IsUpper(r) {
+		return false
+	}
+	return word != "I" && !strings.HasPrefix(word, "I'") && !strings.HasPrefix(word, "I’")
+}
+
+func countCapitalized(words []string) int {
+	n := 0
+	for _, w := range words {
+		if !connectors[w] {
+			n++
+		}
+	}
+	return n
+}
+
+// Term is a recurring proper noun or term.
+type Term struct {
+	Text  string
+	Count int // occurrences
+}
+
+// Extract returns the phrases seen at least minCount times in texts, most
+// frequent first. Phrases only seen at the start of a sentence are left out,
+// as their capital may just be the sentence's.
+func Extract(texts []string, minCount int) []Term {
+	counts := make(map[string]int)
+	midSentence := make(map[string]bool)
+	for _, text := range texts {
+		for _, p := range Phrases(text) {
+			counts[p.Text]++
+			if !p.SentenceStart {
+				midSentence[p.Text] = true
+				continue
+			}
+			if rest := p.Tail(); rest != "" {
+				counts[rest]++
+				midSentence[rest] = true
+			}
+		}
+	}
+	var terms []Term
+	for text, n := range counts {
+		if n >= max(1, minCount) && midSentence[text] && utf8.RuneCountInString(text) > 1 {
+			terms = append(terms, Term{Text: text, Count: n})
+		}
+	}
+	slices.SortFunc(terms, func(a, b Term) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Text, b.Text))
+	})
+	return terms
+}
@@ -0,0 +1,47 @@
+package glossary
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPhrases(t *testing.T) {
+	got := Phrases("- Walter, the Bank of America called.\n<i>- I'm sure Mr. White knows.</i>")
+	want := []Phrase{
+		{Text: "Walter", SentenceStart: true},
+		{Text: "Bank of America"},
+		{Text: "White"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Phrases()=%+v, want %+v", got, want)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	texts := []string{
+		"Where is Jesse?",
+		"Jesse, wait!",
+		"Tell Jesse I'm coming.",
+		"We sit on the Iron Throne.",
+		"The Iron Throne is mine.",
+		"Yes. Yes, sir.",
+		"Once upon a time.",
+	}
+	got := Extract(texts, 2)
+	want := []Term{{Text: "Jesse", Count: 3}, {Text: "Iron Throne", Count: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Extract()=%+v, want %+v", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b strings.Builder
+	if err := WriteCSV(&b, []Term{{Text: "Jesse", Count: 3}, {Text: "Bank of America, Inc", Count: 2}}); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want := "term,translation,count\nJesse,,3\n\"Bank of America, Inc\",,2\n"
+	if b.String() != want {
+		t.Fatalf("WriteCSV()=%q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,80 @@
+// Package httpclient builds the HTTP transport shared by the commands that talk
+// to remote APIs (translate providers, GitHub releases), so proxy and TLS
+// settings apply everywhere.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type Options struct {
+	// ProxyURL routes every request through this proxy (http, https or socks5).
+	// Empty honors the HTTPS_PROXY/HTTP_PROXY/NO_PROXY environment variables.
+	ProxyURL string
+	// CACertFile is a PEM bundle trusted in addition to the system roots.
+	CACertFile string
+	// InsecureSkipVerify disables TLS certificate verification.
+	InsecureSkipVerify bool
+}
+
+// NewTransport returns a transport based on http.DefaultTransport with the
+// proxy and TLS settings of opts.
+func NewTransport(opts Options) (*http.Transport, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = http.ProxyFromEnvironment
+
+	if p := strings.TrimSpace(opts.ProxyURL); p != "" {
+		u, err := url.Parse(p)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q", p)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q (supported: http, https, socks5)", u.Scheme)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	if opts.CACertFile == "" && !opts.InsecureSkipVerify {
+		return tr, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CACertFile != "" {
+		pool, err := certPool(opts.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if opts.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	tr.TLSClientConfig = tlsConfig
+	return tr, nil
+}
+
+// certPool returns the system roots plus the certificates in path.
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca cert file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("ca cert file " + path + " contains no PEM certificates")
+	}
+	return pool, nil
+}
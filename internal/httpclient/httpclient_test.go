@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTransport_ProxyURL(t *testing.T) {
+	tr, err := NewTransport(Options{ProxyURL: "http://proxy.local:3128"})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.openai.com/v1/models", nil)
+	u, err := tr.Proxy(req)
+	if err != nil {
+		t.Fatalf("Proxy: %v", err)
+	}
+	if u == nil || u.Host != "proxy.local:3128" {
+		t.Fatalf("unexpected proxy: %v", u)
+	}
+}
+
+func TestNewTransport_InvalidProxy(t *testing.T) {
+	for _, p := range []string{"proxy.local:3128", "ftp://proxy.local", "://"} {
+		if _, err := NewTransport(Options{ProxyURL: p}); err == nil {
+			t.Fatalf("expected error for proxy %q", p)
+		}
+	}
+}
+
+func TestNewTransport_CACertFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// Without the server certificate the request fails verification.
+	tr, err := NewTransport(Options{})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	if _, err := (&http.Client{Transport: tr}).Get(srv.URL); err == nil {
+		t.Fatalf("expected certificate error")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o644); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	tr, err = NewTransport(Options{CACertFile: caFile})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get with ca cert: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+}
+
+func TestNewTransport_CACertFileWithoutCertificates(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	if _, err := NewTransport(Options{CACertFile: caFile}); err == nil {
+		t.Fatalf("expected error for file without certificates")
+	}
+}
+
+func TestNewTransport_InsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport(Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	_ = resp.Body.Close()
+}
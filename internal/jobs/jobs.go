@@ -0,0 +1,447 @@
+// Package jobs keeps a queue of fix and translate runs on disk, so long
+// translations can be queued, followed, canceled and resumed across process
+// restarts.
+//
+// Every job is a directory of the store holding its state (job.json) and,
+// once canceled, a cancel marker. A single worker at a time, holding the
+// worker lock of the store, runs the queued jobs in order.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State is the stage of a job.
+type State string
+
+const (
+	StateQueued   State = "queued"
+	StateRunning  State = "running"
+	StatePartial  State = "partial" // some files of a batch failed
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+	StateCanceled State = "canceled"
+)
+
+// Final reports whether a job in state s won't run again unless resumed.
+func (s State) Final() bool {
+	return s != StateQueued && s != StateRunning
+}
+
+// StaleAfter is the age after which the worker lock of a worker that stopped
+// refreshing it is ignored.
+const StaleAfter = 30 * time.Second
+
+const (
+	jobFile    = "job.json"
+	cancelFile = "cancel"
+	lockFile   = "worker.lock"
+)
+
+var (
+	// ErrNotFound is returned for an unknown job ID.
+	ErrNotFound = errors.New("job not found")
+	// ErrWorkerRunning is returned by LockWorker when another worker holds the
+	// lock of the store.
+	ErrWorkerRunning = errors.New("another worker is running")
+)
+
+// Progress is the last progress reported by a task of a job (e.g. the batches
+// of "translate es").
+type Progress struct {
+	Task  string `json:"task"`
+	Unit  string `json:"unit,omitempty"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// Job is a queued run of a command.
+type Job struct {
+	ID      string   `json:"id"`
+	Command string   `json:"command"` // fix or translate
+	Args    []string `json:"args"`
+	// Dir is the working directory the job was added from; relative paths
+	// of Args are resolved from it.
+	Dir        string     `json:"dir"`
+	State      State      `json:"state"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Attempts counts the times the job started running.
+	Attempts   int        `json:"attempts"`
+	Error      string     `json:"error,omitempty"`
+	ExitReason string     `json:"exit_reason,omitempty"`
+	Progress   []Progress `json:"progress,omitempty"`
+	// Result is the --json document of the last run.
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// SetProgress records p, replacing the previous progress of its task.
+func (j *Job) SetProgress(p Progress) {
+	for i := range j.Progress {
+		if j.Progress[i].Task == p.Task {
+			j.Progress[i] = p
+			return
+		}
+	}
+	j.Progress = append(j.Progress, p)
+}
+
+// Store is a directory of jobs.
+type Store struct {
+	dir string
+	now func() time.Time
+}
+
+// DefaultDir returns the default store directory under the user cache dir
+// (e.g. ~/.cache/subtitle-tools/jobs).
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "subtitle-tools", "jobs"), nil
+}
+
+// Open opens the store at dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create jobs dir: %w", err)
+	}
+	return &Store{dir: dir, now: time.Now}, nil
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Add queues a new job running command with args from dir.
+func (s *Store) Add(command string, args []string, dir string) (Job, error) {
+	id, err := s.newID()
+	if err != nil {
+		return Job{}, err
+	}
+	now := s.now().UTC()
+	job := Job{ID: id, Command: command, Args: args, Dir: dir, State: StateQueued, CreatedAt: now, UpdatedAt: now}
+	// The arguments may hold API keys: keep them private to the user.
+	if err := os.Mkdir(filepath.Join(s.dir, id), 0o700); err != nil {
+		return Job{}, fmt.Errorf("create job dir: %w", err)
+	}
+	return job, s.Save(&job)
+}
+
+// newID returns a job ID sorting by creation time.
+func (s *Store) newID() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return s.now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b), nil
+}
+
+// Get returns the job with the given ID, or with the only ID starting with it.
+func (s *Store) Get(id string) (Job, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return Job{}, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	job, err := s.read(id)
+	if !errors.Is(err, ErrNotFound) {
+		return job, err
+	}
+	jobs, err := s.List()
+	if err != nil {
+		return Job{}, err
+	}
+	var matches []Job
+	for _, j := range jobs {
+		if strings.HasPrefix(j.ID, id) {
+			matches = append(matches, j)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return Job{}, fmt.Errorf("job ID %s is ambiguous (%d jobs match)", id, len(matches))
+	}
+}
+
+func (s *Store) read(id string) (Job, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, id, jobFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	var job Job
+	if err := json.Unmarshal(b, &job); err != nil {
+		return Job{}, fmt.Errorf("job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// List returns every job, oldest first.
+func (s *Store) List() ([]Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Job
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		job, err := s.read(e.Name())
+		if errors.Is(err, ErrNotFound) {
+			continue // being added or removed
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return jobs, nil
+}
+
+// Save writes job, updating its UpdatedAt. The file is replaced atomically so
+// readers never see a partial document.
+func (s *Store) Save(job *Job) error {
+	job.UpdatedAt = s.now().UTC()
+	b, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(s.dir, job.ID)
+	f, err := os.CreateTemp(dir, ".job-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, jobFile)); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Next returns the oldest queued job; ok is false when there is none.
+func (s *Store) Next() (job Job, ok bool, err error) {
+	jobs, err := s.List()
+	if err != nil {
+		return Job{}, false, err
+	}
+	for _, j := range jobs {
+		if j.State == StateQueued {
+			return j, true, nil
+		}
+	}
+	return Job{}, false, nil
+}
+
+// Start marks job as running, clearing the outcome of its previous run.
+func (s *Store) Start(job *Job) error {
+	now := s.now().UTC()
+	job.State = StateRunning
+	job.StartedAt = &now
+	job.FinishedAt = nil
+	job.Attempts++
+	job.Error, job.ExitReason = "", ""
+	job.Progress, job.Result = nil, nil
+	return s.Save(job)
+}
+
+// Finish records the final state of job.
+func (s *Store) Finish(job *Job, state State, errMsg, exitReason string) error {
+	now := s.now().UTC()
+	job.State = state
+	job.FinishedAt = &now
+	job.Error, job.ExitReason = errMsg, exitReason
+	return s.Save(job)
+}
+
+// Cancel cancels a queued or running job. A queued job, or one left running
+// by a worker that stopped, is canceled at once; a running one when its worker
+// sees the cancel marker.
+func (s *Store) Cancel(id string) (Job, error) {
+	job, err := s.Get(id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.State.Final() {
+		return job, fmt.Errorf("job %s is already %s", job.ID, job.State)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, job.ID, cancelFile), nil, 0o644); err != nil {
+		return job, err
+	}
+	if job.State == StateQueued || !s.WorkerAlive() {
+		return job, s.Finish(&job, StateCanceled, "", "")
+	}
+	return job, nil
+}
+
+// Canceled reports whether the job with the given ID was canceled.
+func (s *Store) Canceled(id string) bool {
+	_, err := os.Stat(filepath.Join(s.dir, id, cancelFile))
+	return err == nil
+}
+
+// Resume queues again a failed, partial or canceled job, or a running one left
+// behind by a worker that stopped.
+func (s *Store) Resume(id string) (Job, error) {
+	job, err := s.Get(id)
+	if err != nil {
+		return Job{}, err
+	}
+	switch job.State {
+	case StateQueued:
+		return job, fmt.Errorf("job %s is already queued", job.ID)
+	case StateDone:
+		return job, fmt.Errorf("job %s is done", job.ID)
+	case StateRunning:
+		if s.WorkerAlive() {
+			return job, fmt.Errorf("job %s is running", job.ID)
+		}
+	}
+	if err := os.Remove(filepath.Join(s.dir, job.ID, cancelFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return job, err
+	}
+	job.State = StateQueued
+	job.FinishedAt = nil
+	job.Error, job.ExitReason = "", ""
+	return job, s.Save(&job)
+}
+
+// Requeue queues again the running jobs, left behind by a worker that stopped
+// (e.g. killed), or cancels them if that was requested; call it while holding
+// the worker lock. It returns the requeued jobs.
+func (s *Store) Requeue() ([]Job, error) {
+	jobs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var requeued []Job
+	for _, job := range jobs {
+		if job.State != StateRunning {
+			continue
+		}
+		if s.Canceled(job.ID) {
+			if err := s.Finish(&job, StateCanceled, "", ""); err != nil {
+				return requeued, err
+			}
+			continue
+		}
+		job.State = StateQueued
+		if err := s.Save(&job); err != nil {
+			return requeued, err
+		}
+		requeued = append(requeued, job)
+	}
+	return requeued, nil
+}
+
+// Remove deletes a job that isn't running.
+func (s *Store) Remove(id string) error {
+	job, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if job.State == StateRunning && s.WorkerAlive() {
+		return fmt.Errorf("job %s is running; cancel it first", job.ID)
+	}
+	return os.RemoveAll(filepath.Join(s.dir, job.ID))
+}
+
+// WorkerLock is the lock of the worker of a store. Refresh it more often than
+// StaleAfter, or other processes take the worker for stopped.
+type WorkerLock struct {
+	path string
+}
+
+// LockWorker takes the worker lock of the store, replacing a stale one.
+func (s *Store) LockWorker() (*WorkerLock, error) {
+	path := filepath.Join(s.dir, lockFile)
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, werr := f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				_ = os.Remove(path)
+				return nil, werr
+			}
+			return &WorkerLock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if attempt > 0 || s.WorkerAlive() {
+			pid, _ := os.ReadFile(path)
+			return nil, fmt.Errorf("%w (pid %s, lock %s)", ErrWorkerRunning, strings.TrimSpace(string(pid)), path)
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// WorkerAlive reports whether a worker holds the lock of the store: its
+// process is running and it refreshed the lock within StaleAfter.
+func (s *Store) WorkerAlive() bool {
+	path := filepath.Join(s.dir, lockFile)
+	st, err := os.Stat(path)
+	if err != nil || s.now().Sub(st.ModTime()) >= StaleAfter {
+		return false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return true // being written
+	}
+	return pid == os.Getpid() || processAlive(pid)
+}
+
+// Refresh tells other processes that the worker is still running.
+func (l *WorkerLock) Refresh() error {
+	now := time.Now()
+	return os.Chtimes(l.path, now, now)
+}
+
+// Release frees the lock.
+func (l *WorkerLock) Release() error {
+	return os.Remove(l.path)
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_Lifecycle(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Add("translate", []string{"-t", "es", "movie.srt"}, "/videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Add("fix", []string{"movie.srt"}, "/videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, ok, err := s.Next()
+	if err != nil || !ok || next.ID != first.ID {
+		t.Fatalf("Next() = %q, %v, %v; want %q", next.ID, ok, err, first.ID)
+	}
+	if err := s.Start(&next); err != nil {
+		t.Fatal(err)
+	}
+	next.SetProgress(Progress{Task: "translate es", Unit: "batches", Done: 2, Total: 5})
+	next.SetProgress(Progress{Task: "translate es", Unit: "batches", Done: 3, Total: 5})
+	if err := s.Save(&next); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh store, like a new process, reads what was saved.
+	reopened, err := Open(s.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.Get(first.ID[:len(first.ID)-2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != first.ID {
+		t.Fatalf("Get(prefix) = %q, want %q", got.ID, first.ID)
+	}
+	if got.State != StateRunning || got.Attempts != 1 || len(got.Progress) != 1 || got.Progress[0].Done != 3 {
+		t.Fatalf("reloaded job = %+v", got)
+	}
+
+	if _, err := reopened.Cancel(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.Canceled(first.ID) {
+		t.Fatal("Canceled() = false after Cancel")
+	}
+	if err := reopened.Finish(&got, StateCanceled, "context canceled", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Cancel(first.ID); err == nil {
+		t.Fatal("Cancel() of a canceled job: want error")
+	}
+
+	resumed, err := reopened.Resume(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.State != StateQueued || resumed.Error != "" || reopened.Canceled(first.ID) {
+		t.Fatalf("resumed job = %+v, canceled = %v", resumed, reopened.Canceled(first.ID))
+	}
+
+	// The queued second job is canceled at once.
+	canceled, err := reopened.Cancel(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.State != StateCanceled {
+		t.Fatalf("state = %s, want %s", canceled.State, StateCanceled)
+	}
+
+	if err := reopened.Remove(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get(second.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after Remove = %v, want ErrNotFound", err)
+	}
+	jobs, err := reopened.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != first.ID {
+		t.Fatalf("List() = %+v", jobs)
+	}
+}
+
+func TestStore_RequeueRunningJobs(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.Add("translate", []string{"movie.srt"}, "/videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(&job); err != nil {
+		t.Fatal(err)
+	}
+
+	requeued, err := s.Requeue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requeued) != 1 {
+		t.Fatalf("requeued %d jobs, want 1", len(requeued))
+	}
+	next, ok, err := s.Next()
+	if err != nil || !ok || next.ID != job.ID || next.Attempts != 1 {
+		t.Fatalf("Next() = %+v, %v, %v", next, ok, err)
+	}
+}
+
+func TestStore_WorkerLock(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := s.LockWorker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.WorkerAlive() {
+		t.Fatal("WorkerAlive() = false while locked")
+	}
+	if _, err := s.LockWorker(); !errors.Is(err, ErrWorkerRunning) {
+		t.Fatalf("second LockWorker() = %v, want ErrWorkerRunning", err)
+	}
+
+	// A lock that wasn't refreshed is taken over.
+	old := time.Now().Add(-2 * StaleAfter)
+	if err := os.Chtimes(filepath.Join(s.Dir(), lockFile), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if s.WorkerAlive() {
+		t.Fatal("WorkerAlive() = true with a stale lock")
+	}
+	lock, err = s.LockWorker()
+	if err != nil {
+		t.Fatalf("LockWorker() over a stale lock: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if s.WorkerAlive() {
+		t.Fatal("WorkerAlive() = true after Release")
+	}
+}
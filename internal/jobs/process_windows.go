@@ -0,0 +1,15 @@
+//go:build windows
+
+package jobs
+
+import "os"
+
+// processAlive reports whether the process with the given PID is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
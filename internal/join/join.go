@@ -0,0 +1,172 @@
+// Package join concatenates subtitle files of a release in several parts
+// (CD1/CD2) into one, shifting every part to where it starts in the whole.
+package join
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/adrianmusante/subtitle-tools/internal/validate"
+)
+
+// checkedRules are the rules the joined cues are validated against: those a
+// wrong offset breaks. The others are about the text, as it was in the parts.
+var checkedRules = []string{validate.RuleNegativeDuration, validate.RuleOutOfOrder, validate.RuleOverlap}
+
+type Options struct {
+	InputPaths []string
+	OutputPath string
+	// Offsets are where the inputs start in the output, by position in
+	// InputPaths. An input without one starts where the previous ends: after
+	// its duration in Durations or, without it, its last cue.
+	Offsets   map[int]time.Duration
+	Durations []time.Duration // durations of the parts (e.g. of their videos); optional
+	DryRun    bool
+	WorkDir   string
+}
+
+// Part is an input of the output.
+type Part struct {
+	Path   string        `json:"path"`
+	Offset time.Duration `json:"offset"`
+	Cues   int           `json:"cues"`
+}
+
+type Result struct {
+	WrittenPath string
+	Parts       []Part
+	Cues        int
+	// Violations are the timing problems of the output (see checkedRules).
+	Violations []validate.Violation
+}
+
+// Run reads opts.InputPaths and writes them joined to opts.OutputPath (to
+// opts.WorkDir in dry-run).
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if len(opts.InputPaths) < 2 {
+		return Result{}, errors.New("at least two files are needed to join")
+	}
+	if len(opts.Durations) > 0 && len(opts.Durations) != len(opts.InputPaths) {
+		return Result{}, fmt.Errorf("got %d durations for %d files", len(opts.Durations), len(opts.InputPaths))
+	}
+	docs := make([]*srt.Document, len(opts.InputPaths))
+	for i, path := range opts.InputPaths {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		if fs.SameFilePath(path, opts.OutputPath) {
+			return Result{}, fmt.Errorf("output would overwrite the input %s", path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return Result{}, err
+		}
+		doc, err := srt.Read(bytes.NewReader(b))
+		if err != nil {
+			return Result{}, fmt.Errorf("parse %s: %w", path, err)
+		}
+		for _, w := range doc.Warnings {
+			slog.Warn("subtitles file read with a warning", "input_path", path, "warning", w)
+		}
+		srt.Sort(doc.Cues)
+		docs[i] = doc
+	}
+
+	offsets, err := Offsets(docs, opts.Offsets, opts.Durations)
+	if err != nil {
+		return Result{}, err
+	}
+	out, err := Join(docs, offsets)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var unchecked []string
+	for _, rule := range validate.Rules {
+		if !slices.Contains(checkedRules, rule) {
+			unchecked = append(unchecked, rule)
+		}
+	}
+	res := Result{Cues: len(out.Cues), Violations: validate.Check(out.Cues, validate.Options{Disabled: unchecked})}
+	for i, path := range opts.InputPaths {
+		res.Parts = append(res.Parts, Part{Path: path, Offset: offsets[i], Cues: len(docs[i].Cues)})
+	}
+
+	outputPath := opts.OutputPath
+	if opts.DryRun {
+		outputPath = run.NewTempNamer(opts.WorkDir, opts.OutputPath).Step("join")
+	}
+	var buf bytes.Buffer
+	if err := srt.Write(&buf, out, false); err != nil {
+		return Result{}, err
+	}
+	if err := fs.WriteFile(&buf, outputPath); err != nil {
+		return Result{}, err
+	}
+	res.WrittenPath = outputPath
+	return res, nil
+}
+
+// Offsets returns where every doc starts in the output: the offset set for
+// it, or where the previous doc ends (after its duration, when given, or its
+// last cue).
+func Offsets(docs []*srt.Document, set map[int]time.Duration, durations []time.Duration) ([]time.Duration, error) {
+	offsets := make([]time.Duration, len(docs))
+	for i := range docs {
+		if at, ok := set[i]; ok {
+			if at < 0 {
+				return nil, fmt.Errorf("offset of file %d must not be negative, got %s", i+1, at)
+			}
+			offsets[i] = at
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		var end time.Duration
+		if len(durations) > 0 {
+			end = durations[i-1]
+		} else if prev := docs[i-1].Cues; len(prev) > 0 {
+			end = prev[len(prev)-1].ToTime
+		}
+		offsets[i] = offsets[i-1] + end
+	}
+	return offsets, nil
+}
+
+// Join concatenates docs, shifting the cues of each by its offset. The
+// output keeps the header of the first doc and the trailer of the last one,
+// and is numbered from 1. A doc that starts before the previous one ends is
+// an error, as its offset is wrong.
+func Join(docs []*srt.Document, offsets []time.Duration) (*srt.Document, error) {
+	out := docs[0].WithCues(nil)
+	out.Trailer = docs[len(docs)-1].Trailer
+	for i, doc := range docs {
+		if len(doc.Cues) == 0 {
+			continue
+		}
+		if n := len(out.Cues); n > 0 {
+			if start, prevEnd := doc.Cues[0].FromTime+offsets[i], out.Cues[n-1].ToTime; start < prevEnd {
+				return nil, fmt.Errorf("file %d starts at %s, before the previous file ends (%s); check its offset",
+					i+1, srt.FormatTime(start), srt.FormatTime(prevEnd))
+			}
+		}
+		for _, sub := range doc.Cues {
+			cue := *sub
+			cue.FromTime += offsets[i]
+			cue.ToTime += offsets[i]
+			out.Cues = append(out.Cues, &cue)
+		}
+	}
+	srt.Reindex(out.Cues)
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package join
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeParts(t *testing.T, contents ...string) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, c := range contents {
+		p := filepath.Join(dir, fmt.Sprintf("part%d.srt", i+1))
+		if err := os.WriteFile(p, []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return dir, paths
+}
+
+func TestRun(t *testing.T) {
+	dir, inputs := writeParts(t,
+		"1\n00:00:01,000 --> 00:00:02,000\nFirst\n\n2\n00:40:00,000 --> 00:40:02,000\nEnd of CD1\n\n",
+		"1\n00:00:05,000 --> 00:00:06,000\nStart of CD2\n\n",
+		"1\n00:00:01,000 --> 00:00:02,000\nExtra\n\n",
+	)
+	output := filepath.Join(dir, "full.srt")
+
+	res, err := Run(context.Background(), Options{
+		InputPaths: inputs,
+		OutputPath: output,
+		Offsets:    map[int]time.Duration{1: 45 * time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// The third file starts where the second one ends.
+	if res.Cues != 4 || res.Parts[1].Offset != 45*time.Minute || res.Parts[2].Offset != 45*time.Minute+6*time.Second || len(res.Violations) != 0 {
+		t.Fatalf("Run = %+v", res)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,000\nFirst\n\n" +
+		"2\n00:40:00,000 --> 00:40:02,000\nEnd of CD1\n\n" +
+		"3\n00:45:05,000 --> 00:45:06,000\nStart of CD2\n\n" +
+		"4\n00:45:07,000 --> 00:45:08,000\nExtra\n\n"
+	if string(b) != want {
+		t.Fatalf("output = %q, want %q", b, want)
+	}
+
+	res, err = Run(context.Background(), Options{
+		InputPaths: inputs[:2],
+		OutputPath: output,
+		Durations:  []time.Duration{50 * time.Minute, time.Hour},
+	})
+	if err != nil || res.Parts[1].Offset != 50*time.Minute {
+		t.Fatalf("Run with durations = %+v, %v", res, err)
+	}
+
+	_, err = Run(context.Background(), Options{InputPaths: inputs[:2], OutputPath: output, Offsets: map[int]time.Duration{1: 30 * time.Minute}})
+	if err == nil || !strings.Contains(err.Error(), "file 2 starts at 00:30:05,000") {
+		t.Fatalf("Run with an overlapping offset: %v", err)
+	}
+	if _, err := Run(context.Background(), Options{InputPaths: inputs[:2], OutputPath: inputs[0]}); err == nil {
+		t.Fatal("Run overwrote an input")
+	}
+}
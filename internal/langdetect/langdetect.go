@@ -0,0 +1,179 @@
+// Package langdetect guesses the language of subtitle text. It is a small
+// offline heuristic (writing system plus stopword frequency) meant for sanity
+// checks such as "is this file already in the target language?", not a
+// general-purpose classifier.
+package langdetect
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MinWords is the number of words needed before a Latin-script guess is made.
+const MinWords = 20
+
+// Result is a detected language.
+type Result struct {
+	Language   string  // ISO 639-1 code (e.g. "es")
+	Confidence float64 // 0..1
+}
+
+var markupPattern = regexp.MustCompile(`<[^<>]*>|\{[^{}]*\}`)
+
+// stopwords holds frequent short words that are distinctive per language.
+// Words shared by several languages (e.g. "a", "de", "la") are left out on
+// purpose so the counts stay discriminative.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "you", "that", "is", "it", "to", "of", "what", "this", "have", "was", "are", "with", "not", "for", "be", "we", "my", "your", "he", "she", "they", "do", "don't", "i'm", "it's", "just", "know", "can", "will", "there", "all", "get", "go", "me", "right", "here", "out", "if", "about", "so", "now", "want", "got", "oh", "yeah", "well", "how", "why"},
+	"es": {"el", "los", "las", "que", "qué", "y", "es", "está", "estás", "no", "por", "para", "con", "una", "uno", "pero", "como", "cómo", "más", "muy", "yo", "tú", "él", "ella", "nosotros", "bien", "sí", "también", "aquí", "ahora", "porque", "cuando", "dónde", "eso", "esto", "hay", "tengo", "tienes", "vamos", "ya", "nada", "señor", "gracias", "puedo", "quiero", "lo", "del", "al", "hola", "su"},
+	"pt": {"o", "os", "que", "não", "é", "está", "você", "eu", "com", "uma", "um", "mas", "como", "mais", "muito", "ele", "ela", "nós", "bem", "sim", "também", "aqui", "agora", "porque", "quando", "onde", "isso", "isto", "tem", "tenho", "vamos", "já", "nada", "senhor", "obrigado", "obrigada", "posso", "quero", "do", "da", "dos", "das", "no", "na", "ao", "seu", "sua", "então", "estou", "vai"},
+	"fr": {"le", "les", "que", "qui", "et", "est", "pas", "je", "tu", "il", "elle", "nous", "vous", "ils", "une", "un", "mais", "comme", "plus", "très", "bien", "oui", "non", "aussi", "ici", "maintenant", "parce", "quand", "où", "ça", "ce", "c'est", "j'ai", "avec", "pour", "dans", "sur", "au", "du", "des", "mon", "ton", "son", "suis", "êtes", "merci", "monsieur", "rien", "on", "veux"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "du", "er", "sie", "wir", "ihr", "ein", "eine", "aber", "wie", "mehr", "sehr", "gut", "ja", "nein", "auch", "hier", "jetzt", "weil", "wenn", "wo", "was", "mit", "für", "auf", "dem", "den", "zu", "mein", "dein", "bin", "sind", "habe", "hast", "danke", "nichts", "kann", "will", "noch", "schon", "mal", "doch", "uns", "es"},
+	"it": {"il", "che", "non", "è", "sono", "io", "tu", "lui", "lei", "noi", "voi", "loro", "una", "un", "ma", "come", "più", "molto", "bene", "sì", "anche", "qui", "adesso", "ora", "perché", "quando", "dove", "questo", "quello", "con", "per", "nel", "della", "del", "mio", "tuo", "suo", "ho", "hai", "grazie", "niente", "posso", "voglio", "gli", "ci", "cosa", "allora", "sei", "fare", "andiamo"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "ik", "je", "jij", "hij", "zij", "wij", "we", "maar", "hoe", "meer", "heel", "goed", "ja", "nee", "ook", "hier", "nu", "omdat", "als", "waar", "wat", "met", "voor", "op", "van", "mijn", "jouw", "ben", "bent", "zijn", "heb", "hebt", "dank", "niets", "kan", "wil", "nog", "al", "dat", "dit", "er", "naar", "moet", "weet"},
+	"ru": {"и", "в", "не", "что", "я", "ты", "он", "она", "мы", "вы", "они", "это", "как", "но", "да", "нет", "так", "все", "здесь", "сейчас", "потому", "когда", "где", "меня", "тебя", "его", "был", "была", "есть", "хорошо", "спасибо", "ничего", "могу", "хочу", "уже", "ещё", "еще", "вот", "тут", "только"},
+	"uk": {"і", "в", "не", "що", "я", "ти", "він", "вона", "ми", "ви", "вони", "це", "як", "але", "так", "ні", "все", "тут", "зараз", "тому", "коли", "де", "мене", "тебе", "його", "був", "була", "є", "добре", "дякую", "нічого", "можу", "хочу", "вже", "ще", "ось", "тільки"},
+}
+
+var stopwordIndex = func() map[string][]string {
+	idx := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			idx[w] = append(idx[w], lang)
+		}
+	}
+	return idx
+}()
+
+// scriptLanguages maps writing systems used by a single (main) language.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// Detect guesses the language of text. It returns false when the text is too
+// short or ambiguous.
+func Detect(text string) (Result, bool) {
+	text = markupPattern.ReplaceAllString(text, " ")
+
+	letters, counts := scriptCounts(text)
+	if letters == 0 {
+		return Result{}, false
+	}
+	// Japanese mixes kana with Han; Chinese is Han only.
+	if kana := counts["kana"]; float64(kana)/float64(letters) > 0.1 {
+		return Result{Language: "ja", Confidence: confidence(kana+counts["han"], letters)}, true
+	}
+	if han := counts["han"]; float64(han)/float64(letters) > 0.5 {
+		return Result{Language: "zh", Confidence: confidence(han, letters)}, true
+	}
+	for _, s := range scriptLanguages {
+		if n := counts[s.lang]; float64(n)/float64(letters) > 0.5 {
+			return Result{Language: s.lang, Confidence: confidence(n, letters)}, true
+		}
+	}
+	return detectByStopwords(text)
+}
+
+func scriptCounts(text string) (int, map[string]int) {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					counts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	return letters, counts
+}
+
+func detectByStopwords(text string) (Result, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < MinWords {
+		return Result{}, false
+	}
+	hits := make(map[string]int)
+	for _, w := range words {
+		for _, lang := range stopwordIndex[strings.Trim(w, "'")] {
+			hits[lang]++
+		}
+	}
+
+	best, second := "", 0
+	for lang, n := range hits {
+		switch {
+		case best == "" || n > hits[best] || (n == hits[best] && lang < best):
+			second = max(second, hits[best])
+			best = lang
+		case n > second:
+			second = n
+		}
+	}
+	if best == "" {
+		return Result{}, false
+	}
+	score := float64(hits[best]) / float64(len(words))
+	// Require a minimum share of stopwords and a clear lead over the runner-up
+	// (closely related languages share many short words).
+	if score < 0.15 || float64(hits[best]) < 1.3*float64(second) {
+		return Result{}, false
+	}
+	return Result{Language: best, Confidence: confidence(hits[best]-second, hits[best])}, true
+}
+
+func confidence(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// Matches reports whether a detected language code corresponds to a language
+// tag such as "es", "es-419" or "pt_BR" (only the primary subtag is compared).
+func Matches(detected string, tag string) bool {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	primary = strings.ToLower(primary)
+	if primary == detected {
+		return true
+	}
+	// Accept English names ("Spanish") as passed to chat models.
+	return primary != "" && strings.EqualFold(names[detected], primary)
+}
+
+var names = map[string]string{
+	"en": "English", "es": "Spanish", "pt": "Portuguese", "fr": "French", "de": "German",
+	"it": "Italian", "nl": "Dutch", "ru": "Russian", "uk": "Ukrainian", "ar": "Arabic",
+	"he": "Hebrew", "el": "Greek", "ko": "Korean", "th": "Thai", "hi": "Hindi",
+	"ja": "Japanese", "zh": "Chinese",
+}
+
+// Name returns the English name of a detected language code.
+func Name(code string) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return code
+}
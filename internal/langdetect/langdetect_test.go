@@ -0,0 +1,46 @@
+package langdetect
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "What are you doing here? I don't know what you want from me.\nWe have to go now, it's not safe. <i>Come on, get in the car!</i>", "en"},
+		{"spanish", "¿Qué estás haciendo aquí? No sé qué quieres de mí.\nTenemos que irnos ahora, no es seguro. <i>Vamos, sube al coche, por favor.</i>", "es"},
+		{"portuguese", "O que você está fazendo aqui? Eu não sei o que você quer de mim.\nTemos que ir agora, não é seguro. Vamos, entra no carro.", "pt"},
+		{"french", "Qu'est-ce que tu fais ici ? Je ne sais pas ce que tu veux de moi.\nNous devons partir maintenant, ce n'est pas sûr. Allez, monte dans la voiture.", "fr"},
+		{"german", "Was machst du hier? Ich weiß nicht, was du von mir willst.\nWir müssen jetzt gehen, es ist nicht sicher. Komm schon, steig ins Auto ein.", "de"},
+		{"russian", "Что ты здесь делаешь? Я не знаю, что ты хочешь от меня.\nНам надо идти сейчас, здесь не безопасно. Давай, садись в машину, это всё.", "ru"},
+		{"japanese", "ここで何をしているの？あなたが何を望んでいるのか分からない。", "ja"},
+		{"chinese", "你在这里做什么？我不知道你想要什么。", "zh"},
+		{"korean", "여기서 뭐 하는 거야? 네가 뭘 원하는지 모르겠어.", "ko"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Detect(tt.text)
+			if !ok || got.Language != tt.want {
+				t.Fatalf("Detect()=%+v ok=%v, want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect_TooShort(t *testing.T) {
+	if got, ok := Detect("Hello\nBye"); ok {
+		t.Fatalf("expected no guess for short text, got %+v", got)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	for _, tag := range []string{"es", "es-419", "es_AR", "Spanish", "ES"} {
+		if !Matches("es", tag) {
+			t.Fatalf("expected es to match %q", tag)
+		}
+	}
+	if Matches("es", "pt-BR") {
+		t.Fatalf("expected es not to match pt-BR")
+	}
+}
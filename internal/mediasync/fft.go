@@ -0,0 +1,74 @@
+package mediasync
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// fft transforms x in place (radix-2, len(x) a power of two); inverse runs
+// the inverse transform, scaled by 1/len(x).
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	shift := 64 - bits.Len(uint(n-1))
+	for i := range x {
+		if j := int(bits.Reverse64(uint64(i)) >> shift); j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range size / 2 {
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+	if inverse {
+		for i := range x {
+			x[i] /= complex(float64(n), 0)
+		}
+	}
+}
+
+// transform returns the FFT of signal zero-padded to n values.
+func transform(signal []float64, n int) []complex128 {
+	x := make([]complex128, n)
+	for i, v := range signal {
+		x[i] = complex(v, 0)
+	}
+	fft(x, false)
+	return x
+}
+
+// correlate returns, for every shift k in [-maxShift, maxShift], the sum of
+// ref[i+k]*signal[i], as corr[k+maxShift]. refFFT is transform(ref, n), n
+// being at least len(ref)+len(signal).
+func correlate(refFFT []complex128, signal []float64, maxShift int) []float64 {
+	n := len(refFFT)
+	x := transform(signal, n)
+	for i := range x {
+		x[i] = refFFT[i] * cmplx.Conj(x[i])
+	}
+	fft(x, true)
+	corr := make([]float64, 2*maxShift+1)
+	for k := -maxShift; k <= maxShift; k++ {
+		corr[k+maxShift] = real(x[(k+n)%n])
+	}
+	return corr
+}
+
+// fftSize returns the smallest power of two that is at least n.
+func fftSize(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
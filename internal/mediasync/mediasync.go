@@ -0,0 +1,304 @@
+// Package mediasync aligns the cues of a subtitle file to the speech of its
+// video, like ffsubsync: the audio is decoded with ffmpeg, the frames with
+// speech are detected, and the offset (and frame rate drift) that puts most
+// of the cue time over speech is applied.
+package mediasync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// framesPerSecond is the time resolution of the alignment.
+const (
+	framesPerSecond = 50
+	frameDuration   = time.Second / framesPerSecond
+)
+
+// DefaultMaxOffset is the largest offset looked for.
+const DefaultMaxOffset = time.Minute
+
+// ErrNoSpeech is returned when no speech is found in the audio.
+var ErrNoSpeech = errors.New("no speech found in the audio")
+
+// framerateRatios are the time scales tried to fix a drift: subtitles timed
+// for a release at another frame rate (23.976, 24 or 25 fps) drift linearly.
+var framerateRatios = []float64{1, 25 / 23.976, 23.976 / 25, 25 / 24.0, 24 / 25.0, 24 / 23.976, 23.976 / 24}
+
+type Options struct {
+	InputPath string
+	// MediaPath is the video (or audio) file to sync to, or a subtitle file
+	// (.srt) already in sync with it.
+	MediaPath  string
+	OutputPath string // empty means overwriting the input
+	AudioTrack int    // audio stream of the media, from 0
+	MaxOffset  time.Duration
+	// SkipFramerate only looks for an offset, without trying the frame rate
+	// ratios.
+	SkipFramerate bool
+	DryRun        bool
+	WorkDir       string
+	BackupExt     string
+	CreateBackup  bool
+}
+
+type Result struct {
+	WrittenPath string
+	// The cue times are multiplied by Scale (1 without drift) and then
+	// shifted by Offset.
+	Offset time.Duration
+	Scale  float64
+	// Score is the share of the cue time over speech after the sync, from 0
+	// to 1.
+	Score float64
+	// Dropped are the cues moved before the start of the media.
+	Dropped int
+}
+
+// Run syncs opts.InputPath to opts.MediaPath and writes the result.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if opts.WorkDir == "" {
+		return Result{}, errors.New("workdir is required (create one with run.NewWorkdir)")
+	}
+	b, err := os.ReadFile(opts.InputPath)
+	if err != nil {
+		return Result{}, err
+	}
+	doc, err := srt.Read(bytes.NewReader(b))
+	if err != nil {
+		return Result{}, fmt.Errorf("parse %s: %w", opts.InputPath, err)
+	}
+	for _, w := range doc.Warnings {
+		slog.Warn("subtitles file read with a warning", "input_path", opts.InputPath, "warning", w)
+	}
+	if len(doc.Cues) == 0 {
+		return Result{}, errors.New("no cues to sync")
+	}
+	srt.Sort(doc.Cues)
+
+	var speech []bool
+	if strings.EqualFold(filepath.Ext(opts.MediaPath), ".srt") {
+		speech, err = referenceActivity(opts.MediaPath)
+	} else {
+		speech, err = audioActivity(ctx, opts.MediaPath, opts.AudioTrack)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if !slices.Contains(speech, true) {
+		return Result{}, ErrNoSpeech
+	}
+
+	maxOffset := opts.MaxOffset
+	if maxOffset <= 0 {
+		maxOffset = DefaultMaxOffset
+	}
+	ratios := framerateRatios
+	if opts.SkipFramerate {
+		ratios = ratios[:1]
+	}
+	offset, scale, score := Align(speech, doc.Cues, maxOffset, ratios)
+	score = math.Round(score*1000) / 1000
+	slog.Debug("sync found", "offset", offset, "scale", scale, "score", score)
+
+	cues, dropped := Apply(doc.Cues, offset, scale)
+	if len(cues) == 0 {
+		return Result{}, errors.New("every cue would be moved before the start of the media")
+	}
+	res := Result{Offset: offset, Scale: scale, Score: score, Dropped: dropped}
+
+	namer := run.NewTempNamer(opts.WorkDir, opts.InputPath)
+	tmpPath := namer.Step("sync")
+	var buf bytes.Buffer
+	if err := srt.Write(&buf, doc.WithCues(cues), false); err != nil {
+		return Result{}, err
+	}
+	if err := fs.WriteFile(&buf, tmpPath); err != nil {
+		return Result{}, err
+	}
+
+	outputPath := opts.OutputPath
+	if opts.DryRun {
+		res.WrittenPath = tmpPath
+		return res, nil
+	}
+	if outputPath == "" {
+		outputPath = opts.InputPath
+	}
+	if opts.CreateBackup && fs.SameFilePath(outputPath, opts.InputPath) {
+		backupPath := opts.InputPath + opts.BackupExt
+		_ = os.Remove(backupPath)
+		if err := fs.MoveFile(opts.InputPath, backupPath); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := fs.MoveFile(tmpPath, outputPath); err != nil {
+		return Result{}, err
+	}
+	res.WrittenPath = outputPath
+	return res, nil
+}
+
+// Align returns the scale (one of ratios) and then offset, up to maxOffset
+// either way, that put most of the time of cues over speech (one value per
+// frame), and the share of the cue time over speech once applied.
+func Align(speech []bool, cues []*srt.Subtitle, maxOffset time.Duration, ratios []float64) (time.Duration, float64, float64) {
+	// Speech counts for the cues over it and silence against them.
+	ref := make([]float64, len(speech))
+	for i, s := range speech {
+		ref[i] = -1
+		if s {
+			ref[i] = 1
+		}
+	}
+	maxShift := int(maxOffset.Seconds() * framesPerSecond)
+	longest := 0
+	for _, r := range ratios {
+		longest = max(longest, len(cueActivity(cues, r)))
+	}
+	n := fftSize(len(ref) + longest + maxShift + 1)
+	refFFT := transform(ref, n)
+
+	bestShift, bestScale, bestScore := 0, 1.0, math.Inf(-1)
+	for _, r := range ratios {
+		signal := cueActivity(cues, r)
+		total := 0.0
+		for _, v := range signal {
+			total += v
+		}
+		for i, c := range correlate(refFFT, signal, maxShift) {
+			k, score := i-maxShift, c/total
+			// Of equal scores, keep the first ratio and the smaller shift.
+			if score > bestScore+1e-9 || (score > bestScore-1e-9 && r == bestScale && abs(k) < abs(bestShift)) {
+				bestShift, bestScale, bestScore = k, r, score
+			}
+		}
+	}
+	// The score is the cue time over speech minus the cue time over
+	// silence, as a share of the cue time.
+	return time.Duration(bestShift) * frameDuration, bestScale, max(0, (bestScore+1)/2)
+}
+
+func toFrame(d time.Duration) int {
+	return int(d / frameDuration)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// cueActivity returns 1 for the frames a cue is shown in, with its times
+// multiplied by scale, and 0 for the others.
+func cueActivity(cues []*srt.Subtitle, scale float64) []float64 {
+	end := 0
+	for _, c := range cues {
+		end = max(end, toFrame(time.Duration(float64(c.ToTime)*scale)))
+	}
+	signal := make([]float64, end+1)
+	for _, c := range cues {
+		from := toFrame(time.Duration(float64(c.FromTime) * scale))
+		to := toFrame(time.Duration(float64(c.ToTime) * scale))
+		for i := max(0, from); i < to; i++ {
+			signal[i] = 1
+		}
+	}
+	return signal
+}
+
+// Apply returns copies of cues with their times multiplied by scale and then
+// shifted by offset, rounded to the millisecond. Cues that end before 0 are
+// dropped (and counted); those that start before it start at 0.
+func Apply(cues []*srt.Subtitle, offset time.Duration, scale float64) ([]*srt.Subtitle, int) {
+	var out []*srt.Subtitle
+	dropped := 0
+	for _, c := range cues {
+		cue := *c
+		cue.FromTime = (time.Duration(float64(c.FromTime)*scale) + offset).Round(time.Millisecond)
+		cue.ToTime = (time.Duration(float64(c.ToTime)*scale) + offset).Round(time.Millisecond)
+		if cue.ToTime <= 0 {
+			dropped++
+			continue
+		}
+		cue.FromTime = max(0, cue.FromTime)
+		out = append(out, &cue)
+	}
+	srt.Reindex(out)
+	return out, dropped
+}
+
+// referenceActivity takes the cues of a subtitle file in sync with the media
+// as its speech.
+func referenceActivity(path string) ([]bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := srt.Read(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("parse reference %s: %w", path, err)
+	}
+	if len(doc.Cues) == 0 {
+		return nil, nil
+	}
+	signal := cueActivity(doc.Cues, 1)
+	speech := make([]bool, len(signal))
+	for i, v := range signal {
+		speech[i] = v > 0
+	}
+	return speech, nil
+}
+
+// audioActivity decodes the audio track of the media with ffmpeg and returns
+// its speech activity.
+func audioActivity(ctx context.Context, mediaPath string, track int) ([]bool, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found on PATH: %w", err)
+	}
+	args := []string{"-nostdin", "-v", "error",
+		"-i", mediaPath,
+		"-map", fmt.Sprintf("0:a:%d", track),
+		"-ac", "1", "-ar", fmt.Sprint(sampleRate),
+		"-f", "s16le", "-"}
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("running ffmpeg", "bin", ffmpeg, "args", args)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	energies, readErr := frameEnergies(stdout)
+	if readErr != nil {
+		// Let ffmpeg end instead of blocking on a full pipe.
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("read decoded audio: %w", readErr)
+	}
+	return speechActivity(energies), nil
+}
@@ -0,0 +1,152 @@
+package mediasync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestCorrelate(t *testing.T) {
+	ref := []float64{1, -1, 2, 0.5, -3, 1}
+	signal := []float64{0, 1, 2}
+	const maxShift = 3
+	corr := correlate(transform(ref, fftSize(len(ref)+len(signal)+maxShift)), signal, maxShift)
+	for k := -maxShift; k <= maxShift; k++ {
+		want := 0.0
+		for i, v := range signal {
+			if j := i + k; j >= 0 && j < len(ref) {
+				want += ref[j] * v
+			}
+		}
+		if got := corr[k+maxShift]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("corr[%d] = %g, want %g", k, got, want)
+		}
+	}
+}
+
+// testDialogue returns cues of 1 to 3 seconds with pauses of 1 to 4 seconds.
+func testDialogue(n int) []*srt.Subtitle {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var cues []*srt.Subtitle
+	at := 5 * time.Second
+	for i := range n {
+		d := time.Duration(1000+rng.IntN(2000)) * time.Millisecond
+		cues = append(cues, &srt.Subtitle{Idx: i + 1, FromTime: at, ToTime: at + d, Text: "line"})
+		at += d + time.Duration(1000+rng.IntN(3000))*time.Millisecond
+	}
+	return cues
+}
+
+func TestAlign(t *testing.T) {
+	cues := testDialogue(300)
+	// The speech is where the cues are once slowed down to 25 fps and
+	// shifted by 3.2s.
+	const scale = 25 / 23.976
+	moved, _ := Apply(cues, 3200*time.Millisecond, scale)
+	speech := make([]bool, toFrame(moved[len(moved)-1].ToTime)+100)
+	for _, c := range moved {
+		for i := toFrame(c.FromTime); i < toFrame(c.ToTime); i++ {
+			speech[i] = true
+		}
+	}
+
+	offset, gotScale, score := Align(speech, cues, DefaultMaxOffset, framerateRatios)
+	if gotScale != scale || (offset-3200*time.Millisecond).Abs() > frameDuration || score < 0.95 {
+		t.Fatalf("Align = %v, %v, %.3f; want 3.2s, %v", offset, gotScale, score, scale)
+	}
+	offset, gotScale, _ = Align(speech, cues, DefaultMaxOffset, framerateRatios[:1])
+	if gotScale != 1 {
+		t.Fatalf("Align without frame rates = %v, %v", offset, gotScale)
+	}
+}
+
+// pcm returns mono 16-bit audio at sampleRate with a tone during the given
+// seconds and low noise elsewhere.
+func pcm(seconds float64, tones [][2]float64) []byte {
+	rng := rand.New(rand.NewPCG(3, 4))
+	var buf bytes.Buffer
+	for i := range int(seconds * sampleRate) {
+		at := float64(i) / sampleRate
+		v := rng.NormFloat64() * 30
+		for _, tone := range tones {
+			if at >= tone[0] && at < tone[1] {
+				v += 8000 * math.Sin(2*math.Pi*220*at)
+			}
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, int16(v))
+	}
+	return buf.Bytes()
+}
+
+func TestSpeechActivity(t *testing.T) {
+	energies, err := frameEnergies(bytes.NewReader(pcm(6, [][2]float64{{1, 2}, {2.1, 3}, {4.5, 5}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(energies) != 6*framesPerSecond {
+		t.Fatalf("got %d frames", len(energies))
+	}
+	speech := speechActivity(energies)
+	for _, tc := range []struct {
+		at   float64
+		want bool
+	}{{0.5, false}, {1.5, true}, {2.05, true}, {3.5, false}, {4.7, true}, {5.5, false}} {
+		if got := speech[int(tc.at*framesPerSecond)]; got != tc.want {
+			t.Errorf("speech at %.2fs = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestRun_Reference(t *testing.T) {
+	dir := t.TempDir()
+	cues := testDialogue(100)
+	var in, ref bytes.Buffer
+	if err := srt.WriteAll(&in, cues); err != nil {
+		t.Fatal(err)
+	}
+	shifted, _ := Apply(cues, -2*time.Second, 1)
+	if err := srt.WriteAll(&ref, shifted); err != nil {
+		t.Fatal(err)
+	}
+	input, reference := filepath.Join(dir, "movie.srt"), filepath.Join(dir, "reference.srt")
+	if err := os.WriteFile(input, in.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(reference, ref.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Run(context.Background(), Options{InputPath: input, MediaPath: reference, WorkDir: t.TempDir(), CreateBackup: true, BackupExt: ".bak"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Offset != -2*time.Second || res.Scale != 1 || res.WrittenPath != input {
+		t.Fatalf("Run = %+v", res)
+	}
+	b, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != ref.String() {
+		t.Fatalf("synced output differs from the reference:\n%s", b)
+	}
+	if _, err := os.Stat(input + ".bak"); err != nil {
+		t.Fatalf("no backup: %v", err)
+	}
+
+	if err := os.WriteFile(reference, []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(context.Background(), Options{InputPath: input, MediaPath: reference, WorkDir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "no speech") {
+		t.Fatalf("Run with an empty reference: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package mediasync
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"slices"
+)
+
+const (
+	// sampleRate is the rate the audio is decoded at: enough for the voice
+	// band, and small enough to decode a film quickly.
+	sampleRate      = 8000
+	samplesPerFrame = sampleRate / framesPerSecond
+
+	// speechMargin is how much louder than the noise floor, in dB, a frame
+	// has to be to be taken as speech.
+	speechMargin = 9.0
+	// noisePercentile is the share of the frames taken as the noise floor.
+	noisePercentile = 0.1
+	// hangoverFrames keep short pauses within speech (between words) as
+	// speech.
+	hangoverFrames = 10
+)
+
+// frameEnergies reads mono 16-bit little-endian PCM at sampleRate and returns
+// the energy of every frame in dB.
+func frameEnergies(r io.Reader) ([]float64, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	var energies []float64
+	var buf [2 * samplesPerFrame]byte
+	for {
+		n, err := io.ReadFull(br, buf[:])
+		if n < len(buf) {
+			if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return energies, nil
+			}
+			return nil, err
+		}
+		var sum float64
+		for i := 0; i < len(buf); i += 2 {
+			s := float64(int16(binary.LittleEndian.Uint16(buf[i:])))
+			sum += s * s
+		}
+		energies = append(energies, 10*math.Log10(sum/samplesPerFrame+1))
+	}
+}
+
+// speechActivity tells, for every frame, whether it holds speech: frames well
+// above the noise floor of the file, with short pauses bridged. It is a
+// rough detector, but sync only needs where speech starts and stops.
+func speechActivity(energies []float64) []bool {
+	if len(energies) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(energies)
+	slices.Sort(sorted)
+	threshold := sorted[int(float64(len(sorted)-1)*noisePercentile)] + speechMargin
+
+	speech := make([]bool, len(energies))
+	quiet := hangoverFrames + 1
+	for i, e := range energies {
+		if e >= threshold {
+			// Bridge the pause before this frame.
+			if quiet <= hangoverFrames {
+				for j := i - quiet; j < i; j++ {
+					speech[j] = true
+				}
+			}
+			speech[i] = true
+			quiet = 0
+			continue
+		}
+		quiet++
+	}
+	return speech
+}
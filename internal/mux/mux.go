@@ -0,0 +1,182 @@
+// Package mux embeds an SRT file into a video container with ffmpeg. Every
+// other stream is stream-copied, so muxing is fast and lossless.
+package mux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/extract"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+)
+
+type Options struct {
+	VideoPath    string
+	SubtitlePath string
+	OutputPath   string // empty means replacing the video file
+	DryRun       bool
+	WorkDir      string
+	// Language of the subtitle track (e.g. "es" or "spa"). Empty means taking
+	// it from the subtitle file name (movie.es.srt); "und" when there is none.
+	Language string
+	Title    string
+	Default  bool // mark the track as default (and clear the flag on the others)
+	Forced   bool
+	// KeepExisting keeps the subtitle tracks in the same language instead of
+	// replacing them.
+	KeepExisting bool
+}
+
+type Result struct {
+	WrittenPath string
+	Language    string // ISO 639-2 code written to the container
+	Replaced    []int  // stream indexes of the removed tracks
+	Tracks      int    // subtitle tracks in the output
+}
+
+// Run writes the video with the subtitle track added (or replacing the tracks
+// in the same language).
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if opts.VideoPath == "" {
+		return Result{}, errors.New("video path is required")
+	}
+	if opts.SubtitlePath == "" {
+		return Result{}, errors.New("subtitle path is required")
+	}
+	if opts.WorkDir == "" {
+		return Result{}, errors.New("workdir is required (create one with run.NewWorkdir)")
+	}
+	if _, err := os.Stat(opts.SubtitlePath); err != nil {
+		return Result{}, err
+	}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return Result{}, fmt.Errorf("ffmpeg not found on PATH: %w", err)
+	}
+
+	lang := opts.Language
+	if lang == "" {
+		_, name := naming.Parse(opts.SubtitlePath)
+		lang = name.Language
+	}
+	lang = naming.ContainerLanguage(lang)
+
+	tracks, err := extract.ListTracks(ctx, opts.VideoPath, extract.ToolFFmpeg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = opts.VideoPath
+	}
+	p := plan{
+		Tracks:       tracks,
+		Language:     lang,
+		Title:        opts.Title,
+		Default:      opts.Default,
+		Forced:       opts.Forced,
+		KeepExisting: opts.KeepExisting,
+		Codec:        subtitleCodec(outputPath),
+	}
+	args, replaced, kept := p.args(opts.VideoPath, opts.SubtitlePath)
+
+	slog.Info("muxing subtitle track", "video_path", opts.VideoPath, "subtitle_path", opts.SubtitlePath, "language", lang, "replaced", replaced)
+
+	namer := run.NewTempNamer(opts.WorkDir, outputPath)
+	tmpPath := namer.Step("mux")
+	if err := runFFmpeg(ctx, ffmpeg, append(args, tmpPath)...); err != nil {
+		return Result{}, err
+	}
+
+	if opts.DryRun {
+		outputPath = tmpPath
+	} else if err := fs.MoveFile(tmpPath, outputPath); err != nil {
+		return Result{}, err
+	}
+	return Result{WrittenPath: outputPath, Language: lang, Replaced: replaced, Tracks: kept + 1}, nil
+}
+
+// plan builds the ffmpeg arguments for a mux.
+type plan struct {
+	Tracks       []extract.Track // subtitle tracks of the video
+	Language     string
+	Title        string
+	Default      bool
+	Forced       bool
+	KeepExisting bool
+	Codec        string
+}
+
+// args returns the ffmpeg arguments (without the output path), the stream
+// indexes of the replaced tracks and the number of kept subtitle tracks.
+func (p plan) args(videoPath, subtitlePath string) ([]string, []int, int) {
+	args := []string{"-nostdin", "-v", "error", "-y",
+		"-i", videoPath,
+		"-i", subtitlePath,
+		"-map", "0",
+	}
+	var replaced []int
+	for _, t := range p.Tracks {
+		if !p.KeepExisting && p.Language != naming.Undetermined && naming.ContainerLanguage(t.Language) == p.Language {
+			replaced = append(replaced, t.ID)
+			args = append(args, "-map", fmt.Sprintf("-0:%d", t.ID))
+		}
+	}
+	args = append(args, "-map", "1:0", "-c", "copy")
+
+	kept := len(p.Tracks) - len(replaced)
+	if p.Default {
+		for i := 0; i < kept; i++ {
+			args = append(args, fmt.Sprintf("-disposition:s:%d", i), "0")
+		}
+	}
+	s := fmt.Sprintf("s:%d", kept) // the new track is the last subtitle stream
+	args = append(args, "-c:"+s, p.Codec, "-metadata:"+s, "language="+p.Language)
+	if p.Title != "" {
+		args = append(args, "-metadata:"+s, "title="+p.Title)
+	}
+	var disposition []string
+	if p.Default {
+		disposition = append(disposition, "default")
+	}
+	if p.Forced {
+		disposition = append(disposition, "forced")
+	}
+	if len(disposition) == 0 {
+		disposition = []string{"0"}
+	}
+	args = append(args, "-disposition:"+s, strings.Join(disposition, "+"))
+	return args, replaced, kept
+}
+
+// subtitleCodec returns the text subtitle codec supported by the container of
+// path: mov_text for MP4/MOV, srt otherwise (Matroska).
+func subtitleCodec(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp4", ".m4v", ".mov":
+		return "mov_text"
+	default:
+		return "srt"
+	}
+}
+
+func runFFmpeg(ctx context.Context, bin string, args ...string) error {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	slog.Debug("running ffmpeg", "bin", bin, "args", args)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package mux
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/extract"
+)
+
+func TestPlanArgs_ReplacesSameLanguageAndSetsDefault(t *testing.T) {
+	p := plan{
+		Tracks: []extract.Track{
+			{ID: 2, Language: "eng", Default: true},
+			{ID: 3, Language: "spa"},
+			{ID: 4, Language: "spa", Forced: true},
+		},
+		Language: "spa",
+		Title:    "Español",
+		Default:  true,
+		Codec:    "srt",
+	}
+	args, replaced, kept := p.args("movie.mkv", "subs.es.srt")
+	if !slices.Equal(replaced, []int{3, 4}) || kept != 1 {
+		t.Fatalf("replaced = %v, kept = %d", replaced, kept)
+	}
+	got := strings.Join(args, " ")
+	want := "-nostdin -v error -y -i movie.mkv -i subs.es.srt -map 0 -map -0:3 -map -0:4 -map 1:0 -c copy " +
+		"-disposition:s:0 0 -c:s:1 srt -metadata:s:1 language=spa -metadata:s:1 title=Español -disposition:s:1 default"
+	if got != want {
+		t.Fatalf("args:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestPlanArgs_KeepExisting(t *testing.T) {
+	p := plan{
+		Tracks:       []extract.Track{{ID: 2, Language: "spa"}},
+		Language:     "spa",
+		Forced:       true,
+		KeepExisting: true,
+		Codec:        "mov_text",
+	}
+	args, replaced, kept := p.args("movie.mp4", "subs.srt")
+	if len(replaced) != 0 || kept != 1 {
+		t.Fatalf("replaced = %v, kept = %d", replaced, kept)
+	}
+	got := strings.Join(args, " ")
+	if !strings.HasSuffix(got, "-c:s:1 mov_text -metadata:s:1 language=spa -disposition:s:1 forced") {
+		t.Fatalf("unexpected args: %s", got)
+	}
+}
+
+func TestSubtitleCodec(t *testing.T) {
+	if got := subtitleCodec("/media/movie.MP4"); got != "mov_text" {
+		t.Fatalf("expected mov_text for mp4, got %s", got)
+	}
+	if got := subtitleCodec("/media/movie.mkv"); got != "srt" {
+		t.Fatalf("expected srt for mkv, got %s", got)
+	}
+}
@@ -0,0 +1,86 @@
+package naming
+
+import (
+	"strings"
+)
+
+// Undetermined is the ISO 639-2 code for an unknown language.
+const Undetermined = "und"
+
+// iso639_2 maps ISO 639-1 codes to the ISO 639-2/B codes used by containers.
+var iso639_2 = map[string]string{
+	"ar": "ara", "bg": "bul", "ca": "cat", "cs": "cze", "da": "dan",
+	"de": "ger", "el": "gre", "en": "eng", "es": "spa", "et": "est",
+	"eu": "baq", "fa": "per", "fi": "fin", "fr": "fre", "gl": "glg",
+	"he": "heb", "hi": "hin", "hr": "hrv", "hu": "hun", "id": "ind",
+	"is": "ice", "it": "ita", "ja": "jpn", "ko": "kor", "lt": "lit",
+	"lv": "lav", "ms": "may", "nb": "nob", "nl": "dut", "no": "nor",
+	"pl": "pol", "pt": "por", "ro": "rum", "ru": "rus", "sk": "slo",
+	"sl": "slv", "sr": "srp", "sv": "swe", "th": "tha", "tr": "tur",
+	"uk": "ukr", "vi": "vie", "zh": "chi",
+}
+
+// iso639_2T maps the ISO 639-2/T variants to the /B codes above (ffprobe
+// reports whatever the muxer wrote, e.g. "deu" or "ger").
+var iso639_2T = map[string]string{
+	"ces": "cze", "deu": "ger", "ell": "gre", "eus": "baq", "fas": "per",
+	"fra": "fre", "isl": "ice", "msa": "may", "nld": "dut", "ron": "rum",
+	"slk": "slo", "zho": "chi",
+}
+
+// iso639_1 maps the ISO 639-2/B codes back to ISO 639-1.
+var iso639_1 = func() map[string]string {
+	m := make(map[string]string, len(iso639_2))
+	for short, long := range iso639_2 {
+		m[long] = short
+	}
+	return m
+}()
+
+// primarySubtag returns the lowercase language subtag of tag ("pt_BR" -> "pt")
+// and the rest of the tag ("BR").
+func primarySubtag(tag string) (string, string) {
+	primary, rest, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	return strings.ToLower(primary), rest
+}
+
+// ContainerLanguage converts a language tag ("es", "es-419", "pt_BR", "spa")
+// to the ISO 639-2/B code stored in containers. Unknown 3-letter codes are
+// kept; anything else is "und".
+func ContainerLanguage(tag string) string {
+	primary, _ := primarySubtag(tag)
+	if code, ok := iso639_2[primary]; ok {
+		return code
+	}
+	if code, ok := iso639_2T[primary]; ok {
+		return code
+	}
+	if len(primary) == 3 {
+		return primary
+	}
+	return Undetermined
+}
+
+// ShortLanguage converts a language tag to ISO 639-1 when the language is
+// known ("spa" -> "es"), keeping the region ("pt_BR" -> "pt-BR"). Other tags are
+// only normalized.
+func ShortLanguage(tag string) string {
+	primary, region := primarySubtag(tag)
+	if short, ok := iso639_1[ContainerLanguage(primary)]; ok {
+		primary = short
+	}
+	if region == "" {
+		return primary
+	}
+	if len(region) == 2 {
+		region = strings.ToUpper(region)
+	}
+	return primary + "-" + region
+}
+
+// IsKnownLanguage reports whether tag is a language of the tables above, so
+// file name parts such as "final" or "avi" are not taken for languages.
+func IsKnownLanguage(tag string) bool {
+	_, ok := iso639_1[ContainerLanguage(tag)]
+	return ok
+}
@@ -0,0 +1,296 @@
+// Package naming derives subtitle file names from the adjacent video file,
+// following the conventions of media servers (Plex, Jellyfin):
+// "Movie (2020).es.forced.srt".
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/langdetect"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Naming schemes.
+const (
+	SchemePlex     = "plex"     // ISO 639-1 codes when known: Movie (2020).es.forced.srt
+	SchemeJellyfin = "jellyfin" // language tag as given: Movie (2020).spa.sdh.srt
+)
+
+const (
+	suffixForced = "forced"
+	suffixSDH    = "sdh"
+	suffixCC     = "cc" // read as SDH, never written
+)
+
+// ErrUnknownLanguage is returned by DetectLanguage when the text is too short
+// or ambiguous to tell its language.
+var ErrUnknownLanguage = errors.New("can't detect the subtitle language")
+
+// VideoExtensions are the files considered when looking for the video of a
+// subtitle.
+var VideoExtensions = []string{".avi", ".m2ts", ".m4v", ".mkv", ".mov", ".mp4", ".mpg", ".ts", ".webm", ".wmv"}
+
+// SubtitleExtensions are the files considered when looking for the
+// subtitles of a video.
+var SubtitleExtensions = []string{".sami", ".sbv", ".smi", ".srt"}
+
+// Name holds the parts of a subtitle file name that media servers read.
+type Name struct {
+	Language string // empty when unknown
+	Forced   bool
+	SDH      bool
+}
+
+func IsValidScheme(scheme string) bool {
+	return scheme == SchemePlex || scheme == SchemeJellyfin
+}
+
+// Parse splits a subtitle path into the name without the language and flag
+// suffixes ("/media/Movie.es.forced.srt" -> "Movie") and those suffixes.
+func Parse(path string) (string, Name) {
+	base := filepath.Base(path)
+	parts := strings.Split(strings.TrimSuffix(base, filepath.Ext(base)), ".")
+	var n Name
+	end := len(parts)
+	for end > 1 {
+		switch strings.ToLower(parts[end-1]) {
+		case suffixForced:
+			n.Forced = true
+		case suffixSDH, suffixCC:
+			n.SDH = true
+		default:
+			if n.Language == "" && isLanguageSuffix(parts[end-1]) {
+				n.Language = parts[end-1]
+				end--
+				// Flags may also come before the language (Movie.forced.es.srt).
+				continue
+			}
+			return strings.Join(parts[:end], "."), n
+		}
+		end--
+	}
+	return strings.Join(parts[:end], "."), n
+}
+
+// isLanguageSuffix reports whether a file name part is a language tag. The
+// language subtag must be lowercase, so words such as "It" are not taken for
+// languages.
+func isLanguageSuffix(part string) bool {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(part, "_", "-"), "-")
+	return primary == strings.ToLower(primary) && IsKnownLanguage(part)
+}
+
+// Path returns the subtitle path for videoPath: the video name followed by
+// the language and the forced/sdh suffixes.
+func Path(videoPath, scheme string, n Name) string {
+	name := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+	if n.Language != "" {
+		lang := n.Language
+		if scheme == SchemePlex {
+			lang = ShortLanguage(lang)
+		}
+		name += "." + lang
+	}
+	if n.Forced {
+		name += "." + suffixForced
+	}
+	if n.SDH {
+		name += "." + suffixSDH
+	}
+	return name + ".srt"
+}
+
+// FindVideo returns the video next to subtitlePath: the one with the same
+// name (without language and flags), the one whose name prefixes the subtitle
+// name, or the only video of the directory.
+func FindVideo(subtitlePath string) (string, error) {
+	dir := filepath.Dir(subtitlePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	stem, _ := Parse(subtitlePath)
+	var videos, prefixed []string
+	for _, e := range entries {
+		if e.IsDir() || !slices.Contains(VideoExtensions, strings.ToLower(filepath.Ext(e.Name()))) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		videoStem := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if videoStem == stem {
+			return path, nil
+		}
+		if strings.HasPrefix(stem, videoStem) {
+			prefixed = append(prefixed, path)
+		}
+		videos = append(videos, path)
+	}
+	switch {
+	case len(prefixed) == 1:
+		return prefixed[0], nil
+	case len(videos) == 1:
+		return videos[0], nil
+	case len(videos) == 0:
+		return "", fmt.Errorf("no video file found next to %s", subtitlePath)
+	default:
+		return "", fmt.Errorf("several video files next to %s; can't tell which one it belongs to", subtitlePath)
+	}
+}
+
+// FindSubtitles returns the subtitle files next to videoPath named after it,
+// with any language and flags ("Movie.mkv" -> "Movie.srt", "Movie.en.srt",
+// "Movie.es.forced.srt"), sorted by name.
+func FindSubtitles(videoPath string) ([]string, error) {
+	dir := filepath.Dir(videoPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	videoStem := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	var subs []string
+	for _, e := range entries {
+		if e.IsDir() || !slices.Contains(SubtitleExtensions, strings.ToLower(filepath.Ext(e.Name()))) {
+			continue
+		}
+		if stem, _ := Parse(e.Name()); stem == videoStem {
+			subs = append(subs, filepath.Join(dir, e.Name()))
+		}
+	}
+	return subs, nil
+}
+
+// DetectLanguage guesses the language of a subtitle file from its text.
+func DetectLanguage(path string) (langdetect.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return langdetect.Result{}, err
+	}
+	defer fs.CloseOrLog(f, path)
+	subs, err := srt.ReadAll(f)
+	if err != nil {
+		return langdetect.Result{}, err
+	}
+	var b strings.Builder
+	for _, s := range subs {
+		b.WriteString(s.Text)
+		b.WriteByte('\n')
+	}
+	res, ok := langdetect.Detect(b.String())
+	if !ok {
+		return langdetect.Result{}, ErrUnknownLanguage
+	}
+	return res, nil
+}
+
+// WithLanguage returns path with the language suffix lang, before the flag
+// suffixes: "/media/Movie.forced.srt" -> "/media/Movie.es.forced.srt".
+func WithLanguage(path, lang string) string {
+	stem, _ := Parse(path)
+	base := filepath.Base(path)
+	return filepath.Join(filepath.Dir(path), stem+"."+lang+strings.TrimPrefix(base, stem))
+}
+
+type RenameOptions struct {
+	SubtitlePath string
+	VideoPath    string // empty means looking for it with FindVideo
+	Scheme       string
+	Language     string // empty means taking it from the file name or the text
+	Forced       bool   // added to the flags found in the file name
+	SDH          bool
+	DryRun       bool // only compute the new path
+}
+
+type RenameResult struct {
+	From string
+	To   string
+}
+
+// Rename moves a subtitle file next to its video, named after the video with
+// the conventions of the scheme.
+func Rename(opts RenameOptions) (RenameResult, error) {
+	if opts.SubtitlePath == "" {
+		return RenameResult{}, errors.New("subtitle path is required")
+	}
+	if !IsValidScheme(opts.Scheme) {
+		return RenameResult{}, fmt.Errorf("invalid naming scheme %q (supported: %s, %s)", opts.Scheme, SchemePlex, SchemeJellyfin)
+	}
+	videoPath := opts.VideoPath
+	if videoPath == "" {
+		var err error
+		if videoPath, err = FindVideo(opts.SubtitlePath); err != nil {
+			return RenameResult{}, err
+		}
+	}
+
+	_, n := Parse(opts.SubtitlePath)
+	if opts.Language != "" {
+		n.Language = opts.Language
+	}
+	if n.Language == "" {
+		res, err := DetectLanguage(opts.SubtitlePath)
+		if err != nil {
+			return RenameResult{}, fmt.Errorf("unknown subtitle language: %w", err)
+		}
+		n.Language = res.Language
+	}
+	n.Forced = n.Forced || opts.Forced
+	n.SDH = n.SDH || opts.SDH
+
+	res := RenameResult{From: opts.SubtitlePath, To: Path(videoPath, opts.Scheme, n)}
+	if res.To == res.From {
+		return res, nil
+	}
+	if _, err := os.Stat(res.To); err == nil {
+		return RenameResult{}, fmt.Errorf("file already exists: %s", res.To)
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+	return res, fs.MoveFile(res.From, res.To)
+}
+
+type TagOptions struct {
+	SubtitlePath string
+	Rename       bool // add the detected language to the file name
+	DryRun       bool // only compute the new path
+}
+
+type TagResult struct {
+	Path     string
+	Detected langdetect.Result // empty Language when it can't be detected
+	Named    string            // language of the file name suffix, if any
+	// RenamedTo is the new path (or the one it would get in dry-run); empty
+	// when the file isn't renamed.
+	RenamedTo string
+}
+
+// TagLanguage detects the language of a subtitle file and, with Rename, adds
+// it to a file name without one (Movie.srt -> Movie.es.srt). A file whose
+// name already has a language is never renamed.
+func TagLanguage(opts TagOptions) (TagResult, error) {
+	_, n := Parse(opts.SubtitlePath)
+	res := TagResult{Path: opts.SubtitlePath, Named: n.Language}
+	detected, err := DetectLanguage(opts.SubtitlePath)
+	if err != nil && !errors.Is(err, ErrUnknownLanguage) {
+		return TagResult{}, err
+	}
+	res.Detected = detected
+	if !opts.Rename || n.Language != "" || detected.Language == "" {
+		return res, nil
+	}
+	to := WithLanguage(opts.SubtitlePath, detected.Language)
+	if _, err := os.Stat(to); err == nil {
+		return TagResult{}, fmt.Errorf("file already exists: %s", to)
+	}
+	res.RenamedTo = to
+	if opts.DryRun {
+		return res, nil
+	}
+	return res, fs.MoveFile(opts.SubtitlePath, to)
+}
@@ -0,0 +1,180 @@
+package naming
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerLanguage(t *testing.T) {
+	cases := map[string]string{"es": "spa", "pt_BR": "por", "es-419": "spa", "deu": "ger", "spa": "spa", "": "und", "spanish": "und"}
+	for in, want := range cases {
+		if got := ContainerLanguage(in); got != want {
+			t.Fatalf("ContainerLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestShortLanguage(t *testing.T) {
+	cases := map[string]string{"spa": "es", "ES": "es", "pt_br": "pt-BR", "ger": "de", "deu": "de", "es-419": "es-419", "xyz": "xyz"}
+	for in, want := range cases {
+		if got := ShortLanguage(in); got != want {
+			t.Fatalf("ShortLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		path string
+		stem string
+		name Name
+	}{
+		{"/media/Movie (2020).es.srt", "Movie (2020)", Name{Language: "es"}},
+		{"/media/Movie.pt-BR.forced.srt", "Movie", Name{Language: "pt-BR", Forced: true}},
+		{"/media/Movie.eng.sdh.srt", "Movie", Name{Language: "eng", SDH: true}},
+		{"/media/Movie.forced.es.srt", "Movie", Name{Language: "es", Forced: true}},
+		{"/media/Movie.2020.avi.srt", "Movie.2020.avi", Name{}},
+		{"/media/The.It.Crowd.srt", "The.It.Crowd", Name{}},
+		{"/media/es.srt", "es", Name{}},
+	}
+	for _, c := range cases {
+		stem, name := Parse(c.path)
+		if stem != c.stem || name != c.name {
+			t.Fatalf("Parse(%q) = %q, %+v; want %q, %+v", c.path, stem, name, c.stem, c.name)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	video := "/media/Movie (2020)/Movie (2020).mkv"
+	if got := Path(video, SchemePlex, Name{Language: "spa", Forced: true}); got != "/media/Movie (2020)/Movie (2020).es.forced.srt" {
+		t.Fatalf("unexpected plex path %q", got)
+	}
+	if got := Path(video, SchemeJellyfin, Name{Language: "spa", SDH: true}); got != "/media/Movie (2020)/Movie (2020).spa.sdh.srt" {
+		t.Fatalf("unexpected jellyfin path %q", got)
+	}
+	if got := Path(video, SchemePlex, Name{}); got != "/media/Movie (2020)/Movie (2020).srt" {
+		t.Fatalf("unexpected path without language %q", got)
+	}
+}
+
+func TestFindVideo(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "Movie (2020).mkv")
+	touch(t, dir, "Other.mp4")
+	touch(t, dir, "notes.txt")
+
+	got, err := FindVideo(filepath.Join(dir, "Movie (2020).en.srt"))
+	if err != nil || got != filepath.Join(dir, "Movie (2020).mkv") {
+		t.Fatalf("expected the video with the same name, got %q (%v)", got, err)
+	}
+	got, err = FindVideo(filepath.Join(dir, "Movie (2020) 1080p.srt"))
+	if err != nil || got != filepath.Join(dir, "Movie (2020).mkv") {
+		t.Fatalf("expected the video prefixing the name, got %q (%v)", got, err)
+	}
+	if _, err := FindVideo(filepath.Join(dir, "subs.srt")); err == nil || !strings.Contains(err.Error(), "several video files") {
+		t.Fatalf("expected an ambiguity error, got %v", err)
+	}
+}
+
+func TestFindSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "Movie (2020).mkv")
+	touch(t, dir, "Movie (2020).srt")
+	touch(t, dir, "Movie (2020).es.forced.srt")
+	touch(t, dir, "Movie (2020).en.sbv")
+	touch(t, dir, "Movie (2020) 1080p.srt")
+	touch(t, dir, "Other.en.srt")
+	touch(t, dir, "Movie (2020).nfo")
+
+	got, err := FindSubtitles(filepath.Join(dir, "Movie (2020).mkv"))
+	if err != nil {
+		t.Fatalf("FindSubtitles: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "Movie (2020).en.sbv"),
+		filepath.Join(dir, "Movie (2020).es.forced.srt"),
+		filepath.Join(dir, "Movie (2020).srt"),
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("FindSubtitles = %q, want %q", got, want)
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "Movie (2020).mkv")
+	sub := filepath.Join(dir, "downloaded.spa.forced.srt")
+	if err := os.WriteFile(sub, []byte("1\n00:00:01,000 --> 00:00:02,000\nHola\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Rename(RenameOptions{SubtitlePath: sub, Scheme: SchemePlex, DryRun: true})
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	want := filepath.Join(dir, "Movie (2020).es.forced.srt")
+	if res.To != want {
+		t.Fatalf("To = %q, want %q", res.To, want)
+	}
+	if _, err := os.Stat(sub); err != nil {
+		t.Fatalf("dry-run must not move the file: %v", err)
+	}
+
+	if _, err := Rename(RenameOptions{SubtitlePath: sub, Scheme: SchemePlex, SDH: true}); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Movie (2020).es.forced.sdh.srt")); err != nil {
+		t.Fatalf("expected the renamed file: %v", err)
+	}
+}
+
+func TestWithLanguage(t *testing.T) {
+	for path, want := range map[string]string{
+		"/media/Movie.srt":          "/media/Movie.es.srt",
+		"/media/Movie.forced.srt":   "/media/Movie.es.forced.srt",
+		"/media/Movie.2020.sdh.srt": "/media/Movie.2020.es.sdh.srt",
+	} {
+		if got := WithLanguage(path, "es"); got != want {
+			t.Errorf("WithLanguage(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestTagLanguage(t *testing.T) {
+	dir := t.TempDir()
+	text := "1\n00:00:01,000 --> 00:00:04,000\nNo sé qué está pasando aquí, pero no me gusta nada.\n\n" +
+		"2\n00:00:05,000 --> 00:00:08,000\nYo tampoco. Vamos a casa, que ya es muy tarde y hay que dormir.\n\n" +
+		"3\n00:00:09,000 --> 00:00:12,000\nPero ella está con él, y no quiero que se quede sola por la noche.\n\n"
+	sub := filepath.Join(dir, "Movie.forced.srt")
+	if err := os.WriteFile(sub, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := TagLanguage(TagOptions{SubtitlePath: sub})
+	if err != nil || res.Detected.Language != "es" || res.RenamedTo != "" {
+		t.Fatalf("TagLanguage = %+v, %v", res, err)
+	}
+	res, err = TagLanguage(TagOptions{SubtitlePath: sub, Rename: true})
+	want := filepath.Join(dir, "Movie.es.forced.srt")
+	if err != nil || res.RenamedTo != want {
+		t.Fatalf("TagLanguage with Rename = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Fatalf("expected the renamed file: %v", err)
+	}
+	// A file named with a language is left as is.
+	res, err = TagLanguage(TagOptions{SubtitlePath: want, Rename: true})
+	if err != nil || res.Named != "es" || res.RenamedTo != "" {
+		t.Fatalf("TagLanguage of a tagged file = %+v, %v", res, err)
+	}
+}
+
+func touch(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package ocr
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+)
+
+// DefaultCacheDir returns the default cache directory of the vision engine
+// under the user cache dir (e.g. ~/.cache/subtitle-tools/ocr).
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "subtitle-tools", "ocr"), nil
+}
+
+// textCache keeps the texts recognized in subtitle images, keyed by the
+// SHA-256 of the image sent, as NDJSON in a file per (language, model). New
+// entries are appended after each batch, so a failed run keeps its progress.
+type textCache struct {
+	path    string
+	entries map[string]string
+}
+
+type textCacheEntry struct {
+	Hash string `json:"h"`
+	Text string `json:"t"`
+}
+
+func openTextCache(dir, model, language string) (*textCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	if language == "" {
+		language = "und"
+	}
+	language = strings.Map(func(r rune) rune {
+		if r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, language)
+	sum := sha256.Sum256([]byte(model))
+	c := &textCache{
+		path:    filepath.Join(dir, fmt.Sprintf("%s_%s.jsonl", language, hex.EncodeToString(sum[:])[:16])),
+		entries: make(map[string]string),
+	}
+	return c, c.load()
+}
+
+// lookup returns the cached text of the image with hash; a nil cache has
+// none.
+func (c *textCache) lookup(hash string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	t, ok := c.entries[hash]
+	return t, ok
+}
+
+// store records texts (image hash -> text); a nil cache ignores them.
+func (c *textCache) store(texts map[string]string) error {
+	if c == nil {
+		return nil
+	}
+	var buf strings.Builder
+	for h, text := range texts {
+		if prev, ok := c.entries[h]; ok && prev == text {
+			continue
+		}
+		line, err := json.Marshal(textCacheEntry{Hash: h, Text: text})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		c.entries[h] = text
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open cache file: %w", err)
+	}
+	if _, err := f.WriteString(buf.String()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	return f.Close()
+}
+
+func (c *textCache) load() error {
+	f, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open cache file: %w", err)
+	}
+	defer fs.CloseOrLog(f, c.path)
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e textCacheEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil || e.Hash == "" {
+			// A partially written line (e.g. interrupted run) only loses that entry.
+			slog.Debug("skipping invalid OCR cache entry", "path", c.path, "err", err)
+			continue
+		}
+		c.entries[e.Hash] = e.Text
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read cache file: %w", err)
+	}
+	return nil
+}
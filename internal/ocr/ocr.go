@@ -0,0 +1,244 @@
+// Package ocr converts bitmap subtitles (PGS .sup, VobSub .idx/.sub) to SRT
+// by recognizing the text of each image with an OCR engine.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+)
+
+// Engines that recognize the text of the images.
+const (
+	EngineTesseract = "tesseract" // the tesseract binary, with its trained data for the language
+	EngineVision    = "vision"    // a multimodal model through an OpenAI-compatible API
+)
+
+const DefaultEngine = EngineTesseract
+
+// openEnd is how long a bitmap without an end time is shown when no other
+// one follows it.
+const openEnd = 3 * time.Second
+
+var ErrNoBitmaps = errors.New("no subtitle images found")
+
+// Bitmap is a subtitle image and the time it is shown.
+type Bitmap struct {
+	Start, End time.Duration
+	Image      image.Image
+	// Forced bitmaps are shown even with subtitles off.
+	Forced bool
+}
+
+type Options struct {
+	// InputPath is a PGS (.sup) file, or the .idx or .sub of a VobSub pair.
+	InputPath  string
+	OutputPath string // empty means <input>.srt next to the input
+	Engine     string // tesseract, vision
+	// Language of the text (e.g. "es"); empty means taking it from the
+	// VobSub index or the input name, or English.
+	Language string
+	WorkDir  string
+
+	// Client is the model of the vision engine, which must accept images.
+	Client *translate.OpenAIClient
+	// BatchSize is the number of images sent in each request of the vision
+	// engine; 0 means DefaultBatchSize.
+	BatchSize int
+	// CacheDir keeps the texts recognized by the vision engine between runs,
+	// by image hash; empty disables the cache.
+	CacheDir string
+}
+
+type Result struct {
+	WrittenPath string
+	Language    string
+	Cues        int
+	// Empty is the number of images in which no text was recognized; they
+	// aren't written.
+	Empty int
+	// Tokens used by the vision engine, and the images whose text it took
+	// from the cache.
+	Tokens int64
+	Cached int
+}
+
+// recognizer reads the text of subtitle images with an OCR engine.
+type recognizer interface {
+	name() string
+	// recognize returns the text of each image, "" when it has none.
+	recognize(ctx context.Context, images []image.Image) ([]string, error)
+}
+
+func NormalizeEngine(engine string) string {
+	return strings.ToLower(strings.TrimSpace(engine))
+}
+
+func IsValidEngine(engine string) bool {
+	return engine == EngineTesseract || engine == EngineVision
+}
+
+// newRecognizer returns the recognizer of opts.Engine for language, checking
+// that it can run.
+func newRecognizer(opts Options, language string) (recognizer, error) {
+	engine := NormalizeEngine(opts.Engine)
+	if engine == "" {
+		engine = DefaultEngine
+	}
+	switch engine {
+	case EngineTesseract:
+		return newTesseract(language, opts.WorkDir)
+	case EngineVision:
+		return newVision(opts.Client, language, opts.BatchSize, opts.CacheDir)
+	}
+	return nil, fmt.Errorf("invalid OCR engine %q (supported: %s, %s)", engine, EngineTesseract, EngineVision)
+}
+
+// DefaultOutputPath is the input with the .srt extension.
+func DefaultOutputPath(inputPath string) string {
+	return strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".srt"
+}
+
+// Run recognizes the text of the images of opts.InputPath and writes it as
+// SRT, a cue per image with text. Forced images are tagged srt.ForcedTag.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if opts.InputPath == "" {
+		return Result{}, errors.New("input path is required")
+	}
+	if opts.WorkDir == "" {
+		return Result{}, errors.New("workdir is required (create one with run.NewWorkdir)")
+	}
+	bitmaps, indexLanguage, err := ReadBitmaps(opts.InputPath)
+	if err != nil {
+		return Result{}, err
+	}
+	language := opts.Language
+	if language == "" {
+		language = indexLanguage
+	}
+	if language == "" {
+		_, name := naming.Parse(opts.InputPath)
+		language = name.Language
+	}
+	rec, err := newRecognizer(opts, language)
+	if err != nil {
+		return Result{}, err
+	}
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = DefaultOutputPath(opts.InputPath)
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		return Result{}, fmt.Errorf("output file already exists: %s", outputPath)
+	}
+
+	slog.Info("recognizing subtitle images", "input_path", opts.InputPath, "images", len(bitmaps), "language", language, "engine", rec.name())
+	subs, empty, err := recognizeCues(ctx, rec, bitmaps)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(subs) == 0 {
+		return Result{}, fmt.Errorf("no text recognized in %d images", len(bitmaps))
+	}
+	if empty > 0 {
+		slog.Warn("no text recognized in some images; they were dropped", "images", empty)
+	}
+
+	var buf bytes.Buffer
+	if err := srt.Write(&buf, &srt.Document{Cues: subs}, false); err != nil {
+		return Result{}, err
+	}
+	if err := fs.WriteFile(&buf, outputPath); err != nil {
+		return Result{}, err
+	}
+	res := Result{WrittenPath: outputPath, Language: language, Cues: len(subs), Empty: empty}
+	if v, ok := rec.(*vision); ok {
+		res.Tokens, res.Cached = v.tokens, v.cached
+	}
+	return res, nil
+}
+
+// recognizeCues returns a cue per bitmap in which rec finds text, and the
+// number of bitmaps without text.
+func recognizeCues(ctx context.Context, rec recognizer, bitmaps []Bitmap) ([]*srt.Subtitle, int, error) {
+	images := make([]image.Image, len(bitmaps))
+	for i, b := range bitmaps {
+		images[i] = b.Image
+	}
+	texts, err := rec.recognize(ctx, images)
+	if err != nil {
+		return nil, 0, err
+	}
+	var subs []*srt.Subtitle
+	empty := 0
+	for i, b := range bitmaps {
+		text := srt.CleanText(texts[i])
+		if text == "" {
+			empty++
+			continue
+		}
+		if b.Forced {
+			text = srt.ForcedTag + text
+		}
+		sub := &srt.Subtitle{Idx: len(subs) + 1, FromTime: b.Start, ToTime: b.End, Text: text}
+		srt.InferFlags(sub)
+		subs = append(subs, sub)
+	}
+	return subs, empty, nil
+}
+
+// ReadBitmaps returns the images of a PGS (.sup) file or a VobSub pair (the
+// .idx or the .sub), in order, and the language of a VobSub index ("" when
+// unknown).
+func ReadBitmaps(path string) ([]Bitmap, string, error) {
+	var (
+		bitmaps  []Bitmap
+		language string
+		err      error
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sup":
+		var b []byte
+		if b, err = os.ReadFile(path); err != nil {
+			return nil, "", err
+		}
+		bitmaps, err = readPGS(b)
+	case ".idx", ".sub":
+		bitmaps, language, err = readVobSub(strings.TrimSuffix(path, filepath.Ext(path)))
+	default:
+		return nil, "", fmt.Errorf("unsupported bitmap subtitle file %s (supported: .sup, .idx/.sub)", filepath.Base(path))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(bitmaps) == 0 {
+		return nil, "", ErrNoBitmaps
+	}
+	return bitmaps, language, nil
+}
+
+// settleEnds gives the bitmaps without an end time (End <= Start) the start
+// of the next one, or openEnd.
+func settleEnds(bitmaps []Bitmap) {
+	for i := range bitmaps {
+		if bitmaps[i].End > bitmaps[i].Start {
+			continue
+		}
+		bitmaps[i].End = bitmaps[i].Start + openEnd
+		if i+1 < len(bitmaps) && bitmaps[i+1].Start > bitmaps[i].Start {
+			bitmaps[i].End = bitmaps[i+1].Start
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package ocr
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+)
+
+// pgsSegment returns a PGS segment of kind shown at t.
+func pgsSegment(t time.Duration, kind byte, data []byte) []byte {
+	seg := []byte{'P', 'G'}
+	seg = binary.BigEndian.AppendUint32(seg, uint32(t*pgsClock/time.Second))
+	seg = binary.BigEndian.AppendUint32(seg, 0)
+	seg = append(seg, kind)
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(data)))
+	return append(seg, data...)
+}
+
+// pgsDisplaySet returns a display set at t showing the 3x2 object rle, or
+// clearing the screen when rle is nil.
+func pgsDisplaySet(t time.Duration, rle []byte, forced bool) []byte {
+	pcs := []byte{0x07, 0x80, 0x04, 0x38, 0x10, 0, 1, 0x80, 0, 0, 0}
+	if rle == nil {
+		return append(pgsSegment(t, pgsComposition, pcs), pgsSegment(t, pgsEnd, nil)...)
+	}
+	flags := byte(0)
+	if forced {
+		flags = pgsObjectForced
+	}
+	pcs[10] = 1
+	pcs = append(pcs, 0, 1, 0, flags, 0x01, 0x00, 0x03, 0x00) // object 1 at (256, 768)
+	pds := []byte{0, 0, 1, 235, 128, 128, 255}                // palette 0: entry 1 is opaque white
+	ods := []byte{0, 1, 0, 0xC0, 0, 0, byte(4 + len(rle)), 0, 3, 0, 2}
+	var set []byte
+	set = append(set, pgsSegment(t, pgsComposition, pcs)...)
+	set = append(set, pgsSegment(t, pgsPalette, pds)...)
+	set = append(set, pgsSegment(t, pgsObject, append(ods, rle...))...)
+	return append(set, pgsSegment(t, pgsEnd, nil)...)
+}
+
+func TestReadPGS(t *testing.T) {
+	// Line 1: white, transparent, white; line 2: a run of 3 white pixels.
+	rle := []byte{1, 0, 0x01, 1, 0, 0, 0, 0x83, 1, 0, 0}
+	var sup []byte
+	sup = append(sup, pgsDisplaySet(time.Second, rle, false)...)
+	sup = append(sup, pgsDisplaySet(2*time.Second, rle, false)...) // repeated: no new image
+	sup = append(sup, pgsDisplaySet(3*time.Second, nil, false)...)
+	sup = append(sup, pgsDisplaySet(4*time.Second, rle, true)...)
+
+	bitmaps, err := readPGS(sup)
+	if err != nil {
+		t.Fatalf("readPGS: %v", err)
+	}
+	if len(bitmaps) != 2 {
+		t.Fatalf("got %d bitmaps, want 2", len(bitmaps))
+	}
+	if b := bitmaps[0]; b.Start != time.Second || b.End != 3*time.Second || b.Forced {
+		t.Errorf("bitmap 1 = %v --> %v forced=%v", b.Start, b.End, b.Forced)
+	}
+	if b := bitmaps[1]; b.Start != 4*time.Second || b.End != 4*time.Second+openEnd || !b.Forced {
+		t.Errorf("bitmap 2 = %v --> %v forced=%v", b.Start, b.End, b.Forced)
+	}
+	img := bitmaps[0].Image
+	if img.Bounds().Dx() != 3 || img.Bounds().Dy() != 2 {
+		t.Fatalf("image size %v, want 3x2", img.Bounds().Size())
+	}
+	for _, p := range []struct {
+		x, y   int
+		opaque bool
+	}{{0, 0, true}, {1, 0, false}, {2, 0, true}, {0, 1, true}, {2, 1, true}} {
+		if _, _, _, a := img.At(p.x, p.y).RGBA(); (a != 0) != p.opaque {
+			t.Errorf("pixel (%d,%d) alpha %d, want opaque=%v", p.x, p.y, a, p.opaque)
+		}
+	}
+
+	if _, err := readPGS([]byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n")); err == nil {
+		t.Fatal("expected an error reading SRT as PGS")
+	}
+}
+
+// subpicture returns a 2x2 subpicture shown from 0 to about 2s: the top line
+// in color 1 (white), the bottom one in color 2.
+func subpicture() []byte {
+	spu := []byte{0, 0, 0, 7, 0x90, 0x00, 0x02}
+	spu = append(spu, 0, 0, 0, 31, // start sequence, next at 31
+		0x01,
+		0x03, 0x02, 0x10, // pixel 1 -> palette 1, pixel 2 -> palette 2
+		0x04, 0x0F, 0xF0, // pixels 1 and 2 opaque
+		0x05, 0, 0, 1, 0, 0, 1, // (0,0)-(1,1)
+		0x06, 0, 4, 0, 5,
+		0xFF)
+	spu = append(spu, 0, 176, 0, 31, 0x02, 0xFF) // stop after 176 ticks
+	binary.BigEndian.PutUint16(spu, uint16(len(spu)))
+	return spu
+}
+
+func TestReadVobSub(t *testing.T) {
+	dir := t.TempDir()
+	idx := "# VobSub index file, v7\nsize: 720x480\n" +
+		"palette: 000000, ffffff, ffff00, 000000, 000000, 000000, 000000, 000000, 000000, 000000, 000000, 000000, 000000, 000000, 000000, 000000\n" +
+		"id: es, index: 0\ntimestamp: 00:00:05:000, filepos: 000000000\n" +
+		"id: en, index: 1\ntimestamp: 00:00:09:000, filepos: 000000000\n"
+	spu := subpicture()
+	pack := []byte{0, 0, 1, 0xBA, 0x44, 0, 4, 0, 4, 1, 1, 0x89, 0xC3, 0xF8}
+	pes := []byte{0, 0, 1, 0xBD, 0, 0, 0x81, 0x80, 0x05, 0x21, 0, 1, 0, 1, 0x20}
+	binary.BigEndian.PutUint16(pes[4:], uint16(len(pes)-6+len(spu)))
+	sub := append(append(pack, pes...), spu...)
+	if err := os.WriteFile(filepath.Join(dir, "movie.idx"), []byte(idx), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "movie.sub"), sub, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	bitmaps, language, err := ReadBitmaps(filepath.Join(dir, "movie.sub"))
+	if err != nil {
+		t.Fatalf("ReadBitmaps: %v", err)
+	}
+	if language != "es" || len(bitmaps) != 1 {
+		t.Fatalf("language %q with %d bitmaps, want es with 1 (only the first track)", language, len(bitmaps))
+	}
+	b := bitmaps[0]
+	if b.Start != 5*time.Second || b.End != 5*time.Second+176*vobSubTick {
+		t.Errorf("bitmap = %v --> %v", b.Start, b.End)
+	}
+	want := [2][2]color.NRGBA{
+		{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, {R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		{{R: 0xFF, G: 0xFF, A: 0xFF}, {R: 0xFF, G: 0xFF, A: 0xFF}},
+	}
+	for y := range 2 {
+		for x := range 2 {
+			if got := color.NRGBAModel.Convert(b.Image.At(x, y)); got != want[y][x] {
+				t.Errorf("pixel (%d,%d) = %v, want %v", x, y, got, want[y][x])
+			}
+		}
+	}
+}
+
+type fakeRecognizer map[int]string
+
+func (f fakeRecognizer) name() string { return "fake" }
+
+func (f fakeRecognizer) recognize(_ context.Context, images []image.Image) ([]string, error) {
+	texts := make([]string, len(images))
+	for i := range images {
+		texts[i] = f[i]
+	}
+	return texts, nil
+}
+
+func TestRecognizeCues(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	bitmaps := []Bitmap{
+		{Start: time.Second, End: 2 * time.Second, Image: img},
+		{Start: 3 * time.Second, End: 4 * time.Second, Image: img},
+		{Start: 5 * time.Second, End: 6 * time.Second, Image: img, Forced: true},
+	}
+	subs, empty, err := recognizeCues(context.Background(), fakeRecognizer{0: " Hello\n\nworld \n", 2: "Sign"}, bitmaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty != 1 || len(subs) != 2 {
+		t.Fatalf("got %d cues and %d empty, want 2 and 1", len(subs), empty)
+	}
+	if s := subs[0]; s.Idx != 1 || s.Text != "Hello\nworld" || s.FromTime != time.Second {
+		t.Errorf("cue 1 = %d %v %q", s.Idx, s.FromTime, s.Text)
+	}
+	if s := subs[1]; s.Idx != 2 || s.Text != srt.ForcedTag+"Sign" || !s.Forced {
+		t.Errorf("cue 2 = %d %q forced=%v", s.Idx, s.Text, s.Forced)
+	}
+}
+
+func TestTesseractLanguage(t *testing.T) {
+	for tag, want := range map[string]string{
+		"":        "eng",
+		"es":      "spa",
+		"de":      "deu",
+		"fre":     "fra",
+		"pt-BR":   "por",
+		"zh":      "chi_sim",
+		"zh-Hant": "chi_tra",
+	} {
+		if got := tesseractLanguage(tag); got != want {
+			t.Errorf("tesseractLanguage(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestForOCR(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) // text
+	img.Set(1, 0, color.NRGBA{A: 0xFF})                            // outline
+	out := forOCR(img)
+	if out.Bounds().Dx() != 2+2*tesseractMargin {
+		t.Fatalf("width %d", out.Bounds().Dx())
+	}
+	if text, outline, margin := out.GrayAt(tesseractMargin, tesseractMargin).Y, out.GrayAt(tesseractMargin+1, tesseractMargin).Y, out.GrayAt(0, 0).Y; text != 0 || outline != 0xFF || margin != 0xFF {
+		t.Errorf("text %d, outline %d, margin %d; want 0, 255, 255", text, outline, margin)
+	}
+}
+
+func TestVisionBatchesAndCache(t *testing.T) {
+	var requests []int // images per request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		n := strings.Count(string(body.Messages[len(body.Messages)-1].Content), `"type":"image_url"`)
+		requests = append(requests, n)
+		texts := make([]string, n)
+		for i := range texts {
+			texts[i] = fmt.Sprintf("text %d", len(requests)*10+i)
+		}
+		if n == 3 {
+			texts = texts[:2] // a skipped image: the batch is halved
+		}
+		content, _ := json.Marshal(texts)
+		reply, _ := json.Marshal(string(content))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":` + string(reply) + `}}],"usage":{"total_tokens":100}}`))
+	}))
+	defer server.Close()
+
+	images := make([]image.Image, 4)
+	for i := range images {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(i%2, 0, color.NRGBA{R: uint8(i * 60), A: 0xFF})
+		images[i] = img
+	}
+	client := &translate.OpenAIClient{BaseURL: server.URL, APIKey: "test", Model: "gpt-test", RetryOptions: translate.RetryOptions{MaxAttempts: 1}}
+	cacheDir := t.TempDir()
+
+	v, err := newVision(client, "es", 3, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err := v.recognize(t.Context(), images)
+	if err != nil {
+		t.Fatalf("recognize: %v", err)
+	}
+	// 3 images (a text missing), then 1 and 2 of them, then the last one.
+	if fmt.Sprint(requests) != "[3 1 2 1]" {
+		t.Errorf("requests = %v", requests)
+	}
+	if want := "text 20|text 30|text 31|text 40"; strings.Join(texts, "|") != want {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+	if v.tokens != 400 || v.cached != 0 {
+		t.Errorf("tokens %d, cached %d", v.tokens, v.cached)
+	}
+
+	requests = nil
+	v, err = newVision(client, "es", 3, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := v.recognize(t.Context(), images)
+	if err != nil {
+		t.Fatalf("recognize: %v", err)
+	}
+	if len(requests) != 0 || v.cached != 4 || strings.Join(cached, "|") != strings.Join(texts, "|") {
+		t.Errorf("second run: %d requests, %d cached, texts %q", len(requests), v.cached, cached)
+	}
+}
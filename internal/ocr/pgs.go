@@ -0,0 +1,246 @@
+package ocr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+)
+
+// PGS segment types.
+const (
+	pgsPalette     = 0x14
+	pgsObject      = 0x15
+	pgsComposition = 0x16
+	pgsEnd         = 0x80
+)
+
+// pgsObjectForced flags a composition object shown even with subtitles off.
+const pgsObjectForced = 0x40
+
+// pgsObjectCropped flags a composition object with a crop rectangle.
+const pgsObjectCropped = 0x80
+
+// pgsClock is the frequency of the presentation timestamps.
+const pgsClock = 90000
+
+type pgsObjectRef struct {
+	id     uint16
+	x, y   int
+	forced bool
+}
+
+type pgsObjectData struct {
+	width, height int
+	rle           []byte
+}
+
+// readPGS reads the images of a PGS (.sup) stream, the Blu-ray subtitles: a
+// display set (segments up to an end segment) with composition objects
+// shows an image until the next display set, which either replaces it or
+// clears the screen.
+func readPGS(b []byte) ([]Bitmap, error) {
+	var (
+		bitmaps  []Bitmap
+		palettes = map[uint8]color.Palette{}
+		objects  = map[uint16]*pgsObjectData{}
+		refs     []pgsObjectRef
+		palette  uint8
+		pts      time.Duration
+		open     = -1 // the bitmap shown, until the next display set
+	)
+	for len(b) > 0 {
+		if len(b) < 13 || b[0] != 'P' || b[1] != 'G' {
+			return nil, errors.New("not a PGS (.sup) stream")
+		}
+		segPTS := time.Duration(binary.BigEndian.Uint32(b[2:6])) * time.Second / pgsClock
+		kind, size := b[10], int(binary.BigEndian.Uint16(b[11:13]))
+		if len(b) < 13+size {
+			return nil, errors.New("truncated PGS segment")
+		}
+		data := b[13 : 13+size]
+		b = b[13+size:]
+
+		switch kind {
+		case pgsComposition:
+			if len(data) < 11 {
+				return nil, errors.New("invalid PGS composition segment")
+			}
+			pts = segPTS
+			palette = data[9]
+			refs = refs[:0]
+			rest := data[11:]
+			for n := int(data[10]); n > 0 && len(rest) >= 8; n-- {
+				flags := rest[3]
+				refs = append(refs, pgsObjectRef{
+					id:     binary.BigEndian.Uint16(rest[0:2]),
+					x:      int(binary.BigEndian.Uint16(rest[4:6])),
+					y:      int(binary.BigEndian.Uint16(rest[6:8])),
+					forced: flags&pgsObjectForced != 0,
+				})
+				rest = rest[8:]
+				if flags&pgsObjectCropped != 0 && len(rest) >= 8 {
+					rest = rest[8:] // the crop rectangle, ignored
+				}
+			}
+		case pgsPalette:
+			if len(data) < 2 {
+				return nil, errors.New("invalid PGS palette segment")
+			}
+			p := palettes[data[0]]
+			if p == nil {
+				p = make(color.Palette, 256)
+				for i := range p {
+					p[i] = color.NRGBA{} // transparent until set
+				}
+			}
+			for e := data[2:]; len(e) >= 5; e = e[5:] {
+				r, g, bl := color.YCbCrToRGB(e[1], e[3], e[2])
+				p[e[0]] = color.NRGBA{R: r, G: g, B: bl, A: e[4]}
+			}
+			palettes[data[0]] = p
+		case pgsObject:
+			if len(data) < 4 {
+				return nil, errors.New("invalid PGS object segment")
+			}
+			id, seq, rest := binary.BigEndian.Uint16(data[0:2]), data[3], data[4:]
+			if seq&0x80 != 0 { // first fragment: data length, width and height
+				if len(rest) < 7 {
+					return nil, errors.New("invalid PGS object segment")
+				}
+				objects[id] = &pgsObjectData{
+					width:  int(binary.BigEndian.Uint16(rest[3:5])),
+					height: int(binary.BigEndian.Uint16(rest[5:7])),
+				}
+				rest = rest[7:]
+			}
+			if obj := objects[id]; obj != nil {
+				obj.rle = append(obj.rle, rest...)
+			}
+		case pgsEnd:
+			var img *image.NRGBA
+			forced := false
+			if len(refs) > 0 {
+				var err error
+				if img, forced, err = composePGS(refs, objects, palettes[palette]); err != nil {
+					return nil, fmt.Errorf("PGS display set at %s: %w", pts, err)
+				}
+			}
+			// Display sets repeating the image shown (acquisition points)
+			// don't start a new one.
+			if open >= 0 && img != nil && sameImage(bitmaps[open].Image.(*image.NRGBA), img) {
+				continue
+			}
+			if open >= 0 {
+				bitmaps[open].End = pts
+				open = -1
+			}
+			if img != nil {
+				bitmaps = append(bitmaps, Bitmap{Start: pts, Image: img, Forced: forced})
+				open = len(bitmaps) - 1
+			}
+		}
+	}
+	settleEnds(bitmaps)
+	return bitmaps, nil
+}
+
+// composePGS draws the objects of a composition with palette, on a canvas
+// that fits them; it returns nil when they are all transparent.
+func composePGS(refs []pgsObjectRef, objects map[uint16]*pgsObjectData, palette color.Palette) (*image.NRGBA, bool, error) {
+	if palette == nil {
+		return nil, false, errors.New("missing palette")
+	}
+	var (
+		bounds image.Rectangle
+		layers []*image.Paletted
+		forced = true
+	)
+	for _, ref := range refs {
+		obj := objects[ref.id]
+		if obj == nil {
+			return nil, false, fmt.Errorf("missing object %d", ref.id)
+		}
+		img, err := decodePGSRLE(obj.rle, obj.width, obj.height, palette)
+		if err != nil {
+			return nil, false, err
+		}
+		img.Rect = img.Rect.Add(image.Pt(ref.x, ref.y))
+		layers = append(layers, img)
+		bounds = bounds.Union(img.Rect)
+		forced = forced && ref.forced
+	}
+	canvas := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	visible := false
+	for _, img := range layers {
+		draw.Draw(canvas, img.Rect.Sub(bounds.Min), img, img.Rect.Min, draw.Over)
+	}
+	for i := 3; i < len(canvas.Pix); i += 4 {
+		if canvas.Pix[i] != 0 {
+			visible = true
+			break
+		}
+	}
+	if !visible {
+		return nil, false, nil
+	}
+	return canvas, forced, nil
+}
+
+// decodePGSRLE decodes the run-length encoded pixels of a PGS object: a
+// non-zero byte is a pixel of that color, and a zero byte starts a run (or
+// ends the line) as told by the next one.
+func decodePGSRLE(rle []byte, width, height int, palette color.Palette) (*image.Paletted, error) {
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	x, y := 0, 0
+	for i := 0; i < len(rle) && y < height; {
+		b := rle[i]
+		i++
+		if b != 0 {
+			if x < width {
+				img.Pix[y*img.Stride+x] = b
+			}
+			x++
+			continue
+		}
+		if i >= len(rle) {
+			break
+		}
+		flags := rle[i]
+		i++
+		if flags == 0 { // end of line
+			x, y = 0, y+1
+			continue
+		}
+		n, c := int(flags&0x3F), uint8(0)
+		if flags&0x40 != 0 {
+			if i >= len(rle) {
+				return nil, errors.New("truncated object data")
+			}
+			n = n<<8 | int(rle[i])
+			i++
+		}
+		if flags&0x80 != 0 {
+			if i >= len(rle) {
+				return nil, errors.New("truncated object data")
+			}
+			c = rle[i]
+			i++
+		}
+		for ; n > 0; n-- {
+			if x < width {
+				img.Pix[y*img.Stride+x] = c
+			}
+			x++
+		}
+	}
+	return img, nil
+}
+
+// sameImage reports whether a and b have the same size and pixels.
+func sameImage(a, b *image.NRGBA) bool {
+	return a.Rect.Size() == b.Rect.Size() && string(a.Pix) == string(b.Pix)
+}
@@ -0,0 +1,117 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+)
+
+// tesseractMargin is the white border added around each image: tesseract
+// misses text touching the edges.
+const tesseractMargin = 10
+
+// tesseractLanguages maps the ISO 639-2/B codes of naming.ContainerLanguage
+// to the names of tesseract's trained data, where they differ.
+var tesseractLanguages = map[string]string{
+	"baq": "eus", "chi": "chi_sim", "cze": "ces", "fre": "fra", "ger": "deu",
+	"gre": "ell", "ice": "isl", "may": "msa", "per": "fas", "rum": "ron",
+	"slo": "slk", "dut": "nld",
+}
+
+// lookPath is replaced in tests.
+var lookPath = exec.LookPath
+
+type tesseract struct {
+	bin      string
+	language string // trained data, e.g. "spa"
+	workDir  string
+}
+
+func newTesseract(language, workDir string) (tesseract, error) {
+	bin, err := lookPath("tesseract")
+	if err != nil {
+		return tesseract{}, fmt.Errorf("tesseract not found on PATH (install tesseract-ocr and the data of the language): %w", err)
+	}
+	return tesseract{bin: bin, language: tesseractLanguage(language), workDir: workDir}, nil
+}
+
+// tesseractLanguage returns the trained data of a language tag: "es" ->
+// "spa", "zh-Hant" -> "chi_tra"; eng when unknown.
+func tesseractLanguage(tag string) string {
+	code := naming.ContainerLanguage(tag)
+	if code == naming.Undetermined {
+		return "eng"
+	}
+	if t, ok := tesseractLanguages[code]; ok {
+		code = t
+	}
+	if code == "chi_sim" {
+		if lower := strings.ToLower(tag); strings.Contains(lower, "hant") || strings.HasSuffix(lower, "tw") || strings.HasSuffix(lower, "hk") {
+			code = "chi_tra"
+		}
+	}
+	return code
+}
+
+func (t tesseract) name() string { return EngineTesseract }
+
+func (t tesseract) recognize(ctx context.Context, images []image.Image) ([]string, error) {
+	texts := make([]string, len(images))
+	for i, img := range images {
+		path := filepath.Join(t.workDir, fmt.Sprintf("ocr-%05d.png", i+1))
+		if err := writePNG(path, forOCR(img)); err != nil {
+			return nil, err
+		}
+		// --psm 6: a single block of text.
+		cmd := exec.CommandContext(ctx, t.bin, path, "stdout", "-l", t.language, "--psm", "6")
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		slog.Debug("running OCR engine", "bin", t.bin, "image", path)
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		texts[i] = stdout.String()
+		_ = os.Remove(path)
+	}
+	return texts, nil
+}
+
+// forOCR returns img as dark text on white with a margin: subtitles are
+// light text with a dark outline over nothing, and OCR engines read ink on
+// paper best. How dark a pixel gets is its brightness times its opacity, so
+// the outline and the background both turn white.
+func forOCR(img image.Image) *image.Gray {
+	b := img.Bounds()
+	out := image.NewGray(image.Rect(0, 0, b.Dx()+2*tesseractMargin, b.Dy()+2*tesseractMargin))
+	for i := range out.Pix {
+		out.Pix[i] = 0xFF
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			luma := (299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000
+			ink := luma * uint32(c.A) / 0xFF
+			out.SetGray(x-b.Min.X+tesseractMargin, y-b.Min.Y+tesseractMargin, color.Gray{Y: uint8(0xFF - ink)})
+		}
+	}
+	return out
+}
+
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
@@ -0,0 +1,120 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/png"
+	"log/slog"
+
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+)
+
+// DefaultBatchSize is the number of images sent in each request of the vision
+// engine: more save the repeated instructions, fewer keep the model from
+// skipping or merging images.
+const DefaultBatchSize = 10
+
+// vision reads the images with a multimodal model, a batch of them per
+// request, and remembers the texts by image hash in a cache.
+type vision struct {
+	client    *translate.OpenAIClient
+	language  string
+	batchSize int
+	cache     *textCache // nil without a cache
+
+	tokens int64
+	cached int
+}
+
+func newVision(client *translate.OpenAIClient, language string, batchSize int, cacheDir string) (*vision, error) {
+	if client == nil || client.Model == "" {
+		return nil, errors.New("the vision engine needs a model that accepts images (e.g. --model gpt-4o-mini)")
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	v := &vision{client: client, language: language, batchSize: batchSize}
+	if cacheDir != "" {
+		cache, err := openTextCache(cacheDir, client.Model, language)
+		if err != nil {
+			return nil, err
+		}
+		v.cache = cache
+	}
+	return v, nil
+}
+
+func (v *vision) name() string { return EngineVision }
+
+func (v *vision) recognize(ctx context.Context, images []image.Image) ([]string, error) {
+	texts := make([]string, len(images))
+	hashes := make([]string, len(images))
+	var (
+		pending []int // the images not in the cache
+		pngs    [][]byte
+	)
+	for i, img := range images {
+		// The same dark on white images as for tesseract: models read them
+		// better than light text over transparent pixels.
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, forOCR(img)); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		hashes[i] = hex.EncodeToString(sum[:])
+		if text, ok := v.cache.lookup(hashes[i]); ok {
+			texts[i] = text
+			v.cached++
+			continue
+		}
+		pending = append(pending, i)
+		pngs = append(pngs, buf.Bytes())
+	}
+	if v.cached > 0 {
+		slog.Info("texts of subtitle images found in the cache", "images", v.cached)
+	}
+
+	for start := 0; start < len(pending); start += v.batchSize {
+		end := min(start+v.batchSize, len(pending))
+		batch, err := v.recognizeBatch(ctx, pngs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		recognized := make(map[string]string, len(batch))
+		for j, text := range batch {
+			i := pending[start+j]
+			texts[i] = text
+			recognized[hashes[i]] = text
+		}
+		if err := v.cache.store(recognized); err != nil {
+			slog.Warn("could not write the OCR cache", "err", err)
+		}
+		slog.Debug("subtitle images recognized", "done", end, "total", len(pending), "tokens", v.tokens)
+	}
+	return texts, nil
+}
+
+// recognizeBatch sends the images in a request, or halves the batch until the
+// model answers a text per image.
+func (v *vision) recognizeBatch(ctx context.Context, pngs [][]byte) ([]string, error) {
+	texts, tokens, err := v.client.RecognizeImages(ctx, v.language, pngs)
+	v.tokens += tokens
+	if !errors.Is(err, translate.ErrImageTextCount) || len(pngs) == 1 {
+		return texts, err
+	}
+	slog.Debug("splitting the batch of images", "images", len(pngs), "err", err)
+	half := len(pngs) / 2
+	first, err := v.recognizeBatch(ctx, pngs[:half])
+	if err != nil {
+		return nil, err
+	}
+	second, err := v.recognizeBatch(ctx, pngs[half:])
+	if err != nil {
+		return nil, err
+	}
+	return append(first, second...), nil
+}
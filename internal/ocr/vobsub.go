@@ -0,0 +1,325 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+)
+
+// vobSubTick is the unit of the delays of the control sequences of a
+// subpicture: 1024 ticks of the 90 kHz clock.
+const vobSubTick = 1024 * time.Second / 90000
+
+var (
+	vobSubTimestampPattern = regexp.MustCompile(`^timestamp:\s*(\d+):(\d+):(\d+):(\d+),\s*filepos:\s*([0-9a-fA-F]+)`)
+	vobSubIDPattern        = regexp.MustCompile(`^id:\s*([A-Za-z-]*),\s*index:\s*(\d+)`)
+)
+
+type vobSubEntry struct {
+	time time.Duration
+	pos  int64
+}
+
+// vobSubIndex is what readVobSub reads from the .idx: the palette and the
+// subpictures of the first track.
+type vobSubIndex struct {
+	palette  [16]color.NRGBA
+	language string
+	entries  []vobSubEntry
+	tracks   int
+}
+
+// readVobSub reads the images of the first track of a VobSub pair, the DVD
+// subtitles: base.idx holds the palette and the time and position of each
+// subpicture in base.sub, an MPEG program stream.
+func readVobSub(base string) ([]Bitmap, string, error) {
+	idxFile, err := os.Open(base + ".idx")
+	if err != nil {
+		return nil, "", fmt.Errorf("VobSub index: %w", err)
+	}
+	defer fs.CloseOrLog(idxFile, base+".idx")
+	idx, err := readVobSubIndex(idxFile)
+	if err != nil {
+		return nil, "", err
+	}
+	if idx.tracks > 1 {
+		slog.Warn("the VobSub file has several tracks; only the first one is read", "tracks", idx.tracks, "language", idx.language)
+	}
+	sub, err := os.ReadFile(base + ".sub")
+	if err != nil {
+		return nil, "", fmt.Errorf("VobSub images: %w", err)
+	}
+	var bitmaps []Bitmap
+	for _, e := range idx.entries {
+		if e.pos < 0 || e.pos >= int64(len(sub)) {
+			return nil, "", fmt.Errorf("VobSub subpicture at %s: position %d out of the .sub file", e.time, e.pos)
+		}
+		packet, err := readVobSubPacket(sub[e.pos:])
+		if err != nil {
+			return nil, "", fmt.Errorf("VobSub subpicture at %s: %w", e.time, err)
+		}
+		b, err := decodeSubpicture(packet, idx.palette)
+		if err != nil {
+			return nil, "", fmt.Errorf("VobSub subpicture at %s: %w", e.time, err)
+		}
+		if b.Image == nil {
+			continue
+		}
+		b.Start += e.time
+		if b.End > 0 {
+			b.End += e.time
+		}
+		bitmaps = append(bitmaps, b)
+	}
+	settleEnds(bitmaps)
+	return bitmaps, idx.language, nil
+}
+
+// readVobSubIndex reads a .idx file: its palette and the timestamps of the
+// first track (id: ..., index: 0); the others are ignored.
+func readVobSubIndex(r io.Reader) (vobSubIndex, error) {
+	var idx vobSubIndex
+	for i := range idx.palette {
+		idx.palette[i] = color.NRGBA{A: 0xFF}
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "palette:"):
+			for i, hex := range strings.Split(strings.TrimPrefix(line, "palette:"), ",") {
+				rgb, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 32)
+				if err != nil || i >= len(idx.palette) {
+					break
+				}
+				idx.palette[i] = color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xFF}
+			}
+		case vobSubIDPattern.MatchString(line):
+			idx.tracks++
+			if idx.tracks == 1 {
+				idx.language = vobSubIDPattern.FindStringSubmatch(line)[1]
+			}
+		case idx.tracks <= 1 && vobSubTimestampPattern.MatchString(line):
+			m := vobSubTimestampPattern.FindStringSubmatch(line)
+			var n [4]int
+			for i := range n {
+				n[i], _ = strconv.Atoi(m[i+1])
+			}
+			pos, _ := strconv.ParseInt(m[5], 16, 64)
+			t := time.Duration(n[0])*time.Hour + time.Duration(n[1])*time.Minute + time.Duration(n[2])*time.Second + time.Duration(n[3])*time.Millisecond
+			idx.entries = append(idx.entries, vobSubEntry{time: t, pos: pos})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return idx, err
+	}
+	if len(idx.entries) == 0 {
+		return idx, errors.New("no subpictures in the VobSub index")
+	}
+	return idx, nil
+}
+
+// readVobSubPacket returns the subpicture unit starting at the pack at the
+// beginning of b, joining the payloads of the PES packets it spans.
+func readVobSubPacket(b []byte) ([]byte, error) {
+	var spu []byte
+	size := -1
+	for size < 0 || len(spu) < size {
+		payload, rest, err := readPESPayload(b)
+		if err != nil {
+			return nil, err
+		}
+		b = rest
+		spu = append(spu, payload...)
+		if size < 0 && len(spu) >= 2 {
+			size = int(binary.BigEndian.Uint16(spu))
+		}
+	}
+	return spu[:size], nil
+}
+
+// readPESPayload returns the payload of the private stream packet (the
+// subpicture data, after its substream id) of the pack at the start of b,
+// and what follows the pack.
+func readPESPayload(b []byte) ([]byte, []byte, error) {
+	for {
+		if len(b) < 4 || !bytes.Equal(b[:3], []byte{0, 0, 1}) {
+			return nil, nil, errors.New("invalid MPEG stream")
+		}
+		switch b[3] {
+		case 0xBA: // pack header
+			if len(b) < 14 {
+				return nil, nil, errors.New("truncated MPEG pack header")
+			}
+			if b[4]&0xC0 == 0x40 { // MPEG-2
+				b = b[14+int(b[13]&0x07):]
+			} else {
+				b = b[12:]
+			}
+			continue
+		}
+		if len(b) < 6 {
+			return nil, nil, errors.New("truncated MPEG packet")
+		}
+		end := 6 + int(binary.BigEndian.Uint16(b[4:6]))
+		if len(b) < end {
+			return nil, nil, errors.New("truncated MPEG packet")
+		}
+		if b[3] != 0xBD { // not private stream 1, e.g. padding
+			b = b[end:]
+			continue
+		}
+		if end < 9 {
+			return nil, nil, errors.New("invalid MPEG packet")
+		}
+		start := 9 + int(b[8]) + 1 // the PES header, then the substream id
+		if start > end {
+			return nil, nil, errors.New("invalid MPEG packet")
+		}
+		return b[start:end], b[end:], nil
+	}
+}
+
+// decodeSubpicture decodes a subpicture unit: its image and, relative to
+// the timestamp of the index, its start and end.
+func decodeSubpicture(spu []byte, palette [16]color.NRGBA) (Bitmap, error) {
+	if len(spu) < 4 {
+		return Bitmap{}, errors.New("truncated subpicture")
+	}
+	var (
+		b                         Bitmap
+		colors, alphas            [4]uint8
+		x1, x2, y1, y2            int
+		topOffset, bottomOffset   int
+		hasArea, hasData, started bool
+	)
+	for pos, last := int(binary.BigEndian.Uint16(spu[2:4])), -1; pos != last; {
+		if pos+4 > len(spu) {
+			return Bitmap{}, errors.New("truncated subpicture control sequence")
+		}
+		delay := time.Duration(binary.BigEndian.Uint16(spu[pos:])) * vobSubTick
+		next := int(binary.BigEndian.Uint16(spu[pos+2:]))
+		last = pos
+		for i := pos + 4; i < len(spu) && spu[i] != 0xFF; {
+			cmd := spu[i]
+			i++
+			switch cmd {
+			case 0x00: // forced start
+				b.Forced, b.Start, started = true, delay, true
+			case 0x01: // start
+				if !started {
+					b.Start, started = delay, true
+				}
+			case 0x02: // stop
+				b.End = delay
+			case 0x03, 0x04: // colors, alphas: a nibble per pixel value, 3 first
+				if i+2 > len(spu) {
+					return Bitmap{}, errors.New("truncated subpicture command")
+				}
+				v := &colors
+				if cmd == 0x04 {
+					v = &alphas
+				}
+				v[3], v[2], v[1], v[0] = spu[i]>>4, spu[i]&0x0F, spu[i+1]>>4, spu[i+1]&0x0F
+				i += 2
+			case 0x05: // area
+				if i+6 > len(spu) {
+					return Bitmap{}, errors.New("truncated subpicture command")
+				}
+				a := spu[i : i+6]
+				x1, x2 = int(a[0])<<4|int(a[1])>>4, int(a[1]&0x0F)<<8|int(a[2])
+				y1, y2 = int(a[3])<<4|int(a[4])>>4, int(a[4]&0x0F)<<8|int(a[5])
+				hasArea = true
+				i += 6
+			case 0x06: // pixel data of the top and bottom fields
+				if i+4 > len(spu) {
+					return Bitmap{}, errors.New("truncated subpicture command")
+				}
+				topOffset, bottomOffset = int(binary.BigEndian.Uint16(spu[i:])), int(binary.BigEndian.Uint16(spu[i+2:]))
+				hasData = true
+				i += 4
+			default:
+				return Bitmap{}, fmt.Errorf("unknown subpicture command 0x%02x", cmd)
+			}
+		}
+		pos = next
+	}
+	if !hasArea || !hasData || x2 < x1 || y2 < y1 {
+		return b, nil
+	}
+	var pal color.Palette
+	for i := range 4 {
+		c := palette[colors[i]]
+		c.A = alphas[i] * 17
+		pal = append(pal, c)
+	}
+	img := image.NewPaletted(image.Rect(0, 0, x2-x1+1, y2-y1+1), pal)
+	decodeVobSubField(spu, topOffset, img, 0)
+	decodeVobSubField(spu, bottomOffset, img, 1)
+	for _, p := range img.Pix {
+		if alphas[p] != 0 {
+			b.Image = img
+			break
+		}
+	}
+	return b, nil
+}
+
+// decodeVobSubField decodes the run-length encoded lines of a field (every
+// other line, from first) of a subpicture: a run is a count and a pixel value
+// in 4 to 16 bits, a count of 0 fills the line, and lines are byte aligned.
+func decodeVobSubField(spu []byte, offset int, img *image.Paletted, first int) {
+	nibble := offset * 2
+	read := func() int {
+		if nibble/2 >= len(spu) {
+			return -1
+		}
+		b := spu[nibble/2]
+		if nibble%2 == 0 {
+			b >>= 4
+		}
+		nibble++
+		return int(b & 0x0F)
+	}
+	width := img.Rect.Dx()
+	for y := first; y < img.Rect.Dy(); y += 2 {
+		for x := 0; x < width; {
+			v := read()
+			for _, limit := range []int{0x4, 0x10, 0x40} {
+				if v < 0 {
+					return
+				}
+				if v >= limit {
+					break
+				}
+				n := read()
+				if n < 0 {
+					return
+				}
+				v = v<<4 | n
+			}
+			run, c := v>>2, uint8(v&0x3)
+			if run == 0 {
+				run = width - x
+			}
+			for ; run > 0 && x < width; run-- {
+				img.Pix[y*img.Stride+x] = c
+				x++
+			}
+		}
+		nibble += nibble % 2
+	}
+}
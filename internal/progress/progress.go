@@ -0,0 +1,304 @@
+// Package progress renders the progress of long-running commands either as a
+// terminal progress bar or as periodic log records.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output modes.
+const (
+	ModeAuto = "auto" // bar when stderr is a terminal, log records otherwise
+	ModeBar  = "bar"
+	ModeLog  = "log"
+	ModeOff  = "off"
+)
+
+const DefaultMode = ModeAuto
+
+// DefaultLogInterval is the minimum time between two log records of a task.
+const DefaultLogInterval = 10 * time.Second
+
+// barWidth is the number of cells of the drawn bar.
+const barWidth = 24
+
+// Field is an extra value shown next to the counters (e.g. "cues": "30/120").
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Snapshot is the state of a task.
+type Snapshot struct {
+	Task   string // e.g. "translate es"
+	Unit   string // unit of Done/Total, e.g. "batches"
+	Done   int
+	Total  int
+	Fields []Field
+	ETA    time.Duration // 0 when unknown
+}
+
+func (s Snapshot) finished() bool {
+	return s.Done >= s.Total
+}
+
+// Reporter displays snapshots. Implementations are safe for concurrent use.
+type Reporter interface {
+	Update(s Snapshot)
+	// Finish removes the progress display (if any) once the command is done.
+	Finish()
+}
+
+func NormalizeMode(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+func IsValidMode(mode string) bool {
+	return mode == ModeAuto || mode == ModeBar || mode == ModeLog || mode == ModeOff
+}
+
+// ResolveMode turns ModeAuto into ModeBar or ModeLog depending on whether f is
+// a terminal.
+func ResolveMode(mode string, f *os.File) string {
+	if mode != ModeAuto {
+		return mode
+	}
+	if isTerminal(f) {
+		return ModeBar
+	}
+	return ModeLog
+}
+
+func isTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Nop discards every snapshot.
+type Nop struct{}
+
+func (Nop) Update(Snapshot) {}
+func (Nop) Finish()         {}
+
+// Multi passes every snapshot to each of its reporters, in order.
+type Multi []Reporter
+
+func (m Multi) Update(s Snapshot) {
+	for _, r := range m {
+		r.Update(s)
+	}
+}
+
+func (m Multi) Finish() {
+	for _, r := range m {
+		r.Finish()
+	}
+}
+
+// Log writes a log record per task at most every interval, plus a final one
+// when the task finishes (only for tasks that already logged, so short tasks
+// stay quiet).
+type Log struct {
+	logger   *slog.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	started map[string]time.Time
+	last    map[string]time.Time
+}
+
+func NewLog(logger *slog.Logger, interval time.Duration) *Log {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = DefaultLogInterval
+	}
+	return &Log{
+		logger:   logger,
+		interval: interval,
+		now:      time.Now,
+		started:  make(map[string]time.Time),
+		last:     make(map[string]time.Time),
+	}
+}
+
+func (l *Log) Update(s Snapshot) {
+	l.mu.Lock()
+	now := l.now()
+	if _, ok := l.started[s.Task]; !ok {
+		l.started[s.Task] = now
+	}
+	last, logged := l.last[s.Task]
+	if !logged {
+		last = l.started[s.Task]
+	}
+	emit := now.Sub(last) >= l.interval || (s.finished() && logged)
+	if emit {
+		l.last[s.Task] = now
+	}
+	l.mu.Unlock()
+	if !emit {
+		return
+	}
+
+	args := []any{"task", s.Task, s.Unit, fmt.Sprintf("%d/%d", s.Done, s.Total)}
+	for _, f := range s.Fields {
+		args = append(args, f.Key, f.Value)
+	}
+	if s.ETA > 0 && !s.finished() {
+		args = append(args, "eta", FormatDuration(s.ETA))
+	}
+	l.logger.Info("progress", args...)
+}
+
+func (l *Log) Finish() {}
+
+// Bar draws a single status line on w (a terminal), one segment per task. It
+// also implements io.Writer so log output can be routed through it: the line
+// is cleared before each write and redrawn afterwards.
+type Bar struct {
+	mu    sync.Mutex
+	w     io.Writer
+	tasks []string
+	snaps map[string]Snapshot
+	drawn bool
+}
+
+func NewBar(w io.Writer) *Bar {
+	return &Bar{w: w, snaps: make(map[string]Snapshot)}
+}
+
+func (b *Bar) Update(s Snapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.snaps[s.Task]; !ok {
+		b.tasks = append(b.tasks, s.Task)
+	}
+	b.snaps[s.Task] = s
+	b.redraw()
+}
+
+func (b *Bar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	b.tasks, b.snaps = nil, make(map[string]Snapshot)
+}
+
+// Write clears the bar, writes p and draws the bar again.
+func (b *Bar) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	n, err := b.w.Write(p)
+	if err == nil && len(b.tasks) > 0 {
+		b.redraw()
+	}
+	return n, err
+}
+
+func (b *Bar) clear() {
+	if b.drawn {
+		_, _ = io.WriteString(b.w, "\r\033[K")
+		b.drawn = false
+	}
+}
+
+func (b *Bar) redraw() {
+	segments := make([]string, 0, len(b.tasks))
+	for _, task := range b.tasks {
+		segments = append(segments, renderBar(b.snaps[task]))
+	}
+	_, _ = io.WriteString(b.w, "\r\033[K"+strings.Join(segments, " | "))
+	b.drawn = true
+}
+
+// renderBar formats a snapshot as "task [=====>    ] 3/10 batches, key value, ETA 1m5s".
+func renderBar(s Snapshot) string {
+	filled := barWidth
+	if s.Total > 0 {
+		filled = min(barWidth, s.Done*barWidth/s.Total)
+	}
+	cells := strings.Repeat("=", filled)
+	if filled < barWidth {
+		cells += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	parts := []string{fmt.Sprintf("%s [%s] %d/%d %s", s.Task, cells, s.Done, s.Total, s.Unit)}
+	for _, f := range s.Fields {
+		parts = append(parts, f.Key+" "+f.Value)
+	}
+	if s.ETA > 0 && !s.finished() {
+		parts = append(parts, "ETA "+FormatDuration(s.ETA))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatDuration rounds d to whole seconds (e.g. "1m5s").
+func FormatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// FormatCount abbreviates large counts (e.g. 45200 -> "45.2k").
+func FormatCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+// Throughput estimates the remaining time from the completion rate over the
+// last samples (a rolling window), so the ETA follows rate changes such as
+// throttling. It is not safe for concurrent use.
+type Throughput struct {
+	window  int
+	samples []sample
+}
+
+type sample struct {
+	at   time.Time
+	done int
+}
+
+// NewThroughput keeps the last window samples (at least 2).
+func NewThroughput(window int) *Throughput {
+	return &Throughput{window: max(2, window)}
+}
+
+// Add records that done units (cumulative) were completed at time at.
+func (t *Throughput) Add(at time.Time, done int) {
+	t.samples = append(t.samples, sample{at: at, done: done})
+	if len(t.samples) > t.window {
+		t.samples = slices.Delete(t.samples, 0, len(t.samples)-t.window)
+	}
+}
+
+// ETA returns the estimated time to complete remaining units, or 0 when there
+// is not enough data yet.
+func (t *Throughput) ETA(remaining int) time.Duration {
+	if remaining <= 0 || len(t.samples) < 2 {
+		return 0
+	}
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	units := last.done - first.done
+	elapsed := last.at.Sub(first.at)
+	if units <= 0 || elapsed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(elapsed) / float64(units) * float64(remaining))
+}
@@ -0,0 +1,109 @@
+package progress
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThroughput_ETAUsesRollingWindow(t *testing.T) {
+	start := time.Unix(0, 0)
+	tp := NewThroughput(3)
+	if got := tp.ETA(10); got != 0 {
+		t.Fatalf("expected unknown ETA without samples, got %s", got)
+	}
+	tp.Add(start, 0)
+	tp.Add(start.Add(10*time.Second), 10) // 1 unit/s
+	if got := tp.ETA(20); got != 20*time.Second {
+		t.Fatalf("ETA = %s, want 20s", got)
+	}
+	// The rate drops to 1 unit every 2s; the first sample leaves the window.
+	tp.Add(start.Add(30*time.Second), 20)
+	tp.Add(start.Add(50*time.Second), 30)
+	if got := tp.ETA(10); got != 20*time.Second {
+		t.Fatalf("ETA = %s, want 20s", got)
+	}
+	if got := tp.ETA(0); got != 0 {
+		t.Fatalf("expected 0 when nothing remains, got %s", got)
+	}
+}
+
+func TestRenderBar(t *testing.T) {
+	got := renderBar(Snapshot{
+		Task:   "translate es",
+		Unit:   "batches",
+		Done:   3,
+		Total:  12,
+		Fields: []Field{{Key: "cues", Value: "30/120"}},
+		ETA:    65 * time.Second,
+	})
+	want := "translate es [======>                 ] 3/12 batches, cues 30/120, ETA 1m5s"
+	if got != want {
+		t.Fatalf("renderBar:\n got %q\nwant %q", got, want)
+	}
+	if got := renderBar(Snapshot{Task: "fix", Unit: "steps", Done: 3, Total: 3, ETA: time.Second}); strings.Contains(got, "ETA") || !strings.Contains(got, "["+strings.Repeat("=", barWidth)+"]") {
+		t.Fatalf("unexpected finished bar: %q", got)
+	}
+}
+
+func TestBar_WriteRedrawsAfterLogOutput(t *testing.T) {
+	var out bytes.Buffer
+	bar := NewBar(&out)
+	bar.Update(Snapshot{Task: "fix", Unit: "steps", Done: 1, Total: 3})
+	if _, err := bar.Write([]byte("log line\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	bar.Finish()
+
+	got := out.String()
+	if !strings.Contains(got, "\r\033[Klog line\n\r\033[Kfix [") {
+		t.Fatalf("expected the bar to be cleared before the log line and redrawn after it, got %q", got)
+	}
+	if !strings.HasSuffix(got, "\r\033[K") {
+		t.Fatalf("expected Finish to clear the bar, got %q", got)
+	}
+}
+
+func TestLog_ThrottlesRecords(t *testing.T) {
+	var out bytes.Buffer
+	l := NewLog(slog.New(slog.NewTextHandler(&out, nil)), 10*time.Second)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	l.Update(Snapshot{Task: "translate es", Unit: "batches", Done: 0, Total: 3})
+	now = now.Add(5 * time.Second)
+	l.Update(Snapshot{Task: "translate es", Unit: "batches", Done: 1, Total: 3})
+	if out.Len() != 0 {
+		t.Fatalf("expected no record before the interval, got %q", out.String())
+	}
+	now = now.Add(6 * time.Second)
+	l.Update(Snapshot{Task: "translate es", Unit: "batches", Done: 2, Total: 3, ETA: 5 * time.Second})
+	if !strings.Contains(out.String(), "batches=2/3") || !strings.Contains(out.String(), "eta=5s") {
+		t.Fatalf("unexpected record: %q", out.String())
+	}
+	out.Reset()
+	now = now.Add(time.Second)
+	l.Update(Snapshot{Task: "translate es", Unit: "batches", Done: 3, Total: 3})
+	if !strings.Contains(out.String(), "batches=3/3") {
+		t.Fatalf("expected a final record, got %q", out.String())
+	}
+
+	// A task finishing within the interval stays quiet.
+	out.Reset()
+	l.Update(Snapshot{Task: "fix", Unit: "steps", Done: 0, Total: 1})
+	l.Update(Snapshot{Task: "fix", Unit: "steps", Done: 1, Total: 1})
+	if out.Len() != 0 {
+		t.Fatalf("expected no record for a short task, got %q", out.String())
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	cases := map[int64]string{0: "0", 9999: "9999", 45_200: "45.2k", 1_250_000: "1.2M"}
+	for n, want := range cases {
+		if got := FormatCount(n); got != want {
+			t.Fatalf("FormatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package retranslate patches new translations of some cues into an existing
+// translation: the cues are picked from a report or a list of indexes, their
+// text is found in the file they were translated from, and only their text
+// lines are replaced, so the rest of the file is kept byte for byte.
+package retranslate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/review"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Selection returns the cues of the translation at path picked by spec: the
+// path of a report read by review.LoadFlags (validate --format json, or the
+// review or length report of translate), or a comma-separated list of
+// indexes and ranges as parsed by srt.ParseIdxRanges.
+func Selection(spec, path string) (srt.Selection, error) {
+	if _, err := os.Stat(spec); err != nil {
+		idxs, err := srt.ParseIdxRanges(spec)
+		if err != nil {
+			return srt.Selection{}, err
+		}
+		if len(idxs) == 0 {
+			return srt.Selection{}, errors.New("no cue to translate again")
+		}
+		return srt.Selection{Idxs: idxs}, nil
+	}
+	flags, err := review.LoadFlags(spec, path)
+	if err != nil {
+		return srt.Selection{}, err
+	}
+	if len(flags) == 0 {
+		return srt.Selection{}, fmt.Errorf("%s: no flagged cue to translate again", spec)
+	}
+	var sel srt.Selection
+	for _, f := range flags {
+		sel.Idxs = append(sel.Idxs, srt.IdxRange{From: f.Idx, To: f.Idx})
+	}
+	return sel, nil
+}
+
+// SourceCues returns the cue of source each cue of translation selected by
+// sel was translated from, numbered as in translation. A cue is matched by
+// index when the timing agrees, otherwise by timing, and falls back to the
+// index when no source cue has its timing (e.g. the translation was shifted).
+func SourceCues(translation, source []*srt.Subtitle, sel srt.Selection) ([]*srt.Subtitle, error) {
+	byIdx := make(map[int]*srt.Subtitle, len(source))
+	byTiming := make(map[[2]int64]*srt.Subtitle, len(source))
+	for _, s := range source {
+		byIdx[s.Idx] = s
+		key := [2]int64{int64(s.FromTime), int64(s.ToTime)}
+		if _, ok := byTiming[key]; !ok {
+			byTiming[key] = s
+		}
+	}
+
+	var cues []*srt.Subtitle
+	for _, t := range translation {
+		if !sel.Matches(t) {
+			continue
+		}
+		s, ok := byIdx[t.Idx]
+		if !ok || s.FromTime != t.FromTime || s.ToTime != t.ToTime {
+			if m, found := byTiming[[2]int64{int64(t.FromTime), int64(t.ToTime)}]; found {
+				s, ok = m, true
+			} else if ok {
+				slog.Warn("no source cue with the timing of the cue; matched by index", "idx", t.Idx)
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("cue %d: no cue of the source has its index or timing", t.Idx)
+		}
+		c := *s
+		c.Idx, c.FromTime, c.ToTime = t.Idx, t.FromTime, t.ToTime
+		cues = append(cues, &c)
+	}
+	if len(cues) == 0 {
+		return nil, srt.ErrNoSelectedCues
+	}
+	return cues, nil
+}
+
+type Options struct {
+	InputPath string // the translation to patch
+	// Texts are the new translations by cue index of the input. Empty texts
+	// are skipped.
+	Texts        map[int]string
+	OutputPath   string // empty means overwriting the input
+	BackupExt    string
+	CreateBackup bool
+}
+
+type Result struct {
+	WrittenPath string
+	Patched     int // cues whose text was replaced
+}
+
+// Patch replaces the text of the cues of opts.InputPath found in opts.Texts,
+// copying everything else from the input as is, and writes the result.
+func Patch(opts Options) (Result, error) {
+	content, err := os.ReadFile(opts.InputPath)
+	if err != nil {
+		return Result{}, err
+	}
+	patched, n := patchCues(string(content), opts.Texts)
+
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = opts.InputPath
+	}
+	if opts.CreateBackup && fs.SameFilePath(outputPath, opts.InputPath) {
+		backupPath := opts.InputPath + opts.BackupExt
+		_ = os.Remove(backupPath)
+		if err := fs.CopyFile(opts.InputPath, backupPath); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := fs.WriteFile(strings.NewReader(patched), outputPath); err != nil {
+		return Result{}, err
+	}
+	return Result{WrittenPath: outputPath, Patched: n}, nil
+}
+
+// patchCues replaces the text lines of the cues of content found in texts and
+// returns the new content and how many cues were patched. Cues are numbered as
+// in srt.Read: a cue named by an identifier gets the index after the previous
+// cue. The replaced lines take the line ending of the timing line.
+func patchCues(content string, texts map[int]string) (string, int) {
+	var b strings.Builder
+	var block []string
+	prevIdx, patched := 0, 0
+	flush := func() {
+		defer func() { block = block[:0] }()
+		raw := strings.Join(block, "")
+		if strings.TrimSpace(raw) == "" {
+			b.WriteString(raw)
+			return
+		}
+		subs, err := srt.ReadAll(strings.NewReader(raw))
+		if err != nil || len(subs) != 1 {
+			b.WriteString(raw)
+			return
+		}
+		if subs[0].ID != "" {
+			subs[0].Idx = prevIdx + 1
+		}
+		prevIdx = subs[0].Idx
+		text, ok := texts[subs[0].Idx]
+		timing := timingLine(block)
+		if !ok || strings.TrimSpace(text) == "" || timing < 0 {
+			b.WriteString(raw)
+			return
+		}
+		newline := lineEnding(block[timing])
+		if newline == "" {
+			newline = "\n"
+		}
+		for _, line := range block[:timing] {
+			b.WriteString(line)
+		}
+		b.WriteString(strings.TrimRight(block[timing], "\r\n") + newline)
+		b.WriteString(strings.ReplaceAll(strings.TrimSpace(text), "\n", newline))
+		b.WriteString(lineEnding(block[len(block)-1]))
+		patched++
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Split(scanLinesWithEndings)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimRight(line, "\r\n") == "" {
+			flush()
+			b.WriteString(line)
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+	return b.String(), patched
+}
+
+// timingLine returns the position of the timing line of a cue block (-1 when
+// there is none).
+func timingLine(block []string) int {
+	for i, line := range block {
+		if strings.Contains(line, "-->") {
+			return i
+		}
+	}
+	return -1
+}
+
+// lineEnding returns the line ending of line ("" for the last line of a file
+// without one).
+func lineEnding(line string) string {
+	return line[len(strings.TrimRight(line, "\r\n")):]
+}
+
+// scanLinesWithEndings is bufio.ScanLines keeping the line endings.
+func scanLinesWithEndings(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
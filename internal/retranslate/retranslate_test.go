@@ -0,0 +1,99 @@
+package retranslate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestSelection(t *testing.T) {
+	dir := t.TempDir()
+	translation := filepath.Join(dir, "out.srt")
+	report := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(report, []byte(`{"target_language":"es","max_cps":17,"violations":[{"idx":2,"text":"Adios","cps":20,"max_line_len":5,"shortened":false}]}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	sel, err := Selection(report, translation)
+	if err != nil {
+		t.Fatalf("Selection(report): %v", err)
+	}
+	if len(sel.Idxs) != 1 || sel.Idxs[0] != (srt.IdxRange{From: 2, To: 2}) {
+		t.Fatalf("unexpected selection from the report: %+v", sel)
+	}
+	if sel, err = Selection("1,3-", translation); err != nil || len(sel.Idxs) != 2 {
+		t.Fatalf("Selection(list) = %+v, %v", sel, err)
+	}
+	if _, err := Selection("", translation); err == nil {
+		t.Fatalf("expected an error for an empty list")
+	}
+}
+
+func TestSourceCues(t *testing.T) {
+	source, err := srt.ReadAll(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\n[door slams]\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\nBye\n"))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	// The sound-only cue was left out of the translation, which is numbered
+	// again from 1.
+	translation, err := srt.ReadAll(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nHola\n\n" +
+		"2\n00:00:05,000 --> 00:00:06,000\nChau\n"))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	cues, err := SourceCues(translation, source, srt.Selection{Idxs: []srt.IdxRange{{From: 2, To: 2}}})
+	if err != nil {
+		t.Fatalf("SourceCues: %v", err)
+	}
+	if len(cues) != 1 || cues[0].Idx != 2 || cues[0].Text != "Bye" {
+		t.Fatalf("unexpected source cues: %+v", cues)
+	}
+	if _, err := SourceCues(translation, source, srt.Selection{Idxs: []srt.IdxRange{{From: 9}}}); err == nil {
+		t.Fatalf("expected an error when no cue is selected")
+	}
+}
+
+func TestPatch(t *testing.T) {
+	orig := "\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\n<i>Hola</i>  \r\n\r\n" +
+		"NOTE kept as is\r\n\r\n" +
+		"intro\r\n00:00:03,000 --> 00:00:04,000\r\nAdios\r\namigo\r\n\r\n" +
+		"3\r\n00:00:05,000 --> 00:00:06,000\r\nGracias"
+	dir := t.TempDir()
+	input := filepath.Join(dir, "out.srt")
+	if err := os.WriteFile(input, []byte(orig), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	res, err := Patch(Options{
+		InputPath:    input,
+		Texts:        map[int]string{2: "Chau,\namigo", 3: "Muchas gracias", 9: "not in the file"},
+		BackupExt:    ".bak",
+		CreateBackup: true,
+	})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if res.Patched != 2 || res.WrittenPath != input {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	b, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\n<i>Hola</i>  \r\n\r\n" +
+		"NOTE kept as is\r\n\r\n" +
+		"intro\r\n00:00:03,000 --> 00:00:04,000\r\nChau,\r\namigo\r\n\r\n" +
+		"3\r\n00:00:05,000 --> 00:00:06,000\r\nMuchas gracias"
+	if string(b) != want {
+		t.Fatalf("unexpected output:\n got %q\nwant %q", b, want)
+	}
+	if b, err := os.ReadFile(input + ".bak"); err != nil || string(b) != orig {
+		t.Fatalf("expected the original in the backup, got %q (%v)", b, err)
+	}
+}
@@ -0,0 +1,467 @@
+// Package review steps through the cues of a subtitle file flagged by a report
+// (validate, the translate review or length report) in the terminal, showing
+// their source and text and letting them be kept, edited or deleted before
+// saving the file back.
+package review
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+	"github.com/adrianmusante/subtitle-tools/internal/validate"
+)
+
+// editEnd ends the text typed for an edit.
+const editEnd = "."
+
+// Flag is a problem a report found in a cue.
+type Flag struct {
+	Idx   int    // cue index as written in the file
+	Issue string // what is wrong, e.g. "[max-cps] 24.0 characters per second"
+	// Source is the text the cue was translated from, when the report has it.
+	Source string
+	// Suggestion is a replacement text proposed by the report (the
+	// corrections of translate --review report).
+	Suggestion string
+}
+
+// LoadFlags reads the flagged cues of inputPath from the report at path: the
+// JSON written by validate --format json, or the review or length report of
+// translate. A validate report of several files must have an entry for
+// inputPath.
+func LoadFlags(path, inputPath string) ([]Flag, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if bytes.HasPrefix(b, []byte("[")) {
+		var reports []validate.Report
+		if err := json.Unmarshal(b, &reports); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		report, err := reportOf(reports, inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return validateFlags(report), nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	switch {
+	case keys["flagged"] != nil:
+		var report translate.ReviewReport
+		if err := json.Unmarshal(b, &report); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		return reviewFlags(report), nil
+	case keys["violations"] != nil && keys["path"] != nil:
+		var report validate.Report
+		if err := json.Unmarshal(b, &report); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		return validateFlags(report), nil
+	case keys["violations"] != nil:
+		var report translate.LengthReport
+		if err := json.Unmarshal(b, &report); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		return lengthFlags(report), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown report (expected the JSON of validate, or a translate review or length report)", path)
+	}
+}
+
+// reportOf returns the report of inputPath, or the only one.
+func reportOf(reports []validate.Report, inputPath string) (validate.Report, error) {
+	if len(reports) == 1 {
+		return reports[0], nil
+	}
+	for _, r := range reports {
+		if p, err := fs.ResolveAbsPath(r.Path); err == nil && fs.SameFilePath(p, inputPath) {
+			return r, nil
+		}
+	}
+	return validate.Report{}, fmt.Errorf("no report of %s", inputPath)
+}
+
+func validateFlags(report validate.Report) []Flag {
+	var flags []Flag
+	for _, v := range report.Violations {
+		if v.Position == 0 { // a problem of the file, not of a cue
+			continue
+		}
+		flags = append(flags, Flag{Idx: v.Idx, Issue: fmt.Sprintf("[%s] %s", v.Rule, v.Message)})
+	}
+	return flags
+}
+
+func reviewFlags(report translate.ReviewReport) []Flag {
+	flags := make([]Flag, 0, len(report.Flagged))
+	for _, f := range report.Flagged {
+		flag := Flag{Idx: f.Idx, Issue: "[review] " + f.Issue, Source: f.Source}
+		if !f.Applied {
+			flag.Suggestion = f.Correction
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+func lengthFlags(report translate.LengthReport) []Flag {
+	flags := make([]Flag, 0, len(report.Violations))
+	for _, v := range report.Violations {
+		flags = append(flags, Flag{
+			Idx:   v.Idx,
+			Issue: fmt.Sprintf("[length] %.1f characters per second, longest line %d characters", v.CPS, v.MaxLineLen),
+		})
+	}
+	return flags
+}
+
+type Options struct {
+	InputPath string
+	Flags     []Flag
+	// SourcePath is the file the input was translated from, to show the
+	// source of the cues whose flag doesn't have it (optional).
+	SourcePath   string
+	OutputPath   string // empty means overwriting the input
+	BackupExt    string
+	CreateBackup bool
+	// In and Out are the terminal: the commands are read from In and the
+	// cues written to Out.
+	In  io.Reader
+	Out io.Writer
+}
+
+type Result struct {
+	WrittenPath string // empty when nothing was saved
+	Flagged     int    // flagged cues found in the input
+	Reviewed    int    // flagged cues shown
+	Edited      int
+	Deleted     int
+}
+
+// item is a flagged cue.
+type item struct {
+	cue        *srt.Subtitle
+	issues     []string
+	source     string
+	suggestion string
+	original   string
+	deleted    bool
+}
+
+// Run steps through the flagged cues of opts.InputPath, reading a command for
+// each from opts.In, and saves the changes when the last cue is reviewed or
+// the review is ended with the quit command.
+func Run(opts Options) (Result, error) {
+	b, err := os.ReadFile(opts.InputPath)
+	if err != nil {
+		return Result{}, err
+	}
+	doc, err := srt.Read(bytes.NewReader(b))
+	if err != nil {
+		return Result{}, fmt.Errorf("parse %s: %w", opts.InputPath, err)
+	}
+	for _, w := range doc.Warnings {
+		slog.Warn("subtitles file read with a warning", "input_path", opts.InputPath, "warning", w)
+	}
+	sources, err := readSources(opts.SourcePath)
+	if err != nil {
+		return Result{}, err
+	}
+
+	items := collectItems(doc.Cues, opts.Flags, sources)
+	res := Result{Flagged: len(items)}
+	if len(items) == 0 {
+		return res, nil
+	}
+
+	s := &session{in: bufio.NewReader(opts.In), out: opts.Out, items: items}
+	save, err := s.run()
+	if err != nil {
+		return Result{}, err
+	}
+	res.Reviewed = s.reviewed
+	for _, it := range items {
+		switch {
+		case it.deleted:
+			res.Deleted++
+		case it.cue.Text != it.original:
+			res.Edited++
+		}
+	}
+	if !save || res.Edited+res.Deleted == 0 {
+		return res, nil
+	}
+
+	cues := make([]*srt.Subtitle, 0, len(doc.Cues))
+	deleted := make(map[*srt.Subtitle]bool)
+	for _, it := range items {
+		deleted[it.cue] = it.deleted
+	}
+	for _, c := range doc.Cues {
+		if !deleted[c] {
+			cues = append(cues, c)
+		}
+	}
+	if len(cues) == 0 {
+		return Result{}, errors.New("every cue was deleted; nothing to save")
+	}
+	var buf bytes.Buffer
+	if err := srt.Write(&buf, doc.WithCues(cues), false); err != nil {
+		return Result{}, err
+	}
+
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = opts.InputPath
+	}
+	if opts.CreateBackup && fs.SameFilePath(outputPath, opts.InputPath) {
+		backupPath := opts.InputPath + opts.BackupExt
+		_ = os.Remove(backupPath)
+		if err := fs.CopyFile(opts.InputPath, backupPath); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := fs.WriteFile(&buf, outputPath); err != nil {
+		return Result{}, err
+	}
+	res.WrittenPath = outputPath
+	return res, nil
+}
+
+// readSources returns the text of the cues of path by index (nil without
+// path).
+func readSources(path string) (map[int]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := srt.Read(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	sources := make(map[int]string, len(doc.Cues))
+	for _, c := range doc.Cues {
+		sources[c.Idx] = c.Text
+	}
+	return sources, nil
+}
+
+// collectItems groups flags by cue, in the order of the cues. Flags of cues
+// that aren't in cues are logged and skipped.
+func collectItems(cues []*srt.Subtitle, flags []Flag, sources map[int]string) []*item {
+	byIdx := make(map[int]*srt.Subtitle, len(cues))
+	for _, c := range cues {
+		byIdx[c.Idx] = c
+	}
+	items := make(map[int]*item)
+	for _, f := range flags {
+		cue, ok := byIdx[f.Idx]
+		if !ok {
+			slog.Warn("flagged cue not found in the subtitles; skipping it", "idx", f.Idx, "issue", f.Issue)
+			continue
+		}
+		it := items[f.Idx]
+		if it == nil {
+			it = &item{cue: cue, source: sources[f.Idx], original: cue.Text}
+			items[f.Idx] = it
+		}
+		it.issues = append(it.issues, f.Issue)
+		if f.Source != "" {
+			it.source = f.Source
+		}
+		if f.Suggestion != "" {
+			it.suggestion = f.Suggestion
+		}
+	}
+	out := make([]*item, 0, len(items))
+	for _, it := range items {
+		out = append(out, it)
+	}
+	slices.SortFunc(out, func(a, b *item) int {
+		return cmp.Or(cmp.Compare(a.cue.FromTime, b.cue.FromTime), cmp.Compare(a.cue.Idx, b.cue.Idx))
+	})
+	return out
+}
+
+// session is the interactive review of items.
+type session struct {
+	in       *bufio.Reader
+	out      io.Writer
+	items    []*item
+	reviewed int // highest position shown, from 1
+}
+
+const help = `Commands:
+  k, Enter  keep the cue as it is and go to the next one
+  e         edit the text: type the new lines and end with a line with a single "."
+  a         accept the suggestion
+  d         delete the cue
+  u         undo the changes to the cue
+  p         go back to the previous cue
+  q         save the changes and quit
+  x         quit without saving
+`
+
+// run shows the items and reads the commands until the last item or a quit
+// command, and reports whether the changes are to be saved. The end of the
+// input saves them, as q does.
+func (s *session) run() (bool, error) {
+	s.printf("%d flagged cues. Type ? for help.\n", len(s.items))
+	for i := 0; i < len(s.items); {
+		it := s.items[i]
+		s.reviewed = max(s.reviewed, i+1)
+		s.show(i, it)
+		line, eof, err := s.prompt("[k]eep [e]dit [a]ccept [d]elete [p]revious [q]uit [?] > ")
+		if err != nil {
+			return false, err
+		}
+		if eof {
+			return true, nil
+		}
+		switch line {
+		case "", "k":
+			i++
+		case "e":
+			text, ok, err := s.readText()
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				it.cue.Text = text
+				it.deleted = false
+				i++
+			}
+		case "a":
+			if it.suggestion == "" {
+				s.printf("No suggestion for this cue.\n")
+				continue
+			}
+			it.cue.Text = it.suggestion
+			it.deleted = false
+			i++
+		case "d":
+			it.deleted = true
+			i++
+		case "u":
+			it.deleted = false
+			it.cue.Text = it.original
+		case "p":
+			if i > 0 {
+				i--
+			}
+		case "q":
+			return true, nil
+		case "x":
+			return false, nil
+		case "?", "h":
+			s.printf("%s", help)
+		default:
+			s.printf("Unknown command %q. Type ? for help.\n", line)
+		}
+	}
+	return true, nil
+}
+
+// show writes the position, timing, issues and texts of it.
+func (s *session) show(i int, it *item) {
+	s.printf("\n[%d/%d] #%d %s --> %s\n", i+1, len(s.items), it.cue.Idx, srt.FormatTime(it.cue.FromTime), srt.FormatTime(it.cue.ToTime))
+	for _, issue := range it.issues {
+		s.printf("  %s\n", issue)
+	}
+	if it.source != "" {
+		s.printf("%s", block("source", it.source))
+	}
+	text := it.cue.Text
+	if it.deleted {
+		text += "\n(deleted)"
+	}
+	s.printf("%s", block("text", text))
+	if it.suggestion != "" {
+		s.printf("%s", block("suggestion", it.suggestion))
+	}
+}
+
+// block formats a labeled text, indenting its lines under the first one.
+func block(label, text string) string {
+	const width = len("suggestion: ")
+	var b strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i == 0 {
+			_, _ = fmt.Fprintf(&b, "%-*s%s\n", width, label+":", line)
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "%-*s%s\n", width, "", line)
+	}
+	return b.String()
+}
+
+// readText reads the lines of an edit, up to a line with editEnd. An empty
+// edit is discarded.
+func (s *session) readText() (string, bool, error) {
+	s.printf("New text (end with a line with a single %q; an empty text cancels):\n", editEnd)
+	var lines []string
+	for {
+		line, eof, err := s.readLine()
+		if err != nil {
+			return "", false, err
+		}
+		if line == editEnd || (eof && line == "") {
+			break
+		}
+		lines = append(lines, line)
+		if eof {
+			break
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		s.printf("Edit canceled.\n")
+		return "", false, nil
+	}
+	return text, true, nil
+}
+
+func (s *session) prompt(p string) (string, bool, error) {
+	s.printf("%s", p)
+	line, eof, err := s.readLine()
+	return strings.ToLower(strings.TrimSpace(line)), eof && line == "", err
+}
+
+// readLine reads a line without its line break, and reports the end of the
+// input.
+func (s *session) readLine() (string, bool, error) {
+	line, err := s.in.ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		return strings.TrimRight(line, "\r\n"), true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimRight(line, "\r\n"), false, nil
+}
+
+func (s *session) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(s.out, format, args...)
+}
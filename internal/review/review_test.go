@@ -0,0 +1,140 @@
+package review
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const threeCues = "1\n00:00:01,000 --> 00:00:02,000\nHola\n\n" +
+	"2\n00:00:03,000 --> 00:00:04,000\nAdios\n\n" +
+	"3\n00:00:05,000 --> 00:00:06,000\nGracias\n"
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadFlags(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, dir, "out.srt", threeCues)
+
+	tests := []struct {
+		name   string
+		report string
+		want   []Flag
+	}{
+		{
+			name:   "validate",
+			report: `[{"path":"out.srt","cues":3,"violations":[{"rule":"parse-error","message":"bad"},{"rule":"max-cps","position":2,"idx":2,"time":"00:00:03,000","message":"too fast"}]}]`,
+			want:   []Flag{{Idx: 2, Issue: "[max-cps] too fast"}},
+		},
+		{
+			name:   "translate review",
+			report: `{"target_language":"es","mode":"report","reviewed":3,"flagged":[{"idx":1,"source":"Hello","translation":"Hola","issue":"register","correction":"Buenas","applied":false},{"idx":3,"source":"Thanks","translation":"Gracias","issue":"typo","correction":"Gracias","applied":true}]}`,
+			want: []Flag{
+				{Idx: 1, Issue: "[review] register", Source: "Hello", Suggestion: "Buenas"},
+				{Idx: 3, Issue: "[review] typo", Source: "Thanks"},
+			},
+		},
+		{
+			name:   "length",
+			report: `{"target_language":"es","max_cps":17,"violations":[{"idx":2,"text":"Adios","cps":20,"max_line_len":5,"shortened":false}]}`,
+			want:   []Flag{{Idx: 2, Issue: "[length] 20.0 characters per second, longest line 5 characters"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, dir, "report.json", tt.report)
+			got, err := LoadFlags(path, input)
+			if err != nil {
+				t.Fatalf("LoadFlags: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("flag %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	path := writeFile(t, dir, "report.json", `{"cues":3}`)
+	if _, err := LoadFlags(path, input); err == nil {
+		t.Fatalf("expected an error for an unknown report")
+	}
+}
+
+func TestRun_EditsAndSaves(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, dir, "out.srt", threeCues)
+	flags := []Flag{
+		{Idx: 3, Issue: "[review] typo", Suggestion: "Muchas gracias"},
+		{Idx: 1, Issue: "[review] register", Source: "Hello"},
+		{Idx: 2, Issue: "[max-cps] too fast"},
+		{Idx: 9, Issue: "[max-cps] not in the file"},
+	}
+	// Cue 1: edited in two lines; cue 2: deleted; cue 3: suggestion accepted.
+	in := strings.NewReader("e\nBuenas\ntardes\n.\nd\na\n")
+	var out bytes.Buffer
+
+	res, err := Run(Options{
+		InputPath:    input,
+		Flags:        flags,
+		BackupExt:    ".bak",
+		CreateBackup: true,
+		In:           in,
+		Out:          &out,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Flagged != 3 || res.Reviewed != 3 || res.Edited != 2 || res.Deleted != 1 || res.WrittenPath != input {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if !strings.Contains(out.String(), "[1/3] #1 00:00:01,000 --> 00:00:02,000") || !strings.Contains(out.String(), "source:     Hello") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+
+	b, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,000\nBuenas\ntardes\n\n" +
+		"2\n00:00:05,000 --> 00:00:06,000\nMuchas gracias\n\n"
+	if string(b) != want {
+		t.Fatalf("unexpected output file:\n%s", b)
+	}
+	if b, err := os.ReadFile(input + ".bak"); err != nil || string(b) != threeCues {
+		t.Fatalf("expected the original in the backup, got %q (%v)", b, err)
+	}
+}
+
+func TestRun_QuitWithoutSaving(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, dir, "out.srt", threeCues)
+
+	res, err := Run(Options{
+		InputPath: input,
+		Flags:     []Flag{{Idx: 1, Issue: "a"}, {Idx: 2, Issue: "b"}},
+		In:        strings.NewReader("d\nx\n"),
+		Out:       &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.WrittenPath != "" || res.Reviewed != 2 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if b, _ := os.ReadFile(input); string(b) != threeCues {
+		t.Fatalf("input changed:\n%s", b)
+	}
+}
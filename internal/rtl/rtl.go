@@ -0,0 +1,138 @@
+// Package rtl handles the text direction of subtitles in right-to-left
+// languages (Arabic, Hebrew, Persian...), which many players show with
+// the punctuation on the wrong side unless the text carries direction marks.
+package rtl
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// RLM is the right-to-left mark: an invisible character with a right-to-left
+// direction, which sets the direction of the punctuation next to it.
+const RLM = '\u200f'
+
+// languages are the ISO 639-1 (and 639-3, without a 639-1 code) codes of the
+// languages written right to left.
+var languages = map[string]bool{
+	"ar": true, "he": true, "iw": true, "fa": true, "ur": true, "yi": true, "ps": true, "sd": true,
+	"ug": true, "dv": true, "ckb": true,
+}
+
+// IsLanguage reports whether the language tag (e.g. "he", "ara", "fa-IR") is
+// written right to left.
+func IsLanguage(tag string) bool {
+	primary, _, _ := strings.Cut(strings.ToLower(naming.ShortLanguage(tag)), "-")
+	return languages[primary]
+}
+
+// isRTL reports whether r is a letter written right to left.
+func isRTL(r rune) bool {
+	return unicode.IsLetter(r) && unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// isLTR reports whether r is a letter written left to right.
+func isLTR(r rune) bool {
+	return unicode.IsLetter(r) && !isRTL(r)
+}
+
+// HasRTL reports whether text has right-to-left letters.
+func HasRTL(text string) bool {
+	return strings.ContainsFunc(text, isRTL)
+}
+
+// Issue is a line of a cue that may be shown wrong.
+type Issue struct {
+	Line int    // 1-based
+	Text string // the visible text of the line
+}
+
+// NeedsMarks reports whether a line with right-to-left text starts or ends
+// with something else (punctuation, digits, a dialogue dash or a word written
+// left to right) and has no direction controls: players that lay lines out
+// left to right show those on the wrong side.
+func NeedsMarks(line string) bool {
+	if strings.ContainsFunc(line, srt.IsBidiControl) {
+		return false
+	}
+	visible := strings.TrimSpace(srt.VisibleText(line))
+	if !HasRTL(visible) {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(visible)
+	last, _ := utf8.DecodeLastRuneInString(visible)
+	return !isRTL(first) || !isRTL(last) || strings.ContainsFunc(visible, isLTR)
+}
+
+// AddMarks puts an RLM at the start and the end of every line of text that
+// needs them (see NeedsMarks), so the punctuation and words at the edges
+// stay on the side they belong to. It returns text unchanged when no line
+// needs them.
+func AddMarks(text string) string {
+	lines := strings.Split(text, "\n")
+	changed := false
+	for i, line := range lines {
+		if NeedsMarks(line) {
+			lines[i] = string(RLM) + line + string(RLM)
+			changed = true
+		}
+	}
+	if !changed {
+		return text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MissingMarks returns the lines of text that need direction marks (see
+// NeedsMarks).
+func MissingMarks(text string) []Issue {
+	var issues []Issue
+	for i, line := range strings.Split(text, "\n") {
+		if NeedsMarks(line) {
+			issues = append(issues, Issue{Line: i + 1, Text: strings.TrimSpace(srt.VisibleText(line))})
+		}
+	}
+	return issues
+}
+
+// closingPunctuation ends a sentence or a clause: found at the start of a
+// right-to-left line, it was likely stored in display order. A leading
+// ellipsis is left out, as it also marks a sentence continued from the
+// previous cue.
+const closingPunctuation = ".,!?:;…،؛؟"
+
+// ReversedPunctuation returns the lines of text whose punctuation looks
+// stored in display order instead of reading order, as left by some
+// converters and OCR tools: closing punctuation before the first word
+// ("!שלום") or a dialogue dash after the last one ("שלום -").
+func ReversedPunctuation(text string) []Issue {
+	var issues []Issue
+	for i, line := range strings.Split(text, "\n") {
+		visible := strings.TrimSpace(srt.VisibleText(line))
+		if !HasRTL(visible) {
+			continue
+		}
+		lead := visible[:strings.IndexFunc(visible, unicode.IsLetter)]
+		lead = strings.NewReplacer("...", "", "…", "").Replace(lead)
+		if strings.ContainsAny(lead, closingPunctuation) || endsWithDash(visible) {
+			issues = append(issues, Issue{Line: i + 1, Text: visible})
+		}
+	}
+	return issues
+}
+
+// endsWithDash reports whether line ends with a dialogue dash, apart from
+// the text before it ("שלום -").
+func endsWithDash(line string) bool {
+	for _, dash := range []string{" -", " —"} {
+		if rest, ok := strings.CutSuffix(line, dash); ok {
+			r, _ := utf8.DecodeLastRuneInString(strings.TrimRightFunc(rest, unicode.IsSpace))
+			return isRTL(r)
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package rtl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsLanguage(t *testing.T) {
+	for tag, want := range map[string]bool{"he": true, "ara": true, "fa-IR": true, "es": false, "en-US": false, "": false} {
+		if got := IsLanguage(tag); got != want {
+			t.Errorf("IsLanguage(%q)=%v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestAddMarks(t *testing.T) {
+	cases := map[string]string{
+		"שלום עולם":                 "שלום עולם",
+		"- שלום.\n<i>מה שלומך?</i>": "\u200f- שלום.\u200f\n\u200f<i>מה שלומך?</i>\u200f",
+		"אני אוהב Netflix ביום":     "\u200fאני אוהב Netflix ביום\u200f",
+		"Hello.":                    "Hello.",
+		"\u200fשלום.\u200f":         "\u200fשלום.\u200f",
+	}
+	for in, want := range cases {
+		if got := AddMarks(in); got != want {
+			t.Errorf("AddMarks(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReversedPunctuation(t *testing.T) {
+	got := ReversedPunctuation("!שלום\nמה שלומך?\n...ואז\nבוא נלך -\nHello -")
+	want := []Issue{{Line: 1, Text: "!שלום"}, {Line: 4, Text: "בוא נלך -"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReversedPunctuation()=%+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+// Package scenes detects the shot changes of a video with ffmpeg's scene
+// detection, and reads its frame rate with ffprobe.
+package scenes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMinScore is the default scene score (0 to 1, how much a frame
+// differs from the previous one) from which a frame starts a new shot.
+const DefaultMinScore = 0.3
+
+// defaultFrameRate is used when ffprobe reports no frame rate.
+const defaultFrameRate = 24
+
+// detectWidth is the width the frames are scaled down to before comparing
+// them, which makes the detection several times faster without missing cuts.
+const detectWidth = 320
+
+// Cuts are the shot changes of a video.
+type Cuts struct {
+	// Times are the start times of the new shots, in order.
+	Times []time.Duration
+	// Frame is the duration of a frame of the video.
+	Frame time.Duration
+}
+
+// Detect returns the shot changes of the first video stream of videoPath:
+// the frames whose scene score is at least minScore (DefaultMinScore when
+// not positive). The whole video is decoded, so it takes a while.
+func Detect(ctx context.Context, videoPath string, minScore float64) (Cuts, error) {
+	if minScore <= 0 {
+		minScore = DefaultMinScore
+	}
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return Cuts{}, fmt.Errorf("ffprobe not found on PATH (install ffmpeg): %w", err)
+	}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return Cuts{}, fmt.Errorf("ffmpeg not found on PATH: %w", err)
+	}
+
+	out, err := runTool(ctx, ffprobe, "-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=avg_frame_rate,r_frame_rate",
+		"-of", "json",
+		videoPath)
+	if err != nil {
+		return Cuts{}, err
+	}
+	frame, err := parseFrameDuration(out)
+	if err != nil {
+		return Cuts{}, err
+	}
+
+	// metadata=print logs the time of each selected frame.
+	filter := fmt.Sprintf("scale=%d:-2,select='gte(scene,%g)',metadata=print", detectWidth, minScore)
+	out, err = runTool(ctx, ffmpeg, "-nostdin", "-hide_banner", "-nostats", "-v", "info",
+		"-i", videoPath,
+		"-map", "0:v:0",
+		"-vf", filter,
+		"-f", "null", "-")
+	if err != nil {
+		return Cuts{}, err
+	}
+	return Cuts{Times: parseCutTimes(out), Frame: frame}, nil
+}
+
+// runTool runs bin and returns what it logged to stderr (ffmpeg) or printed
+// to stdout (ffprobe).
+func runTool(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	slog.Debug("running scene detection tool", "bin", bin, "args", args)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", filepath.Base(bin), err, lastLine(stderr.String()))
+	}
+	if stdout.Len() > 0 {
+		return stdout.Bytes(), nil
+	}
+	return stderr.Bytes(), nil
+}
+
+// lastLine returns the last non-empty line of s, where ffmpeg writes the
+// error that made it fail.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+type ffprobeStreamsOutput struct {
+	Streams []struct {
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+	} `json:"streams"`
+}
+
+// parseFrameDuration returns the frame duration of the video stream listed
+// by ffprobe, assuming defaultFrameRate when it reports none.
+func parseFrameDuration(out []byte) (time.Duration, error) {
+	var probe ffprobeStreamsOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return 0, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return 0, fmt.Errorf("no video stream found")
+	}
+	rate := parseRate(probe.Streams[0].AvgFrameRate)
+	if rate <= 0 {
+		rate = parseRate(probe.Streams[0].RFrameRate)
+	}
+	if rate <= 0 {
+		slog.Debug("frame rate unknown, assuming the default", "frame_rate", defaultFrameRate)
+		rate = defaultFrameRate
+	}
+	return time.Duration(float64(time.Second) / rate), nil
+}
+
+// parseRate parses a frame rate as written by ffprobe ("24000/1001", "25"),
+// returning 0 when unknown ("0/0").
+func parseRate(s string) float64 {
+	num, den, found := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
+
+var ptsTimePattern = regexp.MustCompile(`\bpts_time:(-?\d+(?:\.\d+)?)`)
+
+// parseCutTimes returns the times of the frames printed by ffmpeg's
+// metadata filter, sorted and without duplicates.
+func parseCutTimes(log []byte) []time.Duration {
+	var times []time.Duration
+	for _, m := range ptsTimePattern.FindAllSubmatch(log, -1) {
+		seconds, err := strconv.ParseFloat(string(m[1]), 64)
+		if err != nil || seconds <= 0 {
+			continue // a cut at the very start is no cut
+		}
+		times = append(times, time.Duration(seconds*float64(time.Second)).Round(time.Millisecond))
+	}
+	slices.Sort(times)
+	return slices.Compact(times)
+}
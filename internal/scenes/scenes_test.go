@@ -0,0 +1,46 @@
+package scenes
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseFrameDuration(t *testing.T) {
+	cases := []struct {
+		out  string
+		want time.Duration
+	}{
+		{`{"streams": [{"avg_frame_rate": "24000/1001", "r_frame_rate": "24000/1001"}]}`, 41708333},
+		{`{"streams": [{"avg_frame_rate": "0/0", "r_frame_rate": "25/1"}]}`, 40 * time.Millisecond},
+		{`{"streams": [{"avg_frame_rate": "0/0", "r_frame_rate": "0/0"}]}`, time.Second / defaultFrameRate},
+	}
+	for _, tc := range cases {
+		got, err := parseFrameDuration([]byte(tc.out))
+		if err != nil {
+			t.Fatalf("parseFrameDuration(%s): %v", tc.out, err)
+		}
+		if got != tc.want {
+			t.Errorf("parseFrameDuration(%s) = %v, want %v", tc.out, got, tc.want)
+		}
+	}
+	if _, err := parseFrameDuration([]byte(`{"streams": []}`)); err == nil {
+		t.Fatal("expected an error without a video stream")
+	}
+}
+
+func TestParseCutTimes(t *testing.T) {
+	log := `Input #0, matroska,webm, from 'movie.mkv':
+  Duration: 00:42:10.05, start: 0.000000, bitrate: 2500 kb/s
+[Parsed_metadata_2 @ 0x600002b1c000] frame:0    pts:0       pts_time:0
+[Parsed_metadata_2 @ 0x600002b1c000] lavfi.scene_score=1.000000
+[Parsed_metadata_2 @ 0x600002b1c000] frame:1    pts:5339    pts_time:5.339
+[Parsed_metadata_2 @ 0x600002b1c000] lavfi.scene_score=0.512300
+[Parsed_metadata_2 @ 0x600002b1c000] frame:2    pts:12054   pts_time:12.054
+[Parsed_metadata_2 @ 0x600002b1c000] lavfi.scene_score=0.331000
+`
+	want := []time.Duration{5339 * time.Millisecond, 12054 * time.Millisecond}
+	if got := parseCutTimes([]byte(log)); !slices.Equal(got, want) {
+		t.Fatalf("parseCutTimes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package spell
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// condition is the condition of an affix rule: a sequence of characters, "."
+// (any character) and [abc] or [^abc] classes, matched against the end of
+// the stem (suffixes) or its start (prefixes).
+type condition []conditionChar
+
+type conditionChar struct {
+	any     bool
+	negated bool
+	chars   string
+}
+
+func (c conditionChar) matches(r rune) bool {
+	if c.any {
+		return true
+	}
+	return strings.ContainsRune(c.chars, r) != c.negated
+}
+
+func parseCondition(s string) (condition, error) {
+	if s == "." {
+		return nil, nil
+	}
+	var c condition
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			c = append(c, conditionChar{any: true})
+			i++
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid condition %q", s)
+			}
+			class := s[i+1 : i+end]
+			negated := strings.HasPrefix(class, "^")
+			c = append(c, conditionChar{negated: negated, chars: strings.TrimPrefix(class, "^")})
+			i += end + 1
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			c = append(c, conditionChar{chars: s[i : i+size]})
+			i += size
+		}
+	}
+	return c, nil
+}
+
+func (c condition) matchesEnd(stem string) bool {
+	for i := len(c) - 1; i >= 0; i-- {
+		r, size := utf8.DecodeLastRuneInString(stem)
+		if size == 0 || !c[i].matches(r) {
+			return false
+		}
+		stem = stem[:len(stem)-size]
+	}
+	return true
+}
+
+func (c condition) matchesStart(stem string) bool {
+	for _, cc := range c {
+		r, size := utf8.DecodeRuneInString(stem)
+		if size == 0 || !cc.matches(r) {
+			return false
+		}
+		stem = stem[size:]
+	}
+	return true
+}
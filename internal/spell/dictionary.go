@@ -0,0 +1,405 @@
+// Package spell checks the spelling of subtitle text with hunspell
+// dictionaries (the .aff and .dic files of LibreOffice, Firefox and most Linux
+// distributions). It reads the common subset of the format: prefix and suffix
+// rules with their cross product, flag aliases, forbidden words and the TRY
+// and REP suggestion tables. Compound words and twofold suffixes are not
+// supported, so dictionaries that rely on them (e.g. German, Hungarian)
+// report some valid compounds as misspelled.
+package spell
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+)
+
+// flag is an affix or word flag, in the FLAG type of the dictionary.
+type flag uint32
+
+// Flag types of the FLAG option. With the default and UTF-8 types, each
+// character is a flag.
+const (
+	flagChar = "char"
+	flagLong = "long" // two characters per flag
+	flagNum  = "num"  // comma-separated numbers
+)
+
+// affix is a prefix or suffix rule: strip is removed from the stem and add
+// added, when the stem matches cond.
+type affix struct {
+	flag   flag
+	prefix bool
+	cross  bool // combines with the affixes of the other kind
+	strip  string
+	add    string
+	cond   condition
+}
+
+// Dictionary is a hunspell dictionary loaded with Load.
+type Dictionary struct {
+	words    map[string][][]flag // flags of each homonym
+	prefixes map[string][]*affix // by added text
+	suffixes map[string][]*affix // by added text
+
+	flagType       string
+	aliases        [][]flag // AF flag aliases, 1-based
+	forbidden      flag
+	needAffix      flag
+	onlyInCompound flag
+	noSuggest      flag
+
+	try    string
+	rep    [][2]string
+	ignore string
+}
+
+// Load reads the dictionary at dicPath and the affix file next to it (the
+// same path with the .aff extension).
+func Load(dicPath string) (*Dictionary, error) {
+	affPath := strings.TrimSuffix(dicPath, ".dic") + ".aff"
+	d := &Dictionary{
+		words:    make(map[string][][]flag),
+		prefixes: make(map[string][]*affix),
+		suffixes: make(map[string][]*affix),
+		flagType: flagChar,
+	}
+	aff, err := os.ReadFile(affPath)
+	if err != nil {
+		return nil, err
+	}
+	decode, err := decoder(aff)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", affPath, err)
+	}
+	if err := d.parseAffix(decode(aff)); err != nil {
+		return nil, fmt.Errorf("%s: %w", affPath, err)
+	}
+
+	f, err := os.Open(dicPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, dicPath)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(decode(scanner.Bytes()))
+		if n == 1 || line == "" || strings.HasPrefix(line, "#") {
+			continue // the first line is the word count
+		}
+		if err := d.addWord(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", dicPath, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// decoder returns the function converting the lines of a dictionary to UTF-8,
+// from the SET option of its affix file.
+func decoder(aff []byte) (func([]byte) string, error) {
+	encoding := "UTF-8"
+	for line := range bytes.Lines(aff) {
+		if fields := strings.Fields(string(line)); len(fields) >= 2 && fields[0] == "SET" {
+			encoding = strings.ToUpper(fields[1])
+			break
+		}
+	}
+	switch encoding {
+	case "UTF-8", "UTF8":
+		return func(b []byte) string { return strings.TrimPrefix(string(b), "\ufeff") }, nil
+	case "ISO8859-1", "ISO-8859-1", "ISO8859-15", "ISO-8859-15":
+		latin9 := strings.HasSuffix(encoding, "15")
+		return func(b []byte) string { return decodeLatin(b, latin9) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s (supported: UTF-8, ISO8859-1, ISO8859-15)", encoding)
+	}
+}
+
+// latin9 are the characters of ISO 8859-15 that differ from ISO 8859-1.
+var latin9 = map[byte]rune{0xa4: '€', 0xa6: 'Š', 0xa8: 'š', 0xb4: 'Ž', 0xb8: 'ž', 0xbc: 'Œ', 0xbd: 'œ', 0xbe: 'Ÿ'}
+
+func decodeLatin(b []byte, iso885915 bool) string {
+	var s strings.Builder
+	for _, c := range b {
+		if r, ok := latin9[c]; ok && iso885915 {
+			s.WriteRune(r)
+			continue
+		}
+		s.WriteRune(rune(c))
+	}
+	return s.String()
+}
+
+// parseAffix reads the options and rules of an affix file.
+func (d *Dictionary) parseAffix(text string) error {
+	lines := strings.Split(text, "\n")
+	aliasCount := false // the "AF count" line was read
+	for n := 0; n < len(lines); n++ {
+		fields := strings.Fields(lines[n])
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "FLAG":
+			d.flagType = fields[1]
+		case "TRY":
+			d.try = fields[1]
+		case "IGNORE":
+			d.ignore = fields[1]
+		case "FORBIDDENWORD":
+			d.forbidden, err = d.parseFlag(fields[1])
+		case "NEEDAFFIX", "PSEUDOROOT":
+			d.needAffix, err = d.parseFlag(fields[1])
+		case "ONLYINCOMPOUND":
+			d.onlyInCompound, err = d.parseFlag(fields[1])
+		case "NOSUGGEST":
+			d.noSuggest, err = d.parseFlag(fields[1])
+		case "AF":
+			// "AF count" is followed by count aliases, numbered from 1.
+			if !aliasCount {
+				aliasCount = true
+				continue
+			}
+			var flags []flag
+			if flags, err = d.parseRawFlags(fields[1]); err == nil {
+				d.aliases = append(d.aliases, flags)
+			}
+		case "REP":
+			if len(fields) >= 3 {
+				d.rep = append(d.rep, [2]string{fields[1], strings.ReplaceAll(fields[2], "_", " ")})
+			}
+		case "PFX", "SFX":
+			if len(fields) < 4 || !isCount(fields[3]) {
+				continue // an affix rule, read with its header
+			}
+			var count int
+			count, err = d.parseAffixClass(fields, lines[n+1:])
+			n += count
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	return nil
+}
+
+func isCount(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// parseAffixClass reads the rules of the affix class whose header is
+// "PFX|SFX flag cross count", returning the number of rule lines read.
+func (d *Dictionary) parseAffixClass(header []string, lines []string) (int, error) {
+	f, err := d.parseFlag(header[1])
+	if err != nil {
+		return 0, err
+	}
+	count, _ := strconv.Atoi(header[3])
+	prefix := header[0] == "PFX"
+	read := 0
+	for _, line := range lines {
+		if read == count {
+			break
+		}
+		read++
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != header[0] {
+			return read, fmt.Errorf("expected %d %s rules of flag %s", count, header[0], header[1])
+		}
+		a := &affix{flag: f, prefix: prefix, cross: header[2] == "Y"}
+		if fields[2] != "0" {
+			a.strip = fields[2]
+		}
+		add, _, _ := strings.Cut(fields[3], "/") // continuation classes are not supported
+		if add != "0" {
+			a.add = add
+		}
+		if len(fields) > 4 {
+			if a.cond, err = parseCondition(fields[4]); err != nil {
+				return read, err
+			}
+		}
+		if prefix {
+			d.prefixes[a.add] = append(d.prefixes[a.add], a)
+		} else {
+			d.suffixes[a.add] = append(d.suffixes[a.add], a)
+		}
+	}
+	return read, nil
+}
+
+// addWord adds a "word/flags" line of the .dic file.
+func (d *Dictionary) addWord(line string) error {
+	// Morphological fields follow a tab or a space.
+	if i := strings.IndexAny(line, "\t "); i >= 0 {
+		line = line[:i]
+	}
+	word, flags := line, ""
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++ // an escaped slash is part of the word
+			continue
+		}
+		if line[i] == '/' {
+			word, flags = line[:i], line[i+1:]
+			break
+		}
+	}
+	word = strings.ReplaceAll(word, `\/`, "/")
+	var parsed []flag
+	if flags != "" {
+		var err error
+		if parsed, err = d.parseFlags(flags); err != nil {
+			return err
+		}
+	}
+	d.words[word] = append(d.words[word], parsed)
+	return nil
+}
+
+// parseFlags parses the flags of a word, which are an alias number when the
+// dictionary has AF aliases.
+func (d *Dictionary) parseFlags(s string) ([]flag, error) {
+	if len(d.aliases) > 0 && isCount(s) {
+		n, _ := strconv.Atoi(s)
+		if n < 1 || n > len(d.aliases) {
+			return nil, fmt.Errorf("unknown flag alias %d", n)
+		}
+		return d.aliases[n-1], nil
+	}
+	return d.parseRawFlags(s)
+}
+
+// parseRawFlags parses flags written in the FLAG type of the dictionary.
+func (d *Dictionary) parseRawFlags(s string) ([]flag, error) {
+	var flags []flag
+	switch d.flagType {
+	case flagLong:
+		if len(s)%2 != 0 {
+			return nil, fmt.Errorf("invalid long flags %q", s)
+		}
+		for i := 0; i < len(s); i += 2 {
+			flags = append(flags, flag(s[i])<<8|flag(s[i+1]))
+		}
+	case flagNum:
+		for part := range strings.SplitSeq(s, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("invalid numeric flag %q", part)
+			}
+			flags = append(flags, flag(n))
+		}
+	default:
+		for _, r := range s {
+			flags = append(flags, flag(r))
+		}
+	}
+	return flags, nil
+}
+
+func (d *Dictionary) parseFlag(s string) (flag, error) {
+	flags, err := d.parseRawFlags(s)
+	if err != nil || len(flags) == 0 {
+		return 0, fmt.Errorf("invalid flag %q", s)
+	}
+	return flags[0], nil
+}
+
+func hasFlag(flags []flag, f flag) bool {
+	if f == 0 {
+		return false
+	}
+	for _, g := range flags {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup reports whether word, as written, is a word of the dictionary or
+// derived from one by its affix rules.
+func (d *Dictionary) lookup(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, flags := range d.words[word] {
+		if hasFlag(flags, d.forbidden) {
+			return false
+		}
+	}
+	for _, flags := range d.words[word] {
+		if !hasFlag(flags, d.needAffix) && !hasFlag(flags, d.onlyInCompound) {
+			return true
+		}
+	}
+	return d.lookupSuffixed(word, nil) || d.lookupPrefixed(word)
+}
+
+// lookupSuffixed reports whether word is a stem with one of its suffixes.
+// With pfx, the stem must also take that prefix.
+func (d *Dictionary) lookupSuffixed(word string, pfx *affix) bool {
+	for i := len(word); i >= 0; i-- {
+		if i < len(word) && !utf8.RuneStart(word[i]) {
+			continue
+		}
+		for _, sfx := range d.suffixes[word[i:]] {
+			if pfx != nil && !sfx.cross {
+				continue
+			}
+			stem := word[:i] + sfx.strip
+			if i == 0 || !sfx.cond.matchesEnd(stem) {
+				continue
+			}
+			if d.stemHas(stem, sfx.flag, pfx) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupPrefixed reports whether word is a stem with one of its prefixes, and
+// maybe one of its suffixes.
+func (d *Dictionary) lookupPrefixed(word string) bool {
+	for i := 0; i <= len(word); i++ {
+		if i < len(word) && !utf8.RuneStart(word[i]) {
+			continue
+		}
+		for _, pfx := range d.prefixes[word[:i]] {
+			stem := pfx.strip + word[i:]
+			if i == len(word) || !pfx.cond.matchesStart(stem) {
+				continue
+			}
+			if d.stemHas(stem, pfx.flag, nil) || pfx.cross && d.lookupSuffixed(stem, pfx) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stemHas reports whether stem is a word of the dictionary with flag f (and
+// the flag of pfx, when set).
+func (d *Dictionary) stemHas(stem string, f flag, pfx *affix) bool {
+	for _, flags := range d.words[stem] {
+		if hasFlag(flags, d.forbidden) || hasFlag(flags, d.onlyInCompound) {
+			continue
+		}
+		if hasFlag(flags, f) && (pfx == nil || hasFlag(flags, pfx.flag)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package spell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+)
+
+// dictionaryDirs are the directories searched for dictionaries after the
+// ones of the DICPATH environment variable, as hunspell does.
+var dictionaryDirs = []string{
+	"/usr/share/hunspell",
+	"/usr/share/myspell",
+	"/usr/share/myspell/dicts",
+	"/usr/local/share/hunspell",
+	"/opt/homebrew/share/hunspell",
+	"/Library/Spelling",
+	"~/Library/Spelling",
+	"~/.local/share/hunspell",
+}
+
+// defaultRegions are the regions preferred for the languages whose
+// dictionaries are not named after themselves (en_EN).
+var defaultRegions = map[string]string{"en": "US", "pt": "BR", "sv": "SE", "da": "DK", "nb": "NO", "el": "GR", "cs": "CZ", "uk": "UA"}
+
+// Find returns the path of the .dic file of language (e.g. "es", "pt-BR"):
+// in the directories of DICPATH and the usual dictionary directories, the
+// file named after the language and region (pt_BR.dic), the language alone
+// (es.dic), or the language with its usual region (es_ES.dic, en_US.dic) or
+// any region.
+func Find(language string) (string, error) {
+	primary, region, _ := strings.Cut(naming.ShortLanguage(language), "-")
+	if primary == "" {
+		return "", fmt.Errorf("no language to find a dictionary for")
+	}
+	names := []string{primary}
+	if region != "" {
+		names = []string{primary + "_" + region, primary + "-" + region, primary}
+	}
+	usual := defaultRegions[primary]
+	if usual == "" {
+		usual = strings.ToUpper(primary)
+	}
+	names = append(names, primary+"_"+usual)
+
+	dirs := filepath.SplitList(os.Getenv("DICPATH"))
+	home, _ := os.UserHomeDir()
+	for _, dir := range dictionaryDirs {
+		if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+			if home == "" {
+				continue
+			}
+			dir = filepath.Join(home, rest)
+		}
+		dirs = append(dirs, dir)
+	}
+	for _, name := range names {
+		for _, dir := range dirs {
+			if path := filepath.Join(dir, name+".dic"); isFile(path) {
+				return path, nil
+			}
+		}
+	}
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, primary+"[_-]*.dic"))
+		slices.Sort(matches)
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	return "", fmt.Errorf("no %s dictionary found (install a hunspell dictionary, set DICPATH or pass the .dic file)", primary)
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().IsRegular()
+}
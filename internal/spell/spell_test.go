@@ -0,0 +1,128 @@
+package spell
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const testAff = `SET UTF-8
+TRY aeioustnrlcdáéíóú
+FORBIDDENWORD !
+REP 1
+REP ^aver$ a_ver
+
+PFX R Y 1
+PFX R 0 re .
+
+SFX S Y 2
+SFX S 0 s [aeiouáéíóú]
+SFX S 0 es [^aeiouáéíóú]
+
+SFX G N 1
+SFX G ar ando ar
+`
+
+const testDic = `13
+casa/S
+película/S
+también
+hacer/R
+mirar/RG
+ciudad/S
+a
+la
+ver
+qué
+vamos
+es
+hicistes/!
+`
+
+func testDictionary(t *testing.T) *Dictionary {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "es_ES.aff"), []byte(testAff), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dic := filepath.Join(dir, "es_ES.dic")
+	if err := os.WriteFile(dic, []byte(testDic), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Load(dic)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return d
+}
+
+func TestDictionary_Check(t *testing.T) {
+	d := testDictionary(t)
+	for _, w := range []string{"casa", "casas", "ciudades", "películas", "rehacer", "mirando", "remirar", "Casa", "CIUDADES", "También"} {
+		if !d.Check(w) {
+			t.Errorf("Check(%q) = false", w)
+		}
+	}
+	// remirando needs the cross product of a prefix and a suffix that doesn't
+	// allow it; hicistes is forbidden.
+	for _, w := range []string{"casaes", "ciudads", "tambien", "remirando", "hicistes", "cAsa", "xyz"} {
+		if d.Check(w) {
+			t.Errorf("Check(%q) = true", w)
+		}
+	}
+}
+
+func TestDictionary_Suggest(t *testing.T) {
+	d := testDictionary(t)
+	cases := map[string]string{
+		"tambien":  "también",
+		"Pelicula": "Película",
+		"CAZA":     "CASA",
+		"ciudadd":  "ciudad",
+		"aver":     "a ver",
+	}
+	for word, want := range cases {
+		if got := d.Suggest(word); !slices.Contains(got, want) {
+			t.Errorf("Suggest(%q) = %q, want %q among them", word, got, want)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	c := NewChecker(testDictionary(t), []string{"Walter"})
+	text := "<i>Vamos a ver la pelicula</i>\n- Walter's casa, tambien Jesse. Ciudadd 3er"
+	got := c.Check(text)
+	var words []string
+	for _, m := range got {
+		words = append(words, m.Word)
+	}
+	if want := []string{"pelicula", "tambien", "Jesse", "Ciudadd"}; !slices.Equal(words, want) {
+		t.Fatalf("Check: got %q, want %q", words, want)
+	}
+
+	fixed, misspellings := c.Fix(text)
+	if want := "<i>Vamos a ver la película</i>\n- Walter's casa, también Jesse. Ciudad 3er"; fixed != want {
+		t.Fatalf("Fix:\n got %q\nwant %q", fixed, want)
+	}
+	if len(misspellings) != 4 || misspellings[0].Fix != "película" || misspellings[2].Fix != "" {
+		t.Fatalf("unexpected misspellings %+v", misspellings)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"es_AR.dic", "es_ES.dic", "pt_BR.dic", "pt_PT.dic"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DICPATH", dir)
+	cases := map[string]string{"es": "es_ES.dic", "spa": "es_ES.dic", "es-AR": "es_AR.dic", "pt": "pt_BR.dic", "pt_PT": "pt_PT.dic"}
+	for language, want := range cases {
+		got, err := Find(language)
+		if err != nil || got != filepath.Join(dir, want) {
+			t.Errorf("Find(%q) = %q, %v; want %s", language, got, err, want)
+		}
+	}
+}
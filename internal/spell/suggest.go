@@ -0,0 +1,203 @@
+package spell
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSuggestions caps the suggestions of a misspelled word.
+const maxSuggestions = 5
+
+// defaultTry are the characters tried in suggestions when the dictionary has
+// no TRY option.
+const defaultTry = "esianrtolcdugmphbyfvkwzxjq"
+
+// Check reports whether word is spelled correctly: as written, or in lower
+// case when it is capitalized (at the start of a sentence) or in upper case.
+func (d *Dictionary) Check(word string) bool {
+	if d.ignore != "" {
+		word = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(d.ignore, r) {
+				return -1
+			}
+			return r
+		}, word)
+	}
+	if d.checkCase(word) {
+		return true
+	}
+	// Dictionaries spell apostrophes as ', subtitles often as ’.
+	return strings.ContainsRune(word, '’') && d.checkCase(strings.ReplaceAll(word, "’", "'"))
+}
+
+func (d *Dictionary) checkCase(word string) bool {
+	if d.lookup(word) {
+		return true
+	}
+	switch caseOf(word) {
+	case caseInitial:
+		return d.lookup(lowerFirst(word))
+	case caseUpper:
+		lower := strings.ToLower(word)
+		return d.lookup(lower) || d.lookup(upperFirst(lower))
+	}
+	return false
+}
+
+// Word cases.
+const (
+	caseLower = iota
+	caseInitial
+	caseUpper
+	caseMixed
+)
+
+func caseOf(word string) int {
+	upper, letters := 0, 0
+	first := false
+	for i, r := range word {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+			first = first || i == 0
+		}
+	}
+	switch {
+	case upper == 0:
+		return caseLower
+	case upper == letters && letters > 1:
+		return caseUpper
+	case upper == 1 && first:
+		return caseInitial
+	}
+	return caseMixed
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// Suggest returns the corrections of a misspelled word, most likely first:
+// the replacements of the REP table, then the words one edit away (a swapped,
+// missing, extra or wrong character) and the word split in two. Suggestions
+// keep the case of word.
+func (d *Dictionary) Suggest(word string) []string {
+	wordCase := caseOf(word)
+	base := word
+	switch wordCase {
+	case caseInitial:
+		base = lowerFirst(word)
+	case caseUpper:
+		base = strings.ToLower(word)
+	}
+
+	var out []string
+	seen := map[string]bool{word: true}
+	add := func(candidate string) {
+		switch wordCase {
+		case caseInitial:
+			candidate = upperFirst(candidate)
+		case caseUpper:
+			candidate = strings.ToUpper(candidate)
+		}
+		if len(out) == maxSuggestions || seen[candidate] {
+			return
+		}
+		seen[candidate] = true
+		if d.suggestible(candidate) {
+			out = append(out, candidate)
+		}
+	}
+	for _, c := range d.replacements(base) {
+		add(c)
+	}
+	if wordCase == caseLower {
+		add(upperFirst(base)) // a name written in lower case
+	}
+	for _, c := range d.edits(base) {
+		add(c)
+	}
+	return out
+}
+
+// suggestible reports whether candidate (maybe two words) is spelled
+// correctly and may be suggested.
+func (d *Dictionary) suggestible(candidate string) bool {
+	for part := range strings.FieldsSeq(candidate) {
+		if !d.Check(part) {
+			return false
+		}
+		for _, flags := range d.words[part] {
+			if hasFlag(flags, d.noSuggest) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// replacements applies each REP rule once at every position of word. A ^ or
+// $ anchors the rule to the start or end of the word.
+func (d *Dictionary) replacements(word string) []string {
+	var out []string
+	for _, rep := range d.rep {
+		from, to := rep[0], rep[1]
+		start, end := strings.HasPrefix(from, "^"), strings.HasSuffix(from, "$")
+		from = strings.TrimSuffix(strings.TrimPrefix(from, "^"), "$")
+		to = strings.TrimSuffix(strings.TrimPrefix(to, "^"), "$")
+		if from == "" {
+			continue
+		}
+		for i := 0; i+len(from) <= len(word); i++ {
+			if word[i:i+len(from)] != from || start && i > 0 || end && i+len(from) < len(word) {
+				continue
+			}
+			out = append(out, word[:i]+to+word[i+len(from):])
+		}
+	}
+	return out
+}
+
+// edits returns the words one edit away from word, and word split in two.
+func (d *Dictionary) edits(word string) []string {
+	try := []rune(d.try)
+	if len(try) == 0 {
+		try = []rune(defaultTry)
+	}
+	r := []rune(word)
+	var out []string
+	for i := 0; i+1 < len(r); i++ { // swapped characters
+		s := append([]rune{}, r...)
+		s[i], s[i+1] = s[i+1], s[i]
+		out = append(out, string(s))
+	}
+	for i := range r { // extra character
+		out = append(out, string(r[:i])+string(r[i+1:]))
+	}
+	for i := 0; i <= len(r); i++ { // missing character
+		for _, c := range try {
+			out = append(out, string(r[:i])+string(c)+string(r[i:]))
+		}
+	}
+	for i := range r { // wrong character
+		for _, c := range try {
+			if c != r[i] {
+				out = append(out, string(r[:i])+string(c)+string(r[i+1:]))
+			}
+		}
+	}
+	for i := 1; i < len(r); i++ { // missing space
+		out = append(out, string(r[:i])+" "+string(r[i:]))
+	}
+	return out
+}
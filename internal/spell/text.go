@@ -0,0 +1,239 @@
+package spell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// minFixLength is the length, in characters, under which a misspelled word
+// is never fixed automatically: short words have too many close neighbors.
+const minFixLength = 4
+
+// Misspelling is a word of the text that is not in the dictionary.
+type Misspelling struct {
+	Word        string   `json:"word"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Fix is the correction applied by Checker.Fix: the only suggestion of a
+	// word that is not a name.
+	Fix string `json:"fix,omitempty"`
+}
+
+// Checker checks the words of subtitle text against a dictionary and a list
+// of accepted words (character names, places...).
+type Checker struct {
+	dict     *Dictionary
+	accepted map[string]bool // lower case
+}
+
+// NewChecker returns a Checker of dict that also accepts words, ignoring
+// case.
+func NewChecker(dict *Dictionary, words []string) *Checker {
+	c := &Checker{dict: dict, accepted: make(map[string]bool, len(words))}
+	for _, w := range words {
+		c.accepted[strings.ToLower(w)] = true
+	}
+	return c
+}
+
+// Open returns the Checker of the dictionary at dicPath, or of the dictionary
+// of language (see Find) when empty, accepting the words of wordsPath when
+// set.
+func Open(dicPath, wordsPath, language string) (*Checker, error) {
+	if dicPath == "" {
+		var err error
+		if dicPath, err = Find(language); err != nil {
+			return nil, err
+		}
+	}
+	dict, err := Load(dicPath)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	if wordsPath != "" {
+		if words, err = LoadWords(wordsPath); err != nil {
+			return nil, err
+		}
+	}
+	return NewChecker(dict, words), nil
+}
+
+// LoadWords reads a list of accepted words, one per line. Empty lines and
+// lines starting with # are ignored.
+func LoadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return words, nil
+}
+
+// Check returns the misspelled words of text, in order. Inline tags and
+// words with digits are skipped.
+func (c *Checker) Check(text string) []Misspelling {
+	var out []Misspelling
+	for _, w := range c.misspelled(text) {
+		out = append(out, w.Misspelling)
+	}
+	return out
+}
+
+// Fix corrects the misspelled words of text that have a single suggestion,
+// except the capitalized words in the middle of a sentence, which are likely
+// names. It returns the text and every misspelling, with Fix set on the
+// corrected ones.
+func (c *Checker) Fix(text string) (string, []Misspelling) {
+	words := c.misspelled(text)
+	var out []Misspelling
+	var b strings.Builder
+	last := 0
+	for _, w := range words {
+		if len(w.Suggestions) == 1 && !w.midSentenceCapital && utf8.RuneCountInString(w.Word) >= minFixLength {
+			w.Fix = w.Suggestions[0]
+			b.WriteString(text[last:w.start])
+			b.WriteString(w.Fix)
+			last = w.start + len(w.Word)
+		}
+		out = append(out, w.Misspelling)
+	}
+	if last == 0 {
+		return text, out
+	}
+	b.WriteString(text[last:])
+	return b.String(), out
+}
+
+// word is a misspelled word of a text, at byte offset start.
+type word struct {
+	Misspelling
+	start              int
+	midSentenceCapital bool
+}
+
+// misspelled scans the words of text outside its inline tags.
+func (c *Checker) misspelled(text string) []word {
+	var out []word
+	sentenceStart, lineStart := true, true
+	check := func(start int, w string) {
+		midSentenceCapital := !sentenceStart && caseOf(w) == caseInitial
+		sentenceStart = false
+		if strings.ContainsFunc(w, unicode.IsDigit) || c.accepts(w) || c.dict.Check(w) {
+			return
+		}
+		out = append(out, word{
+			Misspelling:        Misspelling{Word: w, Suggestions: c.dict.Suggest(w)},
+			start:              start,
+			midSentenceCapital: midSentenceCapital,
+		})
+	}
+
+	pos := 0
+	tags := srt.InlineTagPattern.FindAllStringIndex(text, -1)
+	tags = append(tags, []int{len(text), len(text)})
+	for _, tag := range tags {
+		segment := text[pos:tag[0]]
+		wordStart := -1
+		for i, r := range segment {
+			if isWordRune(segment, i, r) {
+				if wordStart < 0 {
+					wordStart = i
+				}
+				continue
+			}
+			if wordStart >= 0 {
+				check(pos+wordStart, segment[wordStart:i])
+				wordStart = -1
+			}
+			switch {
+			case r == '\n':
+				lineStart = true
+				continue
+			case strings.ContainsRune(".!?…¿¡", r), lineStart && strings.ContainsRune("-–—", r):
+				sentenceStart = true
+			}
+			if !unicode.IsSpace(r) {
+				lineStart = false
+			}
+		}
+		if wordStart >= 0 {
+			check(pos+wordStart, segment[wordStart:])
+		}
+		pos = tag[1]
+	}
+	return out
+}
+
+// isWordRune reports whether the rune r at byte offset i of s is part of a
+// word: a letter, a digit or a combining mark, or an apostrophe between two
+// letters (it's, l'homme). Hyphenated words are checked part by part.
+func isWordRune(s string, i int, r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+		return true
+	}
+	if r != '\'' && r != '’' {
+		return false
+	}
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):])
+	return unicode.IsLetter(before) && unicode.IsLetter(after)
+}
+
+// accepts reports whether w, or w without a possessive 's, is an accepted
+// word.
+func (c *Checker) accepts(w string) bool {
+	lower := strings.ToLower(w)
+	if c.accepted[lower] {
+		return true
+	}
+	for _, suffix := range []string{"'s", "’s"} {
+		if stem, ok := strings.CutSuffix(lower, suffix); ok && c.accepted[stem] {
+			return true
+		}
+	}
+	return false
+}
+
+// CueMisspelling is a misspelled word of a cue.
+type CueMisspelling struct {
+	Idx  int    `json:"idx"`
+	Time string `json:"time"`
+	Misspelling
+}
+
+// CheckCues returns the misspelled words of cues. With fix, the words with a
+// trusted correction are corrected in place (see Checker.Fix).
+func CheckCues(cues []*srt.Subtitle, c *Checker, fix bool) []CueMisspelling {
+	var out []CueMisspelling
+	for _, cue := range cues {
+		var misspellings []Misspelling
+		if fix {
+			cue.Text, misspellings = c.Fix(cue.Text)
+		} else {
+			misspellings = c.Check(cue.Text)
+		}
+		for _, m := range misspellings {
+			out = append(out, CueMisspelling{Idx: cue.Idx, Time: srt.FormatTime(cue.FromTime), Misspelling: m})
+		}
+	}
+	return out
+}
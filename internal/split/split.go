@@ -0,0 +1,241 @@
+// Package split cuts a subtitle file into parts, for subtitles that span a
+// release in several files (CD1/CD2) or episodes muxed together. The cues of
+// every part are shifted so the part starts at 00:00:00.
+package split
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// PartPlaceholder is replaced by the part number (from 1) in the output
+// pattern.
+const PartPlaceholder = "{n}"
+
+// gapWindow is how far, as a share of the part length, Parts looks around
+// an even cut for a pause between cues to cut at.
+const gapWindow = 0.1
+
+type Options struct {
+	InputPath string
+	// OutputPattern is the path of the parts, with PartPlaceholder; empty
+	// means next to the input: movie.en.srt -> movie.part1.en.srt.
+	OutputPattern string
+	// Exactly one of At, Parts and Every sets where the parts are cut.
+	At      []time.Duration // cut at these times, in ascending order
+	Parts   int             // cut into this many parts of about the same length
+	Every   int             // cut every this many cues
+	DryRun  bool
+	WorkDir string
+}
+
+// Part is a written part.
+type Part struct {
+	Path string `json:"path"`
+	// Offset is the time of the input where the part starts, subtracted from
+	// its cues.
+	Offset time.Duration `json:"offset"`
+	Cues   int           `json:"cues"`
+}
+
+type Result struct {
+	Parts []Part
+}
+
+// Run reads opts.InputPath and writes its parts. In dry-run, the parts are
+// written to opts.WorkDir.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	b, err := os.ReadFile(opts.InputPath)
+	if err != nil {
+		return Result{}, err
+	}
+	doc, err := srt.Read(bytes.NewReader(b))
+	if err != nil {
+		return Result{}, fmt.Errorf("parse %s: %w", opts.InputPath, err)
+	}
+	for _, w := range doc.Warnings {
+		slog.Warn("subtitles file read with a warning", "input_path", opts.InputPath, "warning", w)
+	}
+	srt.Sort(doc.Cues)
+
+	cuts, err := Cuts(doc.Cues, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	parts, err := Split(doc, cuts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	pattern := opts.OutputPattern
+	if pattern == "" {
+		pattern = DefaultOutputPattern(opts.InputPath)
+	}
+	if !strings.Contains(pattern, PartPlaceholder) {
+		return Result{}, fmt.Errorf("output path must contain %s, the part number", PartPlaceholder)
+	}
+	namer := run.NewTempNamer(opts.WorkDir, opts.InputPath)
+	var res Result
+	for i, part := range parts {
+		n := strconv.Itoa(i + 1)
+		outputPath := strings.ReplaceAll(pattern, PartPlaceholder, n)
+		if fs.SameFilePath(outputPath, opts.InputPath) {
+			return Result{}, fmt.Errorf("part %s would overwrite the input %s", n, opts.InputPath)
+		}
+		if opts.DryRun {
+			outputPath = namer.Step("part" + n)
+		}
+		var buf bytes.Buffer
+		if err := srt.Write(&buf, part, false); err != nil {
+			return Result{}, err
+		}
+		if err := fs.WriteFile(&buf, outputPath); err != nil {
+			return Result{}, err
+		}
+		res.Parts = append(res.Parts, Part{Path: outputPath, Offset: cuts[i], Cues: len(part.Cues)})
+	}
+	return res, nil
+}
+
+// DefaultOutputPattern returns the parts of inputPath next to it, numbered
+// before the language and flag suffixes: movie.en.srt -> movie.part{n}.en.srt.
+func DefaultOutputPattern(inputPath string) string {
+	stem, _ := naming.Parse(inputPath)
+	base := filepath.Base(inputPath)
+	return filepath.Join(filepath.Dir(inputPath), stem+".part"+PartPlaceholder+strings.TrimPrefix(base, stem))
+}
+
+// Cuts returns the start of every part of cues (sorted by time) as set by
+// opts; the first part starts at 0. At cuts at the given times, while Parts
+// and Every cut before a cue, so the later parts start with a cue.
+func Cuts(cues []*srt.Subtitle, opts Options) ([]time.Duration, error) {
+	modes := 0
+	for _, set := range []bool{len(opts.At) > 0, opts.Parts != 0, opts.Every != 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return nil, errors.New("set exactly one of the cut times, the number of parts or the cues per part")
+	}
+	if len(cues) == 0 {
+		return nil, errors.New("no cues to split")
+	}
+
+	cuts := []time.Duration{0}
+	switch {
+	case len(opts.At) > 0:
+		for _, at := range opts.At {
+			if at <= cuts[len(cuts)-1] {
+				return nil, fmt.Errorf("cut times must be positive and in ascending order, got %s", srt.FormatTime(at))
+			}
+			cuts = append(cuts, at)
+		}
+	case opts.Every != 0:
+		if opts.Every < 1 {
+			return nil, fmt.Errorf("cues per part must be at least 1, got %d", opts.Every)
+		}
+		for i := opts.Every; i < len(cues); i += opts.Every {
+			cuts = append(cuts, cues[i].FromTime)
+		}
+	default:
+		if opts.Parts < 2 {
+			return nil, fmt.Errorf("number of parts must be at least 2, got %d", opts.Parts)
+		}
+		if opts.Parts > len(cues) {
+			return nil, fmt.Errorf("can't split %d cues into %d parts", len(cues), opts.Parts)
+		}
+		cuts = append(cuts, evenCuts(cues, opts.Parts)...)
+	}
+	return cuts, nil
+}
+
+// evenCuts cuts cues into n parts of about the same length. Every cut is
+// moved to the longest pause between cues within gapWindow of the even cut,
+// which is more likely the end of a CD or of an episode.
+func evenCuts(cues []*srt.Subtitle, n int) []time.Duration {
+	end := cues[len(cues)-1].ToTime
+	window := time.Duration(float64(end) / float64(n) * gapWindow)
+	var cuts []time.Duration
+	prev := 0 // index of the first cue of the current part
+	for k := 1; k < n; k++ {
+		even := end * time.Duration(k) / time.Duration(n)
+		// Leave at least a cue for every remaining part.
+		last := len(cues) - (n - k)
+		best, bestGap := -1, time.Duration(-1)
+		for i := prev + 1; i <= last; i++ {
+			if cues[i].FromTime < even-window {
+				continue
+			}
+			if cues[i].FromTime > even+window {
+				break
+			}
+			if gap := cues[i].FromTime - cues[i-1].ToTime; gap > bestGap {
+				best, bestGap = i, gap
+			}
+		}
+		if best < 0 {
+			// No cue near the even cut: take the first one after it.
+			best = last
+			for i := prev + 1; i <= last; i++ {
+				if cues[i].FromTime >= even {
+					best = i
+					break
+				}
+			}
+		}
+		cuts = append(cuts, cues[best].FromTime)
+		prev = best
+	}
+	return cuts
+}
+
+// Split cuts doc at cuts (ascending, the first being the start of the first
+// part). A cue goes to the part it starts in and is shifted by the start of
+// the part; every part is numbered from 1. The header goes to the first part
+// and the trailer to the last one.
+func Split(doc *srt.Document, cuts []time.Duration) ([]*srt.Document, error) {
+	parts := make([]*srt.Document, len(cuts))
+	for i := range parts {
+		parts[i] = doc.WithCues(nil)
+		if i > 0 {
+			parts[i].Header = nil
+		}
+		if i < len(parts)-1 {
+			parts[i].Trailer = nil
+		}
+	}
+	p := 0
+	for _, sub := range doc.Cues {
+		for p+1 < len(cuts) && sub.FromTime >= cuts[p+1] {
+			p++
+		}
+		cue := *sub
+		cue.FromTime -= cuts[p]
+		cue.ToTime -= cuts[p]
+		parts[p].Cues = append(parts[p].Cues, &cue)
+	}
+	for i, part := range parts {
+		if len(part.Cues) == 0 {
+			return nil, fmt.Errorf("part %d (from %s) has no cues", i+1, srt.FormatTime(cuts[i]))
+		}
+		srt.Reindex(part.Cues)
+	}
+	return parts, nil
+}
@@ -0,0 +1,94 @@
+package split
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// testCues returns cues of a second starting at the given seconds.
+func testCues(starts ...int) []*srt.Subtitle {
+	var cues []*srt.Subtitle
+	for i, s := range starts {
+		from := time.Duration(s) * time.Second
+		cues = append(cues, &srt.Subtitle{Idx: i + 1, FromTime: from, ToTime: from + time.Second, Text: "line"})
+	}
+	return cues
+}
+
+func TestCuts(t *testing.T) {
+	cues := testCues(0, 10, 20, 30, 40, 55, 60, 70, 80, 90)
+	for _, tc := range []struct {
+		name string
+		opts Options
+		want []time.Duration
+	}{
+		{"at", Options{At: []time.Duration{25 * time.Second, time.Minute}}, []time.Duration{0, 25 * time.Second, time.Minute}},
+		{"every", Options{Every: 4}, []time.Duration{0, 40 * time.Second, 80 * time.Second}},
+		// The even cut (45.5s) moves to the pause before the cue at 55s.
+		{"parts", Options{Parts: 2}, []time.Duration{0, 55 * time.Second}},
+	} {
+		got, err := Cuts(cues, tc.opts)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Cuts = %v, %v, want %v", tc.name, got, err, tc.want)
+		}
+	}
+	for _, opts := range []Options{
+		{},
+		{Parts: 2, Every: 3},
+		{Parts: 1},
+		{Parts: 11},
+		{Every: -1},
+		{At: []time.Duration{time.Minute, 30 * time.Second}},
+	} {
+		if _, err := Cuts(cues, opts); err == nil {
+			t.Errorf("Cuts(%+v) accepted invalid options", opts)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "movie.en.srt")
+	content := "1\n00:00:01,000 --> 00:00:02,000\nFirst\n\n" +
+		"2\n00:44:59,000 --> 00:45:01,500\nAcross the cut\n\n" +
+		"3\n00:45:10,000 --> 00:45:12,000\nSecond part\n\n" +
+		"4\n01:20:00,000 --> 01:20:02,000\nLast\n\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Run(context.Background(), Options{InputPath: input, At: []time.Duration{45 * time.Minute}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Parts) != 2 || res.Parts[0].Cues != 2 || res.Parts[1].Cues != 2 || res.Parts[1].Offset != 45*time.Minute {
+		t.Fatalf("Run = %+v", res)
+	}
+	if want := filepath.Join(dir, "movie.part2.en.srt"); res.Parts[1].Path != want {
+		t.Fatalf("part 2 written to %s, want %s", res.Parts[1].Path, want)
+	}
+	b, err := os.ReadFile(res.Parts[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n00:00:10,000 --> 00:00:12,000\nSecond part\n\n2\n00:35:00,000 --> 00:35:02,000\nLast\n\n"
+	if string(b) != want {
+		t.Fatalf("part 2 = %q, want %q", b, want)
+	}
+
+	_, err = Run(context.Background(), Options{InputPath: input, OutputPattern: filepath.Join(dir, "out.srt"), Parts: 2})
+	if err == nil || !strings.Contains(err.Error(), PartPlaceholder) {
+		t.Fatalf("Run without %s: %v", PartPlaceholder, err)
+	}
+	_, err = Run(context.Background(), Options{InputPath: input, At: []time.Duration{2 * time.Hour}})
+	if err == nil || !strings.Contains(err.Error(), "has no cues") {
+		t.Fatalf("Run with an empty part: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package srt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Subtitle formats of Document.Format.
+const (
+	FormatSRT      = "srt"
+	FormatMicroDVD = "microdvd"
+	FormatTTML     = "ttml"
+	FormatSAMI     = "sami" // read only; written as SRT
+	FormatSBV      = "sbv"  // read only; written as SRT
+)
+
+// Text encodings of Document.Encoding.
+const (
+	EncodingUTF8 = "UTF-8"
+)
+
+// Document is a subtitle file: its cues and everything else read from it, so
+// it can be written back as it was.
+type Document struct {
+	Cues     []*Subtitle
+	Format   string // FormatSRT, FormatMicroDVD, FormatTTML... ("" means FormatSRT)
+	Encoding string // EncodingUTF8 ("" means EncodingUTF8)
+	BOM      bool   // the file starts with a UTF-8 BOM
+	// FrameRate is the frames per second of the video the times of a
+	// frame-based format (FormatMicroDVD) are converted with.
+	FrameRate float64
+	// Language is the language of the text of formats that declare it
+	// (xml:lang of FormatTTML, the class lang of FormatSAMI), "" when unknown.
+	Language string
+	// Header holds the blocks before the first cue that aren't cues.
+	Header []string
+	// Trailer holds the blocks after the last cue (e.g. credits or tool
+	// metadata).
+	Trailer []string
+	// Warnings are problems found while reading that didn't stop it, such as
+	// text that isn't valid UTF-8. They aren't written.
+	Warnings []string
+}
+
+// WithCues returns a copy of d holding cues instead, without the warnings:
+// the document to write after processing the cues of d.
+func (d *Document) WithCues(cues []*Subtitle) *Document {
+	c := *d
+	c.Cues = cues
+	c.Warnings = nil
+	return &c
+}
+
+// Read reads the document of r: the comment blocks (NOTE ...) between cues
+// become the Notes of the next cue, and any block before the first cue or
+// after the last one the Header or Trailer. Other blocks that aren't cues are
+// an error, as is a file without cues. A cue named by an identifier instead of
+// a number gets the number after the previous cue.
+//
+// SAMI (.smi) and SubViewer (.sbv) documents are detected and read too.
+func Read(r io.Reader) (*Document, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	switch {
+	case isSAMI(head):
+		return readSAMI(br)
+	case isSBV(head):
+		return readSBV(br)
+	}
+	scanner := bufio.NewScanner(br)
+	doc := &Document{Format: FormatSRT, Encoding: EncodingUTF8}
+	var (
+		pending []string // blocks since the last cue
+		other   bool     // whether a pending block isn't a comment
+		invalid int      // cues whose text isn't valid UTF-8
+	)
+	lines, err := readBlock(scanner)
+	for lines != nil && err == nil {
+		first := len(doc.Cues) == 0 && len(pending) == 0
+		if first && strings.HasPrefix(strings.TrimLeft(lines[0], " \t"), "\uFEFF") {
+			doc.BOM = true
+		}
+		next, nextErr := readBlock(scanner)
+		sub, perr := parseBlock(lines, next == nil && nextErr == nil)
+		if perr != nil {
+			return nil, perr
+		}
+		if sub == nil {
+			block := blockText(lines, first)
+			other = other || !isNote(block)
+			pending = append(pending, block)
+		} else {
+			switch {
+			case len(doc.Cues) == 0:
+				doc.Header = pending
+			case other:
+				return nil, &ParseError{Msg: "invalid subtitle index"}
+			default:
+				sub.Notes = pending
+			}
+			if sub.ID != "" {
+				sub.Idx = 1
+				if len(doc.Cues) > 0 {
+					sub.Idx = doc.Cues[len(doc.Cues)-1].Idx + 1
+				}
+			}
+			if !utf8.ValidString(sub.Text) {
+				invalid++
+			}
+			doc.Cues = append(doc.Cues, sub)
+			pending, other = nil, false
+		}
+		lines, err = next, nextErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Cues) == 0 && len(pending) > 0 {
+		return nil, &ParseError{Msg: "invalid subtitle index"}
+	}
+	doc.Trailer = pending
+	if invalid > 0 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d cues aren't valid UTF-8; the file may use another encoding", invalid))
+	}
+	return doc, nil
+}
+
+// Write writes doc, numbering the cues from 1 (or keeping their indexes with
+// keepIndex).
+func Write(w io.Writer, doc *Document, keepIndex bool) error {
+	if doc.Encoding != "" && doc.Encoding != EncodingUTF8 {
+		return fmt.Errorf("unsupported subtitle encoding: %s", doc.Encoding)
+	}
+	switch doc.Format {
+	case "", FormatSRT, FormatSAMI, FormatSBV:
+	case FormatMicroDVD:
+		return writeMicroDVD(w, doc)
+	case FormatTTML:
+		return writeTTML(w, doc)
+	default:
+		return fmt.Errorf("unsupported subtitle format: %s", doc.Format)
+	}
+	if doc.BOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+	}
+	if err := writeBlocks(w, doc.Header); err != nil {
+		return err
+	}
+	write := WriteAll
+	if keepIndex {
+		write = WriteAllIndexed
+	}
+	if err := write(w, doc.Cues); err != nil {
+		return err
+	}
+	return writeBlocks(w, doc.Trailer)
+}
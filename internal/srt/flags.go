@@ -0,0 +1,47 @@
+package srt
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ForcedTag marks a forced cue in its text. It is an ASS override block, so
+// players that render those ignore it and the others strip it like {\an8}.
+const ForcedTag = `{\forced}`
+
+// ErrNoForcedCues is returned when only the forced cues of a file are wanted
+// and it has none.
+var ErrNoForcedCues = errors.New("no forced cues in the input (forced cues are tagged " + ForcedTag + ")")
+
+var (
+	// sdhLinePattern matches a line that only describes a sound: [door
+	// slams], (laughs), or music notes without lyrics.
+	sdhLinePattern = regexp.MustCompile(`^-?\s*(?:\[[^\[\]]+\]|\([^()]+\)|[♪♫#\s]+)$`)
+	// speakerPattern matches a leading speaker label (JOHN: ...).
+	speakerPattern = regexp.MustCompile(`^-?\s*([A-Z][A-Z0-9 .'-]{0,29}[A-Z0-9.]):\s*\S`)
+)
+
+// InferFlags sets the flags of sub shown by its text: Forced for a cue tagged
+// with ForcedTag, SDH for a cue that only describes sounds, and Speaker from a
+// leading speaker label. Flags already set, e.g. by a richer format, are kept.
+func InferFlags(sub *Subtitle) {
+	if strings.Contains(sub.Text, ForcedTag) {
+		sub.Forced = true
+	}
+	lines := strings.Split(CleanText(VisibleText(sub.Text)), "\n")
+	if lines[0] == "" {
+		return
+	}
+	sdh := true
+	for _, line := range lines {
+		if !sdhLinePattern.MatchString(line) {
+			sdh = false
+			break
+		}
+	}
+	sub.SDH = sub.SDH || sdh
+	if m := speakerPattern.FindStringSubmatch(lines[0]); m != nil && sub.Speaker == "" {
+		sub.Speaker = m[1]
+	}
+}
@@ -0,0 +1,270 @@
+package srt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrNoFrameRate is returned when reading or writing a frame-based format
+// (MicroDVD) without knowing the frame rate of the video.
+var ErrNoFrameRate = errors.New("frame rate unknown: MicroDVD times are frame numbers")
+
+// openEndSeconds is how long, in seconds, the last cue is shown when its end
+// frame is missing ({100}{}...); the others are shown until the next one.
+const openEndSeconds = 3
+
+// microDVDLinePattern matches a MicroDVD cue: {start}{end}text, with the end
+// frame optional.
+var microDVDLinePattern = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+
+// microDVDCodePattern matches a control code: {y:i} styles a line and {Y:i}
+// the whole cue; likewise for colors ({c:$BBGGRR}), fonts, sizes...
+var microDVDCodePattern = regexp.MustCompile(`\{([a-zA-Z]):([^{}]*)\}`)
+
+// FramesToTime returns the time of frame number frames of a video at fps
+// frames per second, rounded to the millisecond.
+func FramesToTime(frames int, fps float64) time.Duration {
+	return time.Duration(float64(frames) / fps * float64(time.Second)).Round(time.Millisecond)
+}
+
+// TimeToFrames returns the number of the frame shown at d in a video at fps
+// frames per second.
+func TimeToFrames(d time.Duration, fps float64) int {
+	return int(math.Round(d.Seconds() * fps))
+}
+
+// IsMicroDVD reports whether b looks like a MicroDVD file: its first line is
+// a cue ({1}{25}...).
+func IsMicroDVD(b []byte) bool {
+	line, _, _ := strings.Cut(string(b[:min(len(b), 512)]), "\n")
+	return microDVDLinePattern.MatchString(strings.TrimSpace(trimUTF8BOM(line)))
+}
+
+type microDVDCue struct {
+	start, end int // end is -1 when missing
+	text       string
+}
+
+// ReadMicroDVD reads a MicroDVD (.sub) document, whose times are frame
+// numbers of a video at fps frames per second. When fps is not positive, the
+// frame rate of the file is used: the text of a first cue at frame 0 or 1
+// ({1}{1}23.976), as most players do; without it, ErrNoFrameRate is
+// returned. Lines are separated by |, and the style codes of lines and cues
+// (italic, bold, underline, color) become tags; other codes are dropped.
+func ReadMicroDVD(r io.Reader, fps float64) (*Document, error) {
+	scanner := bufio.NewScanner(r)
+	doc := &Document{Format: FormatMicroDVD, Encoding: EncodingUTF8}
+	var cues []microDVDCue
+	var fileFPS float64
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 && strings.HasPrefix(line, "\uFEFF") {
+			doc.BOM = true
+			line = trimUTF8BOM(line)
+		}
+		if line == "" {
+			continue
+		}
+		m := microDVDLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			return nil, &ParseError{Msg: fmt.Sprintf("invalid MicroDVD cue at line %d", n)}
+		}
+		start, _ := strconv.Atoi(m[1])
+		end := -1
+		if m[2] != "" {
+			end, _ = strconv.Atoi(m[2])
+		}
+		if len(cues) == 0 && fileFPS == 0 && start <= 1 && end <= 1 {
+			if rate, err := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); err == nil && rate > 0 {
+				fileFPS = rate
+				continue
+			}
+		}
+		cues = append(cues, microDVDCue{start: start, end: end, text: m[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cues) == 0 {
+		return nil, &ParseError{Msg: "no MicroDVD cues found"}
+	}
+	if fps <= 0 {
+		fps = fileFPS
+	}
+	if fps <= 0 {
+		return nil, ErrNoFrameRate
+	}
+	doc.FrameRate = fps
+
+	invalid := 0
+	for i, c := range cues {
+		if c.end < 0 {
+			c.end = c.start + int(math.Round(openEndSeconds*fps))
+			if i+1 < len(cues) {
+				c.end = cues[i+1].start
+			}
+		}
+		sub := &Subtitle{
+			Idx:      i + 1,
+			FromTime: FramesToTime(c.start, fps),
+			ToTime:   FramesToTime(c.end, fps),
+			Text:     microDVDToTags(c.text),
+		}
+		if !utf8.ValidString(sub.Text) {
+			invalid++
+		}
+		InferFlags(sub)
+		doc.Cues = append(doc.Cues, sub)
+	}
+	if invalid > 0 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d cues aren't valid UTF-8; the file may use another encoding", invalid))
+	}
+	return doc, nil
+}
+
+// microDVDToTags converts the text of a MicroDVD cue to the text of a cue:
+// lines separated by newlines and style codes replaced by tags. A line
+// starting with / is in italics, as in some old files.
+func microDVDToTags(raw string) string {
+	var cueTags []string
+	lines := strings.Split(raw, "|")
+	for i, line := range lines {
+		var lineTags []string
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "/"); ok {
+			line = "<i>" + rest
+			lineTags = append(lineTags, "<i>")
+		}
+		line = microDVDCodePattern.ReplaceAllStringFunc(line, func(code string) string {
+			m := microDVDCodePattern.FindStringSubmatch(code)
+			tags := microDVDTags(unicode.ToLower(rune(m[1][0])), m[2])
+			if unicode.IsUpper(rune(m[1][0])) {
+				cueTags = append(cueTags, tags...)
+				return ""
+			}
+			lineTags = append(lineTags, tags...)
+			return strings.Join(tags, "")
+		})
+		lines[i] = line + closingTags(lineTags)
+	}
+	text := strings.Join(cueTags, "") + strings.Join(lines, "\n") + closingTags(cueTags)
+	return CleanText(text)
+}
+
+// microDVDTags returns the opening tags of a style (y) or color (c) code;
+// other codes have none.
+func microDVDTags(kind rune, value string) []string {
+	var tags []string
+	switch kind {
+	case 'y':
+		for style := range strings.SplitSeq(strings.ToLower(value), ",") {
+			if style = strings.TrimSpace(style); style == "i" || style == "b" || style == "u" || style == "s" {
+				tags = append(tags, "<"+style+">")
+			}
+		}
+	case 'c':
+		if bgr, ok := strings.CutPrefix(strings.TrimSpace(value), "$"); ok && isHexColor(bgr) {
+			bgr = strings.ToUpper(bgr)
+			tags = append(tags, `<font color="#`+bgr[4:6]+bgr[2:4]+bgr[0:2]+`">`)
+		}
+	}
+	return tags
+}
+
+func isHexColor(s string) bool {
+	if len(s) != 6 {
+		return false
+	}
+	_, err := strconv.ParseUint(s, 16, 32)
+	return err == nil
+}
+
+// closingTags returns the closing tags of the opening tags, in reverse order.
+func closingTags(tags []string) string {
+	var b strings.Builder
+	for _, tag := range slices.Backward(tags) {
+		name, _, _ := strings.Cut(strings.Trim(tag, "<>"), " ")
+		b.WriteString("</" + name + ">")
+	}
+	return b.String()
+}
+
+// writeMicroDVD writes doc as MicroDVD at doc.FrameRate, starting with the
+// frame rate cue ({1}{1}23.976). Notes, header and trailer blocks are
+// dropped: the format has none.
+func writeMicroDVD(w io.Writer, doc *Document) error {
+	if doc.FrameRate <= 0 {
+		return ErrNoFrameRate
+	}
+	if doc.BOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+	}
+	rate := strconv.FormatFloat(math.Round(doc.FrameRate*1000)/1000, 'f', -1, 64)
+	if _, err := fmt.Fprintf(w, "{1}{1}%s\n", rate); err != nil {
+		return err
+	}
+	for _, sub := range doc.Cues {
+		_, err := fmt.Fprintf(w, "{%d}{%d}%s\n",
+			TimeToFrames(sub.FromTime, doc.FrameRate), TimeToFrames(sub.ToTime, doc.FrameRate), tagsToMicroDVD(sub.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagsToMicroDVD converts the text of a cue to MicroDVD: lines separated by
+// |, and the styles (italic, bold, underline, strikeout, color) that cover
+// whole lines turned into codes, for the cue ({Y:i}) when they cover every
+// line. Styles of part of a line are dropped.
+func tagsToMicroDVD(text string) string {
+	lines := styledLines(text)
+	cueStyles := commonStyles(slices.Concat(lines...))
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		t := strings.TrimSpace(runsText(line))
+		if t == "" {
+			continue
+		}
+		styles := slices.DeleteFunc(commonStyles(line), func(s string) bool { return slices.Contains(cueStyles, s) })
+		out = append(out, microDVDCodes(styles, false)+t)
+	}
+	return microDVDCodes(cueStyles, true) + strings.Join(out, "|")
+}
+
+// microDVDCodes returns the codes of styles: {y:i,b} and {c:$BBGGRR}, in
+// upper case for the whole cue.
+func microDVDCodes(styles []string, cue bool) string {
+	var faces []string
+	color := ""
+	for _, s := range styles {
+		if strings.HasPrefix(s, "#") {
+			color = "$" + s[5:7] + s[3:5] + s[1:3]
+		} else {
+			faces = append(faces, s)
+		}
+	}
+	y, c := "y", "c"
+	if cue {
+		y, c = "Y", "C"
+	}
+	var b strings.Builder
+	if len(faces) > 0 {
+		b.WriteString("{" + y + ":" + strings.Join(faces, ",") + "}")
+	}
+	if color != "" {
+		b.WriteString("{" + c + ":" + color + "}")
+	}
+	return b.String()
+}
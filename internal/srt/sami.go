@@ -0,0 +1,177 @@
+package srt
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	samiSyncPattern    = regexp.MustCompile(`(?i)<sync\b[^>]*?\bstart\s*=\s*["']?(\d+)[^>]*>`)
+	samiClassPattern   = regexp.MustCompile(`(?i)<p\b[^>]*?\bclass\s*=\s*["']?([\w-]+)[^>]*>`)
+	samiLangPattern    = regexp.MustCompile(`(?i)\.([\w-]+)\s*\{[^}]*?\blang\s*:\s*([\w-]+)`)
+	samiTagPattern     = regexp.MustCompile(`<(/?)([a-zA-Z]+)([^>]*)>`)
+	samiColorPattern   = regexp.MustCompile(`(?i)\bcolor\s*=\s*["']?([#\w]+)`)
+	samiCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	samiBodyPattern    = regexp.MustCompile(`(?i)<body\b[^>]*>`)
+)
+
+// isSAMI reports whether b looks like a SAMI (.smi) file: a <SAMI> element
+// near its start.
+func isSAMI(b []byte) bool {
+	return bytes.Contains(bytes.ToLower(b[:min(len(b), 512)]), []byte("<sami"))
+}
+
+type samiSync struct {
+	start time.Duration
+	text  string
+}
+
+// readSAMI reads a SAMI document, the format of Windows Media Player and of
+// many old Korean and Japanese releases: each <SYNC Start=ms> starts a cue
+// that lasts until the next one, and a SYNC without text (&nbsp;) ends it. Of
+// the paragraphs of several languages (classes), only those of the first one
+// are read, and its lang becomes the Language. <br> breaks lines and the
+// italic, bold, underline, strikeout and font color tags are kept; other
+// markup is dropped.
+func readSAMI(r io.Reader) (*Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src := string(b)
+	doc := &Document{Format: FormatSAMI, Encoding: EncodingUTF8, BOM: strings.HasPrefix(src, "\uFEFF")}
+
+	// The style sheet declaring the languages is usually in a comment.
+	languages := map[string]string{}
+	for _, m := range samiLangPattern.FindAllStringSubmatch(src, -1) {
+		languages[strings.ToLower(m[1])] = m[2]
+	}
+	src = samiCommentPattern.ReplaceAllString(src, "")
+	if loc := samiBodyPattern.FindStringIndex(src); loc != nil {
+		src = src[loc[1]:]
+	}
+
+	var classes []string
+	for _, m := range samiClassPattern.FindAllStringSubmatch(src, -1) {
+		if class := strings.ToLower(m[1]); !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+	class := ""
+	if len(classes) > 0 {
+		class = classes[0]
+		doc.Language = languages[class]
+	}
+	if len(classes) > 1 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d languages (%s); only %s was read", len(classes), strings.Join(classes, ", "), class))
+	}
+
+	var syncs []samiSync
+	locs := samiSyncPattern.FindAllStringSubmatchIndex(src, -1)
+	for i, loc := range locs {
+		end := len(src)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		content, ok := samiParagraph(src[loc[1]:end], class)
+		if !ok {
+			continue
+		}
+		ms, _ := strconv.Atoi(src[loc[2]:loc[3]])
+		syncs = append(syncs, samiSync{start: time.Duration(ms) * time.Millisecond, text: samiText(content)})
+	}
+	slices.SortStableFunc(syncs, func(a, b samiSync) int { return cmp.Compare(a.start, b.start) })
+
+	invalid := 0
+	for i, s := range syncs {
+		if s.text == "" {
+			continue
+		}
+		end := s.start + openEndSeconds*time.Second
+		if i+1 < len(syncs) {
+			end = syncs[i+1].start
+		}
+		if end <= s.start {
+			continue
+		}
+		sub := &Subtitle{Idx: len(doc.Cues) + 1, FromTime: s.start, ToTime: end, Text: s.text}
+		if !utf8.ValidString(sub.Text) {
+			invalid++
+		}
+		InferFlags(sub)
+		doc.Cues = append(doc.Cues, sub)
+	}
+	if len(doc.Cues) == 0 {
+		return nil, &ParseError{Msg: "no SAMI cues found"}
+	}
+	if invalid > 0 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d cues aren't valid UTF-8; the file may use another encoding", invalid))
+	}
+	return doc, nil
+}
+
+// samiParagraph returns the content of the paragraphs of class in the content
+// of a SYNC, or the whole content when it has no classed paragraphs. It
+// reports false when the SYNC only holds paragraphs of other classes.
+func samiParagraph(content, class string) (string, bool) {
+	locs := samiClassPattern.FindAllStringSubmatchIndex(content, -1)
+	if len(locs) == 0 {
+		return content, true
+	}
+	var parts []string
+	for i, loc := range locs {
+		if !strings.EqualFold(content[loc[2]:loc[3]], class) {
+			continue
+		}
+		end := len(content)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		parts = append(parts, content[loc[1]:end])
+	}
+	return strings.Join(parts, "<br>"), len(parts) > 0
+}
+
+// samiText converts the HTML of a SAMI paragraph to the text of a cue.
+func samiText(content string) string {
+	var fonts []bool // whether each open <font> was kept
+	text := samiTagPattern.ReplaceAllStringFunc(whitespacePattern.ReplaceAllString(content, " "), func(tag string) string {
+		m := samiTagPattern.FindStringSubmatch(tag)
+		closing, name := m[1] == "/", strings.ToLower(m[2])
+		switch name {
+		case "br":
+			return "\n"
+		case "i", "b", "u", "s":
+			return "<" + m[1] + name + ">"
+		case "font":
+			if closing {
+				if len(fonts) == 0 {
+					return ""
+				}
+				kept := fonts[len(fonts)-1]
+				fonts = fonts[:len(fonts)-1]
+				if kept {
+					return "</font>"
+				}
+				return ""
+			}
+			color := samiColorPattern.FindStringSubmatch(m[3])
+			fonts = append(fonts, color != nil)
+			if color != nil {
+				return `<font color="` + color[1] + `">`
+			}
+		}
+		return ""
+	})
+	text = strings.ReplaceAll(html.UnescapeString(text), "\u00a0", " ")
+	return CleanText(text)
+}
@@ -0,0 +1,100 @@
+package srt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// sbvTimingPattern matches the timing line of a SubViewer cue: start and end
+// separated by a comma, with milliseconds (YouTube's .sbv) or hundredths
+// (SubViewer 2.0).
+var sbvTimingPattern = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})\.(\d{2,3})\s*,\s*(\d+):(\d{2}):(\d{2})\.(\d{2,3})$`)
+
+// isSBV reports whether b looks like a SubViewer (.sbv, .sub) file: its first
+// line, after any [INFORMATION] header, is a timing line.
+func isSBV(b []byte) bool {
+	for line := range strings.SplitSeq(string(b[:min(len(b), 512)]), "\n") {
+		line = strings.TrimSpace(trimUTF8BOM(line))
+		if line == "" || strings.HasPrefix(line, "[") {
+			continue
+		}
+		return sbvTimingPattern.MatchString(line)
+	}
+	return false
+}
+
+// readSBV reads a SubViewer document, the format of YouTube's caption
+// exports (.sbv): cues of a timing line (0:00:01.000,0:00:03.500) and text,
+// separated by blank lines. The [INFORMATION] header of SubViewer 2.0 is
+// dropped and its [br] line breaks become newlines.
+func readSBV(r io.Reader) (*Document, error) {
+	scanner := bufio.NewScanner(r)
+	doc := &Document{Format: FormatSBV, Encoding: EncodingUTF8}
+	var sub *Subtitle
+	invalid := 0
+	flush := func() {
+		if sub == nil {
+			return
+		}
+		sub.Text = CleanText(strings.ReplaceAll(sub.Text, "[br]", "\n"))
+		if !utf8.ValidString(sub.Text) {
+			invalid++
+		}
+		InferFlags(sub)
+		doc.Cues = append(doc.Cues, sub)
+		sub = nil
+	}
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 && strings.HasPrefix(line, "\uFEFF") {
+			doc.BOM = true
+			line = trimUTF8BOM(line)
+		}
+		if line == "" {
+			flush()
+			continue
+		}
+		if sub != nil {
+			sub.Text += "\n" + line
+			continue
+		}
+		m := sbvTimingPattern.FindStringSubmatch(line)
+		if m == nil {
+			if len(doc.Cues) == 0 && strings.HasPrefix(line, "[") {
+				continue // header
+			}
+			return nil, &ParseError{Msg: fmt.Sprintf("invalid SubViewer timing at line %d", n)}
+		}
+		sub = &Subtitle{Idx: len(doc.Cues) + 1, FromTime: sbvTime(m[1:5]), ToTime: sbvTime(m[5:9])}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	if len(doc.Cues) == 0 {
+		return nil, &ParseError{Msg: "no SubViewer cues found"}
+	}
+	if invalid > 0 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d cues aren't valid UTF-8; the file may use another encoding", invalid))
+	}
+	return doc, nil
+}
+
+// sbvTime returns the time of the hours, minutes, seconds and fraction of a
+// timing.
+func sbvTime(parts []string) time.Duration {
+	h, _ := strconv.Atoi(parts[0])
+	m, _ := strconv.Atoi(parts[1])
+	s, _ := strconv.Atoi(parts[2])
+	frac, _ := strconv.Atoi(parts[3])
+	if len(parts[3]) == 2 {
+		frac *= 10
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(frac)*time.Millisecond
+}
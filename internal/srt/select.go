@@ -0,0 +1,147 @@
+package srt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoSelectedCues is returned when a Selection matches no cue of a file.
+var ErrNoSelectedCues = errors.New("no cue matches the selected indexes and time ranges")
+
+// Selection picks the cues to process by index and by time. A cue is
+// selected when its index is in one of Idxs and it overlaps one of Times; an
+// empty list doesn't restrict, so the zero Selection selects every cue.
+type Selection struct {
+	Idxs  []IdxRange
+	Times []TimeRange
+}
+
+// IdxRange is an inclusive range of cue indexes. To is 0 for a range open at
+// the end.
+type IdxRange struct {
+	From, To int
+}
+
+// TimeRange is a span of the timeline. To is 0 for a range open at the end.
+type TimeRange struct {
+	From, To time.Duration
+}
+
+// IsZero reports whether s selects every cue.
+func (s Selection) IsZero() bool {
+	return len(s.Idxs) == 0 && len(s.Times) == 0
+}
+
+// Matches reports whether sub is selected.
+func (s Selection) Matches(sub *Subtitle) bool {
+	return s.matchesIdx(sub.Idx) && s.matchesTime(sub)
+}
+
+func (s Selection) matchesIdx(idx int) bool {
+	if len(s.Idxs) == 0 {
+		return true
+	}
+	for _, r := range s.Idxs {
+		if idx >= r.From && (r.To == 0 || idx <= r.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTime reports whether sub overlaps a time range: a cue starting
+// before a range and ending inside it is selected.
+func (s Selection) matchesTime(sub *Subtitle) bool {
+	if len(s.Times) == 0 {
+		return true
+	}
+	for _, r := range s.Times {
+		if (r.To == 0 || sub.FromTime < r.To) && (sub.ToTime > r.From || sub.FromTime >= r.From) {
+			return true
+		}
+	}
+	return false
+}
+
+// Split returns the cues of subs that s selects and the others, both in the
+// order of subs.
+func (s Selection) Split(subs []*Subtitle) (selected, others []*Subtitle) {
+	if s.IsZero() {
+		return subs, nil
+	}
+	for _, sub := range subs {
+		if s.Matches(sub) {
+			selected = append(selected, sub)
+		} else {
+			others = append(others, sub)
+		}
+	}
+	return selected, others
+}
+
+// ParseIdxRanges parses a comma-separated list of cue indexes and inclusive
+// ranges, open at either end: "120-180", "5,8,10-" or "-20".
+func ParseIdxRanges(s string) ([]IdxRange, error) {
+	var ranges []IdxRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		r, err := parseIdxRange(strings.TrimSpace(from), strings.TrimSpace(to), isRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cue range %q: %w", part, err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseIdxRange(from, to string, isRange bool) (IdxRange, error) {
+	r := IdxRange{From: 1}
+	var err error
+	if from != "" {
+		if r.From, err = strconv.Atoi(from); err != nil || r.From < 1 {
+			return IdxRange{}, errors.New("indexes start at 1")
+		}
+	}
+	if !isRange {
+		r.To = r.From
+		return r, nil
+	}
+	if to != "" {
+		if r.To, err = strconv.Atoi(to); err != nil || r.To < r.From {
+			return IdxRange{}, errors.New("the end must be an index not before the start")
+		}
+	}
+	return r, nil
+}
+
+// ParseTimeRange parses a time range, with both ends given as in ParseTime
+// and either one optional: "00:10:00-00:20:00", "10m-20m" or "01:30:00-".
+func ParseTimeRange(s string) (TimeRange, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return TimeRange{}, fmt.Errorf("invalid time range %q (use start-end, e.g. 00:10:00-00:20:00)", s)
+	}
+	var r TimeRange
+	var err error
+	if strings.TrimSpace(from) != "" {
+		if r.From, err = ParseTime(from); err != nil {
+			return TimeRange{}, err
+		}
+	}
+	if strings.TrimSpace(to) != "" {
+		if r.To, err = ParseTime(to); err != nil {
+			return TimeRange{}, err
+		}
+		if r.To <= r.From {
+			return TimeRange{}, fmt.Errorf("invalid time range %q: the end must be after the start", s)
+		}
+	}
+	return r, nil
+}
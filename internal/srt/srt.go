@@ -2,7 +2,6 @@ package srt
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"io"
 	"regexp"
@@ -17,8 +16,22 @@ type Subtitle struct {
 	FromTime time.Duration
 	ToTime   time.Duration
 	Text     string
+	// ID is the identifier line of a cue that has a name instead of a number
+	// (e.g. "intro"); it is written in place of the number.
+	ID string
+	// Notes are the comment blocks (NOTE ...) before the cue, kept verbatim.
+	Notes []string
+	// Forced cues are shown even with subtitles off (foreign dialogue, signs),
+	// SDH cues are only meant for deaf and hard of hearing viewers, and
+	// Speaker names who speaks. Read infers them from the text (InferFlags).
+	Forced  bool
+	SDH     bool
+	Speaker string
 }
 
+// notePrefix starts a comment block, as in WebVTT.
+const notePrefix = "NOTE"
+
 var timeFramePattern = regexp.MustCompile(`(\d+):(\d+):(\d+),(\d+) --> (\d+):(\d+):(\d+),(\d+)`)
 
 func getDuration(parts []string) time.Duration {
@@ -61,104 +74,146 @@ func CleanText(text string) string {
 	return strings.Join(cleaned, "\n")
 }
 
-// readStructuralLine reads structural SRT lines (index and timing), normalizes
-// whitespace, and strips a leading UTF-8 BOM before parsing those fields.
-func readStructuralLine(scanner *bufio.Scanner) (string, error) {
-	if !scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			return "", err
-		}
-		return "", io.EOF
-	}
-	text := scanner.Text()
-	text = trimUTF8BOM(text)
-	text = CleanText(text)
-	text = trimUTF8BOM(text)
-	return text, nil
+// ParseError is returned when the input is not a valid SRT file.
+type ParseError struct {
+	Msg string
 }
 
-// readCueContent reads raw subtitle content lines until a physically empty line
-// and then applies a single normalization pass.
-func readCueContent(scanner *bufio.Scanner) (string, error) {
+func (e *ParseError) Error() string { return e.Msg }
+
+// readBlock reads the lines of the next block, up to a physically empty line,
+// skipping the blank lines before it. It returns nil at the end of the input.
+func readBlock(scanner *bufio.Scanner) ([]string, error) {
 	var lines []string
 	for scanner.Scan() {
 		line := scanner.Text()
-		if line == "" {
-			break
+		if lines == nil {
+			if CleanText(trimUTF8BOM(line)) == "" {
+				continue
+			}
+		} else if line == "" {
+			return lines, nil
 		}
 		lines = append(lines, line)
 	}
-	if err := scanner.Err(); err != nil {
-		return "", err
-	}
-	return CleanText(strings.Join(lines, "\n")), nil
+	return lines, scanner.Err()
 }
 
-func ReadOne(scanner *bufio.Scanner) (*Subtitle, error) {
-	// Read lines until we find a non-empty one for the subtitle index
-	var idxRaw string
-	for {
-		var err error
-		idxRaw, err = readStructuralLine(scanner)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				return nil, nil
-			}
-			return nil, err
-		}
-		if strings.TrimSpace(idxRaw) != "" {
-			break
-		}
+// structuralLine normalizes an index or timing line: whitespace and a leading
+// UTF-8 BOM are removed.
+func structuralLine(line string) string {
+	return trimUTF8BOM(CleanText(trimUTF8BOM(line)))
+}
+
+// parseBlock parses a block as a cue, or returns nil for a block that isn't
+// one: its first line is neither a number nor an identifier followed by a
+// timing line. atEOF tells whether the block is the last of the input.
+func parseBlock(lines []string, atEOF bool) (*Subtitle, error) {
+	idxRaw := structuralLine(lines[0])
+	idx, idxErr := strconv.Atoi(idxRaw)
+	var timing []string
+	if len(lines) > 1 {
+		timing = timeFramePattern.FindStringSubmatch(structuralLine(lines[1]))
 	}
-	idx, err := strconv.Atoi(idxRaw)
-	if err != nil {
-		return nil, errors.New("invalid subtitle index")
+	if idxErr != nil && timing == nil {
+		return nil, nil
 	}
-	timingRaw, err := readStructuralLine(scanner)
-	if err != nil {
-		if errors.Is(err, io.EOF) {
-			return nil, errors.New("could not find subtitle timing")
+	if timing == nil {
+		if len(lines) == 1 && atEOF {
+			return nil, &ParseError{Msg: "could not find subtitle timing"}
 		}
-		return nil, err
+		return nil, &ParseError{Msg: "invalid subtitle timing"}
 	}
-	timing := timeFramePattern.FindStringSubmatch(timingRaw)
-	if timing == nil {
-		return nil, errors.New("invalid subtitle timing")
+	sub := &Subtitle{
+		Idx:      idx,
+		FromTime: getDuration(timing[1:5]),
+		ToTime:   getDuration(timing[5:9]),
+		Text:     CleanText(strings.Join(lines[2:], "\n")),
 	}
-	fromTime := getDuration(timing[1:5])
-	toTime := getDuration(timing[5:9])
-	content, err := readCueContent(scanner)
-	if err != nil {
-		return nil, err
+	if idxErr != nil {
+		sub.ID = idxRaw
 	}
-	return &Subtitle{Idx: idx, FromTime: fromTime, ToTime: toTime, Text: content}, nil
+	InferFlags(sub)
+	return sub, nil
 }
 
-func ReadAll(r io.Reader) ([]*Subtitle, error) {
-	scanner := bufio.NewScanner(r)
-	var subs []*Subtitle
+// blockText returns the lines of a block that isn't a cue, as written back.
+func blockText(lines []string, first bool) string {
+	if first {
+		lines[0] = trimUTF8BOM(strings.TrimLeft(lines[0], " \t"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isNote(block string) bool {
+	rest, ok := strings.CutPrefix(block, notePrefix)
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n')
+}
+
+// ReadOne reads the next cue, with the comment blocks before it as its Notes.
+// It returns nil at the end of the input.
+func ReadOne(scanner *bufio.Scanner) (*Subtitle, error) {
+	var notes []string
 	for {
-		s, err := ReadOne(scanner)
+		lines, err := readBlock(scanner)
 		if err != nil {
 			return nil, err
 		}
-		if s == nil {
-			break
+		if lines == nil {
+			return nil, nil
+		}
+		sub, err := parseBlock(lines, false)
+		if err != nil {
+			return nil, err
+		}
+		if sub != nil {
+			sub.Notes = notes
+			return sub, nil
 		}
-		subs = append(subs, s)
+		note := blockText(lines, false)
+		if !isNote(note) {
+			return nil, &ParseError{Msg: "invalid subtitle index"}
+		}
+		notes = append(notes, note)
 	}
-	return subs, nil
 }
 
+// ReadAll reads the cues of r; see Read for the rest of the document.
+func ReadAll(r io.Reader) ([]*Subtitle, error) {
+	doc, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Cues, nil
+}
+
+// WriteOne writes subtitle, preceded by its Notes, numbered *idx (or named by
+// its ID), and increments *idx.
 func WriteOne(w io.Writer, subtitle *Subtitle, idx *int) error {
+	if err := writeBlocks(w, subtitle.Notes); err != nil {
+		return err
+	}
+	var id any = *idx
+	if subtitle.ID != "" {
+		id = subtitle.ID
+	}
 	_, err := fmt.Fprint(w,
-		*idx, "\n",
+		id, "\n",
 		formatDuration(subtitle.FromTime), " --> ", formatDuration(subtitle.ToTime), "\n",
 		CleanText(subtitle.Text), "\n\n")
 	*idx++
 	return err
 }
 
+func writeBlocks(w io.Writer, blocks []string) error {
+	for _, b := range blocks {
+		if _, err := fmt.Fprint(w, b, "\n\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func WriteAll(w io.Writer, subs []*Subtitle) error {
 	idx := 1
 	for _, s := range subs {
@@ -169,6 +224,17 @@ func WriteAll(w io.Writer, subs []*Subtitle) error {
 	return nil
 }
 
+// WriteAllIndexed writes subs keeping their own indexes.
+func WriteAllIndexed(w io.Writer, subs []*Subtitle) error {
+	for _, s := range subs {
+		idx := s.Idx
+		if err := WriteOne(w, s, &idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Sort sorts subtitles in-place by FromTime; if equal, by ToTime; if still equal, by Idx.
 func Sort(subtitles []*Subtitle) {
 	sort.Slice(subtitles, func(i, j int) bool {
@@ -190,7 +256,7 @@ func ValidateSequentialIdx(subtitles []*Subtitle) error {
 		}
 		expected := i + 1
 		if s.Idx != expected {
-			return fmt.Errorf("invalid subtitle index at position %d: expected %d, got %d", i+1, expected, s.Idx)
+			return &ParseError{Msg: fmt.Sprintf("invalid subtitle index at position %d: expected %d, got %d", i+1, expected, s.Idx)}
 		}
 	}
 	return nil
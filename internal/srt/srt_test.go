@@ -317,3 +317,463 @@ func TestReadAll_PropagatesScannerErrTooLong(t *testing.T) {
 		t.Fatalf("expected bufio.ErrTooLong, got %v", err)
 	}
 }
+
+func TestRead_KeepsMetadata(t *testing.T) {
+	in := "\ufeffTitle: Pilot\nLanguage: en\n\n" +
+		"1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"NOTE translator: keep the pun\n\n" +
+		"intro\n00:00:03,000 --> 00:00:04,000\nWorld\n\n" +
+		"Subtitles by someone\n"
+	doc, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if doc.Format != FormatSRT || doc.Encoding != EncodingUTF8 || len(doc.Warnings) != 0 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if !doc.BOM || len(doc.Header) != 1 || doc.Header[0] != "Title: Pilot\nLanguage: en" {
+		t.Fatalf("unexpected header: %+v", doc)
+	}
+	if len(doc.Trailer) != 1 || doc.Trailer[0] != "Subtitles by someone" {
+		t.Fatalf("unexpected trailer: %q", doc.Trailer)
+	}
+	subs := doc.Cues
+	if len(subs) != 2 || subs[1].ID != "intro" || subs[1].Idx != 2 || len(subs[1].Notes) != 1 || subs[1].Notes[0] != "NOTE translator: keep the pun" {
+		t.Fatalf("unexpected cues: %+v %+v", subs[0], subs[1])
+	}
+
+	var b strings.Builder
+	if err := Write(&b, doc, false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if b.String() != in+"\n" {
+		t.Fatalf("round trip:\n got %q\nwant %q", b.String(), in+"\n")
+	}
+}
+
+func TestRead_RejectsNonCueBlocks(t *testing.T) {
+	for name, in := range map[string]string{
+		"no_cues":      "just some text\n\nmore text\n",
+		"between_cues": "1\n00:00:01,000 --> 00:00:02,000\nA\n\nstray text\n\n2\n00:00:03,000 --> 00:00:04,000\nB\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var pe *ParseError
+			if _, err := Read(strings.NewReader(in)); !errors.As(err, &pe) {
+				t.Fatalf("expected ParseError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRead_WarnsOnInvalidUTF8(t *testing.T) {
+	doc, err := Read(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nOl\xe1\n\n"))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(doc.Warnings) != 1 || !strings.Contains(doc.Warnings[0], "valid UTF-8") {
+		t.Fatalf("unexpected warnings: %q", doc.Warnings)
+	}
+	if out := doc.WithCues(nil); len(out.Warnings) != 0 || out.Encoding != doc.Encoding {
+		t.Fatalf("WithCues kept the warnings: %+v", out)
+	}
+}
+
+func TestWrite_UnsupportedFormat(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, &Document{Format: "ass"}, false); err == nil {
+		t.Fatal("expected an error writing an unsupported format")
+	}
+}
+
+func TestInferFlags(t *testing.T) {
+	cases := []struct {
+		text    string
+		forced  bool
+		sdh     bool
+		speaker string
+	}{
+		{text: "Hello"},
+		{text: "{\\forced}Welcome to Berlin", forced: true},
+		{text: "[DOOR SLAMS]", sdh: true},
+		{text: "<i>(laughs)</i>\n- [sighs]", sdh: true},
+		{text: "♪ ♪", sdh: true},
+		{text: "♪ Happy birthday ♪"},
+		{text: "[door slams]\nWho's there?"},
+		{text: "JOHN: Who's there?", speaker: "JOHN"},
+		{text: "- MR. SMITH: Sit down.", speaker: "MR. SMITH"},
+		{text: "Note: it's late"},
+	}
+	for _, tc := range cases {
+		sub := &Subtitle{Text: tc.text}
+		InferFlags(sub)
+		if sub.Forced != tc.forced || sub.SDH != tc.sdh || sub.Speaker != tc.speaker {
+			t.Errorf("InferFlags(%q) = forced %v, sdh %v, speaker %q", tc.text, sub.Forced, sub.SDH, sub.Speaker)
+		}
+	}
+
+	// Flags set by a richer format are kept.
+	sub := &Subtitle{Text: "Hello", Forced: true, Speaker: "Ann"}
+	if InferFlags(sub); !sub.Forced || sub.Speaker != "Ann" {
+		t.Fatalf("InferFlags cleared flags: %+v", sub)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"00:45:00":     45 * time.Minute,
+		"01:02:03,500": time.Hour + 2*time.Minute + 3*time.Second + 500*time.Millisecond,
+		"0:00:01.5":    1500 * time.Millisecond,
+		"45m":          45 * time.Minute,
+		"1h2m":         time.Hour + 2*time.Minute,
+	} {
+		if got, err := ParseTime(in); err != nil || got != want {
+			t.Errorf("ParseTime(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "00:61:00", "45 minutes", "00:45"} {
+		if _, err := ParseTime(in); err == nil {
+			t.Errorf("ParseTime(%q) accepted an invalid time", in)
+		}
+	}
+}
+
+func TestSelection(t *testing.T) {
+	idxs, err := ParseIdxRanges("2, 5-6,9-")
+	if err != nil {
+		t.Fatalf("ParseIdxRanges: %v", err)
+	}
+	r, err := ParseTimeRange("00:00:10-20s")
+	if err != nil {
+		t.Fatalf("ParseTimeRange: %v", err)
+	}
+	sel := Selection{Idxs: idxs, Times: []TimeRange{r}}
+
+	var subs []*Subtitle
+	for i := 1; i <= 10; i++ {
+		from := time.Duration(i*3) * time.Second
+		subs = append(subs, &Subtitle{Idx: i, FromTime: from, ToTime: from + 2*time.Second})
+	}
+	// Cue 2 (6-8s) is before the range, cue 9 (27-29s) after it; cue 5
+	// (15-17s) and 6 (18-20s) are inside.
+	selected, others := sel.Split(subs)
+	if len(selected) != 2 || selected[0].Idx != 5 || selected[1].Idx != 6 || len(others) != 8 {
+		t.Fatalf("unexpected split: %d selected, %d others", len(selected), len(others))
+	}
+	if all, none := (Selection{}).Split(subs); len(all) != len(subs) || none != nil {
+		t.Fatalf("the zero Selection must select every cue")
+	}
+	// A cue starting before the range and ending inside it is selected.
+	if !(Selection{Times: []TimeRange{{From: 7 * time.Second}}}).Matches(subs[1]) {
+		t.Fatalf("expected the overlapping cue to be selected")
+	}
+
+	for _, in := range []string{"0", "5-3", "a", "3-x"} {
+		if _, err := ParseIdxRanges(in); err == nil {
+			t.Errorf("ParseIdxRanges(%q) accepted an invalid range", in)
+		}
+	}
+	for _, in := range []string{"00:10:00", "00:20:00-00:10:00", "x-10m"} {
+		if _, err := ParseTimeRange(in); err == nil {
+			t.Errorf("ParseTimeRange(%q) accepted an invalid range", in)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	cases := map[string]int{
+		"Hello":             5,
+		"<i>Hello</i>":      5,
+		"こんにちは":             10,
+		"안녕하세요":             10,
+		"你好，世界":             10,
+		"Café\nbar":         7,
+		"e\u0301":           1, // combining accent
+		"\u200fשלום!\u200f": 5,
+	}
+	for text, want := range cases {
+		if got := DisplayWidth(text); got != want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestReadMicroDVD(t *testing.T) {
+	in := "{1}{1}25\n" +
+		"{25}{75}Hello|{y:i}world\n" +
+		"{100}{150}{Y:b}{C:$0000FF}Both|lines\n" +
+		"{200}{}/Italic line|{f:Arial}plain\n" +
+		"{300}{}Last\n"
+	doc, err := ReadMicroDVD(strings.NewReader(in), 0)
+	if err != nil {
+		t.Fatalf("ReadMicroDVD: %v", err)
+	}
+	if doc.Format != FormatMicroDVD || doc.FrameRate != 25 {
+		t.Fatalf("format %q at %g fps, want microdvd at 25", doc.Format, doc.FrameRate)
+	}
+	want := []struct {
+		from, to time.Duration
+		text     string
+	}{
+		{time.Second, 3 * time.Second, "Hello\n<i>world</i>"},
+		{4 * time.Second, 6 * time.Second, "<b><font color=\"#FF0000\">Both\nlines</font></b>"},
+		{8 * time.Second, 12 * time.Second, "<i>Italic line</i>\nplain"}, // shown until the next cue
+		{12 * time.Second, 15 * time.Second, "Last"},
+	}
+	if len(doc.Cues) != len(want) {
+		t.Fatalf("got %d cues, want %d", len(doc.Cues), len(want))
+	}
+	for i, w := range want {
+		if c := doc.Cues[i]; c.Idx != i+1 || c.FromTime != w.from || c.ToTime != w.to || c.Text != w.text {
+			t.Errorf("cue %d = %d %v --> %v %q, want %v --> %v %q", i, c.Idx, c.FromTime, c.ToTime, c.Text, w.from, w.to, w.text)
+		}
+	}
+
+	// --fps wins over the frame rate of the file, which is required otherwise.
+	if doc, err := ReadMicroDVD(strings.NewReader(in), 50); err != nil || doc.Cues[0].FromTime != 500*time.Millisecond {
+		t.Fatalf("ReadMicroDVD at 50 fps: %v", err)
+	}
+	if _, err := ReadMicroDVD(strings.NewReader("{25}{75}Hello\n"), 0); !errors.Is(err, ErrNoFrameRate) {
+		t.Fatalf("got %v, want ErrNoFrameRate", err)
+	}
+	if _, err := ReadMicroDVD(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nHi\n"), 25); err == nil {
+		t.Fatal("expected an error reading SRT as MicroDVD")
+	}
+}
+
+func TestWriteMicroDVD(t *testing.T) {
+	doc := &Document{Format: FormatMicroDVD, FrameRate: 24000.0 / 1001, Cues: []*Subtitle{
+		{FromTime: time.Second, ToTime: 3 * time.Second, Text: "Hello\n<i>world</i>"},
+		{FromTime: 4 * time.Second, ToTime: 6 * time.Second, Text: "<i><font color=\"#FF0000\">Both\nlines</font></i>"},
+		{FromTime: 7 * time.Second, ToTime: 8 * time.Second, Text: "Only <b>part</b> {\\an8}bold"},
+	}}
+	var b strings.Builder
+	if err := Write(&b, doc, false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := "{1}{1}23.976\n" +
+		"{24}{72}Hello|{y:i}world\n" +
+		"{96}{144}{Y:i}{C:$0000FF}Both|lines\n" +
+		"{168}{192}Only part bold\n"
+	if b.String() != want {
+		t.Fatalf("Write =\n%s\nwant\n%s", b.String(), want)
+	}
+	if err := Write(&b, &Document{Format: FormatMicroDVD}, false); !errors.Is(err, ErrNoFrameRate) {
+		t.Fatalf("got %v, want ErrNoFrameRate", err)
+	}
+
+	back, err := ReadMicroDVD(strings.NewReader(want), 0)
+	if err != nil {
+		t.Fatalf("ReadMicroDVD: %v", err)
+	}
+	if got := back.Cues[1].Text; got != doc.Cues[1].Text {
+		t.Fatalf("round trip = %q, want %q", got, doc.Cues[1].Text)
+	}
+	if !IsMicroDVD([]byte(want)) || IsMicroDVD([]byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n")) {
+		t.Fatal("IsMicroDVD misdetected the format")
+	}
+}
+
+func TestReadTTML(t *testing.T) {
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter"
+    xmlns:tts="http://www.w3.org/ns/ttml#styling" xml:lang="es" ttp:frameRate="24" ttp:frameRateMultiplier="1000 1001">
+  <head>
+    <styling>
+      <style xml:id="italic" tts:fontStyle="italic"/>
+      <style xml:id="yellow" style="italic" tts:color="yellow"/>
+    </styling>
+    <layout>
+      <region xml:id="top" tts:origin="10% 5%" tts:extent="80% 20%"/>
+      <region xml:id="bottom" tts:origin="10% 10%" tts:extent="80% 80%" tts:displayAlign="after"/>
+    </layout>
+  </head>
+  <body region="bottom">
+    <div begin="10s">
+      <p begin="00:00:01.000" end="00:00:03.500">Hola,
+        <span style="italic">mundo</span><br/>y   adiós</p>
+      <p begin="5s" dur="1500ms" region="top" style="yellow">Arriba<br/>también</p>
+      <p begin="00:00:08:12" end="216f"><span tts:fontWeight="bold">Negrita</span> <span tts:color="#FFFFFF">blanco</span></p>
+      <p>Sin tiempo</p>
+    </div>
+  </body>
+</tt>`
+	doc, err := ReadTTML(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadTTML: %v", err)
+	}
+	if doc.Format != FormatTTML || doc.Language != "es" || doc.FrameRate != 24000.0/1001 {
+		t.Fatalf("format %q, language %q, frame rate %g", doc.Format, doc.Language, doc.FrameRate)
+	}
+	want := []struct {
+		from, to time.Duration
+		text     string
+	}{
+		{11 * time.Second, 13500 * time.Millisecond, "Hola, <i>mundo</i>\ny adiós"},
+		{15 * time.Second, 16500 * time.Millisecond, "{\\an8}<i><font color=\"#FFFF00\">Arriba\ntambién</font></i>"},
+		{18501 * time.Millisecond, 19009 * time.Millisecond, "<b>Negrita</b> blanco"},
+	}
+	if len(doc.Cues) != len(want) {
+		t.Fatalf("got %d cues, want %d: %+v", len(doc.Cues), len(want), doc.Cues)
+	}
+	for i, w := range want {
+		if c := doc.Cues[i]; c.Idx != i+1 || c.FromTime != w.from || c.ToTime != w.to || c.Text != w.text {
+			t.Errorf("cue %d = %d %v --> %v %q, want %v --> %v %q", i, c.Idx, c.FromTime, c.ToTime, c.Text, w.from, w.to, w.text)
+		}
+	}
+	if len(doc.Warnings) != 1 {
+		t.Fatalf("warnings = %q, want the untimed paragraph", doc.Warnings)
+	}
+	if _, err := ReadTTML(strings.NewReader(`<html><body/></html>`)); err == nil {
+		t.Fatal("expected an error reading a document that isn't TTML")
+	}
+}
+
+func TestWriteTTML(t *testing.T) {
+	doc := &Document{Format: FormatTTML, Language: "en", Cues: []*Subtitle{
+		{FromTime: time.Second, ToTime: 2500 * time.Millisecond, Text: "Tom & Jerry\n<i>said <b>hi</b></i>"},
+		{FromTime: 3 * time.Second, ToTime: 4 * time.Second, Text: "{\\an8}<font color=\"#00ff00\">Sign</font>"},
+	}}
+	var b strings.Builder
+	if err := Write(&b, doc, false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	for _, want := range []string{
+		`xml:lang="en"`,
+		`ttp:contentProfiles="http://www.w3.org/ns/ttml/profile/imsc1.1/text"`,
+		`<p xml:id="c1" begin="00:00:01.000" end="00:00:02.500" region="bottom">Tom &amp; Jerry<br/><span tts:fontStyle="italic">said </span><span tts:fontStyle="italic" tts:fontWeight="bold">hi</span></p>`,
+		`<p xml:id="c2" begin="00:00:03.000" end="00:00:04.000" region="top"><span tts:color="#00FF00">Sign</span></p>`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output lacks %s:\n%s", want, b.String())
+		}
+	}
+
+	back, err := ReadTTML(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ReadTTML: %v", err)
+	}
+	want := []string{"Tom & Jerry\n<i>said <b>hi</b></i>", "{\\an8}<font color=\"#00FF00\">Sign</font>"}
+	for i, w := range want {
+		if back.Cues[i].Text != w {
+			t.Errorf("round trip of cue %d = %q, want %q", i+1, back.Cues[i].Text, w)
+		}
+	}
+	if !IsTTML([]byte(b.String())) || IsTTML([]byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n")) {
+		t.Fatal("IsTTML misdetected the format")
+	}
+}
+
+func TestReadSAMI(t *testing.T) {
+	in := `<SAMI>
+<HEAD>
+<STYLE TYPE="text/css"><!--
+P { font-family: Arial; }
+.KRCC { Name: Korean; lang: ko-KR; }
+.ENCC { Name: English; lang: en-US; }
+--></STYLE>
+</HEAD>
+<BODY>
+<SYNC Start=1000><P Class=KRCC>안녕<br><i>세상</i>
+<P Class=ENCC>Hello<br>world
+<SYNC Start=3500><P Class=KRCC>&nbsp;
+<SYNC Start=5000><P Class=KRCC><font color="yellow" face="Gulim">노랑</font> &amp; <font face="Gulim">평범</font>
+<SYNC Start=6000><P Class=ENCC>English only
+<SYNC Start=7000><P Class=KRCC>마지막
+</BODY>
+</SAMI>`
+	doc, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if doc.Format != FormatSAMI || doc.Language != "ko-KR" || len(doc.Warnings) != 1 {
+		t.Fatalf("format %q, language %q, warnings %q", doc.Format, doc.Language, doc.Warnings)
+	}
+	want := []struct {
+		from, to time.Duration
+		text     string
+	}{
+		{time.Second, 3500 * time.Millisecond, "안녕\n<i>세상</i>"},
+		{5 * time.Second, 7 * time.Second, `<font color="yellow">노랑</font> & 평범`}, // the English SYNC doesn't end it
+		{7 * time.Second, 10 * time.Second, "마지막"},
+	}
+	if len(doc.Cues) != len(want) {
+		t.Fatalf("got %d cues, want %d", len(doc.Cues), len(want))
+	}
+	for i, w := range want {
+		if c := doc.Cues[i]; c.Idx != i+1 || c.FromTime != w.from || c.ToTime != w.to || c.Text != w.text {
+			t.Errorf("cue %d = %d %v --> %v %q, want %v --> %v %q", i, c.Idx, c.FromTime, c.ToTime, c.Text, w.from, w.to, w.text)
+		}
+	}
+
+	// Written back as SRT.
+	var b strings.Builder
+	if err := Write(&b, doc, false); err != nil || !strings.HasPrefix(b.String(), "1\n00:00:01,000 --> 00:00:03,500\n") {
+		t.Fatalf("Write = %q, %v", b.String(), err)
+	}
+}
+
+func TestReadSBV(t *testing.T) {
+	youtube := "0:00:01.000,0:00:03.500\nHello\nworld\n\n0:00:04.000,0:00:05.250\n<i>Bye</i>\n"
+	subViewer := "[INFORMATION]\n[TITLE]Movie\n[END INFORMATION]\n[SUBTITLE]\n" +
+		"00:00:01.00,00:00:03.50\nHello[br]world\n\n00:00:04.00,00:00:05.25\n<i>Bye</i>\n"
+	for name, in := range map[string]string{"youtube": youtube, "subviewer": subViewer} {
+		doc, err := Read(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("%s: Read: %v", name, err)
+		}
+		if doc.Format != FormatSBV || len(doc.Cues) != 2 {
+			t.Fatalf("%s: format %q with %d cues, want sbv with 2", name, doc.Format, len(doc.Cues))
+		}
+		if c := doc.Cues[0]; c.FromTime != time.Second || c.ToTime != 3500*time.Millisecond || c.Text != "Hello\nworld" {
+			t.Errorf("%s: cue 1 = %v --> %v %q", name, c.FromTime, c.ToTime, c.Text)
+		}
+		if c := doc.Cues[1]; c.Idx != 2 || c.ToTime != 5250*time.Millisecond || c.Text != "<i>Bye</i>" {
+			t.Errorf("%s: cue 2 = %d %v %q", name, c.Idx, c.ToTime, c.Text)
+		}
+	}
+	if _, err := Read(strings.NewReader("0:00:01.000,0:00:03.500\nHi\n\nnot a timing\n")); err == nil {
+		t.Fatal("expected an error for an invalid SubViewer timing")
+	}
+}
+
+func TestSplitTiming(t *testing.T) {
+	sub := &Subtitle{Idx: 7, FromTime: 10 * time.Second, ToTime: 12 * time.Second, Forced: true,
+		Text: ForcedTag + "- Hi, <i>my friend</i>.\n- Go!", Notes: []string{"NOTE x"}}
+
+	words := SplitTiming([]*Subtitle{sub}, TimingWord)
+	want := []struct {
+		from, to time.Duration
+		text     string
+	}{
+		// The 2s shared by length: "- Hi," 5, "my" 2, "friend." 7, "- Go!" 5.
+		{10 * time.Second, 10526 * time.Millisecond, ForcedTag + "- Hi,"},
+		{10526 * time.Millisecond, 10737 * time.Millisecond, ForcedTag + "<i>my</i>"},
+		{10737 * time.Millisecond, 11474 * time.Millisecond, ForcedTag + "<i>friend</i>."},
+		{11474 * time.Millisecond, 12 * time.Second, ForcedTag + "- Go!"},
+	}
+	if len(words) != len(want) {
+		t.Fatalf("got %d cues, want %d", len(words), len(want))
+	}
+	for i, w := range want {
+		if c := words[i]; c.Idx != i+1 || c.FromTime != w.from || c.ToTime != w.to || c.Text != w.text || !c.Forced {
+			t.Errorf("word %d = %d %v --> %v %q forced=%v, want %v --> %v %q", i, c.Idx, c.FromTime, c.ToTime, c.Text, c.Forced, w.from, w.to, w.text)
+		}
+	}
+	if len(words[0].Notes) != 1 || words[1].Notes != nil {
+		t.Error("the notes should stay with the first piece")
+	}
+
+	phrases := SplitTiming([]*Subtitle{sub}, TimingPhrase)
+	var texts []string
+	for _, c := range phrases {
+		texts = append(texts, c.Text)
+	}
+	if got := strings.Join(texts, "|"); got != ForcedTag+"- Hi,|"+ForcedTag+"<i>my friend</i>.|"+ForcedTag+"- Go!" {
+		t.Errorf("phrases = %q", got)
+	}
+
+	single := SplitTiming([]*Subtitle{{Idx: 3, FromTime: 0, ToTime: time.Second, Text: "Hello"}}, TimingWord)
+	if len(single) != 1 || single[0].Idx != 1 || single[0].Text != "Hello" {
+		t.Errorf("one word = %+v", single)
+	}
+}
@@ -0,0 +1,151 @@
+package srt
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// styledRun is a piece of the text of a cue with the styles of the tags
+// around it: "i", "b", "u", "s", or a color ("#RRGGBB").
+type styledRun struct {
+	text   string
+	styles []string
+}
+
+// styleTagPattern matches the tags styledLines understands, and the ASS
+// override blocks it drops.
+var styleTagPattern = regexp.MustCompile(`<(/?)([a-zA-Z]+)([^<>]*)>|\{\\[^{}]*\}`)
+
+var fontColorPattern = regexp.MustCompile(`(?i)color\s*=\s*["']?#([0-9a-f]{6})`)
+
+// styledLines splits the text of a cue into its lines of styled runs, for
+// the formats that style text otherwise. Tags other than <i>, <b>, <u>, <s>
+// and <font color> are dropped, as are ASS override blocks.
+func styledLines(text string) [][]styledRun {
+	var open []string // styles, and "font" for a font tag without a color
+	lines := [][]styledRun{nil}
+	addText := func(s string) {
+		for i, part := range strings.Split(s, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if part == "" {
+				continue
+			}
+			styles := slices.DeleteFunc(slices.Clone(open), func(s string) bool { return s == "font" })
+			lines[len(lines)-1] = append(lines[len(lines)-1], styledRun{text: part, styles: styles})
+		}
+	}
+	pos := 0
+	for _, m := range styleTagPattern.FindAllStringSubmatchIndex(text, -1) {
+		addText(text[pos:m[0]])
+		pos = m[1]
+		if m[4] < 0 { // ASS override block
+			continue
+		}
+		closing, name := m[3] > m[2], strings.ToLower(text[m[4]:m[5]])
+		style := name
+		switch name {
+		case "i", "b", "u", "s":
+		case "font":
+			if c := fontColorPattern.FindStringSubmatch(text[m[6]:m[7]]); c != nil && !closing {
+				style = "#" + strings.ToUpper(c[1])
+			}
+		default:
+			continue
+		}
+		if !closing {
+			open = append(open, style)
+			continue
+		}
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] == style || name == "font" && (open[i] == "font" || strings.HasPrefix(open[i], "#")) {
+				open = slices.Delete(open, i, i+1)
+				break
+			}
+		}
+	}
+	addText(text[pos:])
+	return lines
+}
+
+// commonStyles returns the styles of every run of runs with visible text.
+func commonStyles(runs []styledRun) []string {
+	var styles []string
+	first := true
+	for _, r := range runs {
+		if strings.TrimSpace(r.text) == "" {
+			continue
+		}
+		if first {
+			styles, first = slices.Clone(r.styles), false
+			continue
+		}
+		styles = slices.DeleteFunc(styles, func(s string) bool { return !slices.Contains(r.styles, s) })
+	}
+	return styles
+}
+
+func runsText(runs []styledRun) string {
+	var b strings.Builder
+	for _, r := range runs {
+		b.WriteString(r.text)
+	}
+	return b.String()
+}
+
+// runsToTags returns the text of a cue with the styles of lines as tags: the
+// styles of every line wrap the whole text, the others are opened and closed
+// as the runs of a line need them, nested.
+func runsToTags(lines [][]styledRun) string {
+	cueStyles := commonStyles(slices.Concat(lines...))
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var b strings.Builder
+		open := slices.Clone(cueStyles)
+		space := "" // written after the tags closed before the next run
+		for _, run := range line {
+			text := strings.TrimSpace(run.text)
+			if text == "" {
+				space += run.text
+				continue
+			}
+			keep := 0
+			for keep < len(open) && slices.Contains(run.styles, open[keep]) {
+				keep++
+			}
+			b.WriteString(closingTags(openingTags(open[keep:])))
+			open = open[:keep]
+			b.WriteString(space + run.text[:strings.Index(run.text, text)])
+			for _, s := range run.styles {
+				if !slices.Contains(open, s) {
+					open = append(open, s)
+					b.WriteString(styleTags([]string{s}))
+				}
+			}
+			b.WriteString(text)
+			space = run.text[strings.Index(run.text, text)+len(text):]
+		}
+		b.WriteString(closingTags(openingTags(open[len(cueStyles):])) + space)
+		out = append(out, b.String())
+	}
+	return CleanText(styleTags(cueStyles) + strings.Join(out, "\n") + closingTags(openingTags(cueStyles)))
+}
+
+// openingTags returns the tags of styles: <i>, <font color="#RRGGBB">...
+func openingTags(styles []string) []string {
+	tags := make([]string, 0, len(styles))
+	for _, s := range styles {
+		if strings.HasPrefix(s, "#") {
+			tags = append(tags, `<font color="`+s+`">`)
+		} else {
+			tags = append(tags, "<"+s+">")
+		}
+	}
+	return tags
+}
+
+func styleTags(styles []string) string {
+	return strings.Join(openingTags(styles), "")
+}
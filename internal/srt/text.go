@@ -0,0 +1,83 @@
+package srt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// InlineTagPattern matches inline markup: HTML-like tags (<i>, </b>,
+// <font color="...">) and ASS override blocks ({\an8}).
+var InlineTagPattern = regexp.MustCompile(`</?[a-zA-Z][^<>]*>|\{\\[^{}]*\}`)
+
+// VisibleText removes the inline tags of text, and the invisible direction
+// marks of right-to-left text (see IsBidiControl).
+func VisibleText(text string) string {
+	text = InlineTagPattern.ReplaceAllString(text, "")
+	if strings.ContainsFunc(text, IsBidiControl) {
+		text = strings.Map(func(r rune) rune {
+			if IsBidiControl(r) {
+				return -1
+			}
+			return r
+		}, text)
+	}
+	return text
+}
+
+// IsBidiControl reports whether r is an invisible Unicode control of the text
+// direction: the marks (LRM, RLM, ALM), embeddings, overrides and isolates.
+func IsBidiControl(r rune) bool {
+	return r == '\u200e' || r == '\u200f' || r == '\u061c' || r >= '\u202a' && r <= '\u202e' || r >= '\u2066' && r <= '\u2069'
+}
+
+// VisibleLength counts the characters shown on screen: inline tags and line
+// breaks are not counted.
+func VisibleLength(text string) int {
+	return utf8.RuneCountInString(strings.ReplaceAll(VisibleText(text), "\n", ""))
+}
+
+// CharsPerSecond returns the reading speed of a cue (0 for cues without duration).
+func CharsPerSecond(sub *Subtitle) float64 {
+	d := (sub.ToTime - sub.FromTime).Seconds()
+	if d <= 0 {
+		return 0
+	}
+	return float64(VisibleLength(sub.Text)) / d
+}
+
+// FormatTime formats d as an SRT timestamp (00:01:02,500).
+func FormatTime(d time.Duration) string {
+	return formatDuration(d)
+}
+
+// timestampPattern matches a timestamp given by the user: 00:45:00,000, with
+// the milliseconds optional and a dot accepted in place of the comma.
+var timestampPattern = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})(?:[,.](\d{1,3}))?$`)
+
+// ParseTime parses a time given as an SRT timestamp (00:45:00,000, 00:45:00
+// or 00:45:00.5) or as a Go duration (45m, 1h2m30s).
+func ParseTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if m := timestampPattern.FindStringSubmatch(s); m != nil {
+		ms := m[4]
+		if ms != "" {
+			ms += strings.Repeat("0", 3-len(ms)) // ,5 is half a second
+		}
+		parts := []string{m[1], m[2], m[3], ms}
+		for _, p := range parts[1:3] {
+			if n, _ := strconv.Atoi(p); n > 59 {
+				return 0, fmt.Errorf("invalid time %q", s)
+			}
+		}
+		return getDuration(parts), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use 00:45:00,000 or a duration such as 45m)", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,527 @@
+package srt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TTML namespaces and the profile of the documents written.
+const (
+	ttmlNamespace          = "http://www.w3.org/ns/ttml"
+	ttmlParameterNamespace = "http://www.w3.org/ns/ttml#parameter"
+	ttmlStylingNamespace   = "http://www.w3.org/ns/ttml#styling"
+	imsc11TextProfile      = "http://www.w3.org/ns/ttml/profile/imsc1.1/text"
+)
+
+// topPlacement is the ASS override block that puts a cue at the top of the
+// screen, as most players of SRT files understand it.
+const topPlacement = `{\an8}`
+
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+var topPlacementPattern = regexp.MustCompile(`\{\\an[789]\}`)
+
+// ttmlStyleAttrs are the styling attributes read from TTML documents.
+var ttmlStyleAttrs = []string{"fontStyle", "fontWeight", "textDecoration", "color", "displayAlign", "origin", "extent"}
+
+// xmlNode is an element of an XML document, or a piece of its text when name
+// is empty. Attributes are keyed by local name (xml:id is "id").
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     string
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "iso-8859-1", "latin1", "latin-1":
+			b, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			runes := make([]rune, len(b))
+			for i, c := range b {
+				runes[i] = rune(c)
+			}
+			return strings.NewReader(string(runes)), nil
+		}
+		return nil, fmt.Errorf("unsupported encoding %s (convert the file to UTF-8)", charset)
+	}
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &ParseError{Msg: fmt.Sprintf("invalid XML: %v", err)}
+		}
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = strings.TrimSpace(a.Value)
+			}
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.children = append(parent.children, &xmlNode{text: string(t)})
+		}
+	}
+	return root, nil
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// IsTTML reports whether b looks like a TTML document (a <tt> root element).
+func IsTTML(b []byte) bool {
+	head := bytes.TrimSpace(bytes.TrimPrefix(b[:min(len(b), 1024)], []byte("\uFEFF")))
+	return bytes.HasPrefix(head, []byte("<")) && bytes.Contains(head, []byte("<tt"))
+}
+
+// ttmlTiming holds the timing parameters of a TTML document.
+type ttmlTiming struct {
+	frameRate    float64
+	subFrameRate float64
+	tickRate     float64
+}
+
+func newTTMLTiming(tt *xmlNode) ttmlTiming {
+	t := ttmlTiming{frameRate: 30, subFrameRate: 1}
+	if v, err := strconv.ParseFloat(tt.attrs["frameRate"], 64); err == nil && v > 0 {
+		t.frameRate = v
+	}
+	if num, den, ok := strings.Cut(tt.attrs["frameRateMultiplier"], " "); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(strings.TrimSpace(den), 64)
+		if err1 == nil && err2 == nil && n > 0 && d > 0 {
+			t.frameRate *= n / d
+		}
+	}
+	if v, err := strconv.ParseFloat(tt.attrs["subFrameRate"], 64); err == nil && v > 0 {
+		t.subFrameRate = v
+	}
+	t.tickRate = 1
+	if tt.attrs["frameRate"] != "" {
+		t.tickRate = t.frameRate * t.subFrameRate
+	}
+	if v, err := strconv.ParseFloat(tt.attrs["tickRate"], 64); err == nil && v > 0 {
+		t.tickRate = v
+	}
+	return t
+}
+
+var (
+	ttmlClockPattern  = regexp.MustCompile(`^(\d{2,}):(\d{2}):(\d{2})(?:(\.\d+)|:(\d{2,})(?:\.(\d+))?)?$`)
+	ttmlOffsetPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|ms|m|s|f|t)$`)
+)
+
+// parse parses a time expression: a clock time (00:01:02.500, or with frames
+// 00:01:02:12) or an offset (62.5s, 1500ms, 75f, 625000t).
+func (t ttmlTiming) parse(s string) (time.Duration, error) {
+	seconds := 0.0
+	if m := ttmlClockPattern.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.ParseFloat(m[1], 64)
+		mins, _ := strconv.ParseFloat(m[2], 64)
+		sec, _ := strconv.ParseFloat(m[3], 64)
+		seconds = h*3600 + mins*60 + sec
+		if m[4] != "" {
+			fraction, _ := strconv.ParseFloat(m[4], 64)
+			seconds += fraction
+		}
+		if m[5] != "" {
+			frames, _ := strconv.ParseFloat(m[5], 64)
+			if m[6] != "" {
+				sub, _ := strconv.ParseFloat(m[6], 64)
+				frames += sub / t.subFrameRate
+			}
+			seconds += frames / t.frameRate
+		}
+	} else if m := ttmlOffsetPattern.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		switch m[2] {
+		case "h":
+			seconds = v * 3600
+		case "m":
+			seconds = v * 60
+		case "s":
+			seconds = v
+		case "ms":
+			seconds = v / 1000
+		case "f":
+			seconds = v / t.frameRate
+		case "t":
+			seconds = v / t.tickRate
+		}
+	} else {
+		return 0, fmt.Errorf("invalid TTML time %q", s)
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond), nil
+}
+
+// ttmlReader holds the state of a TTML document being read.
+type ttmlReader struct {
+	timing  ttmlTiming
+	styles  map[string]*xmlNode // by xml:id
+	regions map[string]*xmlNode
+	cues    []*Subtitle
+	untimed int
+	err     error
+}
+
+// ReadTTML reads a TTML document, such as the IMSC 1.1 files of streaming and
+// broadcast deliveries (or the older DFXP), as cues: each timed paragraph
+// (<p>) is a cue, <br/> a line break, and the styles of the text (italic,
+// bold, underline, line-through, color), given inline or by reference, become
+// tags. Paragraphs shown at the top of the screen by their region get an
+// {\an8} placement block; other layout (positions, fonts, sizes) is dropped.
+func ReadTTML(r io.Reader) (*Document, error) {
+	root, err := parseXML(r)
+	if err != nil {
+		return nil, err
+	}
+	tt := root.child("tt")
+	if tt == nil {
+		return nil, &ParseError{Msg: "not a TTML document (no <tt> element)"}
+	}
+	tr := &ttmlReader{timing: newTTMLTiming(tt), styles: make(map[string]*xmlNode), regions: make(map[string]*xmlNode)}
+	if head := tt.child("head"); head != nil {
+		for _, section := range head.children {
+			for _, n := range section.children {
+				switch {
+				case section.name == "styling" && n.name == "style":
+					tr.styles[n.attrs["id"]] = n
+				case section.name == "layout" && n.name == "region":
+					tr.regions[n.attrs["id"]] = n
+				}
+			}
+		}
+	}
+	body := tt.child("body")
+	if body == nil {
+		return nil, &ParseError{Msg: "TTML document without <body>"}
+	}
+	tr.walk(body, 0, -1, nil, "")
+	if tr.err != nil {
+		return nil, tr.err
+	}
+	if len(tr.cues) == 0 {
+		return nil, &ParseError{Msg: "no timed TTML paragraphs found"}
+	}
+
+	doc := &Document{Format: FormatTTML, Encoding: EncodingUTF8, Language: tt.attrs["lang"], Cues: tr.cues}
+	if tt.attrs["frameRate"] != "" {
+		doc.FrameRate = tr.timing.frameRate
+	}
+	invalid := 0
+	for i, sub := range doc.Cues {
+		sub.Idx = i + 1
+		InferFlags(sub)
+		if !utf8.ValidString(sub.Text) {
+			invalid++
+		}
+	}
+	if tr.untimed > 0 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d paragraphs without timing were skipped", tr.untimed))
+	}
+	if invalid > 0 {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("%d cues aren't valid UTF-8; the file may use another encoding", invalid))
+	}
+	return doc, nil
+}
+
+// span returns the begin and end of n, whose parent spans from begin to end
+// (-1 when unknown): times are relative to the parent, as in TTML's default
+// parallel time containers.
+func (tr *ttmlReader) span(n *xmlNode, begin, end time.Duration) (time.Duration, time.Duration) {
+	parse := func(attr string) (time.Duration, bool) {
+		v, ok := n.attrs[attr]
+		if !ok || tr.err != nil {
+			return 0, false
+		}
+		d, err := tr.timing.parse(v)
+		if err != nil {
+			tr.err = &ParseError{Msg: err.Error()}
+			return 0, false
+		}
+		return d, true
+	}
+	parentBegin := begin
+	if d, ok := parse("begin"); ok {
+		begin = parentBegin + d
+	}
+	if d, ok := parse("end"); ok {
+		end = parentBegin + d
+	} else if d, ok := parse("dur"); ok {
+		end = begin + d
+	}
+	return begin, end
+}
+
+// style returns the styling attributes of n: those of parent, the styles it
+// references and its own.
+func (tr *ttmlReader) style(n *xmlNode, parent map[string]string) map[string]string {
+	style := make(map[string]string, len(parent))
+	for k, v := range parent {
+		style[k] = v
+	}
+	tr.applyStyle(n, style, 0)
+	return style
+}
+
+func (tr *ttmlReader) applyStyle(n *xmlNode, style map[string]string, depth int) {
+	if depth > 8 { // styles referencing each other in a loop
+		return
+	}
+	for id := range strings.FieldsSeq(n.attrs["style"]) {
+		if def := tr.styles[id]; def != nil {
+			tr.applyStyle(def, style, depth+1)
+		}
+	}
+	for _, attr := range ttmlStyleAttrs {
+		if v, ok := n.attrs[attr]; ok {
+			style[attr] = v
+		}
+	}
+}
+
+func (tr *ttmlReader) walk(n *xmlNode, begin, end time.Duration, style map[string]string, region string) {
+	begin, end = tr.span(n, begin, end)
+	style = tr.style(n, style)
+	if id := n.attrs["region"]; id != "" {
+		region = id
+	}
+	if n.name != "p" {
+		for _, c := range n.children {
+			if c.name == "div" || c.name == "p" {
+				tr.walk(c, begin, end, style, region)
+			}
+		}
+		return
+	}
+
+	lines := [][]styledRun{nil}
+	tr.inline(n.children, style, &lines)
+	text := runsToTags(lines)
+	if text == "" {
+		return
+	}
+	if end < 0 || end <= begin {
+		tr.untimed++
+		return
+	}
+	if tr.atTop(style, region) {
+		text = topPlacement + text
+	}
+	tr.cues = append(tr.cues, &Subtitle{FromTime: begin, ToTime: end, Text: text})
+}
+
+// inline appends the text of nodes, in a paragraph, to lines.
+func (tr *ttmlReader) inline(nodes []*xmlNode, style map[string]string, lines *[][]styledRun) {
+	for _, c := range nodes {
+		switch c.name {
+		case "":
+			// Whitespace collapses to a space, as xml:space="default".
+			if text := whitespacePattern.ReplaceAllString(c.text, " "); text != "" {
+				last := len(*lines) - 1
+				(*lines)[last] = append((*lines)[last], styledRun{text: text, styles: ttmlRunStyles(style)})
+			}
+		case "br":
+			*lines = append(*lines, nil)
+		case "span":
+			tr.inline(c.children, tr.style(c, style), lines)
+		}
+	}
+}
+
+// atTop reports whether a paragraph with style in region is shown at the
+// top of the screen: its region aligns the text to its top (displayAlign
+// before) in the upper half, or lies in the upper half.
+func (tr *ttmlReader) atTop(style map[string]string, region string) bool {
+	placement := make(map[string]string)
+	if n := tr.regions[region]; n != nil {
+		tr.applyStyle(n, placement, 0)
+	}
+	for _, attr := range []string{"displayAlign", "origin", "extent"} {
+		if v, ok := style[attr]; ok {
+			placement[attr] = v
+		}
+	}
+	top, topOK := percentY(placement["origin"])
+	height, heightOK := percentY(placement["extent"])
+	if topOK && heightOK && top+height <= 50 {
+		return true
+	}
+	return placement["displayAlign"] == "before" && (!topOK || top < 50)
+}
+
+// percentY returns the vertical component of a TTML length pair given in
+// percent ("10% 80%").
+func percentY(v string) (float64, bool) {
+	fields := strings.Fields(v)
+	if len(fields) != 2 {
+		return 0, false
+	}
+	y, ok := strings.CutSuffix(fields[1], "%")
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(y, 64)
+	return f, err == nil
+}
+
+// ttmlRunStyles returns the styles of text with the TTML style.
+func ttmlRunStyles(style map[string]string) []string {
+	var styles []string
+	if s := style["fontStyle"]; s == "italic" || s == "oblique" {
+		styles = append(styles, "i")
+	}
+	if style["fontWeight"] == "bold" {
+		styles = append(styles, "b")
+	}
+	for d := range strings.FieldsSeq(style["textDecoration"]) {
+		switch d {
+		case "underline":
+			styles = append(styles, "u")
+		case "lineThrough":
+			styles = append(styles, "s")
+		}
+	}
+	if c := ttmlColor(style["color"]); c != "" {
+		styles = append(styles, c)
+	}
+	return styles
+}
+
+// ttmlNamedColors are the named colors of TTML, by their #RRGGBB value.
+// White, the color of subtitles, is no color.
+var ttmlNamedColors = map[string]string{
+	"black": "#000000", "silver": "#C0C0C0", "gray": "#808080", "maroon": "#800000", "red": "#FF0000",
+	"purple": "#800080", "fuchsia": "#FF00FF", "magenta": "#FF00FF", "green": "#008000", "lime": "#00FF00",
+	"olive": "#808000", "yellow": "#FFFF00", "navy": "#000080", "blue": "#0000FF", "teal": "#008080",
+	"aqua": "#00FFFF", "cyan": "#00FFFF",
+}
+
+var ttmlRGBPattern = regexp.MustCompile(`^rgba?\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)`)
+
+// ttmlColor returns a TTML color as #RRGGBB, or "" for white or an unknown
+// color.
+func ttmlColor(c string) string {
+	c = strings.ToLower(strings.TrimSpace(c))
+	if named, ok := ttmlNamedColors[c]; ok {
+		return named
+	}
+	var rgb string
+	if hex, ok := strings.CutPrefix(c, "#"); ok && (len(hex) == 6 || len(hex) == 8) && isHexColor(hex[:6]) {
+		rgb = strings.ToUpper(hex[:6])
+	} else if m := ttmlRGBPattern.FindStringSubmatch(c); m != nil {
+		for _, v := range m[1:] {
+			n, _ := strconv.Atoi(v)
+			rgb += fmt.Sprintf("%02X", min(n, 255))
+		}
+	}
+	if rgb == "" || rgb == "FFFFFF" {
+		return ""
+	}
+	return "#" + rgb
+}
+
+// writeTTML writes doc as an IMSC 1.1 Text Profile document: a paragraph per
+// cue in a bottom region, or a top one for cues with an {\an7}, {\an8} or
+// {\an9} placement block, with the styles of the tags as span attributes.
+// Notes, header and trailer blocks are dropped.
+func writeTTML(w io.Writer, doc *Document) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<tt xmlns="%s" xmlns:ttp="%s" xmlns:tts="%s" xml:lang="%s" ttp:timeBase="media" ttp:contentProfiles="%s">`+"\n",
+		ttmlNamespace, ttmlParameterNamespace, ttmlStylingNamespace, escapeXML(doc.Language), imsc11TextProfile)
+	b.WriteString("  <head>\n    <layout>\n")
+	b.WriteString(`      <region xml:id="bottom" tts:origin="10% 10%" tts:extent="80% 80%" tts:displayAlign="after" tts:textAlign="center"/>` + "\n")
+	b.WriteString(`      <region xml:id="top" tts:origin="10% 10%" tts:extent="80% 80%" tts:displayAlign="before" tts:textAlign="center"/>` + "\n")
+	b.WriteString("    </layout>\n  </head>\n  <body>\n    <div>\n")
+	for i, sub := range doc.Cues {
+		region := "bottom"
+		if topPlacementPattern.MatchString(sub.Text) {
+			region = "top"
+		}
+		fmt.Fprintf(&b, `      <p xml:id="c%d" begin="%s" end="%s" region="%s">`, i+1, formatTTMLTime(sub.FromTime), formatTTMLTime(sub.ToTime), region)
+		for j, line := range styledLines(CleanText(sub.Text)) {
+			if j > 0 {
+				b.WriteString("<br/>")
+			}
+			for _, run := range line {
+				writeTTMLRun(&b, run)
+			}
+		}
+		b.WriteString("</p>\n")
+	}
+	b.WriteString("    </div>\n  </body>\n</tt>\n")
+	if doc.BOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTTMLRun(b *strings.Builder, run styledRun) {
+	if len(run.styles) == 0 {
+		b.WriteString(escapeXML(run.text))
+		return
+	}
+	b.WriteString("<span")
+	var decorations []string
+	for _, s := range run.styles {
+		switch s {
+		case "i":
+			b.WriteString(` tts:fontStyle="italic"`)
+		case "b":
+			b.WriteString(` tts:fontWeight="bold"`)
+		case "u":
+			decorations = append(decorations, "underline")
+		case "s":
+			decorations = append(decorations, "lineThrough")
+		default:
+			b.WriteString(` tts:color="` + s + `"`)
+		}
+	}
+	if len(decorations) > 0 {
+		b.WriteString(` tts:textDecoration="` + strings.Join(decorations, " ") + `"`)
+	}
+	b.WriteString(">" + escapeXML(run.text) + "</span>")
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(s)); err != nil {
+		return s
+	}
+	return b.String()
+}
+
+// formatTTMLTime formats d as a TTML clock time (00:01:02.500).
+func formatTTMLTime(d time.Duration) string {
+	return strings.Replace(formatDuration(d), ",", ".", 1)
+}
@@ -0,0 +1,64 @@
+package srt
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRanges are the East Asian Wide and Fullwidth ranges (UAX #11), shown
+// two columns wide: CJK ideographs, kana, Hangul, fullwidth forms and emoji.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo (leading consonants)
+	{0x2e80, 0x303e},   // CJK radicals, symbols and punctuation
+	{0x3041, 0x33ff},   // hiragana, katakana, bopomofo, CJK compatibility
+	{0x3400, 0x4dbf},   // CJK unified ideographs extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo extended-A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small form variants
+	{0xff00, 0xff60},   // fullwidth forms
+	{0xffe0, 0xffe6},   // fullwidth signs
+	{0x1f300, 0x1f64f}, // pictographs and emoticons
+	{0x1f900, 0x1f9ff}, // supplemental pictographs
+	{0x20000, 0x3fffd}, // CJK unified ideographs extensions B and later
+}
+
+// IsWide reports whether r is shown two columns wide (East Asian Wide or
+// Fullwidth).
+func IsWide(r rune) bool {
+	for _, rg := range wideRanges {
+		if r < rg[0] {
+			return false
+		}
+		if r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// runeWidth is the number of columns r takes: 2 when wide, 0 for combining
+// marks and invisible controls, 1 otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case IsWide(r):
+		return 2
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || IsBidiControl(r):
+		return 0
+	}
+	return 1
+}
+
+// DisplayWidth is the line length of text in columns, as used for wrapping:
+// a CJK character or any other wide character counts as two, as it takes the
+// room of two Latin letters. Inline tags and line breaks are not counted.
+func DisplayWidth(text string) int {
+	width := 0
+	for _, r := range strings.ReplaceAll(VisibleText(text), "\n", "") {
+		width += runeWidth(r)
+	}
+	return width
+}
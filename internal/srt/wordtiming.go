@@ -0,0 +1,157 @@
+package srt
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Units SplitTiming splits cues into.
+const (
+	TimingWord   = "word"
+	TimingPhrase = "phrase" // up to a comma, a full stop or another pause, or the end of a line
+)
+
+// leadingOverridesPattern matches the ASS override blocks at the start of a
+// cue (e.g. {\an8}, ForcedTag), which every piece of a split cue keeps.
+var leadingOverridesPattern = regexp.MustCompile(`^(?:\{\\[^{}]*\}\s*)+`)
+
+// phraseEnd is the punctuation that ends a phrase, when a word ends with it
+// (before closing quotes or brackets).
+const phraseEnd = ",;:.!?…"
+
+// IsValidTimingUnit reports whether unit is TimingWord or TimingPhrase.
+func IsValidTimingUnit(unit string) bool {
+	return unit == TimingWord || unit == TimingPhrase
+}
+
+// SplitTiming returns subs with every cue split into sequential cues of a word
+// or a phrase each (unit), for karaoke-style display or lip-sync tools. The
+// time of a cue is shared between its pieces by their length, as if it was
+// read at an even pace; they keep the styles of their text as tags, the
+// override blocks at the start of the cue and its flags. The cues are
+// renumbered.
+func SplitTiming(subs []*Subtitle, unit string) []*Subtitle {
+	var out []*Subtitle
+	for _, sub := range subs {
+		out = append(out, splitCueTiming(sub, unit)...)
+	}
+	Reindex(out)
+	return out
+}
+
+func splitCueTiming(sub *Subtitle, unit string) []*Subtitle {
+	overrides := leadingOverridesPattern.FindString(sub.Text)
+	pieces := timingPieces(styledLines(sub.Text), unit)
+	if len(pieces) < 2 || sub.ToTime <= sub.FromTime {
+		c := *sub
+		return []*Subtitle{&c}
+	}
+	weights := make([]int, len(pieces))
+	total := 0
+	for i, p := range pieces {
+		weights[i] = max(1, utf8.RuneCountInString(strings.TrimSpace(runsText(p))))
+		total += weights[i]
+	}
+	duration := sub.ToTime - sub.FromTime
+	out := make([]*Subtitle, len(pieces))
+	from, done := sub.FromTime, 0
+	for i, p := range pieces {
+		done += weights[i]
+		to := sub.FromTime + (duration * time.Duration(done) / time.Duration(total)).Round(time.Millisecond)
+		if i == len(pieces)-1 {
+			to = sub.ToTime
+		}
+		out[i] = &Subtitle{
+			FromTime: from,
+			ToTime:   to,
+			Text:     strings.TrimSpace(overrides) + runsToTags([][]styledRun{p}),
+			Forced:   sub.Forced,
+			SDH:      sub.SDH,
+			Speaker:  sub.Speaker,
+		}
+		from = to
+	}
+	out[0].Notes = sub.Notes
+	return out
+}
+
+// timingPieces returns the runs of each word or phrase of lines. A word
+// without letters or digits (e.g. the dash of a dialogue line) goes with the
+// next one, or the previous one at the end of a line.
+func timingPieces(lines [][]styledRun, unit string) [][]styledRun {
+	var pieces [][]styledRun
+	for _, line := range lines {
+		words := lineWords(line)
+		start := len(pieces)
+		var pending []styledRun // words without letters, for the next word
+		for _, w := range words {
+			if !slices.ContainsFunc([]rune(runsText(w)), func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+				pending = appendWord(pending, w)
+				continue
+			}
+			w = appendWord(pending, w)
+			pending = nil
+			if unit == TimingPhrase && len(pieces) > start && !endsPhrase(pieces[len(pieces)-1]) {
+				pieces[len(pieces)-1] = appendWord(pieces[len(pieces)-1], w)
+				continue
+			}
+			pieces = append(pieces, w)
+		}
+		if pending != nil {
+			if len(pieces) > start {
+				pieces[len(pieces)-1] = appendWord(pieces[len(pieces)-1], pending)
+			} else {
+				pieces = append(pieces, pending)
+			}
+		}
+	}
+	return pieces
+}
+
+// lineWords splits a line of styled runs at its spaces into the runs of each
+// word; a word can span runs of different styles.
+func lineWords(line []styledRun) [][]styledRun {
+	var words [][]styledRun
+	var word []styledRun
+	for _, run := range line {
+		rest := run.text
+		for rest != "" {
+			i := strings.IndexFunc(rest, unicode.IsSpace)
+			if i < 0 {
+				word = append(word, styledRun{text: rest, styles: run.styles})
+				break
+			}
+			if i > 0 {
+				word = append(word, styledRun{text: rest[:i], styles: run.styles})
+			}
+			if word != nil {
+				words = append(words, word)
+				word = nil
+			}
+			_, size := utf8.DecodeRuneInString(rest[i:])
+			rest = rest[i+size:]
+		}
+	}
+	if word != nil {
+		words = append(words, word)
+	}
+	return words
+}
+
+// appendWord returns the runs of a followed by a space and the runs of b.
+func appendWord(a, b []styledRun) []styledRun {
+	if len(a) == 0 {
+		return b
+	}
+	return append(append(slices.Clone(a), styledRun{text: " "}), b...)
+}
+
+func endsPhrase(runs []styledRun) bool {
+	text := strings.TrimRight(runsText(runs), `"')]»”’`)
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(phraseEnd, r)
+}
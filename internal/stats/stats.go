@@ -0,0 +1,206 @@
+// Package stats computes reading-speed, line-length, timing and language
+// statistics of a subtitle file, to triage which files need fixing.
+package stats
+
+import (
+	"cmp"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/langdetect"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// DefaultTopGaps is the number of longest gaps reported.
+const DefaultTopGaps = 5
+
+// lineLengthBuckets are the upper bounds of the line length histogram; the
+// last bucket holds longer lines.
+var lineLengthBuckets = []int{20, 32, 42, 50, 60}
+
+type Stats struct {
+	Path string `json:"path"`
+	Cues int    `json:"cues"`
+	// SpanMS goes from the start of the first cue to the end of the last one;
+	// CoveredMS is the time with at least one cue on screen.
+	SpanMS    int64 `json:"span_ms"`
+	CoveredMS int64 `json:"covered_ms"`
+
+	CPS        Distribution `json:"cps"`
+	LineLength Distribution `json:"line_length"`
+	// LineLengthHistogram counts lines per length range ("0-20", ..., "61+").
+	LineLengthHistogram []Bucket     `json:"line_length_histogram"`
+	Words               Distribution `json:"words"`
+	WordsPerCue         []int        `json:"words_per_cue"`
+	LongestGaps         []Gap        `json:"longest_gaps"`
+
+	Language           string  `json:"language,omitempty"` // empty when it can't be detected
+	LanguageConfidence float64 `json:"language_confidence,omitempty"`
+}
+
+// Distribution summarizes a set of values.
+type Distribution struct {
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+type Bucket struct {
+	Range string `json:"range"`
+	Count int    `json:"count"`
+}
+
+// Gap is the time between two consecutive cues.
+type Gap struct {
+	AfterIdx   int    `json:"after_idx"`
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type Options struct {
+	TopGaps int // longest gaps reported (0 means DefaultTopGaps)
+}
+
+// File computes the statistics of the subtitle file at path.
+func File(path string, opts Options) (Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer fs.CloseOrLog(f, path)
+	subs, err := srt.ReadAll(f)
+	if err != nil {
+		return Stats{}, err
+	}
+	s := Compute(subs, opts)
+	s.Path = path
+	return s, nil
+}
+
+// Compute returns the statistics of subs. Cues are sorted by time first
+// (without modifying subs).
+func Compute(subs []*srt.Subtitle, opts Options) Stats {
+	topGaps := opts.TopGaps
+	if topGaps <= 0 {
+		topGaps = DefaultTopGaps
+	}
+	sorted := slices.Clone(subs)
+	srt.Sort(sorted)
+
+	st := Stats{
+		Cues:                len(sorted),
+		WordsPerCue:         make([]int, len(sorted)),
+		LineLengthHistogram: newHistogram(),
+		LongestGaps:         []Gap{},
+	}
+	var cps, lineLengths, words []float64
+	var text strings.Builder
+	var coveredUntil time.Duration
+	var gaps []Gap
+	for i, s := range sorted {
+		if c := srt.CharsPerSecond(s); c > 0 {
+			cps = append(cps, c)
+		}
+		for line := range strings.SplitSeq(s.Text, "\n") {
+			n := srt.VisibleLength(line)
+			lineLengths = append(lineLengths, float64(n))
+			st.LineLengthHistogram[bucketIndex(n)].Count++
+		}
+		n := len(strings.Fields(srt.VisibleText(s.Text)))
+		st.WordsPerCue[i] = n
+		words = append(words, float64(n))
+		text.WriteString(s.Text)
+		text.WriteByte('\n')
+
+		// Covered time is the union of the cue intervals.
+		start := max(s.FromTime, coveredUntil)
+		if s.ToTime > start {
+			st.CoveredMS += (s.ToTime - start).Milliseconds()
+		}
+		if i > 0 && s.FromTime > coveredUntil {
+			gaps = append(gaps, Gap{
+				AfterIdx:   sorted[i-1].Idx,
+				Start:      srt.FormatTime(coveredUntil),
+				End:        srt.FormatTime(s.FromTime),
+				DurationMS: (s.FromTime - coveredUntil).Milliseconds(),
+			})
+		}
+		coveredUntil = max(coveredUntil, s.ToTime)
+	}
+	if len(sorted) > 0 {
+		st.SpanMS = (coveredUntil - sorted[0].FromTime).Milliseconds()
+	}
+
+	st.CPS = distribution(cps)
+	st.LineLength = distribution(lineLengths)
+	st.Words = distribution(words)
+
+	slices.SortStableFunc(gaps, func(a, b Gap) int { return cmp.Compare(b.DurationMS, a.DurationMS) })
+	st.LongestGaps = append(st.LongestGaps, gaps[:min(topGaps, len(gaps))]...)
+
+	if res, ok := langdetect.Detect(text.String()); ok {
+		st.Language = res.Language
+		st.LanguageConfidence = math.Round(res.Confidence*100) / 100
+	}
+	return st
+}
+
+func newHistogram() []Bucket {
+	buckets := make([]Bucket, 0, len(lineLengthBuckets)+1)
+	lower := 0
+	for _, upper := range lineLengthBuckets {
+		buckets = append(buckets, Bucket{Range: strconv.Itoa(lower) + "-" + strconv.Itoa(upper)})
+		lower = upper + 1
+	}
+	return append(buckets, Bucket{Range: strconv.Itoa(lower) + "+"})
+}
+
+func bucketIndex(n int) int {
+	for i, upper := range lineLengthBuckets {
+		if n <= upper {
+			return i
+		}
+	}
+	return len(lineLengthBuckets)
+}
+
+// distribution computes the summary of values (percentiles use the nearest
+// rank). Values are rounded to 2 decimals.
+func distribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	var total float64
+	for _, v := range sorted {
+		total += v
+	}
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return sorted[max(0, rank-1)]
+	}
+	return Distribution{
+		Count: len(sorted),
+		Total: round2(total),
+		Avg:   round2(total / float64(len(sorted))),
+		P50:   round2(percentile(50)),
+		P90:   round2(percentile(90)),
+		P95:   round2(percentile(95)),
+		Max:   round2(sorted[len(sorted)-1]),
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
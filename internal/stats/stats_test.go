@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestCompute(t *testing.T) {
+	s := time.Second
+	subs := []*srt.Subtitle{
+		{Idx: 3, FromTime: 10 * s, ToTime: 12 * s, Text: "Third cue here"},
+		{Idx: 1, FromTime: 1 * s, ToTime: 3 * s, Text: "Hello there"},
+		{Idx: 2, FromTime: 2 * s, ToTime: 4 * s, Text: "<i>Overlapping</i>\nSecond line of the cue"},
+	}
+	st := Compute(subs, Options{TopGaps: 1})
+
+	if st.Cues != 3 || st.SpanMS != 11000 || st.CoveredMS != 5000 {
+		t.Fatalf("unexpected timing stats: cues=%d span=%d covered=%d", st.Cues, st.SpanMS, st.CoveredMS)
+	}
+	if len(st.LongestGaps) != 1 || st.LongestGaps[0] != (Gap{AfterIdx: 2, Start: "00:00:04,000", End: "00:00:10,000", DurationMS: 6000}) {
+		t.Fatalf("unexpected gaps: %+v", st.LongestGaps)
+	}
+	// Words are counted in time order.
+	if want := []int{2, 6, 3}; len(st.WordsPerCue) != 3 || st.WordsPerCue[0] != want[0] || st.WordsPerCue[1] != want[1] || st.WordsPerCue[2] != want[2] {
+		t.Fatalf("WordsPerCue = %v, want %v", st.WordsPerCue, want)
+	}
+	// Tags are not counted: "Overlapping" is 11 characters.
+	if st.LineLength.Count != 4 || st.LineLength.Max != 22 || st.LineLength.P50 != 11 {
+		t.Fatalf("unexpected line length stats: %+v", st.LineLength)
+	}
+	if st.LineLengthHistogram[0].Count != 3 || st.LineLengthHistogram[1].Count != 1 || st.LineLengthHistogram[0].Range != "0-20" {
+		t.Fatalf("unexpected histogram: %+v", st.LineLengthHistogram)
+	}
+	// 11/2 = 5.5, 33/2 = 16.5 and 14/2 = 7 characters per second.
+	if st.CPS.Max != 16.5 || st.CPS.P50 != 7 || st.CPS.Avg != 9.67 {
+		t.Fatalf("unexpected CPS stats: %+v", st.CPS)
+	}
+}
+
+func TestCompute_Empty(t *testing.T) {
+	st := Compute(nil, Options{})
+	if st.Cues != 0 || st.CPS.Count != 0 || len(st.LongestGaps) != 0 || st.Language != "" {
+		t.Fatalf("unexpected stats for an empty file: %+v", st)
+	}
+}
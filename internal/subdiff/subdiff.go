@@ -0,0 +1,226 @@
+// Package subdiff compares two subtitle files. Cues are aligned by time
+// overlap rather than by index, so merged, split or re-timed cues (as produced
+// by fix or translate) are still paired with their counterpart.
+package subdiff
+
+import (
+	"os"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Change kinds.
+const (
+	KindChanged = "changed" // paired cues with different timing and/or text
+	KindRemoved = "removed" // cue only in the first file
+	KindAdded   = "added"   // cue only in the second file
+)
+
+// Cue is a cue of one of the compared files.
+type Cue struct {
+	Idx   int    `json:"idx"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Text  string `json:"text"`
+}
+
+type Change struct {
+	Kind string `json:"kind"`
+	A    *Cue   `json:"a,omitempty"`
+	B    *Cue   `json:"b,omitempty"`
+	// Timing and text differences of changed cues.
+	TimingChanged bool  `json:"timing_changed,omitempty"`
+	TextChanged   bool  `json:"text_changed,omitempty"`
+	StartDeltaMS  int64 `json:"start_delta_ms,omitempty"`
+	EndDeltaMS    int64 `json:"end_delta_ms,omitempty"`
+}
+
+type Summary struct {
+	Unchanged int `json:"unchanged"`
+	Retimed   int `json:"retimed"` // changed timing only
+	Edited    int `json:"edited"`  // changed text (and maybe timing)
+	Removed   int `json:"removed"`
+	Added     int `json:"added"`
+}
+
+type Result struct {
+	A       string   `json:"a"`
+	B       string   `json:"b"`
+	Changes []Change `json:"changes"`
+	Summary Summary  `json:"summary"`
+}
+
+type Options struct {
+	// Tolerance is the largest start/end difference not reported as a timing
+	// change.
+	Tolerance time.Duration
+}
+
+// Equal reports whether there are no differences.
+func (r Result) Equal() bool {
+	return len(r.Changes) == 0
+}
+
+// Files compares the subtitle files at pathA and pathB.
+func Files(pathA, pathB string, opts Options) (Result, error) {
+	a, err := readFile(pathA)
+	if err != nil {
+		return Result{}, err
+	}
+	b, err := readFile(pathB)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Compare(a, b, opts)
+	res.A, res.B = pathA, pathB
+	return res, nil
+}
+
+func readFile(path string) ([]*srt.Subtitle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+	return srt.ReadAll(f)
+}
+
+// Compare returns the differences between a and b in time order. Each cue is
+// paired with the cue of the other file it overlaps the most, when that cue
+// also overlaps it the most (mutual best match); the other cues are reported
+// as removed or added.
+func Compare(a, b []*srt.Subtitle, opts Options) Result {
+	a, b = sortedCopy(a), sortedCopy(b)
+	matches := match(a, b)
+	pairedB := make([]bool, len(b))
+	for _, k := range matches {
+		if k >= 0 {
+			pairedB[k] = true
+		}
+	}
+
+	res := Result{Changes: []Change{}}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && matches[i] == j:
+			c := pair(a[i], b[j], opts.Tolerance)
+			switch {
+			case c == nil:
+				res.Summary.Unchanged++
+			case c.TextChanged:
+				res.Summary.Edited++
+			default:
+				res.Summary.Retimed++
+			}
+			if c != nil {
+				res.Changes = append(res.Changes, *c)
+			}
+			i++
+			j++
+		case i < len(a) && matches[i] < 0 && (j >= len(b) || pairedB[j] || a[i].FromTime <= b[j].FromTime):
+			res.Changes = append(res.Changes, Change{Kind: KindRemoved, A: cue(a[i])})
+			res.Summary.Removed++
+			i++
+		default:
+			// Pairs are in order, so every cue of b before the pair of a[i]
+			// is unpaired.
+			res.Changes = append(res.Changes, Change{Kind: KindAdded, B: cue(b[j])})
+			res.Summary.Added++
+			j++
+		}
+	}
+	return res
+}
+
+// match returns, for every cue of a, the index of its paired cue of b (-1 when
+// unpaired). Pairs are mutual best overlaps, in increasing order of both
+// indexes (a crossing pair is dropped).
+func match(a, b []*srt.Subtitle) []int {
+	bestA := bestOverlaps(a, b)
+	bestB := bestOverlaps(b, a)
+	matches := make([]int, len(a))
+	last := -1
+	for i, k := range bestA {
+		matches[i] = -1
+		if k > last && bestB[k] == i {
+			matches[i] = k
+			last = k
+		}
+	}
+	return matches
+}
+
+// pair returns the change between two paired cues, or nil when they are equal.
+func pair(a, b *srt.Subtitle, tolerance time.Duration) *Change {
+	startDelta := b.FromTime - a.FromTime
+	endDelta := b.ToTime - a.ToTime
+	c := &Change{
+		Kind:          KindChanged,
+		A:             cue(a),
+		B:             cue(b),
+		TimingChanged: absDuration(startDelta) > tolerance || absDuration(endDelta) > tolerance,
+		TextChanged:   srt.CleanText(a.Text) != srt.CleanText(b.Text),
+	}
+	if !c.TimingChanged && !c.TextChanged {
+		return nil
+	}
+	if c.TimingChanged {
+		c.StartDeltaMS = startDelta.Milliseconds()
+		c.EndDeltaMS = endDelta.Milliseconds()
+	}
+	return c
+}
+
+// bestOverlaps returns, for every cue of x, the index of the cue of y it
+// overlaps the most (-1 when it overlaps none). Both are sorted by time.
+func bestOverlaps(x, y []*srt.Subtitle) []int {
+	// maxEnd[k] is the latest end of y[:k+1]; cues of y before start can't
+	// overlap cues of x starting after maxEnd[start].
+	maxEnd := make([]time.Duration, len(y))
+	for k, s := range y {
+		maxEnd[k] = s.ToTime
+		if k > 0 {
+			maxEnd[k] = max(maxEnd[k], maxEnd[k-1])
+		}
+	}
+	best := make([]int, len(x))
+	start := 0
+	for i, s := range x {
+		for start < len(y) && maxEnd[start] <= s.FromTime {
+			start++
+		}
+		best[i] = -1
+		var bestOverlap time.Duration
+		for k := start; k < len(y) && y[k].FromTime < s.ToTime; k++ {
+			if o := overlap(s, y[k]); o > bestOverlap {
+				best[i], bestOverlap = k, o
+			}
+		}
+	}
+	return best
+}
+
+func overlap(a, b *srt.Subtitle) time.Duration {
+	return max(0, min(a.ToTime, b.ToTime)-max(a.FromTime, b.FromTime))
+}
+
+func sortedCopy(subs []*srt.Subtitle) []*srt.Subtitle {
+	out := make([]*srt.Subtitle, len(subs))
+	copy(out, subs)
+	srt.Sort(out)
+	return out
+}
+
+func cue(s *srt.Subtitle) *Cue {
+	return &Cue{Idx: s.Idx, Start: srt.FormatTime(s.FromTime), End: srt.FormatTime(s.ToTime), Text: s.Text}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,72 @@
+package subdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func sub(idx int, from, to time.Duration, text string) *srt.Subtitle {
+	return &srt.Subtitle{Idx: idx, FromTime: from, ToTime: to, Text: text}
+}
+
+func kinds(changes []Change) string {
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		switch c.Kind {
+		case KindChanged:
+			out[i] = fmt.Sprintf("~%d:%d", c.A.Idx, c.B.Idx)
+		case KindRemoved:
+			out[i] = fmt.Sprintf("-%d", c.A.Idx)
+		case KindAdded:
+			out[i] = fmt.Sprintf("+%d", c.B.Idx)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestCompare_AlignsByTimeOverlap(t *testing.T) {
+	s := time.Second
+	a := []*srt.Subtitle{
+		sub(1, 1*s, 2*s, "Hello"),
+		sub(2, 3*s, 4*s, "Short"),
+		sub(3, 4*s, 6*s, "line merged by fix"),
+		sub(4, 7*s, 8*s, "Unchanged"),
+		sub(5, 9*s, 10*s, "Removed"),
+	}
+	b := []*srt.Subtitle{
+		sub(1, 1500*time.Millisecond, 2500*time.Millisecond, "Hello"),
+		sub(2, 3*s, 6*s, "Short line merged by fix"),
+		sub(3, 7*s, 8*s, "Unchanged"),
+		sub(4, 11*s, 12*s, "Added"),
+	}
+	res := Compare(a, b, Options{})
+	if got, want := kinds(res.Changes), "~1:1 -2 ~3:2 -5 +4"; got != want {
+		t.Fatalf("changes = %s, want %s", got, want)
+	}
+	retimed := res.Changes[0]
+	if !retimed.TimingChanged || retimed.TextChanged || retimed.StartDeltaMS != 500 || retimed.EndDeltaMS != 500 {
+		t.Fatalf("unexpected retimed change: %+v", retimed)
+	}
+	if !res.Changes[2].TextChanged {
+		t.Fatalf("expected a text change: %+v", res.Changes[2])
+	}
+	want := Summary{Unchanged: 1, Retimed: 1, Edited: 1, Removed: 2, Added: 1}
+	if res.Summary != want {
+		t.Fatalf("summary = %+v, want %+v", res.Summary, want)
+	}
+}
+
+func TestCompare_Tolerance(t *testing.T) {
+	a := []*srt.Subtitle{sub(1, time.Second, 2*time.Second, "Hello")}
+	b := []*srt.Subtitle{sub(1, time.Second+40*time.Millisecond, 2*time.Second, "Hello")}
+	if res := Compare(a, b, Options{Tolerance: 50 * time.Millisecond}); !res.Equal() || res.Summary.Unchanged != 1 {
+		t.Fatalf("expected no changes within the tolerance, got %+v", res)
+	}
+	if res := Compare(a, b, Options{}); res.Equal() {
+		t.Fatal("expected a timing change without tolerance")
+	}
+}
@@ -0,0 +1,580 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBatchPollInterval is how often the status of a Batch API job is checked.
+const DefaultBatchPollInterval = 30 * time.Second
+
+// batchJobRequestPrefix prefixes the position of each request in a job
+// (request-0, request-1, ...), which the results are matched by.
+const batchJobRequestPrefix = "request-"
+
+// batchJobClient is implemented by the providers with an asynchronous Batch
+// API, which runs the requests of a job within hours at a lower price. Jobs
+// belong to the account of the key, so they are submitted and polled with the
+// first API key.
+type batchJobClient interface {
+	// submitBatchJob submits a job translating every payload and returns its id.
+	submitBatchJob(ctx context.Context, sourceLanguage string, targetLanguage string, payloads []string) (string, error)
+	// pollBatchJob returns the status of the job and, once it is done, the
+	// result of each of its n requests by position.
+	pollBatchJob(ctx context.Context, id string, n int) (batchJobStatus, []batchJobResult, error)
+}
+
+// batchJobStatus is the status of a Batch API job.
+type batchJobStatus struct {
+	state     string // as reported by the provider
+	done      bool
+	failed    bool // done without results (failed or cancelled)
+	completed int  // requests done, when reported
+	total     int
+}
+
+// batchJobResult is the outcome of one request of a Batch API job, like the
+// response of TranslateBatch.
+type batchJobResult struct {
+	content string
+	usage   tokenUsage
+	err     error
+}
+
+// batchJobState is the state file of a submitted job, which lets a run
+// interrupted while waiting resume the job instead of submitting it again.
+type batchJobState struct {
+	Provider    string    `json:"provider"`
+	ID          string    `json:"id"`
+	Requests    int       `json:"requests"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// batchAPIProviders translates batches with a Batch API job of the primary
+// provider and returns the providers with the primary answering from the job
+// results. The requests missing from the results, the retries and the split
+// batches are sent to the provider as usual.
+func batchAPIProviders(ctx context.Context, opts Options, providers []namedTranslator, batches []batch) ([]namedTranslator, error) {
+	p := providers[0]
+	client, ok := p.client.(batchJobClient)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support the batch api", opts.Provider)
+	}
+	payloads := make([]string, len(batches))
+	for i, b := range batches {
+		texts, _ := maskBatchTags(b, !opts.SkipTagProtection)
+		payload, err := formatForTranslation(b.idxs, texts, opts.lineLimits())
+		if err != nil {
+			return nil, err
+		}
+		payloads[i] = payload
+	}
+
+	statePath := filepath.Join(opts.BatchStateDir, batchJobKey(p.name, opts.SourceLanguage, opts.TargetLanguage, payloads)+".json")
+	state, err := readBatchJobState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && state.Provider == p.name && state.Requests == len(payloads) {
+		slog.Info("resuming batch job", "id", state.ID, "submitted_at", state.SubmittedAt, "target_language", opts.TargetLanguage)
+	} else {
+		id, err := client.submitBatchJob(ctx, opts.SourceLanguage, opts.TargetLanguage, payloads)
+		if err != nil {
+			return nil, fmt.Errorf("submit batch job: %w", err)
+		}
+		state = &batchJobState{Provider: p.name, ID: id, Requests: len(payloads), SubmittedAt: time.Now().UTC()}
+		if err := writeBatchJobState(statePath, state); err != nil {
+			return nil, err
+		}
+		slog.Info("batch job submitted", "id", id, "requests", len(payloads), "target_language", opts.TargetLanguage, "state", statePath)
+	}
+
+	interval := opts.BatchPollInterval
+	if interval <= 0 {
+		interval = DefaultBatchPollInterval
+	}
+	for {
+		status, results, err := client.pollBatchJob(ctx, state.ID, len(payloads))
+		if err != nil {
+			return nil, fmt.Errorf("poll batch job %s: %w", state.ID, err)
+		}
+		if status.done {
+			if err := os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			if status.failed {
+				slog.Warn("batch job ended without results; translating synchronously", "id", state.ID, "state", status.state)
+				return providers, nil
+			}
+			return withBatchJobResults(providers, state.ID, payloads, results), nil
+		}
+		slog.Info("waiting for the batch job", "id", state.ID, "state", status.state, "completed", status.completed, "total", status.total)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// withBatchJobResults returns providers with the primary answering each
+// payload from results once. Failed requests are left to the provider, except
+// for blocked and truncated responses, which the runner handles by splitting
+// the batch.
+func withBatchJobResults(providers []namedTranslator, id string, payloads []string, results []batchJobResult) []namedTranslator {
+	prefetched := &prefetchedTranslator{BatchTranslator: providers[0].client, results: make(map[string]batchJobResult, len(payloads))}
+	failed := 0
+	for i, payload := range payloads {
+		res := results[i]
+		if res.err != nil && !isBlocked(res.err) && !isTruncated(res.err) {
+			slog.Debug("batch job request failed; translating it synchronously", "id", id, "request", i, "err", res.err)
+			failed++
+			continue
+		}
+		prefetched.results[payload] = res
+	}
+	if failed > 0 {
+		slog.Warn("batch job requests failed; translating them synchronously", "id", id, "failed", failed, "requests", len(payloads))
+	}
+	out := append([]namedTranslator(nil), providers...)
+	out[0].client = prefetched
+	return out
+}
+
+// prefetchedTranslator answers each payload once with the result of a batch
+// job and sends any other request to the wrapped client.
+type prefetchedTranslator struct {
+	BatchTranslator
+	mu      sync.Mutex
+	results map[string]batchJobResult
+}
+
+func (t *prefetchedTranslator) TranslateBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error) {
+	t.mu.Lock()
+	res, ok := t.results[payload]
+	delete(t.results, payload)
+	t.mu.Unlock()
+	if !ok {
+		return t.BatchTranslator.TranslateBatch(ctx, sourceLanguage, targetLanguage, payload)
+	}
+	reportTokenUsage(ctx, res.usage)
+	return res.content, res.err
+}
+
+// batchJobKey names the state file of the job translating payloads, so a run
+// resumes only the job of the same requests.
+func batchJobKey(provider, sourceLanguage, targetLanguage string, payloads []string) string {
+	h := sha256.New()
+	for _, s := range append([]string{provider, sourceLanguage, targetLanguage}, payloads...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// readBatchJobState returns the state at path, or nil when there is none.
+func readBatchJobState(path string) (*batchJobState, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state batchJobState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("parse batch job state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+func writeBatchJobState(path string, state *batchJobState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// batchJobIndex returns the position of a request from its id (-1 if invalid).
+func batchJobIndex(id string, n int) int {
+	i, err := strconv.Atoi(strings.TrimPrefix(id, batchJobRequestPrefix))
+	if err != nil || !strings.HasPrefix(id, batchJobRequestPrefix) || i < 0 || i >= n {
+		return -1
+	}
+	return i
+}
+
+// newBatchJobResults returns n results failing until they are set.
+func newBatchJobResults(n int) []batchJobResult {
+	results := make([]batchJobResult, n)
+	for i := range results {
+		results[i].err = errors.New("no result in the batch job")
+	}
+	return results
+}
+
+// batchAPIRequest sends a request of a Batch API and returns the response
+// body, retrying network errors and retryable statuses.
+func batchAPIRequest(ctx context.Context, hc *http.Client, retry RetryOptions, method string, u string, header http.Header, body []byte) ([]byte, error) {
+	return requestWithRetry[[]byte](ctx, retry, func(attempt int) ([]byte, retryDecision) {
+		r, err := doJSONRequestWithHeader(ctx, hc, method, u, header, body)
+		if err != nil {
+			return nil, retryDecision{err: err, retry: isRetryableNetErr(err)}
+		}
+		if r.statusCode < 200 || r.statusCode >= 300 {
+			hErr := &httpStatusError{StatusCode: r.statusCode, Body: strings.TrimSpace(string(r.bodyBytes))}
+			return nil, retryDecision{err: hErr, retry: isRetryableHTTPStatus(r.statusCode), delay: retryDelayFromHeader(r.header)}
+		}
+		return r.bodyBytes, retryDecision{}
+	})
+}
+
+// openAIBatchLine is a request or a result line of an OpenAI batch file.
+type openAIBatchLine struct {
+	CustomID string                  `json:"custom_id"`
+	Method   string                  `json:"method,omitempty"`
+	URL      string                  `json:"url,omitempty"`
+	Body     *chatCompletionsRequest `json:"body,omitempty"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response,omitempty"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type openAIBatch struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// submitBatchJob uploads the requests as a JSONL file and creates a batch of
+// chat completions (see batchJobClient).
+func (c *OpenAIClient) submitBatchJob(ctx context.Context, sourceLanguage string, targetLanguage string, payloads []string) (string, error) {
+	if c.Model == "" {
+		return "", errors.New("model is required")
+	}
+	mode := normalizeResponseMode(c.ResponseMode)
+	structured := mode != ResponseModeNDJSON
+	var lines bytes.Buffer
+	enc := json.NewEncoder(&lines)
+	for i, payload := range payloads {
+		req, err := c.buildRequest(sourceLanguage, targetLanguage, payload, structured)
+		if err != nil {
+			return "", err
+		}
+		req.Stream = false
+		line := openAIBatchLine{CustomID: batchJobRequestPrefix + strconv.Itoa(i), Method: http.MethodPost, URL: "/v1/chat/completions", Body: &req}
+		if err := enc.Encode(line); err != nil {
+			return "", err
+		}
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	if err := mw.WriteField("purpose", "batch"); err != nil {
+		return "", err
+	}
+	fw, err := mw.CreateFormFile("file", "subtitle-tools-batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(lines.Bytes()); err != nil {
+		return "", err
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	u, err := c.endpointURL("/files")
+	if err != nil {
+		return "", err
+	}
+	header := c.batchAPIHeader()
+	header.Set("Content-Type", mw.FormDataContentType())
+	b, err := batchAPIRequest(ctx, c.httpClient(), c.RetryOptions, http.MethodPost, u.String(), header, form.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("upload batch file: %w", err)
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return "", err
+	}
+	if u, err = c.endpointURL("/batches"); err != nil {
+		return "", err
+	}
+	if b, err = batchAPIRequest(ctx, c.httpClient(), c.RetryOptions, http.MethodPost, u.String(), c.batchAPIHeader(), body); err != nil {
+		return "", fmt.Errorf("create batch: %w", err)
+	}
+	var created openAIBatch
+	if err := json.Unmarshal(b, &created); err != nil {
+		return "", err
+	}
+	if created.ID == "" {
+		return "", errors.New("no id in the created batch")
+	}
+	return created.ID, nil
+}
+
+// pollBatchJob returns the status of the batch and, once it ended, the
+// results from its output and error files (see batchJobClient). An expired
+// batch returns the requests it completed.
+func (c *OpenAIClient) pollBatchJob(ctx context.Context, id string, n int) (batchJobStatus, []batchJobResult, error) {
+	u, err := c.endpointURL("/batches/" + id)
+	if err != nil {
+		return batchJobStatus{}, nil, err
+	}
+	b, err := batchAPIRequest(ctx, c.httpClient(), c.RetryOptions, http.MethodGet, u.String(), c.batchAPIHeader(), nil)
+	if err != nil {
+		return batchJobStatus{}, nil, err
+	}
+	var batch openAIBatch
+	if err := json.Unmarshal(b, &batch); err != nil {
+		return batchJobStatus{}, nil, err
+	}
+	status := batchJobStatus{state: batch.Status, completed: batch.RequestCounts.Completed + batch.RequestCounts.Failed, total: batch.RequestCounts.Total}
+	switch batch.Status {
+	case "completed", "expired":
+		status.done = true
+	case "failed", "cancelled":
+		status.done, status.failed = true, true
+		return status, nil, nil
+	default:
+		return status, nil, nil
+	}
+
+	results := newBatchJobResults(n)
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		if u, err = c.endpointURL("/files/" + fileID + "/content"); err != nil {
+			return status, nil, err
+		}
+		b, err := batchAPIRequest(ctx, c.httpClient(), c.RetryOptions, http.MethodGet, u.String(), c.batchAPIHeader(), nil)
+		if err != nil {
+			return status, nil, fmt.Errorf("download batch results: %w", err)
+		}
+		for _, raw := range bytes.Split(b, []byte("\n")) {
+			if len(bytes.TrimSpace(raw)) == 0 {
+				continue
+			}
+			var line openAIBatchLine
+			if err := json.Unmarshal(raw, &line); err != nil {
+				return status, nil, fmt.Errorf("parse batch results: %w", err)
+			}
+			i := batchJobIndex(line.CustomID, n)
+			if i < 0 {
+				continue
+			}
+			switch {
+			case line.Error != nil:
+				results[i] = batchJobResult{err: fmt.Errorf("%s: %s", line.Error.Code, line.Error.Message)}
+			case line.Response == nil:
+				results[i] = batchJobResult{err: errors.New("no response in the batch result")}
+			case line.Response.StatusCode < 200 || line.Response.StatusCode >= 300:
+				body := strings.TrimSpace(string(line.Response.Body))
+				err := error(&httpStatusError{StatusCode: line.Response.StatusCode, Body: body})
+				if isContentFilterRejection(line.Response.StatusCode, body) {
+					err = fmt.Errorf("%w: %w", &blockedResponseError{reason: finishReasonContentFilter}, err)
+				}
+				results[i] = batchJobResult{err: err}
+			default:
+				content, usage, err := parseChatCompletionContent(line.Response.Body)
+				results[i] = batchJobResult{content: content, usage: usage, err: err}
+			}
+		}
+	}
+	return status, results, nil
+}
+
+// batchAPIHeader authenticates the requests of the Batch API with the first key.
+func (c *OpenAIClient) batchAPIHeader() http.Header {
+	header := http.Header{}
+	if keys := c.apiKeys(); len(keys) > 0 {
+		header.Set("Authorization", "Bearer "+keys[0])
+	}
+	return header
+}
+
+// geminiBatchOperation is a batch of the Gemini API, as returned when it is
+// created and polled.
+type geminiBatchOperation struct {
+	Name     string `json:"name"`
+	Done     bool   `json:"done"`
+	Metadata struct {
+		State  string             `json:"state"`
+		Output *geminiBatchOutput `json:"output"`
+	} `json:"metadata"`
+	Response *geminiBatchOutput `json:"response"`
+	Error    *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type geminiBatchOutput struct {
+	InlinedResponses *struct {
+		InlinedResponses []struct {
+			Response json.RawMessage `json:"response"`
+			Error    *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Metadata struct {
+				Key string `json:"key"`
+			} `json:"metadata"`
+		} `json:"inlinedResponses"`
+	} `json:"inlinedResponses"`
+}
+
+type geminiBatchRequest struct {
+	Request  geminiRequest     `json:"request"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// submitBatchJob creates a batch with the requests inlined (see
+// batchJobClient); the name of the batch is the job id.
+func (c *GeminiClient) submitBatchJob(ctx context.Context, sourceLanguage string, targetLanguage string, payloads []string) (string, error) {
+	if c.Model == "" {
+		return "", errors.New("model is required")
+	}
+	structured := normalizeResponseMode(c.ResponseMode) != ResponseModeNDJSON
+	requests := make([]geminiBatchRequest, len(payloads))
+	for i, payload := range payloads {
+		messages, err := buildPrompt(c.Prompt, sourceLanguage, targetLanguage, payload, structured)
+		if err != nil {
+			return "", fmt.Errorf("build prompt: %w", err)
+		}
+		requests[i] = geminiBatchRequest{
+			Request:  c.buildRequest(messages, structured),
+			Metadata: map[string]string{"key": batchJobRequestPrefix + strconv.Itoa(i)},
+		}
+	}
+	body, err := json.Marshal(map[string]any{
+		"batch": map[string]any{
+			"display_name": "subtitle-tools",
+			"input_config": map[string]any{"requests": map[string]any{"requests": requests}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	u, err := c.endpointURL(":batchGenerateContent")
+	if err != nil {
+		return "", err
+	}
+	b, err := batchAPIRequest(ctx, c.httpClient(), c.RetryOptions, http.MethodPost, u.String(), c.batchAPIHeader(), body)
+	if err != nil {
+		return "", err
+	}
+	var op geminiBatchOperation
+	if err := json.Unmarshal(b, &op); err != nil {
+		return "", err
+	}
+	if op.Name == "" {
+		return "", errors.New("no name in the created batch")
+	}
+	return op.Name, nil
+}
+
+// pollBatchJob returns the state of the batch and, once it succeeded, the
+// inlined responses (see batchJobClient).
+func (c *GeminiClient) pollBatchJob(ctx context.Context, id string, n int) (batchJobStatus, []batchJobResult, error) {
+	u, err := c.apiURL("/" + id)
+	if err != nil {
+		return batchJobStatus{}, nil, err
+	}
+	b, err := batchAPIRequest(ctx, c.httpClient(), c.RetryOptions, http.MethodGet, u.String(), c.batchAPIHeader(), nil)
+	if err != nil {
+		return batchJobStatus{}, nil, err
+	}
+	var op geminiBatchOperation
+	if err := json.Unmarshal(b, &op); err != nil {
+		return batchJobStatus{}, nil, err
+	}
+	// The states are named BATCH_STATE_* (JOB_STATE_* in older versions).
+	state := op.Metadata.State
+	if i := strings.LastIndex(state, "_STATE_"); i >= 0 {
+		state = state[i+len("_STATE_"):]
+	}
+	status := batchJobStatus{state: strings.ToLower(state)}
+	switch {
+	case state == "SUCCEEDED" || (op.Done && op.Error == nil && state == ""):
+		status.done = true
+	case state == "FAILED" || state == "CANCELLED" || state == "EXPIRED" || op.Done:
+		status.done, status.failed = true, true
+		return status, nil, nil
+	default:
+		return status, nil, nil
+	}
+
+	output := op.Response
+	if output == nil || output.InlinedResponses == nil {
+		output = op.Metadata.Output
+	}
+	results := newBatchJobResults(n)
+	if output == nil || output.InlinedResponses == nil {
+		return status, results, nil
+	}
+	for pos, r := range output.InlinedResponses.InlinedResponses {
+		i := pos
+		if r.Metadata.Key != "" {
+			i = batchJobIndex(r.Metadata.Key, n)
+		}
+		if i < 0 || i >= n {
+			continue
+		}
+		if r.Error != nil {
+			results[i] = batchJobResult{err: errors.New(r.Error.Message)}
+			continue
+		}
+		content, usage, err := parseGeminiContent(r.Response)
+		results[i] = batchJobResult{content: content, usage: usage, err: err}
+	}
+	return status, results, nil
+}
+
+// batchAPIHeader authenticates the requests of the Batch API with the first key.
+func (c *GeminiClient) batchAPIHeader() http.Header {
+	header := http.Header{}
+	if keys := splitAPIKeys(c.APIKey); len(keys) > 0 {
+		header.Set(geminiAPIKeyHeader, keys[0])
+	}
+	return header
+}
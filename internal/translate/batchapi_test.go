@@ -0,0 +1,114 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_BatchAPIResumesJobAndAssemblesResults(t *testing.T) {
+	var uploads, batches, completions atomic.Int32
+	var done atomic.Bool
+	var customID atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			uploads.Add(1)
+			f, _, err := r.FormFile("file")
+			if err != nil || r.FormValue("purpose") != "batch" {
+				http.Error(w, "bad upload", http.StatusBadRequest)
+				return
+			}
+			var line openAIBatchLine
+			if err := json.NewDecoder(f).Decode(&line); err != nil || line.Body == nil || line.Body.Stream {
+				http.Error(w, "bad batch file", http.StatusBadRequest)
+				return
+			}
+			customID.Store(line.CustomID)
+			_, _ = w.Write([]byte(`{"id":"file-in"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/batches":
+			batches.Add(1)
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"validating"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/batches/batch_1":
+			if !done.Load() {
+				_, _ = w.Write([]byte(`{"id":"batch_1","status":"in_progress","request_counts":{"total":1,"completed":0}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"completed","output_file_id":"file-out","request_counts":{"total":1,"completed":1}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file-out/content":
+			content, _ := json.Marshal("{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Chau\"}")
+			_, _ = w.Write([]byte(`{"custom_id":"` + customID.Load().(string) + `","response":{"status_code":200,"body":` +
+				`{"choices":[{"message":{"content":` + string(content) + `},"finish_reason":"stop"}],"usage":{"total_tokens":7}}}}` + "\n"))
+		default:
+			completions.Add(1)
+			http.Error(w, "unexpected request", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	stateDir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	opts := Options{
+		InputPath:         inPath,
+		OutputPath:        outPath,
+		WorkDir:           workdir,
+		TargetLanguage:    "es",
+		APIKey:            "test",
+		Model:             "gpt-test",
+		BaseURL:           server.URL,
+		ResponseMode:      ResponseModeNDJSON,
+		BatchAPI:          true,
+		BatchStateDir:     stateDir,
+		BatchPollInterval: 10 * time.Millisecond,
+	}
+
+	// The run is interrupted while the job is in progress.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := Run(ctx, opts); err == nil {
+		t.Fatalf("expected the interrupted run to fail")
+	}
+	if entries, _ := os.ReadDir(stateDir); len(entries) != 1 {
+		t.Fatalf("expected the job state to be kept, got %d entries", len(entries))
+	}
+
+	done.Store(true)
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if uploads.Load() != 1 || batches.Load() != 1 || completions.Load() != 0 {
+		t.Fatalf("expected a single job and no synchronous request, got %d uploads, %d batches, %d other requests",
+			uploads.Load(), batches.Load(), completions.Load())
+	}
+	if res.TokensUsed != 7 {
+		t.Fatalf("expected the tokens of the job, got %d", res.TokensUsed)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hola") || !strings.Contains(string(b), "Chau") {
+		t.Fatalf("expected the translations of the job, got:\n%s", b)
+	}
+	if entries, _ := os.ReadDir(stateDir); len(entries) != 0 {
+		t.Fatalf("expected the job state to be removed, got %d entries", len(entries))
+	}
+}
+
+func TestDefaultOptions_BatchAPI(t *testing.T) {
+	if _, err := defaultOptions(Options{TargetLanguage: "es", Provider: ProviderDeepL, APIKey: "k", BatchAPI: true, BatchStateDir: "x"}); err == nil {
+		t.Fatalf("expected an error for deepl")
+	}
+	if _, err := defaultOptions(Options{TargetLanguage: "es", Model: "gpt-5", BatchAPI: true}); err == nil {
+		t.Fatalf("expected an error without a state directory")
+	}
+}
@@ -0,0 +1,120 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultCircuitBreakerThreshold is the number of consecutive server errors
+// that trip the circuit breaker of a provider.
+const DefaultCircuitBreakerThreshold = 5
+
+// DefaultCircuitBreakerCooldown is how long the requests to a provider are
+// paused when its circuit breaker trips.
+const DefaultCircuitBreakerCooldown = 30 * time.Second
+
+// circuitBreaker pauses every request to a provider for a cooldown once
+// consecutive requests, from any worker, fail with a server error (5xx), so a
+// provider outage doesn't burn the retry budget of every batch at once. After
+// the cooldown, requests go through again: the next server error trips the
+// breaker again, and a response that isn't one closes it. A nil breaker does
+// nothing.
+type circuitBreaker struct {
+	name      string // provider label for the logs
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int // consecutive server errors
+	openUntil time.Time
+	tripped   bool // set until a request succeeds after a trip
+}
+
+// newCircuitBreaker returns a breaker for the provider labeled name, or nil
+// when threshold is not positive.
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitBreakerCooldown
+	}
+	return &circuitBreaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// wait blocks while the breaker is open.
+func (b *circuitBreaker) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	d := b.openUntil.Sub(b.now())
+	b.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	slog.Debug("circuit breaker open; waiting before sending the request", "provider", b.name, "wait", d)
+	return sleepWithContext(ctx, d)
+}
+
+// record counts the outcome of a request: a server error adds to the
+// consecutive failures, tripping the breaker at the threshold, and any other
+// response resets them.
+func (b *circuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isServerError(err) {
+		if err == nil || isHTTPResponseError(err) {
+			if b.tripped {
+				slog.Info("provider recovered; circuit breaker closed", "provider", b.name)
+			}
+			b.failures, b.tripped = 0, false
+		}
+		return
+	}
+	b.failures++
+	now := b.now()
+	if b.failures < b.threshold || now.Before(b.openUntil) {
+		return
+	}
+	b.openUntil = now.Add(b.cooldown)
+	b.tripped = true
+	slog.Warn("provider keeps failing with server errors; pausing all its requests",
+		"provider", b.name, "consecutive_failures", b.failures, "cooldown", b.cooldown, "err", err)
+}
+
+// isServerError reports whether err is a 5xx response.
+func isServerError(err error) bool {
+	var hErr *httpStatusError
+	return errors.As(err, &hErr) && hErr.StatusCode >= 500 && hErr.StatusCode <= 599
+}
+
+// isHTTPResponseError reports whether err carries a response of the provider
+// (a status other than 2xx), which shows it is reachable.
+func isHTTPResponseError(err error) bool {
+	var hErr *httpStatusError
+	return errors.As(err, &hErr)
+}
+
+type circuitBreakerKey struct{}
+
+// withCircuitBreaker returns a context whose requests (see requestWithRetry)
+// go through b.
+func withCircuitBreaker(ctx context.Context, b *circuitBreaker) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, circuitBreakerKey{}, b)
+}
+
+func circuitBreakerFrom(ctx context.Context) *circuitBreaker {
+	b, _ := ctx.Value(circuitBreakerKey{}).(*circuitBreaker)
+	return b
+}
@@ -0,0 +1,82 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_TripsAfterConsecutiveServerErrors(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	b := newCircuitBreaker("gpt-test", 3, 10*time.Second)
+	b.now = func() time.Time { return now }
+	serverErr := &httpStatusError{StatusCode: http.StatusServiceUnavailable}
+
+	b.record(serverErr)
+	b.record(serverErr)
+	b.record(errors.New("connection reset")) // neither counts nor resets
+	if !b.openUntil.IsZero() {
+		t.Fatalf("expected the breaker to stay closed below the threshold")
+	}
+	b.record(serverErr)
+	if got := b.openUntil.Sub(now); got != 10*time.Second {
+		t.Fatalf("expected the breaker to open for the cooldown, got %v", got)
+	}
+
+	// Requests wait while the breaker is open.
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected wait to block until the context is done, got %v", err)
+	}
+
+	// After the cooldown, a server error trips it again right away.
+	now = now.Add(11 * time.Second)
+	if err := b.wait(t.Context()); err != nil {
+		t.Fatalf("wait after the cooldown: %v", err)
+	}
+	b.record(serverErr)
+	if !b.openUntil.After(now) {
+		t.Fatalf("expected a server error after the cooldown to trip the breaker again")
+	}
+
+	// Any other response closes it.
+	now = now.Add(11 * time.Second)
+	b.record(&httpStatusError{StatusCode: http.StatusBadRequest})
+	b.record(serverErr)
+	if b.openUntil.After(now) || b.tripped {
+		t.Fatalf("expected a non-5xx response to reset the consecutive failures")
+	}
+}
+
+func TestRequestWithRetry_WaitsForOpenCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker("gpt-test", 1, time.Minute)
+	ctx, cancel := context.WithTimeout(withCircuitBreaker(t.Context(), b), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	_, err := requestWithRetry(ctx, RetryOptions{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		func(int) (string, retryDecision) {
+			calls++
+			return "", retryDecision{err: &httpStatusError{StatusCode: http.StatusBadGateway}, retry: true}
+		})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the retry to wait for the breaker cooldown, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected no request while the breaker is open, got %d", calls)
+	}
+}
+
+func TestNewCircuitBreaker_Disabled(t *testing.T) {
+	if b := newCircuitBreaker("gpt-test", 0, 0); b != nil {
+		t.Fatalf("expected no breaker with a zero threshold")
+	}
+	var b *circuitBreaker
+	b.record(&httpStatusError{StatusCode: http.StatusInternalServerError})
+	if err := b.wait(t.Context()); err != nil {
+		t.Fatalf("wait on a nil breaker: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package translate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+)
+
+// cacheAutoLanguage is used in cache file names when the source language is
+// auto-detected.
+const cacheAutoLanguage = "auto"
+
+// DefaultCacheDir returns the default translation cache directory under the
+// user cache dir (e.g. ~/.cache/subtitle-tools/translate).
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "subtitle-tools", "translate"), nil
+}
+
+// translationCache is an on-disk translation memory shared between runs.
+//
+// Entries are keyed by the SHA-256 of the source text and stored as NDJSON, one
+// file per (source language, target language, model, variant). New entries are
+// appended as soon as a batch is translated, so a failed run keeps its
+// progress.
+//
+// The model is the primary one of the run: the translations of its fallbacks
+// and the corrections of the review pass are kept with its own, so a later
+// run finds them.
+type translationCache struct {
+	mu    sync.Mutex
+	scope *cacheScope
+}
+
+type cacheScope struct {
+	path    string
+	entries map[string]string
+}
+
+type cacheEntry struct {
+	Hash string `json:"h"`
+	Text string `json:"t"`
+}
+
+// openTranslationCache opens the cache of model in dir. variant (see
+// cacheVariant) separates the translations made with options that change the
+// output; empty for the defaults.
+func openTranslationCache(dir, sourceLanguage, targetLanguage, model, variant string) (*translationCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	key := model
+	if variant != "" {
+		key += "\x00" + variant
+	}
+	name := fmt.Sprintf("%s_%s_%s.jsonl", languageFileTag(sourceLanguage), languageFileTag(targetLanguage), cacheTextHash(key)[:16])
+	s := &cacheScope{path: filepath.Join(dir, name), entries: make(map[string]string)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return &translationCache{scope: s}, nil
+}
+
+// cacheVariant returns the variant of the cache for opts: a hash of the
+// options that change the translation, including the contents of the prompt
+// and glossary files and the terms of the SeriesContext, so that changing one
+// of them doesn't reuse the translations made before. It is empty with the
+// defaults.
+func cacheVariant(opts Options) (string, error) {
+	var parts []string
+	if opts.Formality != "" && opts.Formality != FormalityDefault {
+		parts = append(parts, "formality="+opts.Formality)
+	}
+	if opts.SDH != SDHKeep {
+		parts = append(parts, "sdh="+opts.SDH)
+	}
+	if opts.LineBreaks != "" && opts.LineBreaks != LineBreaksPreserve {
+		parts = append(parts, "linebreaks="+opts.LineBreaks)
+	}
+	if opts.LocalizeNumbers {
+		parts = append(parts, "localize-numbers")
+	}
+	if opts.ConvertUnits {
+		parts = append(parts, "convert-units")
+	}
+	if limits := opts.lineLimits(); limits != (lineLimits{}) {
+		parts = append(parts, fmt.Sprintf("length=%d/%d", limits.maxLen, limits.maxLines))
+	}
+	for _, guidance := range []struct{ name, value string }{{"style", opts.Style}, {"audience", opts.Audience}, {"notes", opts.Notes}} {
+		if v := strings.TrimSpace(guidance.value); v != "" {
+			parts = append(parts, guidance.name+"="+v)
+		}
+	}
+	if summary := opts.SeriesContext.summary(opts.TargetLanguage); summary != "" {
+		parts = append(parts, "series="+cacheTextHash(summary))
+	}
+	for _, file := range []struct{ name, path string }{{"prompt", opts.PromptFile}, {"glossary", opts.GlossaryFile}} {
+		if file.path == "" {
+			continue
+		}
+		b, err := os.ReadFile(file.path)
+		if err != nil {
+			return "", fmt.Errorf("read %s file: %w", file.name, err)
+		}
+		parts = append(parts, file.name+"="+cacheTextHash(string(b)))
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return cacheTextHash(strings.Join(parts, "\x00")), nil
+}
+
+// lookup returns the cached translation of text.
+func (c *translationCache) lookup(text string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.scope.entries[cacheTextHash(text)]
+	return t, ok
+}
+
+// store records translations (source text -> translated text).
+func (c *translationCache) store(translations map[string]string) error {
+	if len(translations) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scope := c.scope
+
+	var buf strings.Builder
+	for source, translated := range translations {
+		h := cacheTextHash(source)
+		if prev, ok := scope.entries[h]; ok && prev == translated {
+			continue
+		}
+		line, err := json.Marshal(cacheEntry{Hash: h, Text: translated})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		scope.entries[h] = translated
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(scope.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open cache file: %w", err)
+	}
+	if _, err := f.WriteString(buf.String()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *cacheScope) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open cache file: %w", err)
+	}
+	defer fs.CloseOrLog(f, s.path)
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e cacheEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil || e.Hash == "" {
+			// A partially written line (e.g. interrupted run) only loses that entry.
+			slog.Debug("skipping invalid translation cache entry", "path", s.path, "err", err)
+			continue
+		}
+		s.entries[e.Hash] = e.Text
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read cache file: %w", err)
+	}
+	return nil
+}
+
+// languageFileTag returns a normalized language tag safe to use in file names.
+func languageFileTag(lang string) string {
+	tag, _ := normalizeTargetLanguage(lang)
+	if tag == "" {
+		return cacheAutoLanguage
+	}
+	// Keep file names portable (e.g. "es-*" patterns).
+	return strings.Map(func(r rune) rune {
+		if r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, tag)
+}
+
+func cacheTextHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestTranslationCache_ScopedByLanguageAndModel(t *testing.T) {
+	dir := t.TempDir()
+	c, err := openTranslationCache(dir, "", "es", "gpt-test", "")
+	if err != nil {
+		t.Fatalf("openTranslationCache: %v", err)
+	}
+	if err := c.store(map[string]string{"Hello": "Hola"}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	// Reopen to make sure entries are persisted.
+	c, err = openTranslationCache(dir, "", "es", "gpt-test", "")
+	if err != nil {
+		t.Fatalf("openTranslationCache: %v", err)
+	}
+	if got, ok := c.lookup("Hello"); !ok || got != "Hola" {
+		t.Fatalf("expected cached translation, got %q ok=%v", got, ok)
+	}
+
+	strip, err := cacheVariant(Options{SDH: SDHStrip})
+	if err != nil {
+		t.Fatalf("cacheVariant: %v", err)
+	}
+	for _, scope := range []struct{ name, target, model, variant string }{
+		{name: "model", target: "es", model: "other-model"},
+		{name: "target language", target: "fr", model: "gpt-test"},
+		{name: "variant", target: "es", model: "gpt-test", variant: strip},
+	} {
+		other, err := openTranslationCache(dir, "", scope.target, scope.model, scope.variant)
+		if err != nil {
+			t.Fatalf("openTranslationCache: %v", err)
+		}
+		if _, ok := other.lookup("Hello"); ok {
+			t.Fatalf("cache must be scoped by %s", scope.name)
+		}
+	}
+}
+
+func TestCacheVariant(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		return path
+	}
+	glossary := writeFile("glossary.txt", "Winterfell -> Invernalia\n")
+	otherGlossary := writeFile("other-glossary.txt", "Winterfell -> Winterfell\n")
+	prompt := writeFile("prompt.tmpl", "Translate:\n{{.Input}}")
+	series := NewSeriesContext()
+	subs := []*srt.Subtitle{{Idx: 1, Text: "Jon Snow is here."}, {Idx: 2, Text: "Where is Jon Snow?"}}
+	if n := series.learn("es", subs, map[int]string{1: "Jon Snow está aquí.", 2: "¿Dónde está Jon Snow?"}); n != 1 {
+		t.Fatalf("expected 1 learned term, got %d", n)
+	}
+
+	for _, defaults := range []Options{
+		{SDH: SDHKeep},
+		{SDH: SDHKeep, LineBreaks: LineBreaksPreserve},
+		{SDH: SDHKeep, TargetLanguage: "es", SeriesContext: NewSeriesContext()},
+		{SDH: SDHKeep, TargetLanguage: "fr", SeriesContext: series},
+	} {
+		if got, err := cacheVariant(defaults); err != nil || got != "" {
+			t.Fatalf("expected no variant with the defaults, got %q (err %v)", got, err)
+		}
+	}
+	variants := map[string]bool{}
+	for _, opts := range []Options{
+		{SDH: SDHStrip},
+		{SDH: SDHGenerate},
+		{SDH: SDHKeep, Formality: FormalityMore},
+		{SDH: SDHKeep, GlossaryFile: glossary},
+		{SDH: SDHKeep, GlossaryFile: otherGlossary},
+		{SDH: SDHKeep, PromptFile: prompt},
+		{SDH: SDHKeep, Style: StyleFormal},
+		{SDH: SDHKeep, Style: StyleInformal},
+		{SDH: SDHKeep, Audience: "children"},
+		{SDH: SDHKeep, Notes: "Keep character names untranslated."},
+		{SDH: SDHKeep, LengthHints: true, MaxLineLength: 42},
+		{SDH: SDHKeep, LengthHints: true, MaxLineLength: 42, MaxLines: 2},
+		{SDH: SDHKeep, LineBreaks: LineBreaksReflow},
+		{SDH: SDHKeep, LocalizeNumbers: true},
+		{SDH: SDHKeep, ConvertUnits: true},
+		{SDH: SDHKeep, LocalizeNumbers: true, ConvertUnits: true},
+		{SDH: SDHKeep, TargetLanguage: "es", SeriesContext: series},
+	} {
+		v, err := cacheVariant(opts)
+		if err != nil {
+			t.Fatalf("cacheVariant: %v", err)
+		}
+		if v == "" || variants[v] {
+			t.Fatalf("expected a distinct variant for %+v, got %q", opts, v)
+		}
+		variants[v] = true
+	}
+}
+
+func TestTranslationCache_SkipsCorruptLines(t *testing.T) {
+	dir := t.TempDir()
+	c, err := openTranslationCache(dir, "en", "es", "m", "")
+	if err != nil {
+		t.Fatalf("openTranslationCache: %v", err)
+	}
+	if err := c.store(map[string]string{"Hello": "Hola"}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	f, err := os.OpenFile(c.scope.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	_, _ = f.WriteString(`{"h":"trunc`)
+	_ = f.Close()
+
+	c, err = openTranslationCache(dir, "en", "es", "m", "")
+	if err != nil {
+		t.Fatalf("openTranslationCache: %v", err)
+	}
+	if got, ok := c.lookup("Hello"); !ok || got != "Hola" {
+		t.Fatalf("expected entry to survive a truncated line, got %q ok=%v", got, ok)
+	}
+}
@@ -0,0 +1,35 @@
+package translate
+
+import (
+	"log/slog"
+
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// censorCues censors the words of opts.CensorListPath in the translated subs
+// and returns the cues to write and how many had a listed word. subs are left
+// untouched.
+func censorCues(subs []*srt.Subtitle, opts Options) ([]*srt.Subtitle, int) {
+	if opts.censorList == nil {
+		return subs, 0
+	}
+	out := make([]*srt.Subtitle, 0, len(subs))
+	censored := 0
+	for _, s := range subs {
+		text, words := opts.censorList.Apply(s.Text, opts.CensorStyle)
+		if len(words) == 0 {
+			out = append(out, s)
+			continue
+		}
+		censored++
+		slog.Debug("censored cue", "target_language", opts.TargetLanguage, "idx", s.Idx, "words", words)
+		if opts.CensorStyle == censor.StyleRemoveCue {
+			continue
+		}
+		c := *s
+		c.Text = text
+		out = append(out, &c)
+	}
+	return out, censored
+}
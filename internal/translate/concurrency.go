@@ -0,0 +1,145 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// adaptiveDecreaseInterval groups throttle signals from requests that were
+// in-flight at the same time, so a single burst of 429s halves the concurrency
+// once instead of collapsing it to 1.
+const adaptiveDecreaseInterval = 2 * time.Second
+
+// concurrencyController limits in-flight batches with an AIMD policy: the limit
+// starts at 1, grows by one after a full window of clean batches and is halved
+// when the provider signals throttling (429/503 or timeouts).
+type concurrencyController struct {
+	max int
+	now func() time.Time
+
+	mu           sync.Mutex
+	limit        int
+	inFlight     int
+	successes    int
+	lastDecrease time.Time
+	changed      chan struct{} // closed (and replaced) whenever a slot may be free
+}
+
+func newConcurrencyController(maxWorkers int) *concurrencyController {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	return &concurrencyController{
+		max:     maxWorkers,
+		now:     time.Now,
+		limit:   1,
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire blocks until a slot is available under the current limit.
+func (c *concurrencyController) acquire(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if c.inFlight < c.limit {
+			c.inFlight++
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release frees a slot. Clean batches (ok: translated without being
+// throttled) count towards the next additive increase.
+func (c *concurrencyController) release(ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	if ok {
+		c.successes++
+		if c.successes >= c.limit && c.limit < c.max {
+			c.limit++
+			c.successes = 0
+			slog.Debug("increasing translation concurrency", "concurrency", c.limit, "max_workers", c.max)
+		}
+	}
+	c.notifyLocked()
+}
+
+// throttled halves the limit (multiplicative decrease).
+func (c *concurrencyController) throttled() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.successes = 0
+	now := c.now()
+	if !c.lastDecrease.IsZero() && now.Sub(c.lastDecrease) < adaptiveDecreaseInterval {
+		return
+	}
+	c.lastDecrease = now
+	if next := max(1, c.limit/2); next != c.limit {
+		c.limit = next
+		slog.Debug("decreasing translation concurrency after throttling", "concurrency", c.limit, "max_workers", c.max)
+	}
+}
+
+func (c *concurrencyController) current() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+func (c *concurrencyController) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+type throttleObserverKey struct{}
+
+// throttleObserver passes the throttle signals seen while translating a batch
+// to its controller, and remembers them: a throttled batch doesn't count
+// towards the next additive increase, even when its retries succeed.
+type throttleObserver struct {
+	controller *concurrencyController
+	throttled  atomic.Bool
+}
+
+// withThrottleObserver returns a context that reports throttle signals seen by
+// requestWithRetry to c, and the observer recording them.
+func withThrottleObserver(ctx context.Context, c *concurrencyController) (context.Context, *throttleObserver) {
+	o := &throttleObserver{controller: c}
+	return context.WithValue(ctx, throttleObserverKey{}, o), o
+}
+
+func reportThrottle(ctx context.Context, err error) {
+	o, _ := ctx.Value(throttleObserverKey{}).(*throttleObserver)
+	if o == nil || !isThrottleSignal(err) {
+		return
+	}
+	o.throttled.Store(true)
+	o.controller.throttled()
+}
+
+// isThrottleSignal reports whether err means the provider is overloaded.
+func isThrottleSignal(err error) bool {
+	var hErr *httpStatusError
+	if errors.As(err, &hErr) {
+		return hErr.StatusCode == http.StatusTooManyRequests || hErr.StatusCode == http.StatusServiceUnavailable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return isTimeoutNetErr(err)
+}
@@ -0,0 +1,109 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyController_AdditiveIncreaseUpToMax(t *testing.T) {
+	c := newConcurrencyController(3)
+	if got := c.current(); got != 1 {
+		t.Fatalf("expected initial concurrency 1, got %d", got)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := c.acquire(t.Context()); err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		c.release(true)
+	}
+	if got := c.current(); got != 3 {
+		t.Fatalf("expected concurrency capped at 3, got %d", got)
+	}
+}
+
+func TestConcurrencyController_MultiplicativeDecreaseOncePerBurst(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	c := newConcurrencyController(8)
+	c.now = func() time.Time { return now }
+	c.limit = 8
+
+	c.throttled()
+	c.throttled() // same burst; ignored
+	if got := c.current(); got != 4 {
+		t.Fatalf("expected concurrency 4 after one burst, got %d", got)
+	}
+
+	now = now.Add(adaptiveDecreaseInterval)
+	c.throttled()
+	if got := c.current(); got != 2 {
+		t.Fatalf("expected concurrency 2 after second burst, got %d", got)
+	}
+}
+
+func TestConcurrencyController_AcquireBlocksAtLimit(t *testing.T) {
+	c := newConcurrencyController(4)
+	if err := c.acquire(t.Context()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected acquire to block at the limit, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.acquire(t.Context()) }()
+	c.release(false)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("acquire after release: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("acquire did not resume after release")
+	}
+}
+
+func TestReportThrottle_OnlyForThrottleSignals(t *testing.T) {
+	c := newConcurrencyController(8)
+	c.limit = 8
+	ctx, observer := withThrottleObserver(t.Context(), c)
+
+	reportThrottle(ctx, &httpStatusError{StatusCode: http.StatusBadRequest})
+	if got := c.current(); got != 8 || observer.throttled.Load() {
+		t.Fatalf("400 must not reduce concurrency, got %d", got)
+	}
+	reportThrottle(ctx, &httpStatusError{StatusCode: http.StatusTooManyRequests})
+	if got := c.current(); got != 4 || !observer.throttled.Load() {
+		t.Fatalf("429 must halve concurrency, got %d", got)
+	}
+}
+
+func TestConcurrencyController_ThrottledBatchIsNotClean(t *testing.T) {
+	c := newConcurrencyController(4)
+	batch := func(status int) {
+		if err := c.acquire(t.Context()); err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		ctx, observer := withThrottleObserver(t.Context(), c)
+		if status != 0 {
+			reportThrottle(ctx, &httpStatusError{StatusCode: status})
+		}
+		// The batch succeeds, after retrying the throttled requests.
+		c.release(!observer.throttled.Load())
+	}
+
+	batch(http.StatusTooManyRequests)
+	if got := c.current(); got != 1 {
+		t.Fatalf("a throttled batch must not increase concurrency, got %d", got)
+	}
+	batch(0)
+	if got := c.current(); got != 2 {
+		t.Fatalf("expected a clean batch to increase concurrency to 2, got %d", got)
+	}
+}
@@ -0,0 +1,343 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+const (
+	deeplFreeBaseURL = "https://api-free.deepl.com"
+	deeplProBaseURL  = "https://api.deepl.com"
+	// deeplFreeKeySuffix identifies keys of the DeepL API Free plan.
+	deeplFreeKeySuffix = ":fx"
+	// deeplMaxTextsPerRequest is the max number of texts accepted by /v2/translate.
+	deeplMaxTextsPerRequest = 50
+	// deeplLineBreakTag replaces cue line breaks while tag handling is enabled,
+	// because XML tag handling does not guarantee raw newlines are preserved.
+	deeplLineBreakTag = "<br/>"
+)
+
+// deeplXMLEscaper escapes the text of a cue for XML tag handling, so a literal
+// "&" or "<" is not read as markup.
+var deeplXMLEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// deeplXMLTagPattern matches a tag that is well-formed XML: a name and quoted
+// attributes. The groups are the slash of a closing tag, the name, the
+// attributes and the slash of a self-closing tag.
+var deeplXMLTagPattern = regexp.MustCompile(`^<(/?)([a-zA-Z][\w.-]*)((?:\s+[a-zA-Z_][\w.:-]*\s*=\s*(?:"[^"<&]*"|'[^'<&]*'))*)\s*(/?)>$`)
+
+// deeplXMLText returns text as sent with XML tag handling: the inline tags
+// (<i>, <font color="...">) kept as elements, so DeepL moves them with the
+// words they wrap, the rest escaped and the line breaks as <br/>. The tags
+// that wouldn't make valid XML (see deeplXMLElements) are escaped as text.
+func deeplXMLText(text string) string {
+	matches := srt.InlineTagPattern.FindAllStringIndex(text, -1)
+	elements := deeplXMLElements(text, matches)
+	var b strings.Builder
+	last := 0
+	for i, m := range matches {
+		if !elements[i] {
+			continue
+		}
+		b.WriteString(deeplXMLEscaper.Replace(text[last:m[0]]))
+		b.WriteString(text[m[0]:m[1]])
+		last = m[1]
+	}
+	b.WriteString(deeplXMLEscaper.Replace(text[last:]))
+	return strings.ReplaceAll(b.String(), "\n", deeplLineBreakTag)
+}
+
+// deeplXMLElements reports which of the tags of text at matches (see
+// srt.InlineTagPattern) are sent as XML elements: the well-formed ones that are
+// self-closing or closed in the same cue, properly nested. ASS override
+// blocks, unquoted attributes (<font color=#ff0000>) and tags opened in a cue
+// and closed in the next are not.
+func deeplXMLElements(text string, matches [][]int) []bool {
+	elements := make([]bool, len(matches))
+	type openTag struct {
+		i    int
+		name string
+	}
+	var open []openTag
+	for i, m := range matches {
+		parts := deeplXMLTagPattern.FindStringSubmatch(text[m[0]:m[1]])
+		switch {
+		case parts == nil:
+		case parts[1] == "/":
+			if parts[3] != "" || parts[4] != "" {
+				continue
+			}
+			if n := len(open); n > 0 && open[n-1].name == parts[2] {
+				elements[open[n-1].i], elements[i] = true, true
+				open = open[:n-1]
+			}
+		case parts[4] == "/":
+			elements[i] = true
+		default:
+			open = append(open, openTag{i: i, name: parts[2]})
+		}
+	}
+	return elements
+}
+
+// DeepL formality values (only honored for some target languages).
+const (
+	FormalityDefault    = "default"
+	FormalityMore       = "more"
+	FormalityLess       = "less"
+	FormalityPreferMore = "prefer_more"
+	FormalityPreferLess = "prefer_less"
+)
+
+// DeepLClient translates cues with the DeepL /v2/translate endpoint.
+//
+// It implements BatchTranslator by decoding the NDJSON payload, sending the cue
+// texts as-is (no prompt) and re-encoding the translated texts with their idx.
+type DeepLClient struct {
+	HTTPClient   *http.Client
+	Transport    http.RoundTripper // used when HTTPClient is nil
+	BaseURL      string            // optional; inferred from the key type when empty
+	APIKey       string            // can be a single key or a comma-separated list of keys
+	Formality    string
+	Timeout      time.Duration
+	RetryOptions RetryOptions
+
+	// KeyRPS limits requests per second for each API key (0 disables it).
+	KeyRPS float64
+
+	keyPoolOnce sync.Once
+	keyPool     *apiKeyPool
+}
+
+type deeplTranslateRequest struct {
+	Text               []string `json:"text"`
+	TargetLang         string   `json:"target_lang"`
+	SourceLang         string   `json:"source_lang,omitempty"`
+	Formality          string   `json:"formality,omitempty"`
+	TagHandling        string   `json:"tag_handling,omitempty"`
+	PreserveFormatting bool     `json:"preserve_formatting"`
+}
+
+type deeplTranslateResponse struct {
+	Translations []struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	} `json:"translations"`
+}
+
+func normalizeFormality(formality string) string {
+	return strings.ToLower(strings.TrimSpace(formality))
+}
+
+func isValidFormality(formality string) bool {
+	switch formality {
+	case "", FormalityDefault, FormalityMore, FormalityLess, FormalityPreferMore, FormalityPreferLess:
+		return true
+	default:
+		return false
+	}
+}
+
+// deeplFormalityForStyle derives a formality from --style when no explicit
+// formality was requested.
+func deeplFormalityForStyle(formality, style string) string {
+	if normalizeFormality(formality) != "" {
+		return formality
+	}
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case StyleFormal:
+		return FormalityPreferMore
+	case StyleInformal, StyleColloquial:
+		return FormalityPreferLess
+	default:
+		return ""
+	}
+}
+
+func (c *DeepLClient) TranslateBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error) {
+	if targetLanguage == "" {
+		return "", errors.New("target language is required")
+	}
+	c.keyPoolOnce.Do(func() {
+		c.keyPool = newAPIKeyPool(splitAPIKeys(c.APIKey), c.KeyRPS)
+	})
+	keys := c.keyPool
+	if keys.size() == 0 {
+		return "", errors.New("api key is required for deepl")
+	}
+
+	items, err := ParseTranslatedLines(payload)
+	if err != nil {
+		return "", fmt.Errorf("decode deepl payload: %w", err)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Transport: c.Transport, Timeout: c.Timeout}
+	}
+
+	base := strings.TrimSpace(c.BaseURL)
+	if base == "" {
+		base = deeplBaseURLForKey(keys.keys[0])
+	}
+	u, err := buildURL(base, "/v2/translate")
+	if err != nil {
+		return "", err
+	}
+
+	targetLang := deeplTargetLanguage(targetLanguage)
+	sourceLang := deeplSourceLanguage(sourceLanguage)
+
+	var out strings.Builder
+	for start := 0; start < len(items); start += deeplMaxTextsPerRequest {
+		end := min(start+deeplMaxTextsPerRequest, len(items))
+		chunk := items[start:end]
+
+		texts := make([]string, 0, len(chunk))
+		for _, it := range chunk {
+			texts = append(texts, deeplXMLText(it.Text))
+		}
+		body, err := json.Marshal(deeplTranslateRequest{
+			Text:               texts,
+			TargetLang:         targetLang,
+			SourceLang:         sourceLang,
+			Formality:          normalizeFormality(c.Formality),
+			TagHandling:        "xml",
+			PreserveFormatting: true,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		translated, err := c.post(ctx, hc, u.String(), keys, body)
+		if err != nil {
+			return "", err
+		}
+		if len(translated) != len(chunk) {
+			return "", fmt.Errorf("deepl returned %d translations for %d texts", len(translated), len(chunk))
+		}
+		for i, it := range chunk {
+			text := html.UnescapeString(strings.ReplaceAll(translated[i], deeplLineBreakTag, "\n"))
+			enc, err := FormatOneForTranslation(it.Idx, text)
+			if err != nil {
+				return "", err
+			}
+			if out.Len() > 0 {
+				out.WriteByte('\n')
+			}
+			out.Write(enc)
+		}
+	}
+	return out.String(), nil
+}
+
+func (c *DeepLClient) post(ctx context.Context, hc *http.Client, u string, keys *apiKeyPool, body []byte) ([]string, error) {
+	return requestWithRetry[[]string](ctx, c.RetryOptions, func(attempt int) ([]string, retryDecision) {
+		apiKey, keyIdx, err := keys.acquire(ctx)
+		if err != nil {
+			return nil, retryDecision{err: err}
+		}
+
+		r, err := doJSONRequest(ctx, hc, http.MethodPost, u, "DeepL-Auth-Key "+apiKey, body)
+		if err != nil {
+			if isRetryableNetErr(err) {
+				return nil, retryDecision{err: err, retry: true}
+			}
+			return nil, retryDecision{err: err}
+		}
+
+		if r.statusCode < 200 || r.statusCode >= 300 {
+			hErr := &httpStatusError{StatusCode: r.statusCode, Body: strings.TrimSpace(string(r.bodyBytes))}
+			retryAfter := retryDelayFromHeader(r.header)
+			// 456: quota exceeded. Another key may still have quota left.
+			rotate := keys.size() > 1 && (isRejectedHTTPStatus(r.statusCode) || r.statusCode == deeplQuotaExceededStatus)
+			if rotate {
+				cooldown := keyCooldown(r.statusCode, retryAfter)
+				if r.statusCode == deeplQuotaExceededStatus {
+					cooldown = DefaultKeyRejectCooldown
+				}
+				slog.Warn("deepl api rejected request; rotating api key",
+					"attempt", attempt,
+					"status_code", r.statusCode,
+					"rejected_key", run.MaskKey(apiKey),
+					"cooldown", cooldown,
+					"keys", keys.size(),
+				)
+				keys.bench(keyIdx, cooldown)
+				return nil, retryDecision{err: hErr, retry: true, delay: time.Millisecond}
+			}
+			if isRetryableHTTPStatus(r.statusCode) {
+				return nil, retryDecision{err: hErr, retry: true, delay: retryAfter}
+			}
+			return nil, retryDecision{err: hErr}
+		}
+
+		var resp deeplTranslateResponse
+		if err := json.Unmarshal(r.bodyBytes, &resp); err != nil {
+			return nil, retryDecision{err: fmt.Errorf("decode deepl response: %w", err), retry: true}
+		}
+		texts := make([]string, 0, len(resp.Translations))
+		for _, t := range resp.Translations {
+			texts = append(texts, t.Text)
+		}
+		return texts, retryDecision{}
+	})
+}
+
+// deeplQuotaExceededStatus is DeepL's non-standard "Quota exceeded" status code.
+const deeplQuotaExceededStatus = 456
+
+func deeplBaseURLForKey(apiKey string) string {
+	if strings.HasSuffix(apiKey, deeplFreeKeySuffix) {
+		return deeplFreeBaseURL
+	}
+	return deeplProBaseURL
+}
+
+// deeplTargetLanguage maps a user language tag to a DeepL target_lang value.
+// DeepL only accepts regional variants for a few languages (e.g. EN-US, PT-BR,
+// ES-419); for the rest, the region is dropped.
+func deeplTargetLanguage(input string) string {
+	tag, _ := normalizeTargetLanguage(input)
+	parts := strings.Split(tag, LanguageSeparator)
+	lang := strings.ToUpper(parts[0])
+	if len(parts) < 2 {
+		return lang
+	}
+	region := strings.ToUpper(parts[1])
+	switch lang {
+	case "EN", "PT":
+		return lang + "-" + region
+	case "ES":
+		if region == "419" {
+			return lang + "-" + region
+		}
+	case "ZH":
+		if region == "HANS" || region == "HANT" {
+			return lang + "-" + region
+		}
+	}
+	return lang
+}
+
+// deeplSourceLanguage maps a user language tag to a DeepL source_lang value.
+// Source languages never carry a region. Empty means auto-detect.
+func deeplSourceLanguage(input string) string {
+	tag, _ := normalizeTargetLanguage(input)
+	if tag == "" {
+		return ""
+	}
+	return strings.ToUpper(strings.Split(tag, LanguageSeparator)[0])
+}
@@ -0,0 +1,153 @@
+package translate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeepLClient_TranslateBatch(t *testing.T) {
+	var got deeplTranslateRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/translate" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"translations":[{"text":"Hola<br/>mundo"},{"text":"<i>Adiós</i>"},{"text":"Tom &amp; Jerry &lt;3"}]}`))
+	}))
+	defer server.Close()
+
+	c := DeepLClient{BaseURL: server.URL, APIKey: "key:fx", Formality: FormalityLess, RetryOptions: RetryOptions{MaxAttempts: 1}}
+	payload, err := FormatForTranslation([]int{3, 4, 5}, []string{"Hello\nworld", "<i>Bye</i>", "Tom & Jerry <3"})
+	if err != nil {
+		t.Fatalf("FormatForTranslation: %v", err)
+	}
+	out, err := (&c).TranslateBatch(t.Context(), "en", "es-419", payload)
+	if err != nil {
+		t.Fatalf("TranslateBatch: %v", err)
+	}
+
+	if auth != "DeepL-Auth-Key key:fx" {
+		t.Fatalf("unexpected Authorization header %q", auth)
+	}
+	if got.TargetLang != "ES-419" || got.SourceLang != "EN" || got.Formality != FormalityLess {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if len(got.Text) != 3 || got.Text[0] != "Hello<br/>world" || got.Text[1] != "<i>Bye</i>" || got.Text[2] != "Tom &amp; Jerry &lt;3" {
+		t.Fatalf("unexpected texts: %#v", got.Text)
+	}
+
+	parsed, err := ParseTranslatedLines(out)
+	if err != nil {
+		t.Fatalf("ParseTranslatedLines: %v", err)
+	}
+	if len(parsed) != 3 || parsed[0].Idx != 3 || parsed[0].Text != "Hola\nmundo" || parsed[1].Text != "<i>Adiós</i>" ||
+		parsed[2].Text != "Tom & Jerry <3" {
+		t.Fatalf("unexpected parsed output: %+v", parsed)
+	}
+}
+
+func TestDeepLXMLText(t *testing.T) {
+	cases := map[string]string{
+		"<i>Hello</i>\nworld":                   "<i>Hello</i><br/>world",
+		`<font color="#ff0000">Red</font> & <3`: `<font color="#ff0000">Red</font> &amp; &lt;3`,
+		"<i><b>Hi</b></i><br/>":                 "<i><b>Hi</b></i><br/>",
+		"{\\an8}Top":                            "{\\an8}Top",
+		// Unquoted attributes are not XML.
+		"<font color=#ff0000>Red</font>": "&lt;font color=#ff0000&gt;Red&lt;/font&gt;",
+		// A tag closed in the next cue, or not closed in order.
+		"<i>Hello":                   "&lt;i&gt;Hello",
+		"world</i>":                  "world&lt;/i&gt;",
+		"<i><b>Hi</i></b>":           "&lt;i&gt;<b>Hi&lt;/i&gt;</b>",
+		"<I>Hi</i>":                  "&lt;I&gt;Hi&lt;/i&gt;",
+		`<font color="a&b">x</font>`: `&lt;font color="a&amp;b"&gt;x&lt;/font&gt;`,
+	}
+	for in, want := range cases {
+		if got := deeplXMLText(in); got != want {
+			t.Errorf("deeplXMLText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDeepLClient_MalformedTagsRoundTrip(t *testing.T) {
+	// A DeepL stand-in that returns the texts as sent.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req deeplTranslateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type translation struct {
+			Text string `json:"text"`
+		}
+		var resp struct {
+			Translations []translation `json:"translations"`
+		}
+		for _, text := range req.Text {
+			resp.Translations = append(resp.Translations, translation{Text: text})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	texts := []string{"<font color=#ff0000>Red</font>", "<i>Hello", "world</i>"}
+	c := DeepLClient{BaseURL: server.URL, APIKey: "key:fx", RetryOptions: RetryOptions{MaxAttempts: 1}}
+	payload, err := FormatForTranslation([]int{1, 2, 3}, texts)
+	if err != nil {
+		t.Fatalf("FormatForTranslation: %v", err)
+	}
+	out, err := (&c).TranslateBatch(t.Context(), "en", "es", payload)
+	if err != nil {
+		t.Fatalf("TranslateBatch: %v", err)
+	}
+	parsed, err := ParseTranslatedLines(out)
+	if err != nil {
+		t.Fatalf("ParseTranslatedLines: %v", err)
+	}
+	for i, pl := range parsed {
+		if pl.Text != texts[i] {
+			t.Fatalf("cue %d: got %q, want %q", pl.Idx, pl.Text, texts[i])
+		}
+	}
+}
+
+func TestDeepLTargetLanguage(t *testing.T) {
+	cases := map[string]string{
+		"es":      "ES",
+		"es-MX":   "ES",
+		"es_419":  "ES-419",
+		"en-us":   "EN-US",
+		"pt-BR":   "PT-BR",
+		"fr-CA":   "FR",
+		"zh-Hans": "ZH-HANS",
+	}
+	for in, want := range cases {
+		if got := deeplTargetLanguage(in); got != want {
+			t.Fatalf("deeplTargetLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDeepLBaseURLForKey(t *testing.T) {
+	if got := deeplBaseURLForKey("abc:fx"); got != deeplFreeBaseURL {
+		t.Fatalf("free key: got %q", got)
+	}
+	if got := deeplBaseURLForKey("abc"); !strings.Contains(got, "api.deepl.com") {
+		t.Fatalf("pro key: got %q", got)
+	}
+}
+
+func TestDeepLFormalityForStyle(t *testing.T) {
+	if got := deeplFormalityForStyle("", "formal"); got != FormalityPreferMore {
+		t.Fatalf("formal: got %q", got)
+	}
+	if got := deeplFormalityForStyle("", "colloquial"); got != FormalityPreferLess {
+		t.Fatalf("colloquial: got %q", got)
+	}
+	if got := deeplFormalityForStyle(FormalityLess, "formal"); got != FormalityLess {
+		t.Fatalf("explicit formality must win, got %q", got)
+	}
+}
@@ -0,0 +1,43 @@
+package translate
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/rtl"
+)
+
+// markRTLTranslations adds RLM marks to the lines of the translated cues that
+// need them (see rtl.AddMarks). It returns the number of cues changed.
+func markRTLTranslations(translated map[int]string) int {
+	changed := 0
+	for idx, t := range translated {
+		if marked := rtl.AddMarks(t); marked != t {
+			translated[idx] = marked
+			changed++
+		}
+	}
+	return changed
+}
+
+// logReversedPunctuation warns about the translated cues whose punctuation
+// looks stored in display order (see rtl.ReversedPunctuation).
+func logReversedPunctuation(targetLanguage string, translated map[int]string) {
+	var idxs []int
+	for idx, t := range translated {
+		if len(rtl.ReversedPunctuation(t)) > 0 {
+			idxs = append(idxs, idx)
+		}
+	}
+	if len(idxs) == 0 {
+		return
+	}
+	slices.Sort(idxs)
+	s := make([]string, 0, len(idxs))
+	for _, idx := range idxs {
+		s = append(s, fmt.Sprint(idx))
+	}
+	slog.Warn("translated cues with the punctuation at the wrong end", "target_language", targetLanguage, "cues", len(idxs), "idxs", abbreviate(strings.Join(s, ","), AbbreviationMax))
+}
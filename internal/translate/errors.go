@@ -0,0 +1,27 @@
+package translate
+
+import (
+	"errors"
+	"net/http"
+)
+
+// IsAuthError reports whether err is the provider rejecting the API key
+// (401 or 403).
+func IsAuthError(err error) bool {
+	var hErr *httpStatusError
+	return errors.As(err, &hErr) && (hErr.StatusCode == http.StatusUnauthorized || hErr.StatusCode == http.StatusForbidden)
+}
+
+// IsRateLimitError reports whether err is the provider still answering 429 Too
+// Many Requests after all the retries.
+func IsRateLimitError(err error) bool {
+	var hErr *httpStatusError
+	return errors.As(err, &hErr) && hErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsParseRetryError reports whether err is a batch whose output was still
+// invalid after all the parse retries.
+func IsParseRetryError(err error) bool {
+	var parseErr *batchParseError
+	return errors.As(err, &parseErr)
+}
@@ -0,0 +1,25 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorClasses(t *testing.T) {
+	wrap := func(err error) error { return fmt.Errorf("es: batch 3: %w", err) }
+	auth := wrap(&httpStatusError{StatusCode: 401})
+	limited := wrap(&httpStatusError{StatusCode: 429})
+	parse := wrap(&batchParseError{err: errNoTranslatedLinesParsed})
+	other := wrap(&httpStatusError{StatusCode: 500})
+
+	if !IsAuthError(auth) || IsAuthError(limited) || IsAuthError(other) {
+		t.Fatalf("IsAuthError")
+	}
+	if !IsRateLimitError(limited) || IsRateLimitError(auth) {
+		t.Fatalf("IsRateLimitError")
+	}
+	if !IsParseRetryError(parse) || IsParseRetryError(errors.New("x")) {
+		t.Fatalf("IsParseRetryError")
+	}
+}
@@ -21,6 +21,8 @@ import (
 // Example:
 //   {"idx":196,"text":"Line 1\nLine 2"}
 // The JSON string can contain newlines via standard JSON escaping; callers see real "\n".
+// With Options.LengthHints, input items also carry the line limits of the cue:
+//   {"idx":196,"text":"Line 1\nLine 2","max_len":42,"max_lines":2}
 
 const AbbreviationMax = 250
 
@@ -29,14 +31,27 @@ var errNoTranslatedLinesParsed = errors.New("no translated lines parsed")
 type wireItem struct {
 	Idx  int    `json:"idx"`
 	Text string `json:"text"`
+	// MaxLen and MaxLines are only sent to the model (see lineLimits).
+	MaxLen   int `json:"max_len,omitempty"`
+	MaxLines int `json:"max_lines,omitempty"`
 }
 
 func FormatOneForTranslation(idx int, text string) ([]byte, error) {
-	item := wireItem{Idx: idx, Text: strings.ReplaceAll(text, "\r\n", "\n")}
+	return formatOneForTranslation(idx, text, lineLimits{})
+}
+
+func formatOneForTranslation(idx int, text string, limits lineLimits) ([]byte, error) {
+	item := wireItem{Idx: idx, Text: strings.ReplaceAll(text, "\r\n", "\n"), MaxLen: limits.maxLen, MaxLines: limits.maxLines}
 	return json.Marshal(item)
 }
 
 func FormatForTranslation(idxs []int, texts []string) (string, error) {
+	return formatForTranslation(idxs, texts, lineLimits{})
+}
+
+// formatForTranslation is FormatForTranslation with the line limits of every
+// cue.
+func formatForTranslation(idxs []int, texts []string, limits lineLimits) (string, error) {
 	if len(idxs) != len(texts) {
 		return "", errors.New("idxs and texts length mismatch")
 	}
@@ -48,7 +63,7 @@ func FormatForTranslation(idxs []int, texts []string) (string, error) {
 		if i > 0 {
 			b.WriteByte('\n')
 		}
-		enc, err := FormatOneForTranslation(idxs[i], texts[i])
+		enc, err := formatOneForTranslation(idxs[i], texts[i], limits)
 		if err != nil {
 			return "", err
 		}
@@ -62,48 +77,153 @@ type ParsedLine struct {
 	Text string
 }
 
+// Parse strictness: which fallbacks are allowed to read a model output.
+const (
+	// ParseStrict only accepts well-formed output: a JSON array, the
+	// structured output envelope or one JSON object per line.
+	ParseStrict = "strict"
+	// ParseTolerant also accepts valid JSON objects wrapped in a code fence,
+	// surrounded by other text or not one per line.
+	ParseTolerant = "tolerant"
+	// ParseSalvage also repairs broken JSON heuristically (e.g. unescaped
+	// quotes in the text).
+	ParseSalvage = "salvage"
+
+	DefaultParseStrictness = ParseSalvage
+)
+
+func normalizeParseStrictness(strictness string) string {
+	return strings.ToLower(strings.TrimSpace(strictness))
+}
+
+func isValidParseStrictness(strictness string) bool {
+	return strictness == ParseStrict || strictness == ParseTolerant || strictness == ParseSalvage
+}
+
+// Parse modes of ParseDiagnostics, from the strictest to the last-resort
+// salvage.
+const (
+	ParseModeArray           = "array"            // a JSON array of items
+	ParseModeEnvelope        = "envelope"         // structured output: {"items":[...]}
+	ParseModeObjects         = "objects"          // NDJSON, or JSON objects not one per line
+	ParseModeLines           = "lines"            // NDJSON read line by line
+	ParseModeLinesRepaired   = "lines-repaired"   // NDJSON with broken lines repaired
+	ParseModeObjectsRepaired = "objects-repaired" // JSON objects with broken escaping repaired
+)
+
+// ParseDiagnostics describes how a model output was parsed.
+type ParseDiagnostics struct {
+	Mode string
+	// CodeFence is true when the output was wrapped in a Markdown code fence.
+	CodeFence bool
+	Salvaged  int // broken lines repaired with ParseModeLinesRepaired
+	Repaired  int // broken objects repaired with ParseModeObjectsRepaired
+}
+
+// ParseStats sums the diagnostics of the model outputs parsed in a run, to
+// track the output quality of a model.
+type ParseStats struct {
+	Modes      map[string]int // outputs parsed per mode
+	CodeFences int
+	Salvaged   int // lines
+	Repaired   int // objects
+	Failures   int // outputs that couldn't be parsed or didn't match the batch
+	// Truncated counts the outputs cut at the output token limit; their
+	// batch is split.
+	Truncated int
+}
+
+func (s *ParseStats) add(d ParseDiagnostics) {
+	if s.Modes == nil {
+		s.Modes = make(map[string]int)
+	}
+	s.Modes[d.Mode]++
+	if d.CodeFence {
+		s.CodeFences++
+	}
+	s.Salvaged += d.Salvaged
+	s.Repaired += d.Repaired
+}
+
+// ParseTranslatedLines parses a model output with every fallback
+// (ParseSalvage).
 func ParseTranslatedLines(out string) ([]ParsedLine, error) {
+	res, _, err := ParseTranslatedLinesWithDiagnostics(out, ParseSalvage)
+	return res, err
+}
+
+// ParseTranslatedLinesWithDiagnostics parses out with the fallbacks allowed by
+// strictness (ParseSalvage when empty) and reports how it was parsed.
+func ParseTranslatedLinesWithDiagnostics(out, strictness string) ([]ParsedLine, ParseDiagnostics, error) {
 	out = strings.ReplaceAll(out, "\r\n", "\n")
+	var diag ParseDiagnostics
+	diag.CodeFence = strings.HasPrefix(strings.TrimSpace(out), "```")
+	if diag.CodeFence && strictness == ParseStrict {
+		return nil, diag, errors.New("translation output is wrapped in a code fence (not allowed by strict parsing)")
+	}
 	out = stripCodeFences(out)
 	out = strings.TrimSpace(out)
 	if out == "" {
-		return nil, errors.New("empty translation output")
+		return nil, diag, errors.New("empty translation output")
 	}
 
 	if strings.HasPrefix(out, "[") {
-		return parseWireItemsJSONArray(out)
+		diag.Mode = ParseModeArray
+		res, err := parseWireItemsJSONArray(out)
+		return res, diag, err
+	}
+
+	// Structured output mode: {"items":[...]}.
+	if res, ok, err := parseWireEnvelope(out); ok {
+		diag.Mode = ParseModeEnvelope
+		return res, diag, err
+	}
+
+	if strictness == ParseStrict {
+		res, err := parseWireItemsByLines(out)
+		diag.Mode = ParseModeLines
+		return res, diag, err
 	}
 
 	// Robust mode: extract balanced JSON objects and unmarshal each.
 	// This tolerates whitespace, code fences already stripped, and even cases where
 	// objects are not strictly one-per-line.
 	if res, err := parseWireItemsByBraces(out); err == nil {
-		return res, nil
+		diag.Mode = ParseModeObjects
+		return res, diag, nil
 	}
 
 	// Strict/diagnostic mode: try line-by-line NDJSON to return a more precise error
 	// message when the output looks like NDJSON but one line is broken.
-	if res, err := parseWireItemsByLines(out); err == nil {
-		return res, nil
+	res, strictErr := parseWireItemsByLines(out)
+	if strictErr == nil {
+		diag.Mode = ParseModeLines
+		return res, diag, nil
+	}
+	if strictness == ParseTolerant {
+		return nil, diag, strictErr
 	}
 
 	// Safer salvage: try to repair only the broken NDJSON lines. This minimizes the
 	// risk of heuristics over unrelated content.
 	if res, salvaged, err := parseWireItemsByLinesWithRepair(out); err == nil {
 		logSalvagedNDJSONLines(salvaged)
-		return res, nil
+		diag.Mode = ParseModeLinesRepaired
+		diag.Salvaged = salvaged
+		return res, diag, nil
 	}
 
 	// Last-resort mode: attempt to salvage items when the LLM returned almost-JSON
 	// but broke string escaping (most commonly: unescaped double quotes inside text).
-	if res, err := parseWireItemsByRepairingText(out); err == nil {
-		slog.Debug("salvaged invalid json output by repairing extracted json objects")
-		return res, nil
+	if res, repaired, err := parseWireItemsByRepairingText(out); err == nil {
+		slog.Debug("salvaged invalid json output by repairing extracted json objects", "repaired", repaired)
+		diag.Mode = ParseModeObjectsRepaired
+		diag.Repaired = repaired
+		return res, diag, nil
 	}
 
 	// If we got here, return the strict error (it tends to be most actionable).
-	_, err := parseWireItemsByLines(out)
-	return nil, err
+	return nil, diag, strictErr
 }
 
 func parseWireItemsJSONArray(trim string) ([]ParsedLine, error) {
@@ -288,40 +408,46 @@ func stripCodeFences(s string) string {
 	return s
 }
 
-func parseWireItemsByRepairingText(s string) ([]ParsedLine, error) {
+// parseWireItemsByRepairingText also returns the number of objects that
+// weren't valid JSON.
+func parseWireItemsByRepairingText(s string) ([]ParsedLine, int, error) {
 	segs := extractJSONObjectSegmentsWithOffsets(s)
 	if len(segs) == 0 {
-		return nil, errNoTranslatedLinesParsed
+		return nil, 0, errNoTranslatedLinesParsed
 	}
 
 	res := make([]ParsedLine, 0, len(segs))
+	repaired := 0
 	for i, seg := range segs {
+		if !json.Valid([]byte(seg.JSON)) {
+			repaired++
+		}
 		idx, text, ok, err := extractIdxAndTextBestEffort(seg.JSON)
 		if err != nil {
-			return nil, fmt.Errorf("cannot salvage json object #%d at offset %d: %w (obj=%q)", i+1, seg.Start, err, abbreviate(seg.JSON, AbbreviationMax))
+			return nil, 0, fmt.Errorf("cannot salvage json object #%d at offset %d: %w (obj=%q)", i+1, seg.Start, err, abbreviate(seg.JSON, AbbreviationMax))
 		}
 		if !ok {
-			return nil, fmt.Errorf("cannot salvage json object #%d at offset %d (obj=%q)", i+1, seg.Start, abbreviate(seg.JSON, AbbreviationMax))
+			return nil, 0, fmt.Errorf("cannot salvage json object #%d at offset %d (obj=%q)", i+1, seg.Start, abbreviate(seg.JSON, AbbreviationMax))
 		}
 		if idx <= 0 {
-			return nil, fmt.Errorf("invalid idx in salvaged item in object #%d at offset %d: %d", i+1, seg.Start, idx)
+			return nil, 0, fmt.Errorf("invalid idx in salvaged item in object #%d at offset %d: %d", i+1, seg.Start, idx)
 		}
 
 		// Re-encode as valid JSON to let encoding/json validate the escaping.
 		fixed, mErr := json.Marshal(wireItem{Idx: idx, Text: text})
 		if mErr != nil {
-			return nil, fmt.Errorf("cannot marshal salvaged item in object #%d at offset %d: %w", i+1, seg.Start, mErr)
+			return nil, 0, fmt.Errorf("cannot marshal salvaged item in object #%d at offset %d: %w", i+1, seg.Start, mErr)
 		}
 		var it wireItem
 		if uErr := json.Unmarshal(fixed, &it); uErr != nil {
-			return nil, fmt.Errorf("cannot unmarshal salvaged item in object #%d at offset %d: %w (fixed=%q)", i+1, seg.Start, uErr, abbreviate(string(fixed), AbbreviationMax))
+			return nil, 0, fmt.Errorf("cannot unmarshal salvaged item in object #%d at offset %d: %w (fixed=%q)", i+1, seg.Start, uErr, abbreviate(string(fixed), AbbreviationMax))
 		}
 		res = append(res, ParsedLine{Idx: it.Idx, Text: it.Text})
 	}
 	if len(res) == 0 {
-		return nil, errNoTranslatedLinesParsed
+		return nil, 0, errNoTranslatedLinesParsed
 	}
-	return res, nil
+	return res, repaired, nil
 }
 
 // extractIdxAndTextBestEffort tries to recover idx and text from an object that
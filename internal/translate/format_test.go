@@ -126,3 +126,77 @@ func TestParseTranslatedLines_SalvagesUnescapedQuotesFollowedByComma(t *testing.
 		t.Fatalf("mismatch: %+v (want text %q)", parsed[0], want)
 	}
 }
+
+func TestParseTranslatedLines_StructuredEnvelope(t *testing.T) {
+	out := `{"items":[{"idx":1,"text":"Hola"},{"idx":2,"text":"L1\nL2"}]}`
+	parsed, err := ParseTranslatedLines(out)
+	if err != nil {
+		t.Fatalf("ParseTranslatedLines: %v", err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(parsed))
+	}
+	if parsed[1].Idx != 2 || parsed[1].Text != "L1\nL2" {
+		t.Fatalf("line1 mismatch: %+v", parsed[1])
+	}
+}
+
+func TestParseTranslatedLinesWithDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want ParseDiagnostics
+	}{
+		{"array", `[{"idx":1,"text":"Hola"}]`, ParseDiagnostics{Mode: ParseModeArray}},
+		{"envelope", `{"items":[{"idx":1,"text":"Hola"}]}`, ParseDiagnostics{Mode: ParseModeEnvelope}},
+		{"ndjson", "{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Chau\"}", ParseDiagnostics{Mode: ParseModeObjects}},
+		{"code fence", "```json\n{\"idx\":1,\"text\":\"Hola\"}\n```", ParseDiagnostics{Mode: ParseModeObjects, CodeFence: true}},
+		{
+			"broken line",
+			"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Ella dijo \"chau\"\"}\n{\"idx\":3,\"text\":\"Fin\"}",
+			ParseDiagnostics{Mode: ParseModeLinesRepaired, Salvaged: 1},
+		},
+		{
+			"broken pretty-printed object",
+			"{\n  \"idx\": 1,\n  \"text\": \"Dijo \"hola\"\"\n}\n{\n  \"idx\": 2,\n  \"text\": \"Chau\"\n}",
+			ParseDiagnostics{Mode: ParseModeObjectsRepaired, Repaired: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diag, err := ParseTranslatedLinesWithDiagnostics(tt.out, ParseSalvage)
+			if err != nil {
+				t.Fatalf("ParseTranslatedLinesWithDiagnostics: %v", err)
+			}
+			if diag != tt.want {
+				t.Fatalf("diagnostics = %+v, want %+v", diag, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTranslatedLinesWithDiagnostics_Strictness(t *testing.T) {
+	fenced := "```json\n{\"idx\":1,\"text\":\"Hola\"}\n```"
+	oneLine := "{\"idx\":1,\"text\":\"Hola\"} {\"idx\":2,\"text\":\"Chau\"}"
+	broken := "{\"idx\":1,\"text\":\"Ella dijo \"chau\"\"}"
+	tests := []struct {
+		strictness string
+		out        string
+		wantErr    bool
+	}{
+		{ParseStrict, "{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Chau\"}", false},
+		{ParseStrict, `{"items":[{"idx":1,"text":"Hola"}]}`, false},
+		{ParseStrict, fenced, true},
+		{ParseStrict, oneLine, true},
+		{ParseTolerant, fenced, false},
+		{ParseTolerant, oneLine, false},
+		{ParseTolerant, broken, true},
+		{ParseSalvage, broken, false},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseTranslatedLinesWithDiagnostics(tt.out, tt.strictness)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %q: err = %v, want error %v", tt.strictness, tt.out, err, tt.wantErr)
+		}
+	}
+}
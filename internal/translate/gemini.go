@@ -0,0 +1,455 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+)
+
+const (
+	geminiBaseURL    = "https://generativelanguage.googleapis.com"
+	geminiAPIVersion = "/v1beta"
+	// geminiAPIKeyHeader carries the API key, which keeps it out of the URLs
+	// logged on errors (the API also accepts it as the "key" query parameter).
+	geminiAPIKeyHeader = "x-goog-api-key"
+)
+
+// Safety thresholds of the native Gemini API, applied to every harm category.
+// Subtitles often carry song lyrics and violent or crude dialogue, which the
+// default thresholds of the API may block.
+const (
+	SafetyBlockNone   = "none"    // BLOCK_NONE: never block, still rate the content
+	SafetyOff         = "off"     // OFF: turn the filter off
+	SafetyBlockHigh   = "high"    // BLOCK_ONLY_HIGH
+	SafetyBlockMedium = "medium"  // BLOCK_MEDIUM_AND_ABOVE
+	SafetyBlockLow    = "low"     // BLOCK_LOW_AND_ABOVE
+	SafetyDefault     = "default" // the defaults of the API (no safetySettings sent)
+)
+
+const DefaultSafetyThreshold = SafetyBlockNone
+
+var geminiSafetyThresholds = map[string]string{
+	SafetyBlockNone:   "BLOCK_NONE",
+	SafetyOff:         "OFF",
+	SafetyBlockHigh:   "BLOCK_ONLY_HIGH",
+	SafetyBlockMedium: "BLOCK_MEDIUM_AND_ABOVE",
+	SafetyBlockLow:    "BLOCK_LOW_AND_ABOVE",
+}
+
+var geminiHarmCategories = []string{
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+}
+
+// geminiThinkingBudgets maps the reasoning effort to a thinking budget in
+// tokens, as the OpenAI-compatible endpoint of Gemini does.
+var geminiThinkingBudgets = map[string]int{
+	ReasoningEffortNone:    0,
+	ReasoningEffortMinimal: 512,
+	ReasoningEffortLow:     1024,
+	ReasoningEffortMedium:  8192,
+	ReasoningEffortHigh:    24576,
+}
+
+// geminiBlockFinishReasons are the finish reasons of a candidate withheld by
+// the content filters.
+var geminiBlockFinishReasons = map[string]bool{
+	"SAFETY": true, "RECITATION": true, "BLOCKLIST": true, "PROHIBITED_CONTENT": true, "SPII": true,
+}
+
+func normalizeSafetyThreshold(threshold string) string {
+	return strings.ToLower(strings.TrimSpace(threshold))
+}
+
+func isValidSafetyThreshold(threshold string) bool {
+	_, ok := geminiSafetyThresholds[threshold]
+	return ok || threshold == SafetyDefault
+}
+
+// isGeminiModel reports whether model is a Gemini model (gemini-*).
+func isGeminiModel(model string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(model)), "gemini-")
+}
+
+// chatProvider returns the provider for a chat model: the native Gemini API for
+// Gemini models without an explicit base URL, which supports more features
+// than its OpenAI-compatible layer, and an OpenAI-compatible API otherwise.
+func chatProvider(model, baseURL string) string {
+	if strings.TrimSpace(baseURL) == "" && isGeminiModel(model) {
+		return ProviderGemini
+	}
+	return ProviderOpenAI
+}
+
+// GeminiClient translates cues with the native generateContent endpoint of the
+// Gemini API (generativelanguage.googleapis.com), using the same prompt and
+// wire format as OpenAIClient.
+type GeminiClient struct {
+	HTTPClient   *http.Client
+	Transport    http.RoundTripper // used when HTTPClient is nil
+	BaseURL      string            // optional; defaults to the Gemini API
+	APIKey       string            // can be a single key or a comma-separated list of keys
+	Model        string
+	Timeout      time.Duration
+	RetryOptions RetryOptions
+	// ResponseMode selects how the output shape is enforced (auto, ndjson,
+	// json-schema). Empty means DefaultResponseMode.
+	ResponseMode string
+
+	// KeyRPS limits requests per second for each API key (0 disables it).
+	KeyRPS float64
+
+	Prompt   PromptOptions
+	Sampling SamplingOptions
+
+	// SafetyThreshold is applied to every harm category (DefaultSafetyThreshold
+	// when empty; SafetyDefault keeps the thresholds of the API).
+	SafetyThreshold string
+
+	keyPoolOnce sync.Once
+	keyPool     *apiKeyPool
+
+	// schemaUnsupported is set in auto mode once the model rejects the
+	// response schema, so later batches go straight to the NDJSON prompt.
+	schemaUnsupported atomic.Bool
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	SafetySettings    []geminiSafetySetting  `json:"safetySettings,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought,omitempty"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float64              `json:"temperature,omitempty"`
+	TopP             *float64              `json:"topP,omitempty"`
+	MaxOutputTokens  int                   `json:"maxOutputTokens,omitempty"`
+	ResponseMIMEType string                `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any        `json:"responseSchema,omitempty"`
+	ThinkingConfig   *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget int `json:"thinkingBudget"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+func (c *GeminiClient) apiKeyPool() *apiKeyPool {
+	c.keyPoolOnce.Do(func() {
+		c.keyPool = newAPIKeyPool(splitAPIKeys(c.APIKey), c.KeyRPS)
+	})
+	return c.keyPool
+}
+
+func (c *GeminiClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Transport: c.Transport, Timeout: c.Timeout}
+}
+
+func (c *GeminiClient) TranslateBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error) {
+	if targetLanguage == "" {
+		return "", errors.New("target language is required")
+	}
+	mode := normalizeResponseMode(c.ResponseMode)
+	if mode == "" {
+		mode = DefaultResponseMode
+	}
+	if !isValidResponseMode(mode) {
+		return "", fmt.Errorf("invalid response mode %q (supported: %s, %s, %s)", c.ResponseMode, ResponseModeAuto, ResponseModeNDJSON, ResponseModeJSONSchema)
+	}
+	structured := mode == ResponseModeJSONSchema || (mode == ResponseModeAuto && !c.schemaUnsupported.Load())
+
+	messages, err := buildPrompt(c.Prompt, sourceLanguage, targetLanguage, payload, structured)
+	if err != nil {
+		return "", fmt.Errorf("build prompt: %w", err)
+	}
+	content, err := c.generate(ctx, messages, structured)
+	if err != nil && structured && mode == ResponseModeAuto && isStructuredOutputRejection(err) {
+		if c.schemaUnsupported.CompareAndSwap(false, true) {
+			slog.Warn("model rejected the response schema; falling back to ndjson response mode", "model", c.Model, "err", err)
+		}
+		if messages, err = buildPrompt(c.Prompt, sourceLanguage, targetLanguage, payload, false); err != nil {
+			return "", fmt.Errorf("build prompt: %w", err)
+		}
+		return c.generate(ctx, messages, false)
+	}
+	return content, err
+}
+
+// ReviewBatch runs the QA pass over translated lines (see batchReviewer).
+func (c *GeminiClient) ReviewBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error) {
+	return c.generate(ctx, buildReviewPrompt(sourceLanguage, targetLanguage, payload), false)
+}
+
+// CondenseBatch shortens translated lines (see batchCondenser).
+func (c *GeminiClient) CondenseBatch(ctx context.Context, targetLanguage string, payload string) (string, error) {
+	return c.generate(ctx, buildCondensePrompt(targetLanguage, payload), false)
+}
+
+// generate sends messages to generateContent and returns the text of the
+// response. structured requests JSON output following the wire envelope.
+func (c *GeminiClient) generate(ctx context.Context, messages []ChatMessage, structured bool) (string, error) {
+	if c.Model == "" {
+		return "", errors.New("model is required")
+	}
+	keys := c.apiKeyPool()
+	if keys.size() == 0 {
+		return "", errors.New("api key is required for gemini")
+	}
+	u, err := c.endpointURL(":generateContent")
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(c.buildRequest(messages, structured))
+	if err != nil {
+		return "", err
+	}
+	return c.post(ctx, u.String(), keys, body)
+}
+
+func (c *GeminiClient) buildRequest(messages []ChatMessage, structured bool) geminiRequest {
+	var req geminiRequest
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, geminiPart{Text: m.Content})
+		case "assistant":
+			req.Contents = append(req.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: m.Content}}})
+		default:
+			req.Contents = append(req.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: m.Content}}})
+		}
+	}
+
+	threshold := normalizeSafetyThreshold(c.SafetyThreshold)
+	if threshold == "" {
+		threshold = DefaultSafetyThreshold
+	}
+	if value, ok := geminiSafetyThresholds[threshold]; ok {
+		for _, category := range geminiHarmCategories {
+			req.SafetySettings = append(req.SafetySettings, geminiSafetySetting{Category: category, Threshold: value})
+		}
+	}
+
+	sampling := resolveSampling(c.Model, c.Sampling)
+	req.GenerationConfig = geminiGenerationConfig{
+		Temperature:     sampling.Temperature,
+		TopP:            sampling.TopP,
+		MaxOutputTokens: c.Sampling.MaxOutputTokens,
+	}
+	if budget, ok := geminiThinkingBudgets[sampling.ReasoningEffort]; ok {
+		req.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget}
+	}
+	if structured {
+		req.GenerationConfig.ResponseMIMEType = "application/json"
+		req.GenerationConfig.ResponseSchema = geminiResponseSchema()
+	}
+	return req
+}
+
+// geminiResponseSchema is the structured output definition of
+// subtitleResponseFormat in the OpenAPI subset accepted by responseSchema.
+func geminiResponseSchema() map[string]any {
+	return map[string]any{
+		"type": "OBJECT",
+		"properties": map[string]any{
+			wireEnvelopeItemsKey: map[string]any{
+				"type": "ARRAY",
+				"items": map[string]any{
+					"type": "OBJECT",
+					"properties": map[string]any{
+						"idx":  map[string]any{"type": "INTEGER"},
+						"text": map[string]any{"type": "STRING"},
+					},
+					"required":         []string{"idx", "text"},
+					"propertyOrdering": []string{"idx", "text"},
+				},
+			},
+		},
+		"required": []string{wireEnvelopeItemsKey},
+	}
+}
+
+func (c *GeminiClient) post(ctx context.Context, u string, keys *apiKeyPool, body []byte) (string, error) {
+	hc := c.httpClient()
+	return requestWithRetry[string](ctx, c.RetryOptions, func(attempt int) (string, retryDecision) {
+		apiKey, keyIdx, err := keys.acquire(ctx)
+		if err != nil {
+			return "", retryDecision{err: err}
+		}
+
+		r, err := doJSONRequestWithHeader(ctx, hc, http.MethodPost, u, http.Header{geminiAPIKeyHeader: {apiKey}}, body)
+		if err != nil {
+			if isRetryableNetErr(err) {
+				return "", retryDecision{err: err, retry: true}
+			}
+			return "", retryDecision{err: err}
+		}
+
+		if r.statusCode < 200 || r.statusCode >= 300 {
+			hErr := &httpStatusError{StatusCode: r.statusCode, Body: strings.TrimSpace(string(r.bodyBytes))}
+			retryAfter := retryDelayFromHeader(r.header)
+			if isRejectedHTTPStatus(r.statusCode) && keys.size() > 1 {
+				cooldown := keyCooldown(r.statusCode, retryAfter)
+				slog.Warn("gemini api rejected request; rotating api key",
+					"attempt", attempt,
+					"status_code", r.statusCode,
+					"status_text", http.StatusText(r.statusCode),
+					"rejected_key", run.MaskKey(apiKey),
+					"cooldown", cooldown,
+					"keys", keys.size(),
+				)
+				keys.bench(keyIdx, cooldown)
+				return "", retryDecision{err: hErr, retry: true, delay: time.Millisecond}
+			}
+			if isRetryableHTTPStatus(r.statusCode) {
+				return "", retryDecision{err: hErr, retry: true, delay: retryAfter}
+			}
+			return "", retryDecision{err: hErr}
+		}
+
+		content, usage, err := parseGeminiContent(r.bodyBytes)
+		reportTokenUsage(ctx, usage)
+		var tErr *truncatedResponseError
+		var bErr *blockedResponseError
+		if errors.As(err, &tErr) || errors.As(err, &bErr) {
+			// Not retried: the batch is split instead, or would be blocked again.
+			return "", retryDecision{err: err}
+		}
+		if err != nil {
+			return "", retryDecision{err: err, retry: true}
+		}
+		return content, retryDecision{}
+	})
+}
+
+// parseGeminiContent returns the text of the first candidate, without the
+// thoughts, and the token usage (zero when absent). A
+// response cut at the output token limit returns a *truncatedResponseError and
+// a blocked one a *blockedResponseError.
+func parseGeminiContent(bodyBytes []byte) (string, tokenUsage, error) {
+	var out geminiResponse
+	if err := json.Unmarshal(bodyBytes, &out); err != nil {
+		return "", tokenUsage{}, err
+	}
+	var usage tokenUsage
+	if out.UsageMetadata != nil {
+		usage = tokenUsage{total: out.UsageMetadata.TotalTokenCount, cached: out.UsageMetadata.CachedContentTokenCount}
+	}
+	if out.PromptFeedback != nil && out.PromptFeedback.BlockReason != "" {
+		return "", usage, &blockedResponseError{reason: out.PromptFeedback.BlockReason}
+	}
+	if len(out.Candidates) == 0 {
+		return "", usage, errors.New("no candidates in response")
+	}
+	candidate := out.Candidates[0]
+	var content strings.Builder
+	for _, p := range candidate.Content.Parts {
+		if !p.Thought {
+			content.WriteString(p.Text)
+		}
+	}
+	text := strings.TrimSpace(content.String())
+	switch {
+	case candidate.FinishReason == "MAX_TOKENS":
+		return "", usage, &truncatedResponseError{partial: text}
+	case geminiBlockFinishReasons[candidate.FinishReason]:
+		return "", usage, &blockedResponseError{reason: candidate.FinishReason}
+	case text == "":
+		return "", usage, errors.New("empty content in response")
+	}
+	return text, usage, nil
+}
+
+// ValidateModel returns an error when the configured model is not available.
+func (c *GeminiClient) ValidateModel(ctx context.Context) error {
+	apiKey, _, err := c.apiKeyPool().acquire(ctx)
+	if err != nil {
+		return err
+	}
+	u, err := c.endpointURL("")
+	if err != nil {
+		return err
+	}
+	r, err := doJSONRequestWithHeader(ctx, c.httpClient(), http.MethodGet, u.String(), http.Header{geminiAPIKeyHeader: {apiKey}}, nil)
+	if err != nil {
+		return fmt.Errorf("get model: %w", err)
+	}
+	if r.statusCode == http.StatusNotFound {
+		return fmt.Errorf("model %q not found on server", geminiModelName(c.Model))
+	}
+	if r.statusCode < 200 || r.statusCode >= 300 {
+		return fmt.Errorf("get model: %w", &httpStatusError{StatusCode: r.statusCode, Body: strings.TrimSpace(string(r.bodyBytes))})
+	}
+	return nil
+}
+
+// endpointURL returns the URL of the model followed by method (e.g.
+// ":generateContent"), under the API version of the base URL, or /v1beta.
+func (c *GeminiClient) endpointURL(method string) (*url.URL, error) {
+	return c.apiURL("/models/" + geminiModelName(c.Model) + method)
+}
+
+// apiURL returns the URL of a resource path (e.g. "/batches/123") under the
+// API version of the base URL, or /v1beta.
+func (c *GeminiClient) apiURL(resource string) (*url.URL, error) {
+	base := strings.TrimRight(strings.TrimSpace(c.BaseURL), "/")
+	if base == "" {
+		base = geminiBaseURL
+	}
+	if !strings.HasSuffix(base, "/v1beta") && !strings.HasSuffix(base, "/v1") {
+		base += geminiAPIVersion
+	}
+	return buildURL(base, resource)
+}
+
+// geminiModelName returns the model id without the "models/" prefix of the
+// resource name.
+func geminiModelName(model string) string {
+	return strings.TrimPrefix(strings.TrimSpace(model), "models/")
+}
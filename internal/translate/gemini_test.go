@@ -0,0 +1,101 @@
+package translate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGeminiClient_TranslateBatch(t *testing.T) {
+	var got geminiRequest
+	var path, key string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, key = r.URL.Path, r.Header.Get(geminiAPIKeyHeader)
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[` +
+			`{"text":"thinking about it","thought":true},{"text":"{\"items\":[{\"idx\":1,\"text\":\"Hola\"}]}"}]},` +
+			`"finishReason":"STOP"}],"usageMetadata":{"totalTokenCount":42}}`))
+	}))
+	defer server.Close()
+
+	c := GeminiClient{
+		BaseURL: server.URL, APIKey: "k", Model: "gemini-2.5-flash",
+		RetryOptions: RetryOptions{MaxAttempts: 1},
+		Sampling:     SamplingOptions{ReasoningEffort: ReasoningEffortNone},
+	}
+	payload, err := FormatForTranslation([]int{1}, []string{"Hello"})
+	if err != nil {
+		t.Fatalf("FormatForTranslation: %v", err)
+	}
+	out, err := (&c).TranslateBatch(t.Context(), "en", "es", payload)
+	if err != nil {
+		t.Fatalf("TranslateBatch: %v", err)
+	}
+
+	if path != "/v1beta/models/gemini-2.5-flash:generateContent" || key != "k" {
+		t.Fatalf("unexpected request to %q with key %q", path, key)
+	}
+	if got.SystemInstruction == nil || len(got.Contents) != 1 || got.Contents[0].Role != "user" {
+		t.Fatalf("unexpected contents: %+v", got)
+	}
+	if len(got.SafetySettings) != len(geminiHarmCategories) || got.SafetySettings[0].Threshold != "BLOCK_NONE" {
+		t.Fatalf("unexpected safety settings: %+v", got.SafetySettings)
+	}
+	cfg := got.GenerationConfig
+	if cfg.ResponseMIMEType != "application/json" || cfg.ResponseSchema == nil {
+		t.Fatalf("expected a response schema, got %+v", cfg)
+	}
+	if cfg.ThinkingConfig == nil || cfg.ThinkingConfig.ThinkingBudget != 0 {
+		t.Fatalf("expected thinking to be disabled, got %+v", cfg.ThinkingConfig)
+	}
+	parsed, err := ParseTranslatedLines(out)
+	if err != nil || len(parsed) != 1 || parsed[0].Text != "Hola" {
+		t.Fatalf("unexpected output %q (parsed %+v, err %v)", out, parsed, err)
+	}
+}
+
+func TestGeminiClient_BlockedResponseIsNotRetried(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"finishReason":"SAFETY"}]}`))
+	}))
+	defer server.Close()
+
+	c := GeminiClient{BaseURL: server.URL + "/v1beta", APIKey: "k", Model: "gemini-2.5-flash", RetryOptions: RetryOptions{MaxAttempts: 3}}
+	payload, _ := FormatForTranslation([]int{1}, []string{"Hello"})
+	_, err := (&c).TranslateBatch(t.Context(), "en", "es", payload)
+	var bErr *blockedResponseError
+	if !errors.As(err, &bErr) || bErr.reason != "SAFETY" {
+		t.Fatalf("expected a blocked response error, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected a single request, got %d", requests)
+	}
+}
+
+func TestDefaultOptions_GeminiProvider(t *testing.T) {
+	tests := []struct {
+		model, url, want string
+	}{
+		{"gemini-2.5-flash", "", ProviderGemini},
+		{"gemini-2.5-flash", "https://generativelanguage.googleapis.com/v1beta/openai", ProviderOpenAI},
+		{"gpt-5", "", ProviderOpenAI},
+	}
+	for _, tt := range tests {
+		opts, err := defaultOptions(Options{TargetLanguage: "es", Model: tt.model, BaseURL: tt.url})
+		if err != nil {
+			t.Fatalf("defaultOptions(%s): %v", tt.model, err)
+		}
+		if opts.Provider != tt.want || opts.SafetyThreshold != DefaultSafetyThreshold {
+			t.Fatalf("defaultOptions(%s, %q): provider %q, safety %q", tt.model, tt.url, opts.Provider, opts.SafetyThreshold)
+		}
+	}
+	if _, err := defaultOptions(Options{TargetLanguage: "es", Model: "gemini-2.5-flash", SafetyThreshold: "lax"}); err == nil {
+		t.Fatalf("expected an error for an invalid safety threshold")
+	}
+}
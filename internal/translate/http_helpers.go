@@ -18,6 +18,9 @@ type httpResult struct {
 	statusCode int
 	header     http.Header
 	bodyBytes  []byte
+	// usage is the usage reported by a streamed response (the usage of a
+	// regular response is parsed from bodyBytes).
+	usage tokenUsage
 }
 
 func doJSONPost(
@@ -27,13 +30,53 @@ func doJSONPost(
 	authBearer string,
 	body []byte,
 ) (httpResult, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	authorization := ""
+	if authBearer != "" {
+		authorization = "Bearer " + authBearer
+	}
+	return doJSONRequest(ctx, hc, http.MethodPost, u, authorization, body)
+}
+
+// doJSONRequest sends a JSON request with an optional raw Authorization header
+// value (e.g. "Bearer <key>" or "DeepL-Auth-Key <key>"). body may be nil.
+func doJSONRequest(
+	ctx context.Context,
+	hc *http.Client,
+	method string,
+	u string,
+	authorization string,
+	body []byte,
+) (httpResult, error) {
+	header := http.Header{}
+	if authorization != "" {
+		header.Set("Authorization", authorization)
+	}
+	return doJSONRequestWithHeader(ctx, hc, method, u, header, body)
+}
+
+// doJSONRequestWithHeader sends a JSON request with extra headers (e.g. the
+// API key header of the Gemini API). body may be nil.
+func doJSONRequestWithHeader(
+	ctx context.Context,
+	hc *http.Client,
+	method string,
+	u string,
+	header http.Header,
+	body []byte,
+) (httpResult, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
 	if err != nil {
 		return httpResult{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if authBearer != "" {
-		req.Header.Set("Authorization", "Bearer "+authBearer)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
 	}
 
 	resp, err := hc.Do(req)
@@ -49,31 +92,53 @@ func doJSONPost(
 	return httpResult{statusCode: resp.StatusCode, header: resp.Header.Clone(), bodyBytes: bodyBytes}, nil
 }
 
+// retryDelayFromHeader returns the delay asked by the server in the
+// retry-after-ms header (sent by OpenAI and Azure OpenAI) or in Retry-After,
+// as seconds or an HTTP date; 0 when absent, invalid or in the past.
 func retryDelayFromHeader(h http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(h.Get("Retry-After-Ms")), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
 	ra := strings.TrimSpace(h.Get("Retry-After"))
 	if ra == "" {
 		return 0
 	}
-	secs, err := strconv.Atoi(ra)
-	if err != nil || secs < 0 {
-		return 0
+	if secs, err := strconv.Atoi(ra); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		return max(time.Until(t), 0)
 	}
-	return time.Duration(secs) * time.Second
+	return 0
 }
 
-func parseChatCompletionContent(bodyBytes []byte) (string, error) {
+// parseChatCompletionContent returns the message content and the token usage
+// (zero when absent). A response cut at the output token
+// limit returns a *truncatedResponseError, with the usage, and one withheld by
+// the content filters a *blockedResponseError.
+func parseChatCompletionContent(bodyBytes []byte) (string, tokenUsage, error) {
 	var out chatCompletionsResponse
 	if err := json.Unmarshal(bodyBytes, &out); err != nil {
-		return "", err
+		return "", tokenUsage{}, err
 	}
 	if len(out.Choices) == 0 {
-		return "", errors.New("no choices in response")
+		return "", tokenUsage{}, errors.New("no choices in response")
 	}
+	usage := out.Usage.usage()
 	content := strings.TrimSpace(out.Choices[0].Message.Content)
+	if isTruncatedFinishReason(out.Choices[0].FinishReason) {
+		return "", usage, &truncatedResponseError{partial: content}
+	}
+	if out.Choices[0].FinishReason == finishReasonContentFilter {
+		return "", usage, &blockedResponseError{reason: finishReasonContentFilter}
+	}
+	if content == "" && out.Choices[0].Message.Refusal != "" {
+		return "", usage, &blockedResponseError{reason: "refusal: " + out.Choices[0].Message.Refusal}
+	}
 	if content == "" {
-		return "", errors.New("empty content in response")
+		return "", tokenUsage{}, errors.New("empty content in response")
 	}
-	return content, nil
+	return content, usage, nil
 }
 
 func buildURL(baseUrl, urlPath string) (*url.URL, error) {
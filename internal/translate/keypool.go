@@ -0,0 +1,133 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultKeyCooldown is how long a key is benched after a 429 without a usable
+// Retry-After header.
+const DefaultKeyCooldown = 30 * time.Second
+
+// DefaultKeyRejectCooldown is how long a key is benched after a 401/403. These
+// usually mean the key is invalid or lacks access, so the key stays out of the
+// rotation for longer.
+const DefaultKeyRejectCooldown = 5 * time.Minute
+
+// apiKeyPool distributes requests across multiple API keys.
+//
+// Keys are picked round-robin. Each key can have its own rate limiter and can be
+// benched for a cooldown period after being rejected; benched keys are skipped
+// and automatically reinstated once the cooldown expires. When every key is
+// benched, acquire waits for the earliest reinstatement.
+type apiKeyPool struct {
+	keys     []string
+	limiters []*rate.Limiter // nil entries when per-key rate limiting is disabled
+	now      func() time.Time
+
+	mu           sync.Mutex
+	next         int
+	benchedUntil []time.Time
+}
+
+func newAPIKeyPool(keys []string, perKeyRPS float64) *apiKeyPool {
+	p := &apiKeyPool{
+		keys:         keys,
+		limiters:     make([]*rate.Limiter, len(keys)),
+		benchedUntil: make([]time.Time, len(keys)),
+		now:          time.Now,
+	}
+	for i := range keys {
+		p.limiters[i] = newLimiter(perKeyRPS)
+	}
+	return p
+}
+
+func (p *apiKeyPool) size() int {
+	return len(p.keys)
+}
+
+// acquire returns the next healthy key (and its index), waiting for its rate
+// limiter. It returns an empty key when the pool has no keys.
+func (p *apiKeyPool) acquire(ctx context.Context) (string, int, error) {
+	if len(p.keys) == 0 {
+		return "", -1, nil
+	}
+	for {
+		idx, wait := p.pick()
+		if wait > 0 {
+			if err := sleepWithContext(ctx, wait); err != nil {
+				return "", -1, err
+			}
+			continue
+		}
+		if l := p.limiters[idx]; l != nil {
+			if err := l.Wait(ctx); err != nil {
+				return "", -1, err
+			}
+		}
+		return p.keys[idx], idx, nil
+	}
+}
+
+// pick selects the next non-benched key in round-robin order. If all keys are
+// benched, it returns how long to wait until the first one is reinstated.
+func (p *apiKeyPool) pick() (int, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	earliest := -1
+	for i := 0; i < len(p.keys); i++ {
+		idx := (p.next + i) % len(p.keys)
+		if !now.Before(p.benchedUntil[idx]) {
+			p.next = (idx + 1) % len(p.keys)
+			return idx, 0
+		}
+		if earliest < 0 || p.benchedUntil[idx].Before(p.benchedUntil[earliest]) {
+			earliest = idx
+		}
+	}
+	return earliest, p.benchedUntil[earliest].Sub(now)
+}
+
+// bench takes the key at idx out of the rotation for d. It is a no-op for
+// single-key pools, where regular retry backoff applies instead.
+func (p *apiKeyPool) bench(idx int, d time.Duration) {
+	if len(p.keys) < 2 {
+		return
+	}
+	p.pause(idx, d)
+}
+
+// pause holds the key at idx for d, also in single-key pools: the provider
+// reported that its rate limit is exhausted until then, so acquire waits
+// instead of sending a request bound to be rejected.
+func (p *apiKeyPool) pause(idx int, d time.Duration) {
+	if idx < 0 || idx >= len(p.keys) || d <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until := p.now().Add(d)
+	if until.After(p.benchedUntil[idx]) {
+		p.benchedUntil[idx] = until
+	}
+}
+
+// keyCooldown returns how long a key should be benched after a rejected request.
+func keyCooldown(statusCode int, retryAfter time.Duration) time.Duration {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return DefaultKeyRejectCooldown
+	default:
+		if retryAfter > 0 {
+			return retryAfter
+		}
+		return DefaultKeyCooldown
+	}
+}
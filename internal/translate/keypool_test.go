@@ -0,0 +1,89 @@
+package translate
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyPool_SkipsBenchedKeysUntilCooldownExpires(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	p := newAPIKeyPool([]string{"k1", "k2", "k3"}, 0)
+	p.now = func() time.Time { return now }
+
+	key, idx, err := p.acquire(t.Context())
+	if err != nil || key != "k1" {
+		t.Fatalf("acquire: got %q, %v", key, err)
+	}
+	p.bench(idx, 10*time.Second)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		key, _, err := p.acquire(t.Context())
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		got = append(got, key)
+	}
+	for _, k := range got {
+		if k == "k1" {
+			t.Fatalf("benched key was used before cooldown expired: %v", got)
+		}
+	}
+
+	now = now.Add(11 * time.Second)
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		key, _, _ := p.acquire(t.Context())
+		seen[key] = true
+	}
+	if !seen["k1"] {
+		t.Fatalf("expected k1 to be reinstated after cooldown, got %v", seen)
+	}
+}
+
+func TestAPIKeyPool_AllBenched_WaitsForEarliest(t *testing.T) {
+	p := newAPIKeyPool([]string{"k1", "k2"}, 0)
+	p.bench(0, time.Hour)
+	p.bench(1, 20*time.Millisecond)
+
+	start := time.Now()
+	key, _, err := p.acquire(t.Context())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if key != "k2" {
+		t.Fatalf("expected k2 (earliest reinstated), got %q", key)
+	}
+	if time.Since(start) < 15*time.Millisecond {
+		t.Fatalf("expected acquire to wait for the cooldown")
+	}
+}
+
+func TestAPIKeyPool_SingleKeyIsNeverBenched(t *testing.T) {
+	p := newAPIKeyPool([]string{"k1"}, 0)
+	p.bench(0, time.Hour)
+	if _, wait := p.pick(); wait != 0 {
+		t.Fatalf("expected single key to stay available, wait=%v", wait)
+	}
+}
+
+func TestAPIKeyPool_PauseHoldsSingleKey(t *testing.T) {
+	p := newAPIKeyPool([]string{"k1"}, 0)
+	p.pause(0, time.Minute)
+	if _, wait := p.pick(); wait <= 0 {
+		t.Fatalf("expected the paused key to wait, wait=%v", wait)
+	}
+}
+
+func TestKeyCooldown(t *testing.T) {
+	if got := keyCooldown(http.StatusTooManyRequests, 3*time.Second); got != 3*time.Second {
+		t.Fatalf("429 with Retry-After: got %v", got)
+	}
+	if got := keyCooldown(http.StatusTooManyRequests, 0); got != DefaultKeyCooldown {
+		t.Fatalf("429 without Retry-After: got %v", got)
+	}
+	if got := keyCooldown(http.StatusUnauthorized, 0); got != DefaultKeyRejectCooldown {
+		t.Fatalf("401: got %v", got)
+	}
+}
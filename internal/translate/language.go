@@ -86,3 +86,32 @@ func normalizeTargetLanguageLabel(input string) (label string) {
 	}
 	return label
 }
+
+// regionVocabularyHints are keyed by lowercase normalized tag and take precedence
+// over labelVocabularyHints.
+var regionVocabularyHints = map[string]string{
+	"es-ar": "Rioplatense Spanish: use voseo (\"vos tenés\", \"vos sabés\") instead of \"tú\", \"ustedes\" for the plural and Argentine vocabulary (e.g. \"auto\", \"celular\", \"departamento\").",
+	"es-uy": "Rioplatense Spanish: use voseo (\"vos tenés\", \"vos sabés\") instead of \"tú\", \"ustedes\" for the plural and Uruguayan vocabulary.",
+	"es-mx": "Mexican Spanish: use \"tú\", \"ustedes\" for the plural and Mexican vocabulary (e.g. \"carro\", \"celular\", \"computadora\").",
+	"pt-br": "Brazilian Portuguese: use \"você\" and Brazilian vocabulary (e.g. \"ônibus\", \"celular\", \"trem\").",
+	"pt-pt": "European Portuguese: use \"tu\"/\"você\" as in Portugal and Portuguese vocabulary (e.g. \"autocarro\", \"telemóvel\", \"comboio\").",
+}
+
+// labelVocabularyHints are keyed by the language label from normalizeTargetLanguage.
+var labelVocabularyHints = map[string]string{
+	LanguageSpanishLatin:   "Neutral Latin American Spanish: use \"tú\", \"ustedes\" for the plural (never \"vosotros\") and avoid country-specific slang.",
+	LanguageSpanishSpain:   "Peninsular Spanish: use \"tú\", \"vosotros\" for the informal plural and Spain vocabulary (e.g. \"coche\", \"móvil\", \"ordenador\").",
+	LanguageSpanishNeutral: "Neutral Spanish: avoid region-specific slang and vocabulary.",
+	LanguageEnglishUS:      "American English spelling and vocabulary (e.g. \"color\", \"apartment\", \"truck\").",
+	LanguageEnglishUK:      "British English spelling and vocabulary (e.g. \"colour\", \"flat\", \"lorry\").",
+}
+
+// languageVocabularyHint returns a regional vocabulary hint for the target
+// language, or "" when there is none.
+func languageVocabularyHint(input string) string {
+	tag, label := normalizeTargetLanguage(input)
+	if hint, ok := regionVocabularyHints[strings.ToLower(tag)]; ok {
+		return hint
+	}
+	return labelVocabularyHints[label]
+}
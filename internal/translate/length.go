@@ -0,0 +1,366 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fix"
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"golang.org/x/time/rate"
+)
+
+// Length policies applied to translated cues that exceed MaxCPS, MaxLineLength
+// or MaxLines.
+const (
+	LengthPolicyWrap    = "wrap"    // re-wrap long lines; CPS violations are only flagged
+	LengthPolicyShorten = "shorten" // re-wrap and ask the model to condense cues over MaxCPS
+	LengthPolicyReport  = "report"  // leave the text untouched and flag violations
+)
+
+const DefaultLengthPolicy = LengthPolicyWrap
+
+// lengthReportSuffix is appended to the output path (without extension) when
+// the report policy is used without an explicit report path.
+const lengthReportSuffix = ".length.json"
+
+// condenseBatchSize is the number of cues sent per condensation request.
+const condenseBatchSize = 40
+
+// batchCondenser is implemented by providers that can shorten translated cues.
+type batchCondenser interface {
+	// CondenseBatch receives NDJSON lines {"idx","text","max_chars"} and returns
+	// NDJSON lines {"idx","text"} with the shortened texts.
+	CondenseBatch(ctx context.Context, targetLanguage string, payload string) (string, error)
+}
+
+// LengthViolation is a translated cue that is still over the limits.
+type LengthViolation struct {
+	Idx        int     `json:"idx"`
+	Text       string  `json:"text"`
+	CPS        float64 `json:"cps"`
+	MaxLineLen int     `json:"max_line_len"` // longest line, in characters
+	Lines      int     `json:"lines"`
+	Shortened  bool    `json:"shortened"`
+}
+
+// LengthReport is written as JSON when length violations are reported.
+type LengthReport struct {
+	TargetLanguage string            `json:"target_language"`
+	MaxCPS         float64           `json:"max_cps,omitempty"`
+	MaxLineLength  int               `json:"max_line_length,omitempty"`
+	MaxLines       int               `json:"max_lines,omitempty"`
+	Violations     []LengthViolation `json:"violations"`
+}
+
+// lineLimits are the line limits sent to the model with every cue (see
+// Options.LengthHints), so it wraps the translations itself; 0 omits a limit.
+type lineLimits struct {
+	maxLen   int
+	maxLines int
+}
+
+type lengthResult struct {
+	wrapped    int
+	shortened  int
+	violations []LengthViolation
+}
+
+const condenseSystemPrompt = "You are a subtitle editor. Output must follow the requested format exactly. Do not add commentary."
+
+const condensePromptRules = "" +
+	"Rules:\n" +
+	"- Each item has a max_chars limit (line breaks not counted). Rewrite text to fit it while keeping the meaning.\n" +
+	"- Drop filler words, repetitions and redundant details first; never change names or numbers.\n" +
+	"- Keep inline tags (e.g. <i>) and leading dialogue dashes.\n" +
+	"- Output NDJSON: one {\"idx\":N,\"text\":\"...\"} object per input item, same idx, same order.\n" +
+	"- Do not output markdown, code fences, headers, or explanations.\n"
+
+func normalizeLengthPolicy(policy string) string {
+	return strings.ToLower(strings.TrimSpace(policy))
+}
+
+func isValidLengthPolicy(policy string) bool {
+	return policy == LengthPolicyWrap || policy == LengthPolicyShorten || policy == LengthPolicyReport
+}
+
+func defaultLengthReportPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + lengthReportSuffix
+}
+
+func buildCondensePrompt(targetLanguage, input string) []ChatMessage {
+	user := "Shorten these `" + normalizeTargetLanguageLabel(targetLanguage) + "` subtitles so they can be read in time.\n\n" +
+		condensePromptRules +
+		"\nInput:\n\n" + input + "\n"
+	return []ChatMessage{
+		{Role: "system", Content: condenseSystemPrompt},
+		{Role: "user", Content: user},
+	}
+}
+
+// visibleLength counts the characters shown on screen: inline tags and line
+// breaks are not counted.
+func visibleLength(text string) int {
+	return srt.VisibleLength(text)
+}
+
+func lineCount(text string) int {
+	return strings.Count(text, "\n") + 1
+}
+
+func longestLine(text string) int {
+	longest := 0
+	for line := range strings.SplitSeq(text, "\n") {
+		longest = max(longest, srt.DisplayWidth(line))
+	}
+	return longest
+}
+
+// charsPerSecond returns the reading speed of a cue (0 for cues without duration).
+func charsPerSecond(sub *srt.Subtitle, text string) float64 {
+	d := (sub.ToTime - sub.FromTime).Seconds()
+	if d <= 0 {
+		return 0
+	}
+	return float64(visibleLength(text)) / d
+}
+
+// cpsCharBudget is the number of characters readable within the cue duration.
+func cpsCharBudget(sub *srt.Subtitle, maxCPS float64) int {
+	return int(math.Floor(maxCPS * (sub.ToTime - sub.FromTime).Seconds()))
+}
+
+// wrapMaxLines caps the lines of a wrapped cue when MaxLines is not set: the
+// usual limit of two lines per cue.
+const wrapMaxLines = 2
+
+// wrapLines breaks text with lines longer than maxLen columns (see
+// srt.DisplayWidth) again across the whole cue, into the fewest balanced lines
+// that fit and at most maxLines (0 means wrapMaxLines, or the lines text
+// already has when more), using the line break rules of language. Text that
+// doesn't fit is split evenly into maxLines lines longer than maxLen. Dialogue cues
+// are wrapped line by line so the dashes stay at the start of a line, and are
+// kept when that takes more than maxLines lines.
+func wrapLines(text string, maxLen, maxLines int, language string) string {
+	if maxLines <= 0 {
+		maxLines = max(wrapMaxLines, lineCount(text))
+	}
+	if !fix.IsReflowable(text) {
+		var out []string
+		for line := range strings.SplitSeq(text, "\n") {
+			if srt.DisplayWidth(line) <= maxLen {
+				out = append(out, line)
+				continue
+			}
+			out = append(out, fix.WrapLine(line, maxLen)...)
+		}
+		if len(out) > maxLines {
+			return text
+		}
+		return strings.Join(out, "\n")
+	}
+	for n := 1; n <= maxLines; n++ {
+		if wrapped := fix.BalanceLines(text, n, maxLen, language); longestLine(wrapped) <= maxLen {
+			return wrapped
+		}
+	}
+	return fix.BalanceLines(text, maxLines, math.MaxInt, language)
+}
+
+// lengthEnforcer applies the length policy to the translated cues.
+type lengthEnforcer struct {
+	client         batchCondenser // nil unless the policy is shorten
+	limiter        *rate.Limiter
+	targetLanguage string
+	policy         string
+	maxCPS         float64
+	maxLineLength  int
+	maxLines       int
+	parseRetry     RetryOptions
+	// parseStrictness is the ParseStrictness of the options.
+	parseStrictness string
+	transcript      *transcript // optional
+}
+
+func (e *lengthEnforcer) enabled() bool {
+	return e.maxCPS > 0 || e.maxLineLength > 0 || e.maxLines > 0
+}
+
+func (e *lengthEnforcer) overCPS(sub *srt.Subtitle, text string) bool {
+	return e.maxCPS > 0 && charsPerSecond(sub, text) > e.maxCPS
+}
+
+func (e *lengthEnforcer) overLineLength(text string) bool {
+	return e.maxLineLength > 0 && longestLine(text) > e.maxLineLength
+}
+
+func (e *lengthEnforcer) overLines(text string) bool {
+	return e.maxLines > 0 && lineCount(text) > e.maxLines
+}
+
+// enforce updates translated in place (unless the policy is report) and returns
+// the cues still over the limits.
+func (e *lengthEnforcer) enforce(ctx context.Context, subs []*srt.Subtitle, translated map[int]string) (lengthResult, error) {
+	var res lengthResult
+	if !e.enabled() {
+		return res, nil
+	}
+
+	shortened := make(map[int]bool)
+	if e.policy == LengthPolicyShorten && e.maxCPS > 0 {
+		var over []*srt.Subtitle
+		for _, sub := range subs {
+			if t, ok := translated[sub.Idx]; ok && e.overCPS(sub, t) {
+				over = append(over, sub)
+			}
+		}
+		for chunk := range slices.Chunk(over, condenseBatchSize) {
+			texts, err := e.condense(ctx, chunk, translated)
+			if err != nil {
+				return res, err
+			}
+			for idx, t := range texts {
+				translated[idx] = t
+				shortened[idx] = true
+			}
+		}
+		res.shortened = len(shortened)
+	}
+
+	for _, sub := range subs {
+		t, ok := translated[sub.Idx]
+		if !ok {
+			continue
+		}
+		if e.policy != LengthPolicyReport && (e.overLineLength(t) || e.overLines(t)) {
+			wrapped := t
+			if e.overLineLength(wrapped) {
+				wrapped = wrapLines(wrapped, e.maxLineLength, e.maxLines, e.targetLanguage)
+			}
+			if e.overLines(wrapped) {
+				wrapped = fix.ReflowLines(wrapped, e.maxLineLength, e.maxLines, e.targetLanguage)
+			}
+			if wrapped != t {
+				t = wrapped
+				translated[sub.Idx] = t
+				res.wrapped++
+			}
+		}
+		if e.overCPS(sub, t) || e.overLineLength(t) || e.overLines(t) {
+			res.violations = append(res.violations, LengthViolation{
+				Idx:        sub.Idx,
+				Text:       t,
+				CPS:        math.Round(charsPerSecond(sub, t)*10) / 10,
+				MaxLineLen: longestLine(t),
+				Lines:      lineCount(t),
+				Shortened:  shortened[sub.Idx],
+			})
+		}
+	}
+	return res, nil
+}
+
+// condense asks the model to shorten subs and returns the accepted texts: a
+// result is kept only if it is shorter and has the same inline tags.
+func (e *lengthEnforcer) condense(ctx context.Context, subs []*srt.Subtitle, translated map[int]string) (map[int]string, error) {
+	type condenseItem struct {
+		Idx      int    `json:"idx"`
+		Text     string `json:"text"`
+		MaxChars int    `json:"max_chars"`
+	}
+	var payload bytes.Buffer
+	enc := json.NewEncoder(&payload)
+	enc.SetEscapeHTML(false)
+	for _, sub := range subs {
+		if err := enc.Encode(condenseItem{Idx: sub.Idx, Text: translated[sub.Idx], MaxChars: cpsCharBudget(sub, e.maxCPS)}); err != nil {
+			return nil, err
+		}
+	}
+
+	request := strings.TrimSuffix(payload.String(), "\n")
+	attempts := max(1, e.parseRetry.MaxAttempts)
+	var lines []ParsedLine
+	for attempt := 1; ; attempt++ {
+		if e.limiter != nil {
+			if err := e.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		entry := TranscriptEntry{
+			Time:           time.Now(),
+			Kind:           TranscriptKindCondense,
+			TargetLanguage: e.targetLanguage,
+			FirstIdx:       subs[0].Idx,
+			LastIdx:        subs[len(subs)-1].Idx,
+			Cues:           len(subs),
+			Attempt:        attempt,
+		}
+		out, err := e.client.CondenseBatch(ctx, e.targetLanguage, request)
+		entry.DurationMS = time.Since(entry.Time).Milliseconds()
+		if err != nil {
+			e.transcript.record(entry, request, "", err)
+			if isBlocked(err) {
+				slog.Warn("content filters of the provider blocked the shortening of the cues; leaving them as they are", "first_idx", subs[0].Idx, "last_idx", subs[len(subs)-1].Idx, "err", err)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("shorten cues: %w", err)
+		}
+		lines, _, err = ParseTranslatedLinesWithDiagnostics(out, e.parseStrictness)
+		e.transcript.record(entry, request, out, err)
+		if err == nil {
+			break
+		}
+		if attempt >= attempts {
+			return nil, fmt.Errorf("shorten cues: %w", err)
+		}
+		slog.Warn("invalid output while shortening cues; retrying", "attempt", attempt, "max_attempts", attempts, "err", err)
+		if err := sleepWithContext(ctx, computeBackoff(attempt, e.parseRetry)); err != nil {
+			return nil, err
+		}
+	}
+
+	accepted := make(map[int]string, len(lines))
+	for _, pl := range lines {
+		prev, ok := translated[pl.Idx]
+		if !ok || strings.TrimSpace(pl.Text) == "" || visibleLength(pl.Text) >= visibleLength(prev) {
+			continue
+		}
+		if !slices.Equal(sortedInlineTags(prev), sortedInlineTags(pl.Text)) {
+			slog.Debug("discarding shortened cue with changed tags", "idx", pl.Idx)
+			continue
+		}
+		accepted[pl.Idx] = pl.Text
+	}
+	return accepted, nil
+}
+
+func logLengthViolations(targetLanguage string, violations []LengthViolation) {
+	if len(violations) == 0 {
+		return
+	}
+	idxs := make([]string, 0, len(violations))
+	for _, v := range violations {
+		idxs = append(idxs, fmt.Sprint(v.Idx))
+	}
+	slog.Warn("translated cues exceed length limits", "target_language", targetLanguage, "cues", len(violations), "idxs", abbreviate(strings.Join(idxs, ","), AbbreviationMax))
+}
+
+func writeLengthReport(path string, report LengthReport) error {
+	if report.Violations == nil {
+		report.Violations = []LengthViolation{}
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	return fs.WriteFile(bytes.NewReader(b), path)
+}
@@ -0,0 +1,115 @@
+package translate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestVisibleLengthAndCPS(t *testing.T) {
+	text := "<i>Hola</i>\n¿Qué tal?"
+	if got := visibleLength(text); got != 13 {
+		t.Fatalf("visibleLength=%d, want 13", got)
+	}
+	sub := &srt.Subtitle{FromTime: time.Second, ToTime: 2 * time.Second}
+	if got := charsPerSecond(sub, text); got != 13 {
+		t.Fatalf("charsPerSecond=%v, want 13", got)
+	}
+	if got := charsPerSecond(&srt.Subtitle{}, text); got != 0 {
+		t.Fatalf("charsPerSecond without duration=%v, want 0", got)
+	}
+}
+
+func TestWrapLines(t *testing.T) {
+	long := "Esta es una línea de subtítulo demasiado larga para una sola"
+	dialogue := "- <i>Esta es una línea demasiado larga</i>\n- Corta"
+	tests := []struct {
+		name     string
+		text     string
+		maxLines int
+		want     string
+	}{
+		{"fewest lines that fit", long, 4, "Esta es una línea de\nsubtítulo demasiado\nlarga para una sola"},
+		{"capped at two lines by default", long, 0, "Esta es una línea de subtítulo\ndemasiado larga para una sola"},
+		{"rebalanced across the cue", "Esta es una línea de subtítulo\nlarga", 0, "Esta es una línea\nde subtítulo larga"},
+		{"dialogue wrapped line by line", dialogue, 3, "- <i>Esta es una línea\ndemasiado larga</i>\n- Corta"},
+		{"dialogue over the max lines kept", dialogue, 0, dialogue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapLines(tt.text, 20, tt.maxLines, "es"); got != tt.want {
+				t.Fatalf("wrapLines=%q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeCondenser struct{ out string }
+
+func (f fakeCondenser) CondenseBatch(context.Context, string, string) (string, error) {
+	return f.out, nil
+}
+
+func TestLengthEnforcer(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, FromTime: 0, ToTime: time.Second, Text: "Hello there, my friend"},
+		{Idx: 2, FromTime: 0, ToTime: 10 * time.Second, Text: "Bye"},
+	}
+	newTexts := func() map[int]string {
+		return map[int]string{1: "Hola, qué tal, amigo mío", 2: "Adiós a todos los que están aquí"}
+	}
+
+	t.Run(LengthPolicyShorten, func(t *testing.T) {
+		e := lengthEnforcer{
+			client:        fakeCondenser{out: `{"idx":1,"text":"Hola, amigo"}`},
+			policy:        LengthPolicyShorten,
+			maxCPS:        15,
+			maxLineLength: 20,
+			parseRetry:    RetryOptions{MaxAttempts: 1},
+		}
+		texts := newTexts()
+		res, err := e.enforce(context.Background(), subs, texts)
+		if err != nil {
+			t.Fatalf("enforce: %v", err)
+		}
+		if texts[1] != "Hola, amigo" || res.shortened != 1 {
+			t.Fatalf("expected cue 1 to be shortened, got %q (shortened=%d)", texts[1], res.shortened)
+		}
+		if !strings.Contains(texts[2], "\n") || res.wrapped != 1 || len(res.violations) != 0 {
+			t.Fatalf("expected cue 2 to be wrapped with no violations left, got %q %+v", texts[2], res)
+		}
+	})
+
+	t.Run(LengthPolicyReport, func(t *testing.T) {
+		e := lengthEnforcer{policy: LengthPolicyReport, maxCPS: 15, maxLineLength: 20}
+		texts := newTexts()
+		res, err := e.enforce(context.Background(), subs, texts)
+		if err != nil {
+			t.Fatalf("enforce: %v", err)
+		}
+		if texts[1] != newTexts()[1] || texts[2] != newTexts()[2] {
+			t.Fatalf("report policy must not change texts, got %v", texts)
+		}
+		if len(res.violations) != 2 || res.violations[0].CPS != 24 || res.violations[1].MaxLineLen != 32 {
+			t.Fatalf("unexpected violations: %+v", res.violations)
+		}
+	})
+
+	t.Run("max lines", func(t *testing.T) {
+		e := lengthEnforcer{policy: LengthPolicyWrap, targetLanguage: "es", maxLineLength: 20, maxLines: 2}
+		texts := map[int]string{1: "Hola,\nqué tal,\namigo mío", 2: "- Hola.\n- ¿Qué tal?\n- Bien."}
+		res, err := e.enforce(context.Background(), subs, texts)
+		if err != nil {
+			t.Fatalf("enforce: %v", err)
+		}
+		if texts[1] != "Hola, qué tal,\namigo mío" || res.wrapped != 1 {
+			t.Fatalf("expected cue 1 to be broken again into 2 lines, got %q (wrapped=%d)", texts[1], res.wrapped)
+		}
+		if len(res.violations) != 1 || res.violations[0].Idx != 2 || res.violations[0].Lines != 3 {
+			t.Fatalf("expected the dialogue cue to be kept and flagged, got %+v", res.violations)
+		}
+	})
+}
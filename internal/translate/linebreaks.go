@@ -0,0 +1,66 @@
+package translate
+
+import (
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fix"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Line break modes: how the line breaks inside a cue are treated during
+// translation.
+const (
+	// LineBreaksPreserve asks the model to keep the line breaks of each cue.
+	LineBreaksPreserve = "preserve"
+	// LineBreaksReflow lets the model move or drop them; the translations are
+	// broken again into balanced lines afterwards.
+	LineBreaksReflow = "reflow"
+)
+
+const DefaultLineBreaks = LineBreaksPreserve
+
+// promptLineBreakRulesReflow is added to the prompt with LineBreaksReflow.
+const promptLineBreakRulesReflow = "" +
+	"- Line breaks (\\n) inside text are soft: translate each item as running text and put line breaks wherever they read best, or leave them out.\n"
+
+func normalizeLineBreaks(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+func isValidLineBreaks(mode string) bool {
+	return mode == LineBreaksPreserve || mode == LineBreaksReflow
+}
+
+// lineBreakRules returns the prompt rules for the line break mode.
+func lineBreakRules(mode string) string {
+	if mode == LineBreaksReflow {
+		return promptLineBreakRulesReflow
+	}
+	return ""
+}
+
+// reflowTranslations breaks every translated cue again into balanced lines,
+// as many as its source cue has (at most maxLines when positive, and a single
+// line when it fits in maxLen). Dialogue cues are left as they are. It
+// returns the number of cues changed.
+func reflowTranslations(subs []*srt.Subtitle, translated map[int]string, targetLanguage string, maxLen, maxLines int) int {
+	changed := 0
+	for _, sub := range subs {
+		t, ok := translated[sub.Idx]
+		if !ok || strings.TrimSpace(t) == "" {
+			continue
+		}
+		n := lineCount(strings.TrimSpace(sub.Text))
+		if maxLines > 0 {
+			n = min(n, maxLines)
+		}
+		if maxLen > 0 && srt.DisplayWidth(fix.BalanceLines(t, 1, maxLen, targetLanguage)) <= maxLen {
+			n = 1
+		}
+		if reflowed := fix.BalanceLines(t, n, maxLen, targetLanguage); reflowed != t {
+			translated[sub.Idx] = reflowed
+			changed++
+		}
+	}
+	return changed
+}
@@ -0,0 +1,54 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestReflowTranslations(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, Text: "We are going to look for\na house."},
+		{Idx: 2, Text: "I told you."},
+		{Idx: 3, Text: "- Hi.\n- Hello."},
+		{Idx: 4, Text: "Short\nlines"},
+	}
+	translated := map[int]string{
+		1: "Vamos a buscar una casa en el campo para pasar el verano.",
+		2: "Te lo\ndije.",
+		3: "- Hola.\n- Buenas.",
+		4: "Líneas\ncortas",
+	}
+	changed := reflowTranslations(subs, translated, "es", 42, 2)
+	if translated[1] != "Vamos a buscar una casa\nen el campo para pasar el verano." {
+		t.Fatalf("expected cue 1 in 2 balanced lines, got %q", translated[1])
+	}
+	if translated[2] != "Te lo dije." || translated[4] != "Líneas cortas" {
+		t.Fatalf("expected short cues on one line, got %q and %q", translated[2], translated[4])
+	}
+	if translated[3] != "- Hola.\n- Buenas." {
+		t.Fatalf("expected the dialogue cue to be kept, got %q", translated[3])
+	}
+	if changed != 3 {
+		t.Fatalf("expected 3 cues changed, got %d", changed)
+	}
+}
+
+func TestBuildPrompt_LineBreakRules(t *testing.T) {
+	input := `{"idx":1,"text":"Hello\nworld"}`
+	msgs, err := buildPrompt(PromptOptions{LineBreaks: LineBreaksReflow}, "", "es", input, false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if !strings.Contains(msgs[1].Content, promptLineBreakRulesReflow) {
+		t.Fatalf("expected the reflow rule in prompt:\n%s", msgs[1].Content)
+	}
+	msgs, err = buildPrompt(PromptOptions{LineBreaks: LineBreaksPreserve}, "", "es", input, false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if strings.Contains(msgs[1].Content, "soft") {
+		t.Fatalf("expected no reflow rule with preserve:\n%s", msgs[1].Content)
+	}
+}
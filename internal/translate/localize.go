@@ -0,0 +1,455 @@
+package translate
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/adrianmusante/subtitle-tools/internal/langdetect"
+	"github.com/adrianmusante/subtitle-tools/internal/naming"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Prompt guidance added with Options.LocalizeNumbers and Options.ConvertUnits.
+const (
+	localizeNumbersInstruction = "write numbers, dates, times and currency amounts with the conventions of the target locale (decimal and thousands separators, day and month order, position of the currency symbol) without changing their values."
+	convertUnitsInstruction    = "convert imperial and US customary units to metric (e.g. 1.5 miles to 2.4 km, 70 °F to 21 °C), rounded as a native speaker would say it, and write the metric unit symbol."
+)
+
+// numberFormat is how a locale writes decimal numbers.
+type numberFormat struct {
+	decimal rune
+	group   rune
+}
+
+var (
+	pointFormat      = numberFormat{decimal: '.', group: ','}
+	commaFormat      = numberFormat{decimal: ',', group: '.'}
+	commaSpaceFormat = numberFormat{decimal: ',', group: '\u00a0'} // no-break space
+)
+
+// numberFormats are keyed by ISO 639-1 code.
+var numberFormats = map[string]numberFormat{
+	"en": pointFormat, "ja": pointFormat, "zh": pointFormat, "ko": pointFormat, "he": pointFormat,
+	"th": pointFormat, "hi": pointFormat, "ms": pointFormat,
+	"es": commaFormat, "pt": commaFormat, "it": commaFormat, "de": commaFormat, "nl": commaFormat,
+	"id": commaFormat, "tr": commaFormat, "da": commaFormat, "el": commaFormat, "ro": commaFormat,
+	"hr": commaFormat, "sl": commaFormat, "sr": commaFormat, "vi": commaFormat, "ca": commaFormat,
+	"fr": commaSpaceFormat, "ru": commaSpaceFormat, "uk": commaSpaceFormat, "pl": commaSpaceFormat,
+	"cs": commaSpaceFormat, "sk": commaSpaceFormat, "sv": commaSpaceFormat, "fi": commaSpaceFormat,
+	"nb": commaSpaceFormat, "no": commaSpaceFormat, "hu": commaSpaceFormat, "bg": commaSpaceFormat,
+}
+
+// regionNumberFormats override numberFormats; a zero format means the region
+// has no single convention (e.g. Latin America), so numbers are left alone.
+var regionNumberFormats = map[string]numberFormat{
+	"es-mx": pointFormat, "es-us": pointFormat, "es-419": {},
+	"de-ch": {}, "de-li": {}, "en-za": {},
+}
+
+// numberFormatFor returns the number format of language, or false when it is
+// unknown.
+func numberFormatFor(language string) (numberFormat, bool) {
+	tag, _ := normalizeTargetLanguage(language)
+	short := strings.ToLower(naming.ShortLanguage(tag))
+	if f, ok := regionNumberFormats[short]; ok {
+		return f, f != numberFormat{}
+	}
+	primary, _, _ := strings.Cut(short, "-")
+	f, ok := numberFormats[primary]
+	return f, ok
+}
+
+// usesImperialUnits reports whether language is spoken where imperial units
+// are the norm, so converting them would be wrong. English without a region
+// counts, as it doesn't say which.
+func usesImperialUnits(language string) bool {
+	tag, _ := normalizeTargetLanguage(language)
+	short := strings.ToLower(naming.ShortLanguage(tag))
+	switch short {
+	case "en", "en-us", "en-lr", "my":
+		return true
+	}
+	return false
+}
+
+// splitNumber splits tok, written in format f, into the digits of its
+// integer and fraction parts. ok is false when tok isn't a well-formed number
+// in f.
+func splitNumber(tok string, f numberFormat) (intDigits, frac string, grouped, ok bool) {
+	intPart, frac, hasDecimal := strings.Cut(tok, string(f.decimal))
+	if hasDecimal && !isDigits(frac) {
+		return "", "", false, false
+	}
+	groups := strings.Split(intPart, string(f.group))
+	for i, g := range groups {
+		if !isDigits(g) || (i > 0 && len(g) != 3) || (len(groups) > 1 && len(g) > 3) {
+			return "", "", false, false
+		}
+	}
+	return strings.Join(groups, ""), frac, len(groups) > 1, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatNumber writes a number split with splitNumber in format f.
+func formatNumber(intDigits, frac string, grouped bool, f numberFormat) string {
+	var b strings.Builder
+	for i, r := range intDigits {
+		if grouped && i > 0 && (len(intDigits)-i)%3 == 0 {
+			b.WriteRune(f.group)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteRune(f.decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// parseNumber returns the value of tok written in format f.
+func parseNumber(tok string, f numberFormat) (float64, bool) {
+	intDigits, frac, _, ok := splitNumber(tok, f)
+	if !ok {
+		return 0, false
+	}
+	if frac != "" {
+		intDigits += "." + frac
+	}
+	v, err := strconv.ParseFloat(intDigits, 64)
+	return v, err == nil
+}
+
+// formatValue writes a converted measure: one decimal below 10, none above.
+func formatValue(v float64, f numberFormat) string {
+	if math.Abs(v) < 10 {
+		v = math.Round(v*10) / 10
+	} else {
+		v = math.Round(v)
+	}
+	s := strconv.FormatFloat(math.Abs(v), 'f', -1, 64)
+	intDigits, frac, _ := strings.Cut(s, ".")
+	out := formatNumber(intDigits, frac, len(intDigits) > 4, f)
+	if v < 0 {
+		out = "-" + out
+	}
+	return out
+}
+
+var numberTokenPattern = regexp.MustCompile(`\d+(?:[.,\x{00a0}]\d+)*`)
+
+// numberTokens returns the positions of the numbers in text, skipping those
+// glued to letters (e.g. "MP3" or "4K").
+func numberTokens(text string) [][]int {
+	var out [][]int
+	for _, loc := range numberTokenPattern.FindAllStringIndex(text, -1) {
+		if isNumberBoundary(text, loc[0], loc[1]) {
+			out = append(out, loc)
+		}
+	}
+	return out
+}
+
+func isNumberBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r := lastRune(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r := firstRune(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	r := []rune(s)
+	if len(r) == 0 {
+		return 0
+	}
+	return r[len(r)-1]
+}
+
+// replaceNumber replaces every occurrence of the number tok in text.
+func replaceNumber(text, tok, repl string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(text, tok)
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		if isNumberBoundary(text, i, i+len(tok)) {
+			b.WriteString(text[:i] + repl)
+		} else {
+			b.WriteString(text[:i+len(tok)])
+		}
+		text = text[i+len(tok):]
+	}
+}
+
+// imperialUnit converts an imperial or US customary unit to metric.
+type imperialUnit struct {
+	symbol  string // metric unit symbol
+	convert func(float64) float64
+}
+
+var imperialUnits = map[string]imperialUnit{
+	"mile":   {"km", func(v float64) float64 { return v * 1.609344 }},
+	"mph":    {"km/h", func(v float64) float64 { return v * 1.609344 }},
+	"foot":   {"m", func(v float64) float64 { return v * 0.3048 }},
+	"inch":   {"cm", func(v float64) float64 { return v * 2.54 }},
+	"yard":   {"m", func(v float64) float64 { return v * 0.9144 }},
+	"pound":  {"kg", func(v float64) float64 { return v * 0.45359237 }},
+	"ounce":  {"g", func(v float64) float64 { return v * 28.349523125 }},
+	"gallon": {"l", func(v float64) float64 { return v * 3.785411784 }},
+	"degF":   {"°C", func(v float64) float64 { return (v - 32) * 5 / 9 }},
+}
+
+var imperialQuantityPattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)*)(?:\s|-)?(miles per hour|mph|miles?|feet|foot|ft|inch(?:es)?|yards?|yds?|pounds?|lbs?|ounces?|oz|gallons?|°\s?f|degrees fahrenheit|fahrenheit)\b`)
+
+// imperialUnitKey maps a unit as written in the source to imperialUnits.
+func imperialUnitKey(unit string) string {
+	unit = strings.ToLower(unit)
+	switch {
+	case unit == "mph" || unit == "miles per hour":
+		return "mph"
+	case strings.HasPrefix(unit, "mile"):
+		return "mile"
+	case unit == "feet" || unit == "foot" || unit == "ft":
+		return "foot"
+	case strings.HasPrefix(unit, "inch"):
+		return "inch"
+	case strings.HasPrefix(unit, "yard") || strings.HasPrefix(unit, "yd"):
+		return "yard"
+	case strings.HasPrefix(unit, "pound") || strings.HasPrefix(unit, "lb"):
+		return "pound"
+	case strings.HasPrefix(unit, "ounce") || unit == "oz":
+		return "ounce"
+	case strings.HasPrefix(unit, "gallon"):
+		return "gallon"
+	default:
+		return "degF"
+	}
+}
+
+// imperialQuantity is a measure in the source cue.
+type imperialQuantity struct {
+	number string  // as written in the source
+	value  float64 // of number
+	metric float64 // converted value
+	unit   imperialUnit
+}
+
+func imperialQuantities(text string, f numberFormat) []imperialQuantity {
+	var out []imperialQuantity
+	for _, m := range imperialQuantityPattern.FindAllStringSubmatchIndex(text, -1) {
+		if r := lastRune(text[:m[2]]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			continue
+		}
+		number := text[m[2]:m[3]]
+		v, ok := parseNumber(number, f)
+		if !ok {
+			continue
+		}
+		unit := imperialUnits[imperialUnitKey(text[m[4]:m[5]])]
+		out = append(out, imperialQuantity{number: number, value: v, metric: unit.convert(v), unit: unit})
+	}
+	return out
+}
+
+// localizer is the local pass of Options.LocalizeNumbers and
+// Options.ConvertUnits. It checks the translations against their source and
+// fixes what the model left as in the source: numbers copied with the
+// separators of the source locale, and imperial measures not converted.
+type localizer struct {
+	from, to numberFormat
+	numbers  bool // fix the separators of numbers
+	units    bool // convert imperial measures
+}
+
+// newLocalizer returns the localizer for translating subs into
+// targetLanguage, or nil when there is nothing to localize. The source
+// language is detected when it isn't given.
+func newLocalizer(opts Options, subs []*srt.Subtitle) *localizer {
+	if !opts.LocalizeNumbers && !opts.ConvertUnits {
+		return nil
+	}
+	source := opts.SourceLanguage
+	if source == "" {
+		texts := make([]string, 0, len(subs))
+		for _, s := range subs {
+			texts = append(texts, s.Text)
+		}
+		if detected, ok := langdetect.Detect(strings.Join(texts, "\n")); ok {
+			source = detected.Language
+		}
+	}
+	from, fromOK := numberFormatFor(source)
+	to, toOK := numberFormatFor(opts.TargetLanguage)
+	l := &localizer{
+		from:    from,
+		to:      to,
+		numbers: opts.LocalizeNumbers && fromOK && toOK && from != to,
+		units:   opts.ConvertUnits && !usesImperialUnits(opts.TargetLanguage),
+	}
+	if !fromOK {
+		l.from = pointFormat // imperial measures come from English sources
+	}
+	if !toOK {
+		l.to = pointFormat
+	}
+	if opts.LocalizeNumbers && !l.numbers {
+		slog.Debug("number formats of the source and target languages are the same or unknown; leaving numbers as they are",
+			"source_language", source, "target_language", opts.TargetLanguage)
+	}
+	if !l.numbers && !l.units {
+		return nil
+	}
+	return l
+}
+
+// localize updates translated in place and returns the number of cues
+// changed and the idxs of the cues with imperial measures it couldn't
+// convert.
+func (l *localizer) localize(subs []*srt.Subtitle, translated map[int]string) (int, []int) {
+	changed := 0
+	var missed []int
+	for _, sub := range subs {
+		t, ok := translated[sub.Idx]
+		if !ok || t == "" {
+			continue
+		}
+		out := t
+		if l.units {
+			var ok bool
+			out, ok = l.convertUnits(sub.Text, out)
+			if !ok {
+				missed = append(missed, sub.Idx)
+			}
+		}
+		if l.numbers {
+			out = l.localizeNumbers(sub.Text, out)
+		}
+		if out != t {
+			translated[sub.Idx] = out
+			changed++
+		}
+	}
+	return changed, missed
+}
+
+// localizeNumbers rewrites the numbers of source that the translation kept as
+// written in the source locale.
+func (l *localizer) localizeNumbers(source, translation string) string {
+	for _, loc := range numberTokens(source) {
+		tok := source[loc[0]:loc[1]]
+		intDigits, frac, grouped, ok := splitNumber(tok, l.from)
+		if !ok || (frac == "" && !grouped) {
+			continue
+		}
+		if localized := formatNumber(intDigits, frac, grouped, l.to); localized != tok {
+			translation = replaceNumber(translation, tok, localized)
+		}
+	}
+	return translation
+}
+
+// convertUnits checks that every imperial measure of source was converted
+// in translation, converting it when the translation kept the number of the
+// source followed by the (translated) unit. ok is false when a measure is
+// missing from both.
+func (l *localizer) convertUnits(source, translation string) (string, bool) {
+	ok := true
+	for _, q := range imperialQuantities(source, l.from) {
+		if containsConverted(translation, q, l.to) {
+			continue
+		}
+		converted := formatValue(q.metric, l.to) + " " + q.unit.symbol
+		replaced := false
+		for _, number := range []string{q.number, l.localizedNumber(q.number)} {
+			pattern := regexp.MustCompile(regexp.QuoteMeta(number) + `(?:\s?[°º]\s?[FfCc]?|\s+\p{L}+(?:\s+[Ff]ahrenheit)?)`)
+			if loc := pattern.FindStringIndex(translation); loc != nil && isNumberBoundary(translation, loc[0], loc[0]+len(number)) {
+				translation = translation[:loc[0]] + converted + translation[loc[1]:]
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			ok = false
+		}
+	}
+	return translation, ok
+}
+
+// localizedNumber returns number written in the target format.
+func (l *localizer) localizedNumber(number string) string {
+	intDigits, frac, grouped, ok := splitNumber(number, l.from)
+	if !ok {
+		return number
+	}
+	return formatNumber(intDigits, frac, grouped, l.to)
+}
+
+// containsConverted reports whether text has a number close to the metric
+// value of q: within 6% or half a unit, to accept the rounding of the model.
+// The number of the source, in either format, doesn't count: it is often
+// that close for small measures (3 yards are 2.7 m).
+func containsConverted(text string, q imperialQuantity, f numberFormat) bool {
+	for _, loc := range numberTokens(text) {
+		n, ok := parseNumber(text[loc[0]:loc[1]], f)
+		if ok && n != q.value && math.Abs(n-q.metric) <= max(0.06*math.Abs(q.metric), 0.5) {
+			return true
+		}
+	}
+	return false
+}
+
+// localizationGuidance returns the prompt guidance lines for the options.
+func localizationGuidance(settings PromptOptions, targetLanguage string) string {
+	var lines []string
+	if settings.LocalizeNumbers {
+		lines = append(lines, "Numbers: "+localizeNumbersInstruction)
+	}
+	if settings.ConvertUnits && !usesImperialUnits(targetLanguage) {
+		lines = append(lines, "Units: "+convertUnitsInstruction)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func logUnconvertedUnits(targetLanguage string, idxs []int) {
+	if len(idxs) == 0 {
+		return
+	}
+	s := make([]string, 0, len(idxs))
+	for _, idx := range idxs {
+		s = append(s, fmt.Sprint(idx))
+	}
+	slog.Warn("translated cues still have imperial units", "target_language", targetLanguage, "cues", len(idxs), "idxs", abbreviate(strings.Join(s, ","), AbbreviationMax))
+}
@@ -0,0 +1,125 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestNumberFormatFor(t *testing.T) {
+	cases := []struct {
+		language string
+		want     numberFormat
+		ok       bool
+	}{
+		{"en", pointFormat, true},
+		{"es-ES", commaFormat, true},
+		{"es-MX", pointFormat, true},
+		{"es-419", numberFormat{}, false},
+		{"fra", commaSpaceFormat, true},
+		{"xx", numberFormat{}, false},
+	}
+	for _, c := range cases {
+		got, ok := numberFormatFor(c.language)
+		if got != c.want || ok != c.ok {
+			t.Errorf("numberFormatFor(%q)=%v,%v, want %v,%v", c.language, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestLocalizer(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, Text: "It costs 1,250.50 dollars."},
+		{Idx: 2, Text: "We walked 1.5 miles."},
+		{Idx: 3, Text: "He weighs 200 pounds."},
+		{Idx: 4, Text: "It's 70 °F outside."},
+		{Idx: 5, Text: "Only 3.5% of MP3 files"},
+	}
+	translated := map[int]string{
+		1: "Cuesta 1,250.50 dólares.",
+		2: "Caminamos 2,4 km.",  // converted by the model
+		3: "Pesa 200 libras.",   // left as in the source
+		4: "Hace calor afuera.", // the measure is gone
+		5: "Solo el 3.5% de los MP3",
+	}
+	l := newLocalizer(Options{SourceLanguage: "en", TargetLanguage: "es", LocalizeNumbers: true, ConvertUnits: true}, subs)
+	if l == nil {
+		t.Fatalf("expected a localizer")
+	}
+	changed, unconverted := l.localize(subs, translated)
+
+	want := map[int]string{
+		1: "Cuesta 1.250,50 dólares.",
+		2: "Caminamos 2,4 km.",
+		3: "Pesa 91 kg.",
+		4: "Hace calor afuera.",
+		5: "Solo el 3,5% de los MP3",
+	}
+	for idx, w := range want {
+		if translated[idx] != w {
+			t.Errorf("cue %d: got %q, want %q", idx, translated[idx], w)
+		}
+	}
+	if changed != 3 {
+		t.Errorf("expected 3 cues changed, got %d", changed)
+	}
+	if len(unconverted) != 1 || unconverted[0] != 4 {
+		t.Errorf("expected cue 4 to be reported unconverted, got %v", unconverted)
+	}
+}
+
+func TestLocalizer_SmallMeasuresLeftAsInSource(t *testing.T) {
+	// The numbers of the source are within half a unit of the metric value,
+	// but weren't converted.
+	subs := []*srt.Subtitle{
+		{Idx: 1, Text: "It's 3 yards away."},
+		{Idx: 2, Text: "It's 0.5 miles away."},
+	}
+	translated := map[int]string{
+		1: "Está a 3 yardas.",
+		2: "Está a 0,5 millas.",
+	}
+	l := newLocalizer(Options{SourceLanguage: "en", TargetLanguage: "es", ConvertUnits: true}, subs)
+	changed, unconverted := l.localize(subs, translated)
+
+	want := map[int]string{
+		1: "Está a 2,7 m.",
+		2: "Está a 0,8 km.",
+	}
+	for idx, w := range want {
+		if translated[idx] != w {
+			t.Errorf("cue %d: got %q, want %q", idx, translated[idx], w)
+		}
+	}
+	if changed != 2 || len(unconverted) != 0 {
+		t.Errorf("changed %d, unconverted %v", changed, unconverted)
+	}
+}
+
+func TestNewLocalizer_SkipsImperialTargetsAndSameFormats(t *testing.T) {
+	subs := []*srt.Subtitle{{Idx: 1, Text: "1.5 miles"}}
+	if l := newLocalizer(Options{SourceLanguage: "en", TargetLanguage: "en-US", LocalizeNumbers: true, ConvertUnits: true}, subs); l != nil {
+		t.Fatalf("expected no localizer for en to en-US, got %+v", l)
+	}
+	if l := newLocalizer(Options{SourceLanguage: "en", TargetLanguage: "es-MX", LocalizeNumbers: true}, subs); l != nil {
+		t.Fatalf("expected no localizer for the same number format, got %+v", l)
+	}
+}
+
+func TestBuildPrompt_LocalizationGuidance(t *testing.T) {
+	msgs, err := buildPrompt(PromptOptions{LocalizeNumbers: true, ConvertUnits: true}, "", "de", "{\"idx\":1,\"text\":\"Hi\"}", false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if !strings.Contains(msgs[0].Content, "Numbers: "+localizeNumbersInstruction) || !strings.Contains(msgs[0].Content, "Units: "+convertUnitsInstruction) {
+		t.Fatalf("expected localization guidance in the system message:\n%s", msgs[0].Content)
+	}
+	msgs, err = buildPrompt(PromptOptions{ConvertUnits: true}, "", "en-US", "{\"idx\":1,\"text\":\"Hi\"}", false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if strings.Contains(msgs[0].Content, "Units:") {
+		t.Fatalf("expected no unit conversion for an imperial target:\n%s", msgs[0].Content)
+	}
+}
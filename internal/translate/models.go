@@ -0,0 +1,79 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// modelValidator is implemented by clients that can check the configured model
+// against the provider before any batch is sent.
+type modelValidator interface {
+	ValidateModel(ctx context.Context) error
+}
+
+// ListModels queries the OpenAI-compatible /v1/models endpoint and returns the
+// available model ids.
+func (c *OpenAIClient) ListModels(ctx context.Context) ([]string, error) {
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Transport: c.Transport, Timeout: c.Timeout}
+	}
+	u, err := c.endpointURL("/models")
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := ""
+	apiKey, _, err := c.apiKeyPool().acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		authorization = "Bearer " + apiKey
+	}
+	r, err := doJSONRequest(ctx, hc, http.MethodGet, u.String(), authorization, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list models: %w", err)
+	}
+	if r.statusCode < 200 || r.statusCode >= 300 {
+		return nil, fmt.Errorf("list models: %w", &httpStatusError{StatusCode: r.statusCode, Body: strings.TrimSpace(string(r.bodyBytes))})
+	}
+
+	var out modelsResponse
+	if err := json.Unmarshal(r.bodyBytes, &out); err != nil {
+		return nil, fmt.Errorf("decode models response: %w", err)
+	}
+	ids := make([]string, 0, len(out.Data))
+	for _, m := range out.Data {
+		ids = append(ids, m.ID)
+	}
+	return ids, nil
+}
+
+// ValidateModel returns an error when the configured model is not listed by the
+// provider. Local servers (e.g. Ollama) may list tags like "llama3.1:latest",
+// so a model without an explicit tag also matches its ":latest" variant.
+func (c *OpenAIClient) ValidateModel(ctx context.Context) error {
+	ids, err := c.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	name := requestModelName(c.Model)
+	for _, id := range ids {
+		if id == name || id == name+":latest" || strings.TrimPrefix(id, "models/") == name {
+			return nil
+		}
+	}
+	slices.Sort(ids)
+	return fmt.Errorf("model %q not found on server (available: %s)", name, strings.Join(ids, ", "))
+}
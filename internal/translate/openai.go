@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,38 +19,155 @@ import (
 
 type OpenAIClient struct {
 	HTTPClient   *http.Client
-	BaseURL      string // e.g. https://api.openai.com
-	APIKey       string // can be a single key or a comma-separated list of keys
+	Transport    http.RoundTripper // used when HTTPClient is nil
+	BaseURL      string            // e.g. https://api.openai.com
+	APIKey       string            // can be a single key or a comma-separated list of keys
 	Model        string
 	Timeout      time.Duration
 	RetryOptions RetryOptions
+	// ResponseMode selects how the output shape is enforced (auto, ndjson, json-schema).
+	// Empty means DefaultResponseMode.
+	ResponseMode string
 
-	apiKeyRR uint32 // round-robin counter for multi-key rotation
+	// KeyRPS limits requests per second for each API key (0 disables per-key
+	// limiting). It complements the global limiter used by translate.Run.
+	KeyRPS float64
+
+	// Prompt customizes the prompt (template, glossary, style guidance).
+	Prompt PromptOptions
+
+	// Sampling sets temperature, top_p, the output token limit and the
+	// reasoning effort. Unset values use per-model defaults.
+	Sampling SamplingOptions
+
+	// Stream requests server-sent events and accumulates the deltas. Timeout
+	// then limits the time without receiving data instead of the whole request.
+	Stream bool
+
+	// SkipPromptCache sends no prompt caching hints (see markPromptCache).
+	SkipPromptCache bool
+
+	keyPoolOnce sync.Once
+	keyPool     *apiKeyPool
+
+	// schemaUnsupported is set in auto mode once the provider rejects structured
+	// output, so later batches go straight to the NDJSON prompt.
+	schemaUnsupported atomic.Bool
 }
 
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+
+	// static is the length of the leading part of Content that is the same
+	// for every batch (the instructions before the cues), which the prompt
+	// cache of the provider can reuse.
+	static int
+	// cacheControl sends the static part with a cache_control breakpoint.
+	cacheControl bool
+	// images are data URLs of images sent after Content (see
+	// RecognizeImages).
+	images []string
 }
 
 type chatCompletionsRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	samplingParams
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	PromptCacheKey string          `json:"prompt_cache_key,omitempty"`
+}
+
+// httpStatusError is returned when the API answers with a non-2xx status.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("translation api error: status=%d body=%s", e.StatusCode, e.Body)
+}
+
+// truncatedResponseError is returned when the model stopped at the output token
+// limit (finish_reason "length"): the content is cut short, and sending the
+// same request again would cut it again.
+type truncatedResponseError struct {
+	partial string // content received
+}
+
+func (e *truncatedResponseError) Error() string {
+	return fmt.Sprintf("model response truncated at the output token limit (received %d chars)", len(e.partial))
+}
+
+// blockedResponseError is returned when the content filters of the provider
+// withheld the response (finish_reason "content_filter", a refusal, or a
+// Gemini safety block). Sending the same request again would be blocked again,
+// so it is not retried: the batch is split to isolate the cues instead.
+type blockedResponseError struct {
+	reason string
+}
+
+func (e *blockedResponseError) Error() string {
+	return fmt.Sprintf("response blocked by the content filters of the provider (reason %s)", e.reason)
+}
+
+// finishReasonContentFilter is the finish_reason of a response withheld by
+// the content filters (OpenAI, Azure OpenAI and Gemini's compatible layer).
+const finishReasonContentFilter = "content_filter"
+
+// isContentFilterRejection reports whether a non-2xx answer is the prompt
+// being rejected by the content filters, as Azure OpenAI answers (400 with the
+// "content_filter" code).
+func isContentFilterRejection(statusCode int, body string) bool {
+	return statusCode == http.StatusBadRequest &&
+		(strings.Contains(body, `"content_filter"`) || strings.Contains(body, "ResponsibleAIPolicyViolation"))
+}
+
+// isTruncatedFinishReason reports whether a finish_reason means the output
+// token limit was reached ("max_tokens" on some OpenAI-compatible servers).
+func isTruncatedFinishReason(reason string) bool {
+	return reason == "length" || reason == "max_tokens"
 }
 
 type chatCompletionsResponse struct {
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
+			Refusal string `json:"refusal"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *chatCompletionUsage `json:"usage"`
+}
+
+type chatCompletionUsage struct {
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// usage returns the token usage (zero when u is nil).
+func (u *chatCompletionUsage) usage() tokenUsage {
+	if u == nil {
+		return tokenUsage{}
+	}
+	usage := tokenUsage{total: u.TotalTokens}
+	if u.PromptTokensDetails != nil {
+		usage.cached = u.PromptTokensDetails.CachedTokens
+	}
+	return usage
 }
 
 func (c *OpenAIClient) apiKeys() []string {
-	// Accept comma-separated keys, trimming whitespace, ignoring empties.
-	// If no comma is present, still returns a 1-item slice.
-	raw := strings.TrimSpace(c.APIKey)
+	return splitAPIKeys(c.APIKey)
+}
+
+// splitAPIKeys accepts comma-separated keys, trimming whitespace, ignoring empties.
+// If no comma is present, still returns a 1-item slice.
+func splitAPIKeys(raw string) []string {
+	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return []string{}
 	}
@@ -64,24 +183,23 @@ func (c *OpenAIClient) apiKeys() []string {
 	return keys
 }
 
-func (c *OpenAIClient) pickAPIKey(keys []string, rotated bool) (string, int) {
-	if len(keys) == 0 {
-		return "", -1
+// httpClient returns the configured client or one honoring Timeout. When
+// streaming, the timeout is applied between received chunks instead.
+func (c *OpenAIClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
 	}
-	if len(keys) == 1 {
-		return keys[0], 0
+	if c.Stream {
+		return &http.Client{Transport: c.Transport}
 	}
-
-	base := int(atomic.LoadUint32(&c.apiKeyRR))
-	idx := base % len(keys)
-	if rotated {
-		idx = (idx + 1) % len(keys)
-	}
-	return keys[idx], idx
+	return &http.Client{Transport: c.Transport, Timeout: c.Timeout}
 }
 
-func (c *OpenAIClient) advanceAPIKeyRR() {
-	atomic.AddUint32(&c.apiKeyRR, 1)
+func (c *OpenAIClient) apiKeyPool() *apiKeyPool {
+	c.keyPoolOnce.Do(func() {
+		c.keyPool = newAPIKeyPool(c.apiKeys(), c.KeyRPS)
+	})
+	return c.keyPool
 }
 
 func (c *OpenAIClient) TranslateBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error) {
@@ -92,90 +210,279 @@ func (c *OpenAIClient) TranslateBatch(ctx context.Context, sourceLanguage string
 		return "", errors.New("target language is required")
 	}
 
-	keys := c.apiKeys()
+	keys := c.apiKeyPool()
 
-	hc := c.HTTPClient
-	if hc == nil {
-		hc = &http.Client{Timeout: c.Timeout}
-	}
+	hc := c.httpClient()
 
-	base, err := resolveBaseURLForModel(c.Model, c.BaseURL)
+	u, err := c.endpointURL("/chat/completions")
 	if err != nil {
 		return "", err
 	}
-	u, err := buildURL(base, "/v1/chat/completions")
+
+	mode := normalizeResponseMode(c.ResponseMode)
+	if mode == "" {
+		mode = DefaultResponseMode
+	}
+	if !isValidResponseMode(mode) {
+		return "", fmt.Errorf("invalid response mode %q (supported: %s, %s, %s)", c.ResponseMode, ResponseModeAuto, ResponseModeNDJSON, ResponseModeJSONSchema)
+	}
+	structured := mode == ResponseModeJSONSchema || (mode == ResponseModeAuto && !c.schemaUnsupported.Load())
+
+	body, err := c.buildRequestBody(sourceLanguage, targetLanguage, payload, structured)
 	if err != nil {
 		return "", err
 	}
 
-	messages := buildPrompt(sourceLanguage, targetLanguage, payload)
+	content, err := c.postChatCompletion(ctx, hc, u.String(), keys, body)
+	if err != nil && structured && mode == ResponseModeAuto && isStructuredOutputRejection(err) {
+		if c.schemaUnsupported.CompareAndSwap(false, true) {
+			slog.Warn("provider rejected structured output; falling back to ndjson response mode", "model", c.Model, "err", err)
+		}
+		body, err = c.buildRequestBody(sourceLanguage, targetLanguage, payload, false)
+		if err != nil {
+			return "", err
+		}
+		return c.postChatCompletion(ctx, hc, u.String(), keys, body)
+	}
+	return content, err
+}
+
+// ReviewBatch runs the QA pass over translated lines (see batchReviewer).
+func (c *OpenAIClient) ReviewBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error) {
+	return c.complete(ctx, buildReviewPrompt(sourceLanguage, targetLanguage, payload))
+}
 
-	reqBody := chatCompletionsRequest{
-		Model:       c.Model,
-		Messages:    messages,
-		Temperature: 0,
+// CondenseBatch shortens translated lines (see batchCondenser).
+func (c *OpenAIClient) CondenseBatch(ctx context.Context, targetLanguage string, payload string) (string, error) {
+	return c.complete(ctx, buildCondensePrompt(targetLanguage, payload))
+}
+
+// complete sends a plain chat completion (no response_format) and returns the
+// message content.
+func (c *OpenAIClient) complete(ctx context.Context, messages []ChatMessage) (string, error) {
+	if c.Model == "" {
+		return "", errors.New("model is required")
 	}
-	body, err := json.Marshal(reqBody)
+	hc := c.httpClient()
+	u, err := c.endpointURL("/chat/completions")
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(chatCompletionsRequest{
+		Model:          requestModelName(c.Model),
+		Messages:       messages,
+		samplingParams: resolveSampling(c.Model, c.Sampling),
+		Stream:         c.Stream,
+	})
 	if err != nil {
 		return "", err
 	}
+	return c.postChatCompletion(ctx, hc, u.String(), c.apiKeyPool(), body)
+}
+
+func (c *OpenAIClient) buildRequestBody(sourceLanguage string, targetLanguage string, payload string, structured bool) ([]byte, error) {
+	reqBody, err := c.buildRequest(sourceLanguage, targetLanguage, payload, structured)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(reqBody)
+}
+
+func (c *OpenAIClient) buildRequest(sourceLanguage string, targetLanguage string, payload string, structured bool) (chatCompletionsRequest, error) {
+	messages, err := buildPrompt(c.Prompt, sourceLanguage, targetLanguage, payload, structured)
+	if err != nil {
+		return chatCompletionsRequest{}, fmt.Errorf("build prompt: %w", err)
+	}
+	reqBody := chatCompletionsRequest{
+		Model:          requestModelName(c.Model),
+		Messages:       messages,
+		samplingParams: resolveSampling(c.Model, c.Sampling),
+		Stream:         c.Stream,
+	}
+	if structured {
+		reqBody.ResponseFormat = subtitleResponseFormat()
+	}
+	if !c.SkipPromptCache {
+		c.markPromptCache(&reqBody)
+	}
+	return reqBody, nil
+}
 
+func (c *OpenAIClient) postChatCompletion(ctx context.Context, hc *http.Client, u string, keys *apiKeyPool, body []byte) (string, error) {
 	retry := c.RetryOptions
-	rotatedOnReject := false
 
 	return requestWithRetry[string](ctx, retry, func(attempt int) (string, retryDecision) {
-		apiKey, _ := c.pickAPIKey(keys, rotatedOnReject)
-		rotatedOnReject = false
+		apiKey, keyIdx, err := keys.acquire(ctx)
+		if err != nil {
+			return "", retryDecision{err: err}
+		}
 
-		r, err := doJSONPost(ctx, hc, u.String(), apiKey, body)
+		var r httpResult
+		var content string
+		if c.Stream {
+			r, content, err = doChatCompletionStream(ctx, hc, u, apiKey, body, c.Timeout)
+		} else {
+			r, err = doJSONPost(ctx, hc, u, apiKey, body)
+		}
+		var tErr *truncatedResponseError
+		var bErr *blockedResponseError
+		if errors.As(err, &tErr) || errors.As(err, &bErr) {
+			// Not retried: the batch is split instead.
+			reportTokenUsage(ctx, r.usage)
+			return "", retryDecision{err: err}
+		}
 		if err != nil {
-			if isRetryableNetErr(err) {
+			var sErr *streamError
+			if isRetryableNetErr(err) || errors.As(err, &sErr) {
 				return "", retryDecision{err: err, retry: true}
 			}
 			return "", retryDecision{err: err}
 		}
 
 		if r.statusCode < 200 || r.statusCode >= 300 {
-			hErr := fmt.Errorf("translation api error: status=%d body=%s", r.statusCode, strings.TrimSpace(string(r.bodyBytes)))
-
-			if isRejectedHTTPStatus(r.statusCode) {
-				if len(keys) > 1 {
-					slog.Warn("translation api rejected request; rotating api key",
-						"attempt", attempt,
-						"status_code", r.statusCode,
-						"status_text", http.StatusText(r.statusCode),
-						"rejected_key", run.MaskKey(apiKey),
-						"keys", len(keys),
-					)
-					rotatedOnReject = true
-				}
+			hErr := &httpStatusError{StatusCode: r.statusCode, Body: strings.TrimSpace(string(r.bodyBytes))}
+			if isContentFilterRejection(r.statusCode, hErr.Body) {
+				return "", retryDecision{err: fmt.Errorf("%w: %w", &blockedResponseError{reason: finishReasonContentFilter}, hErr)}
+			}
+
+			retryAfter := retryDelayFromHeader(r.header)
+			if retryAfter == 0 && r.statusCode == http.StatusTooManyRequests {
+				retryAfter = parseRateLimitHeaders(r.header).retryAfter()
+			}
+			rotated := false
+			if isRejectedHTTPStatus(r.statusCode) && keys.size() > 1 {
+				cooldown := keyCooldown(r.statusCode, retryAfter)
+				slog.Warn("translation api rejected request; rotating api key",
+					"attempt", attempt,
+					"status_code", r.statusCode,
+					"status_text", http.StatusText(r.statusCode),
+					"rejected_key", run.MaskKey(apiKey),
+					"cooldown", cooldown,
+					"keys", keys.size(),
+				)
+				keys.bench(keyIdx, cooldown)
+				rotated = true
 			}
 
-			if rotatedOnReject || isRetryableHTTPStatus(r.statusCode) {
-				return "", retryDecision{err: hErr, retry: true, delay: retryDelayFromHeader(r.header)}
+			if rotated {
+				// Another key is available (or the pool waits for reinstatement).
+				return "", retryDecision{err: hErr, retry: true, delay: time.Millisecond}
+			}
+			if r.statusCode == http.StatusTooManyRequests {
+				// The other requests with the key wait too.
+				keys.pause(keyIdx, retryAfter)
+			}
+			if isRetryableHTTPStatus(r.statusCode) {
+				return "", retryDecision{err: hErr, retry: true, delay: retryAfter}
 			}
 			return "", retryDecision{err: hErr}
 		}
 
-		// Success: advance RR so the next request starts from the next key.
-		if len(keys) > 1 {
-			c.advanceAPIKeyRR()
+		usage := r.usage
+		if content == "" {
+			content, usage, err = parseChatCompletionContent(r.bodyBytes)
+			if errors.As(err, &tErr) || errors.As(err, &bErr) {
+				reportTokenUsage(ctx, usage)
+				return "", retryDecision{err: err}
+			}
+			if err != nil {
+				return "", retryDecision{err: err, retry: true}
+			}
 		}
-
-		content, err := parseChatCompletionContent(r.bodyBytes)
-		if err != nil {
-			return "", retryDecision{err: err, retry: true}
+		reportTokenUsage(ctx, usage)
+		if d := parseRateLimitHeaders(r.header).pause(usage.total); d > 0 {
+			slog.Info("rate limit of the api key nearly exhausted; pausing its requests until it resets",
+				"key", run.MaskKey(apiKey),
+				"remaining_requests", r.header.Get("X-Ratelimit-Remaining-Requests"),
+				"remaining_tokens", r.header.Get("X-Ratelimit-Remaining-Tokens"),
+				"pause", d,
+			)
+			keys.pause(keyIdx, d)
 		}
 		return content, retryDecision{}
 	})
 }
 
+// isStructuredOutputRejection reports whether err looks like the provider does
+// not support response_format (usually a 400/422 mentioning the field).
+func isStructuredOutputRejection(err error) bool {
+	var hErr *httpStatusError
+	if !errors.As(err, &hErr) {
+		return false
+	}
+	if hErr.StatusCode != http.StatusBadRequest && hErr.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	body := strings.ToLower(hErr.Body)
+	return strings.Contains(body, "response_format") ||
+		strings.Contains(body, "json_schema") ||
+		strings.Contains(body, "schema")
+}
+
+// endpointURL resolves an OpenAI-compatible endpoint (e.g. "/chat/completions")
+// under the /v1 prefix of the base URL for the configured model.
+func (c *OpenAIClient) endpointURL(endpoint string) (*url.URL, error) {
+	base, err := resolveBaseURLForModel(c.Model, c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return buildURL(base, openAIVersionedPath(base, endpoint))
+}
+
+// openAIVersionedPath prefixes endpoint with /v1 unless the base URL already
+// ends with it (as local servers are usually configured, e.g. http://localhost:11434/v1).
+func openAIVersionedPath(base string, endpoint string) string {
+	if strings.HasSuffix(strings.TrimRight(base, "/"), "/v1") {
+		return endpoint
+	}
+	return "/v1" + endpoint
+}
+
+// Model prefixes for local OpenAI-compatible servers. The prefix selects the
+// default base URL and is stripped before sending the model name.
+const (
+	ModelPrefixOllama   = "ollama:"
+	ModelPrefixLMStudio = "lmstudio:"
+)
+
+const (
+	DefaultOllamaBaseURL   = "http://localhost:11434/v1"
+	DefaultLMStudioBaseURL = "http://localhost:1234/v1"
+)
+
+var localModelBaseURLs = map[string]string{
+	ModelPrefixOllama:   DefaultOllamaBaseURL,
+	ModelPrefixLMStudio: DefaultLMStudioBaseURL,
+}
+
+// splitLocalModel returns the local server prefix (if any) and the bare model name.
+func splitLocalModel(model string) (prefix string, name string) {
+	trimmed := strings.TrimSpace(model)
+	lower := strings.ToLower(trimmed)
+	for p := range localModelBaseURLs {
+		if strings.HasPrefix(lower, p) {
+			return p, strings.TrimSpace(trimmed[len(p):])
+		}
+	}
+	return "", trimmed
+}
+
+// requestModelName returns the model name as expected by the API.
+func requestModelName(model string) string {
+	_, name := splitLocalModel(model)
+	return name
+}
+
 func resolveBaseURLForModel(model string, explicitBaseURL string) (string, error) {
 	explicitBaseURL = strings.TrimSpace(explicitBaseURL)
 	if explicitBaseURL != "" {
 		return explicitBaseURL, nil
 	}
 
+	if prefix, _ := splitLocalModel(model); prefix != "" {
+		return localModelBaseURLs[prefix], nil
+	}
+
 	m := strings.ToLower(strings.TrimSpace(model))
 	switch {
 	case strings.HasPrefix(m, "gemini-"):
@@ -183,38 +490,6 @@ func resolveBaseURLForModel(model string, explicitBaseURL string) (string, error
 	case strings.HasPrefix(m, "gpt-"):
 		return "https://api.openai.com", nil
 	default:
-		return "", fmt.Errorf("cannot resolve base url for model %q; set BaseURL explicitly", model)
-	}
-}
-
-func buildPrompt(sourceLanguage string, targetLanguage string, input string) []ChatMessage {
-	sourcePromptLabel := normalizeTargetLanguageLabel(sourceLanguage)
-	targetPromptLabel := normalizeTargetLanguageLabel(targetLanguage)
-
-	system := ChatMessage{Role: "system", Content: "You are a translation engine. Output must follow the requested format exactly. Do not add commentary."}
-	userContent := "Translate the following subtitles"
-	if sourcePromptLabel != "" {
-		userContent += " from `" + sourcePromptLabel + "`"
-	}
-	userContent += " to: `" + targetPromptLabel + "`\n"
-	userContent += "\n" +
-		"Rules:\n" +
-		"- Output MUST contain the same number of items as the input.\n" +
-		"- Preserve idx values exactly and do not reorder.\n" +
-		"- Output MUST be NDJSON: one JSON object per line (no surrounding array).\n" +
-		"- Each output line MUST be valid JSON with exactly two keys: idx (number) and text (string).\n" +
-		"- Do not output markdown, code fences, headers, or explanations.\n" +
-		"\n" +
-		"Example:\n" +
-		"Input:\n" +
-		"{\"idx\":1,\"text\":\"Hello\\nworld\"}\n" +
-		"{\"idx\":2,\"text\":\"How are you?\"}\n" +
-		"Output:\n" +
-		"{\"idx\":1,\"text\":\"Hola\\nmundo\"}\n" +
-		"{\"idx\":2,\"text\":\"¿Cómo estás?\"}\n" +
-		"\n" +
-		"Input:\n\n" + input + "\n"
-	user := ChatMessage{Role: "user", Content: userContent}
-
-	return []ChatMessage{system, user}
+		return "", fmt.Errorf("cannot resolve base url for model %q; set BaseURL explicitly or use the %s/%s prefixes for local servers", model, ModelPrefixOllama, ModelPrefixLMStudio)
+	}
 }
@@ -2,6 +2,7 @@ package translate
 
 import (
 	"bytes"
+	"encoding/json"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -110,3 +111,175 @@ func TestOpenAIClient_429RotatesAPIKey(t *testing.T) {
 		t.Fatalf("expected log to mention api key rotation on 429; got logs: %s", logBuf.String())
 	}
 }
+
+func TestOpenAIClient_AutoResponseMode_FallsBackToNDJSON(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if _, ok := body["response_format"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"response_format json_schema is not supported"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	c := OpenAIClient{
+		BaseURL:      server.URL,
+		APIKey:       "test",
+		Model:        "gpt-test",
+		RetryOptions: RetryOptions{MaxAttempts: 1},
+	}
+
+	for i := 0; i < 2; i++ {
+		out, err := (&c).TranslateBatch(t.Context(), "en", "es", `{"idx":1,"text":"Hello"}`)
+		if err != nil {
+			t.Fatalf("TranslateBatch: %v", err)
+		}
+		if !strings.Contains(out, "Hola") {
+			t.Fatalf("unexpected output: %q", out)
+		}
+	}
+	// First call: structured (rejected) + ndjson; second call: ndjson only.
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(bodies))
+	}
+	if _, ok := bodies[0]["response_format"]; !ok {
+		t.Fatalf("expected first request to use response_format")
+	}
+	if _, ok := bodies[2]["response_format"]; ok {
+		t.Fatalf("expected fallback to stick for later requests")
+	}
+}
+
+func TestOpenAIClient_NDJSONResponseMode_OmitsResponseFormat(t *testing.T) {
+	var gotFormat bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, gotFormat = body["response_format"]
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	c := OpenAIClient{BaseURL: server.URL, Model: "gpt-test", ResponseMode: ResponseModeNDJSON, RetryOptions: RetryOptions{MaxAttempts: 1}}
+	if _, err := (&c).TranslateBatch(t.Context(), "", "es", `{"idx":1,"text":"Hello"}`); err != nil {
+		t.Fatalf("TranslateBatch: %v", err)
+	}
+	if gotFormat {
+		t.Fatalf("expected no response_format in ndjson mode")
+	}
+}
+
+func TestResolveBaseURLForModel_LocalPrefixes(t *testing.T) {
+	got, err := resolveBaseURLForModel("ollama:llama3.1", "")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got != DefaultOllamaBaseURL {
+		t.Fatalf("got %q", got)
+	}
+	got, err = resolveBaseURLForModel("LMStudio:qwen", "")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got != DefaultLMStudioBaseURL {
+		t.Fatalf("got %q", got)
+	}
+	if name := requestModelName("ollama:llama3.1:8b"); name != "llama3.1:8b" {
+		t.Fatalf("requestModelName: got %q", name)
+	}
+}
+
+func TestOpenAIClient_LocalModel_NoAPIKeyAndValidateModel(t *testing.T) {
+	var paths []string
+	var sentModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no Authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/models" {
+			_, _ = w.Write([]byte(`{"data":[{"id":"llama3.1:latest"},{"id":"qwen2.5"}]}`))
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sentModel, _ = body["model"].(string)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	c := OpenAIClient{BaseURL: server.URL + "/v1", Model: "ollama:llama3.1", RetryOptions: RetryOptions{MaxAttempts: 1}}
+	if err := (&c).ValidateModel(t.Context()); err != nil {
+		t.Fatalf("ValidateModel: %v", err)
+	}
+	if _, err := (&c).TranslateBatch(t.Context(), "", "es", `{"idx":1,"text":"Hello"}`); err != nil {
+		t.Fatalf("TranslateBatch: %v", err)
+	}
+	if sentModel != "llama3.1" {
+		t.Fatalf("expected prefix to be stripped, got %q", sentModel)
+	}
+	if len(paths) != 2 || paths[1] != "/v1/chat/completions" {
+		t.Fatalf("unexpected request paths: %v", paths)
+	}
+
+	missing := OpenAIClient{BaseURL: server.URL + "/v1", Model: "ollama:mistral"}
+	if err := (&missing).ValidateModel(t.Context()); err == nil {
+		t.Fatalf("expected error for missing model")
+	}
+}
+
+func TestOpenAIClient_PromptCacheHints(t *testing.T) {
+	payloadA, _ := FormatForTranslation([]int{1}, []string{"Goodnight"})
+	payloadB, _ := FormatForTranslation([]int{2}, []string{"Bye"})
+
+	c := &OpenAIClient{Model: "gpt-5"}
+	a, err := c.buildRequest("en", "es", payloadA, true)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	b, _ := c.buildRequest("en", "es", payloadB, true)
+	if a.PromptCacheKey == "" || a.PromptCacheKey != b.PromptCacheKey {
+		t.Fatalf("expected the same prompt_cache_key for both batches, got %q and %q", a.PromptCacheKey, b.PromptCacheKey)
+	}
+	for _, c := range []*OpenAIClient{
+		{Model: "gpt-5", SkipPromptCache: true},
+		{Model: "gpt-5", BaseURL: "http://localhost:8080"},
+	} {
+		if req, _ := c.buildRequest("en", "es", payloadA, true); req.PromptCacheKey != "" {
+			t.Fatalf("unexpected prompt_cache_key for %+v", c)
+		}
+	}
+
+	c = &OpenAIClient{Model: "anthropic/claude-sonnet-4", BaseURL: "http://localhost:8080"}
+	req, _ := c.buildRequest("en", "es", payloadA, false)
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got struct {
+		Messages []struct {
+			Content []chatContentPart `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("expected messages with content parts: %v\n%s", err, body)
+	}
+	user := got.Messages[1].Content
+	if len(user) != 2 || user[0].CacheControl == nil || user[1].CacheControl != nil {
+		t.Fatalf("expected a cache breakpoint after the instructions, got %+v", user)
+	}
+	if strings.Contains(user[0].Text, "Goodnight") || !strings.Contains(user[1].Text, "Goodnight") {
+		t.Fatalf("expected the cues after the breakpoint, got %+v", user)
+	}
+	if got.Messages[0].Content[0].CacheControl == nil {
+		t.Fatalf("expected the system message to be cached, got %+v", got.Messages[0].Content)
+	}
+}
@@ -0,0 +1,155 @@
+package translate
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/progress"
+)
+
+// progressWindow is the number of completed batches used to estimate the ETA.
+const progressWindow = 10
+
+// Progress is a snapshot of the translation of one target language.
+type Progress struct {
+	TargetLanguage   string
+	CompletedBatches int
+	TotalBatches     int
+	CuesDone         int
+	TotalCues        int // cues sent to the provider (cached cues are not counted)
+	// TokensUsed is the total reported by the provider in the response usage
+	// (0 for providers that don't report it, such as DeepL).
+	TokensUsed int64
+	Elapsed    time.Duration
+	ETA        time.Duration // 0 until it can be estimated
+}
+
+// ProgressFunc receives a Progress when a target starts, after every completed
+// batch and when the target is done. Targets are translated concurrently, so it
+// must be safe for concurrent use; calls for the same target are sequential.
+type ProgressFunc func(Progress)
+
+// progressTracker aggregates the progress of one target and counts the tokens
+// used. Without a ProgressFunc it only counts the tokens; a nil tracker does
+// nothing.
+type progressTracker struct {
+	fn             ProgressFunc
+	targetLanguage string
+	totalBatches   int
+	totalCues      int
+	started        time.Time
+	tokens         atomic.Int64
+	cachedTokens   atomic.Int64
+
+	mu               sync.Mutex
+	completedBatches int
+	cuesDone         int
+	throughput       *progress.Throughput
+}
+
+func newProgressTracker(fn ProgressFunc, targetLanguage string, batches []batch) *progressTracker {
+	p := &progressTracker{
+		fn:             fn,
+		targetLanguage: targetLanguage,
+		totalBatches:   len(batches),
+		started:        time.Now(),
+		throughput:     progress.NewThroughput(progressWindow),
+	}
+	for _, b := range batches {
+		p.totalCues += len(b.idxs)
+	}
+	p.throughput.Add(p.started, 0)
+	return p
+}
+
+// start reports the initial (empty) progress.
+func (p *progressTracker) start() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report()
+}
+
+func (p *progressTracker) batchDone(cues int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completedBatches++
+	p.cuesDone += cues
+	p.throughput.Add(time.Now(), p.cuesDone)
+	p.report()
+}
+
+// finish reports the final progress, including the tokens used after the last
+// batch (review and shortening requests).
+func (p *progressTracker) finish() {
+	p.start()
+}
+
+func (p *progressTracker) report() {
+	if p.fn == nil {
+		return
+	}
+	p.fn(Progress{
+		TargetLanguage:   p.targetLanguage,
+		CompletedBatches: p.completedBatches,
+		TotalBatches:     p.totalBatches,
+		CuesDone:         p.cuesDone,
+		TotalCues:        p.totalCues,
+		TokensUsed:       p.tokens.Load(),
+		Elapsed:          time.Since(p.started),
+		ETA:              p.throughput.ETA(p.totalCues - p.cuesDone),
+	})
+}
+
+type tokenCounterKey struct{}
+
+// tokensUsed returns the tokens reported by the provider so far.
+func (p *progressTracker) tokensUsed() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.tokens.Load()
+}
+
+// withTokenCounter returns a context whose chat completions add the tokens
+// reported by the provider to p.
+func withTokenCounter(ctx context.Context, p *progressTracker) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenCounterKey{}, p)
+}
+
+// tokenUsage is the usage reported by the provider for a response.
+type tokenUsage struct {
+	total  int
+	cached int // prompt tokens read from the prompt cache of the provider
+}
+
+// cachedTokensUsed returns the prompt tokens read from the prompt cache so far.
+func (p *progressTracker) cachedTokensUsed() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.cachedTokens.Load()
+}
+
+func reportTokenUsage(ctx context.Context, usage tokenUsage) {
+	p, _ := ctx.Value(tokenCounterKey{}).(*progressTracker)
+	if p == nil {
+		return
+	}
+	if usage.total > 0 {
+		p.tokens.Add(int64(usage.total))
+	}
+	if usage.cached > 0 {
+		p.cachedTokens.Add(int64(usage.cached))
+	}
+}
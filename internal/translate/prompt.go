@@ -0,0 +1,267 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// promptSystemTemplateName is the optional template block that overrides the
+// system message in a custom prompt template.
+const promptSystemTemplateName = "system"
+
+const defaultSystemPrompt = "You are a translation engine. Output must follow the requested format exactly. Do not add commentary."
+
+// defaultPromptTemplate renders the user message when no --prompt-file is set.
+const defaultPromptTemplate = "" +
+	"Translate the following subtitles{{if .SourceLanguage}} from `{{.SourceLanguage}}`{{end}} to: `{{.TargetLanguage}}`\n" +
+	"\n" +
+	"{{with .Glossary}}Glossary (always use these translations):\n{{.}}\n\n{{end}}" +
+	"{{with .SeriesContext}}Names and terms from earlier episodes (translate them the same way):\n{{.}}\n\n{{end}}" +
+	"Rules:\n" +
+	"- Output MUST contain the same number of items as the input.\n" +
+	"- Preserve idx values exactly and do not reorder.\n" +
+	"{{if .HasPlaceholders}}- Keep placeholders like ⟦1⟧ unchanged, each exactly once, around the words they format.\n{{end}}" +
+	"{{.FormatRules}}" +
+	"{{.LengthRules}}" +
+	"{{.LineBreakRules}}" +
+	"- Do not output markdown, code fences, headers, or explanations.\n" +
+	"\n" +
+	"Example:\n" +
+	"Input:\n" +
+	"{{.ExampleInput}}" +
+	"Output:\n" +
+	"{{.ExampleOutput}}" +
+	"\n" +
+	"Input:\n\n{{.Input}}\n"
+
+const promptFormatRulesNDJSON = "" +
+	"- Output MUST be NDJSON: one JSON object per line (no surrounding array).\n" +
+	"- Each output line MUST be valid JSON with exactly two keys: idx (number) and text (string).\n"
+
+const promptFormatRulesStructured = "" +
+	"- Output MUST be a single JSON object with an `items` array.\n" +
+	"- Each item MUST have exactly two keys: idx (number) and text (string).\n"
+
+// promptLengthRules is added when the input carries line limits (see
+// Options.LengthHints).
+const promptLengthRules = "" +
+	"- Items with max_len: break the text into lines (\\n) of at most max_len characters, not counting tags or placeholders. Full-width characters (Chinese, Japanese, Korean) count as two.\n" +
+	"- Items with max_lines: use at most max_lines lines. Rephrase more concisely when the translation does not fit.\n" +
+	"- Do not copy max_len or max_lines to the output.\n"
+
+const promptExampleInput = "" +
+	"{\"idx\":1,\"text\":\"Hello\\nworld\"}\n" +
+	"{\"idx\":2,\"text\":\"How are you?\"}\n"
+
+const promptExampleOutputNDJSON = "" +
+	"{\"idx\":1,\"text\":\"Hola\\nmundo\"}\n" +
+	"{\"idx\":2,\"text\":\"¿Cómo estás?\"}\n"
+
+const promptExampleOutputStructured = "" +
+	"{\"items\":[{\"idx\":1,\"text\":\"Hola\\nmundo\"},{\"idx\":2,\"text\":\"¿Cómo estás?\"}]}\n"
+
+var defaultPrompt = template.Must(template.New("prompt").Parse(defaultPromptTemplate))
+
+// PromptData holds the variables available to prompt templates.
+type PromptData struct {
+	SourceLanguage    string // human-friendly label; empty when auto-detected
+	SourceLanguageTag string // normalized tag (e.g. en-US); empty when auto-detected
+	TargetLanguage    string // human-friendly label
+	TargetLanguageTag string // normalized tag (e.g. es-419)
+	Glossary          string // glossary file contents, if any
+	SeriesContext     string // names and terms learned from earlier files, one per line; empty without a SeriesContext
+	Style             string // style instruction (known styles expanded)
+	Audience          string
+	Notes             string
+	LanguageHint      string // regional vocabulary hint for the target language
+	SDH               string // SDH instruction; empty unless an SDH mode asks for one
+	Localization      string // number and unit localization lines; empty unless asked for
+	Guidance          string // Style, Audience, Notes, LanguageHint, SDH and Localization as prompt lines
+
+	// FormatRules, ExampleInput and ExampleOutput describe the expected output
+	// shape for the active response mode (NDJSON or structured output).
+	FormatRules   string
+	ExampleInput  string
+	ExampleOutput string
+	// LengthRules asks the model to respect the line limits sent with each
+	// item; empty when the input has none.
+	LengthRules string
+	// LineBreakRules tells the model how to treat the line breaks of each
+	// item; empty with LineBreaksPreserve, where the example shows they are
+	// kept.
+	LineBreakRules string
+
+	Input string // NDJSON payload to translate
+	// HasPlaceholders reports whether Input contains inline tag placeholders.
+	HasPlaceholders bool
+}
+
+// PromptOptions customizes the prompt sent to chat models.
+type PromptOptions struct {
+	Template *template.Template // nil uses the built-in prompt
+	Glossary string
+
+	// Style, Audience and Notes are free-text guidance added to the system
+	// message. Known styles (see StyleFormal and friends) are expanded into
+	// fuller instructions.
+	Style    string
+	Audience string
+	Notes    string
+	// SDH is the SDH mode (SDHKeep and friends).
+	SDH string
+	// LineBreaks is the line break mode (LineBreaksPreserve or
+	// LineBreaksReflow).
+	LineBreaks string
+	// LocalizeNumbers and ConvertUnits add the localization guidance (see
+	// Options).
+	LocalizeNumbers bool
+	ConvertUnits    bool
+	// SeriesContext supplies the names and terms learned from the files
+	// translated before; nil adds none.
+	SeriesContext *SeriesContext
+}
+
+// loadPromptTemplate parses a Go text/template file. The main template renders
+// the user message and must reference {{.Input}}; a {{define "system"}} block,
+// if present, replaces the default system message.
+func loadPromptTemplate(path string) (*template.Template, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read prompt file: %w", err)
+	}
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt file %s: %w", path, err)
+	}
+
+	const sentinel = "\x00input\x00"
+	msgs, err := buildPrompt(PromptOptions{Template: tmpl}, "en", "es", sentinel, false)
+	if err != nil {
+		return nil, fmt.Errorf("render prompt file %s: %w", path, err)
+	}
+	if !strings.Contains(msgs[len(msgs)-1].Content, sentinel) {
+		return nil, fmt.Errorf("prompt file %s must include {{.Input}}", path)
+	}
+	return tmpl, nil
+}
+
+func buildPrompt(settings PromptOptions, sourceLanguage string, targetLanguage string, input string, structured bool) ([]ChatMessage, error) {
+	sourceTag, _ := normalizeTargetLanguage(sourceLanguage)
+	targetTag, _ := normalizeTargetLanguage(targetLanguage)
+	data := PromptData{
+		SourceLanguage:    normalizeTargetLanguageLabel(sourceLanguage),
+		SourceLanguageTag: sourceTag,
+		TargetLanguage:    normalizeTargetLanguageLabel(targetLanguage),
+		TargetLanguageTag: targetTag,
+		Glossary:          strings.TrimSpace(settings.Glossary),
+		SeriesContext:     settings.SeriesContext.summary(targetLanguage),
+		Style:             styleInstruction(settings.Style),
+		Audience:          strings.TrimSpace(settings.Audience),
+		Notes:             strings.TrimSpace(settings.Notes),
+		LanguageHint:      languageVocabularyHint(targetLanguage),
+		SDH:               sdhInstruction(settings.SDH),
+		Localization:      localizationGuidance(settings, targetLanguage),
+		LineBreakRules:    lineBreakRules(settings.LineBreaks),
+		FormatRules:       promptFormatRulesNDJSON,
+		ExampleInput:      promptExampleInput,
+		ExampleOutput:     promptExampleOutputNDJSON,
+		Input:             input,
+		HasPlaceholders:   strings.Contains(input, tagPlaceholderOpen),
+	}
+	if strings.Contains(input, `"max_len":`) || strings.Contains(input, `"max_lines":`) {
+		data.LengthRules = promptLengthRules
+	}
+	data.Guidance = promptGuidance(data)
+	if structured {
+		data.FormatRules = promptFormatRulesStructured
+		data.ExampleOutput = promptExampleOutputStructured
+	}
+
+	tmpl := settings.Template
+	if tmpl == nil {
+		tmpl = defaultPrompt
+	}
+
+	var user strings.Builder
+	if err := tmpl.Execute(&user, data); err != nil {
+		return nil, err
+	}
+	system := defaultSystemPrompt
+	if data.Guidance != "" {
+		system += "\n\n" + data.Guidance
+	}
+	if t := tmpl.Lookup(promptSystemTemplateName); t != nil {
+		var sb strings.Builder
+		if err := t.Execute(&sb, data); err != nil {
+			return nil, err
+		}
+		system = strings.TrimSpace(sb.String())
+	}
+	if strings.TrimSpace(user.String()) == "" {
+		return nil, errors.New("prompt template rendered an empty user message")
+	}
+
+	// The cues come last in the built-in prompt, after the instructions.
+	static := max(strings.Index(user.String(), input), 0)
+	systemStatic := len(system)
+	if strings.Contains(system, input) {
+		systemStatic = 0
+	}
+	return []ChatMessage{
+		{Role: "system", Content: system, static: systemStatic},
+		{Role: "user", Content: user.String(), static: static},
+	}, nil
+}
+
+// Known translation styles.
+const (
+	StyleFormal     = "formal"
+	StyleInformal   = "informal"
+	StyleColloquial = "colloquial"
+	StyleNeutral    = "neutral"
+)
+
+var styleInstructions = map[string]string{
+	StyleFormal:     "Use a formal register and polite forms of address (e.g. \"usted\" in Spanish, \"Sie\" in German, \"vous\" in French).",
+	StyleInformal:   "Use an informal register and familiar forms of address (e.g. \"tú\"/\"vos\" in Spanish, \"du\" in German, \"tu\" in French).",
+	StyleColloquial: "Use natural, colloquial spoken language, including everyday idioms and contractions where a native speaker would use them.",
+	StyleNeutral:    "Use a neutral register and avoid slang or region-specific idioms.",
+}
+
+// styleInstruction expands a known style into a full instruction. Unknown
+// values are passed through as free text.
+func styleInstruction(style string) string {
+	style = strings.TrimSpace(style)
+	if s, ok := styleInstructions[strings.ToLower(style)]; ok {
+		return s
+	}
+	return style
+}
+
+// promptGuidance renders the optional style/audience/notes lines appended to
+// the system message.
+func promptGuidance(data PromptData) string {
+	var lines []string
+	if data.Style != "" {
+		lines = append(lines, "Style: "+data.Style)
+	}
+	if data.Audience != "" {
+		lines = append(lines, "Audience: "+data.Audience)
+	}
+	if data.LanguageHint != "" {
+		lines = append(lines, "Language variant: "+data.LanguageHint)
+	}
+	if data.Notes != "" {
+		lines = append(lines, "Notes: "+data.Notes)
+	}
+	if data.SDH != "" {
+		lines = append(lines, "Hearing impaired: "+data.SDH)
+	}
+	if data.Localization != "" {
+		lines = append(lines, data.Localization)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,179 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildPrompt_DefaultTemplate(t *testing.T) {
+	msgs, err := buildPrompt(PromptOptions{Glossary: "Winter = Invierno\n"}, "", "es-419", "{\"idx\":1,\"text\":\"Hi\"}", false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	// The only guidance of the defaults is the vocabulary hint of es-419 (see
+	// TestBuildPrompt_LanguageVariant).
+	if len(msgs) != 2 || msgs[0].Content != defaultSystemPrompt+"\n\nLanguage variant: "+languageVocabularyHint("es-419") {
+		t.Fatalf("unexpected system message: %#v", msgs)
+	}
+	user := msgs[1].Content
+	if !strings.HasPrefix(user, "Translate the following subtitles to: `") {
+		t.Fatalf("unexpected prompt header:\n%s", user)
+	}
+	if !strings.Contains(user, "Glossary (always use these translations):\nWinter = Invierno\n\nRules:") {
+		t.Fatalf("expected glossary in prompt:\n%s", user)
+	}
+	if !strings.Contains(user, promptFormatRulesNDJSON) || !strings.HasSuffix(user, "{\"idx\":1,\"text\":\"Hi\"}\n") {
+		t.Fatalf("expected format rules and input in prompt:\n%s", user)
+	}
+}
+
+func TestBuildPrompt_LengthRules(t *testing.T) {
+	input, err := formatForTranslation([]int{1}, []string{"Hello there"}, lineLimits{maxLen: 42, maxLines: 2})
+	if err != nil {
+		t.Fatalf("formatForTranslation: %v", err)
+	}
+	if input != `{"idx":1,"text":"Hello there","max_len":42,"max_lines":2}` {
+		t.Fatalf("unexpected payload: %s", input)
+	}
+	msgs, err := buildPrompt(PromptOptions{}, "", "es", input, false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if !strings.Contains(msgs[1].Content, promptLengthRules) {
+		t.Fatalf("expected length rules in prompt:\n%s", msgs[1].Content)
+	}
+
+	msgs, err = buildPrompt(PromptOptions{}, "", "es", `{"idx":1,"text":"Hello there"}`, false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if strings.Contains(msgs[1].Content, "max_len") {
+		t.Fatalf("expected no length rules without limits:\n%s", msgs[1].Content)
+	}
+}
+
+func TestLoadPromptTemplate_CustomSystemAndUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	tmpl := `{{define "system"}}You are a legal translator.{{end}}To {{.TargetLanguageTag}}:
+{{.FormatRules}}{{.Input}}`
+	if err := os.WriteFile(path, []byte(tmpl), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	parsed, err := loadPromptTemplate(path)
+	if err != nil {
+		t.Fatalf("loadPromptTemplate: %v", err)
+	}
+
+	msgs, err := buildPrompt(PromptOptions{Template: parsed}, "en", "pt_br", "PAYLOAD", true)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if msgs[0].Content != "You are a legal translator." {
+		t.Fatalf("unexpected system message: %q", msgs[0].Content)
+	}
+	want := "To pt-BR:\n" + promptFormatRulesStructured + "PAYLOAD"
+	if msgs[1].Content != want {
+		t.Fatalf("unexpected user message:\n%q\nwant:\n%q", msgs[1].Content, want)
+	}
+}
+
+func TestLoadPromptTemplate_Errors(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"missing-input": "Translate to {{.TargetLanguage}}",
+		"unknown-field": "{{.Nope}} {{.Input}}",
+		"syntax":        "{{.Input",
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name+".tmpl")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if _, err := loadPromptTemplate(path); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBuildPrompt_StyleAudienceNotesAndRegionHint(t *testing.T) {
+	opts := PromptOptions{Style: "Formal", Audience: "children", Notes: "Keep character names untranslated."}
+	msgs, err := buildPrompt(opts, "en", "es-AR", "PAYLOAD", false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	system := msgs[0].Content
+	for _, want := range []string{
+		defaultSystemPrompt + "\n\n",
+		"Style: " + styleInstructions[StyleFormal],
+		"Audience: children",
+		"Language variant: Rioplatense Spanish",
+		"Notes: Keep character names untranslated.",
+	} {
+		if !strings.Contains(system, want) {
+			t.Fatalf("expected %q in system message:\n%s", want, system)
+		}
+	}
+
+	msgs, err = buildPrompt(PromptOptions{Style: "like a pirate"}, "", "ja", "PAYLOAD", false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if !strings.HasSuffix(msgs[0].Content, "\n\nStyle: like a pirate") {
+		t.Fatalf("expected free-text style, got:\n%s", msgs[0].Content)
+	}
+}
+
+func TestBuildPrompt_LanguageVariant(t *testing.T) {
+	for target, want := range map[string]string{
+		"es-419": "\n\nLanguage variant: Neutral Latin American Spanish",
+		"es-AR":  "\n\nLanguage variant: Rioplatense Spanish",
+		"fr":     "",
+	} {
+		msgs, err := buildPrompt(PromptOptions{}, "", target, "PAYLOAD", false)
+		if err != nil {
+			t.Fatalf("buildPrompt: %v", err)
+		}
+		system := msgs[0].Content
+		ok := system == defaultSystemPrompt
+		if want != "" {
+			ok = strings.HasPrefix(system, defaultSystemPrompt+want)
+		}
+		if !ok {
+			t.Fatalf("%s: expected the language variant %q after the system prompt, got:\n%s", target, want, system)
+		}
+	}
+}
+
+func TestLanguageVocabularyHint(t *testing.T) {
+	cases := map[string]string{
+		"es_ar": "Rioplatense",
+		"es-ES": "Peninsular",
+		"es-CO": "Neutral Latin American",
+		"en-gb": "British",
+		"de":    "",
+	}
+	for in, want := range cases {
+		got := languageVocabularyHint(in)
+		if want == "" && got != "" || !strings.HasPrefix(got, want) {
+			t.Fatalf("%s: expected hint starting with %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestBuildPrompt_SDHMode(t *testing.T) {
+	for mode, want := range map[string]string{SDHStrip: "leave out sound descriptions", SDHGenerate: "speaker label in uppercase"} {
+		msgs, err := buildPrompt(PromptOptions{SDH: mode}, "en", "es", "{\"idx\":1,\"text\":\"Hi\"}", false)
+		if err != nil {
+			t.Fatalf("buildPrompt: %v", err)
+		}
+		if system := msgs[0].Content; !strings.Contains(system, "Hearing impaired: ") || !strings.Contains(system, want) {
+			t.Fatalf("%s: expected the SDH instruction in the system message:\n%s", mode, system)
+		}
+	}
+	msgs, err := buildPrompt(PromptOptions{SDH: SDHKeep}, "en", "fr", "{\"idx\":1,\"text\":\"Hi\"}", false)
+	if err != nil || msgs[0].Content != defaultSystemPrompt {
+		t.Fatalf("keep: unexpected system message %q (err %v)", msgs[0].Content, err)
+	}
+}
@@ -0,0 +1,102 @@
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// promptCacheKeyPrefix prefixes the prompt_cache_key sent to the OpenAI API.
+const promptCacheKeyPrefix = "subtitle-tools-"
+
+// openAIAPIHost is the host of the OpenAI API, the only OpenAI-compatible API
+// known to accept prompt_cache_key.
+const openAIAPIHost = "api.openai.com"
+
+// chatContentPart is a part of a message sent as a list of parts, the form
+// that carries a cache_control breakpoint or images.
+type chatContentPart struct {
+	Type         string            `json:"type"`
+	Text         string            `json:"text,omitempty"`
+	ImageURL     *chatImageURL     `json:"image_url,omitempty"`
+	CacheControl *chatCacheControl `json:"cache_control,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"` // a data URL
+}
+
+type chatCacheControl struct {
+	Type string `json:"type"`
+}
+
+// MarshalJSON sends the content as a string, as a static part with a
+// cache_control breakpoint followed by the rest when cacheControl is set, or
+// as a text part followed by the images when it has any.
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type plain ChatMessage
+	if len(m.images) > 0 {
+		parts := []chatContentPart{{Type: "text", Text: m.Content}}
+		for _, u := range m.images {
+			parts = append(parts, chatContentPart{Type: "image_url", ImageURL: &chatImageURL{URL: u}})
+		}
+		return json.Marshal(struct {
+			Role    string            `json:"role"`
+			Content []chatContentPart `json:"content"`
+		}{Role: m.Role, Content: parts})
+	}
+	if !m.cacheControl || m.static <= 0 || m.static > len(m.Content) {
+		return json.Marshal(plain(m))
+	}
+	parts := []chatContentPart{{Type: "text", Text: m.Content[:m.static], CacheControl: &chatCacheControl{Type: "ephemeral"}}}
+	if rest := m.Content[m.static:]; rest != "" {
+		parts = append(parts, chatContentPart{Type: "text", Text: rest})
+	}
+	return json.Marshal(struct {
+		Role    string            `json:"role"`
+		Content []chatContentPart `json:"content"`
+	}{Role: m.Role, Content: parts})
+}
+
+// markPromptCache adds the hints that let the provider cache the instructions
+// repeated in every batch, which come first in the prompt: a prompt_cache_key
+// for the OpenAI API, which caches prompt prefixes on its own but routes the
+// requests with the same key to the same cache, and cache_control breakpoints
+// for Claude models (e.g. through OpenRouter or LiteLLM), which only cache the
+// marked prefixes. Gemini caches prompt prefixes implicitly.
+func (c *OpenAIClient) markPromptCache(req *chatCompletionsRequest) {
+	if isClaudeModel(c.Model) {
+		for i := range req.Messages {
+			req.Messages[i].cacheControl = req.Messages[i].static > 0
+		}
+		return
+	}
+	base, err := resolveBaseURLForModel(c.Model, c.BaseURL)
+	if err != nil {
+		return
+	}
+	if u, err := url.Parse(base); err == nil && strings.EqualFold(u.Hostname(), openAIAPIHost) {
+		req.PromptCacheKey = promptCacheKey(req.Messages)
+	}
+}
+
+// isClaudeModel reports whether model is an Anthropic Claude model, with or
+// without the vendor prefix of a router (e.g. anthropic/claude-sonnet-4).
+func isClaudeModel(model string) bool {
+	return strings.Contains(strings.ToLower(model), "claude")
+}
+
+// promptCacheKey returns a key shared by the requests with the same static
+// prompt prefix.
+func promptCacheKey(messages []ChatMessage) string {
+	h := sha256.New()
+	for _, m := range messages {
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Content[:min(max(m.static, 0), len(m.Content))]))
+		h.Write([]byte{0})
+	}
+	return promptCacheKeyPrefix + hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,188 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Supported translation providers.
+const (
+	ProviderOpenAI = "openai" // any OpenAI-compatible chat completions API
+	ProviderGemini = "gemini" // the native Gemini API
+	ProviderDeepL  = "deepl"
+)
+
+const DefaultProvider = ProviderOpenAI
+
+// BatchTranslator translates one NDJSON payload (see FormatForTranslation) and
+// returns the raw output, which is later parsed with ParseTranslatedLines.
+type BatchTranslator interface {
+	TranslateBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error)
+}
+
+func normalizeProvider(provider string) string {
+	return strings.ToLower(strings.TrimSpace(provider))
+}
+
+func isValidProvider(provider string) bool {
+	return provider == ProviderOpenAI || provider == ProviderGemini || provider == ProviderDeepL
+}
+
+// namedTranslator pairs a client with a label used in logs (model or provider).
+type namedTranslator struct {
+	name   string
+	client BatchTranslator
+	// breaker pauses the requests to the provider during an outage (nil when
+	// disabled).
+	breaker *circuitBreaker
+}
+
+// keepsTags reports whether the provider keeps the inline tags itself, so they
+// are sent as-is instead of as placeholders: DeepL, in XML tag handling mode.
+func (p namedTranslator) keepsTags() bool {
+	_, ok := p.client.(*DeepLClient)
+	return ok
+}
+
+// newBatchTranslators returns the primary client followed by one chat client
+// per fallback model (see chatProvider).
+func newBatchTranslators(opts Options) ([]namedTranslator, error) {
+	prompt, err := loadPromptOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	primary, err := newBatchTranslator(opts, prompt)
+	if err != nil {
+		return nil, err
+	}
+	name := opts.Model
+	if opts.Provider == ProviderDeepL {
+		name = opts.Provider
+	}
+	providers := []namedTranslator{{name: name, client: primary, breaker: newCircuitBreaker(name, opts.CircuitBreakerThreshold, opts.CircuitBreakerCooldown)}}
+
+	for i, model := range opts.FallbackModels {
+		fallbackOpts := opts
+		fallbackOpts.Model = model
+		fallbackOpts.BaseURL = ""
+		if i < len(opts.FallbackBaseURLs) {
+			fallbackOpts.BaseURL = opts.FallbackBaseURLs[i]
+		}
+		fallbackOpts.Provider = chatProvider(model, fallbackOpts.BaseURL)
+		fallbackOpts.APIKey = ""
+		if i < len(opts.FallbackAPIKeys) {
+			fallbackOpts.APIKey = opts.FallbackAPIKeys[i]
+		}
+		if fallbackOpts.APIKey, err = fallbackAPIKey(opts, fallbackOpts); err != nil {
+			return nil, err
+		}
+		client, err := newBatchTranslator(fallbackOpts, prompt)
+		if err != nil {
+			return nil, err
+		}
+		breaker := newCircuitBreaker(model, opts.CircuitBreakerThreshold, opts.CircuitBreakerCooldown)
+		providers = append(providers, namedTranslator{name: model, client: client, breaker: breaker})
+	}
+	return providers, nil
+}
+
+// fallbackAPIKey returns the api key of fallback: its own, or the one of
+// primary when both are sent to the same API, since a key is never sent to
+// another provider. Local models need none.
+func fallbackAPIKey(primary, fallback Options) (string, error) {
+	if fallback.APIKey != "" {
+		return fallback.APIKey, nil
+	}
+	if api := apiEndpoint(primary); api != "" && api == apiEndpoint(fallback) {
+		return primary.APIKey, nil
+	}
+	if prefix, _ := splitLocalModel(fallback.Model); prefix != "" || primary.APIKey == "" {
+		return "", nil
+	}
+	return "", fmt.Errorf("fallback model %q is not sent to the api of the primary model; set its own api key", fallback.Model)
+}
+
+// apiEndpoint identifies the API the requests of opts are sent to (empty when
+// the base URL can't be resolved).
+func apiEndpoint(opts Options) string {
+	base := opts.BaseURL
+	if opts.Provider == ProviderOpenAI {
+		var err error
+		if base, err = resolveBaseURLForModel(opts.Model, opts.BaseURL); err != nil {
+			return ""
+		}
+	}
+	return opts.Provider + " " + strings.TrimRight(strings.TrimSpace(base), "/")
+}
+
+// loadPromptOptions reads the optional prompt template and glossary files.
+func loadPromptOptions(opts Options) (PromptOptions, error) {
+	prompt := PromptOptions{Style: opts.Style, Audience: opts.Audience, Notes: opts.Notes, SDH: opts.SDH, LineBreaks: opts.LineBreaks,
+		LocalizeNumbers: opts.LocalizeNumbers, ConvertUnits: opts.ConvertUnits, SeriesContext: opts.SeriesContext}
+	if opts.PromptFile != "" {
+		tmpl, err := loadPromptTemplate(opts.PromptFile)
+		if err != nil {
+			return PromptOptions{}, err
+		}
+		prompt.Template = tmpl
+	}
+	if opts.GlossaryFile != "" {
+		b, err := os.ReadFile(opts.GlossaryFile)
+		if err != nil {
+			return PromptOptions{}, fmt.Errorf("read glossary file: %w", err)
+		}
+		prompt.Glossary = string(b)
+	}
+	return prompt, nil
+}
+
+func newBatchTranslator(opts Options, prompt PromptOptions) (BatchTranslator, error) {
+	retryOptions := DefaultRetryOptions()
+	retryOptions.MaxAttempts = opts.RetryMaxAttempts
+
+	switch opts.Provider {
+	case ProviderOpenAI:
+		return &OpenAIClient{
+			BaseURL: opts.BaseURL, APIKey: opts.APIKey, Model: opts.Model,
+			Transport:       opts.Transport,
+			Timeout:         opts.RequestTimeout,
+			RetryOptions:    retryOptions,
+			ResponseMode:    opts.ResponseMode,
+			KeyRPS:          opts.KeyRPS,
+			Prompt:          prompt,
+			Sampling:        opts.sampling(),
+			Stream:          opts.Stream,
+			SkipPromptCache: opts.SkipPromptCache,
+		}, nil
+	case ProviderGemini:
+		if opts.Stream {
+			slog.Warn("streaming is not supported by the native gemini api; sending regular requests", "model", opts.Model)
+		}
+		return &GeminiClient{
+			BaseURL: opts.BaseURL, APIKey: opts.APIKey, Model: opts.Model,
+			Transport:       opts.Transport,
+			Timeout:         opts.RequestTimeout,
+			RetryOptions:    retryOptions,
+			ResponseMode:    opts.ResponseMode,
+			KeyRPS:          opts.KeyRPS,
+			Prompt:          prompt,
+			Sampling:        opts.sampling(),
+			SafetyThreshold: opts.SafetyThreshold,
+		}, nil
+	case ProviderDeepL:
+		return &DeepLClient{
+			Transport:    opts.Transport,
+			BaseURL:      opts.BaseURL,
+			APIKey:       opts.APIKey,
+			Formality:    deeplFormalityForStyle(opts.Formality, opts.Style),
+			KeyRPS:       opts.KeyRPS,
+			Timeout:      opts.RequestTimeout,
+			RetryOptions: retryOptions,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q", opts.Provider)
+	}
+}
@@ -0,0 +1,78 @@
+package translate
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRateLimitPause caps how long a key is held after the provider reports
+// its rate limit exhausted, in case of a reset far away (e.g. a daily quota).
+const maxRateLimitPause = 2 * time.Minute
+
+// rateLimitStatus is the state of the rate limit of an API key reported in the
+// x-ratelimit-* headers of OpenAI-compatible APIs (also sent by Groq,
+// OpenRouter and others): the requests and tokens left in the current window
+// and the time until each is replenished.
+type rateLimitStatus struct {
+	remainingRequests int // -1 when not reported
+	remainingTokens   int // -1 when not reported
+	resetRequests     time.Duration
+	resetTokens       time.Duration
+}
+
+func parseRateLimitHeaders(h http.Header) rateLimitStatus {
+	return rateLimitStatus{
+		remainingRequests: rateLimitCount(h.Get("X-Ratelimit-Remaining-Requests")),
+		remainingTokens:   rateLimitCount(h.Get("X-Ratelimit-Remaining-Tokens")),
+		resetRequests:     rateLimitReset(h.Get("X-Ratelimit-Reset-Requests")),
+		resetTokens:       rateLimitReset(h.Get("X-Ratelimit-Reset-Tokens")),
+	}
+}
+
+// pause returns how long to hold the key so its next request doesn't get a
+// 429: until the requests are replenished when none is left, or the tokens
+// when fewer are left than nextTokens (the tokens of a request like the last
+// one). It returns 0 when there is room.
+func (s rateLimitStatus) pause(nextTokens int) time.Duration {
+	var d time.Duration
+	if s.remainingRequests == 0 {
+		d = s.resetRequests
+	}
+	if s.remainingTokens >= 0 && s.remainingTokens < nextTokens {
+		d = max(d, s.resetTokens)
+	}
+	return min(d, maxRateLimitPause)
+}
+
+// retryAfter returns how long to wait after a 429 without Retry-After: until
+// the requests are replenished when none is left, or the tokens otherwise.
+func (s rateLimitStatus) retryAfter() time.Duration {
+	if s.remainingRequests == 0 {
+		return min(s.resetRequests, maxRateLimitPause)
+	}
+	return min(s.resetTokens, maxRateLimitPause)
+}
+
+// rateLimitCount parses a remaining count (-1 when absent or invalid).
+func rateLimitCount(v string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// rateLimitReset parses a reset time, a duration such as "1s", "6m0s" or
+// "20ms", or a number of seconds (0 when absent or invalid).
+func rateLimitReset(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if d, err := time.ParseDuration(v); err == nil {
+		return max(d, 0)
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package translate
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryDelayFromHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"seconds", http.Header{"Retry-After": {"3"}}, 3 * time.Second, 3 * time.Second},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"1"}}, 250 * time.Millisecond, 250 * time.Millisecond},
+		{"http date", http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}, 58 * time.Second, time.Minute},
+		{"past date", http.Header{"Retry-After": {"Wed, 21 Oct 2015 07:28:00 GMT"}}, 0, 0},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0, 0},
+		{"absent", http.Header{}, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := retryDelayFromHeader(tt.header); got < tt.min || got > tt.max {
+			t.Errorf("%s: got %v, want between %v and %v", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRateLimitStatus(t *testing.T) {
+	h := http.Header{}
+	h.Set("x-ratelimit-remaining-requests", "0")
+	h.Set("x-ratelimit-reset-requests", "1.5s")
+	h.Set("x-ratelimit-remaining-tokens", "900")
+	h.Set("x-ratelimit-reset-tokens", "6m0s")
+	s := parseRateLimitHeaders(h)
+	if got := s.pause(100); got != 1500*time.Millisecond {
+		t.Fatalf("expected to wait for the requests, got %v", got)
+	}
+	if got := s.pause(1000); got != maxRateLimitPause {
+		t.Fatalf("expected to wait for the tokens (capped), got %v", got)
+	}
+	if got := s.retryAfter(); got != 1500*time.Millisecond {
+		t.Fatalf("unexpected retry after: %v", got)
+	}
+
+	h.Set("x-ratelimit-remaining-requests", "10")
+	h.Set("x-ratelimit-reset-tokens", "2")
+	s = parseRateLimitHeaders(h)
+	if got := s.pause(100); got != 0 {
+		t.Fatalf("expected no pause with room left, got %v", got)
+	}
+	if got := s.retryAfter(); got != 2*time.Second {
+		t.Fatalf("expected the reset of the tokens, got %v", got)
+	}
+	if got := parseRateLimitHeaders(http.Header{}).pause(100); got != 0 {
+		t.Fatalf("expected no pause without headers, got %v", got)
+	}
+}
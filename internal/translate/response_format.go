@@ -0,0 +1,105 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Response modes control how the model is asked to shape its output.
+const (
+	// ResponseModeAuto requests structured output (json_schema) and falls back to
+	// the NDJSON prompt when the provider rejects it.
+	ResponseModeAuto = "auto"
+	// ResponseModeNDJSON only relies on prompt instructions (one JSON object per line).
+	ResponseModeNDJSON = "ndjson"
+	// ResponseModeJSONSchema always requests structured output and never falls back.
+	ResponseModeJSONSchema = "json-schema"
+)
+
+const DefaultResponseMode = ResponseModeAuto
+
+// wireEnvelopeItemsKey is the top-level key used by the structured output schema.
+// JSON schema responses must be objects, so items are wrapped as {"items":[...]}.
+const wireEnvelopeItemsKey = "items"
+
+type responseFormat struct {
+	Type       string              `json:"type"`
+	JSONSchema *responseJSONSchema `json:"json_schema,omitempty"`
+}
+
+type responseJSONSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
+}
+
+type wireEnvelope struct {
+	Items []wireItem `json:"items"`
+}
+
+func normalizeResponseMode(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+func isValidResponseMode(mode string) bool {
+	return mode == ResponseModeAuto ||
+		mode == ResponseModeNDJSON ||
+		mode == ResponseModeJSONSchema
+}
+
+// subtitleResponseFormat returns the structured output definition that constrains
+// the model to the wire format. The same definition is accepted by OpenAI and by
+// Gemini's OpenAI-compatible endpoint (mapped to responseSchema).
+func subtitleResponseFormat() *responseFormat {
+	return &responseFormat{
+		Type: "json_schema",
+		JSONSchema: &responseJSONSchema{
+			Name:   "subtitle_translation",
+			Strict: true,
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					wireEnvelopeItemsKey: map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"idx":  map[string]any{"type": "integer"},
+								"text": map[string]any{"type": "string"},
+							},
+							"required":             []string{"idx", "text"},
+							"additionalProperties": false,
+						},
+					},
+				},
+				"required":             []string{wireEnvelopeItemsKey},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// parseWireEnvelope parses a structured output response ({"items":[...]}).
+// ok is false when the input is not an envelope, so callers can keep trying the
+// NDJSON parsers.
+func parseWireEnvelope(trim string) (res []ParsedLine, ok bool, err error) {
+	if !strings.HasPrefix(trim, "{") || !strings.Contains(trim, `"`+wireEnvelopeItemsKey+`"`) {
+		return nil, false, nil
+	}
+	var env wireEnvelope
+	if err := json.Unmarshal([]byte(trim), &env); err != nil {
+		return nil, false, nil
+	}
+	if len(env.Items) == 0 {
+		return nil, true, errNoTranslatedLinesParsed
+	}
+	res = make([]ParsedLine, 0, len(env.Items))
+	for i, it := range env.Items {
+		if it.Idx <= 0 {
+			return nil, true, fmt.Errorf("invalid idx in item #%d: %d", i+1, it.Idx)
+		}
+		res = append(res, ParsedLine{Idx: it.Idx, Text: it.Text})
+	}
+	return res, true, nil
+}
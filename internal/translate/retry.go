@@ -61,6 +61,11 @@ func isRetryableNetErr(err error) bool {
 	return errors.As(err, &ne) && (ne.Timeout() || ne.Temporary())
 }
 
+func isTimeoutNetErr(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
 var jitterMu sync.Mutex
 var jitterRng = rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -126,17 +131,23 @@ func requestWithRetry[T any](
 		o.MaxAttempts = 1
 	}
 
+	breaker := circuitBreakerFrom(ctx)
 	var lastErr error
 	for attempt := 1; attempt <= o.MaxAttempts; attempt++ {
 		if ctx.Err() != nil {
 			return zero, ctx.Err()
 		}
+		if err := breaker.wait(ctx); err != nil {
+			return zero, err
+		}
 
 		v, d := do(attempt)
+		breaker.record(d.err)
 		if d.err == nil {
 			return v, nil
 		}
 		lastErr = d.err
+		reportThrottle(ctx, d.err)
 
 		if d.retry && attempt < o.MaxAttempts {
 			delay := d.delay
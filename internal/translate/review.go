@@ -0,0 +1,328 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+	"golang.org/x/time/rate"
+)
+
+// Review modes for the QA pass that runs after translation.
+const (
+	ReviewModeOff    = ""
+	ReviewModeFix    = "fix"    // apply the reviewer's corrections and write the report
+	ReviewModeReport = "report" // only write the report; translations are kept as-is
+)
+
+// reviewReportSuffix is appended to the output path (without extension) when no
+// report path is given.
+const reviewReportSuffix = ".review.json"
+
+// batchReviewer is implemented by providers that can run the QA pass.
+type batchReviewer interface {
+	// ReviewBatch receives NDJSON lines {"idx","source","text"} and returns the
+	// raw NDJSON review lines {"idx","ok","issue","text"}.
+	ReviewBatch(ctx context.Context, sourceLanguage string, targetLanguage string, payload string) (string, error)
+}
+
+type reviewWireItem struct {
+	Idx    int    `json:"idx"`
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+type reviewVerdict struct {
+	Idx   int    `json:"idx"`
+	OK    *bool  `json:"ok"`
+	Issue string `json:"issue"`
+	Text  string `json:"text"`
+}
+
+// ReviewIssue is a cue flagged by the QA pass.
+type ReviewIssue struct {
+	Idx         int    `json:"idx"`
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+	Issue       string `json:"issue"`
+	Correction  string `json:"correction,omitempty"`
+	Applied     bool   `json:"applied"`
+}
+
+// ReviewReport is written as JSON after the QA pass.
+type ReviewReport struct {
+	TargetLanguage string        `json:"target_language"`
+	Mode           string        `json:"mode"`
+	Reviewed       int           `json:"reviewed"`
+	Flagged        []ReviewIssue `json:"flagged"`
+}
+
+const reviewSystemPrompt = "You are a meticulous subtitle translation reviewer. Output must follow the requested format exactly. Do not add commentary."
+
+const reviewPromptRules = "" +
+	"Rules:\n" +
+	"- Check each translation against its source for omissions, additions, mistranslations, wrong register and excessive length for a subtitle.\n" +
+	"- Output NDJSON: exactly one JSON object per input item, same idx, same order.\n" +
+	"- If the translation is acceptable, output {\"idx\":N,\"ok\":true}.\n" +
+	"- Otherwise output {\"idx\":N,\"ok\":false,\"issue\":\"short description\",\"text\":\"corrected translation\"}.\n" +
+	"- Keep line breaks (\\n), inline tags and placeholders like ⟦1⟧ in corrected text.\n" +
+	"- Do not output markdown, code fences, headers, or explanations.\n"
+
+func normalizeReviewMode(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+func isValidReviewMode(mode string) bool {
+	return mode == ReviewModeOff || mode == ReviewModeFix || mode == ReviewModeReport
+}
+
+func defaultReviewReportPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + reviewReportSuffix
+}
+
+func buildReviewPrompt(sourceLanguage, targetLanguage, input string) []ChatMessage {
+	user := "Review these subtitle translations"
+	if label := normalizeTargetLanguageLabel(sourceLanguage); label != "" {
+		user += " from `" + label + "`"
+	}
+	user += " to: `" + normalizeTargetLanguageLabel(targetLanguage) + "`\n\n" +
+		reviewPromptRules +
+		"\nInput:\n\n" + input + "\n"
+	return []ChatMessage{
+		{Role: "system", Content: reviewSystemPrompt},
+		{Role: "user", Content: user},
+	}
+}
+
+// parseReviewVerdicts extracts the review objects from the model output.
+func parseReviewVerdicts(out string) ([]reviewVerdict, error) {
+	out = stripCodeFences(strings.ReplaceAll(out, "\r\n", "\n"))
+	segs := extractJSONObjectSegmentsWithOffsets(out)
+	if len(segs) == 0 {
+		return nil, errors.New("empty review output")
+	}
+	res := make([]reviewVerdict, 0, len(segs))
+	for i, seg := range segs {
+		var v reviewVerdict
+		if err := json.Unmarshal([]byte(seg.JSON), &v); err != nil {
+			return nil, fmt.Errorf("invalid review object #%d: %w (obj=%q)", i+1, err, abbreviate(seg.JSON, AbbreviationMax))
+		}
+		if v.Idx <= 0 || v.OK == nil {
+			return nil, fmt.Errorf("invalid review object #%d: missing idx or ok (obj=%q)", i+1, abbreviate(seg.JSON, AbbreviationMax))
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// reviewer runs the QA pass over the batches translated in this run.
+type reviewer struct {
+	client         batchReviewer
+	limiter        *rate.Limiter
+	sourceLanguage string
+	targetLanguage string
+	mode           string
+	maxWorkers     int
+	parseRetry     RetryOptions
+	transcript     *transcript // optional
+}
+
+// review checks every translated cue of batches and returns the flagged ones.
+// In fix mode, accepted corrections are written back to translated.
+func (rv *reviewer) review(ctx context.Context, batches []batch, translated map[int]string) ([]ReviewIssue, error) {
+	var (
+		mu     sync.Mutex
+		issues []ReviewIssue
+		wg     sync.WaitGroup
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	sem := make(chan struct{}, max(1, rv.maxWorkers))
+
+	for _, b := range batches {
+		items := make([]reviewWireItem, 0, len(b.idxs))
+		for i, idx := range b.idxs {
+			if t, ok := translated[idx]; ok {
+				items = append(items, reviewWireItem{Idx: idx, Source: b.texts[i], Text: t})
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found, err := rv.reviewBatch(ctx, items)
+			if err != nil {
+				reportWorkerErrorAndCancel(cancel, errCh, err)
+				return
+			}
+			mu.Lock()
+			issues = append(issues, found...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if err := firstErr(errCh); err != nil {
+		return nil, err
+	}
+	if err := nonCanceledContextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(issues, func(a, b ReviewIssue) int { return a.Idx - b.Idx })
+	if rv.mode == ReviewModeFix {
+		for _, is := range issues {
+			if is.Applied {
+				translated[is.Idx] = is.Correction
+			}
+		}
+	}
+	return issues, nil
+}
+
+func (rv *reviewer) reviewBatch(ctx context.Context, items []reviewWireItem) ([]ReviewIssue, error) {
+	// Tags are sent unescaped so the model sees the cue as it is displayed.
+	var payload bytes.Buffer
+	enc := json.NewEncoder(&payload)
+	enc.SetEscapeHTML(false)
+	for _, it := range items {
+		if err := enc.Encode(it); err != nil {
+			return nil, err
+		}
+	}
+
+	byIdx := make(map[int]reviewWireItem, len(items))
+	for _, it := range items {
+		byIdx[it.Idx] = it
+	}
+
+	attempts := max(1, rv.parseRetry.MaxAttempts)
+	var verdicts []reviewVerdict
+	for attempt := 1; ; attempt++ {
+		if rv.limiter != nil {
+			if err := rv.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		entry := TranscriptEntry{
+			Time:           time.Now(),
+			Kind:           TranscriptKindReview,
+			TargetLanguage: rv.targetLanguage,
+			FirstIdx:       items[0].Idx,
+			LastIdx:        items[len(items)-1].Idx,
+			Cues:           len(items),
+			Attempt:        attempt,
+		}
+		request := strings.TrimSuffix(payload.String(), "\n")
+		resp, err := rv.client.ReviewBatch(ctx, rv.sourceLanguage, rv.targetLanguage, request)
+		entry.DurationMS = time.Since(entry.Time).Milliseconds()
+		if err != nil {
+			rv.transcript.record(entry, request, "", err)
+			if isBlocked(err) {
+				slog.Warn("content filters of the provider blocked the review of the cues; leaving them unreviewed", "first_idx", items[0].Idx, "last_idx", items[len(items)-1].Idx, "err", err)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("review batch: %w", err)
+		}
+		verdicts, err = parseReviewVerdicts(resp)
+		rv.transcript.record(entry, request, resp, err)
+		if err == nil {
+			break
+		}
+		if attempt >= attempts {
+			return nil, fmt.Errorf("review batch: %w", err)
+		}
+		slog.Warn("invalid review output; retrying batch", "attempt", attempt, "max_attempts", attempts, "err", err)
+		if err := sleepWithContext(ctx, computeBackoff(attempt, rv.parseRetry)); err != nil {
+			return nil, err
+		}
+	}
+
+	var issues []ReviewIssue
+	for _, v := range verdicts {
+		it, ok := byIdx[v.Idx]
+		if !ok || *v.OK {
+			continue
+		}
+		issue := ReviewIssue{
+			Idx:         v.Idx,
+			Source:      it.Source,
+			Translation: it.Text,
+			Issue:       strings.TrimSpace(v.Issue),
+			Correction:  v.Text,
+		}
+		// Never accept a correction that drops or breaks inline tags.
+		issue.Applied = strings.TrimSpace(v.Text) != "" && v.Text != it.Text &&
+			slices.Equal(sortedInlineTags(it.Source), sortedInlineTags(v.Text))
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+func sortedInlineTags(text string) []string {
+	tags := srt.InlineTagPattern.FindAllString(text, -1)
+	slices.Sort(tags)
+	return tags
+}
+
+func writeReviewReport(path string, report ReviewReport) error {
+	if report.Flagged == nil {
+		report.Flagged = []ReviewIssue{}
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	return fs.WriteFile(bytes.NewReader(b), path)
+}
+
+func (r ReviewReport) corrected() int {
+	if r.Mode != ReviewModeFix {
+		return 0
+	}
+	n := 0
+	for _, is := range r.Flagged {
+		if is.Applied {
+			n++
+		}
+	}
+	return n
+}
+
+// storeReviewCorrections replaces the cached translations of corrected cues so
+// later runs reuse the reviewed text.
+func storeReviewCorrections(cache *translationCache, issues []ReviewIssue) {
+	entries := make(map[string]string)
+	for _, is := range issues {
+		if is.Applied {
+			entries[is.Source] = is.Correction
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err := cache.store(entries); err != nil {
+		slog.Warn("failed to update translation cache", "err", err)
+	}
+}
@@ -0,0 +1,58 @@
+package translate
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseReviewVerdicts(t *testing.T) {
+	out := "```json\n{\"idx\":1,\"ok\":true}\n{\"idx\":2,\"ok\":false,\"issue\":\"omission\",\"text\":\"Adiós, amigo\"}\n```"
+	got, err := parseReviewVerdicts(out)
+	if err != nil {
+		t.Fatalf("parseReviewVerdicts: %v", err)
+	}
+	if len(got) != 2 || !*got[0].OK || *got[1].OK || got[1].Issue != "omission" || got[1].Text != "Adiós, amigo" {
+		t.Fatalf("unexpected verdicts: %+v", got)
+	}
+
+	if _, err := parseReviewVerdicts(`{"idx":1}`); err == nil {
+		t.Fatalf("expected error for verdict without ok")
+	}
+	if _, err := parseReviewVerdicts("looks good"); err == nil {
+		t.Fatalf("expected error for empty output")
+	}
+}
+
+type fakeReviewer struct {
+	out      string
+	payloads []string
+}
+
+func (f *fakeReviewer) ReviewBatch(_ context.Context, _ string, _ string, payload string) (string, error) {
+	f.payloads = append(f.payloads, payload)
+	return f.out, nil
+}
+
+func TestReviewer_RejectsCorrectionsThatChangeTags(t *testing.T) {
+	client := &fakeReviewer{out: "" +
+		`{"idx":1,"ok":false,"issue":"tone","text":"Hola"}` + "\n" +
+		`{"idx":2,"ok":false,"issue":"typo","text":"<i>Adiós</i>"}`}
+	rv := reviewer{client: client, targetLanguage: "es", mode: ReviewModeFix, maxWorkers: 1, parseRetry: RetryOptions{MaxAttempts: 1}}
+	translated := map[int]string{1: "<i>Holaa</i>", 2: "<i>Adios</i>"}
+	batches := []batch{{idxs: []int{1, 2}, texts: []string{"<i>Hello</i>", "<i>Bye</i>"}}}
+
+	issues, err := rv.review(context.Background(), batches, translated)
+	if err != nil {
+		t.Fatalf("review: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Applied || !issues[1].Applied {
+		t.Fatalf("expected only the tag-preserving correction to be applied, got %+v", issues)
+	}
+	if translated[1] != "<i>Holaa</i>" || translated[2] != "<i>Adiós</i>" {
+		t.Fatalf("unexpected translations after review: %v", translated)
+	}
+	if len(client.payloads) != 1 || !strings.Contains(client.payloads[0], `"source":"<i>Hello</i>"`) {
+		t.Fatalf("expected source and translation in the review payload, got %q", client.payloads)
+	}
+}
@@ -0,0 +1,92 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reasoning effort levels accepted by reasoning models.
+const (
+	ReasoningEffortNone    = "none"
+	ReasoningEffortMinimal = "minimal"
+	ReasoningEffortLow     = "low"
+	ReasoningEffortMedium  = "medium"
+	ReasoningEffortHigh    = "high"
+)
+
+// defaultTemperature keeps translations literal and repeatable on models that
+// accept it.
+const defaultTemperature = 0.0
+
+// SamplingOptions tunes chat completion requests. Zero values mean "use the
+// model default" (see resolveSampling).
+type SamplingOptions struct {
+	Temperature     *float64
+	TopP            *float64
+	MaxOutputTokens int
+	ReasoningEffort string
+}
+
+// samplingParams are the request fields derived from SamplingOptions.
+type samplingParams struct {
+	Temperature         *float64 `json:"temperature,omitempty"`
+	TopP                *float64 `json:"top_p,omitempty"`
+	MaxTokens           int      `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int      `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string   `json:"reasoning_effort,omitempty"`
+}
+
+func normalizeReasoningEffort(effort string) string {
+	return strings.ToLower(strings.TrimSpace(effort))
+}
+
+func validateSampling(s SamplingOptions) error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("invalid temperature %g (expected 0..2)", *s.Temperature)
+	}
+	if s.TopP != nil && (*s.TopP <= 0 || *s.TopP > 1) {
+		return fmt.Errorf("invalid top-p %g (expected >0..1)", *s.TopP)
+	}
+	if s.MaxOutputTokens < 0 {
+		return fmt.Errorf("invalid max output tokens %d", s.MaxOutputTokens)
+	}
+	switch normalizeReasoningEffort(s.ReasoningEffort) {
+	case "", ReasoningEffortNone, ReasoningEffortMinimal, ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh:
+		return nil
+	default:
+		return fmt.Errorf("invalid reasoning effort %q (supported: %s, %s, %s, %s, %s)", s.ReasoningEffort,
+			ReasoningEffortNone, ReasoningEffortMinimal, ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh)
+	}
+}
+
+// isReasoningModel reports whether model is an OpenAI reasoning model (o1, o3,
+// o4-mini, gpt-5...). These reject a custom temperature and expect
+// max_completion_tokens instead of max_tokens.
+func isReasoningModel(model string) bool {
+	m := strings.ToLower(requestModelName(model))
+	if strings.HasPrefix(m, "gpt-5") && !strings.Contains(m, "chat") {
+		return true
+	}
+	return len(m) >= 2 && m[0] == 'o' && m[1] >= '1' && m[1] <= '9'
+}
+
+// resolveSampling applies the per-model defaults to s. Explicit values are
+// always sent as given.
+func resolveSampling(model string, s SamplingOptions) samplingParams {
+	reasoning := isReasoningModel(model)
+	p := samplingParams{
+		Temperature:     s.Temperature,
+		TopP:            s.TopP,
+		ReasoningEffort: normalizeReasoningEffort(s.ReasoningEffort),
+	}
+	if p.Temperature == nil && !reasoning {
+		t := defaultTemperature
+		p.Temperature = &t
+	}
+	if reasoning {
+		p.MaxCompletionTokens = s.MaxOutputTokens
+	} else {
+		p.MaxTokens = s.MaxOutputTokens
+	}
+	return p
+}
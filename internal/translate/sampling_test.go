@@ -0,0 +1,68 @@
+package translate
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIsReasoningModel(t *testing.T) {
+	for model, want := range map[string]bool{
+		"o1-mini":           true,
+		"o3":                true,
+		"o4-mini":           true,
+		"gpt-5":             true,
+		"gpt-5-mini":        true,
+		"gpt-5-chat-latest": false,
+		"gpt-4o-mini":       false,
+		"ollama:llama3.1":   false,
+		"gemini-2.5-flash":  false,
+	} {
+		if got := isReasoningModel(model); got != want {
+			t.Fatalf("isReasoningModel(%q)=%v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestBuildRequestBody_Sampling(t *testing.T) {
+	body := func(c *OpenAIClient) string {
+		b, err := c.buildRequestBody("", "es", `{"idx":1,"text":"Hi"}`, false)
+		if err != nil {
+			t.Fatalf("buildRequestBody: %v", err)
+		}
+		return string(b)
+	}
+
+	got := body(&OpenAIClient{Model: "gpt-4o-mini", Sampling: SamplingOptions{MaxOutputTokens: 2048}})
+	if !strings.Contains(got, `"temperature":0`) || !strings.Contains(got, `"max_tokens":2048`) {
+		t.Fatalf("expected default temperature and max_tokens, got %s", got)
+	}
+
+	got = body(&OpenAIClient{Model: "o4-mini", Sampling: SamplingOptions{MaxOutputTokens: 2048, ReasoningEffort: "Low"}})
+	if strings.Contains(got, `"temperature"`) || !strings.Contains(got, `"max_completion_tokens":2048`) || !strings.Contains(got, `"reasoning_effort":"low"`) {
+		t.Fatalf("expected reasoning model params, got %s", got)
+	}
+
+	temp, topP := 0.7, 0.9
+	got = body(&OpenAIClient{Model: "gpt-4o-mini", Sampling: SamplingOptions{Temperature: &temp, TopP: &topP}})
+	var req map[string]any
+	if err := json.Unmarshal([]byte(got), &req); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if req["temperature"] != 0.7 || req["top_p"] != 0.9 {
+		t.Fatalf("expected explicit sampling values, got %s", got)
+	}
+}
+
+func TestValidateSampling(t *testing.T) {
+	bad := 3.0
+	if err := validateSampling(SamplingOptions{Temperature: &bad}); err == nil {
+		t.Fatalf("expected error for temperature out of range")
+	}
+	if err := validateSampling(SamplingOptions{ReasoningEffort: "extreme"}); err == nil {
+		t.Fatalf("expected error for unknown reasoning effort")
+	}
+	if err := validateSampling(SamplingOptions{ReasoningEffort: "high"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package translate
+
+import (
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// SDH modes: how the hearing-impaired annotations (sound descriptions,
+// speaker labels) of the subtitles are handled by the translation.
+const (
+	SDHKeep     = "keep"     // translate them as they are
+	SDHStrip    = "strip"    // leave them out, for a plain track from an SDH source
+	SDHGenerate = "generate" // produce SDH output: speaker labels and sound cues
+	DefaultSDH  = SDHKeep
+)
+
+// sdhInstructions are added to the prompt of chat models for each SDH mode.
+var sdhInstructions = map[string]string{
+	SDHStrip: "Produce subtitles for hearing viewers: leave out sound descriptions in brackets or parentheses " +
+		"(e.g. [door slams], (laughs)), music notes and speaker labels (e.g. JOHN:), and translate only the dialogue. " +
+		"Keep every idx; use an empty text for items that only describe sounds.",
+	SDHGenerate: "Produce SDH subtitles for deaf and hard-of-hearing viewers: keep and translate the sound descriptions " +
+		"in square brackets (e.g. [door slams]), and add a speaker label in uppercase followed by a colon (e.g. JOHN:) " +
+		"when the speaker is off-screen or changes within an item and the context makes clear who speaks. " +
+		"Do not invent sounds the text doesn't suggest.",
+}
+
+func normalizeSDHMode(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+func isValidSDHMode(mode string) bool {
+	return mode == SDHKeep || mode == SDHStrip || mode == SDHGenerate
+}
+
+// sdhInstruction returns the prompt instruction of mode (empty for SDHKeep).
+func sdhInstruction(mode string) string {
+	return sdhInstructions[normalizeSDHMode(mode)]
+}
+
+// removeStrippedCues drops the cues the model left without visible text with
+// SDHStrip (the cues that only described sounds) and returns the cues to
+// write and how many were dropped. subs are left untouched.
+func removeStrippedCues(subs []*srt.Subtitle, mode string) ([]*srt.Subtitle, int) {
+	if mode != SDHStrip {
+		return subs, 0
+	}
+	out := make([]*srt.Subtitle, 0, len(subs))
+	for _, s := range subs {
+		if strings.TrimSpace(srt.VisibleText(s.Text)) != "" {
+			out = append(out, s)
+		}
+	}
+	return out, len(subs) - len(out)
+}
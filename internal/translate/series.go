@@ -0,0 +1,164 @@
+package translate
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/adrianmusante/subtitle-tools/internal/glossary"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+const (
+	// seriesTermMinCount is how many times a name or term must appear in a
+	// file to be learned.
+	seriesTermMinCount = 2
+	// seriesRenderingMinRatio is the share of the cues with a source term
+	// whose translation must contain the same rendering.
+	seriesRenderingMinRatio = 0.6
+	// seriesContextMaxTerms caps the names and terms sent with each prompt.
+	seriesContextMaxTerms = 50
+)
+
+// SeriesContext carries the recurring names and terms of the files already
+// translated (e.g. the earlier episodes of a season) into the prompts of the
+// next ones, so they are translated consistently. It is learned locally from
+// each translated file: a capitalized phrase seen in several cues of the
+// source is paired with the phrase its translations agree on. Safe for
+// concurrent use; the zero value is not, use NewSeriesContext.
+type SeriesContext struct {
+	mu    sync.Mutex
+	terms map[string]map[string]*seriesTerm // by target language, then source term
+	seq   int
+}
+
+type seriesTerm struct {
+	source, target string
+	count          int // occurrences in the source of every file
+	seq            int // learning order, to break ties
+}
+
+// NewSeriesContext returns an empty SeriesContext.
+func NewSeriesContext() *SeriesContext {
+	return &SeriesContext{terms: make(map[string]map[string]*seriesTerm)}
+}
+
+func seriesLanguageKey(targetLanguage string) string {
+	return strings.ToLower(strings.TrimSpace(targetLanguage))
+}
+
+// learn pairs the recurring names and terms of subs with their rendering in
+// translated. A term keeps the first rendering learned for it. It returns the
+// number of new terms.
+func (c *SeriesContext) learn(targetLanguage string, subs []*srt.Subtitle, translated map[int]string) int {
+	if c == nil {
+		return 0
+	}
+	var texts []string
+	sourcePhrases := make(map[int][]glossary.Phrase)
+	for _, sub := range subs {
+		if _, ok := translated[sub.Idx]; !ok {
+			continue
+		}
+		texts = append(texts, sub.Text)
+		sourcePhrases[sub.Idx] = glossary.Phrases(sub.Text)
+	}
+	terms := glossary.Extract(texts, seriesTermMinCount)
+	if len(terms) == 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := seriesLanguageKey(targetLanguage)
+	known := c.terms[key]
+	if known == nil {
+		known = make(map[string]*seriesTerm)
+		c.terms[key] = known
+	}
+	added := 0
+	for _, term := range terms {
+		if t, ok := known[term.Text]; ok {
+			t.count += term.Count
+			continue
+		}
+		target, ok := seriesRendering(term.Text, subs, sourcePhrases, translated)
+		if !ok {
+			continue
+		}
+		c.seq++
+		known[term.Text] = &seriesTerm{source: term.Text, target: target, count: term.Count, seq: c.seq}
+		added++
+	}
+	return added
+}
+
+// seriesRendering returns the capitalized phrase that most translations of
+// the cues with term have in common, preferring term itself (names are
+// usually kept as they are).
+func seriesRendering(term string, subs []*srt.Subtitle, sourcePhrases map[int][]glossary.Phrase, translated map[int]string) (string, bool) {
+	cues := 0
+	candidates := make(map[string]int)
+	for _, sub := range subs {
+		if !slices.ContainsFunc(sourcePhrases[sub.Idx], func(p glossary.Phrase) bool { return p.Text == term || p.Tail() == term }) {
+			continue
+		}
+		cues++
+		seen := make(map[string]bool)
+		for _, p := range glossary.Phrases(translated[sub.Idx]) {
+			seen[p.Text] = true
+			if rest := p.Tail(); rest != "" {
+				seen[rest] = true
+			}
+		}
+		for text := range seen {
+			candidates[text]++
+		}
+	}
+	minCount := max(seriesTermMinCount, int(float64(cues)*seriesRenderingMinRatio+0.999))
+	if candidates[term] >= minCount {
+		return term, true
+	}
+	best, bestCount := "", 0
+	for text, n := range candidates {
+		if n > bestCount || n == bestCount && (len(text) > len(best) || len(text) == len(best) && text < best) {
+			best, bestCount = text, n
+		}
+	}
+	return best, bestCount >= minCount
+}
+
+// summary renders the names and terms learned for targetLanguage as prompt
+// lines, most frequent first; empty when there are none.
+func (c *SeriesContext) summary(targetLanguage string) string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	terms := make([]*seriesTerm, 0, len(c.terms[seriesLanguageKey(targetLanguage)]))
+	for _, t := range c.terms[seriesLanguageKey(targetLanguage)] {
+		terms = append(terms, t)
+	}
+	slices.SortFunc(terms, func(a, b *seriesTerm) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), cmp.Compare(a.seq, b.seq))
+	})
+	var names, others []string
+	for _, t := range terms[:min(len(terms), seriesContextMaxTerms)] {
+		if t.source == t.target {
+			names = append(names, t.source)
+		} else {
+			others = append(others, t.source+" = "+t.target)
+		}
+	}
+	c.mu.Unlock()
+
+	var lines []string
+	if len(names) > 0 {
+		lines = append(lines, "- Keep as is: "+strings.Join(names, ", "))
+	}
+	for _, t := range others {
+		lines = append(lines, "- "+t)
+	}
+	return strings.Join(lines, "\n")
+}
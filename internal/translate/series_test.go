@@ -0,0 +1,60 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestSeriesContext(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, Text: "Where is Jesse?"},
+		{Idx: 2, Text: "Tell Jesse to wait."},
+		{Idx: 3, Text: "Ask Jesse."},
+		{Idx: 4, Text: "We sit on the Iron Throne."},
+		{Idx: 5, Text: "Nobody takes the Iron Throne."},
+		{Idx: 6, Text: "Yes. Yes, sir."},
+	}
+	translated := map[int]string{
+		1: "¿Dónde está Jesse?",
+		2: "Dile a Jesse que espere.",
+		3: "Pregúntale a Jesse.",
+		4: "Nos sentamos en el Trono de Hierro.",
+		5: "Nadie se lleva el Trono de Hierro.",
+		6: "Sí. Sí, señor.",
+	}
+	c := NewSeriesContext()
+	if n := c.learn("es", subs, translated); n != 2 {
+		t.Fatalf("expected 2 terms learned, got %d", n)
+	}
+	want := "- Keep as is: Jesse\n- Iron Throne = Trono de Hierro"
+	if got := c.summary("es"); got != want {
+		t.Fatalf("summary=%q, want %q", got, want)
+	}
+	if got := c.summary("fr"); got != "" {
+		t.Fatalf("expected no terms for another target language, got %q", got)
+	}
+
+	// A later file keeps the first rendering.
+	translated[4] = "Nos sentamos en el Trono Férreo."
+	translated[5] = "Nadie se lleva el Trono Férreo."
+	if n := c.learn("es", subs, translated); n != 0 {
+		t.Fatalf("expected no new terms, got %d", n)
+	}
+	if got := c.summary("es"); got != want {
+		t.Fatalf("summary=%q, want %q", got, want)
+	}
+}
+
+func TestBuildPrompt_SeriesContext(t *testing.T) {
+	c := NewSeriesContext()
+	c.learn("es", []*srt.Subtitle{{Idx: 1, Text: "Hi, Walter."}, {Idx: 2, Text: "Bye, Walter."}}, map[int]string{1: "Hola, Walter.", 2: "Adiós, Walter."})
+	msgs, err := buildPrompt(PromptOptions{SeriesContext: c}, "", "es", "{\"idx\":1,\"text\":\"Hi\"}", false)
+	if err != nil {
+		t.Fatalf("buildPrompt: %v", err)
+	}
+	if !strings.Contains(msgs[1].Content, "earlier episodes") || !strings.Contains(msgs[1].Content, "- Keep as is: Walter\n") {
+		t.Fatalf("expected the series context in the prompt:\n%s", msgs[1].Content)
+	}
+}
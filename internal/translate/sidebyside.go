@@ -0,0 +1,144 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Formats of the side-by-side review file written with DryRun.
+const (
+	SideBySideMarkdown = "markdown"
+	SideBySideCSV      = "csv"
+	SideBySideHTML     = "html"
+	DefaultSideBySide  = SideBySideMarkdown
+)
+
+// sideBySideSuffix is appended to the output path (without extension),
+// followed by the extension of the format.
+const sideBySideSuffix = ".side-by-side"
+
+var sideBySideExtensions = map[string]string{
+	SideBySideMarkdown: ".md",
+	SideBySideCSV:      ".csv",
+	SideBySideHTML:     ".html",
+}
+
+func normalizeSideBySideFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "md" {
+		return SideBySideMarkdown
+	}
+	return format
+}
+
+func isValidSideBySideFormat(format string) bool {
+	_, ok := sideBySideExtensions[format]
+	return ok
+}
+
+func defaultSideBySidePath(outputPath, format string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + sideBySideSuffix + sideBySideExtensions[format]
+}
+
+// sideBySideRow is a cue of the side-by-side review file.
+type sideBySideRow struct {
+	Idx         int
+	Start, End  string
+	Source      string
+	Translation string // empty when the cue isn't written
+}
+
+// sideBySideRows pairs the source cues with the written cues by index.
+func sideBySideRows(sources, written []*srt.Subtitle) []sideBySideRow {
+	translations := make(map[int]string, len(written))
+	for _, s := range written {
+		translations[s.Idx] = s.Text
+	}
+	rows := make([]sideBySideRow, 0, len(sources))
+	for _, s := range sources {
+		rows = append(rows, sideBySideRow{
+			Idx:         s.Idx,
+			Start:       srt.FormatTime(s.FromTime),
+			End:         srt.FormatTime(s.ToTime),
+			Source:      s.Text,
+			Translation: translations[s.Idx],
+		})
+	}
+	return rows
+}
+
+// writeSideBySide writes rows to path as a table in format, with the source
+// and translated text of every cue side by side for a human review.
+func writeSideBySide(path, format, sourceLanguage, targetLanguage string, rows []sideBySideRow) error {
+	if sourceLanguage == "" {
+		sourceLanguage = "source"
+	}
+	var buf bytes.Buffer
+	switch format {
+	case SideBySideCSV:
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"idx", "start", "end", sourceLanguage, targetLanguage})
+		for _, r := range rows {
+			_ = w.Write([]string{strconv.Itoa(r.Idx), r.Start, r.End, r.Source, r.Translation})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	case SideBySideHTML:
+		data := struct {
+			SourceLanguage, TargetLanguage string
+			Rows                           []sideBySideRow
+		}{sourceLanguage, targetLanguage, rows}
+		if err := sideBySideHTML.Execute(&buf, data); err != nil {
+			return err
+		}
+	case SideBySideMarkdown:
+		_, _ = fmt.Fprintf(&buf, "| # | Time | %s | %s |\n|---|---|---|---|\n", sourceLanguage, targetLanguage)
+		for _, r := range rows {
+			_, _ = fmt.Fprintf(&buf, "| %d | %s --> %s | %s | %s |\n", r.Idx, r.Start, r.End, markdownCell(r.Source), markdownCell(r.Translation))
+		}
+	default:
+		return fmt.Errorf("unsupported side-by-side format %q", format)
+	}
+	return fs.WriteFile(&buf, path)
+}
+
+// markdownCell escapes text for a Markdown table cell, breaking lines with
+// <br>.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+var sideBySideHTML = template.Must(template.New("side-by-side").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Translation review</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.text { white-space: pre-wrap; width: 40%; }
+td.time { white-space: nowrap; font-family: monospace; }
+</style>
+</head>
+<body>
+<table>
+<tr><th>#</th><th>Time</th><th>{{.SourceLanguage}}</th><th>{{.TargetLanguage}}</th></tr>
+{{- range .Rows}}
+<tr><td>{{.Idx}}</td><td class="time">{{.Start}} --&gt; {{.End}}</td><td class="text">{{.Source}}</td><td class="text">{{.Translation}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,187 @@
+package translate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxStreamLineBytes bounds a single SSE line (one JSON chunk).
+const maxStreamLineBytes = 4 << 20
+
+const streamDoneMarker = "[DONE]"
+
+// streamError reports a stream that broke or ended before the model finished.
+// It is retryable: the request is sent again.
+type streamError struct {
+	err     error
+	partial string // content received before the failure
+}
+
+func (e *streamError) Error() string {
+	return fmt.Sprintf("chat completion stream: %v (received %d chars)", e.err, len(e.partial))
+}
+
+func (e *streamError) Unwrap() error { return e.err }
+
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Usage *chatCompletionUsage `json:"usage"`
+}
+
+// doChatCompletionStream posts a streaming chat completion and accumulates the
+// content deltas. idleTimeout (0 disables) aborts the request when no data is
+// received for that long, so long batches are bounded by stalls rather than by
+// their total duration. Non-2xx answers are returned with their body, as
+// doJSONPost does; a broken or truncated stream returns a *streamError.
+func doChatCompletionStream(
+	ctx context.Context,
+	hc *http.Client,
+	u string,
+	authBearer string,
+	body []byte,
+	idleTimeout time.Duration,
+) (httpResult, string, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	errIdle := fmt.Errorf("no data received for %s: %w", idleTimeout, context.DeadlineExceeded)
+	var idle *time.Timer
+	if idleTimeout > 0 {
+		idle = time.AfterFunc(idleTimeout, func() { cancel(errIdle) })
+		defer idle.Stop()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return httpResult{}, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if authBearer != "" {
+		req.Header.Set("Authorization", "Bearer "+authBearer)
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errIdle) {
+			return httpResult{}, "", &streamError{err: errIdle}
+		}
+		return httpResult{}, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	r := httpResult{statusCode: resp.StatusCode, header: resp.Header.Clone()}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Errors, and servers that ignore "stream", answer with a regular body.
+		// The caller parses bodyBytes when no streamed content is returned.
+		r.bodyBytes, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return httpResult{}, "", err
+		}
+		return r, "", nil
+	}
+
+	content, usage, err := readChatCompletionStream(resp.Body, func() {
+		if idle != nil {
+			idle.Reset(idleTimeout)
+		}
+	})
+	var tErr *truncatedResponseError
+	var bErr *blockedResponseError
+	if errors.As(err, &tErr) || errors.As(err, &bErr) {
+		r.usage = usage
+		return r, "", err
+	}
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errIdle) {
+			err = errIdle
+		}
+		sErr := &streamError{err: err, partial: content}
+		slog.Debug("chat completion stream ended early", "err", err, "partial_content", abbreviate(content, AbbreviationMax))
+		return r, "", sErr
+	}
+	r.usage = usage
+	return r, content, nil
+}
+
+// readChatCompletionStream parses an OpenAI-style SSE stream ("data: {json}"
+// lines ending with "data: [DONE]") and returns the concatenated content and
+// the token usage, if a chunk reports it. onData is called for every
+// received line. A stream cut at the output token limit returns a
+// *truncatedResponseError, with the tokens, and one withheld by the content
+// filters a *blockedResponseError.
+func readChatCompletionStream(body io.Reader, onData func()) (string, tokenUsage, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
+
+	var content strings.Builder
+	var usage tokenUsage
+	finished := false
+	truncated := false
+	blocked := false
+	for scanner.Scan() {
+		onData()
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue // blank separators, comments (":") and event/id fields
+		}
+		data = strings.TrimSpace(data)
+		if data == streamDoneMarker {
+			finished = true
+			break
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return content.String(), tokenUsage{}, fmt.Errorf("invalid stream chunk: %w (chunk=%q)", err, abbreviate(data, AbbreviationMax))
+		}
+		if chunk.Error != nil {
+			return content.String(), tokenUsage{}, fmt.Errorf("stream error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.usage()
+		}
+		for _, ch := range chunk.Choices {
+			content.WriteString(ch.Delta.Content)
+			if ch.FinishReason != nil && *ch.FinishReason != "" {
+				finished = true
+				truncated = isTruncatedFinishReason(*ch.FinishReason)
+				blocked = *ch.FinishReason == finishReasonContentFilter
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return content.String(), tokenUsage{}, err
+	}
+	if !finished {
+		return content.String(), tokenUsage{}, io.ErrUnexpectedEOF
+	}
+	if truncated {
+		return "", usage, &truncatedResponseError{partial: content.String()}
+	}
+	if blocked {
+		return "", usage, &blockedResponseError{reason: finishReasonContentFilter}
+	}
+	out := strings.TrimSpace(content.String())
+	if out == "" {
+		return "", tokenUsage{}, errors.New("empty content in response")
+	}
+	return out, usage, nil
+}
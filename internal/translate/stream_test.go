@@ -0,0 +1,97 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sseChunk(content string) string {
+	b, _ := json.Marshal(map[string]any{"choices": []any{map[string]any{"delta": map[string]any{"content": content}}}})
+	return "data: " + string(b) + "\n\n"
+}
+
+func TestReadChatCompletionStream(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		sseChunk(`{"idx":1,`) +
+		sseChunk(`"text":"Hola"}`) +
+		"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"total_tokens\":42,\"prompt_tokens_details\":{\"cached_tokens\":30}}}\n\n" +
+		"data: [DONE]\n\n"
+	got, usage, err := readChatCompletionStream(strings.NewReader(stream), func() {})
+	if err != nil {
+		t.Fatalf("readChatCompletionStream: %v", err)
+	}
+	if got != `{"idx":1,"text":"Hola"}` || usage.total != 42 || usage.cached != 30 {
+		t.Fatalf("got %q, %+v usage", got, usage)
+	}
+
+	partial, _, err := readChatCompletionStream(strings.NewReader(sseChunk("Hol")), func() {})
+	if !errors.Is(err, io.ErrUnexpectedEOF) || partial != "Hol" {
+		t.Fatalf("expected truncated stream error with partial content, got %q, %v", partial, err)
+	}
+
+	cut := sseChunk(`{"idx":1,"te`) +
+		"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\n" +
+		"data: [DONE]\n\n"
+	_, _, err = readChatCompletionStream(strings.NewReader(cut), func() {})
+	var tErr *truncatedResponseError
+	if !errors.As(err, &tErr) || tErr.partial != `{"idx":1,"te` {
+		t.Fatalf("expected a truncated response error, got %v", err)
+	}
+}
+
+func TestOpenAIClient_StreamRetriesBrokenStream(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			t.Errorf("expected stream in request, got %s", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, sseChunk(`{"idx":1,"text":"Hola"}`))
+		if calls.Add(1) == 1 {
+			return // connection closed before the model finished
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	c := &OpenAIClient{BaseURL: server.URL, APIKey: "k", Model: "gpt-test", ResponseMode: ResponseModeNDJSON, Stream: true, RetryOptions: RetryOptions{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+	got, err := c.TranslateBatch(context.Background(), "", "es", `{"idx":1,"text":"Hello"}`)
+	if err != nil {
+		t.Fatalf("TranslateBatch: %v", err)
+	}
+	if got != `{"idx":1,"text":"Hola"}` || calls.Load() != 2 {
+		t.Fatalf("got %q after %d calls", got, calls.Load())
+	}
+}
+
+func TestOpenAIClient_StreamIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, sseChunk("Hol"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := &OpenAIClient{BaseURL: server.URL, APIKey: "k", Model: "gpt-test", ResponseMode: ResponseModeNDJSON, Stream: true, Timeout: 50 * time.Millisecond, RetryOptions: RetryOptions{MaxAttempts: 1}}
+	_, err := c.TranslateBatch(context.Background(), "", "es", `{"idx":1,"text":"Hello"}`)
+	var sErr *streamError
+	if !errors.As(err, &sErr) || !errors.Is(err, context.DeadlineExceeded) || sErr.partial != "Hol" {
+		t.Fatalf("expected idle timeout stream error with partial content, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// leadingOverridePattern matches the ASS override blocks at the start of a cue,
+// usually positioning such as {\an8}.
+var leadingOverridePattern = regexp.MustCompile(`^(?:\{\\[^{}]*\}\s*)+`)
+
+// detachOverrides returns copies of subs without their leading ASS override
+// blocks and the removed blocks by cue index. The blocks are kept out of the
+// provider payload, batch sizing, caches and length checks, and put back by
+// attachOverrides.
+func detachOverrides(subs []*srt.Subtitle) ([]*srt.Subtitle, map[int]string) {
+	overrides := make(map[int]string)
+	out := make([]*srt.Subtitle, len(subs))
+	for i, s := range subs {
+		out[i] = s
+		prefix := leadingOverridePattern.FindString(s.Text)
+		if prefix == "" || strings.TrimSpace(s.Text[len(prefix):]) == "" {
+			continue
+		}
+		detached := *s
+		detached.Text = s.Text[len(prefix):]
+		out[i] = &detached
+		overrides[s.Idx] = prefix
+	}
+	return out, overrides
+}
+
+// attachOverrides returns copies of subs with the blocks removed by
+// detachOverrides put back.
+func attachOverrides(subs []*srt.Subtitle, overrides map[int]string) []*srt.Subtitle {
+	if len(overrides) == 0 {
+		return subs
+	}
+	out := make([]*srt.Subtitle, len(subs))
+	for i, s := range subs {
+		out[i] = s
+		if prefix, ok := overrides[s.Idx]; ok {
+			attached := *s
+			attached.Text = prefix + s.Text
+			out[i] = &attached
+		}
+	}
+	return out
+}
+
+// Tags are replaced by numbered placeholders before sending the text to the
+// provider. The brackets are uncommon in subtitles, so models leave them alone.
+const (
+	tagPlaceholderOpen  = "⟦"
+	tagPlaceholderClose = "⟧"
+)
+
+var tagPlaceholderPattern = regexp.MustCompile(tagPlaceholderOpen + `\s*(\d+)\s*` + tagPlaceholderClose)
+
+// protectTags replaces the inline tags in text with placeholders and returns
+// the masked text and the tags in placeholder order (placeholder n is tags[n-1]).
+func protectTags(text string) (string, []string) {
+	var tags []string
+	masked := srt.InlineTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		tags = append(tags, tag)
+		return tagPlaceholder(len(tags))
+	})
+	return masked, tags
+}
+
+func tagPlaceholder(n int) string {
+	return tagPlaceholderOpen + strconv.Itoa(n) + tagPlaceholderClose
+}
+
+// restoreTags replaces placeholders with their tags. It returns an error when
+// the tag structure changed: a placeholder is missing, duplicated or unknown,
+// or the restored tags are no longer properly nested. In that case the
+// returned text is a best-effort restoration (unknown placeholders dropped).
+func restoreTags(text string, tags []string) (string, error) {
+	if len(tags) == 0 && !strings.Contains(text, tagPlaceholderOpen) {
+		return text, nil
+	}
+	seen := make([]int, len(tags))
+	var problems []string
+	restored := tagPlaceholderPattern.ReplaceAllStringFunc(text, func(ph string) string {
+		n, _ := strconv.Atoi(tagPlaceholderPattern.FindStringSubmatch(ph)[1])
+		if n < 1 || n > len(tags) {
+			problems = append(problems, fmt.Sprintf("unknown placeholder %d", n))
+			return ""
+		}
+		seen[n-1]++
+		if seen[n-1] > 1 {
+			problems = append(problems, fmt.Sprintf("duplicated tag %s", tags[n-1]))
+			return ""
+		}
+		return tags[n-1]
+	})
+	for i, c := range seen {
+		if c == 0 {
+			problems = append(problems, fmt.Sprintf("missing tag %s", tags[i]))
+		}
+	}
+	if len(problems) == 0 && tagsBalanced(tags) && !tagsBalanced(srt.InlineTagPattern.FindAllString(restored, -1)) {
+		problems = append(problems, "tags are no longer properly nested")
+	}
+	if len(problems) > 0 {
+		return restored, fmt.Errorf("inline tags changed: %s", strings.Join(problems, ", "))
+	}
+	return restored, nil
+}
+
+// compareTags returns an error when translated, whose inline tags were sent
+// as-is, doesn't have the tags of source, or has them no longer properly
+// nested.
+func compareTags(source, translated string) error {
+	want := srt.InlineTagPattern.FindAllString(source, -1)
+	got := srt.InlineTagPattern.FindAllString(translated, -1)
+	counts := make(map[string]int, len(want))
+	for _, tag := range want {
+		counts[tag]++
+	}
+	var problems []string
+	for _, tag := range got {
+		if counts[tag] == 0 {
+			problems = append(problems, fmt.Sprintf("unexpected tag %s", tag))
+			continue
+		}
+		counts[tag]--
+	}
+	for _, tag := range want {
+		if counts[tag] > 0 {
+			problems = append(problems, fmt.Sprintf("missing tag %s", tag))
+			counts[tag]--
+		}
+	}
+	if len(problems) == 0 && tagsBalanced(want) && !tagsBalanced(got) {
+		problems = append(problems, "tags are no longer properly nested")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("inline tags changed: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// tagsBalanced reports whether HTML-like tags are properly nested. ASS override
+// blocks have no closing counterpart and are ignored.
+func tagsBalanced(tags []string) bool {
+	var stack []string
+	for _, t := range tags {
+		if !strings.HasPrefix(t, "<") || strings.HasSuffix(t, "/>") {
+			continue
+		}
+		name := tagName(t)
+		if strings.HasPrefix(t, "</") {
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		stack = append(stack, name)
+	}
+	return len(stack) == 0
+}
+
+func tagName(tag string) string {
+	name := strings.TrimLeft(strings.TrimSuffix(tag, ">"), "</")
+	if i := strings.IndexAny(name, " \t/"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
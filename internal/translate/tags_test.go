@@ -0,0 +1,90 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestProtectAndRestoreTags(t *testing.T) {
+	in := `{\an8}<i>Hello</i> <font color="#ff0000">world</font>`
+	masked, tags := protectTags(in)
+	if masked != "⟦1⟧⟦2⟧Hello⟦3⟧ ⟦4⟧world⟦5⟧" {
+		t.Fatalf("unexpected masked text: %q", masked)
+	}
+	if len(tags) != 5 || tags[3] != `<font color="#ff0000">` {
+		t.Fatalf("unexpected tags: %q", tags)
+	}
+
+	// Word order changes are fine as long as the structure is kept.
+	out, err := restoreTags("⟦1⟧⟦4⟧mundo⟦5⟧ ⟦2⟧Hola⟦3⟧", tags)
+	if err != nil {
+		t.Fatalf("restoreTags: %v", err)
+	}
+	if out != `{\an8}<font color="#ff0000">mundo</font> <i>Hola</i>` {
+		t.Fatalf("unexpected restored text: %q", out)
+	}
+}
+
+func TestRestoreTags_DetectsStructureChanges(t *testing.T) {
+	_, tags := protectTags("<i>Hello</i> <b>there</b>")
+	cases := map[string]string{
+		"missing":    "⟦1⟧Hola⟦2⟧ ahí",
+		"duplicated": "⟦1⟧Hola⟦2⟧ ⟦3⟧ahí⟦4⟧⟦4⟧",
+		"unknown":    "⟦1⟧Hola⟦2⟧ ⟦3⟧ahí⟦4⟧⟦9⟧",
+		"misnested":  "⟦1⟧Hola⟦3⟧ ⟦2⟧ahí⟦4⟧",
+	}
+	for name, translated := range cases {
+		out, err := restoreTags(translated, tags)
+		if err == nil {
+			t.Fatalf("%s: expected mismatch error, got %q", name, out)
+		}
+		if strings.Contains(out, "⟦") {
+			t.Fatalf("%s: placeholders must not leak into the output: %q", name, out)
+		}
+	}
+}
+
+func TestRestoreTags_NoTags(t *testing.T) {
+	out, err := restoreTags("Hola", nil)
+	if err != nil || out != "Hola" {
+		t.Fatalf("unexpected result: %q, %v", out, err)
+	}
+}
+
+func TestCompareTags(t *testing.T) {
+	source := "<i>Hello</i> <b>there</b>"
+	if err := compareTags(source, "<b>Hola</b> <i>ahí</i>"); err != nil {
+		t.Fatalf("moved tags must be accepted: %v", err)
+	}
+	for name, translated := range map[string]string{
+		"missing":    "<i>Hola</i> ahí",
+		"unexpected": "<i>Hola</i> <b>ahí</b><u></u>",
+		"misnested":  "<i>Hola <b></i> ahí</b>",
+	} {
+		if err := compareTags(source, translated); err == nil {
+			t.Fatalf("%s: expected mismatch error", name)
+		}
+	}
+}
+
+func TestDetachAndAttachOverrides(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, Text: "{\\an8}{\\i1}Hello"},
+		{Idx: 2, Text: "No {\\i1}override{\\i0} at the start"},
+		{Idx: 3, Text: "{\\an8}"},
+	}
+	detached, overrides := detachOverrides(subs)
+	if detached[0].Text != "Hello" || detached[1] != subs[1] || detached[2] != subs[2] {
+		t.Fatalf("unexpected detached cues: %q, %q, %q", detached[0].Text, detached[1].Text, detached[2].Text)
+	}
+	if subs[0].Text != "{\\an8}{\\i1}Hello" {
+		t.Fatalf("input cue was modified: %q", subs[0].Text)
+	}
+	translated := []*srt.Subtitle{{Idx: 1, Text: "Hola"}, {Idx: 2, Text: "Sin"}, {Idx: 3, Text: "{\\an8}"}}
+	attached := attachOverrides(translated, overrides)
+	if attached[0].Text != "{\\an8}{\\i1}Hola" || attached[1].Text != "Sin" || attached[2].Text != "{\\an8}" {
+		t.Fatalf("unexpected attached cues: %q, %q, %q", attached[0].Text, attached[1].Text, attached[2].Text)
+	}
+}
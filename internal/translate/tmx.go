@@ -0,0 +1,216 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// tmxAllLanguages is the TMX srclang value used when the source language is
+// unknown (auto-detected); the source variants are then tagged as undetermined.
+const (
+	tmxAllLanguages     = "*all*"
+	tmxUndeterminedLang = "und"
+)
+
+// translationMemory maps a source text to its translation.
+type translationMemory map[string]string
+
+// TMX 1.4 documents. Reading and writing use separate types because
+// encoding/xml resolves the xml:lang attribute to its namespace on decode but
+// needs the literal prefix on encode.
+
+type tmxDocument struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Header  tmxHeader `xml:"header"`
+	Body    struct {
+		TUs []struct {
+			TUVs []struct {
+				Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+				LangOld string `xml:"lang,attr"` // TMX 1.1
+				Seg     struct {
+					Inner []byte `xml:",innerxml"`
+				} `xml:"seg"`
+			} `xml:"tuv"`
+		} `xml:"tu"`
+	} `xml:"body"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type tmxOutDocument struct {
+	XMLName xml.Name   `xml:"tmx"`
+	Version string     `xml:"version,attr"`
+	Header  tmxHeader  `xml:"header"`
+	TUs     []tmxOutTU `xml:"body>tu"`
+}
+
+type tmxOutTU struct {
+	TUVs []tmxOutTUV `xml:"tuv"`
+}
+
+type tmxOutTUV struct {
+	Lang string `xml:"xml:lang,attr"`
+	Seg  string `xml:"seg"`
+}
+
+// readTMX loads the translation units of a TMX file for the given language pair.
+// Variants are matched by exact tag first and then by primary language subtag
+// (e.g. "es-MX" matches "es"). An empty source language uses the header srclang.
+func readTMX(path, sourceLanguage, targetLanguage string) (translationMemory, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc tmxDocument
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse tmx %s: %w", path, err)
+	}
+
+	source, _ := normalizeTargetLanguage(sourceLanguage)
+	if source == "" && doc.Header.SrcLang != tmxAllLanguages {
+		source = doc.Header.SrcLang
+	}
+	target, _ := normalizeTargetLanguage(targetLanguage)
+
+	tm := make(translationMemory)
+	for _, tu := range doc.Body.TUs {
+		langs := make([]string, len(tu.TUVs))
+		for i, tuv := range tu.TUVs {
+			langs[i] = tuv.Lang
+			if langs[i] == "" {
+				langs[i] = tuv.LangOld
+			}
+		}
+		ti := matchTMXLanguage(langs, target, -1)
+		if ti < 0 {
+			continue
+		}
+		si := -1
+		if source != "" {
+			si = matchTMXLanguage(langs, source, ti)
+		} else {
+			for i := range langs {
+				if i != ti {
+					si = i
+					break
+				}
+			}
+		}
+		if si < 0 {
+			continue
+		}
+		src, err := tmxSegText(tu.TUVs[si].Seg.Inner)
+		if err != nil {
+			return nil, fmt.Errorf("parse tmx %s: %w", path, err)
+		}
+		tgt, err := tmxSegText(tu.TUVs[ti].Seg.Inner)
+		if err != nil {
+			return nil, fmt.Errorf("parse tmx %s: %w", path, err)
+		}
+		if strings.TrimSpace(src) == "" || strings.TrimSpace(tgt) == "" {
+			continue
+		}
+		tm[src] = tgt
+	}
+	return tm, nil
+}
+
+func matchTMXLanguage(langs []string, want string, skip int) int {
+	for i, l := range langs {
+		if i != skip && strings.EqualFold(l, want) {
+			return i
+		}
+	}
+	primary := strings.Split(want, LanguageSeparator)[0]
+	for i, l := range langs {
+		if i != skip && strings.EqualFold(strings.Split(strings.ReplaceAll(l, "_", LanguageSeparator), LanguageSeparator)[0], primary) {
+			return i
+		}
+	}
+	return -1
+}
+
+// tmxSegText returns the text of a <seg>. Inline elements (<bpt>, <ept>, <ph>,
+// <it>) carry the native markup (e.g. "<i>") as escaped text, so all character
+// data is concatenated to rebuild the original cue text.
+func tmxSegText(inner []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(inner))
+	var sb strings.Builder
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			sb.Write(cd)
+		}
+	}
+}
+
+// writeTMX writes the source/translated cue pairs as a TMX 1.4 file. Identical
+// pairs are written once and empty cues are skipped.
+func writeTMX(path, sourceLanguage, targetLanguage string, sources, translated []*srt.Subtitle) error {
+	source, _ := normalizeTargetLanguage(sourceLanguage)
+	target, _ := normalizeTargetLanguage(targetLanguage)
+	srcLang, srcTUVLang := source, source
+	if source == "" {
+		srcLang, srcTUVLang = tmxAllLanguages, tmxUndeterminedLang
+	}
+
+	doc := tmxOutDocument{
+		Version: "1.4",
+		Header: tmxHeader{
+			CreationTool:        "subtitle-tools",
+			CreationToolVersion: "1",
+			SegType:             "block",
+			OTMF:                "subtitle-tools",
+			AdminLang:           "en",
+			SrcLang:             srcLang,
+			DataType:            "plaintext",
+		},
+	}
+	seen := make(map[[2]string]struct{})
+	for i, s := range sources {
+		if i >= len(translated) || strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+		pair := [2]string{s.Text, translated[i].Text}
+		if _, ok := seen[pair]; ok {
+			continue
+		}
+		seen[pair] = struct{}{}
+		doc.TUs = append(doc.TUs, tmxOutTU{TUVs: []tmxOutTUV{
+			{Lang: srcTUVLang, Seg: pair[0]},
+			{Lang: target, Seg: pair[1]},
+		}})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(out)
+	buf.WriteByte('\n')
+	return fs.WriteFile(&buf, path)
+}
@@ -0,0 +1,83 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func TestReadTMX_MatchesLanguagesAndInlineCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.tmx")
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<tmx version="1.4">
+  <header creationtool="cat" creationtoolversion="1" segtype="sentence" o-tmf="cat" adminlang="en-US" srclang="en-US" datatype="plaintext"/>
+  <body>
+    <tu>
+      <tuv xml:lang="en-US"><seg>Previously on...</seg></tuv>
+      <tuv xml:lang="es-ES"><seg>Anteriormente en...</seg></tuv>
+    </tu>
+    <tu>
+      <tuv xml:lang="en-US"><seg><bpt i="1">&lt;i&gt;</bpt>Run!<ept i="1">&lt;/i&gt;</ept></seg></tuv>
+      <tuv xml:lang="es-ES"><seg><bpt i="1">&lt;i&gt;</bpt>¡Corre!<ept i="1">&lt;/i&gt;</ept></seg></tuv>
+    </tu>
+    <tu>
+      <tuv xml:lang="en-US"><seg>Hello</seg></tuv>
+      <tuv xml:lang="fr-FR"><seg>Bonjour</seg></tuv>
+    </tu>
+  </body>
+</tmx>
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tm, err := readTMX(path, "", "es")
+	if err != nil {
+		t.Fatalf("readTMX: %v", err)
+	}
+	if len(tm) != 2 {
+		t.Fatalf("expected 2 units for es, got %d: %v", len(tm), tm)
+	}
+	if got := tm["Previously on..."]; got != "Anteriormente en..." {
+		t.Fatalf("unexpected translation: %q", got)
+	}
+	if got := tm["<i>Run!</i>"]; got != "<i>¡Corre!</i>" {
+		t.Fatalf("expected inline codes to be restored, got %q", got)
+	}
+}
+
+func TestWriteTMX_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.tmx")
+	sources := []*srt.Subtitle{
+		{Idx: 1, Text: "Hello\n<i>world</i>"},
+		{Idx: 2, Text: "Bye"},
+		{Idx: 3, Text: "Bye"},
+	}
+	translated := []*srt.Subtitle{
+		{Idx: 1, Text: "Hola\n<i>mundo</i>"},
+		{Idx: 2, Text: "Adiós"},
+		{Idx: 3, Text: "Adiós"},
+	}
+	if err := writeTMX(path, "en", "es-AR", sources, translated); err != nil {
+		t.Fatalf("writeTMX: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), `xml:lang="es-AR"`) || strings.Count(string(b), "<tu>") != 2 {
+		t.Fatalf("unexpected tmx output:\n%s", b)
+	}
+
+	tm, err := readTMX(path, "en", "es-AR")
+	if err != nil {
+		t.Fatalf("readTMX: %v", err)
+	}
+	if got := tm["Hello\n<i>world</i>"]; got != "Hola\n<i>mundo</i>" {
+		t.Fatalf("round-trip mismatch: %q", got)
+	}
+}
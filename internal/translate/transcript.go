@@ -0,0 +1,124 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+)
+
+// Kinds of requests recorded in a transcript.
+const (
+	TranscriptKindTranslate = "translate"
+	TranscriptKindReview    = "review"
+	TranscriptKindCondense  = "condense"
+)
+
+// transcriptIndexFile lists every recorded request, one JSON object per line.
+const transcriptIndexFile = "index.jsonl"
+
+// TranscriptEntry is a line of the transcript index. RequestFile and
+// ResponseFile are relative to the transcript directory; ResponseFile is empty
+// when the request failed without a response.
+type TranscriptEntry struct {
+	Seq            int       `json:"seq"`
+	Time           time.Time `json:"time"`
+	Kind           string    `json:"kind"`
+	TargetLanguage string    `json:"target_language"`
+	Provider       string    `json:"provider,omitempty"`
+	FirstIdx       int       `json:"first_idx"`
+	LastIdx        int       `json:"last_idx"`
+	Cues           int       `json:"cues"`
+	Attempt        int       `json:"attempt"`
+	DurationMS     int64     `json:"duration_ms"`
+	RequestFile    string    `json:"request_file"`
+	ResponseFile   string    `json:"response_file,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// transcript writes the payload and raw model response of every batch request
+// to numbered files, for debugging bad translations. It is best-effort: write
+// failures are logged and never fail the run. A nil *transcript records nothing.
+type transcript struct {
+	dir string
+
+	mu    sync.Mutex
+	seq   int
+	index *os.File
+}
+
+// openTranscript creates a unique run directory inside baseDir.
+func openTranscript(baseDir string) (*transcript, error) {
+	dir, _, err := run.NewWorkdir(baseDir, "transcript")
+	if err != nil {
+		return nil, fmt.Errorf("create transcript dir: %w", err)
+	}
+	index, err := os.OpenFile(filepath.Join(dir, transcriptIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("create transcript index: %w", err)
+	}
+	return &transcript{dir: dir, index: index}, nil
+}
+
+func (t *transcript) close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.index.Close(); err != nil {
+		slog.Warn("failed to close transcript index", "err", err)
+	}
+}
+
+// record writes request and response (if any) and appends e to the index.
+// reqErr is the transport or parse error of the attempt, if any.
+func (t *transcript) record(e TranscriptEntry, request, response string, reqErr error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	e.Seq = t.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if reqErr != nil {
+		e.Error = reqErr.Error()
+	}
+	name := fmt.Sprintf("%04d-%s-%s", e.Seq, e.Kind, languageFileTag(e.TargetLanguage))
+	e.RequestFile = name + ".request.ndjson"
+	if err := os.WriteFile(filepath.Join(t.dir, e.RequestFile), []byte(request+"\n"), 0o644); err != nil {
+		slog.Warn("failed to write transcript request", "err", err)
+		return
+	}
+	if response != "" {
+		e.ResponseFile = name + ".response.txt"
+		if err := os.WriteFile(filepath.Join(t.dir, e.ResponseFile), []byte(response+"\n"), 0o644); err != nil {
+			slog.Warn("failed to write transcript response", "err", err)
+			return
+		}
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		slog.Warn("failed to encode transcript entry", "err", err)
+		return
+	}
+	if _, err := t.index.Write(append(b, '\n')); err != nil {
+		slog.Warn("failed to write transcript index", "err", err)
+	}
+}
+
+func transcriptDir(t *transcript) string {
+	if t == nil {
+		return ""
+	}
+	return t.dir
+}
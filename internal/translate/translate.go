@@ -5,12 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
 	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/langdetect"
+	"github.com/adrianmusante/subtitle-tools/internal/rtl"
 	"github.com/adrianmusante/subtitle-tools/internal/run"
 	"github.com/adrianmusante/subtitle-tools/internal/srt"
 	"golang.org/x/time/rate"
@@ -28,12 +33,214 @@ type Options struct {
 	BaseURL        string
 	RequestTimeout time.Duration
 
+	// Provider selects the translation backend (openai, gemini or deepl).
+	// Empty means DefaultProvider. Gemini models are sent to the native Gemini
+	// API unless BaseURL is set (see chatProvider).
+	Provider string
+	// SafetyThreshold sets the safety filters of the native Gemini API for
+	// every harm category (DefaultSafetyThreshold when empty).
+	SafetyThreshold string
+	// Formality is forwarded to providers that support it (deepl).
+	Formality string
+	// FallbackModels are tried in order (chat models, see chatProvider) when a batch
+	// exhausts its retries on the primary provider (429/5xx, network or parse
+	// failures). FallbackAPIKeys and FallbackBaseURLs are matched by position;
+	// missing base URLs are inferred from the model, and missing keys reuse
+	// APIKey only for a fallback sent to the same API as the primary model.
+	FallbackModels   []string
+	FallbackAPIKeys  []string
+	FallbackBaseURLs []string
+
+	// CheckModel queries the provider's model list before translating and
+	// fails early if the model is not available.
+	CheckModel bool
+
+	// ResponseMode controls whether structured output (json_schema) is requested
+	// from the provider: auto, ndjson or json-schema.
+	ResponseMode string
+	// ParseStrictness selects the fallbacks allowed to read the model output:
+	// strict, tolerant or salvage (DefaultParseStrictness when empty). An
+	// output rejected is retried like an unparseable one.
+	ParseStrictness string
+
+	// Temperature and TopP override the model defaults when set (nil keeps the
+	// default: temperature 0, except for reasoning models, which reject it).
+	// MaxOutputTokens caps the response length (0 = provider default).
+	// ReasoningEffort (none, minimal, low, medium, high) is sent to reasoning
+	// models when set.
+	Temperature     *float64
+	TopP            *float64
+	MaxOutputTokens int
+	ReasoningEffort string
+
+	// Stream requests streamed (SSE) chat completions. RequestTimeout then
+	// applies to the time between received chunks, not to the whole response.
+	Stream bool
+
+	// SkipPromptCache sends no prompt caching hints: by default, the requests
+	// to the OpenAI API carry a prompt_cache_key and those to Claude models
+	// mark the instructions repeated in every batch with cache_control, so the
+	// provider bills them at the price of cached input.
+	SkipPromptCache bool
+
+	// BatchAPI sends the batches of each target language as a single job of
+	// the asynchronous Batch API of the provider (openai or gemini), at about
+	// half the price, and waits for it to finish (usually within hours). The
+	// job is recorded in BatchStateDir, so a run interrupted while waiting
+	// resumes it. Review, length enforcement and the requests failed in the
+	// job are sent synchronously.
+	BatchAPI      bool
+	BatchStateDir string
+	// BatchPollInterval is how often the job is checked
+	// (DefaultBatchPollInterval when 0).
+	BatchPollInterval time.Duration
+
+	// Transport carries the proxy and TLS settings for API requests (see
+	// internal/httpclient). Nil means http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// PromptFile is an optional Go text/template that replaces the built-in
+	// prompt for chat models (see PromptData for the available variables).
+	PromptFile string
+	// GlossaryFile is an optional text file injected into the prompt as-is.
+	GlossaryFile string
+	// SeriesContext, when set, adds the names and terms learned from the files
+	// translated before with the same SeriesContext to the prompt, and learns
+	// those of this one. Share it across the episodes of a season.
+	SeriesContext *SeriesContext
+	// Style (e.g. formal, informal, colloquial), Audience and Notes are free-text
+	// guidance added to the system prompt. Style also sets the DeepL formality
+	// when Formality is empty.
+	Style    string
+	Audience string
+	Notes    string
+
+	// CacheDir is the translation cache directory. Cues already translated with
+	// the same source/target language, model (the primary one, see
+	// FallbackModels) and options that change the translation (see
+	// cacheVariant) are reused from the cache instead of being sent to the
+	// provider. Empty disables the cache.
+	CacheDir string
+
+	// TMXImportPath is an optional TMX file used as a pre-seeded translation
+	// memory: cues whose text matches a unit are not sent to the provider.
+	TMXImportPath string
+	// TMXExportPath, when set, receives the source/translated cue pairs as TMX.
+	TMXExportPath string
+
+	// PreserveIndex keeps the cue numbers of the input (when they are unique)
+	// instead of renumbering the output from 1.
+	PreserveIndex bool
+	// OnlyForced translates only the forced cues of the input (see
+	// srt.Subtitle), for a forced track; SkipSDH leaves out the cues that only
+	// describe sounds. The other cues aren't written.
+	OnlyForced bool
+	SkipSDH    bool
+	// Selection limits the translation to the cues it selects, e.g. to
+	// translate a section again; the other cues are written untranslated.
+	// The zero Selection translates every cue.
+	Selection srt.Selection
+	// SDH asks chat models to handle the hearing-impaired annotations
+	// (DefaultSDH when empty): SDHStrip leaves them out, dropping the cues
+	// that only describe sounds, and SDHGenerate produces SDH output with
+	// speaker labels and sound cues.
+	SDH string
+
+	// CensorListPath, when set, is a word list (see censor.Load) whose words
+	// are censored in the written translation following CensorStyle
+	// (censor.DefaultStyle when empty). The cache, the exported TMX and the
+	// review report keep the uncensored text.
+	CensorListPath string
+	CensorStyle    string
+
+	// SkipTagProtection sends inline tags (<i>, <font ...>, {\an8}) to the provider
+	// as-is instead of replacing them with placeholders, and leaves them
+	// unchecked. DeepL always gets them as-is, as XML elements.
+	SkipTagProtection bool
+	// RetryTagMismatch retries a batch (within RetryParseMaxAttempts) when the
+	// tag structure of a translated cue differs from the source.
+	RetryTagMismatch bool
+
+	// Review runs a second pass per batch asking the model to check the
+	// translations against the source: ReviewModeFix applies its corrections,
+	// ReviewModeReport only flags them. Both write a JSON report. Empty disables it.
+	Review string
+	// ReviewReportPath is where the review report is written. Empty means the
+	// output path with a .review.json extension.
+	ReviewReportPath string
+
+	// MaxCPS (characters per second), MaxLineLength and MaxLines limit the
+	// reading speed, line length and number of lines of translated cues; 0
+	// disables each check. LengthPolicy decides what happens to cues over the
+	// limits (wrap, shorten or report).
+	MaxCPS        float64
+	MaxLineLength int
+	MaxLines      int
+	LengthPolicy  string
+	// LengthHints sends MaxLineLength and MaxLines with every cue and asks the
+	// model to respect them, so translations come back already wrapped; the
+	// LengthPolicy still applies to the cues the model leaves over the limits.
+	LengthHints bool
+	// LocalizeNumbers asks the model to write numbers, dates and currency
+	// amounts with the conventions of the target locale, and fixes the
+	// separators of the numbers it copied from the source as they were.
+	LocalizeNumbers bool
+	// ConvertUnits asks the model to convert imperial units to metric (unless
+	// the target locale uses them), and converts the measures it left
+	// unconverted.
+	ConvertUnits bool
+
+	// LineBreaks is how the line breaks inside a cue are treated:
+	// LineBreaksPreserve (default) asks the model to keep them, and
+	// LineBreaksReflow lets it move them and breaks the translations again into
+	// balanced lines (see MaxLineLength and MaxLines).
+	LineBreaks string
+	// RTLMarks adds right-to-left marks (RLM) around the lines of a
+	// right-to-left target language (see rtl.IsLanguage) that start or end
+	// with punctuation, digits or words written left to right, which many
+	// players show on the wrong side otherwise.
+	RTLMarks bool
+	// LengthReportPath receives the cues still over the limits as JSON. Empty
+	// means no report, except with LengthPolicyReport, which defaults to the
+	// output path with a .length.json extension.
+	LengthReportPath string
+
+	// SideBySideFormat is the format of the review file written next to the
+	// output with DryRun, with the source and translated text of every cue
+	// side by side (DefaultSideBySide when empty).
+	SideBySideFormat string
+
+	// TranscriptDir, when set, receives the payload and raw model response of
+	// every batch request as numbered files plus an index.jsonl, in a unique
+	// subdirectory per run (see Result.TranscriptDir).
+	TranscriptDir string
+
+	// Progress, when set, receives progress snapshots for each target language
+	// (see ProgressFunc).
+	Progress ProgressFunc
+
+	// Force skips the pre-flight check that aborts when the input already looks
+	// like it is in the target language.
+	Force bool
+
 	// batching
 	MaxBatchChars int // soft limit for payload size
 
 	// execution
-	MaxWorkers int     // number of concurrent batches
+	MaxWorkers int     // number of concurrent batches (upper bound when AdaptiveWorkers is set)
 	RPS        float64 // requests per second (0 disables rate limiting)
+	KeyRPS     float64 // requests per second per API key (0 disables per-key limiting)
+
+	// CircuitBreakerThreshold is the number of consecutive server errors (5xx),
+	// across workers, after which the requests to a provider are paused for
+	// CircuitBreakerCooldown (DefaultCircuitBreakerCooldown when 0). 0
+	// disables the circuit breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+
+	// AdaptiveWorkers starts with a single in-flight batch and adjusts concurrency
+	// (up to MaxWorkers) based on throttling signals from the provider.
+	AdaptiveWorkers bool
 
 	// retry
 	// RetryMaxAttempts controls how many attempts are made for retryable errors.
@@ -45,13 +252,71 @@ type Options struct {
 	// fails or the output doesn't match the requested idx set).
 	// Must be >= 1.
 	RetryParseMaxAttempts int
+
+	censorList *censor.List // loaded from CensorListPath
 }
 
 type Result struct {
-	WrittenPath string
-	Batches     int
+	TargetLanguage string
+	WrittenPath    string
+	Batches        int
+	// TokensUsed is the total reported by the provider (0 for providers
+	// that don't report it, such as DeepL).
+	TokensUsed int64
+	// CachedTokens is the part of the prompt tokens that the provider read
+	// from its prompt cache, when reported (OpenAI, Gemini and most routers).
+	CachedTokens int64
+	CacheHits    int // cues reused from the translation cache
+	MemoryHits   int // cues reused from the imported TMX
+	// TagMismatches counts cues whose inline tags could not be restored cleanly.
+	TagMismatches int
+	// Blocked counts the cues written untranslated because the content
+	// filters of the provider kept blocking them.
+	Blocked int
+	// Parse describes how the responses to the translation batches were
+	// parsed: the salvage needed shows the output quality of the model.
+	Parse ParseStats
+
+	ReviewFlagged    int    // cues flagged by the review pass
+	ReviewCorrected  int    // flagged cues whose correction was applied
+	ReviewReportPath string // empty when the review pass is disabled
+
+	LengthWrapped    int    // cues re-wrapped to MaxLineLength or MaxLines
+	LengthShortened  int    // cues condensed by the model to fit MaxCPS
+	LengthViolations int    // cues still over MaxCPS, MaxLineLength or MaxLines
+	LengthReportPath string // empty when no length report was written
+	// Localized counts the cues whose numbers or units were fixed by the local
+	// pass of LocalizeNumbers and ConvertUnits.
+	Localized int
+	// SeriesTerms counts the names and terms learned for SeriesContext.
+	SeriesTerms int
+	// RTLMarked counts the cues given right-to-left marks by RTLMarks.
+	RTLMarked int
+
+	TranscriptDir  string // empty when no transcript was recorded
+	SideBySidePath string // empty unless DryRun is set
+
+	// SDHStripped counts the cues left out with SDHStrip because they only
+	// described sounds.
+	SDHStripped int
+
+	// Censored counts the cues with a censored word (dropped with
+	// censor.StyleRemoveCue).
+	Censored int
+
+	// Unselected counts the cues outside Options.Selection, written
+	// untranslated.
+	Unselected int
 }
 
+// ErrAlreadyTargetLanguage is returned when the input already appears to be in
+// the target language and Options.Force is not set.
+var ErrAlreadyTargetLanguage = errors.New("input is already in the target language")
+
+// ErrNoForcedCues is returned with OnlyForced when the input has no forced
+// cue.
+var ErrNoForcedCues = srt.ErrNoForcedCues
+
 const DefaultRequestTimeout = 150 * time.Second
 const DefaultMaxBatchChars = 7_000
 const DefaultMaxWorkers = 2
@@ -59,47 +324,564 @@ const DefaultRequestPerSecond = 4
 const DefaultParseRetryMaxAttempts = 2
 
 func Run(ctx context.Context, opts Options) (Result, error) {
-	opts, err := validateAndDefaultOptions(opts)
+	results, err := RunTargets(ctx, opts, []Target{{
+		Language:         opts.TargetLanguage,
+		OutputPath:       opts.OutputPath,
+		TMXExportPath:    opts.TMXExportPath,
+		ReviewReportPath: opts.ReviewReportPath,
+		LengthReportPath: opts.LengthReportPath,
+	}})
 	if err != nil {
 		return Result{}, err
 	}
+	return results[0], nil
+}
+
+// Target is one output language of RunTargets.
+type Target struct {
+	Language         string
+	OutputPath       string
+	TMXExportPath    string // optional
+	ReviewReportPath string // optional
+	LengthReportPath string // optional
+}
+
+// RunTargets translates the input into several target languages. The input is
+// read and batched once, and the providers and the --rps limiter are shared by
+// all languages, which are translated concurrently. The TargetLanguage,
+// OutputPath and TMXExportPath fields of opts are replaced by each target.
+// Results are returned in the same order as targets.
+func RunTargets(ctx context.Context, opts Options, targets []Target) ([]Result, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("target language is required")
+	}
+	targetOpts := make([]Options, len(targets))
+	outputs := make(map[string]string, len(targets))
+	for i, t := range targets {
+		o := opts
+		o.TargetLanguage = t.Language
+		o.OutputPath = t.OutputPath
+		o.TMXExportPath = t.TMXExportPath
+		o.ReviewReportPath = t.ReviewReportPath
+		o.LengthReportPath = t.LengthReportPath
+		o, err := validateAndDefaultOptions(o)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := outputs[o.OutputPath]; ok {
+			return nil, fmt.Errorf("target languages %q and %q have the same output path %s", prev, o.TargetLanguage, o.OutputPath)
+		}
+		outputs[o.OutputPath] = o.TargetLanguage
+		targetOpts[i] = o
+	}
+	opts = targetOpts[0]
 
+	targetLabels := make([]string, len(targetOpts))
+	for i, o := range targetOpts {
+		targetLabels[i] = normalizeTargetLanguageLabel(o.TargetLanguage)
+	}
 	slog.Info("reading subtitles for translation",
 		"input_path", opts.InputPath,
 		"source_language", normalizeTargetLanguageLabel(opts.SourceLanguage),
-		"target_language", normalizeTargetLanguageLabel(opts.TargetLanguage))
+		"target_language", strings.Join(targetLabels, ", "))
 
-	subs, err := readSubtitles(opts.InputPath)
+	doc, err := readDocument(opts.InputPath, opts.PreserveIndex)
 	if err != nil {
-		return Result{}, err
+		return nil, err
+	}
+	for _, w := range doc.Warnings {
+		slog.Warn("subtitles file read with a warning", "input_path", opts.InputPath, "warning", w)
+	}
+	if doc.Cues, err = selectFlaggedCues(doc.Cues, opts); err != nil {
+		return nil, err
+	}
+	shared, err := newSharedRun(ctx, targetOpts, doc.Cues)
+	if err != nil {
+		return nil, err
+	}
+	shared.doc = doc
+	defer shared.transcript.close()
+
+	if len(targetOpts) == 1 {
+		res, err := shared.translateTarget(ctx, targetOpts[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Result{res}, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]Result, len(targetOpts))
+	errCh := make(chan error, 1)
+	var wg sync.WaitGroup
+	for i, o := range targetOpts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := shared.translateTarget(ctx, o)
+			if err != nil {
+				reportWorkerErrorAndCancel(cancel, errCh, fmt.Errorf("%s: %w", o.TargetLanguage, err))
+				return
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	if err := firstErr(errCh); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// TranslateDocument translates a parsed document into opts.TargetLanguage
+// without reading or writing subtitle files: it returns the translated
+// document, with the timing and untranslated metadata of doc, which is left
+// untouched. The output options (OutputPath, DryRun, WorkDir, TMXExportPath
+// and the report paths) are ignored, so Result.WrittenPath and the report
+// paths are empty.
+func TranslateDocument(ctx context.Context, doc *srt.Document, opts Options) (*srt.Document, Result, error) {
+	opts, err := defaultOptions(opts)
+	if err != nil {
+		return nil, Result{}, err
+	}
+	cues := make([]*srt.Subtitle, 0, len(doc.Cues))
+	for i, sub := range doc.Cues {
+		if sub == nil {
+			return nil, Result{}, fmt.Errorf("nil subtitle at position %d", i+1)
+		}
+		c := *sub
+		c.Text = srt.CleanText(c.Text)
+		cues = append(cues, &c)
+	}
+	checkIndexes(cues, opts.PreserveIndex)
+	if cues, err = selectFlaggedCues(cues, opts); err != nil {
+		return nil, Result{}, err
 	}
 
-	retryOptions := DefaultRetryOptions()
-	retryOptions.MaxAttempts = opts.RetryMaxAttempts
-	client := OpenAIClient{
-		BaseURL: opts.BaseURL, APIKey: opts.APIKey, Model: opts.Model,
-		Timeout:      opts.RequestTimeout,
-		RetryOptions: retryOptions,
+	shared, err := newSharedRun(ctx, []Options{opts}, cues)
+	if err != nil {
+		return nil, Result{}, err
 	}
+	defer shared.transcript.close()
 
-	batches, err := buildBatches(subs, opts.MaxBatchChars)
+	out, err := shared.translateCues(ctx, opts)
 	if err != nil {
-		return Result{}, err
+		return nil, Result{}, err
 	}
+	out.tracker.finish()
+	res := out.result
+	res.TokensUsed = out.tracker.tokensUsed()
+	res.CachedTokens = out.tracker.cachedTokensUsed()
+	subs, stripped := removeStrippedCues(out.subs, opts.SDH)
+	subs, censored := censorCues(subs, opts)
+	res.SDHStripped, res.Censored = stripped, censored
+	return doc.WithCues(attachOverrides(subs, shared.overrides)), res, nil
+}
 
-	translatedTexts, err := translateBatches(ctx, opts, &client, batches)
+// TranslateSubtitles is TranslateDocument for bare cues: it returns the
+// translated cues.
+func TranslateSubtitles(ctx context.Context, subs []*srt.Subtitle, opts Options) ([]*srt.Subtitle, Result, error) {
+	doc, res, err := TranslateDocument(ctx, &srt.Document{Cues: subs}, opts)
 	if err != nil {
-		return Result{}, err
+		return nil, Result{}, err
+	}
+	return doc.Cues, res, nil
+}
+
+// newSharedRun prepares the state shared by the target languages of a run
+// translating subs. The caller closes its transcript.
+func newSharedRun(ctx context.Context, targetOpts []Options, subs []*srt.Subtitle) (sharedRun, error) {
+	opts := targetOpts[0]
+	subs, overrides := detachOverrides(subs)
+	selected, unselected := opts.Selection.Split(subs)
+	if len(selected) == 0 {
+		return sharedRun{}, srt.ErrNoSelectedCues
+	}
+	if len(unselected) > 0 {
+		slog.Info("translating the selected cues only", "selected", len(selected), "unselected", len(unselected))
+	}
+	if !opts.Force {
+		for _, o := range targetOpts {
+			if err := checkNotTargetLanguage(selected, o.TargetLanguage); err != nil {
+				return sharedRun{}, err
+			}
+		}
+	}
+
+	providers, err := newBatchTranslators(opts)
+	if err != nil {
+		return sharedRun{}, err
+	}
+	if opts.CheckModel {
+		for _, p := range providers {
+			if v, ok := p.client.(modelValidator); ok {
+				if err := v.ValidateModel(ctx); err != nil {
+					return sharedRun{}, err
+				}
+			}
+		}
+	}
+
+	var reviewClient batchReviewer
+	if opts.Review != ReviewModeOff {
+		if reviewClient = firstProviderAs[batchReviewer](providers); reviewClient == nil {
+			return sharedRun{}, fmt.Errorf("review requires a chat model; provider %q does not support it", opts.Provider)
+		}
+	}
+	var condenseClient batchCondenser
+	if opts.LengthPolicy == LengthPolicyShorten && opts.MaxCPS > 0 {
+		if condenseClient = firstProviderAs[batchCondenser](providers); condenseClient == nil {
+			return sharedRun{}, fmt.Errorf("length policy %s requires a chat model; provider %q does not support it", LengthPolicyShorten, opts.Provider)
+		}
+	}
+
+	allBatches, err := buildBatches(selected, opts.MaxBatchChars)
+	if err != nil {
+		return sharedRun{}, err
+	}
+
+	var tr *transcript
+	if opts.TranscriptDir != "" {
+		if tr, err = openTranscript(opts.TranscriptDir); err != nil {
+			return sharedRun{}, err
+		}
+		slog.Info("recording translation transcript", "dir", tr.dir)
+	}
+
+	return sharedRun{
+		subs:           subs,
+		selected:       selected,
+		overrides:      overrides,
+		allBatches:     allBatches,
+		providers:      providers,
+		limiter:        newLimiter(opts.RPS),
+		reviewClient:   reviewClient,
+		condenseClient: condenseClient,
+		transcript:     tr,
+	}, nil
+}
+
+// sharedRun holds the state reused by every target language of a run.
+type sharedRun struct {
+	subs       []*srt.Subtitle // without leading ASS override blocks
+	selected   []*srt.Subtitle // the cues of subs to translate (see Options.Selection)
+	doc        *srt.Document   // the input, whose metadata is written untranslated
+	overrides  map[int]string  // leading ASS override blocks by cue index
+	allBatches []batch         // batches for all selected cues, reused when nothing is cached
+	providers  []namedTranslator
+	limiter    *rate.Limiter
+	// reviewClient runs the review pass; nil when it is disabled.
+	reviewClient batchReviewer
+	// condenseClient shortens cues over MaxCPS; nil unless the policy is shorten.
+	condenseClient batchCondenser
+	transcript     *transcript // nil unless TranscriptDir is set
+}
+
+// firstProviderAs returns the first provider implementing T (zero if none).
+func firstProviderAs[T any](providers []namedTranslator) T {
+	for _, p := range providers {
+		if c, ok := p.client.(T); ok {
+			return c
+		}
+	}
+	var zero T
+	return zero
+}
+
+// translateCues translates the cues of the run into the target language of
+// opts, without writing any file.
+func (s sharedRun) translateCues(ctx context.Context, opts Options) (targetOutput, error) {
+	var cache *translationCache
+	var err error
+	if opts.CacheDir != "" {
+		variant, err := cacheVariant(opts)
+		if err != nil {
+			return targetOutput{}, err
+		}
+		cache, err = openTranslationCache(opts.CacheDir, opts.SourceLanguage, opts.TargetLanguage, s.providers[0].name, variant)
+		if err != nil {
+			return targetOutput{}, err
+		}
+	}
+	pending, memoryTexts := s.selected, map[int]string{}
+	if opts.TMXImportPath != "" {
+		tm, err := readTMX(opts.TMXImportPath, opts.SourceLanguage, opts.TargetLanguage)
+		if err != nil {
+			return targetOutput{}, err
+		}
+		pending, memoryTexts = applyTranslationMemory(tm, s.selected)
+		slog.Info("translation memory loaded", "path", opts.TMXImportPath, "target_language", opts.TargetLanguage, "units", len(tm), "hits", len(memoryTexts))
+	}
+
+	pending, cachedTexts := lookupCachedTranslations(cache, pending)
+	if cache != nil {
+		slog.Info("translation cache lookup", "cache_dir", opts.CacheDir, "target_language", opts.TargetLanguage, "hits", len(cachedTexts), "pending", len(pending))
+	}
+
+	batches := s.allBatches
+	if len(pending) != len(s.selected) {
+		batches, err = buildBatches(pending, opts.MaxBatchChars)
+		if err != nil {
+			return targetOutput{}, err
+		}
+	}
+
+	providers := s.providers
+	if opts.BatchAPI && len(batches) > 0 {
+		if providers, err = batchAPIProviders(ctx, opts, providers, batches); err != nil {
+			return targetOutput{}, err
+		}
+	}
+
+	tracker := newProgressTracker(opts.Progress, opts.TargetLanguage, batches)
+	ctx = withTokenCounter(ctx, tracker)
+	tracker.start()
+
+	results, err := translateBatches(ctx, opts, providers, s.limiter, batches, cache, s.transcript, tracker)
+	if err != nil {
+		return targetOutput{}, err
+	}
+	translatedTexts := results.texts
+	reviewed := len(translatedTexts)
+
+	var review *ReviewReport
+	if s.reviewClient != nil {
+		rv := reviewer{
+			client:         s.reviewClient,
+			limiter:        s.limiter,
+			sourceLanguage: opts.SourceLanguage,
+			targetLanguage: opts.TargetLanguage,
+			mode:           opts.Review,
+			maxWorkers:     opts.MaxWorkers,
+			parseRetry:     parseRetryOptions(opts),
+			transcript:     s.transcript,
+		}
+		issues, err := rv.review(ctx, batches, translatedTexts)
+		if err != nil {
+			return targetOutput{}, err
+		}
+		review = &ReviewReport{TargetLanguage: opts.TargetLanguage, Mode: opts.Review, Reviewed: reviewed, Flagged: issues}
+		if opts.Review == ReviewModeFix && cache != nil {
+			storeReviewCorrections(cache, issues)
+		}
+	}
+
+	for idx, text := range cachedTexts {
+		translatedTexts[idx] = text
+	}
+	if opts.LineBreaks == LineBreaksReflow {
+		n := reflowTranslations(s.subs, translatedTexts, opts.TargetLanguage, opts.MaxLineLength, opts.MaxLines)
+		slog.Debug("translated cues broken again into balanced lines", "target_language", opts.TargetLanguage, "cues", n)
+	}
+	localized := 0
+	if l := newLocalizer(opts, s.subs); l != nil {
+		var unconverted []int
+		localized, unconverted = l.localize(s.subs, translatedTexts)
+		logUnconvertedUnits(opts.TargetLanguage, unconverted)
+	}
+	for idx, text := range memoryTexts {
+		translatedTexts[idx] = text
+	}
+	seriesTerms := opts.SeriesContext.learn(opts.TargetLanguage, s.selected, translatedTexts)
+
+	enforcer := lengthEnforcer{
+		client:          s.condenseClient,
+		limiter:         s.limiter,
+		targetLanguage:  opts.TargetLanguage,
+		policy:          opts.LengthPolicy,
+		maxCPS:          opts.MaxCPS,
+		maxLineLength:   opts.MaxLineLength,
+		maxLines:        opts.MaxLines,
+		parseRetry:      parseRetryOptions(opts),
+		parseStrictness: opts.ParseStrictness,
+		transcript:      s.transcript,
+	}
+	lengths, err := enforcer.enforce(ctx, s.subs, translatedTexts)
+	if err != nil {
+		return targetOutput{}, err
+	}
+	logLengthViolations(opts.TargetLanguage, lengths.violations)
+	rtlMarked := 0
+	if rtl.IsLanguage(opts.TargetLanguage) {
+		logReversedPunctuation(opts.TargetLanguage, translatedTexts)
+		if opts.RTLMarks {
+			rtlMarked = markRTLTranslations(translatedTexts)
+		}
 	}
 
-	outSubs := applyTranslations(subs, translatedTexts)
+	out := targetOutput{
+		subs:           applyTranslations(s.subs, translatedTexts),
+		review:         review,
+		lengths:        lengths,
+		checkedLengths: enforcer.enabled(),
+		tracker:        tracker,
+		result: Result{
+			TargetLanguage: opts.TargetLanguage,
+			Batches:        len(batches),
+			CacheHits:      len(cachedTexts),
+			MemoryHits:     len(memoryTexts),
+			TagMismatches:  results.tagMismatches,
+			Blocked:        results.blocked,
+			Parse:          results.parse,
 
-	writtenPath, err := writeOutput(opts, outSubs)
+			LengthWrapped:    lengths.wrapped,
+			LengthShortened:  lengths.shortened,
+			LengthViolations: len(lengths.violations),
+			Localized:        localized,
+			SeriesTerms:      seriesTerms,
+			RTLMarked:        rtlMarked,
+
+			TranscriptDir: transcriptDir(s.transcript),
+			Unselected:    len(s.subs) - len(s.selected),
+		},
+	}
+	if review != nil {
+		out.result.ReviewFlagged = len(review.Flagged)
+		out.result.ReviewCorrected = review.corrected()
+	}
+	return out, nil
+}
+
+// targetOutput is the translation of a target language, before it is written.
+type targetOutput struct {
+	subs           []*srt.Subtitle // without the leading ASS override blocks
+	result         Result          // without TokensUsed and the written paths
+	review         *ReviewReport   // nil when the review pass is disabled
+	lengths        lengthResult
+	checkedLengths bool // whether a length limit is set
+	tracker        *progressTracker
+}
+
+func (s sharedRun) translateTarget(ctx context.Context, opts Options) (Result, error) {
+	out, err := s.translateCues(ctx, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	subs, stripped := removeStrippedCues(out.subs, opts.SDH)
+	subs, censored := censorCues(subs, opts)
+	writtenPath, err := writeOutput(opts, s.doc.WithCues(attachOverrides(subs, s.overrides)))
 	if err != nil {
 		return Result{}, err
 	}
 
-	return Result{WrittenPath: writtenPath, Batches: len(batches)}, nil
+	var sideBySidePath string
+	if opts.DryRun {
+		sideBySidePath = defaultSideBySidePath(writtenPath, opts.SideBySideFormat)
+		if err := writeSideBySide(sideBySidePath, opts.SideBySideFormat, opts.SourceLanguage, opts.TargetLanguage, sideBySideRows(s.subs, subs)); err != nil {
+			return Result{}, fmt.Errorf("write side-by-side review file: %w", err)
+		}
+	}
+
+	if opts.TMXExportPath != "" {
+		sources, translated := s.translatedPairs(out.subs)
+		if err := writeTMX(opts.TMXExportPath, opts.SourceLanguage, opts.TargetLanguage, sources, translated); err != nil {
+			return Result{}, fmt.Errorf("export tmx: %w", err)
+		}
+		slog.Info("translation memory exported", "path", opts.TMXExportPath)
+	}
+
+	var reviewReportPath string
+	if out.review != nil {
+		reviewReportPath = opts.ReviewReportPath
+		if reviewReportPath == "" {
+			reviewReportPath = defaultReviewReportPath(writtenPath)
+		}
+		if err := writeReviewReport(reviewReportPath, *out.review); err != nil {
+			return Result{}, fmt.Errorf("write review report: %w", err)
+		}
+	}
+
+	lengthReportPath := opts.LengthReportPath
+	if lengthReportPath == "" && opts.LengthPolicy == LengthPolicyReport && out.checkedLengths {
+		lengthReportPath = defaultLengthReportPath(writtenPath)
+	}
+	if lengthReportPath != "" {
+		report := LengthReport{TargetLanguage: opts.TargetLanguage, MaxCPS: opts.MaxCPS, MaxLineLength: opts.MaxLineLength, MaxLines: opts.MaxLines, Violations: out.lengths.violations}
+		if err := writeLengthReport(lengthReportPath, report); err != nil {
+			return Result{}, fmt.Errorf("write length report: %w", err)
+		}
+	}
+
+	out.tracker.finish()
+
+	res := out.result
+	res.WrittenPath = writtenPath
+	res.TokensUsed = out.tracker.tokensUsed()
+	res.CachedTokens = out.tracker.cachedTokensUsed()
+	res.ReviewReportPath = reviewReportPath
+	res.LengthReportPath = lengthReportPath
+	res.SDHStripped = stripped
+	res.Censored = censored
+	res.SideBySidePath = sideBySidePath
+	return res, nil
+}
+
+// translatedPairs returns the selected cues and their translations in
+// translated, the output of every cue, matched by position.
+func (s sharedRun) translatedPairs(translated []*srt.Subtitle) ([]*srt.Subtitle, []*srt.Subtitle) {
+	if len(s.selected) == len(s.subs) {
+		return s.subs, translated
+	}
+	selected := make(map[int]bool, len(s.selected))
+	for _, sub := range s.selected {
+		selected[sub.Idx] = true
+	}
+	var out []*srt.Subtitle
+	for _, t := range translated {
+		if selected[t.Idx] {
+			out = append(out, t)
+		}
+	}
+	return s.selected, out
+}
+
+// checkNotTargetLanguage fails when the subtitles already look like they are in
+// targetLanguage (usually a mislabeled file), to avoid paying for a no-op run.
+func checkNotTargetLanguage(subs []*srt.Subtitle, targetLanguage string) error {
+	texts := make([]string, 0, len(subs))
+	for _, s := range subs {
+		texts = append(texts, s.Text)
+	}
+	detected, ok := langdetect.Detect(strings.Join(texts, "\n"))
+	if !ok || !langdetect.Matches(detected.Language, targetLanguage) {
+		return nil
+	}
+	return fmt.Errorf("%w: input looks like %s (confidence %.0f%%), the same as the target language %q; use --force to translate anyway",
+		ErrAlreadyTargetLanguage, langdetect.Name(detected.Language), detected.Confidence*100, targetLanguage)
+}
+
+// applyTranslationMemory splits subs into cues that still need translation and
+// translations found in tm keyed by cue idx.
+func applyTranslationMemory(tm translationMemory, subs []*srt.Subtitle) ([]*srt.Subtitle, map[int]string) {
+	found := make(map[int]string)
+	pending := make([]*srt.Subtitle, 0, len(subs))
+	for _, s := range subs {
+		if t, ok := tm[s.Text]; ok {
+			found[s.Idx] = t
+			continue
+		}
+		pending = append(pending, s)
+	}
+	return pending, found
+}
+
+// lookupCachedTranslations splits subs into cues that still need translation and
+// cached translations keyed by cue idx. With a nil cache, every cue is pending.
+func lookupCachedTranslations(cache *translationCache, subs []*srt.Subtitle) ([]*srt.Subtitle, map[int]string) {
+	cached := make(map[int]string)
+	if cache == nil {
+		return subs, cached
+	}
+	pending := make([]*srt.Subtitle, 0, len(subs))
+	for _, s := range subs {
+		if t, ok := cache.lookup(s.Text); ok {
+			cached[s.Idx] = t
+			continue
+		}
+		pending = append(pending, s)
+	}
+	return pending, cached
 }
 
 type batch struct {
@@ -107,6 +889,12 @@ type batch struct {
 	texts []string
 }
 
+// split halves a batch (len(idxs) must be > 1).
+func (b batch) split() (batch, batch) {
+	mid := len(b.idxs) / 2
+	return batch{idxs: b.idxs[:mid], texts: b.texts[:mid]}, batch{idxs: b.idxs[mid:], texts: b.texts[mid:]}
+}
+
 func validateAndDefaultOptions(opts Options) (Options, error) {
 	if opts.InputPath == "" {
 		return Options{}, errors.New("input path is required")
@@ -114,12 +902,57 @@ func validateAndDefaultOptions(opts Options) (Options, error) {
 	if opts.WorkDir == "" {
 		return Options{}, errors.New("workdir is required")
 	}
+	opts, err := defaultOptions(opts)
+	if err != nil {
+		return Options{}, err
+	}
+	if opts.OutputPath == "" {
+		return Options{}, errors.New("output is required")
+	}
+	return opts, nil
+}
+
+// defaultOptions validates opts and fills in the defaults, except for the file
+// options.
+func defaultOptions(opts Options) (Options, error) {
 	if opts.TargetLanguage == "" {
 		return Options{}, errors.New("target language is required")
 	}
-	if opts.Model == "" {
+	opts.Provider = normalizeProvider(opts.Provider)
+	if opts.Provider == "" {
+		opts.Provider = DefaultProvider
+	}
+	if !isValidProvider(opts.Provider) {
+		return Options{}, fmt.Errorf("invalid provider %q (supported: %s, %s, %s)", opts.Provider, ProviderOpenAI, ProviderGemini, ProviderDeepL)
+	}
+	if opts.Provider != ProviderDeepL && opts.Model == "" {
 		return Options{}, errors.New("model is required")
 	}
+	if opts.Provider == ProviderOpenAI {
+		opts.Provider = chatProvider(opts.Model, opts.BaseURL)
+	}
+	opts.SafetyThreshold = normalizeSafetyThreshold(opts.SafetyThreshold)
+	if opts.SafetyThreshold == "" {
+		opts.SafetyThreshold = DefaultSafetyThreshold
+	}
+	if !isValidSafetyThreshold(opts.SafetyThreshold) {
+		return Options{}, fmt.Errorf("invalid safety threshold %q (supported: %s, %s, %s, %s, %s, %s)", opts.SafetyThreshold,
+			SafetyBlockNone, SafetyOff, SafetyBlockHigh, SafetyBlockMedium, SafetyBlockLow, SafetyDefault)
+	}
+	if opts.Provider == ProviderDeepL && opts.APIKey == "" {
+		return Options{}, errors.New("api key is required for deepl")
+	}
+	fallbackModels := make([]string, 0, len(opts.FallbackModels))
+	for _, m := range opts.FallbackModels {
+		if m = strings.TrimSpace(m); m != "" {
+			fallbackModels = append(fallbackModels, m)
+		}
+	}
+	opts.FallbackModels = fallbackModels
+	opts.Formality = normalizeFormality(opts.Formality)
+	if !isValidFormality(opts.Formality) {
+		return Options{}, fmt.Errorf("invalid formality %q (supported: %s, %s, %s, %s, %s)", opts.Formality, FormalityDefault, FormalityMore, FormalityLess, FormalityPreferMore, FormalityPreferLess)
+	}
 	if opts.MaxBatchChars <= 0 {
 		opts.MaxBatchChars = DefaultMaxBatchChars
 	}
@@ -135,29 +968,180 @@ func validateAndDefaultOptions(opts Options) (Options, error) {
 	if opts.RequestTimeout < 0 {
 		opts.RequestTimeout = 0 // disable timeout if negative
 	}
-	if opts.OutputPath == "" {
-		return Options{}, errors.New("output is required")
+	opts.ResponseMode = normalizeResponseMode(opts.ResponseMode)
+	if opts.ResponseMode == "" {
+		opts.ResponseMode = DefaultResponseMode
+	}
+	if !isValidResponseMode(opts.ResponseMode) {
+		return Options{}, fmt.Errorf("invalid response mode %q (supported: %s, %s, %s)", opts.ResponseMode, ResponseModeAuto, ResponseModeNDJSON, ResponseModeJSONSchema)
+	}
+	opts.ParseStrictness = normalizeParseStrictness(opts.ParseStrictness)
+	if opts.ParseStrictness == "" {
+		opts.ParseStrictness = DefaultParseStrictness
+	}
+	if !isValidParseStrictness(opts.ParseStrictness) {
+		return Options{}, fmt.Errorf("invalid parse mode %q (supported: %s, %s, %s)", opts.ParseStrictness, ParseStrict, ParseTolerant, ParseSalvage)
+	}
+	opts.Review = normalizeReviewMode(opts.Review)
+	if !isValidReviewMode(opts.Review) {
+		return Options{}, fmt.Errorf("invalid review mode %q (supported: %s, %s)", opts.Review, ReviewModeFix, ReviewModeReport)
+	}
+	if opts.MaxCPS < 0 || opts.MaxLineLength < 0 || opts.MaxLines < 0 {
+		return Options{}, errors.New("max cps, max line length and max lines must not be negative")
+	}
+	if opts.LengthHints && opts.MaxLineLength == 0 && opts.MaxLines == 0 {
+		return Options{}, errors.New("length hints require a max line length or max lines")
+	}
+	opts.LengthPolicy = normalizeLengthPolicy(opts.LengthPolicy)
+	if opts.LengthPolicy == "" {
+		opts.LengthPolicy = DefaultLengthPolicy
+	}
+	if !isValidLengthPolicy(opts.LengthPolicy) {
+		return Options{}, fmt.Errorf("invalid length policy %q (supported: %s, %s, %s)", opts.LengthPolicy, LengthPolicyWrap, LengthPolicyShorten, LengthPolicyReport)
+	}
+	opts.LineBreaks = normalizeLineBreaks(opts.LineBreaks)
+	if opts.LineBreaks == "" {
+		opts.LineBreaks = DefaultLineBreaks
+	}
+	if !isValidLineBreaks(opts.LineBreaks) {
+		return Options{}, fmt.Errorf("invalid line break mode %q (supported: %s, %s)", opts.LineBreaks, LineBreaksPreserve, LineBreaksReflow)
+	}
+	if err := validateSampling(opts.sampling()); err != nil {
+		return Options{}, err
+	}
+	opts.SideBySideFormat = normalizeSideBySideFormat(opts.SideBySideFormat)
+	if opts.SideBySideFormat == "" {
+		opts.SideBySideFormat = DefaultSideBySide
+	}
+	if !isValidSideBySideFormat(opts.SideBySideFormat) {
+		return Options{}, fmt.Errorf("invalid side-by-side format %q (supported: %s, %s, %s)", opts.SideBySideFormat, SideBySideMarkdown, SideBySideCSV, SideBySideHTML)
+	}
+	opts.SDH = normalizeSDHMode(opts.SDH)
+	if opts.SDH == "" {
+		opts.SDH = DefaultSDH
+	}
+	if !isValidSDHMode(opts.SDH) {
+		return Options{}, fmt.Errorf("invalid sdh mode %q (supported: %s, %s, %s)", opts.SDH, SDHKeep, SDHStrip, SDHGenerate)
+	}
+	if opts.SDH != SDHKeep && opts.Provider == ProviderDeepL {
+		return Options{}, fmt.Errorf("sdh mode %s requires a chat model; provider %q does not support it", opts.SDH, opts.Provider)
+	}
+	if opts.LengthHints && opts.Provider == ProviderDeepL {
+		return Options{}, fmt.Errorf("length hints require a chat model; provider %q does not support them", opts.Provider)
+	}
+	if opts.SeriesContext != nil && opts.Provider == ProviderDeepL {
+		return Options{}, fmt.Errorf("series context requires a chat model; provider %q does not support it", opts.Provider)
+	}
+	if opts.BatchAPI {
+		if opts.Provider == ProviderDeepL {
+			return Options{}, fmt.Errorf("the batch api requires a chat model; provider %q does not support it", opts.Provider)
+		}
+		if opts.BatchStateDir == "" {
+			return Options{}, errors.New("the batch api requires a directory for the job state")
+		}
+	}
+	if opts.CensorStyle == "" {
+		opts.CensorStyle = censor.DefaultStyle
+	}
+	opts.CensorStyle = censor.NormalizeStyle(opts.CensorStyle)
+	if !censor.IsValidStyle(opts.CensorStyle) {
+		return Options{}, fmt.Errorf("invalid censor style %q (supported: %s, %s, %s)", opts.CensorStyle, censor.StyleStars, censor.StyleRemoveCue, censor.StyleBeepText)
+	}
+	if opts.CensorListPath != "" && opts.censorList == nil {
+		var err error
+		if opts.censorList, err = censor.Load(opts.CensorListPath); err != nil {
+			return Options{}, fmt.Errorf("load censor list: %w", err)
+		}
 	}
 	return opts, nil
 }
 
-func readSubtitles(inputPath string) ([]*srt.Subtitle, error) {
+// lineLimits returns the limits sent with every cue: none unless LengthHints
+// is set.
+func (o Options) lineLimits() lineLimits {
+	if !o.LengthHints {
+		return lineLimits{}
+	}
+	return lineLimits{maxLen: o.MaxLineLength, maxLines: o.MaxLines}
+}
+
+func (o Options) sampling() SamplingOptions {
+	return SamplingOptions{
+		Temperature:     o.Temperature,
+		TopP:            o.TopP,
+		MaxOutputTokens: o.MaxOutputTokens,
+		ReasoningEffort: o.ReasoningEffort,
+	}
+}
+
+// readDocument reads inputPath, renumbering the cues when their indexes are
+// not sequential (or, with preserveIndex, not unique).
+func readDocument(inputPath string, preserveIndex bool) (*srt.Document, error) {
 	in, err := os.Open(inputPath)
 	if err != nil {
 		return nil, err
 	}
 	defer fs.CloseOrLog(in, inputPath)
 
-	subs, err := srt.ReadAll(in)
+	doc, err := srt.Read(in)
 	if err != nil {
 		return nil, err
 	}
-	err = srt.ValidateSequentialIdx(subs)
+	checkIndexes(doc.Cues, preserveIndex)
+	return doc, nil
+}
+
+// selectFlaggedCues drops the cues excluded by opts.OnlyForced and
+// opts.SkipSDH, renumbering the rest unless opts.PreserveIndex is set.
+func selectFlaggedCues(subs []*srt.Subtitle, opts Options) ([]*srt.Subtitle, error) {
+	if !opts.OnlyForced && !opts.SkipSDH {
+		return subs, nil
+	}
+	kept := make([]*srt.Subtitle, 0, len(subs))
+	for _, s := range subs {
+		if (opts.OnlyForced && !s.Forced) || (opts.SkipSDH && s.SDH) {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	if opts.OnlyForced && len(kept) == 0 {
+		return nil, ErrNoForcedCues
+	}
+	if len(kept) < len(subs) {
+		slog.Info("cues left out by flag", "only_forced", opts.OnlyForced, "skip_sdh", opts.SkipSDH, "dropped", len(subs)-len(kept))
+		if !opts.PreserveIndex {
+			srt.Reindex(kept)
+		}
+	}
+	return kept, nil
+}
+
+// checkIndexes renumbers subs from 1 when their indexes can't be kept:
+// translations are matched to their cues by index.
+func checkIndexes(subs []*srt.Subtitle, preserveIndex bool) {
+	var err error
+	if preserveIndex {
+		err = validateUniqueIdx(subs)
+	} else {
+		err = srt.ValidateSequentialIdx(subs)
+	}
 	if err != nil {
 		slog.Warn("invalid subtitles index; reindexing...", "err", err)
 		srt.Reindex(subs)
 	}
-	return subs, nil
+}
+
+// validateUniqueIdx ensures no two cues share an index: translations are
+// matched to their cues by index.
+func validateUniqueIdx(subs []*srt.Subtitle) error {
+	seen := make(map[int]struct{}, len(subs))
+	for _, s := range subs {
+		if _, ok := seen[s.Idx]; ok {
+			return &srt.ParseError{Msg: fmt.Sprintf("duplicated subtitle index %d", s.Idx)}
+		}
+		seen[s.Idx] = struct{}{}
+	}
+	return nil
 }
 
 func buildBatches(subs []*srt.Subtitle, maxBatchChars int) ([]batch, error) {
@@ -173,41 +1157,85 @@ func buildBatches(subs []*srt.Subtitle, maxBatchChars int) ([]batch, error) {
 	return batches, nil
 }
 
+// batchResults is the outcome of translateBatches.
+type batchResults struct {
+	texts         map[int]string // translated text by cue idx
+	tagMismatches int
+	blocked       int
+	parse         ParseStats
+}
+
 func translateBatches(
 	ctx context.Context,
 	opts Options,
-	client *OpenAIClient,
+	providers []namedTranslator,
+	limiter *rate.Limiter,
 	batches []batch,
-) (map[int]string, error) {
-	translatedTexts := make(map[int]string)
-	var translatedMu sync.Mutex
-
+	cache *translationCache,
+	tr *transcript,
+	tracker *progressTracker,
+) (batchResults, error) {
 	jobs := make(chan batch)
 	errCh := make(chan error, 1)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	limiter := newLimiter(opts.RPS)
-
 	remaining := atomic.Int64{}
 	remaining.Store(int64(len(batches)))
 
-	parseRetry := RetryOptions{
-		MaxAttempts: opts.RetryParseMaxAttempts,
-		BaseDelay:   250 * time.Millisecond,
-		MaxDelay:    3 * time.Second,
-		Jitter:      0.2,
+	runner := &batchRunner{
+		limiter:          limiter,
+		providers:        providers,
+		sourceLanguage:   opts.SourceLanguage,
+		targetLanguage:   opts.TargetLanguage,
+		parseRetry:       parseRetryOptions(opts),
+		parseStrictness:  opts.ParseStrictness,
+		cache:            cache,
+		protectTags:      !opts.SkipTagProtection,
+		retryTagMismatch: opts.RetryTagMismatch,
+		allowEmpty:       opts.SDH == SDHStrip,
+		limits:           opts.lineLimits(),
+		translatedTexts:  make(map[int]string),
+		transcript:       tr,
+		progress:         tracker,
+	}
+
+	var controller *concurrencyController
+	if opts.AdaptiveWorkers {
+		controller = newConcurrencyController(opts.MaxWorkers)
+		slog.Debug("adaptive translation concurrency enabled", "concurrency", controller.current(), "max_workers", opts.MaxWorkers)
 	}
 
 	worker := func() {
-		for b := range jobs {
+		for {
+			if controller != nil {
+				if err := controller.acquire(ctx); err != nil {
+					return
+				}
+			}
+			b, ok := <-jobs
+			if !ok {
+				if controller != nil {
+					controller.release(false)
+				}
+				return
+			}
 			n := remaining.Add(-1)
 			slog.Info("Processing batch...", "batch_size", len(b.idxs), "remaining_batches", n)
-			if err := runOneBatch(ctx, limiter, client, opts.SourceLanguage, opts.TargetLanguage, b, parseRetry, &translatedMu, translatedTexts); err != nil {
+			var err error
+			if controller != nil {
+				batchCtx, observer := withThrottleObserver(ctx, controller)
+				err = runner.runOneBatch(batchCtx, b)
+				controller.release(err == nil && !observer.throttled.Load())
+			} else {
+				err = runner.runOneBatch(ctx, b)
+			}
+			if err != nil {
 				reportWorkerErrorAndCancel(cancel, errCh, err)
 				return
 			}
+			runner.progress.batchDone(len(b.idxs))
 		}
 	}
 
@@ -224,13 +1252,28 @@ func translateBatches(
 
 	wg.Wait()
 	if err := firstErr(errCh); err != nil {
-		return nil, err
+		return batchResults{}, err
 	}
 	if err := nonCanceledContextErr(ctx); err != nil {
-		return nil, err
+		return batchResults{}, err
 	}
 
-	return translatedTexts, nil
+	return batchResults{
+		texts:         runner.translatedTexts,
+		tagMismatches: int(runner.tagMismatches.Load()),
+		blocked:       int(runner.blocked.Load()),
+		parse:         runner.parseStats,
+	}, nil
+}
+
+// parseRetryOptions is the backoff used when a response can't be parsed.
+func parseRetryOptions(opts Options) RetryOptions {
+	return RetryOptions{
+		MaxAttempts: opts.RetryParseMaxAttempts,
+		BaseDelay:   250 * time.Millisecond,
+		MaxDelay:    3 * time.Second,
+		Jitter:      0.2,
+	}
 }
 
 func newLimiter(rps float64) *rate.Limiter {
@@ -278,31 +1321,157 @@ func nonCanceledContextErr(ctx context.Context) error {
 	return ctx.Err()
 }
 
-func runOneBatch(
-	ctx context.Context,
-	limiter *rate.Limiter,
-	client *OpenAIClient,
-	sourceLanguage string,
-	targetLanguage string,
-	b batch,
-	parseRetry RetryOptions,
-	translatedMu *sync.Mutex,
-	translatedTexts map[int]string,
-) error {
+// batchRunner holds the state shared by all translation workers.
+type batchRunner struct {
+	limiter        *rate.Limiter
+	providers      []namedTranslator // primary first, then fallbacks
+	sourceLanguage string
+	targetLanguage string
+	parseRetry     RetryOptions
+	// parseStrictness is the ParseStrictness of the options.
+	parseStrictness string
+	cache           *translationCache // optional
+	transcript      *transcript       // optional
+	progress        *progressTracker  // optional
+
+	// protectTags replaces inline tags with placeholders before sending a batch
+	// (to the providers that don't keep them as-is, see keepsTags) and checks
+	// them in the translations; retryTagMismatch retries a batch whose tag
+	// structure changed.
+	protectTags      bool
+	retryTagMismatch bool
+	tagMismatches    atomic.Int64
+	// blocked counts the cues left untranslated because the content filters
+	// of every provider blocked them.
+	blocked atomic.Int64
+	// allowEmpty accepts empty translations (cues stripped with SDHStrip)
+	// without reporting their tags as changed.
+	allowEmpty bool
+	// limits are sent with every cue (see Options.LengthHints).
+	limits lineLimits
+
+	parseMu    sync.Mutex
+	parseStats ParseStats
+
+	translatedMu    sync.Mutex
+	translatedTexts map[int]string
+}
+
+func (r *batchRunner) runOneBatch(ctx context.Context, b batch) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
-	if limiter != nil {
-		if err := limiter.Wait(ctx); err != nil {
+
+	validated, err := r.translateBatch(ctx, b)
+	var parseErr *batchParseError
+	isParseErr := errors.As(err, &parseErr)
+	blocked := isBlocked(err)
+	if (isParseErr || blocked) && len(b.idxs) > 1 {
+		// A single pathological cue shouldn't poison the whole batch: bisect it
+		// down to single cues before giving up. A response cut at the output
+		// token limit fits once the batch is smaller, and the cues that trip
+		// the content filters end up alone.
+		left, right := b.split()
+		msg := "batch keeps returning invalid output; splitting it"
+		if isTruncated(err) {
+			msg = "model response truncated at the output token limit; splitting the batch"
+		} else if blocked {
+			msg = "content filters of the provider blocked the batch; splitting it to isolate the cues"
+		}
+		slog.Warn(msg, "batch_size", len(b.idxs), "first_idx", b.idxs[0], "last_idx", b.idxs[len(b.idxs)-1], "err", err)
+		if err := r.runOneBatch(ctx, left); err != nil {
 			return err
 		}
+		return r.runOneBatch(ctx, right)
+	}
+	if blocked {
+		// The cue is kept in the source language rather than failing the run.
+		slog.Warn("content filters of the provider blocked the cue; leaving it untranslated", "idx", b.idxs[0], "text", b.texts[0], "err", err)
+		r.blocked.Add(1)
+		r.translatedMu.Lock()
+		r.translatedTexts[b.idxs[0]] = b.texts[0]
+		r.translatedMu.Unlock()
+		return nil
 	}
-
-	payload, err := FormatForTranslation(b.idxs, b.texts)
 	if err != nil {
+		if isParseErr {
+			return fmt.Errorf("cue %d: %w", b.idxs[0], err)
+		}
 		return err
 	}
 
+	r.translatedMu.Lock()
+	for _, pl := range validated {
+		r.translatedTexts[pl.Idx] = pl.Text
+	}
+	r.translatedMu.Unlock()
+
+	if r.cache != nil {
+		sources := make(map[int]string, len(b.idxs))
+		for i, idx := range b.idxs {
+			sources[idx] = b.texts[i]
+		}
+		entries := make(map[string]string, len(validated))
+		for _, pl := range validated {
+			entries[sources[pl.Idx]] = pl.Text
+		}
+		// The cache is best-effort: a write failure must not fail the run.
+		if err := r.cache.store(entries); err != nil {
+			slog.Warn("failed to update translation cache", "err", err)
+		}
+	}
+	return nil
+}
+
+// maskBatchTags returns the texts of b with the inline tags replaced by
+// placeholders, and the tags by cue idx, when protect is set (the texts as-is
+// otherwise).
+func maskBatchTags(b batch, protect bool) ([]string, map[int][]string) {
+	if !protect {
+		return b.texts, nil
+	}
+	texts := make([]string, len(b.texts))
+	tags := make(map[int][]string)
+	for i, text := range b.texts {
+		masked, t := protectTags(text)
+		texts[i] = masked
+		if len(t) > 0 {
+			tags[b.idxs[i]] = t
+		}
+	}
+	return texts, tags
+}
+
+// translateBatch sends b through the provider chain and returns the validated
+// lines.
+func (r *batchRunner) translateBatch(ctx context.Context, b batch) ([]ParsedLine, error) {
+	for i, p := range r.providers {
+		// The tags are masked for each provider, since DeepL keeps them itself.
+		texts, tags := maskBatchTags(b, r.protectTags && !p.keepsTags())
+		payload, err := formatForTranslation(b.idxs, texts, r.limits)
+		if err != nil {
+			return nil, err
+		}
+		if r.limiter != nil {
+			if err := r.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		validated, err := r.translateWithParseRetry(withCircuitBreaker(ctx, p.breaker), p, b, payload, tags)
+		if err == nil {
+			return validated, nil
+		}
+		if i == len(r.providers)-1 || !isFallbackEligible(err) || isTruncated(err) {
+			return nil, err
+		}
+		slog.Warn("translation provider exhausted retries; falling back to next provider",
+			"provider", p.name, "fallback", r.providers[i+1].name, "batch_size", len(b.idxs), "err", err)
+	}
+	return nil, errors.New("no translation provider configured")
+}
+
+func (r *batchRunner) translateWithParseRetry(ctx context.Context, p namedTranslator, b batch, payload string, tags map[int][]string) ([]ParsedLine, error) {
+	parseRetry := r.parseRetry
 	// Defensive defaults.
 	if parseRetry.MaxAttempts <= 0 {
 		parseRetry.MaxAttempts = 1
@@ -319,54 +1488,198 @@ func runOneBatch(
 	var lastParseErr error
 	for attempt := 1; attempt <= parseRetry.MaxAttempts; attempt++ {
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return nil, ctx.Err()
 		}
 
-		resp, err := client.TranslateBatch(ctx, sourceLanguage, targetLanguage, payload)
+		entry := TranscriptEntry{
+			Time:           time.Now(),
+			Kind:           TranscriptKindTranslate,
+			TargetLanguage: r.targetLanguage,
+			Provider:       p.name,
+			FirstIdx:       b.idxs[0],
+			LastIdx:        b.idxs[len(b.idxs)-1],
+			Cues:           len(b.idxs),
+			Attempt:        attempt,
+		}
+		resp, err := p.client.TranslateBatch(ctx, r.sourceLanguage, r.targetLanguage, payload)
+		entry.DurationMS = time.Since(entry.Time).Milliseconds()
+		var tErr *truncatedResponseError
+		if errors.As(err, &tErr) {
+			// The same request would be cut again: fail the batch so it is
+			// split, without parse retries.
+			r.transcript.record(entry, payload, tErr.partial, err)
+			r.recordTruncated()
+			return nil, &batchParseError{err: err}
+		}
 		if err != nil {
-			return err
+			r.transcript.record(entry, payload, "", err)
+			return nil, err
 		}
 
 		slog.Debug("received translation response", "request", payload, "response", resp, "batch_size", len(b.idxs), "attempt", attempt)
 
-		parsed, err := ParseTranslatedLines(resp)
+		parsed, diag, err := ParseTranslatedLinesWithDiagnostics(resp, r.parseStrictness)
 		if err != nil {
+			r.transcript.record(entry, payload, resp, err)
+			r.recordParse(diag, err)
 			lastParseErr = err
 			if attempt < parseRetry.MaxAttempts {
 				slog.Warn("invalid translation output; retrying batch", "attempt", attempt, "max_attempts", parseRetry.MaxAttempts, "err", err)
 				if err := sleepWithContext(ctx, computeBackoff(attempt, parseRetry)); err != nil {
-					return err
+					return nil, err
 				}
 				continue
 			}
-			return err
+			return nil, &batchParseError{err: err}
 		}
 
 		validated, err := validateParsedBatch(expected, b.idxs, parsed)
+		r.transcript.record(entry, payload, resp, err)
+		r.recordParse(diag, err)
 		if err != nil {
 			lastParseErr = err
 			if attempt < parseRetry.MaxAttempts {
 				slog.Warn("unexpected translation output; retrying batch", "attempt", attempt, "max_attempts", parseRetry.MaxAttempts, "err", err)
 				if err := sleepWithContext(ctx, computeBackoff(attempt, parseRetry)); err != nil {
-					return err
+					return nil, err
 				}
 				continue
 			}
-			return err
+			return nil, &batchParseError{err: err}
 		}
 
-		translatedMu.Lock()
-		for _, pl := range validated {
-			translatedTexts[pl.Idx] = pl.Text
+		if r.protectTags {
+			var restored []ParsedLine
+			var mismatched []int
+			if p.keepsTags() {
+				restored, mismatched = validated, keptTagMismatches(b, validated, r.allowEmpty)
+			} else {
+				restored, mismatched = restoreBatchTags(validated, tags, r.allowEmpty)
+			}
+			if len(mismatched) > 0 && r.retryTagMismatch && attempt < parseRetry.MaxAttempts {
+				slog.Warn("inline tags changed in translation; retrying batch", "attempt", attempt, "max_attempts", parseRetry.MaxAttempts, "idxs", mismatched)
+				if err := sleepWithContext(ctx, computeBackoff(attempt, parseRetry)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if len(mismatched) > 0 {
+				r.tagMismatches.Add(int64(len(mismatched)))
+				slog.Warn("inline tags changed in translation; review these cues", "idxs", mismatched)
+			}
+			validated = restored
 		}
-		translatedMu.Unlock()
-		return nil
+		return validated, nil
 	}
 
 	if lastParseErr != nil {
-		return lastParseErr
+		return nil, &batchParseError{err: lastParseErr}
+	}
+	return nil, errors.New("translation batch failed for unknown reasons")
+}
+
+// recordTruncated counts a response cut at the output token limit.
+func (r *batchRunner) recordTruncated() {
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+	r.parseStats.Truncated++
+}
+
+// recordParse adds the diagnostics of a parsed response to the stats of the
+// run, or a failure when err is set.
+func (r *batchRunner) recordParse(diag ParseDiagnostics, err error) {
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+	if err != nil {
+		r.parseStats.Failures++
+		return
+	}
+	r.parseStats.add(diag)
+}
+
+// restoreBatchTags puts the inline tags back into the translated lines and
+// returns the idxs whose tag structure changed (restored best-effort). With
+// allowEmpty, empty lines are kept empty.
+func restoreBatchTags(lines []ParsedLine, tags map[int][]string, allowEmpty bool) ([]ParsedLine, []int) {
+	restored := make([]ParsedLine, len(lines))
+	var mismatched []int
+	for i, pl := range lines {
+		if allowEmpty && strings.TrimSpace(pl.Text) == "" {
+			restored[i] = ParsedLine{Idx: pl.Idx}
+			continue
+		}
+		text, err := restoreTags(pl.Text, tags[pl.Idx])
+		if err != nil {
+			slog.Debug("inline tag mismatch", "idx", pl.Idx, "err", err)
+			mismatched = append(mismatched, pl.Idx)
+		}
+		restored[i] = ParsedLine{Idx: pl.Idx, Text: text}
+	}
+	return restored, mismatched
+}
+
+// keptTagMismatches returns the idxs of the lines whose inline tags, sent
+// as-is to a provider that keeps them, differ from those of their source in b.
+// With allowEmpty, empty lines are not reported.
+func keptTagMismatches(b batch, lines []ParsedLine, allowEmpty bool) []int {
+	sources := make(map[int]string, len(b.idxs))
+	for i, idx := range b.idxs {
+		sources[idx] = b.texts[i]
+	}
+	var mismatched []int
+	for _, pl := range lines {
+		if allowEmpty && strings.TrimSpace(pl.Text) == "" {
+			continue
+		}
+		if err := compareTags(sources[pl.Idx], pl.Text); err != nil {
+			slog.Debug("inline tag mismatch", "idx", pl.Idx, "err", err)
+			mismatched = append(mismatched, pl.Idx)
+		}
+	}
+	return mismatched
+}
+
+// batchParseError reports that a batch kept returning invalid/unparseable output
+// after all parse retries.
+type batchParseError struct {
+	err error
+}
+
+func (e *batchParseError) Error() string { return e.err.Error() }
+
+func (e *batchParseError) Unwrap() error { return e.err }
+
+// isTruncated reports whether err is a response cut at the output token limit.
+func isTruncated(err error) bool {
+	var tErr *truncatedResponseError
+	return errors.As(err, &tErr)
+}
+
+// isBlocked reports whether err is a response withheld by the content filters
+// of the provider.
+func isBlocked(err error) bool {
+	var bErr *blockedResponseError
+	return errors.As(err, &bErr)
+}
+
+// isFallbackEligible reports whether a failed batch may be retried against the
+// next provider: exhausted retryable HTTP statuses (429/5xx), network errors,
+// parse failures and responses blocked by the content filters, which another
+// model may not block. Cancellation and client errors (e.g. 400) are not
+// retried.
+func isFallbackEligible(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var parseErr *batchParseError
+	if errors.As(err, &parseErr) || isBlocked(err) {
+		return true
+	}
+	var hErr *httpStatusError
+	if errors.As(err, &hErr) {
+		return isRetryableHTTPStatus(hErr.StatusCode)
 	}
-	return errors.New("translation batch failed for unknown reasons")
+	return isRetryableNetErr(err)
 }
 
 func validateParsedBatch(expected map[int]struct{}, idxs []int, parsed []ParsedLine) ([]ParsedLine, error) {
@@ -403,8 +1716,8 @@ func applyTranslations(subs []*srt.Subtitle, translatedTexts map[int]string) []*
 	return outSubs
 }
 
-func writeOutput(opts Options, subs []*srt.Subtitle) (string, error) {
-	tmpOutputPath, err := writeTempOutput(opts, subs)
+func writeOutput(opts Options, doc *srt.Document) (string, error) {
+	tmpOutputPath, err := writeTempOutput(opts, doc)
 	if err != nil {
 		return "", err
 	}
@@ -420,9 +1733,9 @@ func writeOutput(opts Options, subs []*srt.Subtitle) (string, error) {
 	return outputPath, nil
 }
 
-func writeTempOutput(opts Options, subs []*srt.Subtitle) (string, error) {
+func writeTempOutput(opts Options, doc *srt.Document) (string, error) {
 	namer := run.NewTempNamer(opts.WorkDir, opts.InputPath)
-	tmpOutputPath := namer.Step("output")
+	tmpOutputPath := namer.Step("output." + languageFileTag(opts.TargetLanguage))
 
 	fout, err := os.Create(tmpOutputPath)
 	if err != nil {
@@ -430,7 +1743,7 @@ func writeTempOutput(opts Options, subs []*srt.Subtitle) (string, error) {
 	}
 	defer fs.CloseOrLog(fout, tmpOutputPath)
 
-	if err := srt.WriteAll(fout, subs); err != nil {
+	if err := srt.Write(fout, doc, opts.PreserveIndex); err != nil {
 		return "", err
 	}
 
@@ -2,13 +2,20 @@ package translate
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
 )
 
 func TestTranslateFile_Batched_ReconstructsSRT(t *testing.T) {
@@ -118,7 +125,7 @@ func TestTranslateFile_RetryOnParseFailure(t *testing.T) {
 		t.Fatalf("WriteFile: %v", err)
 	}
 
-	_, err = Run(context.Background(), Options{
+	res, err := Run(context.Background(), Options{
 		InputPath:             inPath,
 		OutputPath:            outPath,
 		DryRun:                false,
@@ -139,6 +146,9 @@ func TestTranslateFile_RetryOnParseFailure(t *testing.T) {
 	if got := calls.Load(); got < 2 {
 		t.Fatalf("expected at least 2 calls due to parse retry, got %d", got)
 	}
+	if res.Parse.Failures != 1 || res.Parse.Modes[ParseModeObjects] != 1 {
+		t.Fatalf("unexpected parse stats: %+v", res.Parse)
+	}
 
 	b, readErr := os.ReadFile(outPath)
 	if readErr != nil {
@@ -149,3 +159,1237 @@ func TestTranslateFile_RetryOnParseFailure(t *testing.T) {
 		t.Fatalf("expected translated text in output, got:\n%s", out)
 	}
 }
+
+// writeTwoCueInput writes the standard two-cue fixture used by Run tests and
+// returns the input and output paths inside workdir.
+func writeTwoCueInput(t *testing.T, workdir string) (string, string) {
+	t.Helper()
+	inPath := filepath.Join(workdir, "in.srt")
+	outPath := filepath.Join(workdir, "out.srt")
+	input := strings.Join([]string{
+		"1",
+		"00:00:01,000 --> 00:00:02,000",
+		"Hello",
+		"",
+		"2",
+		"00:00:03,000 --> 00:00:04,000",
+		"Bye",
+		"",
+		"",
+	}, "\n")
+	if err := os.WriteFile(inPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return inPath, outPath
+}
+
+func TestTranslateFile_FallbackModelOnExhaustedRetries(t *testing.T) {
+	var primaryCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("overloaded"))
+	}))
+	defer primary.Close()
+
+	var fallbackAuth string
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}"}}]}`))
+	}))
+	defer fallback.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+
+	_, err := Run(context.Background(), Options{
+		InputPath:        inPath,
+		OutputPath:       outPath,
+		WorkDir:          workdir,
+		TargetLanguage:   "es",
+		APIKey:           "primary",
+		Model:            "gpt-test",
+		BaseURL:          primary.URL,
+		MaxWorkers:       1,
+		RetryMaxAttempts: 1,
+		FallbackModels:   []string{"gemini-test"},
+		FallbackAPIKeys:  []string{"secondary"},
+		FallbackBaseURLs: []string{fallback.URL},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if primaryCalls.Load() != 1 {
+		t.Fatalf("expected 1 primary call, got %d", primaryCalls.Load())
+	}
+	if fallbackAuth != "Bearer secondary" {
+		t.Fatalf("expected fallback api key, got %q", fallbackAuth)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hola") {
+		t.Fatalf("expected translated text in output, got:\n%s", b)
+	}
+}
+
+func TestTranslateFile_NoFallbackOnClientError(t *testing.T) {
+	var fallbackCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("bad request"))
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalls.Add(1)
+	}))
+	defer fallback.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+
+	_, err := Run(context.Background(), Options{
+		InputPath:        inPath,
+		OutputPath:       outPath,
+		WorkDir:          workdir,
+		TargetLanguage:   "es",
+		Model:            "gpt-test",
+		BaseURL:          primary.URL,
+		ResponseMode:     ResponseModeNDJSON,
+		MaxWorkers:       1,
+		RetryMaxAttempts: 1,
+		FallbackModels:   []string{"gemini-test"},
+		FallbackBaseURLs: []string{fallback.URL},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if fallbackCalls.Load() != 0 {
+		t.Fatalf("expected no fallback calls on 400, got %d", fallbackCalls.Load())
+	}
+}
+
+func TestNewBatchTranslators_FallbackAPIKeys(t *testing.T) {
+	providers, err := newBatchTranslators(Options{
+		Provider:         ProviderOpenAI,
+		APIKey:           "primary",
+		Model:            "gpt-test",
+		FallbackModels:   []string{"gpt-other", "ollama:llama3", "gpt-router"},
+		FallbackBaseURLs: []string{"", "", "https://router.example/v1"},
+		FallbackAPIKeys:  []string{"", "", "router"},
+	})
+	if err != nil {
+		t.Fatalf("newBatchTranslators: %v", err)
+	}
+	var keys []string
+	for _, p := range providers {
+		keys = append(keys, p.client.(*OpenAIClient).APIKey)
+	}
+	if want := []string{"primary", "primary", "", "router"}; !slices.Equal(keys, want) {
+		t.Fatalf("api keys = %q, want %q", keys, want)
+	}
+
+	// A DeepL key is never sent to a chat model.
+	_, err = newBatchTranslators(Options{
+		Provider:       ProviderDeepL,
+		APIKey:         "deepl",
+		FallbackModels: []string{"gpt-test"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `fallback model "gpt-test"`) {
+		t.Fatalf("expected a missing fallback api key error, got %v", err)
+	}
+}
+
+func TestTranslateFile_CacheKeepsFallbackTranslations(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	var fallbackCalls atomic.Int32
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}"}}]}`))
+	}))
+	defer fallback.Close()
+
+	cacheDir := t.TempDir()
+	for run := 1; run <= 2; run++ {
+		workdir := t.TempDir()
+		inPath, outPath := writeTwoCueInput(t, workdir)
+		res, err := Run(context.Background(), Options{
+			InputPath:        inPath,
+			OutputPath:       outPath,
+			WorkDir:          workdir,
+			TargetLanguage:   "es",
+			APIKey:           "test",
+			Model:            "gpt-test",
+			BaseURL:          primary.URL,
+			MaxWorkers:       1,
+			RetryMaxAttempts: 1,
+			FallbackModels:   []string{"gpt-fallback"},
+			FallbackAPIKeys:  []string{"fallback"},
+			FallbackBaseURLs: []string{fallback.URL},
+			CacheDir:         cacheDir,
+		})
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if run == 2 && (res.CacheHits != 2 || fallbackCalls.Load() != 1) {
+			t.Fatalf("expected the fallback translations from the cache, got hits=%d fallback calls=%d", res.CacheHits, fallbackCalls.Load())
+		}
+	}
+}
+
+func TestTranslateFile_CacheKeepsReviewCorrections(t *testing.T) {
+	// The primary model only reviews: its batches go to the fallback.
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "Review these subtitle translations") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"ok\":true}\n{\"idx\":2,\"ok\":false,\"issue\":\"mistranslation\",\"text\":\"Adiós\"}"}}]}`))
+	}))
+	defer primary.Close()
+	var fallbackCalls atomic.Int32
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Hola\"}"}}]}`))
+	}))
+	defer fallback.Close()
+
+	cacheDir := t.TempDir()
+	for _, review := range []string{ReviewModeFix, ReviewModeOff} {
+		workdir := t.TempDir()
+		inPath, outPath := writeTwoCueInput(t, workdir)
+		res, err := Run(context.Background(), Options{
+			InputPath:        inPath,
+			OutputPath:       outPath,
+			WorkDir:          workdir,
+			TargetLanguage:   "es",
+			APIKey:           "test",
+			Model:            "gpt-test",
+			BaseURL:          primary.URL,
+			ResponseMode:     ResponseModeNDJSON,
+			MaxWorkers:       1,
+			RetryMaxAttempts: 1,
+			FallbackModels:   []string{"gpt-fallback"},
+			FallbackAPIKeys:  []string{"fallback"},
+			FallbackBaseURLs: []string{fallback.URL},
+			SDH:              SDHGenerate,
+			Review:           review,
+			CacheDir:         cacheDir,
+		})
+		if err != nil {
+			t.Fatalf("review %q: %v", review, err)
+		}
+		if review == ReviewModeFix {
+			continue
+		}
+		b, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if res.CacheHits != 2 || fallbackCalls.Load() != 1 || !strings.Contains(string(b), "Adiós") {
+			t.Fatalf("expected the reviewed translations from the cache, got hits=%d fallback calls=%d, output:\n%s", res.CacheHits, fallbackCalls.Load(), b)
+		}
+	}
+}
+
+func TestTranslateFile_CacheSkipsTranslatedCues(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	run := func() (Result, string) {
+		workdir := t.TempDir()
+		inPath, outPath := writeTwoCueInput(t, workdir)
+		res, err := Run(context.Background(), Options{
+			InputPath:      inPath,
+			OutputPath:     outPath,
+			WorkDir:        workdir,
+			TargetLanguage: "es",
+			APIKey:         "test",
+			Model:          "gpt-test",
+			BaseURL:        server.URL,
+			MaxWorkers:     1,
+			CacheDir:       cacheDir,
+		})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		b, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		return res, string(b)
+	}
+
+	first, _ := run()
+	if first.CacheHits != 0 || calls.Load() != 1 {
+		t.Fatalf("first run: expected no cache hits and 1 call, got hits=%d calls=%d", first.CacheHits, calls.Load())
+	}
+
+	second, out := run()
+	if second.CacheHits != 2 {
+		t.Fatalf("second run: expected 2 cache hits, got %d", second.CacheHits)
+	}
+	if second.Batches != 0 || calls.Load() != 1 {
+		t.Fatalf("second run: expected no api calls, got batches=%d calls=%d", second.Batches, calls.Load())
+	}
+	if !strings.Contains(out, "Hola") || !strings.Contains(out, "Adios") {
+		t.Fatalf("expected cached translations in output, got:\n%s", out)
+	}
+}
+
+func TestTranslateFile_TMXImportSkipsKnownCues(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":2,\"text\":\"Adios\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	importPath := filepath.Join(workdir, "in.tmx")
+	exportPath := filepath.Join(workdir, "out.tmx")
+	tmx := `<tmx version="1.4"><header srclang="en"/><body>` +
+		`<tu><tuv xml:lang="en"><seg>Hello</seg></tuv><tuv xml:lang="es"><seg>Buenas</seg></tuv></tu>` +
+		`</body></tmx>`
+	if err := os.WriteFile(importPath, []byte(tmx), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	res, err := Run(context.Background(), Options{
+		InputPath:      inPath,
+		OutputPath:     outPath,
+		WorkDir:        workdir,
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		MaxWorkers:     1,
+		TMXImportPath:  importPath,
+		TMXExportPath:  exportPath,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.MemoryHits != 1 || calls.Load() != 1 {
+		t.Fatalf("expected 1 memory hit and 1 api call, got hits=%d calls=%d", res.MemoryHits, calls.Load())
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Buenas") || !strings.Contains(string(b), "Adios") {
+		t.Fatalf("unexpected output:\n%s", b)
+	}
+
+	tm, err := readTMX(exportPath, "", "es")
+	if err != nil {
+		t.Fatalf("readTMX: %v", err)
+	}
+	if tm["Hello"] != "Buenas" || tm["Bye"] != "Adios" {
+		t.Fatalf("unexpected exported memory: %v", tm)
+	}
+}
+
+func TestTranslateFile_RetriesOnTagMismatch(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "⟦1⟧Hello⟦2⟧") {
+			t.Errorf("expected tags to be sent as placeholders, got %s", body)
+		}
+		content := `{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}`
+		if calls.Add(1) > 1 {
+			content = `{\"idx\":1,\"text\":\"⟦1⟧Hola⟦2⟧\"}\n{\"idx\":2,\"text\":\"Adios\"}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath := filepath.Join(workdir, "in.srt")
+	outPath := filepath.Join(workdir, "out.srt")
+	input := "1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i>\n\n2\n00:00:03,000 --> 00:00:04,000\nBye\n\n"
+	if err := os.WriteFile(inPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	res, err := Run(context.Background(), Options{
+		InputPath:             inPath,
+		OutputPath:            outPath,
+		WorkDir:               workdir,
+		TargetLanguage:        "es",
+		APIKey:                "test",
+		Model:                 "gpt-test",
+		BaseURL:               server.URL,
+		MaxWorkers:            1,
+		ResponseMode:          ResponseModeNDJSON,
+		RetryParseMaxAttempts: 2,
+		RetryTagMismatch:      true,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if calls.Load() != 2 || res.TagMismatches != 0 {
+		t.Fatalf("expected a retry and no remaining mismatches, got calls=%d mismatches=%d", calls.Load(), res.TagMismatches)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "<i>Hola</i>") {
+		t.Fatalf("expected restored tags in output, got:\n%s", b)
+	}
+}
+
+func TestRunTargets_OneOutputPerLanguage(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		content := `{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}`
+		if strings.Contains(string(body), "to: `fr`") {
+			content = `{\"idx\":1,\"text\":\"Bonjour\"}\n{\"idx\":2,\"text\":\"Au revoir\"}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, _ := writeTwoCueInput(t, workdir)
+	esPath := filepath.Join(workdir, "out.es.srt")
+	frPath := filepath.Join(workdir, "out.fr.srt")
+
+	results, err := RunTargets(context.Background(), Options{
+		InputPath:    inPath,
+		WorkDir:      workdir,
+		APIKey:       "test",
+		Model:        "gpt-test",
+		BaseURL:      server.URL,
+		MaxWorkers:   1,
+		ResponseMode: ResponseModeNDJSON,
+	}, []Target{
+		{Language: "es", OutputPath: esPath},
+		{Language: "fr", OutputPath: frPath},
+	})
+	if err != nil {
+		t.Fatalf("RunTargets: %v", err)
+	}
+	if len(results) != 2 || results[0].TargetLanguage != "es" || results[1].WrittenPath != frPath {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected one request per language, got %d", calls.Load())
+	}
+	for path, want := range map[string]string{esPath: "Hola", frPath: "Bonjour"} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("expected %q in %s, got:\n%s", want, path, b)
+		}
+	}
+}
+
+func TestRunTargets_RejectsDuplicateOutputs(t *testing.T) {
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	_, err := RunTargets(context.Background(), Options{
+		InputPath: inPath,
+		WorkDir:   workdir,
+		Model:     "gpt-test",
+	}, []Target{
+		{Language: "es", OutputPath: outPath},
+		{Language: "fr", OutputPath: outPath},
+	})
+	if err == nil || !strings.Contains(err.Error(), "same output path") {
+		t.Fatalf("expected duplicate output error, got %v", err)
+	}
+}
+
+func TestTranslateFile_ReviewAppliesCorrections(t *testing.T) {
+	for _, mode := range []string{ReviewModeFix, ReviewModeReport} {
+		t.Run(mode, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				content := `{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Hola\"}`
+				if strings.Contains(string(body), "Review these subtitle translations") {
+					content = `{\"idx\":1,\"ok\":true}\n{\"idx\":2,\"ok\":false,\"issue\":\"mistranslation\",\"text\":\"Adiós\"}`
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+			}))
+			defer server.Close()
+
+			workdir := t.TempDir()
+			inPath, outPath := writeTwoCueInput(t, workdir)
+			res, err := Run(context.Background(), Options{
+				InputPath:      inPath,
+				OutputPath:     outPath,
+				WorkDir:        workdir,
+				TargetLanguage: "es",
+				APIKey:         "test",
+				Model:          "gpt-test",
+				BaseURL:        server.URL,
+				ResponseMode:   ResponseModeNDJSON,
+				Review:         mode,
+			})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if res.ReviewFlagged != 1 {
+				t.Fatalf("expected 1 flagged cue, got %d", res.ReviewFlagged)
+			}
+			b, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			wantCorrected := 0
+			if mode == ReviewModeFix {
+				wantCorrected = 1
+			}
+			if strings.Contains(string(b), "Adiós") != (wantCorrected == 1) || res.ReviewCorrected != wantCorrected {
+				t.Fatalf("mode %s: expected %d corrections, got %d, output:\n%s", mode, wantCorrected, res.ReviewCorrected, b)
+			}
+
+			if res.ReviewReportPath != filepath.Join(workdir, "out.review.json") {
+				t.Fatalf("unexpected report path %s", res.ReviewReportPath)
+			}
+			rb, err := os.ReadFile(res.ReviewReportPath)
+			if err != nil {
+				t.Fatalf("ReadFile report: %v", err)
+			}
+			var report ReviewReport
+			if err := json.Unmarshal(rb, &report); err != nil {
+				t.Fatalf("Unmarshal report: %v", err)
+			}
+			if report.Reviewed != 2 || len(report.Flagged) != 1 || report.Flagged[0].Idx != 2 || report.Flagged[0].Issue != "mistranslation" {
+				t.Fatalf("unexpected report: %+v", report)
+			}
+		})
+	}
+}
+
+func TestRunTargets_ReviewRequiresChatModel(t *testing.T) {
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	_, err := Run(context.Background(), Options{
+		InputPath:      inPath,
+		OutputPath:     outPath,
+		WorkDir:        workdir,
+		TargetLanguage: "es",
+		APIKey:         "test:fx",
+		Provider:       ProviderDeepL,
+		Review:         ReviewModeFix,
+	})
+	if err == nil || !strings.Contains(err.Error(), "review requires a chat model") {
+		t.Fatalf("expected review provider error, got %v", err)
+	}
+}
+
+func TestTranslateFile_LengthReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := `{\"idx\":1,\"text\":\"Hola a todos los presentes\"}\n{\"idx\":2,\"text\":\"Adios\"}`
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	res, err := Run(context.Background(), Options{
+		InputPath:      inPath,
+		OutputPath:     outPath,
+		WorkDir:        workdir,
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		ResponseMode:   ResponseModeNDJSON,
+		MaxCPS:         17,
+		LengthPolicy:   LengthPolicyReport,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.LengthViolations != 1 || res.LengthReportPath != filepath.Join(workdir, "out.length.json") {
+		t.Fatalf("unexpected length result: violations=%d report=%s", res.LengthViolations, res.LengthReportPath)
+	}
+	b, err := os.ReadFile(res.LengthReportPath)
+	if err != nil {
+		t.Fatalf("ReadFile report: %v", err)
+	}
+	var report LengthReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("Unmarshal report: %v", err)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Idx != 1 || report.Violations[0].CPS != 26 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestTranslateFile_DryRunWritesSideBySide(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := `{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios, amigo\"}`
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	res, err := Run(context.Background(), Options{
+		InputPath:        inPath,
+		OutputPath:       outPath,
+		DryRun:           true,
+		WorkDir:          workdir,
+		SourceLanguage:   "en",
+		TargetLanguage:   "es",
+		APIKey:           "test",
+		Model:            "gpt-test",
+		BaseURL:          server.URL,
+		ResponseMode:     ResponseModeNDJSON,
+		SideBySideFormat: SideBySideCSV,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := defaultSideBySidePath(res.WrittenPath, SideBySideCSV); res.SideBySidePath != want {
+		t.Fatalf("SideBySidePath = %q, want %q", res.SideBySidePath, want)
+	}
+	b, err := os.ReadFile(res.SideBySidePath)
+	if err != nil {
+		t.Fatalf("ReadFile side-by-side: %v", err)
+	}
+	want := "idx,start,end,en,es\n" +
+		"1,\"00:00:01,000\",\"00:00:02,000\",Hello,Hola\n" +
+		"2,\"00:00:03,000\",\"00:00:04,000\",Bye,\"Adios, amigo\"\n"
+	if string(b) != want {
+		t.Fatalf("unexpected side-by-side file:\n%s", b)
+	}
+}
+
+func TestTranslateFile_TranslatesSelectedCuesOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `\"Hello\"`) {
+			t.Errorf("unselected cue sent to the provider: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":2,\"text\":\"Adios\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	res, err := Run(context.Background(), Options{
+		InputPath:      inPath,
+		OutputPath:     outPath,
+		WorkDir:        workdir,
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		ResponseMode:   ResponseModeNDJSON,
+		Selection:      srt.Selection{Idxs: []srt.IdxRange{{From: 2, To: 2}}},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Unselected != 1 {
+		t.Fatalf("Unselected = %d, want 1", res.Unselected)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hello") || !strings.Contains(string(b), "Adios") {
+		t.Fatalf("unexpected output:\n%s", b)
+	}
+
+	_, err = Run(context.Background(), Options{
+		InputPath:      inPath,
+		OutputPath:     outPath + ".none.srt",
+		WorkDir:        workdir,
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		Selection:      srt.Selection{Idxs: []srt.IdxRange{{From: 9}}},
+	})
+	if !errors.Is(err, srt.ErrNoSelectedCues) {
+		t.Fatalf("expected ErrNoSelectedCues, got %v", err)
+	}
+}
+
+func TestWriteSideBySide_Markdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.side-by-side.md")
+	rows := []sideBySideRow{{Idx: 1, Start: "00:00:01,000", End: "00:00:02,000", Source: "A | B\nC", Translation: ""}}
+	if err := writeSideBySide(path, SideBySideMarkdown, "", "es", rows); err != nil {
+		t.Fatalf("writeSideBySide: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "| # | Time | source | es |\n|---|---|---|---|\n" +
+		"| 1 | 00:00:01,000 --> 00:00:02,000 | A \\| B<br>C |  |\n"
+	if string(b) != want {
+		t.Fatalf("unexpected markdown:\n%s", b)
+	}
+}
+
+func TestTranslateFile_WritesTranscript(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := `{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}`
+		if calls.Add(1) == 1 {
+			content = "not ndjson"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	transcriptBase := filepath.Join(workdir, "transcripts")
+	res, err := Run(context.Background(), Options{
+		InputPath:             inPath,
+		OutputPath:            outPath,
+		WorkDir:               workdir,
+		TargetLanguage:        "es",
+		APIKey:                "test",
+		Model:                 "gpt-test",
+		BaseURL:               server.URL,
+		ResponseMode:          ResponseModeNDJSON,
+		RetryParseMaxAttempts: 2,
+		TranscriptDir:         transcriptBase,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if filepath.Dir(res.TranscriptDir) != transcriptBase {
+		t.Fatalf("unexpected transcript dir: %s", res.TranscriptDir)
+	}
+
+	b, err := os.ReadFile(filepath.Join(res.TranscriptDir, transcriptIndexFile))
+	if err != nil {
+		t.Fatalf("ReadFile index: %v", err)
+	}
+	var entries []TranscriptEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var e TranscriptEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Unmarshal entry %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	first, second := entries[0], entries[1]
+	if first.Seq != 1 || first.Attempt != 1 || first.Error == "" || first.Kind != TranscriptKindTranslate || first.Cues != 2 {
+		t.Fatalf("unexpected first entry: %+v", first)
+	}
+	if second.Seq != 2 || second.Attempt != 2 || second.Error != "" || second.RequestFile != "0002-translate-es.request.ndjson" {
+		t.Fatalf("unexpected second entry: %+v", second)
+	}
+
+	req, err := os.ReadFile(filepath.Join(res.TranscriptDir, first.RequestFile))
+	if err != nil {
+		t.Fatalf("ReadFile request: %v", err)
+	}
+	if !strings.Contains(string(req), `"text":"Hello"`) {
+		t.Fatalf("request file does not contain the payload: %s", req)
+	}
+	resp, err := os.ReadFile(filepath.Join(res.TranscriptDir, first.ResponseFile))
+	if err != nil {
+		t.Fatalf("ReadFile response: %v", err)
+	}
+	if strings.TrimSpace(string(resp)) != "not ndjson" {
+		t.Fatalf("unexpected response file: %q", resp)
+	}
+}
+
+func TestTranslateFile_ReportsProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}"}}],"usage":{"total_tokens":30}}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	var reports []Progress
+	_, err := Run(context.Background(), Options{
+		InputPath:      inPath,
+		OutputPath:     outPath,
+		WorkDir:        workdir,
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		ResponseMode:   ResponseModeNDJSON,
+		Progress:       func(p Progress) { reports = append(reports, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected start, batch and final reports, got %+v", reports)
+	}
+	if first := reports[0]; first.CompletedBatches != 0 || first.TotalBatches != 1 || first.TotalCues != 2 || first.TargetLanguage != "es" {
+		t.Fatalf("unexpected initial report: %+v", first)
+	}
+	if last := reports[2]; last.CompletedBatches != 1 || last.CuesDone != 2 || last.TokensUsed != 30 || last.ETA != 0 {
+		t.Fatalf("unexpected final report: %+v", last)
+	}
+}
+
+func TestRun_RefusesInputAlreadyInTargetLanguage(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adios\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath := filepath.Join(workdir, "in.srt")
+	input := "1\n00:00:01,000 --> 00:00:02,000\n¿Qué estás haciendo aquí? No sé qué quieres de mí.\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nTenemos que irnos ahora, no es seguro. Vamos, sube al coche, por favor.\n\n"
+	if err := os.WriteFile(inPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	opts := Options{
+		InputPath:      inPath,
+		OutputPath:     filepath.Join(workdir, "out.srt"),
+		WorkDir:        workdir,
+		TargetLanguage: "es-419",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		ResponseMode:   ResponseModeNDJSON,
+	}
+	_, err := Run(context.Background(), opts)
+	if !errors.Is(err, ErrAlreadyTargetLanguage) || calls.Load() != 0 {
+		t.Fatalf("expected ErrAlreadyTargetLanguage without calling the provider, got err=%v calls=%d", err, calls.Load())
+	}
+
+	opts.Force = true
+	if _, err := Run(context.Background(), opts); err != nil {
+		t.Fatalf("Run with Force: %v", err)
+	}
+}
+
+func TestTranslateFile_BisectsBatchOnRepeatedParseFailure(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		// The prompt example also contains "Hello", so match the encoded payload item.
+		hello := strings.Contains(string(body), `\"text\":\"Hello\"}`)
+		var content string
+		switch {
+		case hello && strings.Contains(string(body), "Bye"):
+			// The model keeps dropping a cue when both are sent together.
+			content = `{\"idx\":1,\"text\":\"Hola\"}`
+		case hello:
+			content = `{\"idx\":1,\"text\":\"Hola\"}`
+		default:
+			content = `{\"idx\":2,\"text\":\"Adios\"}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"}}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	_, err := Run(context.Background(), Options{
+		InputPath:             inPath,
+		OutputPath:            outPath,
+		WorkDir:               workdir,
+		TargetLanguage:        "es",
+		APIKey:                "test",
+		Model:                 "gpt-test",
+		BaseURL:               server.URL,
+		ResponseMode:          ResponseModeNDJSON,
+		RetryParseMaxAttempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected the failing batch plus one request per half, got %d", calls.Load())
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hola") || !strings.Contains(string(b), "Adios") {
+		t.Fatalf("expected both cues translated, got:\n%s", b)
+	}
+}
+
+func TestTranslateFile_SplitsBatchOnTruncatedResponse(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		hello := strings.Contains(string(body), `\"text\":\"Hello\"}`)
+		var content, finish string
+		switch {
+		case hello && strings.Contains(string(body), "Bye"):
+			// Both cues don't fit in the output token limit.
+			content, finish = `{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"te`, "length"
+		case hello:
+			content, finish = `{\"idx\":1,\"text\":\"Hola\"}`, "stop"
+		default:
+			content, finish = `{\"idx\":2,\"text\":\"Adios\"}`, "stop"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + content + `"},"finish_reason":"` + finish + `"}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	res, err := Run(context.Background(), Options{
+		InputPath:             inPath,
+		OutputPath:            outPath,
+		WorkDir:               workdir,
+		TargetLanguage:        "es",
+		APIKey:                "test",
+		Model:                 "gpt-test",
+		BaseURL:               server.URL,
+		ResponseMode:          ResponseModeNDJSON,
+		RetryMaxAttempts:      3,
+		RetryParseMaxAttempts: 3,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// The truncated batch is neither retried nor parsed: it is split at once.
+	if calls.Load() != 3 {
+		t.Fatalf("expected the truncated batch plus one request per half, got %d", calls.Load())
+	}
+	if res.Parse.Truncated != 1 || res.Parse.Failures != 0 {
+		t.Fatalf("unexpected parse stats: %+v", res.Parse)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hola") || !strings.Contains(string(b), "Adios") {
+		t.Fatalf("expected both cues translated, got:\n%s", b)
+	}
+}
+
+func TestTranslateFile_LeavesCuesBlockedByContentFilterUntranslated(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "Bye") {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":""},"finish_reason":"content_filter"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	inPath, outPath := writeTwoCueInput(t, workdir)
+	res, err := Run(context.Background(), Options{
+		InputPath:             inPath,
+		OutputPath:            outPath,
+		WorkDir:               workdir,
+		TargetLanguage:        "es",
+		APIKey:                "test",
+		Model:                 "gpt-test",
+		BaseURL:               server.URL,
+		ResponseMode:          ResponseModeNDJSON,
+		RetryMaxAttempts:      3,
+		RetryParseMaxAttempts: 3,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// The blocked batch is split at once, and the blocked cue isn't retried.
+	if calls.Load() != 3 {
+		t.Fatalf("expected the blocked batch plus one request per half, got %d", calls.Load())
+	}
+	if res.Blocked != 1 {
+		t.Fatalf("expected 1 blocked cue, got %d", res.Blocked)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hola") || !strings.Contains(string(b), "Bye") {
+		t.Fatalf("expected the blocked cue untranslated, got:\n%s", b)
+	}
+}
+
+func TestReadDocument_PreserveIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.srt")
+	content := "3\n00:00:01,000 --> 00:00:02,000\nHello\n\n7\n00:00:03,000 --> 00:00:04,000\nBye\n\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	doc, err := readDocument(path, true)
+	if err != nil {
+		t.Fatalf("readDocument: %v", err)
+	}
+	if doc.Cues[0].Idx != 3 || doc.Cues[1].Idx != 7 {
+		t.Fatalf("expected the original indexes, got %d and %d", doc.Cues[0].Idx, doc.Cues[1].Idx)
+	}
+	if doc, err = readDocument(path, false); err != nil || doc.Cues[0].Idx != 1 || doc.Cues[1].Idx != 2 {
+		t.Fatalf("expected renumbered cues, got %v (err %v)", doc, err)
+	}
+
+	content = "3\n00:00:01,000 --> 00:00:02,000\nHello\n\n3\n00:00:03,000 --> 00:00:04,000\nBye\n\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if doc, err = readDocument(path, true); err != nil || doc.Cues[0].Idx != 1 || doc.Cues[1].Idx != 2 {
+		t.Fatalf("expected duplicated indexes to be renumbered, got %v (err %v)", doc, err)
+	}
+}
+
+func TestTranslateSubtitles_InMemory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}\n{\"idx\":2,\"text\":\"Adiós\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	subs := []*srt.Subtitle{
+		{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: `{\an8}Hello`},
+		{Idx: 2, FromTime: 3 * time.Second, ToTime: 4 * time.Second, Text: "Bye"},
+	}
+	out, res, err := TranslateSubtitles(context.Background(), subs, Options{
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+	})
+	if err != nil {
+		t.Fatalf("TranslateSubtitles: %v", err)
+	}
+	if len(out) != 2 || out[0].Text != `{\an8}Hola` || out[1].Text != "Adiós" || out[1].FromTime != 3*time.Second {
+		t.Fatalf("unexpected cues: %+v %+v", out[0], out[1])
+	}
+	if subs[0].Text != `{\an8}Hello` {
+		t.Fatalf("input cues were modified: %+v", subs[0])
+	}
+	if res.WrittenPath != "" || res.Batches != 1 || res.TargetLanguage != "es" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestTranslateSubtitles_DeepLKeepsTags(t *testing.T) {
+	var got deeplTranslateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"translations":[{"text":"<i>Hola</i> a todos"}]}`))
+	}))
+	defer server.Close()
+
+	subs := []*srt.Subtitle{{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: "<i>Hello</i> everyone"}}
+	out, _, err := TranslateSubtitles(context.Background(), subs, Options{
+		TargetLanguage: "es",
+		APIKey:         "test:fx",
+		Provider:       ProviderDeepL,
+		BaseURL:        server.URL,
+	})
+	if err != nil {
+		t.Fatalf("TranslateSubtitles: %v", err)
+	}
+	// The tags go to DeepL as XML elements, not placeholders.
+	if len(got.Text) != 1 || got.Text[0] != "<i>Hello</i> everyone" || got.TagHandling != "xml" {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if len(out) != 1 || out[0].Text != "<i>Hola</i> a todos" {
+		t.Fatalf("unexpected cues: %+v", out)
+	}
+}
+
+func TestTranslateSubtitles_DeepLFallbackGetsPlaceholders(t *testing.T) {
+	deepl := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer deepl.Close()
+	var payload string
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		payload = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"⟦1⟧Hola⟦2⟧ a todos\"}"}}]}`))
+	}))
+	defer fallback.Close()
+
+	subs := []*srt.Subtitle{{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: "<i>Hello</i> everyone"}}
+	out, res, err := TranslateSubtitles(context.Background(), subs, Options{
+		TargetLanguage:   "es",
+		APIKey:           "test:fx",
+		Provider:         ProviderDeepL,
+		BaseURL:          deepl.URL,
+		RetryMaxAttempts: 1,
+		FallbackModels:   []string{"gpt-test"},
+		FallbackAPIKeys:  []string{"test"},
+		FallbackBaseURLs: []string{fallback.URL},
+	})
+	if err != nil {
+		t.Fatalf("TranslateSubtitles: %v", err)
+	}
+	if strings.Contains(payload, "<i>") || !strings.Contains(payload, "⟦1⟧Hello⟦2⟧") {
+		t.Fatalf("expected the tags of the fallback request as placeholders, got %s", payload)
+	}
+	if len(out) != 1 || out[0].Text != "<i>Hola</i> a todos" || res.TagMismatches != 0 {
+		t.Fatalf("unexpected cues: %+v (tag mismatches %d)", out, res.TagMismatches)
+	}
+}
+
+func TestTranslateSubtitles_DeepLTagMismatches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"translations":[{"text":"Hola a todos"}]}`))
+	}))
+	defer server.Close()
+
+	subs := []*srt.Subtitle{{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: "<i>Hello</i> everyone"}}
+	_, res, err := TranslateSubtitles(context.Background(), subs, Options{
+		TargetLanguage: "es",
+		APIKey:         "test:fx",
+		Provider:       ProviderDeepL,
+		BaseURL:        server.URL,
+	})
+	if err != nil {
+		t.Fatalf("TranslateSubtitles: %v", err)
+	}
+	if res.TagMismatches != 1 {
+		t.Fatalf("expected the tags dropped by DeepL to be reported, got %d", res.TagMismatches)
+	}
+}
+
+func TestSelectFlaggedCues(t *testing.T) {
+	subs := []*srt.Subtitle{
+		{Idx: 1, Text: "Hello"},
+		{Idx: 2, Text: "{\\forced}Welcome", Forced: true},
+		{Idx: 3, Text: "[door slams]", SDH: true},
+	}
+	got, err := selectFlaggedCues(subs, Options{SkipSDH: true})
+	if err != nil || len(got) != 2 || got[1].Idx != 2 {
+		t.Fatalf("SkipSDH = %+v, %v", got, err)
+	}
+	got, err = selectFlaggedCues(subs, Options{OnlyForced: true})
+	if err != nil || len(got) != 1 || got[0].Idx != 1 || !got[0].Forced {
+		t.Fatalf("OnlyForced = %+v, %v", got, err)
+	}
+	if _, err := selectFlaggedCues(subs[:1], Options{OnlyForced: true}); !errors.Is(err, ErrNoForcedCues) {
+		t.Fatalf("OnlyForced without forced cues: %v", err)
+	}
+}
+
+func TestTranslateSubtitles_Censor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"¡Mierda!\"}\n{\"idx\":2,\"text\":\"Adiós\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	list := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(list, []byte("mierda\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	subs := []*srt.Subtitle{
+		{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: "Shit!"},
+		{Idx: 2, FromTime: 3 * time.Second, ToTime: 4 * time.Second, Text: "Bye"},
+	}
+	opts := Options{
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		CensorListPath: list,
+	}
+	out, res, err := TranslateSubtitles(context.Background(), subs, opts)
+	if err != nil {
+		t.Fatalf("TranslateSubtitles: %v", err)
+	}
+	if len(out) != 2 || out[0].Text != "¡M*****!" || res.Censored != 1 {
+		t.Fatalf("unexpected cues: %+v (censored %d)", out, res.Censored)
+	}
+
+	opts.CensorStyle = "remove-cue"
+	if out, _, err = TranslateSubtitles(context.Background(), subs, opts); err != nil || len(out) != 1 || out[0].Text != "Adiós" {
+		t.Fatalf("remove-cue = %+v, %v", out, err)
+	}
+}
+
+func TestTranslateSubtitles_SDHStrip(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		prompt = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"\"}\n{\"idx\":2,\"text\":\"¿Quién es?\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	subs := []*srt.Subtitle{
+		{Idx: 1, FromTime: time.Second, ToTime: 2 * time.Second, Text: "<i>[DOOR SLAMS]</i>"},
+		{Idx: 2, FromTime: 3 * time.Second, ToTime: 4 * time.Second, Text: "JOHN: Who is it?"},
+	}
+	opts := Options{
+		TargetLanguage: "es",
+		APIKey:         "test",
+		Model:          "gpt-test",
+		BaseURL:        server.URL,
+		SDH:            SDHStrip,
+	}
+	out, res, err := TranslateSubtitles(context.Background(), subs, opts)
+	if err != nil {
+		t.Fatalf("TranslateSubtitles: %v", err)
+	}
+	if len(out) != 1 || out[0].Text != "¿Quién es?" || res.SDHStripped != 1 || res.TagMismatches != 0 {
+		t.Fatalf("unexpected cues: %+v (result %+v)", out, res)
+	}
+	if !strings.Contains(prompt, "leave out sound descriptions") {
+		t.Fatalf("expected the SDH instruction in the request: %s", prompt)
+	}
+
+	opts.Provider, opts.Model = ProviderDeepL, ""
+	if _, _, err := TranslateSubtitles(context.Background(), subs, opts); err == nil || !strings.Contains(err.Error(), "requires a chat model") {
+		t.Fatalf("expected a chat model error with deepl, got %v", err)
+	}
+	opts.Provider, opts.SDH = ProviderOpenAI, "auto"
+	if _, _, err := TranslateSubtitles(context.Background(), subs, opts); err == nil {
+		t.Fatalf("expected an error for an invalid sdh mode")
+	}
+}
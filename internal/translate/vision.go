@@ -0,0 +1,69 @@
+package translate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrImageTextCount is returned by RecognizeImages when the model answers
+// with a different number of texts than the images sent; sending fewer
+// images at a time usually avoids it.
+var ErrImageTextCount = errors.New("the model returned a different number of texts than images")
+
+const visionInstructions = `You read the subtitles shown in images, for an OCR tool.
+For each image, in order, transcribe its text exactly as shown%s: keep the line breaks, the punctuation and the case, and wrap italic text in <i></i>. Don't translate, correct, complete or describe the text. Use "" for an image without text.
+Answer only with a JSON array with a string per image, e.g. ["First line\nsecond line", "<i>Italic line</i>", ""].`
+
+// RecognizeImages asks the model (which must accept images) for the text of
+// the subtitle images, PNG encoded, in a single request. It returns a text per
+// image, "" when it has none, and the tokens used.
+func (c *OpenAIClient) RecognizeImages(ctx context.Context, language string, images [][]byte) ([]string, int64, error) {
+	if len(images) == 0 {
+		return nil, 0, nil
+	}
+	tracker := &progressTracker{}
+	content, err := c.complete(withTokenCounter(ctx, tracker), buildVisionPrompt(language, images))
+	tokens := tracker.tokensUsed()
+	if err != nil {
+		return nil, tokens, err
+	}
+	texts, err := parseImageTexts(content, len(images))
+	return texts, tokens, err
+}
+
+func buildVisionPrompt(language string, images [][]byte) []ChatMessage {
+	in := ""
+	if language != "" {
+		in = " (the text is in " + normalizeTargetLanguageLabel(language) + ")"
+	}
+	urls := make([]string, len(images))
+	for i, img := range images {
+		urls[i] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)
+	}
+	return []ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(visionInstructions, in)},
+		{Role: "user", Content: fmt.Sprintf("%d images:", len(images)), images: urls},
+	}
+}
+
+// parseImageTexts reads the JSON array of texts answered for n images,
+// tolerating code fences and text around it.
+func parseImageTexts(content string, n int) ([]string, error) {
+	s := stripCodeFences(content)
+	start, end := strings.Index(s, "["), strings.LastIndex(s, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid OCR response: no JSON array: %s", abbreviate(content, 200))
+	}
+	var texts []string
+	if err := json.Unmarshal([]byte(s[start:end+1]), &texts); err != nil {
+		return nil, fmt.Errorf("invalid OCR response: %w: %s", err, abbreviate(content, 200))
+	}
+	if len(texts) != n {
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrImageTextCount, len(texts), n)
+	}
+	return texts, nil
+}
@@ -0,0 +1,61 @@
+package translate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecognizeImages(t *testing.T) {
+	var body struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		content, _ := json.Marshal("```json\n[\"Hola\\nmundo\", \"\"]\n```")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":` + string(content) + `}}],"usage":{"total_tokens":120}}`))
+	}))
+	defer server.Close()
+
+	c := OpenAIClient{BaseURL: server.URL, APIKey: "test", Model: "gpt-test", RetryOptions: RetryOptions{MaxAttempts: 1}}
+	texts, tokens, err := c.RecognizeImages(t.Context(), "es", [][]byte{[]byte("png1"), []byte("png2")})
+	if err != nil {
+		t.Fatalf("RecognizeImages: %v", err)
+	}
+	if len(texts) != 2 || texts[0] != "Hola\nmundo" || texts[1] != "" {
+		t.Errorf("texts = %q", texts)
+	}
+	if tokens != 120 {
+		t.Errorf("tokens = %d, want 120", tokens)
+	}
+
+	if len(body.Messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(body.Messages))
+	}
+	var parts []chatContentPart
+	if err := json.Unmarshal(body.Messages[1].Content, &parts); err != nil {
+		t.Fatalf("user content is not a list of parts: %s", body.Messages[1].Content)
+	}
+	if len(parts) != 3 || parts[0].Type != "text" || parts[1].ImageURL == nil ||
+		parts[1].ImageURL.URL != "data:image/png;base64,cG5nMQ==" || parts[2].Type != "image_url" {
+		t.Errorf("parts = %+v", parts)
+	}
+}
+
+func TestParseImageTexts(t *testing.T) {
+	if texts, err := parseImageTexts(`Here they are: ["A", "B"]`, 2); err != nil || strings.Join(texts, "|") != "A|B" {
+		t.Errorf("texts %q, err %v", texts, err)
+	}
+	if _, err := parseImageTexts(`["A"]`, 2); !errors.Is(err, ErrImageTextCount) {
+		t.Errorf("err = %v, want ErrImageTextCount", err)
+	}
+	if _, err := parseImageTexts(`no text`, 1); err == nil || errors.Is(err, ErrImageTextCount) {
+		t.Errorf("err = %v, want an invalid response", err)
+	}
+}
@@ -0,0 +1,314 @@
+// Package unidiff produces line-based unified diffs (as printed by diff -u),
+// which can be reviewed or applied with patch.
+package unidiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// maxEditDistance bounds the work of the Myers diff between two anchors of
+// the patience diff; beyond it the lines are reported as replaced.
+const maxEditDistance = 1000
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// Diff returns the unified diff that turns a into b, or "" when they are
+// equal. fromName and toName are the file names of the header lines.
+func Diff(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	al, bl := splitLines(a), splitLines(b)
+	ops := diffLines(al, bl)
+
+	var out strings.Builder
+	_, _ = fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&out, h, ops, al, bl)
+	}
+	return out.String()
+}
+
+// splitLines splits s after each newline, keeping the line endings so that
+// the last line without one is told apart.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script of a into b: one op per line of a
+// (equal or delete) and per inserted line of b.
+func diffLines(a, b []string) []opKind {
+	// Common prefix and suffix.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops := make([]opKind, 0, len(a)+len(b))
+	ops = appendN(ops, opEqual, pre)
+	ops = append(ops, patience(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	return appendN(ops, opEqual, suf)
+}
+
+// patience anchors the diff on the lines that appear exactly once in both a
+// and b (cue timings, in subtitle files), then diffs the gaps between them.
+func patience(a, b []string) []opKind {
+	if len(a) == 0 || len(b) == 0 {
+		return append(appendN(nil, opDelete, len(a)), appendN(nil, opInsert, len(b))...)
+	}
+	type count struct{ a, b, bIdx int }
+	counts := make(map[string]*count)
+	for _, l := range a {
+		c := counts[l]
+		if c == nil {
+			c = &count{}
+			counts[l] = c
+		}
+		c.a++
+	}
+	for i, l := range b {
+		if c := counts[l]; c != nil {
+			c.b++
+			c.bIdx = i
+		}
+	}
+	var aIdx, bIdx []int // unique common lines, in the order of a
+	for i, l := range a {
+		if c := counts[l]; c.a == 1 && c.b == 1 {
+			aIdx = append(aIdx, i)
+			bIdx = append(bIdx, c.bIdx)
+		}
+	}
+	anchors := longestIncreasing(bIdx)
+	if len(anchors) == 0 {
+		return myers(a, b)
+	}
+
+	var ops []opKind
+	ai, bi := 0, 0
+	for _, n := range anchors {
+		ops = append(ops, diffLines(a[ai:aIdx[n]], b[bi:bIdx[n]])...)
+		ops = append(ops, opEqual)
+		ai, bi = aIdx[n]+1, bIdx[n]+1
+	}
+	return append(ops, diffLines(a[ai:], b[bi:])...)
+}
+
+// longestIncreasing returns the positions of the longest strictly increasing
+// subsequence of values.
+func longestIncreasing(values []int) []int {
+	var tails []int // tails[l]: position of the smallest tail of a subsequence of length l+1
+	prev := make([]int, len(values))
+	for i, v := range values {
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if values[tails[mid]] < v {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		prev[i] = -1
+		if lo > 0 {
+			prev[i] = tails[lo-1]
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+	if len(tails) == 0 {
+		return nil
+	}
+	seq := make([]int, len(tails))
+	for i, p := len(seq)-1, tails[len(tails)-1]; i >= 0; i, p = i-1, prev[p] {
+		seq[i] = p
+	}
+	return seq
+}
+
+// myers returns the shortest edit script of a into b, or a plain replacement
+// when they differ in more than maxEditDistance lines.
+func myers(a, b []string) []opKind {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxEditDistance)
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int // trace[d]: v before step d, indexed by k+d
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[off-d:off+d+1])
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return append(appendN(nil, opDelete, n), appendN(nil, opInsert, m)...)
+}
+
+func backtrack(trace [][]int, n, m int) []opKind {
+	var ops []opKind
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		vd := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && vd[k-1+d] < vd[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := vd[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, opEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, opInsert)
+		} else {
+			ops = append(ops, opDelete)
+		}
+		x, y = prevX, prevY
+	}
+	ops = appendN(ops, opEqual, x)
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func appendN(ops []opKind, op opKind, n int) []opKind {
+	for range n {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// hunk is the run ops[first:last], starting at line aStart of a and bStart
+// of b (0-based).
+type hunk struct {
+	first, last    int
+	aStart, bStart int
+}
+
+// hunks groups the changes of ops with context unchanged lines around them,
+// merging the changes up to 2*context lines apart.
+func hunks(ops []opKind, context int) []hunk {
+	aPos := make([]int, len(ops)+1) // lines of a before ops[i]
+	bPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if op != opInsert {
+			aPos[i+1]++
+		}
+		if op != opDelete {
+			bPos[i+1]++
+		}
+	}
+
+	var out []hunk
+	for i := 0; i < len(ops); {
+		if ops[i] == opEqual {
+			i++
+			continue
+		}
+		first, last := max(0, i-context), i
+		for j := i; j < len(ops); j++ {
+			if ops[j] != opEqual {
+				last = j
+			} else if j-last > 2*context {
+				break
+			}
+		}
+		end := min(len(ops), last+context+1)
+		out = append(out, hunk{first: first, last: end, aStart: aPos[first], bStart: bPos[first]})
+		i = end
+	}
+	return out
+}
+
+func writeHunk(out *strings.Builder, h hunk, ops []opKind, a, b []string) {
+	aCount, bCount := 0, 0
+	for _, op := range ops[h.first:h.last] {
+		if op != opInsert {
+			aCount++
+		}
+		if op != opDelete {
+			bCount++
+		}
+	}
+	_, _ = fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(h.aStart, aCount), hunkRange(h.bStart, bCount))
+	ai, bi := h.aStart, h.bStart
+	for _, op := range ops[h.first:h.last] {
+		switch op {
+		case opEqual:
+			writeLine(out, ' ', a[ai])
+			ai++
+			bi++
+		case opDelete:
+			writeLine(out, '-', a[ai])
+			ai++
+		case opInsert:
+			writeLine(out, '+', b[bi])
+			bi++
+		}
+	}
+}
+
+// hunkRange formats the "start,count" of a hunk header as diff -u does: the
+// count is omitted when 1, and an empty range starts at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func writeLine(out *strings.Builder, prefix byte, line string) {
+	out.WriteByte(prefix)
+	out.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
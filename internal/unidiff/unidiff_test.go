@@ -0,0 +1,55 @@
+package unidiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff_Hunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n12\n"
+	got := Diff("a.srt", "b.srt", a, b, 2)
+	want := strings.Join([]string{
+		"--- a.srt",
+		"+++ b.srt",
+		"@@ -1,5 +1,5 @@",
+		" 1",
+		" 2",
+		"-3",
+		"+three",
+		" 4",
+		" 5",
+		"@@ -9,4 +9,3 @@",
+		" 9",
+		" 10",
+		"-11",
+		" 12",
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("Diff:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestDiff_NoNewlineAtEOF(t *testing.T) {
+	got := Diff("x", "x", "a\nb", "a\nb\n", DefaultContext)
+	want := "--- x\n+++ x\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"
+	if got != want {
+		t.Fatalf("Diff:\n got %q\nwant %q", got, want)
+	}
+	if got := Diff("x", "x", "same\n", "same\n", DefaultContext); got != "" {
+		t.Fatalf("expected no diff for equal inputs, got %q", got)
+	}
+}
+
+func TestDiff_AnchorsOnUniqueLines(t *testing.T) {
+	// Renumbered cues: the unique timing lines keep the hunks small.
+	a := "1\n00:00:01,000 --> 00:00:02,000\nHi\n\n2\n00:00:02,500 --> 00:00:03,000\nHi\n\n3\n00:00:04,000 --> 00:00:05,000\nBye\n"
+	b := "1\n00:00:01,000 --> 00:00:03,000\nHi\n\n2\n00:00:04,000 --> 00:00:05,000\nBye\n"
+	got := Diff("x", "x", a, b, 0)
+	want := "--- x\n+++ x\n@@ -2 +2 @@\n-00:00:01,000 --> 00:00:02,000\n+00:00:01,000 --> 00:00:03,000\n" +
+		"@@ -6,4 +5,0 @@\n-00:00:02,500 --> 00:00:03,000\n-Hi\n-\n-3\n"
+	if got != want {
+		t.Fatalf("Diff:\n got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NotifyInterval is how often Notify queries the releases.
+const NotifyInterval = 24 * time.Hour
+
+// notifyState is the state file of Notify: when the releases were last
+// queried and the newest version found.
+type notifyState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Version   string    `json:"version,omitempty"`
+}
+
+// DefaultNotifyStatePath returns the default state file of Notify under the
+// user cache dir (e.g. ~/.cache/subtitle-tools/update-check.json).
+func DefaultNotifyStatePath() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "subtitle-tools", "update-check.json"), nil
+}
+
+// NotifyCached returns the version found by the last Notify query, kept in
+// statePath, when it is newer than current, or "" otherwise. due tells
+// whether NotifyInterval has passed since that query, so Notify has to query
+// again.
+func NotifyCached(statePath, current string) (version string, due bool) {
+	var state notifyState
+	if b, err := os.ReadFile(statePath); err == nil {
+		// A corrupt state file is replaced by the next query.
+		_ = json.Unmarshal(b, &state)
+	}
+	due = state.CheckedAt.IsZero() || time.Since(state.CheckedAt) >= NotifyInterval
+	return newerVersion(current, state.Version), due
+}
+
+// Notify queries the newest release and returns its version when it is newer
+// than opts.CurrentVersion, or "" otherwise. The version is kept in statePath
+// for NotifyCached; a failed query leaves it untouched, so it is retried.
+// Without opts.Channel, a prerelease current version also looks at the
+// prereleases.
+func Notify(ctx context.Context, opts Options, statePath string) (string, error) {
+	if opts.CurrentVersion == "" || opts.CurrentVersion == "dev" {
+		return "", nil // dev builds have nothing to compare with
+	}
+	if opts.Channel == "" {
+		if v, ok := parseSemver(opts.CurrentVersion); ok && len(v.pre) > 0 {
+			opts.Channel = ChannelPrerelease
+		}
+	}
+	opts.CheckOnly, opts.quiet = true, true
+	res, err := Run(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := saveNotifyState(statePath, notifyState{CheckedAt: time.Now(), Version: res.Version}); err != nil {
+		return "", err
+	}
+	return newerVersion(opts.CurrentVersion, res.Version), nil
+}
+
+// newerVersion returns version when it is newer than current, or "".
+func newerVersion(current, version string) string {
+	if version == "" || current == "" || current == "dev" {
+		return ""
+	}
+	if available, downgrade := isAvailable(current, version, false); !available || downgrade {
+		return ""
+	}
+	return version
+}
+
+// saveNotifyState writes state to path atomically, so concurrent commands
+// never read half a file.
+func saveNotifyState(path string, state notifyState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create update check dir: %w", err)
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".update-check-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,58 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	newReleaseServer(t, []release{testRelease("v2.0.0-rc.1", true), testRelease("v1.9.0", false)})
+	statePath := filepath.Join(t.TempDir(), "subtitle-tools", "update-check.json")
+	opts := Options{Owner: "owner", Repo: "repo", ExePath: "x", CurrentVersion: "1.8.0"}
+
+	if v, due := NotifyCached(statePath, "1.8.0"); v != "" || !due {
+		t.Fatalf("NotifyCached without a state = %q, %v", v, due)
+	}
+	if v, err := Notify(context.Background(), opts, statePath); err != nil || v != "1.9.0" {
+		t.Fatalf("Notify = %q, %v, want 1.9.0", v, err)
+	}
+	if v, due := NotifyCached(statePath, "1.8.0"); v != "1.9.0" || due {
+		t.Fatalf("NotifyCached = %q, %v, want 1.9.0, false", v, due)
+	}
+	if v, _ := NotifyCached(statePath, "v1.9.0"); v != "" {
+		t.Fatalf("NotifyCached when up to date = %q", v)
+	}
+
+	// An expired state is due; a failed query keeps it.
+	b, _ := json.Marshal(notifyState{CheckedAt: time.Now().Add(-NotifyInterval - time.Minute), Version: "1.9.0"})
+	if err := os.WriteFile(statePath, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if v, due := NotifyCached(statePath, "1.8.0"); v != "1.9.0" || !due {
+		t.Fatalf("NotifyCached of an expired state = %q, %v", v, due)
+	}
+	githubAPIURL = "http://invalid"
+	if _, err := Notify(context.Background(), opts, statePath); err == nil {
+		t.Fatal("Notify didn't fail without GitHub")
+	}
+	if got, _ := os.ReadFile(statePath); string(got) != string(b) {
+		t.Fatalf("state after a failed query %s, want %s", got, b)
+	}
+
+	if v, err := Notify(context.Background(), Options{CurrentVersion: "dev"}, statePath); err != nil || v != "" {
+		t.Fatalf("Notify of a dev build = %q, %v", v, err)
+	}
+}
+
+func TestNotify_PrereleaseChannel(t *testing.T) {
+	newReleaseServer(t, []release{testRelease("v2.0.0-rc.2", true), testRelease("v1.9.0", false)})
+	statePath := filepath.Join(t.TempDir(), "update-check.json")
+	opts := Options{Owner: "owner", Repo: "repo", ExePath: "x", CurrentVersion: "2.0.0-rc.1"}
+	if v, err := Notify(context.Background(), opts, statePath); err != nil || v != "2.0.0-rc.2" {
+		t.Fatalf("Notify = %q, %v, want 2.0.0-rc.2", v, err)
+	}
+}
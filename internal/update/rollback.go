@@ -0,0 +1,95 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// selfCheckTimeout bounds the --version run of a new executable.
+const selfCheckTimeout = 10 * time.Second
+
+// ErrSelfCheck is returned when the installed executable fails to run.
+var ErrSelfCheck = errors.New("new version fails to run")
+
+// ErrNoPrevious is returned by Rollback when no previous version was kept.
+var ErrNoPrevious = errors.New("no previous version to roll back to")
+
+// PrevPath returns where an update keeps the executable it replaced:
+// subtitle-tools.prev next to subtitle-tools (or subtitle-tools.exe).
+func PrevPath(exePath string) string {
+	return strings.TrimSuffix(exePath, ".exe") + ".prev"
+}
+
+// selfCheck runs the executable at path with --version and returns the
+// version it prints.
+func selfCheck(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+	defer cancel()
+	var out bytes.Buffer
+	c := exec.CommandContext(ctx, path, "--version")
+	c.Stdout, c.Stderr = &out, &out
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	// The version template prints "<version> (<commit>)".
+	fields := strings.Fields(out.String())
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return normalizeVersion(fields[0]), nil
+}
+
+// restorePrev puts the executable kept by install back at exePath, dropping
+// the one installed over it.
+func restorePrev(exePath string) error {
+	return os.Rename(PrevPath(exePath), exePath)
+}
+
+// Rollback restores the executable kept by the last update. The replaced one
+// is kept in turn, so a second rollback undoes the first. With an empty
+// exePath the current executable is rolled back.
+func Rollback(ctx context.Context, exePath string) (Result, error) {
+	if exePath == "" {
+		var err error
+		if exePath, err = getExePath(); err != nil {
+			return Result{}, err
+		}
+	}
+	prevPath := PrevPath(exePath)
+	if _, err := os.Stat(prevPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Result{}, fmt.Errorf("%w: %s not found", ErrNoPrevious, prevPath)
+		}
+		return Result{}, err
+	}
+	version, err := selfCheck(ctx, prevPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("previous version at %s fails to run: %w", prevPath, err)
+	}
+
+	slog.Info("Rollback started", "exe_path", exePath, "prev_path", prevPath, "version", version)
+	swapPath := exePath + ".rollback"
+	if err := os.Rename(exePath, swapPath); err != nil {
+		return Result{}, fmt.Errorf("move current version aside: %w", err)
+	}
+	if err := os.Rename(prevPath, exePath); err != nil {
+		if rbErr := os.Rename(swapPath, exePath); rbErr != nil {
+			slog.Warn("Failed to restore the current version after rollback failure", "path", swapPath, "dst", exePath, "error", rbErr)
+		}
+		return Result{}, fmt.Errorf("restore previous version: %w", err)
+	}
+	if err := os.Rename(swapPath, prevPath); err != nil {
+		slog.Warn("Could not keep the rolled back version", "path", swapPath, "error", err)
+	}
+	return Result{Updated: true, Version: version, ExePath: exePath}, nil
+}
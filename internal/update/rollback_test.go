@@ -0,0 +1,79 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// installTestExe writes the current executable of an update test, printing
+// version.
+func installTestExe(t *testing.T, version string) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "subtitle-tools")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\necho "+version+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRun_KeepsPreviousAndRollsBack(t *testing.T) {
+	newAssetServer(t, testBinary, "%s  %s\n", nil)
+	exe := installTestExe(t, "1.0.0")
+	old := readFile(t, exe)
+
+	res, err := Run(context.Background(), Options{Owner: "owner", Repo: "repo", CurrentVersion: "v1.0.0", ExePath: exe, WorkDir: t.TempDir()})
+	if err != nil || !res.Updated || res.ExePath != exe {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+	if got := readFile(t, exe); got != string(testBinary) {
+		t.Fatalf("installed %q", got)
+	}
+	if got := readFile(t, PrevPath(exe)); got != old {
+		t.Fatalf("kept %q, want %q", got, old)
+	}
+
+	res, err = Rollback(context.Background(), exe)
+	if err != nil || res.Version != "1.0.0" {
+		t.Fatalf("Rollback = %+v, %v", res, err)
+	}
+	// The rolled back version is kept, so a second rollback undoes the first.
+	if readFile(t, exe) != old || readFile(t, PrevPath(exe)) != string(testBinary) {
+		t.Fatal("Rollback didn't swap the executables")
+	}
+	if res, err = Rollback(context.Background(), exe); err != nil || res.Version != "2.0.0" {
+		t.Fatalf("second Rollback = %+v, %v", res, err)
+	}
+}
+
+func TestRun_RollsBackBrokenUpdate(t *testing.T) {
+	newAssetServer(t, []byte("#!/bin/sh\necho broken >&2\nexit 1\n"), "%s  %s\n", nil)
+	exe := installTestExe(t, "1.0.0")
+	old := readFile(t, exe)
+
+	_, err := Run(context.Background(), Options{Owner: "owner", Repo: "repo", CurrentVersion: "v1.0.0", ExePath: exe, WorkDir: t.TempDir()})
+	if !errors.Is(err, ErrSelfCheck) {
+		t.Fatalf("Run: %v, want %v", err, ErrSelfCheck)
+	}
+	if got := readFile(t, exe); got != old {
+		t.Fatalf("executable after a failed self-check %q, want %q", got, old)
+	}
+}
+
+func TestRollback_NoPrevious(t *testing.T) {
+	exe := installTestExe(t, "1.0.0")
+	if _, err := Rollback(context.Background(), exe); !errors.Is(err, ErrNoPrevious) {
+		t.Fatalf("Rollback: %v, want %v", err, ErrNoPrevious)
+	}
+}
@@ -0,0 +1,52 @@
+package update
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.0", 1},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3+build.5", "1.2.3", 0},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"2.0.0", "10.0.0", -1},
+	} {
+		got, ok := compareVersions(tc.a, tc.b)
+		if !ok || got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, %v, want %d", tc.a, tc.b, got, ok, tc.want)
+		}
+	}
+	for _, v := range []string{"dev", "1.2.3.4", "1.02.0", "1.2.3-", "1..2"} {
+		if _, ok := parseSemver(v); ok {
+			t.Errorf("parseSemver(%q) accepted an invalid version", v)
+		}
+	}
+}
+
+func TestIsAvailable(t *testing.T) {
+	for _, tc := range []struct {
+		current, release     string
+		allowDowngrade       bool
+		available, downgrade bool
+	}{
+		{"", "1.0.0", false, true, false},
+		{"dev", "1.0.0", false, true, false},
+		{"1.9.0", "1.10.0", false, true, false},
+		{"v1.10.0", "1.10.0", false, false, false},
+		{"1.10.0", "1.9.0", false, false, true},
+		{"1.10.0", "1.9.0", true, true, true},
+		{"custom", "1.9.0", false, true, false},
+	} {
+		available, downgrade := isAvailable(tc.current, tc.release, tc.allowDowngrade)
+		if available != tc.available || downgrade != tc.downgrade {
+			t.Errorf("isAvailable(%q, %q, %v) = %v, %v, want %v, %v", tc.current, tc.release, tc.allowDowngrade, available, downgrade, tc.available, tc.downgrade)
+		}
+	}
+}
@@ -12,9 +12,12 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,27 +30,78 @@ const (
 	defaultRepo  = "subtitle-tools"
 )
 
+// Release channels.
+const (
+	ChannelStable     = "stable"     // the latest release
+	ChannelPrerelease = "prerelease" // the newest release, including prereleases
+	DefaultChannel    = ChannelStable
+)
+
+// githubAPIURL is the base URL of the GitHub REST API.
+var githubAPIURL = "https://api.github.com"
+
+// errNotFound is wrapped by the errors of the GitHub API requests answered
+// with 404.
+var errNotFound = errors.New("not found")
+
 type Options struct {
+	// APIURL is the base URL of the GitHub REST API, for GitHub Enterprise
+	// (e.g. https://github.example.com/api/v3) or a mirror of it.
+	APIURL         string
 	Owner          string
 	Repo           string
 	APIKey         string
 	CurrentVersion string
 	ExePath        string
-	DryRun         bool
-	WorkDir        string
-	HTTPClient     *http.Client
+	// Channel selects the release installed when Version is empty.
+	Channel string
+	// Version pins the release to install (e.g. v1.2.3), older ones included.
+	Version string
+	// AssetURL downloads the archive from this URL instead of the assets of a
+	// GitHub release, which isn't queried. {version}, {os}, {arch} and {ext}
+	// are replaced by Version and the platform. The checksums file and its
+	// signature are fetched from the same directory.
+	AssetURL string
+	// CheckOnly reports whether an update is available without downloading it.
+	CheckOnly bool
+	// AllowDowngrade installs the release of the channel even when it is
+	// older than the current version. A pinned Version is always installed.
+	AllowDowngrade bool
+	// SkipChecksum installs the archive without checking it against the
+	// checksums file of the release.
+	SkipChecksum bool
+	// PublicKey is a minisign public key (the base64 line or the content of
+	// the .pub file). When set, the checksums file must have a valid
+	// signature by this key.
+	PublicKey  string
+	DryRun     bool
+	WorkDir    string
+	HTTPClient *http.Client
+	Transport  http.RoundTripper // used when HTTPClient is nil
+
+	// quiet drops the log of the check, for the background checks of Notify.
+	quiet bool
 }
 
 type Result struct {
-	Updated   bool
-	Version   string
-	AssetName string
-	ExePath   string
+	Updated bool
+	// Available is whether the selected release differs from the current
+	// version (set with Options.CheckOnly too).
+	Available bool
+	// Downgrade is whether the selected release is older than the current
+	// version.
+	Downgrade  bool
+	Version    string
+	Prerelease bool
+	AssetName  string
+	ExePath    string
 }
 
 type release struct {
-	TagName string  `json:"tag_name"`
-	Assets  []asset `json:"assets"`
+	TagName    string  `json:"tag_name"`
+	Draft      bool    `json:"draft"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []asset `json:"assets"`
 }
 
 type asset struct {
@@ -56,9 +110,26 @@ type asset struct {
 }
 
 func validateAndDefaultOptions(opts Options) (Options, error) {
-	if opts.WorkDir == "" {
+	if opts.WorkDir == "" && !opts.CheckOnly {
 		return Options{}, errors.New("workdir is required")
 	}
+	if opts.SkipChecksum && opts.PublicKey != "" {
+		return Options{}, errors.New("the signature can't be verified when skipping the checksum")
+	}
+	switch opts.Channel {
+	case "":
+		opts.Channel = DefaultChannel
+	case ChannelStable, ChannelPrerelease:
+	default:
+		return Options{}, fmt.Errorf("invalid channel %q (supported: %s, %s)", opts.Channel, ChannelStable, ChannelPrerelease)
+	}
+	if opts.APIURL == "" {
+		opts.APIURL = githubAPIURL
+	}
+	opts.APIURL = strings.TrimSuffix(opts.APIURL, "/")
+	if strings.Contains(opts.AssetURL, "{version}") && opts.Version == "" {
+		return Options{}, errors.New("the asset URL has a {version} placeholder but no version is set")
+	}
 	if opts.Owner == "" {
 		opts.Owner = defaultOwner
 	}
@@ -81,57 +152,142 @@ func Run(ctx context.Context, opts Options) (Result, error) {
 		return Result{}, err
 	}
 
-	slog.Info("Update check started", "owner", opts.Owner, "repo", opts.Repo, "current_version", opts.CurrentVersion, "exe_path", opts.ExePath)
+	if !opts.quiet {
+		slog.Info("Update check started", "owner", opts.Owner, "repo", opts.Repo, "current_version", opts.CurrentVersion, "exe_path", opts.ExePath, "channel", opts.Channel, "version", opts.Version)
+	}
 
 	client := opts.HTTPClient
 	if client == nil {
-		client = &http.Client{Timeout: 30 * time.Second}
+		client = &http.Client{Transport: opts.Transport, Timeout: 30 * time.Second}
 	}
 
-	rel, err := fetchLatestRelease(ctx, client, opts.Owner, opts.Repo, opts.APIKey)
-	if err != nil {
-		return Result{}, err
+	if opts.PublicKey != "" {
+		// Fail before any download on a malformed key.
+		if _, err := parseMinisignKey(opts.PublicKey); err != nil {
+			return Result{}, err
+		}
 	}
 
-	version := normalizeVersion(rel.TagName)
-	asset, err := findAsset(rel.Assets, version, runtime.GOOS, runtime.GOARCH)
+	var rel release
+	var asset asset
+	if opts.AssetURL != "" {
+		rel, asset, err = mirrorRelease(opts, runtime.GOOS, runtime.GOARCH)
+		// A mirror isn't GitHub: don't send it the API key.
+		opts.APIKey = ""
+	} else {
+		rel, err = fetchRelease(ctx, client, opts)
+		if err == nil {
+			asset, err = findAsset(rel.Assets, normalizeVersion(rel.TagName), runtime.GOOS, runtime.GOARCH)
+		}
+	}
 	if err != nil {
 		return Result{}, err
 	}
 
-	if isUpToDate(opts.CurrentVersion, version) {
-		return Result{Updated: false, Version: version, AssetName: asset.Name, ExePath: opts.ExePath}, nil
+	version := normalizeVersion(rel.TagName)
+	res := Result{Version: version, Prerelease: rel.Prerelease, AssetName: asset.Name, ExePath: opts.ExePath}
+	if version == "" {
+		// An asset URL without a version: nothing to compare.
+		res.Available = true
+	} else {
+		res.Available, res.Downgrade = isAvailable(opts.CurrentVersion, version, opts.AllowDowngrade || opts.Version != "")
+	}
+	if !res.Available || opts.CheckOnly {
+		return res, nil
 	}
 
 	namer := run.NewTempNamer(opts.WorkDir, opts.ExePath)
 
-	newPath, err := downloadAndExtract(ctx, client, namer, asset, opts.APIKey, runtime.GOOS)
+	archive, err := downloadAsset(ctx, client, asset, opts.APIKey)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := verifyArchive(ctx, client, rel, asset.Name, archive, opts); err != nil {
+		return Result{}, err
+	}
+	newPath, err := extractBinary(archive, asset.Name, namer, runtime.GOOS)
 	if err != nil {
 		return Result{}, err
 	}
 
-	outputPath := opts.ExePath
 	if opts.DryRun {
-		outputPath = namer.Step("exec")
+		outputPath := namer.Step("exec")
+		if err := fs.MoveFile(newPath, outputPath); err != nil {
+			return Result{}, err
+		}
+		if _, err := selfCheck(ctx, outputPath); err != nil {
+			return Result{}, fmt.Errorf("%w: %s: %v", ErrSelfCheck, version, err)
+		}
+		res.Updated, res.ExePath = true, outputPath
+		return res, nil
 	}
-	err = moveFileWithFallback(newPath, outputPath)
-	if err != nil {
+
+	if err := install(newPath, opts.ExePath); err != nil {
 		return Result{}, err
 	}
-	return Result{Updated: true, Version: version, AssetName: asset.Name, ExePath: outputPath}, nil
+	if _, err := selfCheck(ctx, opts.ExePath); err != nil {
+		if rbErr := restorePrev(opts.ExePath); rbErr != nil {
+			return Result{}, fmt.Errorf("%w: %s: %v; restoring the previous version failed: %v", ErrSelfCheck, version, err, rbErr)
+		}
+		return Result{}, fmt.Errorf("%w: %s: %v; the previous version was restored", ErrSelfCheck, version, err)
+	}
+	res.Updated = true
+	return res, nil
+}
+
+// fetchRelease returns the release pinned by opts.Version or, without one, the
+// newest release of opts.Channel.
+func fetchRelease(ctx context.Context, client *http.Client, opts Options) (release, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s/releases", opts.APIURL, opts.Owner, opts.Repo)
+	var rel release
+	switch {
+	case opts.Version != "":
+		// Tags are v-prefixed, but accept the ones that aren't.
+		tag := "v" + normalizeVersion(opts.Version)
+		err := getJSON(ctx, client, base+"/tags/"+tag, opts.APIKey, &rel)
+		if errors.Is(err, errNotFound) {
+			tag = normalizeVersion(opts.Version)
+			err = getJSON(ctx, client, base+"/tags/"+tag, opts.APIKey, &rel)
+		}
+		if errors.Is(err, errNotFound) {
+			return release{}, fmt.Errorf("release %s not found", opts.Version)
+		}
+		if err != nil {
+			return release{}, err
+		}
+	case opts.Channel == ChannelPrerelease:
+		// Releases are listed newest first.
+		var rels []release
+		if err := getJSON(ctx, client, base+"?per_page=30", opts.APIKey, &rels); err != nil {
+			return release{}, err
+		}
+		i := slices.IndexFunc(rels, func(r release) bool { return !r.Draft })
+		if i < 0 {
+			return release{}, errors.New("github repository has no releases")
+		}
+		rel = rels[i]
+	default:
+		if err := getJSON(ctx, client, base+"/latest", opts.APIKey, &rel); err != nil {
+			return release{}, err
+		}
+	}
+	if rel.TagName == "" {
+		return release{}, errors.New("github release has no tag_name")
+	}
+	return rel, nil
 }
 
-func fetchLatestRelease(ctx context.Context, client *http.Client, owner, repo, apiKey string) (release, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo)
+// getJSON decodes the response of a GitHub API request into v.
+func getJSON(ctx context.Context, client *http.Client, url, apiKey string, v any) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return release{}, err
+		return err
 	}
 	setGitHubHeaders(req, apiKey)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return release{}, err
+		return err
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -140,26 +296,50 @@ func fetchLatestRelease(ctx context.Context, client *http.Client, owner, repo, a
 		}
 	}(resp.Body)
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("github api error: %s: %w", resp.Status, errNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8192))
-		return release{}, fmt.Errorf("github api error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		return fmt.Errorf("github api error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
 
-	var rel release
-	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
-		return release{}, fmt.Errorf("decode release json: %w", err)
-	}
-	if rel.TagName == "" {
-		return release{}, errors.New("github release has no tag_name")
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode release json: %w", err)
 	}
-	return rel, nil
+	return nil
 }
 
-func findAsset(assets []asset, version, goos, goarch string) (asset, error) {
-	ext := ".tar.gz"
+// mirrorRelease returns the release described by opts.AssetURL, with the
+// archive of the platform and the checksums file and signature next to it.
+func mirrorRelease(opts Options, goos, goarch string) (release, asset, error) {
+	version := normalizeVersion(opts.Version)
+	u, err := url.Parse(strings.NewReplacer(
+		"{version}", version, "{os}", goos, "{arch}", goarch, "{ext}", archiveExt(goos),
+	).Replace(opts.AssetURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return release{}, asset{}, fmt.Errorf("invalid asset URL %q", opts.AssetURL)
+	}
+	archive := asset{Name: path.Base(u.Path), DownloadURL: u.String()}
+	rel := release{TagName: version, Assets: []asset{archive}}
+	for _, name := range []string{checksumsAssetName, checksumsAssetName + signatureAssetExt} {
+		ref := *u
+		ref.Path, ref.RawPath, ref.RawQuery = path.Join(path.Dir(u.Path), name), "", ""
+		rel.Assets = append(rel.Assets, asset{Name: name, DownloadURL: ref.String()})
+	}
+	return rel, archive, nil
+}
+
+// archiveExt returns the extension of the release archives of goos.
+func archiveExt(goos string) string {
 	if goos == "windows" {
-		ext = ".zip"
+		return ".zip"
 	}
+	return ".tar.gz"
+}
+
+func findAsset(assets []asset, version, goos, goarch string) (asset, error) {
+	ext := archiveExt(goos)
 	expected := fmt.Sprintf("subtitle-tools_%s_%s_%s%s", version, goos, goarch, ext)
 	for _, a := range assets {
 		if a.Name == expected {
@@ -173,26 +353,39 @@ func normalizeVersion(tag string) string {
 	return strings.TrimPrefix(strings.TrimSpace(tag), "v")
 }
 
-func isUpToDate(current, latest string) bool {
+// isAvailable reports whether the release version can replace current: it is
+// newer or, with allowDowngrade, older. downgrade tells whether release is
+// older than current. A dev build or a version that
+// isn't semver is only compared for equality.
+func isAvailable(current, release string, allowDowngrade bool) (available, downgrade bool) {
 	if current == "" || current == "dev" {
-		return false
+		return true, false
 	}
-	return normalizeVersion(current) == latest
+	c, ok := compareVersions(current, release)
+	if !ok {
+		return normalizeVersion(current) != release, false
+	}
+	if c > 0 {
+		return allowDowngrade, true
+	}
+	return c < 0, false
 }
 
-func downloadAndExtract(ctx context.Context, client *http.Client,
-	namer run.TempNamer,
-	a asset, apiKey string, goos string) (string, error) {
+// maxAssetSize bounds the size of a downloaded release asset.
+const maxAssetSize = 512 << 20
+
+// downloadAsset returns the content of a release asset.
+func downloadAsset(ctx context.Context, client *http.Client, a asset, apiKey string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.DownloadURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	setGitHubHeaders(req, apiKey)
 	req.Header.Set("Accept", "application/octet-stream")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -201,19 +394,105 @@ func downloadAndExtract(ctx context.Context, client *http.Client,
 		}
 	}(resp.Body)
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("download %s: %w", a.Name, errNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8192))
-		return "", fmt.Errorf("download error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		return nil, fmt.Errorf("download error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", a.Name, err)
+	}
+	if len(b) > maxAssetSize {
+		return nil, fmt.Errorf("download %s: larger than %d MiB", a.Name, maxAssetSize>>20)
+	}
+	return b, nil
+}
+
+// verifyArchive checks the downloaded archive against the checksums file of
+// rel and, with opts.PublicKey, the signature of the checksums file, before
+// anything is extracted.
+func verifyArchive(ctx context.Context, client *http.Client, rel release, name string, archive []byte, opts Options) error {
+	if opts.SkipChecksum {
+		slog.Warn("Checksum verification skipped", "asset", name)
+		return nil
+	}
+	sumsAsset, ok := findAssetByName(rel.Assets, checksumsAssetName)
+	var sumsData []byte
+	var err error
+	if ok {
+		sumsData, err = downloadAsset(ctx, client, sumsAsset, opts.APIKey)
+	}
+	if !ok || errors.Is(err, errNotFound) {
+		return fmt.Errorf("%s has no %s to verify the download", releaseName(rel), checksumsAssetName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.PublicKey != "" {
+		key, err := parseMinisignKey(opts.PublicKey)
+		if err != nil {
+			return err
+		}
+		sigName := checksumsAssetName + signatureAssetExt
+		sigAsset, ok := findAssetByName(rel.Assets, sigName)
+		var sig []byte
+		if ok {
+			sig, err = downloadAsset(ctx, client, sigAsset, opts.APIKey)
+		}
+		if !ok || errors.Is(err, errNotFound) {
+			return fmt.Errorf("%w: %s has no %s", ErrInvalidSignature, releaseName(rel), sigName)
+		}
+		if err != nil {
+			return err
+		}
+		if err := verifyMinisign(key, sumsData, sig); err != nil {
+			return fmt.Errorf("%s: %w", sigName, err)
+		}
+		slog.Info("Signature verified", "asset", checksumsAssetName)
+	}
+
+	sums, err := parseChecksums(sumsData)
+	if err != nil {
+		return fmt.Errorf("%s: %w", checksumsAssetName, err)
+	}
+	if err := verifyChecksum(sums, name, archive); err != nil {
+		return err
+	}
+	slog.Info("Checksum verified", "asset", name)
+	return nil
+}
+
+// releaseName names rel in errors.
+func releaseName(rel release) string {
+	if rel.TagName == "" {
+		return "the asset URL directory"
 	}
+	return "release " + rel.TagName
+}
 
+func findAssetByName(assets []asset, name string) (asset, bool) {
+	for _, a := range assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return asset{}, false
+}
+
+// extractBinary extracts the executable from the archive of the named asset.
+func extractBinary(archive []byte, name string, namer run.TempNamer, goos string) (string, error) {
 	binaryName := expectedBinaryName(goos)
-	if strings.HasSuffix(a.Name, ".tar.gz") {
-		return extractTarGz(resp.Body, namer, binaryName)
+	if strings.HasSuffix(name, ".tar.gz") {
+		return extractTarGz(bytes.NewReader(archive), namer, binaryName)
 	}
-	if strings.HasSuffix(a.Name, ".zip") {
-		return extractZip(resp.Body, namer, binaryName)
+	if strings.HasSuffix(name, ".zip") {
+		return extractZip(archive, namer, binaryName)
 	}
-	return "", fmt.Errorf("unsupported asset format: %s", a.Name)
+	return "", fmt.Errorf("unsupported asset format: %s", name)
 }
 
 func expectedBinaryName(goos string) string {
@@ -250,11 +529,7 @@ func extractTarGz(r io.Reader, namer run.TempNamer, binaryName string) (string,
 	return "", fmt.Errorf("binary %s not found in archive", binaryName)
 }
 
-func extractZip(r io.Reader, namer run.TempNamer, binaryName string) (string, error) {
-	buf, err := io.ReadAll(r)
-	if err != nil {
-		return "", fmt.Errorf("read zip: %w", err)
-	}
+func extractZip(buf []byte, namer run.TempNamer, binaryName string) (string, error) {
 	zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
 	if err != nil {
 		return "", fmt.Errorf("open zip: %w", err)
@@ -315,42 +590,23 @@ func getExePath() (string, error) {
 	return exePath, nil
 }
 
-// moveFileWithFallback attempts to move src to dst.
-// On Windows, if the destination file is in use (sharing violation), it attempts a fallback:
-// rename dst to dst.old and then move src to dst.
-func moveFileWithFallback(src, dst string) error {
-	if err := fs.MoveFile(src, dst); err != nil {
-		if fs.IsFileInUseError(err) {
-			slog.Info("File in use, attempting fallback rename strategy", "path", dst, "error", err)
-
-			// Try to rename the old file to .old
-			oldPath := dst + ".old"
-			// If a previous .old file exists (for example from a prior failed update),
-			// attempt to remove it first so that the rename does not fail with "file exists".
-			if err := os.Remove(oldPath); err != nil && !errors.Is(err, os.ErrNotExist) {
-				slog.Warn("Could not remove existing .old file before rename", "path", oldPath, "error", err)
-			}
-			if err := os.Rename(dst, oldPath); err != nil {
-				return fmt.Errorf("could not rename existing file to .old: %w", err)
-			}
-
-			// Now move again the new file to the destination
-			if err := fs.MoveFile(src, dst); err != nil {
-				// Attempt rollback: try to restore the original file so we don't leave dst missing.
-				if rollbackErr := os.Rename(oldPath, dst); rollbackErr != nil {
-					slog.Warn("Failed to roll back after move failure; original file may be left at .old", "oldPath", oldPath, "dst", dst, "error", rollbackErr)
-				}
-				return fmt.Errorf("could not move new file to destination: %w", err)
-			}
-
-			// Clean up the old file. If this fails, log a warning but don't return an error since the update succeeded.
-			if err := os.Remove(oldPath); err != nil {
-				slog.Warn("Could not remove old file after successful update", "path", oldPath, "error", err)
-			}
-
-			return nil
+// install replaces exePath with newPath, keeping the current executable as
+// PrevPath(exePath). Renaming works on a running executable on every platform,
+// so the new one never has to overwrite a file in use.
+func install(newPath, exePath string) error {
+	prevPath := PrevPath(exePath)
+	if err := os.Remove(prevPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove previous version: %w", err)
+	}
+	if err := os.Rename(exePath, prevPath); err != nil {
+		return fmt.Errorf("keep current version: %w", err)
+	}
+	if err := fs.MoveFile(newPath, exePath); err != nil {
+		// Put the current executable back, so exePath isn't left missing.
+		if rbErr := os.Rename(prevPath, exePath); rbErr != nil {
+			slog.Warn("Failed to restore the current version after move failure", "prevPath", prevPath, "dst", exePath, "error", rbErr)
 		}
-		return err
+		return fmt.Errorf("could not move new file to destination: %w", err)
 	}
 	return nil
 }
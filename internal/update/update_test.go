@@ -0,0 +1,136 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// newReleaseServer serves the releases of a GitHub repository: the first of
+// releases is the newest.
+func newReleaseServer(t *testing.T, releases []release) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/releases")
+		var v any
+		switch {
+		case path == "":
+			v = releases
+		case path == "/latest":
+			for _, rel := range releases {
+				if !rel.Draft && !rel.Prerelease {
+					v = rel
+					break
+				}
+			}
+		case strings.HasPrefix(path, "/tags/"):
+			for _, rel := range releases {
+				if rel.TagName == strings.TrimPrefix(path, "/tags/") {
+					v = rel
+				}
+			}
+		}
+		if v == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	t.Cleanup(server.Close)
+	old := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() { githubAPIURL = old })
+	return server
+}
+
+func testRelease(tag string, prerelease bool) release {
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	name := fmt.Sprintf("subtitle-tools_%s_%s_%s%s", normalizeVersion(tag), runtime.GOOS, runtime.GOARCH, ext)
+	return release{TagName: tag, Prerelease: prerelease, Assets: []asset{{Name: name, DownloadURL: "http://invalid/" + name}}}
+}
+
+func TestRun_CheckSelectsRelease(t *testing.T) {
+	newReleaseServer(t, []release{
+		{TagName: "v2.1.0", Draft: true},
+		testRelease("v2.0.0-rc.1", true),
+		testRelease("v1.9.0", false),
+		testRelease("1.8.0", false),
+	})
+
+	for _, tc := range []struct {
+		name      string
+		opts      Options
+		version   string
+		available bool
+	}{
+		{name: "stable", opts: Options{}, version: "1.9.0", available: true},
+		{name: "prerelease", opts: Options{Channel: ChannelPrerelease}, version: "2.0.0-rc.1", available: true},
+		{name: "pinned", opts: Options{Version: "1.9.0", CurrentVersion: "v1.9.0"}, version: "1.9.0", available: false},
+		{name: "pinned without v", opts: Options{Version: "v1.8.0"}, version: "1.8.0", available: true},
+		{name: "pinned older", opts: Options{Version: "1.8.0", CurrentVersion: "1.9.0"}, version: "1.8.0", available: true},
+		{name: "newer current", opts: Options{CurrentVersion: "1.10.0"}, version: "1.9.0", available: false},
+		{name: "allow downgrade", opts: Options{CurrentVersion: "1.10.0", AllowDowngrade: true}, version: "1.9.0", available: true},
+		{name: "prerelease to stable", opts: Options{CurrentVersion: "2.0.0-rc.1"}, version: "1.9.0", available: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			opts.Owner, opts.Repo, opts.ExePath, opts.CheckOnly = "owner", "repo", "/usr/local/bin/subtitle-tools", true
+			res, err := Run(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if res.Version != tc.version || res.Available != tc.available || res.Updated {
+				t.Fatalf("Run = %+v, want version %s, available %v", res, tc.version, tc.available)
+			}
+		})
+	}
+
+	_, err := Run(context.Background(), Options{Owner: "owner", Repo: "repo", ExePath: "x", CheckOnly: true, Version: "v3.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "release v3.0.0 not found") {
+		t.Fatalf("Run with a missing version: %v", err)
+	}
+	_, err = Run(context.Background(), Options{Owner: "owner", Repo: "repo", ExePath: "x", CheckOnly: true, Channel: "nightly"})
+	if err == nil || !strings.Contains(err.Error(), "invalid channel") {
+		t.Fatalf("Run with an invalid channel: %v", err)
+	}
+}
+
+func TestRun_APIURL(t *testing.T) {
+	server := newReleaseServer(t, []release{testRelease("v1.9.0", false)})
+	githubAPIURL = "http://invalid"
+	res, err := Run(context.Background(), Options{APIURL: server.URL + "/", Owner: "owner", Repo: "repo", ExePath: "x", CheckOnly: true})
+	if err != nil || res.Version != "1.9.0" {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+}
+
+func TestRun_AssetURL(t *testing.T) {
+	base := newAssetServer(t, testBinary, "%s  %s\n", nil)
+	githubAPIURL = "http://invalid" // the release isn't queried
+
+	res, err := runTestUpdate(t, Options{AssetURL: base + "/download/subtitle-tools_{version}_{os}_{arch}{ext}", Version: "v2.0.0"})
+	if err != nil || !res.Updated || res.Version != "2.0.0" {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+
+	_, err = runTestUpdate(t, Options{AssetURL: base + "/download/subtitle-tools_{version}_{os}_{arch}{ext}"})
+	if err == nil || !strings.Contains(err.Error(), "no version") {
+		t.Fatalf("Run without a version: %v", err)
+	}
+	_, err = runTestUpdate(t, Options{AssetURL: base + "/missing/subtitle-tools.tar.gz", SkipChecksum: true})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Run with a missing asset: %v", err)
+	}
+	_, err = runTestUpdate(t, Options{AssetURL: "ftp://mirror/subtitle-tools.tar.gz"})
+	if err == nil || !strings.Contains(err.Error(), "invalid asset URL") {
+		t.Fatalf("Run with an ftp URL: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Release assets used to verify the archive: the goreleaser checksums file and
+// its minisign signature.
+const (
+	checksumsAssetName = "checksums.txt"
+	signatureAssetExt  = ".minisig"
+)
+
+var (
+	// ErrChecksumMismatch is returned when the downloaded archive doesn't
+	// match the checksums file of the release.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrInvalidSignature is returned when the checksums file doesn't match
+	// its signature or was signed by another key.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// parseChecksums reads a checksums file ("<sha256>  <name>" per line) into
+// a map by file name.
+func parseChecksums(b []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*") // binary mode marker
+		if !ok || name == "" || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid checksums line %q", line)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("invalid checksums line %q", line)
+		}
+		sums[name] = strings.ToLower(sum)
+	}
+	return sums, scanner.Err()
+}
+
+// verifyChecksum checks that data has the checksum of name in sums.
+func verifyChecksum(sums map[string]string, name string, data []byte) error {
+	want, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("%s is not listed in %s", name, checksumsAssetName)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, name, got, want)
+	}
+	return nil
+}
+
+// minisignKey is a minisign Ed25519 public key.
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey reads a minisign public key: the base64 line alone or the
+// content of a .pub file, with its untrusted comment.
+func parseMinisignKey(s string) (minisignKey, error) {
+	b, err := base64.StdEncoding.DecodeString(lastMinisignLine(s, "untrusted comment:"))
+	if err != nil || len(b) != 2+8+ed25519.PublicKeySize || string(b[:2]) != "Ed" {
+		return minisignKey{}, errors.New("invalid minisign public key")
+	}
+	var k minisignKey
+	copy(k.id[:], b[2:10])
+	k.key = ed25519.PublicKey(b[10:])
+	return k, nil
+}
+
+// lastMinisignLine returns the last non-empty line of s not starting with
+// skipPrefix.
+func lastMinisignLine(s, skipPrefix string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !strings.HasPrefix(line, skipPrefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+// verifyMinisign checks the minisign signature sig (the content of a .minisig
+// file) of data with key. Both the legacy and the prehashed (BLAKE2b)
+// signatures are supported, and the trusted comment must match its global
+// signature.
+func verifyMinisign(key minisignKey, data, sig []byte) error {
+	lines := strings.Split(strings.TrimRight(string(sig), "\r\n"), "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(b) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+	alg, keyID, signature := string(b[:2]), b[2:10], b[10:]
+	if !bytes.Equal(keyID, key.id[:]) {
+		return fmt.Errorf("%w: signed by key %X, expected %X", ErrInvalidSignature, reverse(keyID), reverse(key.id[:]))
+	}
+	msg := data
+	switch alg {
+	case "ED":
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	case "Ed":
+	default:
+		return fmt.Errorf("%w: unsupported minisign algorithm %q", ErrInvalidSignature, alg)
+	}
+	if !ed25519.Verify(key.key, msg, signature) {
+		return fmt.Errorf("%w: the file doesn't match its signature", ErrInvalidSignature)
+	}
+
+	comment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return fmt.Errorf("%w: missing trusted comment", ErrInvalidSignature)
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || !ed25519.Verify(key.key, append(signature[:len(signature):len(signature)], comment...), global) {
+		return fmt.Errorf("%w: the trusted comment doesn't match its signature", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// reverse returns b reversed: minisign prints key IDs as little-endian
+// numbers.
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
@@ -0,0 +1,220 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestParseChecksums(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	sums, err := parseChecksums([]byte(sum + "  a.tar.gz\n" + sum + " *b.zip\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sums["a.tar.gz"] != sum || sums["b.zip"] != sum {
+		t.Fatalf("parseChecksums = %v", sums)
+	}
+	if _, err := parseChecksums([]byte("abc  a.tar.gz\n")); err == nil {
+		t.Fatal("parseChecksums accepted a short checksum")
+	}
+}
+
+// testMinisignKey is a minisign key pair for tests.
+type testMinisignKey struct {
+	id   [8]byte
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newTestMinisignKey(t *testing.T) testMinisignKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := testMinisignKey{pub: pub, priv: priv}
+	_, _ = rand.Read(k.id[:])
+	return k
+}
+
+// pubFile returns the content of the .pub file of k.
+func (k testMinisignKey) pubFile() string {
+	b := append([]byte("Ed"), k.id[:]...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(append(b, k.pub...)) + "\n"
+}
+
+// sign returns the .minisig of data, prehashed when alg is "ED".
+func (k testMinisignKey) sign(alg string, data []byte) []byte {
+	msg := data
+	if alg == "ED" {
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	}
+	sig := ed25519.Sign(k.priv, msg)
+	comment := "timestamp:1700000000\tfile:checksums.txt"
+	global := ed25519.Sign(k.priv, append(append([]byte{}, sig...), comment...))
+	line := append(append([]byte(alg), k.id[:]...), sig...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(line) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	k := newTestMinisignKey(t)
+	key, err := parseMinisignKey(k.pubFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("checksums")
+	for _, alg := range []string{"ED", "Ed"} {
+		sig := k.sign(alg, data)
+		if err := verifyMinisign(key, data, sig); err != nil {
+			t.Errorf("verifyMinisign(%s): %v", alg, err)
+		}
+		if err := verifyMinisign(key, []byte("tampered"), sig); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("verifyMinisign(%s) of tampered data: %v", alg, err)
+		}
+		forged := bytes.Replace(sig, []byte("file:checksums.txt"), []byte("file:other.txt"), 1)
+		if err := verifyMinisign(key, data, forged); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("verifyMinisign(%s) with a forged trusted comment: %v", alg, err)
+		}
+	}
+
+	other, err := parseMinisignKey(strings.TrimSpace(strings.Split(newTestMinisignKey(t).pubFile(), "\n")[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyMinisign(other, data, k.sign("ED", data)); err == nil || !strings.Contains(err.Error(), "signed by key") {
+		t.Fatalf("verifyMinisign with another key: %v", err)
+	}
+	if _, err := parseMinisignKey("not a key"); err == nil {
+		t.Fatal("parseMinisignKey accepted an invalid key")
+	}
+}
+
+// testBinary is a new executable that passes the self-check.
+var testBinary = []byte("#!/bin/sh\necho 2.0.0\n")
+
+// newAssetServer serves a release with the archive of the current platform
+// holding binary, a checksums file made from the sums format (the sha256 and
+// the name of the archive; none if empty) and, with a key, its signature. The
+// assets are under <URL>/download/, the URL it returns.
+func newAssetServer(t *testing.T, binary []byte, sums string, key *testMinisignKey) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("builds a tar.gz archive of a shell script")
+	}
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	_ = tw.WriteHeader(&tar.Header{Name: expectedBinaryName(runtime.GOOS), Mode: 0o755, Size: int64(len(binary))})
+	_, _ = tw.Write(binary)
+	_ = tw.Close()
+	_ = gzw.Close()
+
+	rel := testRelease("v2.0.0", false)
+	name := rel.Assets[0].Name
+	files := map[string][]byte{name: buf.Bytes()}
+	if sums != "" {
+		sum := sha256.Sum256(buf.Bytes())
+		files[checksumsAssetName] = fmt.Appendf(nil, sums, hex.EncodeToString(sum[:]), name)
+		if key != nil {
+			files[checksumsAssetName+signatureAssetExt] = key.sign("ED", files[checksumsAssetName])
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(server.Close)
+
+	rel.Assets = nil
+	for name := range files {
+		rel.Assets = append(rel.Assets, asset{Name: name, DownloadURL: server.URL + "/download/" + name})
+	}
+	newReleaseServer(t, []release{rel})
+	return server.URL
+}
+
+func runTestUpdate(t *testing.T, opts Options) (Result, error) {
+	t.Helper()
+	dir := t.TempDir()
+	opts.Owner, opts.Repo, opts.CurrentVersion = "owner", "repo", "v1.0.0"
+	opts.ExePath, opts.WorkDir, opts.DryRun = filepath.Join(dir, "subtitle-tools"), dir, true
+	return Run(context.Background(), opts)
+}
+
+func TestRun_VerifiesArchive(t *testing.T) {
+	const sums = "%s  %s\n"
+	k := newTestMinisignKey(t)
+
+	t.Run("checksum", func(t *testing.T) {
+		newAssetServer(t, testBinary, sums, nil)
+		res, err := runTestUpdate(t, Options{})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if b, _ := os.ReadFile(res.ExePath); !res.Updated || !bytes.Equal(b, testBinary) {
+			t.Fatalf("Run = %+v, binary %q", res, b)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		newAssetServer(t, testBinary, strings.Repeat("0", 64)+"  %[2]s\n", nil)
+		if _, err := runTestUpdate(t, Options{}); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Run: %v, want %v", err, ErrChecksumMismatch)
+		}
+	})
+
+	t.Run("missing checksums", func(t *testing.T) {
+		newAssetServer(t, testBinary, "", nil)
+		if _, err := runTestUpdate(t, Options{}); err == nil || !strings.Contains(err.Error(), "has no checksums.txt") {
+			t.Fatalf("Run: %v", err)
+		}
+		if res, err := runTestUpdate(t, Options{SkipChecksum: true}); err != nil || !res.Updated {
+			t.Fatalf("Run with SkipChecksum = %+v, %v", res, err)
+		}
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		newAssetServer(t, testBinary, sums, &k)
+		if res, err := runTestUpdate(t, Options{PublicKey: k.pubFile()}); err != nil || !res.Updated {
+			t.Fatalf("Run = %+v, %v", res, err)
+		}
+		other := newTestMinisignKey(t)
+		if _, err := runTestUpdate(t, Options{PublicKey: other.pubFile()}); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("Run with another key: %v, want %v", err, ErrInvalidSignature)
+		}
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		newAssetServer(t, testBinary, sums, nil)
+		_, err := runTestUpdate(t, Options{PublicKey: k.pubFile()})
+		if !errors.Is(err, ErrInvalidSignature) || !strings.Contains(err.Error(), "has no checksums.txt.minisig") {
+			t.Fatalf("Run: %v", err)
+		}
+	})
+}
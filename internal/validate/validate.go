@@ -0,0 +1,204 @@
+// Package validate checks subtitle files for timing and formatting problems
+// without modifying them.
+package validate
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/rtl"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Rules.
+const (
+	RuleParseError       = "parse-error"
+	RuleInvalidIndex     = "invalid-index"
+	RuleNegativeDuration = "negative-duration"
+	RuleOutOfOrder       = "out-of-order"
+	RuleOverlap          = "overlap"
+	RuleEmptyText        = "empty-text"
+	RuleTooManyLines     = "too-many-lines"
+	RuleMaxCPS           = "max-cps"
+	RuleDuplicate        = "duplicate"
+	RuleRTLDirection     = "rtl-direction"
+	RuleRTLPunctuation   = "rtl-punctuation"
+)
+
+// Rules lists every rule, in report order.
+var Rules = []string{
+	RuleParseError,
+	RuleInvalidIndex,
+	RuleNegativeDuration,
+	RuleOutOfOrder,
+	RuleOverlap,
+	RuleEmptyText,
+	RuleTooManyLines,
+	RuleMaxCPS,
+	RuleDuplicate,
+	RuleRTLDirection,
+	RuleRTLPunctuation,
+}
+
+// warningRules are reported as warnings: the cue is valid, but may be shown
+// wrong by some players.
+var warningRules = []string{RuleRTLDirection, RuleRTLPunctuation}
+
+const (
+	DefaultMaxLines = 2
+	DefaultMaxCPS   = 20.0
+)
+
+type Options struct {
+	MaxLines int     // lines per cue (0 means DefaultMaxLines)
+	MaxCPS   float64 // characters per second (0 means DefaultMaxCPS)
+	Disabled []string
+}
+
+// Violation is a problem found in a cue.
+type Violation struct {
+	Rule     string `json:"rule"`
+	Position int    `json:"position,omitempty"` // 1-based position of the cue in the file
+	Idx      int    `json:"idx,omitempty"`      // cue index as written in the file
+	Time     string `json:"time,omitempty"`     // cue start time
+	Message  string `json:"message"`
+	// Warning is set for the rules of problems that depend on the player,
+	// which don't fail the validation.
+	Warning bool `json:"warning,omitempty"`
+}
+
+type Report struct {
+	Path       string      `json:"path"`
+	Cues       int         `json:"cues"`
+	Violations []Violation `json:"violations"`
+}
+
+func IsValidRule(rule string) bool {
+	return slices.Contains(Rules, rule)
+}
+
+// File validates the subtitle file at path. A file that can't be parsed is
+// reported as a RuleParseError violation.
+func File(path string, opts Options) (Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Report{}, err
+	}
+	defer fs.CloseOrLog(f, path)
+
+	report := Report{Path: path, Violations: []Violation{}}
+	subs, err := srt.ReadAll(f)
+	if err != nil {
+		if !slices.Contains(opts.Disabled, RuleParseError) {
+			report.Violations = append(report.Violations, Violation{Rule: RuleParseError, Message: err.Error()})
+		}
+		return report, nil
+	}
+	report.Cues = len(subs)
+	report.Violations = append(report.Violations, Check(subs, opts)...)
+	return report, nil
+}
+
+// Check returns the violations of subs, in cue order.
+func Check(subs []*srt.Subtitle, opts Options) []Violation {
+	maxLines := opts.MaxLines
+	if maxLines <= 0 {
+		maxLines = DefaultMaxLines
+	}
+	maxCPS := opts.MaxCPS
+	if maxCPS <= 0 {
+		maxCPS = DefaultMaxCPS
+	}
+
+	violations := []Violation{}
+	seen := make(map[string]int) // timing and text -> position
+	for i, s := range subs {
+		add := func(rule, format string, args ...any) {
+			if slices.Contains(opts.Disabled, rule) {
+				return
+			}
+			violations = append(violations, Violation{
+				Rule:     rule,
+				Position: i + 1,
+				Idx:      s.Idx,
+				Time:     srt.FormatTime(s.FromTime),
+				Message:  fmt.Sprintf(format, args...),
+				Warning:  slices.Contains(warningRules, rule),
+			})
+		}
+
+		// Indexes are compared with the previous cue, so a single gap is
+		// reported once instead of for every following cue.
+		expectedIdx := 1
+		if i > 0 {
+			expectedIdx = subs[i-1].Idx + 1
+		}
+		if s.Idx != expectedIdx {
+			add(RuleInvalidIndex, "index %d, expected %d", s.Idx, expectedIdx)
+		}
+
+		if s.ToTime <= s.FromTime {
+			add(RuleNegativeDuration, "cue ends at %s, not after it starts", srt.FormatTime(s.ToTime))
+		}
+
+		if i > 0 {
+			prev := subs[i-1]
+			switch {
+			case s.FromTime < prev.FromTime:
+				add(RuleOutOfOrder, "starts before the previous cue (%s)", srt.FormatTime(prev.FromTime))
+			case s.FromTime < prev.ToTime:
+				add(RuleOverlap, "starts before the previous cue ends (%s)", srt.FormatTime(prev.ToTime))
+			}
+		}
+
+		if srt.VisibleLength(strings.TrimSpace(s.Text)) == 0 {
+			add(RuleEmptyText, "cue has no visible text")
+		}
+
+		if lines := strings.Count(s.Text, "\n") + 1; s.Text != "" && lines > maxLines {
+			add(RuleTooManyLines, "%d lines (max %d)", lines, maxLines)
+		}
+
+		if cps := srt.CharsPerSecond(s); cps > maxCPS {
+			add(RuleMaxCPS, "%.1f characters per second (max %g)", cps, maxCPS)
+		}
+
+		for _, issue := range rtl.MissingMarks(s.Text) {
+			add(RuleRTLDirection, "line %d (%q) starts or ends outside its right-to-left text: players that lay lines out left to right show it on the wrong side (add RLM marks)", issue.Line, issue.Text)
+		}
+		for _, issue := range rtl.ReversedPunctuation(s.Text) {
+			add(RuleRTLPunctuation, "line %d (%q) has its punctuation at the wrong end, as if stored in display order", issue.Line, issue.Text)
+		}
+
+		key := fmt.Sprintf("%d|%d|%s", s.FromTime, s.ToTime, s.Text)
+		if first, ok := seen[key]; ok {
+			add(RuleDuplicate, "same timing and text as cue at position %d", first)
+		} else {
+			seen[key] = i + 1
+		}
+	}
+	return violations
+}
+
+// Errors returns the number of violations that are not warnings.
+func (r Report) Errors() int {
+	n := 0
+	for _, v := range r.Violations {
+		if !v.Warning {
+			n++
+		}
+	}
+	return n
+}
+
+// Counts returns the number of violations per rule.
+func (r Report) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, v := range r.Violations {
+		counts[v.Rule]++
+	}
+	return counts
+}
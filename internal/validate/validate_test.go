@@ -0,0 +1,84 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+func sub(idx int, from, to time.Duration, text string) *srt.Subtitle {
+	return &srt.Subtitle{Idx: idx, FromTime: from, ToTime: to, Text: text}
+}
+
+func rules(violations []Violation) string {
+	out := make([]string, len(violations))
+	for i, v := range violations {
+		out[i] = fmt.Sprintf("%s@%d", v.Rule, v.Position)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestCheck(t *testing.T) {
+	s := time.Second
+	subs := []*srt.Subtitle{
+		sub(1, 1*s, 3*s, "Hello"),
+		sub(2, 2*s, 4*s, "Overlaps"),                                          // overlap
+		sub(4, 5*s, 5*s, "Zero"),                                              // invalid index, negative duration
+		sub(5, 4500*time.Millisecond, 6*s, "Back in time"),                    // out of order
+		sub(6, 7*s, 8*s, "<i></i>"),                                           // empty
+		sub(7, 9*s, 12*s, "One\nTwo\nThree"),                                  // too many lines
+		sub(8, 13*s, 14*s, "This line is way too long to read in one second"), // max cps
+		sub(9, 13*s, 14*s, "This line is way too long to read in one second"), // duplicate (and overlap, max cps)
+	}
+	got := rules(Check(subs, Options{}))
+	want := "overlap@2 invalid-index@3 negative-duration@3 out-of-order@4 empty-text@5 too-many-lines@6 max-cps@7 overlap@8 max-cps@8 duplicate@8"
+	if got != want {
+		t.Fatalf("violations:\n got %s\nwant %s", got, want)
+	}
+
+	got = rules(Check(subs, Options{MaxLines: 3, MaxCPS: 100, Disabled: []string{RuleOverlap, RuleDuplicate}}))
+	want = "invalid-index@3 negative-duration@3 out-of-order@4 empty-text@5"
+	if got != want {
+		t.Fatalf("violations with options:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFile_ReportsParseErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.srt")
+	if err := os.WriteFile(path, []byte("1\nnot a timing\nHello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err := File(path, Options{})
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Rule != RuleParseError {
+		t.Fatalf("expected a parse error violation, got %+v", report.Violations)
+	}
+	if report.Counts()[RuleParseError] != 1 {
+		t.Fatalf("unexpected counts %v", report.Counts())
+	}
+}
+
+func TestCheck_RTL(t *testing.T) {
+	s := time.Second
+	subs := []*srt.Subtitle{
+		sub(1, 1*s, 3*s, "שלום עולם"),
+		sub(2, 4*s, 6*s, "- מה שלומך?"),             // rtl-direction
+		sub(3, 7*s, 9*s, "\u200f- מה שלומך?\u200f"), // marked
+		sub(4, 10*s, 12*s, "\u200f!שלום\u200f"),     // rtl-punctuation
+	}
+	violations := Check(subs, Options{})
+	if got, want := rules(violations), "rtl-direction@2 rtl-punctuation@4"; got != want {
+		t.Fatalf("violations:\n got %s\nwant %s", got, want)
+	}
+	report := Report{Violations: violations}
+	if !violations[0].Warning || report.Errors() != 0 {
+		t.Fatalf("expected warnings only, got %+v", violations)
+	}
+}
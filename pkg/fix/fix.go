@@ -0,0 +1,172 @@
+// Package fix cleans up subtitle files the way the fix command does: merging
+// duplicated and overlapping cues, re-wrapping lines, stripping styles and
+// hearing-impaired annotations, fixing OCR errors and timing.
+//
+//	res, err := fix.Run(ctx, fix.Options{
+//		InputPath:  "movie.en.srt",
+//		OutputPath: "movie.en.fixed.srt",
+//		StripHI:    true,
+//		Progress:   func(p fix.Progress) { log.Printf("%s %d/%d", p.Step, p.Completed, p.Total) },
+//	})
+package fix
+
+import (
+	"context"
+
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
+	"github.com/adrianmusante/subtitle-tools/internal/fix"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/pkg/subtitles"
+)
+
+type (
+	// Options configures Run. The zero value of every field but InputPath
+	// is a valid default.
+	Options = fix.Options
+	// Result is the outcome of Run.
+	Result = fix.Result
+	// Action is a change made by Run.
+	Action = fix.Action
+	// Report is the JSON document written to Options.ReportPath.
+	Report = fix.Report
+	// Typography selects the punctuation normalizations of Options.Typography.
+	Typography = fix.Typography
+	// Progress reports the last completed processing step.
+	Progress = fix.Progress
+	// ProgressFunc receives a Progress after each processing step.
+	ProgressFunc = fix.ProgressFunc
+)
+
+// Action kinds recorded in Result.Actions.
+const (
+	ActionDroppedTranslatorCredit = fix.ActionDroppedTranslatorCredit
+	ActionDroppedNotForced        = fix.ActionDroppedNotForced
+	ActionDroppedSDH              = fix.ActionDroppedSDH
+	ActionNormalizedUnicode       = fix.ActionNormalizedUnicode
+	ActionFixedOCR                = fix.ActionFixedOCR
+	ActionFixedSpelling           = fix.ActionFixedSpelling
+	ActionRemovedCredit           = fix.ActionRemovedCredit
+	ActionStrippedStyle           = fix.ActionStrippedStyle
+	ActionStrippedASSTags         = fix.ActionStrippedASSTags
+	ActionStrippedHI              = fix.ActionStrippedHI
+	ActionRemovedDecorativeLines  = fix.ActionRemovedDecorativeLines
+	ActionNormalizedTypography    = fix.ActionNormalizedTypography
+	ActionAppliedRule             = fix.ActionAppliedRule
+	ActionCensored                = fix.ActionCensored
+	ActionDroppedCensored         = fix.ActionDroppedCensored
+	ActionRemovedEmpty            = fix.ActionRemovedEmpty
+	ActionRemovedInvalidTiming    = fix.ActionRemovedInvalidTiming
+	ActionRemovedDuplicate        = fix.ActionRemovedDuplicate
+	ActionMergedOverlap           = fix.ActionMergedOverlap
+	ActionTrimmedOverlap          = fix.ActionTrimmedOverlap
+	ActionShiftedOverlap          = fix.ActionShiftedOverlap
+	ActionMergedRepeat            = fix.ActionMergedRepeat
+	ActionRewrapped               = fix.ActionRewrapped
+	ActionMergedShortLines        = fix.ActionMergedShortLines
+	ActionRebalanced              = fix.ActionRebalanced
+	ActionDroppedPastEnd          = fix.ActionDroppedPastEnd
+	ActionClampedToEnd            = fix.ActionClampedToEnd
+	ActionNormalizedDialogue      = fix.ActionNormalizedDialogue
+	ActionReindexed               = fix.ActionReindexed
+	ActionSorted                  = fix.ActionSorted
+	ActionShifted                 = fix.ActionShifted
+	ActionTrimmedForGap           = fix.ActionTrimmedForGap
+	ActionExtendedDuration        = fix.ActionExtendedDuration
+	ActionMergedShortCue          = fix.ActionMergedShortCue
+	ActionSplitFastCue            = fix.ActionSplitFastCue
+	ActionSnappedToShot           = fix.ActionSnappedToShot
+)
+
+// Values of Options.StripHIMode.
+const (
+	StripHIModeSafe         = fix.StripHIModeSafe
+	StripHIModeSafePlus     = fix.StripHIModeSafePlus
+	StripHIModeStandard     = fix.StripHIModeStandard
+	StripHIModeStandardPlus = fix.StripHIModeStandardPlus
+	DefaultStripHIMode      = fix.DefaultStripHIMode
+)
+
+// Values of Options.OverlapPolicy.
+const (
+	OverlapPolicyMerge   = fix.OverlapPolicyMerge
+	OverlapPolicyTrim    = fix.OverlapPolicyTrim
+	OverlapPolicyShift   = fix.OverlapPolicyShift
+	OverlapPolicyKeep    = fix.OverlapPolicyKeep
+	DefaultOverlapPolicy = fix.DefaultOverlapPolicy
+)
+
+// Values of Options.DialogueDashes.
+const (
+	DialogueDashesAll    = fix.DialogueDashesAll
+	DialogueDashesSecond = fix.DialogueDashesSecond
+	DialogueDashesNone   = fix.DialogueDashesNone
+)
+
+// Values of Options.CensorStyle.
+const (
+	CensorStyleStars     = censor.StyleStars
+	CensorStyleRemoveCue = censor.StyleRemoveCue
+	CensorStyleBeepText  = censor.StyleBeepText
+	DefaultCensorStyle   = censor.DefaultStyle
+)
+
+// Defaults applied by Run to the zero values of Options.
+const (
+	DefaultMaxLineLength      = fix.DefaultMaxLineLength
+	DefaultMinWordsForMerging = fix.DefaultMinWordsForMerging
+	DefaultSnapThreshold      = fix.DefaultSnapThreshold
+)
+
+// Processing steps reported to Options.Progress.
+const (
+	StepMerge  = fix.StepMerge
+	StepSort   = fix.StepSort
+	StepShift  = fix.StepShift
+	StepSplit  = fix.StepSplit
+	StepSnap   = fix.StepSnap
+	StepTiming = fix.StepTiming
+	StepClamp  = fix.StepClamp
+	StepWrite  = fix.StepWrite
+)
+
+// MaxLineLengthFor returns the default Options.MaxLineLength of a language:
+// DefaultMaxLineLength unless the language (e.g. Japanese) has its own.
+func MaxLineLengthFor(language string) int {
+	return fix.MaxLineLengthFor(language)
+}
+
+// ErrNoForcedCues is returned by Run with Options.OnlyForced when the input
+// has no forced cue.
+var ErrNoForcedCues = fix.ErrNoForcedCues
+
+// Run fixes the file at opts.InputPath and writes the result to
+// opts.OutputPath (the input itself when empty). Intermediate files go to
+// opts.WorkDir; when it is empty, Run uses a temporary directory removed when
+// it returns, except with DryRun, whose output is left there.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if opts.WorkDir == "" {
+		workdir, cleanup, err := run.NewWorkdir("", "fix")
+		if err != nil {
+			return Result{}, err
+		}
+		if !opts.DryRun {
+			defer cleanup()
+		}
+		opts.WorkDir = workdir
+	}
+	return fix.Run(ctx, opts)
+}
+
+// FixDocument fixes a parsed document without files, returning the fixed
+// document, with the metadata of doc, and the actions made; doc is left
+// untouched. The file options of opts are ignored.
+func FixDocument(ctx context.Context, doc *subtitles.Document, opts Options) (*subtitles.Document, []Action, error) {
+	return fix.FixDocument(ctx, doc, opts)
+}
+
+// FixSubtitles fixes parsed cues without files, returning the fixed cues and
+// the actions made; subs are left untouched. The file options of opts are
+// ignored.
+func FixSubtitles(ctx context.Context, subs []*subtitles.Subtitle, opts Options) ([]*subtitles.Subtitle, []Action, error) {
+	return fix.FixSubtitles(ctx, subs, opts)
+}
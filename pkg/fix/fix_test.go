@@ -0,0 +1,43 @@
+package fix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun_WithoutWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.srt")
+	output := filepath.Join(dir, "out.srt")
+	in := "1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i>\n\n2\n00:00:03,000 --> 00:00:04,000\n[DOOR SLAMS]\n\n"
+	if err := os.WriteFile(input, []byte(in), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var steps []string
+	res, err := Run(context.Background(), Options{
+		InputPath:  input,
+		OutputPath: output,
+		StripStyle: true,
+		StripHI:    true,
+		Progress:   func(p Progress) { steps = append(steps, p.Step) },
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.WrittenPath != output {
+		t.Fatalf("WrittenPath = %s, want %s", res.WrittenPath, output)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"; string(b) != want {
+		t.Fatalf("output = %q, want %q", b, want)
+	}
+	if len(steps) == 0 || steps[len(steps)-1] != StepWrite {
+		t.Fatalf("unexpected progress steps: %v", steps)
+	}
+}
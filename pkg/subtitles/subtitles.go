@@ -0,0 +1,195 @@
+// Package subtitles reads and writes SubRip (.srt) subtitles. It is the
+// public entry point to the parser used by the subtitle-tools commands.
+// ParseDocument and WriteDocument keep what the file holds besides its cues;
+// Parse and Write deal with the cues only.
+//
+//	subs, err := subtitles.ParseFile("movie.en.srt")
+//	if err != nil {
+//		return err
+//	}
+//	for _, s := range subs {
+//		s.FromTime += 500 * time.Millisecond
+//	}
+//	return subtitles.WriteFile("movie.en.srt", subs)
+package subtitles
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+
+	"github.com/adrianmusante/subtitle-tools/internal/fs"
+	"github.com/adrianmusante/subtitle-tools/internal/srt"
+)
+
+// Subtitle is a cue: its number, start and end times and text (lines
+// separated by "\n").
+type Subtitle = srt.Subtitle
+
+// Document is a subtitle file: its cues and what else it holds (format,
+// encoding, BOM, header and trailing blocks), plus the warnings found while
+// reading it.
+type Document = srt.Document
+
+// ParseError is returned when the input is not a valid SubRip document.
+type ParseError = srt.ParseError
+
+// Selection picks cues by index and by time (the Selection option of fix and
+// translate). The zero Selection selects every cue.
+type Selection = srt.Selection
+
+// IdxRange is an inclusive range of cue indexes of a Selection; To is 0 for a
+// range open at the end.
+type IdxRange = srt.IdxRange
+
+// TimeRange is a span of the timeline of a Selection; To is 0 for a range
+// open at the end.
+type TimeRange = srt.TimeRange
+
+// ErrNoSelectedCues is returned by fix and translate when a Selection matches
+// no cue.
+var ErrNoSelectedCues = srt.ErrNoSelectedCues
+
+// ErrNoFrameRate is returned when reading or writing MicroDVD without a frame
+// rate.
+var ErrNoFrameRate = srt.ErrNoFrameRate
+
+// Values of Document.Format and Document.Encoding.
+const (
+	FormatSRT      = srt.FormatSRT
+	FormatMicroDVD = srt.FormatMicroDVD
+	FormatTTML     = srt.FormatTTML
+	FormatSAMI     = srt.FormatSAMI
+	FormatSBV      = srt.FormatSBV
+	EncodingUTF8   = srt.EncodingUTF8
+)
+
+// ParseDocument reads the document of r. The text of each cue is trimmed line
+// by line; the blocks that aren't cues are kept as read. SAMI and SubViewer
+// documents are detected and read too, and written back as SRT.
+func ParseDocument(r io.Reader) (*Document, error) {
+	return srt.Read(r)
+}
+
+// ParseDocumentFile reads the document of the file at path.
+func ParseDocumentFile(path string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseOrLog(f, path)
+	return ParseDocument(f)
+}
+
+// ParseMicroDVD reads a MicroDVD (.sub) document, converting its frame
+// numbers to times at fps frames per second (the frame rate of the file when
+// not positive). WriteDocument writes a document back as MicroDVD when its
+// Format is FormatMicroDVD, at its FrameRate.
+func ParseMicroDVD(r io.Reader, fps float64) (*Document, error) {
+	return srt.ReadMicroDVD(r, fps)
+}
+
+// ParseTTML reads a TTML document (IMSC 1.1, DFXP), with the styles of its
+// text as tags and its xml:lang as Language. WriteDocument writes a document
+// back as IMSC 1.1 when its Format is FormatTTML.
+func ParseTTML(r io.Reader) (*Document, error) {
+	return srt.ReadTTML(r)
+}
+
+// FramesToTime returns the time of a frame number of a video at fps frames
+// per second.
+func FramesToTime(frames int, fps float64) time.Duration {
+	return srt.FramesToTime(frames, fps)
+}
+
+// TimeToFrames returns the number of the frame shown at d in a video at fps
+// frames per second.
+func TimeToFrames(d time.Duration, fps float64) int {
+	return srt.TimeToFrames(d, fps)
+}
+
+// Parse reads every cue of r. A leading UTF-8 BOM is skipped, and the text of
+// each cue is trimmed line by line.
+func Parse(r io.Reader) ([]*Subtitle, error) {
+	return srt.ReadAll(r)
+}
+
+// ParseFile reads every cue of the file at path.
+func ParseFile(path string) ([]*Subtitle, error) {
+	doc, err := ParseDocumentFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Cues, nil
+}
+
+// Write writes subs to w, numbering the cues from 1.
+func Write(w io.Writer, subs []*Subtitle) error {
+	return srt.WriteAll(w, subs)
+}
+
+// WriteIndexed writes subs to w keeping the number of each cue.
+func WriteIndexed(w io.Writer, subs []*Subtitle) error {
+	return srt.WriteAllIndexed(w, subs)
+}
+
+// WriteFile writes subs to the file at path, numbering the cues from 1.
+func WriteFile(path string, subs []*Subtitle) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, subs); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// WriteDocument writes doc to w, numbering the cues from 1.
+func WriteDocument(w io.Writer, doc *Document) error {
+	return srt.Write(w, doc, false)
+}
+
+// WriteDocumentFile writes doc to the file at path, numbering the cues from 1.
+func WriteDocumentFile(path string, doc *Document) error {
+	var buf bytes.Buffer
+	if err := WriteDocument(&buf, doc); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Sort sorts subs in place by start time, then end time, then number.
+func Sort(subs []*Subtitle) {
+	srt.Sort(subs)
+}
+
+// Reindex numbers subs in place from 1, in slice order.
+func Reindex(subs []*Subtitle) {
+	srt.Reindex(subs)
+}
+
+// Units of SplitTiming.
+const (
+	TimingWord   = srt.TimingWord
+	TimingPhrase = srt.TimingPhrase
+)
+
+// SplitTiming returns subs with every cue split into sequential cues of a word
+// (TimingWord) or a phrase (TimingPhrase) each, with the time of the cue
+// shared between them by their length, numbered from 1. subs are left
+// untouched.
+func SplitTiming(subs []*Subtitle, unit string) []*Subtitle {
+	return srt.SplitTiming(subs, unit)
+}
+
+// ParseIdxRanges parses a comma-separated list of cue indexes and inclusive
+// ranges, open at either end: "120-180", "5,8,10-" or "-20".
+func ParseIdxRanges(s string) ([]IdxRange, error) {
+	return srt.ParseIdxRanges(s)
+}
+
+// ParseTimeRange parses a time range, with both ends given as SRT timestamps
+// or Go durations and either one optional: "00:10:00-00:20:00", "10m-20m" or
+// "01:30:00-".
+func ParseTimeRange(s string) (TimeRange, error) {
+	return srt.ParseTimeRange(s)
+}
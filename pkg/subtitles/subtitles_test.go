@@ -0,0 +1,67 @@
+package subtitles
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAndWriteFile(t *testing.T) {
+	in := "\uFEFF3\n00:00:01,000 --> 00:00:02,500\n  Hello  \nthere\n\n7\n00:00:03,000 --> 00:00:04,000\nBye\n\n"
+	subs, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(subs) != 2 || subs[0].Idx != 3 || subs[0].Text != "Hello\nthere" || subs[0].ToTime != 2500*time.Millisecond {
+		t.Fatalf("unexpected cues: %+v", subs)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.srt")
+	if err := WriteFile(path, subs); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(got) != 2 || got[0].Idx != 1 || got[1].Idx != 2 || got[1].Text != "Bye" {
+		t.Fatalf("unexpected cues after round trip: %+v", got)
+	}
+}
+
+func TestParse_InvalidInput(t *testing.T) {
+	_, err := Parse(strings.NewReader("1\nnot a timing\nHello\n"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	if _, err := ParseFile(filepath.Join(t.TempDir(), "missing.srt")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+}
+
+func TestParseAndWriteDocument(t *testing.T) {
+	in := "\uFEFFNOTE made by hand\n\n1\n00:00:01,000 --> 00:00:02,000\nHello\n\nThe end\n\n"
+	doc, err := ParseDocument(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ParseDocument: %v", err)
+	}
+	if doc.Format != FormatSRT || !doc.BOM || len(doc.Header) != 1 || len(doc.Trailer) != 1 || len(doc.Cues) != 1 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.srt")
+	if err := WriteDocumentFile(path, doc); err != nil {
+		t.Fatalf("WriteDocumentFile: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != in {
+		t.Fatalf("round trip:\n got %q\nwant %q", got, in)
+	}
+}
@@ -0,0 +1,243 @@
+// Package translate translates subtitle files with an OpenAI-compatible chat
+// model or DeepL, the way the translate command does: cues are sent in
+// batches, concurrently and within a rate limit, with retries, and the output
+// keeps the timing of the input.
+//
+//	res, err := translate.Run(ctx, translate.Options{
+//		InputPath:        "movie.en.srt",
+//		OutputPath:       "movie.es.srt",
+//		TargetLanguage:   "es",
+//		Model:            "gpt-4o-mini",
+//		APIKey:           os.Getenv("OPENAI_API_KEY"),
+//		RetryMaxAttempts: translate.DefaultRetryMaxAttempts,
+//		Progress:         func(p translate.Progress) { log.Printf("%d/%d batches", p.CompletedBatches, p.TotalBatches) },
+//	})
+package translate
+
+import (
+	"context"
+
+	"github.com/adrianmusante/subtitle-tools/internal/censor"
+	"github.com/adrianmusante/subtitle-tools/internal/run"
+	"github.com/adrianmusante/subtitle-tools/internal/translate"
+	"github.com/adrianmusante/subtitle-tools/pkg/subtitles"
+)
+
+type (
+	// Options configures Run. InputPath, OutputPath, TargetLanguage and the
+	// Model (or the DeepL APIKey) are required; zero values of the other fields
+	// are defaults, except RetryMaxAttempts and RetryParseMaxAttempts, where 0
+	// means a single attempt.
+	Options = translate.Options
+	// Result is the outcome of the translation into one target language.
+	Result = translate.Result
+	// Target is one output language of RunTargets.
+	Target = translate.Target
+	// Progress is a snapshot of the translation of one target language.
+	Progress = translate.Progress
+	// ProgressFunc receives a Progress when a target starts, after every
+	// batch and when it is done. It must be safe for concurrent use.
+	ProgressFunc = translate.ProgressFunc
+	// ParseStats describes how the model responses of a run were parsed
+	// (Result.Parse).
+	ParseStats = translate.ParseStats
+	// SeriesContext carries the names and terms of the files translated
+	// before into the prompts of the next ones (Options.SeriesContext).
+	SeriesContext = translate.SeriesContext
+)
+
+// Values of Options.Provider.
+const (
+	ProviderOpenAI  = translate.ProviderOpenAI
+	ProviderGemini  = translate.ProviderGemini
+	ProviderDeepL   = translate.ProviderDeepL
+	DefaultProvider = translate.DefaultProvider
+)
+
+// Values of Options.SafetyThreshold.
+const (
+	SafetyBlockNone        = translate.SafetyBlockNone
+	SafetyOff              = translate.SafetyOff
+	SafetyBlockHigh        = translate.SafetyBlockHigh
+	SafetyBlockMedium      = translate.SafetyBlockMedium
+	SafetyBlockLow         = translate.SafetyBlockLow
+	SafetyDefault          = translate.SafetyDefault
+	DefaultSafetyThreshold = translate.DefaultSafetyThreshold
+)
+
+// Values of Options.Review.
+const (
+	ReviewModeOff    = translate.ReviewModeOff
+	ReviewModeFix    = translate.ReviewModeFix
+	ReviewModeReport = translate.ReviewModeReport
+)
+
+// Values of Options.LengthPolicy.
+const (
+	LengthPolicyWrap    = translate.LengthPolicyWrap
+	LengthPolicyShorten = translate.LengthPolicyShorten
+	LengthPolicyReport  = translate.LengthPolicyReport
+	DefaultLengthPolicy = translate.DefaultLengthPolicy
+)
+
+// Values of Options.LineBreaks.
+const (
+	LineBreaksPreserve = translate.LineBreaksPreserve
+	LineBreaksReflow   = translate.LineBreaksReflow
+	DefaultLineBreaks  = translate.DefaultLineBreaks
+)
+
+// Values of Options.SDH.
+const (
+	SDHKeep     = translate.SDHKeep
+	SDHStrip    = translate.SDHStrip
+	SDHGenerate = translate.SDHGenerate
+	DefaultSDH  = translate.DefaultSDH
+)
+
+// Values of Options.CensorStyle.
+const (
+	CensorStyleStars     = censor.StyleStars
+	CensorStyleRemoveCue = censor.StyleRemoveCue
+	CensorStyleBeepText  = censor.StyleBeepText
+	DefaultCensorStyle   = censor.DefaultStyle
+)
+
+// Values of Options.ParseStrictness.
+const (
+	ParseStrict            = translate.ParseStrict
+	ParseTolerant          = translate.ParseTolerant
+	ParseSalvage           = translate.ParseSalvage
+	DefaultParseStrictness = translate.DefaultParseStrictness
+)
+
+// Keys of ParseStats.Modes, from the strictest parse to the last-resort
+// salvage.
+const (
+	ParseModeArray           = translate.ParseModeArray
+	ParseModeEnvelope        = translate.ParseModeEnvelope
+	ParseModeObjects         = translate.ParseModeObjects
+	ParseModeLines           = translate.ParseModeLines
+	ParseModeLinesRepaired   = translate.ParseModeLinesRepaired
+	ParseModeObjectsRepaired = translate.ParseModeObjectsRepaired
+)
+
+// Values of Options.SideBySideFormat.
+const (
+	SideBySideMarkdown = translate.SideBySideMarkdown
+	SideBySideCSV      = translate.SideBySideCSV
+	SideBySideHTML     = translate.SideBySideHTML
+	DefaultSideBySide  = translate.DefaultSideBySide
+)
+
+// Values of Options.ResponseMode.
+const (
+	ResponseModeAuto       = translate.ResponseModeAuto
+	ResponseModeNDJSON     = translate.ResponseModeNDJSON
+	ResponseModeJSONSchema = translate.ResponseModeJSONSchema
+)
+
+// Values of Options.Formality (DeepL).
+const (
+	FormalityDefault    = translate.FormalityDefault
+	FormalityMore       = translate.FormalityMore
+	FormalityLess       = translate.FormalityLess
+	FormalityPreferMore = translate.FormalityPreferMore
+	FormalityPreferLess = translate.FormalityPreferLess
+)
+
+// Defaults of the translate command.
+const (
+	DefaultRequestTimeout          = translate.DefaultRequestTimeout
+	DefaultMaxBatchChars           = translate.DefaultMaxBatchChars
+	DefaultMaxWorkers              = translate.DefaultMaxWorkers
+	DefaultRequestPerSecond        = translate.DefaultRequestPerSecond
+	DefaultRetryMaxAttempts        = translate.DefaultRetryMaxAttempts
+	DefaultParseRetryMaxAttempts   = translate.DefaultParseRetryMaxAttempts
+	DefaultBatchPollInterval       = translate.DefaultBatchPollInterval
+	DefaultCircuitBreakerThreshold = translate.DefaultCircuitBreakerThreshold
+	DefaultCircuitBreakerCooldown  = translate.DefaultCircuitBreakerCooldown
+)
+
+// NewSeriesContext returns an empty SeriesContext, to share across the
+// episodes of a season.
+func NewSeriesContext() *SeriesContext {
+	return translate.NewSeriesContext()
+}
+
+// ErrNoForcedCues is returned with Options.OnlyForced when the input has no
+// forced cue.
+var ErrNoForcedCues = translate.ErrNoForcedCues
+
+// ErrAlreadyTargetLanguage is returned when the input already appears to be in
+// the target language and Options.Force is not set.
+var ErrAlreadyTargetLanguage = translate.ErrAlreadyTargetLanguage
+
+// Run translates the file at opts.InputPath into opts.TargetLanguage and
+// writes it to opts.OutputPath. Intermediate files go to opts.WorkDir; when it
+// is empty, Run uses a temporary directory removed when it returns, except
+// with DryRun, whose output is left there.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	results, err := RunTargets(ctx, opts, []Target{{
+		Language:         opts.TargetLanguage,
+		OutputPath:       opts.OutputPath,
+		TMXExportPath:    opts.TMXExportPath,
+		ReviewReportPath: opts.ReviewReportPath,
+		LengthReportPath: opts.LengthReportPath,
+	}})
+	if err != nil {
+		return Result{}, err
+	}
+	return results[0], nil
+}
+
+// RunTargets translates the input into several target languages
+// concurrently, reading and batching it once. The results are in the order of
+// targets.
+func RunTargets(ctx context.Context, opts Options, targets []Target) ([]Result, error) {
+	if opts.WorkDir == "" {
+		workdir, cleanup, err := run.NewWorkdir("", "translate")
+		if err != nil {
+			return nil, err
+		}
+		if !opts.DryRun {
+			defer cleanup()
+		}
+		opts.WorkDir = workdir
+	}
+	return translate.RunTargets(ctx, opts, targets)
+}
+
+// TranslateDocument translates a parsed document into opts.TargetLanguage
+// without reading or writing subtitle files, returning the translated
+// document, with the timing and untranslated metadata of doc, which is left
+// untouched. The output options of opts are ignored.
+func TranslateDocument(ctx context.Context, doc *subtitles.Document, opts Options) (*subtitles.Document, Result, error) {
+	return translate.TranslateDocument(ctx, doc, opts)
+}
+
+// TranslateSubtitles translates parsed cues into opts.TargetLanguage without
+// subtitle files, returning the translated cues; subs are left untouched. The
+// output options of opts (OutputPath, DryRun, WorkDir, TMXExportPath and the
+// report paths) are ignored.
+func TranslateSubtitles(ctx context.Context, subs []*subtitles.Subtitle, opts Options) ([]*subtitles.Subtitle, Result, error) {
+	return translate.TranslateSubtitles(ctx, subs, opts)
+}
+
+// IsAuthError reports whether err is a rejection of the API key by the
+// provider (HTTP 401 or 403).
+func IsAuthError(err error) bool {
+	return translate.IsAuthError(err)
+}
+
+// IsRateLimitError reports whether err is a rate limit (HTTP 429) that
+// persisted after every retry.
+func IsRateLimitError(err error) bool {
+	return translate.IsRateLimitError(err)
+}
+
+// IsParseRetryError reports whether err is a model reply that couldn't be
+// parsed after every retry.
+func IsParseRetryError(err error) bool {
+	return translate.IsParseRetryError(err)
+}
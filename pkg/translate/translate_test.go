@@ -0,0 +1,53 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_WithoutWorkdir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"idx\":1,\"text\":\"Hola\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.srt")
+	output := filepath.Join(dir, "out.srt")
+	if err := os.WriteFile(input, []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var last Progress
+	res, err := Run(context.Background(), Options{
+		InputPath:      input,
+		OutputPath:     output,
+		TargetLanguage: "es",
+		Model:          "gpt-test",
+		APIKey:         "test",
+		BaseURL:        server.URL,
+		Progress:       func(p Progress) { last = p },
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.WrittenPath != output || res.Batches != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), "Hola") {
+		t.Fatalf("expected translated output, got:\n%s", b)
+	}
+	if last.CompletedBatches != 1 || last.TotalBatches != 1 {
+		t.Fatalf("unexpected last progress: %+v", last)
+	}
+}